@@ -32,6 +32,102 @@ type config struct {
 		// enabled either disables or enables rate limiting altogether.
 		enabled bool
 	}
+
+	// gc is the configuration of the storage garbage collector.
+	gc struct {
+		// interval is how often the garbage collector runs.
+		interval time.Duration
+
+		// grace is how old unreferenced media and files must be before
+		// they are collected.
+		grace time.Duration
+
+		// dryRun reports garbage without deleting anything.
+		dryRun bool
+
+		// enabled runs the garbage collector in the background.
+		enabled bool
+
+		// once runs the garbage collector a single time, then exits
+		// instead of starting the server.
+		once bool
+	}
+
+	// release is the configuration of scheduled grade releases.
+	release struct {
+		// interval is how often assignments are checked for grades
+		// that are due to be released.
+		interval time.Duration
+
+		// enabled releases scheduled grades in the background.
+		enabled bool
+	}
+
+	// lifecycle is the configuration of scheduled assignment
+	// publishing and locking.
+	lifecycle struct {
+		// interval is how often assignments are checked for being due
+		// to be published or locked.
+		interval time.Duration
+
+		// enabled publishes and locks assignments in the background.
+		enabled bool
+	}
+
+	// peerReview is the configuration of peer reviewer allocation.
+	peerReview struct {
+		// interval is how often assignments are checked for being due,
+		// so that their reviewers can be allocated.
+		interval time.Duration
+
+		// enabled allocates reviewers in the background.
+		enabled bool
+	}
+
+	// similarity is the configuration of similarity reports.
+	similarity struct {
+		// interval is how often reports that are waiting to be made
+		// are looked for.
+		interval time.Duration
+
+		// enabled makes similarity reports in the background.
+		enabled bool
+	}
+
+	// autograde is the configuration of the autograder.
+	autograde struct {
+		// interval is how often queued submissions are looked for.
+		interval time.Duration
+
+		// enabled grades submissions in the background.
+		enabled bool
+
+		// runtime is the container runtime that submissions are run
+		// with, such as docker or podman. The autograder cannot be
+		// enabled without one.
+		runtime string
+
+		// image is the container image that harnesses which do not
+		// name one are run in.
+		image string
+	}
+
+	// scanner is the configuration of the malware scanner that
+	// uploads are checked with.
+	scanner struct {
+		// clamd is the address of a ClamAV daemon. Scanning is
+		// disabled when it is empty.
+		clamd string
+
+		timeout time.Duration
+	}
+
+	// quota is the most storage, in bytes, a user or a course may
+	// use. Zero means unlimited.
+	quota struct {
+		user   int64
+		course int64
+	}
 }
 
 // openDB opens a connection to the database using a certain config.
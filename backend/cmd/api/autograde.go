@@ -0,0 +1,30 @@
+package main
+
+import (
+	"context"
+	"time"
+)
+
+// gradeSubmissions runs the autograder over queued submissions,
+// checking every interval. It is meant to be run in its own goroutine,
+// like collectGarbage.
+func (app *application) gradeSubmissions() {
+	for {
+		time.Sleep(app.config.autograde.interval)
+
+		app.runAutograder()
+	}
+}
+
+// runAutograder grades the queued submissions and logs how many were
+// run.
+func (app *application) runAutograder() {
+	ran, err := app.services.AutogradeService.RunQueued(context.Background())
+	if err != nil {
+		app.logger.Printf("Autograder failed: %v", err)
+	}
+
+	if ran > 0 {
+		app.logger.Printf("Autograder, jobs run: %d", ran)
+	}
+}
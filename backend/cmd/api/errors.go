@@ -4,6 +4,10 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+
+	"github.com/n30w/Darkspace/internal/dal"
+	"github.com/n30w/Darkspace/internal/domain"
+	"github.com/n30w/Darkspace/internal/models"
 )
 
 // Methods in this file define error handling functions.
@@ -139,3 +143,255 @@ func (app *application) notPermittedResponse(
 	message := "your user account doesn't have the necessary permissions to access this resource"
 	app.errorResponse(w, r, http.StatusForbidden, message)
 }
+
+// unsupportedMediaTypeResponse returns a 415 Unsupported Media Type
+// response. This is called when an uploaded file is rejected by a
+// file policy.
+func (app *application) unsupportedMediaTypeResponse(
+	w http.ResponseWriter,
+	r *http.Request,
+	err error,
+) {
+	app.errorResponse(w, r, http.StatusUnsupportedMediaType, err.Error())
+}
+
+// quotaExceededResponse returns a 413 Request Entity Too Large
+// response. This is called when an upload would take a user or a
+// course over its storage quota.
+func (app *application) quotaExceededResponse(
+	w http.ResponseWriter,
+	r *http.Request,
+	err error,
+) {
+	app.errorResponse(w, r, http.StatusRequestEntityTooLarge, err.Error())
+}
+
+// quotaResponse sends a quota exceeded response if err is caused by a
+// storage quota, otherwise a server error.
+func (app *application) quotaResponse(
+	w http.ResponseWriter,
+	r *http.Request,
+	err error,
+) {
+	if errors.Is(err, domain.ERR_QUOTA_EXCEEDED) {
+		app.quotaExceededResponse(w, r, err)
+		return
+	}
+	app.serverError(w, r, err)
+}
+
+// quarantinedResponse returns a 403 Forbidden response. This is
+// called when a file has been found to contain malware, either when
+// it is uploaded for processing or when it is requested.
+func (app *application) quarantinedResponse(
+	w http.ResponseWriter,
+	r *http.Request,
+	scan *models.ScanResult,
+) {
+	message := fmt.Sprintf("this file has been quarantined: %s", scan.Threat)
+	app.errorResponse(w, r, http.StatusForbidden, message)
+}
+
+// discussionErrorResponse sends the response matching an error from
+// a discussion or a comment operation.
+func (app *application) discussionErrorResponse(
+	w http.ResponseWriter,
+	r *http.Request,
+	err error,
+) {
+	switch {
+	case errors.Is(err, dal.ERR_RECORD_NOT_FOUND):
+		app.notFoundResponse(w, r)
+	case errors.Is(err, domain.ERR_NOT_PERMITTED):
+		app.notPermittedResponse(w, r)
+	case errors.Is(err, domain.ERR_DISCUSSION_LOCKED):
+		app.errorResponse(w, r, http.StatusLocked, err.Error())
+	case errors.Is(err, domain.ERR_COMMENT_DELETED):
+		app.errorResponse(w, r, http.StatusConflict, err.Error())
+	default:
+		app.serverError(w, r, err)
+	}
+}
+
+// projectErrorResponse sends the response matching an error from a
+// project or team operation.
+func (app *application) projectErrorResponse(
+	w http.ResponseWriter,
+	r *http.Request,
+	err error,
+) {
+	switch {
+	case errors.Is(err, domain.ERR_TEAM_FULL),
+		errors.Is(err, domain.ERR_ALREADY_ON_TEAM),
+		errors.Is(err, domain.ERR_PROJECT_UNGRADED),
+		errors.Is(err, domain.ERR_NOT_AVAILABLE),
+		errors.Is(err, domain.ERR_SUBMISSION_CLOSED):
+		app.errorResponse(w, r, http.StatusConflict, err.Error())
+	case errors.Is(err, domain.ERR_NOT_ENROLLED):
+		app.errorResponse(w, r, http.StatusUnprocessableEntity, err.Error())
+	default:
+		app.discussionErrorResponse(w, r, err)
+	}
+}
+
+// quizErrorResponse sends the response matching an error from a quiz
+// or attempt operation.
+func (app *application) quizErrorResponse(
+	w http.ResponseWriter,
+	r *http.Request,
+	err error,
+) {
+	switch {
+	case errors.Is(err, domain.ERR_QUIZ_CLOSED),
+		errors.Is(err, domain.ERR_NO_ATTEMPTS_LEFT),
+		errors.Is(err, domain.ERR_ATTEMPT_EXPIRED),
+		errors.Is(err, domain.ERR_ATTEMPT_SUBMITTED),
+		errors.Is(err, domain.ERR_ATTEMPT_NOT_SUBMITTED):
+		app.errorResponse(w, r, http.StatusConflict, err.Error())
+	case errors.Is(err, domain.ERR_UNKNOWN_QUESTION),
+		errors.Is(err, domain.ERR_POINTS_OUT_OF_RANGE):
+		app.errorResponse(w, r, http.StatusUnprocessableEntity, err.Error())
+	default:
+		app.projectErrorResponse(w, r, err)
+	}
+}
+
+// rubricErrorResponse sends the response matching an error from a
+// rubric or rubric grading operation.
+func (app *application) rubricErrorResponse(
+	w http.ResponseWriter,
+	r *http.Request,
+	err error,
+) {
+	switch {
+	case errors.Is(err, domain.ERR_RUBRIC_IN_USE),
+		errors.Is(err, domain.ERR_NO_RUBRIC):
+		app.errorResponse(w, r, http.StatusConflict, err.Error())
+	case errors.Is(err, domain.ERR_UNKNOWN_CRITERION),
+		errors.Is(err, domain.ERR_UNKNOWN_LEVEL),
+		errors.Is(err, domain.ERR_RUBRIC_INCOMPLETE),
+		errors.Is(err, domain.ERR_SCORE_OUT_OF_RANGE):
+		app.errorResponse(w, r, http.StatusUnprocessableEntity, err.Error())
+	default:
+		app.discussionErrorResponse(w, r, err)
+	}
+}
+
+// peerReviewErrorResponse sends the response matching an error from
+// a peer review operation.
+func (app *application) peerReviewErrorResponse(
+	w http.ResponseWriter,
+	r *http.Request,
+	err error,
+) {
+	switch {
+	case errors.Is(err, domain.ERR_NOT_DUE),
+		errors.Is(err, domain.ERR_ALREADY_ALLOCATED),
+		errors.Is(err, domain.ERR_TOO_FEW_SUBMISSIONS),
+		errors.Is(err, domain.ERR_GRADES_RELEASED):
+		app.errorResponse(w, r, http.StatusConflict, err.Error())
+	case errors.Is(err, domain.ERR_NO_PEER_REVIEW),
+		errors.Is(err, domain.ERR_NO_SCORE):
+		app.errorResponse(w, r, http.StatusUnprocessableEntity, err.Error())
+	default:
+		app.rubricErrorResponse(w, r, err)
+	}
+}
+
+// autogradeErrorResponse sends the response matching an error from
+// an autograding operation.
+func (app *application) autogradeErrorResponse(
+	w http.ResponseWriter,
+	r *http.Request,
+	err error,
+) {
+	switch {
+	case errors.Is(err, domain.ERR_NO_HARNESS):
+		app.errorResponse(w, r, http.StatusNotFound, err.Error())
+	default:
+		app.discussionErrorResponse(w, r, err)
+	}
+}
+
+// annotationErrorResponse sends the response matching an error from
+// annotating a submitted file.
+func (app *application) annotationErrorResponse(
+	w http.ResponseWriter,
+	r *http.Request,
+	err error,
+) {
+	switch {
+	case errors.Is(err, domain.ERR_NOT_PDF),
+		errors.Is(err, domain.ERR_PDF_UNREADABLE),
+		errors.Is(err, domain.ERR_UNKNOWN_PAGE),
+		errors.Is(err, domain.ERR_UNKNOWN_MEDIA):
+		app.errorResponse(w, r, http.StatusUnprocessableEntity, err.Error())
+	default:
+		app.discussionErrorResponse(w, r, err)
+	}
+}
+
+// gradebookErrorResponse sends the response matching an error from a
+// gradebook operation.
+func (app *application) gradebookErrorResponse(
+	w http.ResponseWriter,
+	r *http.Request,
+	err error,
+) {
+	switch {
+	case errors.Is(err, domain.ERR_NOT_ENROLLED):
+		app.notPermittedResponse(w, r)
+	default:
+		app.discussionErrorResponse(w, r, err)
+	}
+}
+
+// submissionErrorResponse sends the response matching an error from
+// making a submission or changing how submissions are accepted, such
+// as late policies, extensions, accommodations, attempts, anonymous
+// grading and regrade requests.
+func (app *application) submissionErrorResponse(
+	w http.ResponseWriter,
+	r *http.Request,
+	err error,
+) {
+	switch {
+	case errors.Is(err, domain.ERR_NOT_AVAILABLE),
+		errors.Is(err, domain.ERR_SUBMISSION_CLOSED),
+		errors.Is(err, domain.ERR_NO_ATTEMPTS_LEFT),
+		errors.Is(err, domain.ERR_ANONYMITY_LOCKED),
+		errors.Is(err, domain.ERR_GRADES_RELEASED),
+		errors.Is(err, domain.ERR_NOT_GRADED),
+		errors.Is(err, domain.ERR_REGRADE_CLOSED),
+		errors.Is(err, domain.ERR_REGRADE_PENDING),
+		errors.Is(err, domain.ERR_REGRADE_RESOLVED):
+		app.errorResponse(w, r, http.StatusConflict, err.Error())
+	case errors.Is(err, domain.ERR_NOT_ENROLLED),
+		errors.Is(err, domain.ERR_UNKNOWN_ATTEMPT),
+		errors.Is(err, domain.ERR_UNKNOWN_TEACHER):
+		app.errorResponse(w, r, http.StatusUnprocessableEntity, err.Error())
+	default:
+		app.discussionErrorResponse(w, r, err)
+	}
+}
+
+// moduleErrorResponse sends the response matching an error from a
+// module or module item operation.
+func (app *application) moduleErrorResponse(
+	w http.ResponseWriter,
+	r *http.Request,
+	err error,
+) {
+	switch {
+	case errors.Is(err, domain.ERR_MODULE_LOCKED):
+		app.errorResponse(w, r, http.StatusLocked, err.Error())
+	case errors.Is(err, domain.ERR_KIND_CHANGED),
+		errors.Is(err, domain.ERR_NOT_MARKABLE):
+		app.errorResponse(w, r, http.StatusConflict, err.Error())
+	case errors.Is(err, domain.ERR_INVALID_PREREQUISITE),
+		errors.Is(err, domain.ERR_UNKNOWN_REFERENCE):
+		app.errorResponse(w, r, http.StatusUnprocessableEntity, err.Error())
+	default:
+		app.discussionErrorResponse(w, r, err)
+	}
+}
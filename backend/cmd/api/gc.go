@@ -0,0 +1,45 @@
+package main
+
+import (
+	"time"
+)
+
+// collectGarbage removes orphaned media and files from storage every
+// interval. It is meant to be run in its own goroutine, similar to the
+// client cleanup in rateLimit.
+func (app *application) collectGarbage() {
+	for {
+		time.Sleep(app.config.gc.interval)
+
+		app.runGarbageCollection()
+	}
+}
+
+// runGarbageCollection runs one storage garbage collection and logs
+// what it found.
+func (app *application) runGarbageCollection() {
+	report, err := app.services.StorageService.CollectGarbage(
+		app.config.gc.grace,
+		app.config.gc.dryRun,
+	)
+	if err != nil {
+		app.logger.Printf("Garbage collection failed: %v", err)
+		return
+	}
+
+	app.logger.Printf(
+		"Garbage collection (dry run: %t), orphaned media: %d, removed files: %d, freed bytes: %d",
+		report.DryRun,
+		len(report.OrphanedMedia),
+		len(report.RemovedBlobs),
+		report.FreedBytes,
+	)
+
+	for _, media := range report.DanglingMedia {
+		app.logger.Printf(
+			"Garbage collection, media %s is missing its file: %s",
+			media.ID,
+			media.FilePath,
+		)
+	}
+}
@@ -1,15 +1,17 @@
 package main
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"mime"
 	"net/http"
-
-	"path/filepath"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/n30w/Darkspace/internal/dal"
+	"github.com/n30w/Darkspace/internal/domain"
 	"github.com/n30w/Darkspace/internal/models"
 )
 
@@ -234,6 +236,88 @@ func (app *application) courseDeleteHandler(
 	}
 }
 
+// courseStorageHandler reports how much storage a course uses.
+//
+// REQUEST: course ID
+// RESPONSE: storage usage
+func (app *application) courseStorageHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	courseId := r.PathValue("id")
+
+	usage, err := app.services.StorageService.CourseUsage(courseId)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	res := jsonWrap{"usage": usage}
+
+	err = app.writeJSON(w, http.StatusOK, res, nil)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+}
+
+// userQuotaHandler reports the storage used by the requesting user
+// and each of their courses, against their quotas.
+//
+// REQUEST: token
+// RESPONSE: user and course quota usage
+func (app *application) userQuotaHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	var input struct {
+		Token string `json:"token"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	netId, err := app.services.AuthenticationService.GetNetIdFromToken(input.Token)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	user, err := app.services.UserService.GetByID(netId)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	userUsage, err := app.services.MediaService.UserQuota(netId)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	var courseUsage []*models.QuotaUsage
+
+	for _, courseId := range user.Courses {
+		usage, err := app.services.MediaService.CourseQuota(courseId)
+		if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+		courseUsage = append(courseUsage, usage)
+	}
+
+	res := jsonWrap{"quota": jsonWrap{"user": userUsage, "courses": courseUsage}}
+
+	err = app.writeJSON(w, http.StatusOK, res, nil)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+}
+
 // REQUEST: courseid + image file
 // RESPONSE: status
 func (app *application) bannerCreateHandler(
@@ -258,34 +342,38 @@ func (app *application) bannerCreateHandler(
 
 	defer f.Close()
 
-	ft := GetFileType(handler.Filename)
-
-	if ft == models.NULL {
-		app.serverError(w, r, fmt.Errorf("invalid file type"))
+	ft, err := domain.ImagePolicy.Inspect(handler.Filename, f, handler.Size)
+	if err != nil {
+		app.unsupportedMediaTypeResponse(w, r, err)
 		return
 	}
 
-	fileName := courseid + "_banner." + ft.String()
-
-	// Save the file to disk
-	path, err := app.services.FileService.Save(fileName, f)
+	img, err := domain.BannerPipeline.Process(f, ft)
 	if err != nil {
-		app.serverError(w, r, err)
+		app.failedValidationResponse(w, r, map[string]string{"file": err.Error()})
 		return
 	}
-	app.logger.Printf("Banner create handler, saved banner: %s to disk...", fileName)
 
 	// Create metadata and add to database
 	metadata := &models.Media{
 		FileName:           handler.Filename,
 		AttributionsByType: make(map[string]string),
-		FileType:           ft,
-		FilePath:           path,
 	}
 
 	metadata.AttributionsByType["course"] = courseid
 
-	_, err = app.services.MediaService.AddBanner(metadata)
+	err = app.services.MediaService.CheckQuota(metadata, img.Size())
+	if err != nil {
+		app.quotaResponse(w, r, err)
+		return
+	}
+
+	metadata, err = app.saveImage(
+		courseid+"_banner",
+		img,
+		metadata,
+		app.services.MediaService.AddBanner,
+	)
 	if err != nil {
 		app.serverError(w, r, err)
 		return
@@ -299,45 +387,136 @@ func (app *application) bannerCreateHandler(
 	}
 }
 
-// REQUEST: banner id
+// REQUEST: banner id, optional size query (thumbnail, card, full)
 // RESPONSE: banner image
 func (app *application) bannerReadHandler(
 	w http.ResponseWriter,
 	r *http.Request,
 ) {
 	bannerId := r.PathValue("mediaId")
+	size := models.ParseImageSize(r.URL.Query().Get("size"))
 
-	app.logger.Printf("Banner read handler, received Banner ID: %s...", bannerId)
+	app.logger.Printf("Banner read handler, received Banner ID: %s, size: %s...", bannerId, size)
 
-	banner, err := app.services.MediaService.GetMedia(bannerId)
+	banner, err := app.services.MediaService.GetImage(bannerId, size)
 	if err != nil {
 		app.serverError(w, r, err)
+		return
 	}
 
 	app.logger.Printf("Banner read handler, retrieved metadata: %v...", banner)
 
-	// Set Content-Type header based on file extension
-	contentType := mime.TypeByExtension("." + banner.FileType.String())
-	if contentType == "" {
-		contentType = "application/octet-stream" // Default content type
+	app.serveImage(w, r, banner, cacheImmutable)
+}
+
+// profilePictureCreateHandler sets the profile picture of the user
+// making the request.
+//
+// REQUEST: token + image file
+// RESPONSE: profile picture metadata
+func (app *application) profilePictureCreateHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	token := r.Header.Get("Authorization")
+
+	netId, err := app.services.AuthenticationService.GetNetIdFromToken(token)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	app.logger.Printf("Profile picture create handler, user: %s...", netId)
+
+	// Limit upload size to 10MB
+	err = r.ParseMultipartForm(10 << 20)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	f, handler, err := r.FormFile("file")
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	defer f.Close()
+
+	ft, err := domain.ImagePolicy.Inspect(handler.Filename, f, handler.Size)
+	if err != nil {
+		app.unsupportedMediaTypeResponse(w, r, err)
+		return
+	}
+
+	img, err := domain.ProfilePicturePipeline.Process(f, ft)
+	if err != nil {
+		app.failedValidationResponse(w, r, map[string]string{"file": err.Error()})
+		return
+	}
+
+	metadata := &models.Media{
+		FileName:           handler.Filename,
+		AttributionsByType: make(map[string]string),
+	}
+
+	metadata.AttributionsByType["user"] = netId
+
+	err = app.services.MediaService.CheckQuota(metadata, img.Size())
+	if err != nil {
+		app.quotaResponse(w, r, err)
+		return
 	}
 
-	app.logger.Printf("Banner read handler, setting content type to: %s...", contentType)
+	metadata, err = app.saveImage(
+		netId+"_profile",
+		img,
+		metadata,
+		func(m *models.Media) (*models.Media, error) {
+			return app.services.MediaService.AddProfilePicture(m, netId)
+		},
+	)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
 
-	contentDispositionValue := "inline"
+	res := jsonWrap{"profile_picture": metadata}
 
-	filePath := banner.FilePath
-	if filePath == "" {
-		banner.FilePath = app.services.FileService.Path() + "/defaults/" + banner.FileName + "." + banner.FileType.String()
+	err = app.writeJSON(w, http.StatusOK, res, nil)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
 	}
+}
+
+// profilePictureReadHandler sends back a user's profile picture.
+//
+// REQUEST: user netid, optional size query (thumbnail, card, full)
+// RESPONSE: profile picture image
+func (app *application) profilePictureReadHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	netId := r.PathValue("id")
+	size := models.ParseImageSize(r.URL.Query().Get("size"))
 
-	w.Header().Set("Content-Type", contentType)
-	w.Header().Set("Content-Disposition", contentDispositionValue)
+	user, err := app.services.UserService.GetByID(netId)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
 
-	app.logger.Printf("Banner read handler, serving banner with file path: %s...", banner.FilePath)
+	picture, err := app.services.MediaService.GetImage(
+		user.ProfilePicture.ID,
+		size,
+	)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
 
-	// Serve the file's content
-	http.ServeFile(w, r, banner.FilePath)
+	app.serveImage(w, r, picture, cacheRevalidate)
 }
 
 // REQUEST: course ID, teacher ID, announcement description
@@ -420,6 +599,13 @@ func (app *application) announcementReadHandler(
 			app.serverError(w, r, err)
 			return
 		}
+
+		// Discussions share the course's messages, but are listed
+		// by discussionListHandler.
+		if !msg.Type {
+			continue
+		}
+
 		msgs = append(msgs, *msg)
 	}
 
@@ -486,745 +672,6148 @@ func (app *application) announcementDeleteHandler(
 	}
 }
 
-// User handlers, deals with anything user side.
+// Discussion handlers. Discussions are messages whose Type is false,
+// linked to their course through course_messages.
 
-// userCreateHandler creates a user.
+// discussionCreateHandler starts a discussion in a course.
 //
-// REQUEST: email, password, full name, netid, membership
-// RESPONSE: status
-func (app *application) userCreateHandler(
+// REQUEST: course ID, token, title, description
+// RESPONSE: discussion
+func (app *application) discussionCreateHandler(
 	w http.ResponseWriter,
 	r *http.Request,
 ) {
+	courseId := r.PathValue("id")
+
 	var input struct {
-		FullName   string `json:"fullname"`
-		Password   string `json:"password"`
-		Email      string `json:"email"`
-		Netid      string `json:"netid"`
-		Membership int    `json:"membership"`
+		Token       string `json:"token"`
+		Title       string `json:"title"`
+		Description string `json:"description"`
 	}
 
 	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	netId, err := app.services.AuthenticationService.GetNetIdFromToken(input.Token)
 	if err != nil {
 		app.serverError(w, r, err)
 		return
 	}
 
-	// Map the input fields to the appropriate credentials fields.
-	c := models.Credentials{
-		Username:   app.services.UserService.NewUsername(input.Netid),
-		Password:   app.services.UserService.NewPassword(input.Password),
-		Email:      app.services.UserService.NewEmail(input.Email),
-		Membership: app.services.UserService.NewMembership(input.Membership),
+	if input.Title == "" {
+		app.failedValidationResponse(w, r, map[string]string{"title": "must be provided"})
+		return
 	}
 
-	user, err := models.NewUser(input.Netid, c, input.FullName)
+	msg, err := app.services.MessageService.CreateDiscussion(
+		input.Title,
+		input.Description,
+		netId,
+		courseId,
+	)
 	if err != nil {
 		app.serverError(w, r, err)
 		return
 	}
 
-	err = app.services.UserService.CreateUser(user)
+	res := jsonWrap{"discussion": msg}
+
+	err = app.writeJSON(w, http.StatusCreated, res, nil)
 	if err != nil {
 		app.serverError(w, r, err)
 		return
 	}
 }
 
-// userReadHandler reads a specific user's data,
-// which is specified by the requester.
+// discussionListHandler lists a page of a course's discussions,
+// pinned discussions first. The token is optional and only used to
+// show hidden discussions to teachers.
 //
-// REQUEST: user netid
-// RESPONSE: user
-func (app *application) userReadHandler(
+// REQUEST: course ID, optional token, page, page_size
+// RESPONSE: discussions, metadata
+func (app *application) discussionListHandler(
 	w http.ResponseWriter,
 	r *http.Request,
 ) {
-	id := r.PathValue("id")
+	courseId := r.PathValue("id")
 
-	var err error
-	var user *models.User
+	f, errs := readFilters(r)
+	if len(errs) > 0 {
+		app.failedValidationResponse(w, r, errs)
+		return
+	}
 
-	// Perform a database lookup of user.
-	user, err = app.services.UserService.GetByID(id)
-	user, err = app.services.UserService.GetByID(id)
+	netId, err := app.optionalNetId(r)
 	if err != nil {
 		app.serverError(w, r, err)
 		return
 	}
 
-	res := jsonWrap{"user": user}
-
-	err = app.writeJSON(w, http.StatusOK, res, nil)
+	discussions, metadata, err := app.services.MessageService.ListDiscussions(
+		courseId,
+		netId,
+		f,
+	)
 	if err != nil {
 		app.serverError(w, r, err)
 		return
 	}
 
-}
+	res := jsonWrap{"discussions": discussions, "metadata": metadata}
 
-// userUpdateHandler updates a user's data.
-//
-// REQUEST: user UUID + data to update
-// RESPONSE: new user data
-func (app *application) userUpdateHandler(
-	w http.ResponseWriter,
-	r *http.Request,
-) {
-	id := r.PathValue("id")
-	fmt.Printf("id: %s", id)
+	err = app.writeJSON(w, http.StatusOK, res, nil)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
 }
 
-// userDeleteHandler deletes a user. A request must come from
-// a user themselves to delete themselves.
+// discussionReadHandler sends back a discussion and a page of its
+// comments, with replies nested beneath them.
 //
-// REQUEST: user uuid
-// RESPONSE: logout page
-func (app *application) userDeleteHandler(
+// REQUEST: course ID, discussion ID, optional token, page, page_size
+// RESPONSE: discussion, comments, metadata
+func (app *application) discussionReadHandler(
 	w http.ResponseWriter,
 	r *http.Request,
 ) {
+	courseId := r.PathValue("id")
+	discussionId := r.PathValue("discussionId")
 
-}
-
-// userLoginHandler handles login requests from any user. It requires
-// a username and a password. A login must occur from a genuine domain. This
-// means that the request comes from the frontend server rather than the
-// user's browser. Written to the http response is an authorized
-// login token.
-//
-// REQUEST: username/email, password
-// RESPONSE: auth cookie/login session
-func (app *application) userLoginHandler(
-	w http.ResponseWriter,
-	r *http.Request,
-) {
-	var noToken bool
-	var input struct {
-		NetId    string `json:"netid"`
-		Password string `json:"password"`
+	f, errs := readFilters(r)
+	if len(errs) > 0 {
+		app.failedValidationResponse(w, r, errs)
+		return
 	}
 
-	err := app.readJSON(w, r, &input)
+	netId, err := app.optionalNetId(r)
 	if err != nil {
 		app.serverError(w, r, err)
 		return
 	}
 
-	// 	Validate credentials.
-	err = app.services.UserService.ValidateUser(input.NetId, input.Password)
+	msg, comments, metadata, err := app.services.MessageService.ReadDiscussion(
+		courseId,
+		discussionId,
+		netId,
+		f,
+	)
 	if err != nil {
-		app.serverError(w, r, err)
+		app.discussionErrorResponse(w, r, err)
 		return
 	}
 
-	app.logger.Printf("user validated")
+	res := jsonWrap{
+		"discussion": msg,
+		"comments":   comments,
+		"metadata":   metadata,
+	}
 
-	// If token exists, return token:
-	token, err := app.services.AuthenticationService.RetrieveToken(input.NetId)
+	err = app.writeJSON(w, http.StatusOK, res, nil)
 	if err != nil {
-		switch {
-		case errors.Is(err, dal.ERR_RECORD_NOT_FOUND):
-			app.logger.Printf("user token not found for %s", input.NetId)
-			noToken = true
-		default:
-			app.serverError(w, r, err)
-			return
-		}
+		app.serverError(w, r, err)
+		return
 	}
+}
 
-	if noToken {
-		app.logger.Printf("generating user token...")
-		token, err = app.services.AuthenticationService.NewToken(input.NetId)
+// discussionUpdateHandler lets the author of a discussion change its
+// title and description.
+//
+// REQUEST: course ID, discussion ID, token, title, description
+// RESPONSE: discussion
+func (app *application) discussionUpdateHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	courseId := r.PathValue("id")
+	discussionId := r.PathValue("discussionId")
+
+	var input struct {
+		Token       string `json:"token"`
+		Title       string `json:"title"`
+		Description string `json:"description"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
 	}
 
+	netId, err := app.services.AuthenticationService.GetNetIdFromToken(input.Token)
 	if err != nil {
 		app.serverError(w, r, err)
 		return
 	}
 
-	app.logger.Printf("Token: %v", token)
+	msg, err := app.services.MessageService.UpdateDiscussion(
+		courseId,
+		discussionId,
+		netId,
+		input.Title,
+		input.Description,
+	)
+	if err != nil {
+		app.discussionErrorResponse(w, r, err)
+		return
+	}
 
-	membership, err := app.services.UserService.GetMembership(input.NetId)
+	res := jsonWrap{"discussion": msg}
+
+	err = app.writeJSON(w, http.StatusOK, res, nil)
 	if err != nil {
 		app.serverError(w, r, err)
 		return
 	}
+}
 
-	wrapped := jsonWrap{"authentication_token": token, "permissions": membership}
+// discussionDeleteHandler deletes a discussion and its comments. The
+// author and the course's teachers may delete a discussion.
+//
+// REQUEST: course ID, discussion ID, token in the Authorization header
+// RESPONSE: status
+func (app *application) discussionDeleteHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	courseId := r.PathValue("id")
+	discussionId := r.PathValue("discussionId")
 
-	err = app.writeJSON(
-		w, http.StatusCreated,
-		wrapped, nil,
+	netId, err := app.services.AuthenticationService.GetNetIdFromToken(
+		r.Header.Get("Authorization"),
+	)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	err = app.services.MessageService.DeleteDiscussion(
+		courseId,
+		discussionId,
+		netId,
 	)
+	if err != nil {
+		app.discussionErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, nil, nil)
 	if err != nil {
 		app.serverError(w, r, err)
 		return
 	}
 }
 
-// Assignment handlers. Only teachers should be able to request the use of
-// these handlers. Therefore, teacher permission/authorization is
-// a necessity.
+// discussionModerateHandler lets a teacher lock, pin or hide a
+// discussion. Flags left out of the request are unchanged.
+//
+// REQUEST: course ID, discussion ID, token, locked, pinned, hidden
+// RESPONSE: discussion
+func (app *application) discussionModerateHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	courseId := r.PathValue("id")
+	discussionId := r.PathValue("discussionId")
 
-// assignmentCreateHandler creates an assignment based on the request values.
-// To create an assignment, a request must contain an assignment: title,
-// author, body, and media. The return value is the assignment data along
-// with a uuid.
+	var input struct {
+		Token string `json:"token"`
+		domain.Moderation
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	netId, err := app.services.AuthenticationService.GetNetIdFromToken(input.Token)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	msg, err := app.services.MessageService.ModerateDiscussion(
+		courseId,
+		discussionId,
+		netId,
+		input.Moderation,
+	)
+	if err != nil {
+		app.discussionErrorResponse(w, r, err)
+		return
+	}
+
+	res := jsonWrap{"discussion": msg}
+
+	err = app.writeJSON(w, http.StatusOK, res, nil)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+}
+
+// commentCreateHandler comments on a discussion, or replies to a
+// comment when parent_id is given.
 //
-// REQUEST: title, author, body, media
-// RESPONSE: assignment
-func (app *application) assignmentCreateHandler(
+// REQUEST: course ID, discussion ID, token, body, optional parent_id
+// RESPONSE: comment
+func (app *application) commentCreateHandler(
 	w http.ResponseWriter,
 	r *http.Request,
 ) {
-	app.logger.Printf("Creating assignment...")
+	courseId := r.PathValue("id")
+	discussionId := r.PathValue("discussionId")
+
 	var input struct {
-		Title       string   `json:"title"`
-		Token       string   `json:"token"`
-		Description string   `json:"description"`
-		Media       []string `json:"media"`
-		DueDate     string   `json:"duedate"`
-		CourseId    string   `json:"courseid"`
+		Token    string `json:"token"`
+		Body     string `json:"body"`
+		ParentId string `json:"parent_id"`
 	}
+
 	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	netId, err := app.services.AuthenticationService.GetNetIdFromToken(input.Token)
 	if err != nil {
 		app.serverError(w, r, err)
 		return
 	}
-	netid, err := app.services.AuthenticationService.GetNetIdFromToken(input.Token)
+
+	if input.Body == "" {
+		app.failedValidationResponse(w, r, map[string]string{"body": "must be provided"})
+		return
+	}
+
+	comment, err := app.services.MessageService.AddComment(
+		courseId,
+		discussionId,
+		input.ParentId,
+		netId,
+		input.Body,
+	)
+	if err != nil {
+		app.discussionErrorResponse(w, r, err)
+		return
+	}
+
+	res := jsonWrap{"comment": comment}
+
+	err = app.writeJSON(w, http.StatusCreated, res, nil)
 	if err != nil {
 		app.serverError(w, r, err)
 		return
 	}
+}
 
-	post := models.Post{
-		Title:       input.Title,
-		Description: input.Description,
-		Owner:       netid,
-		Media:       input.Media,
-		Course:      input.CourseId,
+// commentUpdateHandler lets the author of a comment change its text.
+//
+// REQUEST: course ID, discussion ID, comment ID, token, body
+// RESPONSE: comment
+func (app *application) commentUpdateHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	courseId := r.PathValue("id")
+	discussionId := r.PathValue("discussionId")
+	commentId := r.PathValue("commentId")
+
+	var input struct {
+		Token string `json:"token"`
+		Body  string `json:"body"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	netId, err := app.services.AuthenticationService.GetNetIdFromToken(input.Token)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	if input.Body == "" {
+		app.failedValidationResponse(w, r, map[string]string{"body": "must be provided"})
+		return
+	}
+
+	comment, err := app.services.MessageService.EditComment(
+		courseId,
+		discussionId,
+		commentId,
+		netId,
+		input.Body,
+	)
+	if err != nil {
+		app.discussionErrorResponse(w, r, err)
+		return
+	}
+
+	res := jsonWrap{"comment": comment}
+
+	err = app.writeJSON(w, http.StatusOK, res, nil)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+}
+
+// commentDeleteHandler deletes a comment. The author and the
+// course's teachers may delete a comment.
+//
+// REQUEST: course ID, discussion ID, comment ID, token in the
+// Authorization header
+// RESPONSE: status
+func (app *application) commentDeleteHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	courseId := r.PathValue("id")
+	discussionId := r.PathValue("discussionId")
+	commentId := r.PathValue("commentId")
+
+	netId, err := app.services.AuthenticationService.GetNetIdFromToken(
+		r.Header.Get("Authorization"),
+	)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	err = app.services.MessageService.DeleteComment(
+		courseId,
+		discussionId,
+		commentId,
+		netId,
+	)
+	if err != nil {
+		app.discussionErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, nil, nil)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+}
+
+// commentModerateHandler lets a teacher hide or unhide a comment.
+//
+// REQUEST: course ID, discussion ID, comment ID, token, hidden
+// RESPONSE: comment
+func (app *application) commentModerateHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	courseId := r.PathValue("id")
+	discussionId := r.PathValue("discussionId")
+	commentId := r.PathValue("commentId")
+
+	var input struct {
+		Token string `json:"token"`
+		domain.Moderation
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	netId, err := app.services.AuthenticationService.GetNetIdFromToken(input.Token)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	comment, err := app.services.MessageService.ModerateComment(
+		courseId,
+		discussionId,
+		commentId,
+		netId,
+		input.Moderation,
+	)
+	if err != nil {
+		app.discussionErrorResponse(w, r, err)
+		return
+	}
+
+	res := jsonWrap{"comment": comment}
+
+	err = app.writeJSON(w, http.StatusOK, res, nil)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+}
+
+// Project handlers, for group projects and their teams.
+
+// projectCreateHandler lets a teacher create a project in a course.
+//
+// REQUEST: course ID, token, name, description, team size,
+// self enroll, optional assignment ID
+// RESPONSE: project
+func (app *application) projectCreateHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	courseId := r.PathValue("id")
+
+	var input struct {
+		Token        string `json:"token"`
+		Name         string `json:"name"`
+		Description  string `json:"description"`
+		TeamSize     int    `json:"team_size"`
+		SelfEnroll   bool   `json:"self_enroll"`
+		AssignmentId string `json:"assignment_id"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	netId, err := app.services.AuthenticationService.GetNetIdFromToken(input.Token)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	errs := make(map[string]string)
+	if input.Name == "" {
+		errs["name"] = "must be provided"
+	}
+	if input.TeamSize < 1 {
+		errs["team_size"] = "must be at least 1"
+	}
+	if len(errs) > 0 {
+		app.failedValidationResponse(w, r, errs)
+		return
+	}
+
+	p := models.NewProject(input.Name, input.Description, courseId, netId)
+	p.TeamSize = input.TeamSize
+	p.SelfEnroll = input.SelfEnroll
+	p.AssignmentId = input.AssignmentId
+
+	p, err = app.services.ProjectService.CreateProject(p)
+	if err != nil {
+		app.projectErrorResponse(w, r, err)
+		return
+	}
+
+	res := jsonWrap{"project": p}
+
+	err = app.writeJSON(w, http.StatusCreated, res, nil)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+}
+
+// projectListHandler lists a course's projects and their teams.
+//
+// REQUEST: course ID
+// RESPONSE: projects
+func (app *application) projectListHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	courseId := r.PathValue("id")
+
+	projects, err := app.services.ProjectService.ListProjects(courseId)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	res := jsonWrap{"projects": projects}
+
+	err = app.writeJSON(w, http.StatusOK, res, nil)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+}
+
+// projectReadHandler sends back a project and its teams.
+//
+// REQUEST: project ID
+// RESPONSE: project
+func (app *application) projectReadHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	projectId := r.PathValue("projectId")
+
+	p, err := app.services.ProjectService.ReadProject(projectId)
+	if err != nil {
+		app.projectErrorResponse(w, r, err)
+		return
+	}
+
+	res := jsonWrap{"project": p}
+
+	err = app.writeJSON(w, http.StatusOK, res, nil)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+}
+
+// projectDeleteHandler lets a teacher delete a project and its teams.
+//
+// REQUEST: project ID, token
+// RESPONSE: status
+func (app *application) projectDeleteHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	projectId := r.PathValue("projectId")
+
+	netId, err := app.services.AuthenticationService.GetNetIdFromToken(
+		r.Header.Get("Authorization"),
+	)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	err = app.services.ProjectService.DeleteProject(projectId, netId)
+	if err != nil {
+		app.projectErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, nil, nil)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+}
+
+// teamCreateHandler lets a teacher add a team to a project.
+//
+// REQUEST: project ID, token, name
+// RESPONSE: team
+func (app *application) teamCreateHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	projectId := r.PathValue("projectId")
+
+	var input struct {
+		Token string `json:"token"`
+		Name  string `json:"name"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	netId, err := app.services.AuthenticationService.GetNetIdFromToken(input.Token)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	if input.Name == "" {
+		app.failedValidationResponse(w, r, map[string]string{"name": "must be provided"})
+		return
+	}
+
+	team, err := app.services.ProjectService.CreateTeam(
+		projectId,
+		input.Name,
+		netId,
+	)
+	if err != nil {
+		app.projectErrorResponse(w, r, err)
+		return
+	}
+
+	res := jsonWrap{"team": team}
+
+	err = app.writeJSON(w, http.StatusCreated, res, nil)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+}
+
+// teamMemberAddHandler lets a teacher assign a student to a team.
+//
+// REQUEST: team ID, token, netid
+// RESPONSE: team
+func (app *application) teamMemberAddHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	teamId := r.PathValue("teamId")
+
+	var input struct {
+		Token string `json:"token"`
+		NetId string `json:"netid"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	netId, err := app.services.AuthenticationService.GetNetIdFromToken(input.Token)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	if input.NetId == "" {
+		app.failedValidationResponse(w, r, map[string]string{"netid": "must be provided"})
+		return
+	}
+
+	team, err := app.services.ProjectService.AssignMember(
+		teamId,
+		input.NetId,
+		netId,
+	)
+	if err != nil {
+		app.projectErrorResponse(w, r, err)
+		return
+	}
+
+	res := jsonWrap{"team": team}
+
+	err = app.writeJSON(w, http.StatusOK, res, nil)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+}
+
+// teamJoinHandler lets a student join a team of a project that
+// allows self enrollment.
+//
+// REQUEST: team ID, token
+// RESPONSE: team
+func (app *application) teamJoinHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	teamId := r.PathValue("teamId")
+
+	var input struct {
+		Token string `json:"token"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	netId, err := app.services.AuthenticationService.GetNetIdFromToken(input.Token)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	team, err := app.services.ProjectService.Join(teamId, netId)
+	if err != nil {
+		app.projectErrorResponse(w, r, err)
+		return
+	}
+
+	res := jsonWrap{"team": team}
+
+	err = app.writeJSON(w, http.StatusOK, res, nil)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+}
+
+// teamMemberDeleteHandler takes a student off a team. Teachers may
+// remove anyone, students may only remove themselves.
+//
+// REQUEST: team ID, net ID, token
+// RESPONSE: status
+func (app *application) teamMemberDeleteHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	teamId := r.PathValue("teamId")
+	member := r.PathValue("netId")
+
+	netId, err := app.services.AuthenticationService.GetNetIdFromToken(
+		r.Header.Get("Authorization"),
+	)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	err = app.services.ProjectService.RemoveMember(teamId, member, netId)
+	if err != nil {
+		app.projectErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, nil, nil)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+}
+
+// teamDiscussionReadHandler sends back a page of the comments on a
+// team's private discussion.
+//
+// REQUEST: team ID, token, page, page_size
+// RESPONSE: comments, metadata
+func (app *application) teamDiscussionReadHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	teamId := r.PathValue("teamId")
+
+	f, errs := readFilters(r)
+	if len(errs) > 0 {
+		app.failedValidationResponse(w, r, errs)
+		return
+	}
+
+	netId, err := app.services.AuthenticationService.GetNetIdFromToken(
+		r.Header.Get("Authorization"),
+	)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	comments, metadata, err := app.services.ProjectService.ReadTeamDiscussion(
+		teamId,
+		netId,
+		f,
+	)
+	if err != nil {
+		app.projectErrorResponse(w, r, err)
+		return
+	}
+
+	res := jsonWrap{"comments": comments, "metadata": metadata}
+
+	err = app.writeJSON(w, http.StatusOK, res, nil)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+}
+
+// teamCommentCreateHandler comments on a team's discussion, or
+// replies to a comment when a parent ID is given.
+//
+// REQUEST: team ID, token, body, optional parent ID
+// RESPONSE: comment
+func (app *application) teamCommentCreateHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	teamId := r.PathValue("teamId")
+
+	var input struct {
+		Token    string `json:"token"`
+		Body     string `json:"body"`
+		ParentId string `json:"parent_id"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	netId, err := app.services.AuthenticationService.GetNetIdFromToken(input.Token)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	if input.Body == "" {
+		app.failedValidationResponse(w, r, map[string]string{"body": "must be provided"})
+		return
+	}
+
+	comment, err := app.services.ProjectService.AddTeamComment(
+		teamId,
+		input.ParentId,
+		netId,
+		input.Body,
+	)
+	if err != nil {
+		app.projectErrorResponse(w, r, err)
+		return
+	}
+
+	res := jsonWrap{"comment": comment}
+
+	err = app.writeJSON(w, http.StatusCreated, res, nil)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+}
+
+// teamMediaUploadHandler shares files with a team. Files are charged
+// to the course's storage quota.
+//
+// REQUEST: team ID, token, files
+// RESPONSE: media
+func (app *application) teamMediaUploadHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	teamId := r.PathValue("teamId")
+
+	netId, err := app.services.AuthenticationService.GetNetIdFromToken(
+		r.Header.Get("Authorization"),
+	)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	_, err = app.services.ProjectService.AccessTeam(teamId, netId)
+	if err != nil {
+		app.projectErrorResponse(w, r, err)
+		return
+	}
+
+	err = r.ParseMultipartForm(10 << 20) // 10 MB maximum form size
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	files := r.MultipartForm.File["files"]
+
+	// Teams may share any known type of file, as long as its content
+	// matches its name.
+	policy := domain.NewFilePolicy()
+
+	fileTypes := make([]models.FileType, len(files))
+	for i, fileHeader := range files {
+		file, err := fileHeader.Open()
+		if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+
+		fileTypes[i], err = policy.Inspect(fileHeader.Filename, file, fileHeader.Size)
+		file.Close()
+		if err != nil {
+			app.unsupportedMediaTypeResponse(w, r, err)
+			return
+		}
+	}
+
+	err = app.services.MediaService.CheckQuota(
+		&models.Media{
+			AttributionsByType: map[string]string{"team": teamId},
+		},
+		uploadSize(files),
+	)
+	if err != nil {
+		app.quotaResponse(w, r, err)
+		return
+	}
+
+	var uploaded []*models.Media
+
+	for i, fileHeader := range files {
+		file, err := fileHeader.Open()
+		if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+
+		path, scan, err := app.services.FileService.Save(fileHeader.Filename, file)
+		file.Close()
+		if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+
+		if scan.Quarantined() {
+			app.logger.Printf("Quarantined upload %s: %s", path, scan.Threat)
+		}
+
+		media := &models.Media{
+			FileName:           fileHeader.Filename,
+			AttributionsByType: map[string]string{"team": teamId},
+			FileType:           fileTypes[i],
+			FilePath:           path,
+			Size:               fileHeader.Size,
+			ScanResult:         *scan,
+		}
+
+		media, err = app.services.MediaService.AddTeamMedia(media)
+		if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+
+		uploaded = append(uploaded, media)
+	}
+
+	res := jsonWrap{"media": uploaded}
+
+	err = app.writeJSON(w, http.StatusCreated, res, nil)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+}
+
+// teamMediaReadHandler lists the files shared with a team. Files are
+// downloaded like any other course media.
+//
+// REQUEST: team ID, token
+// RESPONSE: media
+func (app *application) teamMediaReadHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	teamId := r.PathValue("teamId")
+
+	netId, err := app.services.AuthenticationService.GetNetIdFromToken(
+		r.Header.Get("Authorization"),
+	)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	media, err := app.services.ProjectService.TeamMedia(teamId, netId)
+	if err != nil {
+		app.projectErrorResponse(w, r, err)
+		return
+	}
+
+	res := jsonWrap{"media": media}
+
+	err = app.writeJSON(w, http.StatusOK, res, nil)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+}
+
+// teamSubmissionCreateHandler creates a team's submission for its
+// project's assignment. Files are then uploaded to the submission
+// like any other.
+//
+// REQUEST: team ID, token
+// RESPONSE: submission
+func (app *application) teamSubmissionCreateHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	teamId := r.PathValue("teamId")
+
+	var input struct {
+		Token string `json:"token"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	netId, err := app.services.AuthenticationService.GetNetIdFromToken(input.Token)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	submission, err := app.services.ProjectService.Submit(teamId, netId)
+	if err != nil {
+		app.projectErrorResponse(w, r, err)
+		return
+	}
+
+	res := jsonWrap{"submission": submission}
+
+	err = app.writeJSON(w, http.StatusOK, res, nil)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+}
+
+// teamGradeHandler lets a teacher grade a team once, applying the
+// grade and feedback to every member.
+//
+// REQUEST: team ID, token, grade, feedback
+// RESPONSE: submissions
+func (app *application) teamGradeHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	teamId := r.PathValue("teamId")
+
+	var input struct {
+		Token    string  `json:"token"`
+		Grade    float64 `json:"grade"`
+		Feedback string  `json:"feedback"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	netId, err := app.services.AuthenticationService.GetNetIdFromToken(input.Token)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	if input.Grade < 0 {
+		app.failedValidationResponse(w, r, map[string]string{"grade": "must not be negative"})
+		return
+	}
+
+	submissions, err := app.services.ProjectService.GradeTeam(
+		teamId,
+		netId,
+		input.Grade,
+		input.Feedback,
+	)
+	if err != nil {
+		app.projectErrorResponse(w, r, err)
+		return
+	}
+
+	res := jsonWrap{"submissions": submissions}
+
+	err = app.writeJSON(w, http.StatusOK, res, nil)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+}
+
+// Quiz handlers, for quizzes and students' attempts at them.
+
+// quizCreateHandler lets a teacher create a quiz in a course, along
+// with its questions.
+//
+// REQUEST: course ID, token, title, description, time limit in
+// seconds, opens at, closes at, max attempts, shuffle questions,
+// shuffle choices, questions
+// RESPONSE: quiz
+func (app *application) quizCreateHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	courseId := r.PathValue("id")
+
+	var input struct {
+		Token            string             `json:"token"`
+		Title            string             `json:"title"`
+		Description      string             `json:"description"`
+		TimeLimit        int                `json:"time_limit"`
+		OpensAt          time.Time          `json:"opens_at"`
+		ClosesAt         time.Time          `json:"closes_at"`
+		MaxAttempts      int                `json:"max_attempts"`
+		ShuffleQuestions bool               `json:"shuffle_questions"`
+		ShuffleChoices   bool               `json:"shuffle_choices"`
+		Questions        []*models.Question `json:"questions"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	netId, err := app.services.AuthenticationService.GetNetIdFromToken(input.Token)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	if input.MaxAttempts == 0 {
+		input.MaxAttempts = 1
+	}
+
+	quiz := &models.Quiz{
+		CourseId:         courseId,
+		Title:            input.Title,
+		Description:      input.Description,
+		Owner:            netId,
+		TimeLimit:        input.TimeLimit,
+		OpensAt:          input.OpensAt,
+		ClosesAt:         input.ClosesAt,
+		MaxAttempts:      input.MaxAttempts,
+		ShuffleQuestions: input.ShuffleQuestions,
+		ShuffleChoices:   input.ShuffleChoices,
+		Questions:        input.Questions,
+	}
+
+	if errs := quiz.Valid(); len(errs) > 0 {
+		app.failedValidationResponse(w, r, errs)
+		return
+	}
+
+	quiz, err = app.services.QuizService.CreateQuiz(quiz)
+	if err != nil {
+		app.quizErrorResponse(w, r, err)
+		return
+	}
+
+	res := jsonWrap{"quiz": quiz}
+
+	err = app.writeJSON(w, http.StatusCreated, res, nil)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+}
+
+// quizListHandler lists a course's quizzes, without their questions.
+//
+// REQUEST: course ID
+// RESPONSE: quizzes
+func (app *application) quizListHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	courseId := r.PathValue("id")
+
+	quizzes, err := app.services.QuizService.ListQuizzes(courseId)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	res := jsonWrap{"quizzes": quizzes}
+
+	err = app.writeJSON(w, http.StatusOK, res, nil)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+}
+
+// quizReadHandler sends back a quiz. Its questions and answer keys
+// are only sent to the course's teachers.
+//
+// REQUEST: quiz ID, optional token
+// RESPONSE: quiz
+func (app *application) quizReadHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	quizId := r.PathValue("quizId")
+
+	netId, err := app.optionalNetId(r)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	quiz, err := app.services.QuizService.ReadQuiz(quizId, netId)
+	if err != nil {
+		app.quizErrorResponse(w, r, err)
+		return
+	}
+
+	res := jsonWrap{"quiz": quiz}
+
+	err = app.writeJSON(w, http.StatusOK, res, nil)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+}
+
+// quizDeleteHandler lets a teacher delete a quiz and its attempts.
+//
+// REQUEST: quiz ID, token
+// RESPONSE: status
+func (app *application) quizDeleteHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	quizId := r.PathValue("quizId")
+
+	netId, err := app.services.AuthenticationService.GetNetIdFromToken(
+		r.Header.Get("Authorization"),
+	)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	err = app.services.QuizService.DeleteQuiz(quizId, netId)
+	if err != nil {
+		app.quizErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, nil, nil)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+}
+
+// quizAttemptCreateHandler starts a student's attempt at a quiz, or
+// resumes the one in progress.
+//
+// REQUEST: quiz ID, token
+// RESPONSE: attempt
+func (app *application) quizAttemptCreateHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	quizId := r.PathValue("quizId")
+
+	var input struct {
+		Token string `json:"token"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	netId, err := app.services.AuthenticationService.GetNetIdFromToken(input.Token)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	attempt, err := app.services.QuizService.StartAttempt(quizId, netId)
+	if err != nil {
+		app.quizErrorResponse(w, r, err)
+		return
+	}
+
+	res := jsonWrap{"attempt": attempt}
+
+	err = app.writeJSON(w, http.StatusOK, res, nil)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+}
+
+// quizAttemptListHandler lists the attempts at a quiz. Teachers get
+// every attempt, students their own.
+//
+// REQUEST: quiz ID, token
+// RESPONSE: attempts
+func (app *application) quizAttemptListHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	quizId := r.PathValue("quizId")
+
+	netId, err := app.services.AuthenticationService.GetNetIdFromToken(
+		r.Header.Get("Authorization"),
+	)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	attempts, err := app.services.QuizService.ListAttempts(quizId, netId)
+	if err != nil {
+		app.quizErrorResponse(w, r, err)
+		return
+	}
+
+	res := jsonWrap{"attempts": attempts}
+
+	err = app.writeJSON(w, http.StatusOK, res, nil)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+}
+
+// quizAttemptReadHandler sends back an attempt and its questions.
+//
+// REQUEST: attempt ID, token
+// RESPONSE: attempt
+func (app *application) quizAttemptReadHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	attemptId := r.PathValue("attemptId")
+
+	netId, err := app.services.AuthenticationService.GetNetIdFromToken(
+		r.Header.Get("Authorization"),
+	)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	attempt, err := app.services.QuizService.ReadAttempt(attemptId, netId)
+	if err != nil {
+		app.quizErrorResponse(w, r, err)
+		return
+	}
+
+	res := jsonWrap{"attempt": attempt}
+
+	err = app.writeJSON(w, http.StatusOK, res, nil)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+}
+
+// quizAttemptUpdateHandler saves a student's answers to an attempt
+// in progress.
+//
+// REQUEST: attempt ID, token, responses
+// RESPONSE: attempt
+func (app *application) quizAttemptUpdateHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	attemptId := r.PathValue("attemptId")
+
+	var input struct {
+		Token     string             `json:"token"`
+		Responses []*models.Response `json:"responses"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	netId, err := app.services.AuthenticationService.GetNetIdFromToken(input.Token)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	attempt, err := app.services.QuizService.SaveResponses(
+		attemptId,
+		netId,
+		input.Responses,
+	)
+	if err != nil {
+		app.quizErrorResponse(w, r, err)
+		return
+	}
+
+	res := jsonWrap{"attempt": attempt}
+
+	err = app.writeJSON(w, http.StatusOK, res, nil)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+}
+
+// quizAttemptSubmitHandler submits and grades an attempt, saving any
+// final answers sent with it.
+//
+// REQUEST: attempt ID, token, optional responses
+// RESPONSE: attempt
+func (app *application) quizAttemptSubmitHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	attemptId := r.PathValue("attemptId")
+
+	var input struct {
+		Token     string             `json:"token"`
+		Responses []*models.Response `json:"responses"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	netId, err := app.services.AuthenticationService.GetNetIdFromToken(input.Token)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	attempt, err := app.services.QuizService.SubmitAttempt(
+		attemptId,
+		netId,
+		input.Responses,
+	)
+	if err != nil {
+		app.quizErrorResponse(w, r, err)
+		return
+	}
+
+	res := jsonWrap{"attempt": attempt}
+
+	err = app.writeJSON(w, http.StatusOK, res, nil)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+}
+
+// quizResponseGradeHandler lets a teacher set the points of one of an
+// attempt's responses, overriding its automatic grade.
+//
+// REQUEST: attempt ID, question ID, token, points
+// RESPONSE: attempt
+func (app *application) quizResponseGradeHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	attemptId := r.PathValue("attemptId")
+	questionId := r.PathValue("questionId")
+
+	var input struct {
+		Token  string   `json:"token"`
+		Points *float64 `json:"points"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	netId, err := app.services.AuthenticationService.GetNetIdFromToken(input.Token)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	if input.Points == nil {
+		app.failedValidationResponse(w, r, map[string]string{"points": "must be provided"})
+		return
+	}
+
+	attempt, err := app.services.QuizService.GradeResponse(
+		attemptId,
+		questionId,
+		netId,
+		*input.Points,
+	)
+	if err != nil {
+		app.quizErrorResponse(w, r, err)
+		return
+	}
+
+	res := jsonWrap{"attempt": attempt}
+
+	err = app.writeJSON(w, http.StatusOK, res, nil)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+}
+
+// Rubric handlers, for rubrics and grading submissions with them.
+
+// rubricCreateHandler lets a teacher create a rubric in a course,
+// along with its criteria and their levels.
+//
+// REQUEST: course ID, token, title, description, criteria
+// RESPONSE: rubric
+func (app *application) rubricCreateHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	courseId := r.PathValue("id")
+
+	var input struct {
+		Token       string              `json:"token"`
+		Title       string              `json:"title"`
+		Description string              `json:"description"`
+		Criteria    []*models.Criterion `json:"criteria"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	netId, err := app.services.AuthenticationService.GetNetIdFromToken(input.Token)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	rubric := &models.Rubric{
+		CourseId:    courseId,
+		Title:       input.Title,
+		Description: input.Description,
+		Owner:       netId,
+		Criteria:    input.Criteria,
+	}
+
+	if errs := rubric.Valid(); len(errs) > 0 {
+		app.failedValidationResponse(w, r, errs)
+		return
+	}
+
+	rubric, err = app.services.RubricService.CreateRubric(rubric)
+	if err != nil {
+		app.rubricErrorResponse(w, r, err)
+		return
+	}
+
+	res := jsonWrap{"rubric": rubric}
+
+	err = app.writeJSON(w, http.StatusCreated, res, nil)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+}
+
+// rubricListHandler lists a course's rubrics, without their criteria.
+//
+// REQUEST: course ID
+// RESPONSE: rubrics
+func (app *application) rubricListHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	courseId := r.PathValue("id")
+
+	rubrics, err := app.services.RubricService.ListRubrics(courseId)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	res := jsonWrap{"rubrics": rubrics}
+
+	err = app.writeJSON(w, http.StatusOK, res, nil)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+}
+
+// rubricReadHandler sends back a rubric with its criteria.
+//
+// REQUEST: rubric ID
+// RESPONSE: rubric
+func (app *application) rubricReadHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	rubricId := r.PathValue("rubricId")
+
+	rubric, err := app.services.RubricService.ReadRubric(rubricId)
+	if err != nil {
+		app.rubricErrorResponse(w, r, err)
+		return
+	}
+
+	res := jsonWrap{"rubric": rubric}
+
+	err = app.writeJSON(w, http.StatusOK, res, nil)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+}
+
+// rubricDeleteHandler lets a teacher delete a rubric that is not
+// attached to any assignment.
+//
+// REQUEST: rubric ID, token
+// RESPONSE: status
+func (app *application) rubricDeleteHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	rubricId := r.PathValue("rubricId")
+
+	netId, err := app.services.AuthenticationService.GetNetIdFromToken(
+		r.Header.Get("Authorization"),
+	)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	err = app.services.RubricService.DeleteRubric(rubricId, netId)
+	if err != nil {
+		app.rubricErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, nil, nil)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+}
+
+// assignmentRubricHandler lets a teacher set the rubric an
+// assignment is graded against. An empty rubric ID removes it.
+//
+// REQUEST: assignment ID, token, rubric ID
+// RESPONSE: assignment
+func (app *application) assignmentRubricHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	assignmentId := r.PathValue("assignmentId")
+
+	var input struct {
+		Token    string `json:"token"`
+		RubricId string `json:"rubric_id"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	netId, err := app.services.AuthenticationService.GetNetIdFromToken(input.Token)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	assignment, err := app.services.RubricService.AttachRubric(
+		assignmentId,
+		input.RubricId,
+		netId,
+	)
+	if err != nil {
+		app.rubricErrorResponse(w, r, err)
+		return
+	}
+
+	res := jsonWrap{"assignment": assignment}
+
+	err = app.writeJSON(w, http.StatusOK, res, nil)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+}
+
+// submissionRubricGradeHandler lets a teacher grade a submission
+// against its assignment's rubric. The submission's grade is the
+// total of its criterion scores.
+//
+// REQUEST: submission ID, token, scores, feedback
+// RESPONSE: submission
+func (app *application) submissionRubricGradeHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	submissionId := r.PathValue("id")
+
+	var input struct {
+		Token    string                   `json:"token"`
+		Scores   []*models.CriterionScore `json:"scores"`
+		Feedback string                   `json:"feedback"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	netId, err := app.services.AuthenticationService.GetNetIdFromToken(input.Token)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	submission, err := app.services.RubricService.GradeSubmission(
+		submissionId,
+		netId,
+		input.Scores,
+		input.Feedback,
+	)
+	if err != nil {
+		app.rubricErrorResponse(w, r, err)
+		return
+	}
+
+	res := jsonWrap{"submission": submission}
+
+	err = app.writeJSON(w, http.StatusOK, res, nil)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+}
+
+// Peer review handlers, for students reviewing each other's
+// submissions.
+
+// peerReviewPolicyHandler lets a teacher set how many students review
+// each submission to an assignment, and the percentage of the grade
+// that their average score makes up.
+//
+// REQUEST: assignmentId, token, reviewers, weight
+// RESPONSE: assignment
+func (app *application) peerReviewPolicyHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	assignmentId := r.PathValue("assignmentId")
+
+	var input struct {
+		Token     string  `json:"token"`
+		Reviewers int     `json:"reviewers"`
+		Weight    float64 `json:"weight"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	policy := &models.PeerReviewPolicy{
+		AssignmentId: assignmentId,
+		Reviewers:    input.Reviewers,
+		Weight:       input.Weight,
+	}
+
+	if errs := policy.Valid(); len(errs) > 0 {
+		app.failedValidationResponse(w, r, errs)
+		return
+	}
+
+	netId, err := app.services.AuthenticationService.GetNetIdFromToken(input.Token)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	assignment, err := app.services.PeerReviewService.SetPolicy(policy, netId)
+	if err != nil {
+		app.peerReviewErrorResponse(w, r, err)
+		return
+	}
+
+	res := jsonWrap{"assignment": assignment}
+
+	err = app.writeJSON(w, http.StatusOK, res, nil)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+}
+
+// peerReviewAllocateHandler lets a teacher allocate reviewers to an
+// assignment's submissions once it is due, instead of waiting for
+// them to be allocated on schedule.
+//
+// REQUEST: assignmentId, token
+// RESPONSE: reviews
+func (app *application) peerReviewAllocateHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	assignmentId := r.PathValue("assignmentId")
+
+	var input struct {
+		Token string `json:"token"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	netId, err := app.services.AuthenticationService.GetNetIdFromToken(input.Token)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	reviews, err := app.services.PeerReviewService.Allocate(assignmentId, netId)
+	if err != nil {
+		app.peerReviewErrorResponse(w, r, err)
+		return
+	}
+
+	res := jsonWrap{"reviews": reviews}
+
+	err = app.writeJSON(w, http.StatusCreated, res, nil)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+}
+
+// peerReviewListHandler sends back every review of an assignment's
+// submissions, with who wrote them, under pseudonyms while the
+// assignment is graded blind. Only the course's teachers may read
+// them.
+//
+// REQUEST: courseId, assignmentId, token
+// RESPONSE: reviews
+func (app *application) peerReviewListHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	assignmentId := r.PathValue("assignmentId")
+
+	netId, err := app.services.AuthenticationService.GetNetIdFromToken(
+		r.Header.Get("Authorization"),
+	)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	reviews, err := app.services.PeerReviewService.AssignmentReviews(
+		assignmentId,
+		netId,
+	)
+	if err != nil {
+		app.peerReviewErrorResponse(w, r, err)
+		return
+	}
+
+	res := jsonWrap{"reviews": reviews}
+
+	err = app.writeJSON(w, http.StatusOK, res, nil)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+}
+
+// peerReviewTodoHandler sends back the reviews a student was
+// allocated for an assignment, with the submissions to review.
+//
+// REQUEST: courseId, assignmentId, token
+// RESPONSE: reviews
+func (app *application) peerReviewTodoHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	assignmentId := r.PathValue("assignmentId")
+
+	netId, err := app.services.AuthenticationService.GetNetIdFromToken(
+		r.Header.Get("Authorization"),
+	)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	reviews, err := app.services.PeerReviewService.ReviewsToDo(
+		assignmentId,
+		netId,
+	)
+	if err != nil {
+		app.peerReviewErrorResponse(w, r, err)
+		return
+	}
+
+	res := jsonWrap{"reviews": reviews}
+
+	err = app.writeJSON(w, http.StatusOK, res, nil)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+}
+
+// peerReviewReceivedHandler sends back the finished reviews of a
+// student's own submission to an assignment, without who wrote them.
+//
+// REQUEST: courseId, assignmentId, token
+// RESPONSE: reviews
+func (app *application) peerReviewReceivedHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	assignmentId := r.PathValue("assignmentId")
+
+	netId, err := app.services.AuthenticationService.GetNetIdFromToken(
+		r.Header.Get("Authorization"),
+	)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	reviews, err := app.services.PeerReviewService.ReceivedReviews(
+		assignmentId,
+		netId,
+	)
+	if err != nil {
+		app.peerReviewErrorResponse(w, r, err)
+		return
+	}
+
+	res := jsonWrap{"reviews": reviews}
+
+	err = app.writeJSON(w, http.StatusOK, res, nil)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+}
+
+// peerReviewSubmitHandler lets a student fill in a review they were
+// allocated. Assignments with a rubric are scored against it, and the
+// rest are given a score.
+//
+// REQUEST: reviewId, token, score or rubric scores, comments
+// RESPONSE: review
+func (app *application) peerReviewSubmitHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	reviewId := r.PathValue("reviewId")
+
+	var input struct {
+		Token    string                   `json:"token"`
+		Score    *float64                 `json:"score"`
+		Scores   []*models.CriterionScore `json:"scores"`
+		Comments string                   `json:"comments"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	netId, err := app.services.AuthenticationService.GetNetIdFromToken(input.Token)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	review := &models.PeerReview{
+		Entity:   models.Entity{ID: reviewId},
+		Score:    input.Score,
+		Rubric:   input.Scores,
+		Comments: input.Comments,
+	}
+
+	review, err = app.services.PeerReviewService.SubmitReview(review, netId)
+	if err != nil {
+		app.peerReviewErrorResponse(w, r, err)
+		return
+	}
+
+	res := jsonWrap{"review": review}
+
+	err = app.writeJSON(w, http.StatusOK, res, nil)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+}
+
+// Gradebook handlers, for grade categories and course grades.
+
+// gradebookReadHandler sends back a course's gradebook with the
+// running and final grades of every student. Only the course's
+// teachers may read it.
+//
+// REQUEST: course ID, token
+// RESPONSE: gradebook
+func (app *application) gradebookReadHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	courseId := r.PathValue("id")
+
+	netId, err := app.services.AuthenticationService.GetNetIdFromToken(
+		r.Header.Get("Authorization"),
+	)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	gradebook, err := app.services.GradebookService.ClassGradebook(courseId, netId)
+	if err != nil {
+		app.gradebookErrorResponse(w, r, err)
+		return
+	}
+
+	res := jsonWrap{"gradebook": gradebook}
+
+	err = app.writeJSON(w, http.StatusOK, res, nil)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+}
+
+// gradebookStudentReadHandler sends back a course's gradebook with
+// only the requesting student's grades.
+//
+// REQUEST: course ID, token
+// RESPONSE: gradebook
+func (app *application) gradebookStudentReadHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	courseId := r.PathValue("id")
+
+	netId, err := app.services.AuthenticationService.GetNetIdFromToken(
+		r.Header.Get("Authorization"),
+	)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	gradebook, err := app.services.GradebookService.StudentGradebook(courseId, netId)
+	if err != nil {
+		app.gradebookErrorResponse(w, r, err)
+		return
+	}
+
+	res := jsonWrap{"gradebook": gradebook}
+
+	err = app.writeJSON(w, http.StatusOK, res, nil)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+}
+
+// gradeCategoryCreateHandler lets a teacher add a grade category to
+// a course.
+//
+// REQUEST: course ID, token, name, weight, drop lowest
+// RESPONSE: category
+func (app *application) gradeCategoryCreateHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	courseId := r.PathValue("id")
+
+	var input struct {
+		Token      string  `json:"token"`
+		Name       string  `json:"name"`
+		Weight     float64 `json:"weight"`
+		DropLowest int     `json:"drop_lowest"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	netId, err := app.services.AuthenticationService.GetNetIdFromToken(input.Token)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	category := &models.GradeCategory{
+		CourseId:   courseId,
+		Name:       input.Name,
+		Weight:     input.Weight,
+		DropLowest: input.DropLowest,
+	}
+
+	if errs := category.Valid(); len(errs) > 0 {
+		app.failedValidationResponse(w, r, errs)
+		return
+	}
+
+	category, err = app.services.GradebookService.CreateCategory(category, netId)
+	if err != nil {
+		app.gradebookErrorResponse(w, r, err)
+		return
+	}
+
+	res := jsonWrap{"category": category}
+
+	err = app.writeJSON(w, http.StatusCreated, res, nil)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+}
+
+// gradeCategoryUpdateHandler lets a teacher change a grade category.
+//
+// REQUEST: category ID, token, name, weight, drop lowest
+// RESPONSE: category
+func (app *application) gradeCategoryUpdateHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	categoryId := r.PathValue("categoryId")
+
+	var input struct {
+		Token      string  `json:"token"`
+		Name       string  `json:"name"`
+		Weight     float64 `json:"weight"`
+		DropLowest int     `json:"drop_lowest"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	netId, err := app.services.AuthenticationService.GetNetIdFromToken(input.Token)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	category := &models.GradeCategory{
+		Entity:     models.Entity{ID: categoryId},
+		Name:       input.Name,
+		Weight:     input.Weight,
+		DropLowest: input.DropLowest,
+	}
+
+	if errs := category.Valid(); len(errs) > 0 {
+		app.failedValidationResponse(w, r, errs)
+		return
+	}
+
+	category, err = app.services.GradebookService.UpdateCategory(category, netId)
+	if err != nil {
+		app.gradebookErrorResponse(w, r, err)
+		return
+	}
+
+	res := jsonWrap{"category": category}
+
+	err = app.writeJSON(w, http.StatusOK, res, nil)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+}
+
+// gradeCategoryDeleteHandler lets a teacher delete a grade category.
+//
+// REQUEST: category ID, token
+// RESPONSE: status
+func (app *application) gradeCategoryDeleteHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	categoryId := r.PathValue("categoryId")
+
+	netId, err := app.services.AuthenticationService.GetNetIdFromToken(
+		r.Header.Get("Authorization"),
+	)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	err = app.services.GradebookService.DeleteCategory(categoryId, netId)
+	if err != nil {
+		app.gradebookErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, nil, nil)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+}
+
+// gradeSchemeUpdateHandler lets a teacher set the letter grades of
+// a course.
+//
+// REQUEST: course ID, token, scheme
+// RESPONSE: scheme
+func (app *application) gradeSchemeUpdateHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	courseId := r.PathValue("id")
+
+	var input struct {
+		Token  string             `json:"token"`
+		Scheme models.GradeScheme `json:"scheme"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	netId, err := app.services.AuthenticationService.GetNetIdFromToken(input.Token)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	if errs := input.Scheme.Valid(); len(errs) > 0 {
+		app.failedValidationResponse(w, r, errs)
+		return
+	}
+
+	err = app.services.GradebookService.SetScheme(courseId, netId, input.Scheme)
+	if err != nil {
+		app.gradebookErrorResponse(w, r, err)
+		return
+	}
+
+	res := jsonWrap{"scheme": input.Scheme}
+
+	err = app.writeJSON(w, http.StatusOK, res, nil)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+}
+
+// assignmentGradebookHandler lets a teacher set how an assignment
+// counts in the gradebook.
+//
+// REQUEST: assignment ID, token, category ID, points, extra credit
+// RESPONSE: item
+func (app *application) assignmentGradebookHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	assignmentId := r.PathValue("assignmentId")
+
+	var input struct {
+		Token       string  `json:"token"`
+		CategoryId  string  `json:"category_id"`
+		Points      float64 `json:"points"`
+		ExtraCredit bool    `json:"extra_credit"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	netId, err := app.services.AuthenticationService.GetNetIdFromToken(input.Token)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	item := &models.GradeItem{
+		AssignmentId: assignmentId,
+		CategoryId:   input.CategoryId,
+		Points:       input.Points,
+		ExtraCredit:  input.ExtraCredit,
+	}
+
+	if errs := item.Valid(); len(errs) > 0 {
+		app.failedValidationResponse(w, r, errs)
+		return
+	}
+
+	item, err = app.services.GradebookService.SetItem(item, netId)
+	if err != nil {
+		app.gradebookErrorResponse(w, r, err)
+		return
+	}
+
+	res := jsonWrap{"item": item}
+
+	err = app.writeJSON(w, http.StatusOK, res, nil)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+}
+
+// User handlers, deals with anything user side.
+
+// userCreateHandler creates a user.
+//
+// REQUEST: email, password, full name, netid, membership
+// RESPONSE: status
+func (app *application) userCreateHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	var input struct {
+		FullName   string `json:"fullname"`
+		Password   string `json:"password"`
+		Email      string `json:"email"`
+		Netid      string `json:"netid"`
+		Membership int    `json:"membership"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	// Map the input fields to the appropriate credentials fields.
+	c := models.Credentials{
+		Username:   app.services.UserService.NewUsername(input.Netid),
+		Password:   app.services.UserService.NewPassword(input.Password),
+		Email:      app.services.UserService.NewEmail(input.Email),
+		Membership: app.services.UserService.NewMembership(input.Membership),
+	}
+
+	user, err := models.NewUser(input.Netid, c, input.FullName)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	err = app.services.UserService.CreateUser(user)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+}
+
+// userReadHandler reads a specific user's data,
+// which is specified by the requester.
+//
+// REQUEST: user netid
+// RESPONSE: user
+func (app *application) userReadHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	id := r.PathValue("id")
+
+	var err error
+	var user *models.User
+
+	// Perform a database lookup of user.
+	user, err = app.services.UserService.GetByID(id)
+	user, err = app.services.UserService.GetByID(id)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	res := jsonWrap{"user": user}
+
+	err = app.writeJSON(w, http.StatusOK, res, nil)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+}
+
+// userUpdateHandler updates a user's data.
+//
+// REQUEST: user UUID + data to update
+// RESPONSE: new user data
+func (app *application) userUpdateHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	id := r.PathValue("id")
+	fmt.Printf("id: %s", id)
+}
+
+// userDeleteHandler deletes a user. A request must come from
+// a user themselves to delete themselves.
+//
+// REQUEST: user uuid
+// RESPONSE: logout page
+func (app *application) userDeleteHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+
+}
+
+// userLoginHandler handles login requests from any user. It requires
+// a username and a password. A login must occur from a genuine domain. This
+// means that the request comes from the frontend server rather than the
+// user's browser. Written to the http response is an authorized
+// login token.
+//
+// REQUEST: username/email, password
+// RESPONSE: auth cookie/login session
+func (app *application) userLoginHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	var noToken bool
+	var input struct {
+		NetId    string `json:"netid"`
+		Password string `json:"password"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	// 	Validate credentials.
+	err = app.services.UserService.ValidateUser(input.NetId, input.Password)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	app.logger.Printf("user validated")
+
+	// If token exists, return token:
+	token, err := app.services.AuthenticationService.RetrieveToken(input.NetId)
+	if err != nil {
+		switch {
+		case errors.Is(err, dal.ERR_RECORD_NOT_FOUND):
+			app.logger.Printf("user token not found for %s", input.NetId)
+			noToken = true
+		default:
+			app.serverError(w, r, err)
+			return
+		}
+	}
+
+	if noToken {
+		app.logger.Printf("generating user token...")
+		token, err = app.services.AuthenticationService.NewToken(input.NetId)
+	}
+
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	app.logger.Printf("Token: %v", token)
+
+	membership, err := app.services.UserService.GetMembership(input.NetId)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	wrapped := jsonWrap{"authentication_token": token, "permissions": membership}
+
+	err = app.writeJSON(
+		w, http.StatusCreated,
+		wrapped, nil,
+	)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+}
+
+// Assignment handlers. Only teachers should be able to request the use of
+// these handlers. Therefore, teacher permission/authorization is
+// a necessity.
+
+// assignmentCreateHandler creates an assignment based on the request values.
+// To create an assignment, a request must contain an assignment: title,
+// author, body, and media. The return value is the assignment data along
+// with a uuid. Assignments are published as soon as they are made,
+// unless they are made as drafts or scheduled to be published later.
+//
+// REQUEST: title, author, body, media, optional state, publish_at,
+// available_from, lock_at
+// RESPONSE: assignment
+func (app *application) assignmentCreateHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	app.logger.Printf("Creating assignment...")
+	var input struct {
+		Title       string   `json:"title"`
+		Token       string   `json:"token"`
+		Description string   `json:"description"`
+		Media       []string `json:"media"`
+		DueDate     string   `json:"duedate"`
+		CourseId    string   `json:"courseid"`
+
+		// TimeZone is where a plain due date falls due, such as
+		// "America/New_York". It is not needed for full times.
+		TimeZone string `json:"timezone"`
+
+		AllowedFileTypes []string `json:"allowed_file_types"`
+
+		scheduleInput
+	}
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	netid, err := app.services.AuthenticationService.GetNetIdFromToken(input.Token)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	post := models.Post{
+		Title:       input.Title,
+		Description: input.Description,
+		Owner:       netid,
+		Media:       input.Media,
+		Course:      input.CourseId,
+	}
+
+	dueDate, err := models.ParseDueDate(input.DueDate, input.TimeZone)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	schedule, errs := input.schedule(input.TimeZone)
+
+	fileTypes, err := models.ParseFileTypes(input.AllowedFileTypes...)
+	if err != nil {
+		errs["allowed_file_types"] = err.Error()
+	}
+
+	assignment := &models.Assignment{
+		Post:      post,
+		DueDate:   dueDate,
+		FileTypes: fileTypes,
+		Schedule:  schedule,
+	}
+
+	for field, msg := range assignment.ValidSchedule() {
+		errs[field] = msg
+	}
+
+	if len(errs) > 0 {
+		app.failedValidationResponse(w, r, errs)
+		return
+	}
+
+	assignment, err = app.services.AssignmentService.CreateAssignment(assignment)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	res := jsonWrap{"assignment": assignment}
+
+	err = app.writeJSON(w, http.StatusOK, res, nil)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+}
+
+// assignmentReadHandler relays assignment data back to the requester. To read
+// one specific assignment, one must only request the UUID of an assignment.
+// Requests with a student's token get the student's own due dates. Drafts
+// are only shown to the course's teachers.
+//
+// REQUEST: uuid, optional token
+// RESPONSE: assignments
+func (app *application) assignmentReadHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	var input struct {
+		AssignmentId string `json:"assignment_id"`
+		Token        string `json:"token"`
+	}
+
+	courseId := r.PathValue("courseId")
+
+	switch r.Method {
+	// Retrieve multiple assignments if its a single GET request.
+	case http.MethodGet:
+		assignmentIds, err := app.services.AssignmentService.RetrieveAssignments(courseId)
+		if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+
+		// Students who send their token see their own due dates.
+		var netId string
+
+		if token := r.Header.Get("Authorization"); token != "" {
+			netId, err = app.services.AuthenticationService.GetNetIdFromToken(token)
+			if err != nil {
+				app.serverError(w, r, err)
+				return
+			}
+		}
+
+		var assignments []models.Assignment
+
+		for _, id := range assignmentIds {
+			assignment, err := app.services.AssignmentService.ReadAssignment(id)
+			if err != nil {
+				app.serverError(w, r, err)
+				return
+			}
+
+			// Drafts are left out for everyone but teachers.
+			hidden, err := app.services.AssignmentService.Hidden(assignment, netId)
+			if err != nil {
+				app.serverError(w, r, err)
+				return
+			}
+
+			if hidden {
+				continue
+			}
+
+			if netId != "" {
+				assignment, err = app.services.ExtensionService.ForStudent(assignment, netId)
+				if err != nil {
+					app.serverError(w, r, err)
+					return
+				}
+			}
+
+			assignments = append(assignments, *assignment)
+		}
+
+		res := jsonWrap{"assignments": assignments}
+
+		err = app.writeJSON(w, http.StatusOK, res, nil)
+		if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+
+	// If it's a post request, we can expect an input and body. Therefore,
+	// only retrieve a single Assignment.
+	case http.MethodPost:
+		err := app.readJSON(w, r, &input)
+		if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+
+		assignment, err := app.services.AssignmentService.ReadAssignment(
+			input.
+				AssignmentId,
+		)
+		if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+
+		var netId string
+
+		if input.Token != "" {
+			netId, err = app.services.AuthenticationService.GetNetIdFromToken(input.Token)
+			if err != nil {
+				app.serverError(w, r, err)
+				return
+			}
+		}
+
+		hidden, err := app.services.AssignmentService.Hidden(assignment, netId)
+		if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+
+		if hidden {
+			app.notFoundResponse(w, r)
+			return
+		}
+
+		if netId != "" {
+			assignment, err = app.services.ExtensionService.ForStudent(assignment, netId)
+			if err != nil {
+				app.serverError(w, r, err)
+				return
+			}
+		}
+
+		res := jsonWrap{"assignment": assignment}
+
+		err = app.writeJSON(w, http.StatusOK, res, nil)
+		if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+		return
+
+	// Bad dog.
+	default:
+		app.serverError(w, r, fmt.Errorf("method %s not allowed", r.Method))
+		return
+	}
+}
+
+// assignmentUpdateHandler updates the information of an assignment.
+//
+// REQUEST: uuid, updated information, type (title, description, duedate)
+// RESPONSE: assignment
+func (app *application) assignmentUpdateHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	var input struct {
+		Uuid         string      `json:"uuid"`
+		UpdatedField interface{} `json:"updatedfield"`
+		Action       string      `json:"action"`
+	}
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	assignment, err := app.services.AssignmentService.UpdateAssignment(input.Uuid, input.UpdatedField, input.Action)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	res := jsonWrap{"assignment": assignment}
+
+	err = app.writeJSON(w, http.StatusOK, res, nil)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+}
+
+// assignmentFileTypesHandler changes which types of files can be
+// submitted for an assignment. An empty list accepts any known type.
+//
+// REQUEST: assignmentId, token, allowed file types
+// RESPONSE: assignment
+func (app *application) assignmentFileTypesHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	assignmentId := r.PathValue("assignmentId")
+
+	var input struct {
+		Token            string   `json:"token"`
+		AllowedFileTypes []string `json:"allowed_file_types"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	fileTypes, err := models.ParseFileTypes(input.AllowedFileTypes...)
+	if err != nil {
+		app.failedValidationResponse(
+			w,
+			r,
+			map[string]string{"allowed_file_types": err.Error()},
+		)
+		return
+	}
+
+	netId, err := app.services.AuthenticationService.GetNetIdFromToken(input.Token)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	assignment, err := app.services.AssignmentService.SetFileTypes(
+		assignmentId,
+		netId,
+		fileTypes,
+	)
+	if err != nil {
+		app.submissionErrorResponse(w, r, err)
+		return
+	}
+
+	res := jsonWrap{"assignment": assignment}
+
+	err = app.writeJSON(w, http.StatusOK, res, nil)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+}
+
+// assignmentLatePolicyHandler changes how an assignment treats late
+// submissions. A null policy removes it, so that late submissions are
+// accepted without a penalty.
+//
+// REQUEST: assignmentId, token, late policy
+// RESPONSE: assignment
+func (app *application) assignmentLatePolicyHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	assignmentId := r.PathValue("assignmentId")
+
+	var input struct {
+		Token      string             `json:"token"`
+		LatePolicy *models.LatePolicy `json:"late_policy"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if input.LatePolicy != nil {
+		if errs := input.LatePolicy.Valid(); len(errs) > 0 {
+			app.failedValidationResponse(w, r, errs)
+			return
+		}
+	}
+
+	netId, err := app.services.AuthenticationService.GetNetIdFromToken(input.Token)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	assignment, err := app.services.AssignmentService.SetLatePolicy(
+		assignmentId,
+		netId,
+		input.LatePolicy,
+	)
+	if err != nil {
+		app.submissionErrorResponse(w, r, err)
+		return
+	}
+
+	res := jsonWrap{"assignment": assignment}
+
+	err = app.writeJSON(w, http.StatusOK, res, nil)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+}
+
+// assignmentAttemptPolicyHandler lets a teacher set how many attempts
+// an assignment allows and which attempt counts, either "latest" or
+// "best". A max_attempts of 0 allows any number of attempts.
+//
+// REQUEST: assignmentId, token, max attempts, counted attempt
+// RESPONSE: assignment
+func (app *application) assignmentAttemptPolicyHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	assignmentId := r.PathValue("assignmentId")
+
+	var input struct {
+		Token          string                  `json:"token"`
+		MaxAttempts    int                     `json:"max_attempts"`
+		CountedAttempt models.AttemptSelection `json:"counted_attempt"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if errs := models.ValidAttempts(input.MaxAttempts, input.CountedAttempt); len(errs) > 0 {
+		app.failedValidationResponse(w, r, errs)
+		return
+	}
+
+	netId, err := app.services.AuthenticationService.GetNetIdFromToken(input.Token)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	assignment, err := app.services.AssignmentService.SetAttemptPolicy(
+		assignmentId,
+		netId,
+		input.MaxAttempts,
+		input.CountedAttempt,
+	)
+	if err != nil {
+		app.submissionErrorResponse(w, r, err)
+		return
+	}
+
+	res := jsonWrap{"assignment": assignment}
+
+	err = app.writeJSON(w, http.StatusOK, res, nil)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+}
+
+// assignmentAnonymousHandler lets a teacher turn anonymous grading on
+// or off for an assignment. It cannot be turned off before grades are
+// released.
+//
+// REQUEST: assignmentId, token, anonymous
+// RESPONSE: assignment
+func (app *application) assignmentAnonymousHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	assignmentId := r.PathValue("assignmentId")
+
+	var input struct {
+		Token     string `json:"token"`
+		Anonymous bool   `json:"anonymous"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	netId, err := app.services.AuthenticationService.GetNetIdFromToken(input.Token)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	assignment, err := app.services.AssignmentService.SetAnonymous(
+		assignmentId,
+		netId,
+		input.Anonymous,
+	)
+	if err != nil {
+		app.submissionErrorResponse(w, r, err)
+		return
+	}
+
+	res := jsonWrap{"assignment": assignment}
+
+	err = app.writeJSON(w, http.StatusOK, res, nil)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+}
+
+// assignmentReleaseGradesHandler lets a teacher release an
+// assignment's grades, revealing who made each submission if it was
+// graded anonymously.
+//
+// REQUEST: assignmentId, token
+// RESPONSE: assignment
+func (app *application) assignmentReleaseGradesHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	assignmentId := r.PathValue("assignmentId")
+
+	var input struct {
+		Token string `json:"token"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	netId, err := app.services.AuthenticationService.GetNetIdFromToken(input.Token)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	assignment, err := app.services.AssignmentService.ReleaseGrades(
+		assignmentId,
+		netId,
+	)
+	if err != nil {
+		app.submissionErrorResponse(w, r, err)
+		return
+	}
+
+	res := jsonWrap{"assignment": assignment}
+
+	err = app.writeJSON(w, http.StatusOK, res, nil)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+}
+
+// assignmentScheduleReleaseHandler lets a teacher schedule when an
+// assignment's grades are released. Plain dates are released at the
+// end of the day in the given time zone, like due dates. An empty
+// release date clears the schedule.
+//
+// REQUEST: assignmentId, token, release date, time zone
+// RESPONSE: assignment
+func (app *application) assignmentScheduleReleaseHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	assignmentId := r.PathValue("assignmentId")
+
+	var input struct {
+		Token     string `json:"token"`
+		ReleaseAt string `json:"release_at"`
+		TimeZone  string `json:"timezone"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	var releaseAt *time.Time
+
+	if input.ReleaseAt != "" {
+		at, err := models.ParseDueDate(input.ReleaseAt, input.TimeZone)
+		if err != nil {
+			app.failedValidationResponse(w, r, map[string]string{"release_at": err.Error()})
+			return
+		}
+		releaseAt = &at
+	}
+
+	netId, err := app.services.AuthenticationService.GetNetIdFromToken(input.Token)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	assignment, err := app.services.AssignmentService.ScheduleRelease(
+		assignmentId,
+		netId,
+		releaseAt,
+	)
+	if err != nil {
+		app.submissionErrorResponse(w, r, err)
+		return
+	}
+
+	res := jsonWrap{"assignment": assignment}
+
+	err = app.writeJSON(w, http.StatusOK, res, nil)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+}
+
+// scheduleInput is when an assignment is published, takes submissions
+// from and is locked. Plain publish and available dates start at the
+// beginning of the day, and plain lock dates at the end of it, in the
+// time zone the assignment's dates are given in.
+type scheduleInput struct {
+	State         models.AssignmentState `json:"state"`
+	PublishAt     string                 `json:"publish_at"`
+	AvailableFrom string                 `json:"available_from"`
+	LockAt        string                 `json:"lock_at"`
+}
+
+// schedule reads the schedule, along with any dates that could not be
+// read. Without a state, assignments scheduled to be published are
+// drafts until then, and others are published.
+func (in *scheduleInput) schedule(zone string) (
+	models.Schedule,
+	map[string]string,
+) {
+	errs := make(map[string]string)
+
+	schedule := models.Schedule{State: in.State}
+
+	if in.PublishAt != "" {
+		at, err := models.ParseOpenDate(in.PublishAt, zone)
+		if err != nil {
+			errs["publish_at"] = err.Error()
+		} else {
+			schedule.PublishAt = &at
+		}
+	}
+
+	if in.AvailableFrom != "" {
+		at, err := models.ParseOpenDate(in.AvailableFrom, zone)
+		if err != nil {
+			errs["available_from"] = err.Error()
+		} else {
+			schedule.AvailableFrom = &at
+		}
+	}
+
+	if in.LockAt != "" {
+		at, err := models.ParseDueDate(in.LockAt, zone)
+		if err != nil {
+			errs["lock_at"] = err.Error()
+		} else {
+			schedule.LockAt = &at
+		}
+	}
+
+	if schedule.State == "" {
+		schedule.State = models.ASSIGNMENT_PUBLISHED
+
+		if schedule.PublishAt != nil {
+			schedule.State = models.ASSIGNMENT_DRAFT
+		}
+	}
+
+	return schedule, errs
+}
+
+// assignmentScheduleHandler lets a teacher change an assignment's
+// state, and when it is published, takes submissions from and is
+// locked. The schedule is replaced as a whole, so dates left out are
+// cleared. Publishing a draft notifies the course's students.
+//
+// REQUEST: assignmentId, token, state, publish_at, available_from,
+// lock_at, time zone
+// RESPONSE: assignment
+func (app *application) assignmentScheduleHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	assignmentId := r.PathValue("assignmentId")
+
+	var input struct {
+		Token    string `json:"token"`
+		TimeZone string `json:"timezone"`
+
+		scheduleInput
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	netId, err := app.services.AuthenticationService.GetNetIdFromToken(input.Token)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	schedule, errs := input.schedule(input.TimeZone)
+
+	assignment, err := app.services.AssignmentService.ReadAssignment(assignmentId)
+	if err != nil {
+		app.submissionErrorResponse(w, r, err)
+		return
+	}
+
+	assignment.Schedule = schedule
+
+	for field, msg := range assignment.ValidSchedule() {
+		errs[field] = msg
+	}
+
+	if len(errs) > 0 {
+		app.failedValidationResponse(w, r, errs)
+		return
+	}
+
+	assignment, err = app.services.AssignmentService.SetSchedule(
+		assignmentId,
+		netId,
+		schedule,
+	)
+	if err != nil {
+		app.submissionErrorResponse(w, r, err)
+		return
+	}
+
+	res := jsonWrap{"assignment": assignment}
+
+	err = app.writeJSON(w, http.StatusOK, res, nil)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+}
+
+// extensionCreateHandler lets a teacher give a student their own due
+// date for an assignment. Plain dates are due at the end of the day in
+// the given time zone, like assignment due dates.
+//
+// REQUEST: assignmentId, token, netid, due date, time zone, reason
+// RESPONSE: extension
+func (app *application) extensionCreateHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	assignmentId := r.PathValue("assignmentId")
+
+	var input struct {
+		Token    string `json:"token"`
+		NetId    string `json:"netid"`
+		DueDate  string `json:"due_date"`
+		TimeZone string `json:"timezone"`
+		Reason   string `json:"reason"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	extension := &models.Extension{
+		AssignmentId: assignmentId,
+		NetId:        input.NetId,
+		Reason:       input.Reason,
+	}
+
+	if input.DueDate != "" {
+		extension.DueDate, err = models.ParseDueDate(input.DueDate, input.TimeZone)
+		if err != nil {
+			app.failedValidationResponse(w, r, map[string]string{"due_date": err.Error()})
+			return
+		}
+	}
+
+	if errs := extension.Valid(); len(errs) > 0 {
+		app.failedValidationResponse(w, r, errs)
+		return
+	}
+
+	netId, err := app.services.AuthenticationService.GetNetIdFromToken(input.Token)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	extension, err = app.services.ExtensionService.GrantExtension(extension, netId)
+	if err != nil {
+		app.submissionErrorResponse(w, r, err)
+		return
+	}
+
+	res := jsonWrap{"extension": extension}
+
+	err = app.writeJSON(w, http.StatusCreated, res, nil)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+}
+
+// extensionListHandler lets a teacher see the extensions granted for
+// an assignment.
+//
+// REQUEST: assignmentId, token in the Authorization header
+// RESPONSE: extensions
+func (app *application) extensionListHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	assignmentId := r.PathValue("assignmentId")
+
+	netId, err := app.services.AuthenticationService.GetNetIdFromToken(
+		r.Header.Get("Authorization"),
+	)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	extensions, err := app.services.ExtensionService.ListExtensions(assignmentId, netId)
+	if err != nil {
+		app.submissionErrorResponse(w, r, err)
+		return
+	}
+
+	res := jsonWrap{"extensions": extensions}
+
+	err = app.writeJSON(w, http.StatusOK, res, nil)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+}
+
+// extensionDeleteHandler lets a teacher take back a student's
+// extension for an assignment.
+//
+// REQUEST: assignmentId, student's netid, token
+// RESPONSE: status
+func (app *application) extensionDeleteHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	assignmentId := r.PathValue("assignmentId")
+	studentId := r.PathValue("netId")
+
+	netId, err := app.services.AuthenticationService.GetNetIdFromToken(
+		r.Header.Get("Authorization"),
+	)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	err = app.services.ExtensionService.RevokeExtension(assignmentId, studentId, netId)
+	if err != nil {
+		app.submissionErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, nil, nil)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+}
+
+// accommodationUpdateHandler lets a teacher set a student's standing
+// accommodation in a course, such as extra time on every assignment or
+// a longer time limit on quizzes.
+//
+// REQUEST: course ID, token, accommodation
+// RESPONSE: accommodation
+func (app *application) accommodationUpdateHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	courseId := r.PathValue("id")
+
+	var input struct {
+		Token          string  `json:"token"`
+		NetId          string  `json:"netid"`
+		ExtraTime      int     `json:"extra_time"`
+		TimeMultiplier float64 `json:"time_multiplier"`
+		Note           string  `json:"note"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	accommodation := &models.Accommodation{
+		CourseId:       courseId,
+		NetId:          input.NetId,
+		ExtraTime:      input.ExtraTime,
+		TimeMultiplier: input.TimeMultiplier,
+		Note:           input.Note,
+	}
+
+	if errs := accommodation.Valid(); len(errs) > 0 {
+		app.failedValidationResponse(w, r, errs)
+		return
+	}
+
+	netId, err := app.services.AuthenticationService.GetNetIdFromToken(input.Token)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	accommodation, err = app.services.ExtensionService.SetAccommodation(accommodation, netId)
+	if err != nil {
+		app.submissionErrorResponse(w, r, err)
+		return
+	}
+
+	res := jsonWrap{"accommodation": accommodation}
+
+	err = app.writeJSON(w, http.StatusOK, res, nil)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+}
+
+// accommodationListHandler lets a teacher see the accommodations of
+// a course's students.
+//
+// REQUEST: course ID, token in the Authorization header
+// RESPONSE: accommodations
+func (app *application) accommodationListHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	courseId := r.PathValue("id")
+
+	netId, err := app.services.AuthenticationService.GetNetIdFromToken(
+		r.Header.Get("Authorization"),
+	)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	accommodations, err := app.services.ExtensionService.ListAccommodations(courseId, netId)
+	if err != nil {
+		app.submissionErrorResponse(w, r, err)
+		return
+	}
+
+	res := jsonWrap{"accommodations": accommodations}
+
+	err = app.writeJSON(w, http.StatusOK, res, nil)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+}
+
+// accommodationDeleteHandler lets a teacher remove a student's
+// accommodation in a course.
+//
+// REQUEST: course ID, student's netid, token
+// RESPONSE: status
+func (app *application) accommodationDeleteHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	courseId := r.PathValue("id")
+	studentId := r.PathValue("netId")
+
+	netId, err := app.services.AuthenticationService.GetNetIdFromToken(
+		r.Header.Get("Authorization"),
+	)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	err = app.services.ExtensionService.RemoveAccommodation(courseId, studentId, netId)
+	if err != nil {
+		app.submissionErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, nil, nil)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+}
+
+// assignmentDeleteHandler deletes an assignment.
+//
+// REQUEST: assignmentId
+// RESPONSE: updated list of assignments
+func (app *application) assignmentDeleteHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	assignmentid := r.PathValue("assignmentId")
+
+	err := app.services.AssignmentService.DeleteAssignment(assignmentid)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, nil, nil)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+}
+
+func (app *application) assignmentMediaUploadHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	assignmentid := r.PathValue("id")
+	// Parse the multipart form
+	err := r.ParseMultipartForm(10 << 20) // 10 MB maximum form size
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	// Retrieve the file(s) from the form
+	files := r.MultipartForm.File["files"]
+
+	err = app.services.MediaService.CheckQuota(
+		&models.Media{
+			AttributionsByType: map[string]string{"assignment": assignmentid},
+		},
+		uploadSize(files),
+	)
+	if err != nil {
+		app.quotaResponse(w, r, err)
+		return
+	}
+
+	policy := domain.NewFilePolicy()
+
+	for _, fileHeader := range files {
+		// Open the uploaded file
+		file, err := fileHeader.Open()
+		if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+		defer file.Close()
+
+		ft, err := policy.Inspect(fileHeader.Filename, file, fileHeader.Size)
+		if err != nil {
+			app.unsupportedMediaTypeResponse(w, r, err)
+			return
+		}
+
+		path, scan, err := app.services.FileService.Save(fileHeader.Filename, file)
+		if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+		media := &models.Media{
+			FileName:           fileHeader.Filename,
+			AttributionsByType: make(map[string]string),
+			FileType:           ft,
+			FilePath:           path,
+			Size:               fileHeader.Size,
+			ScanResult:         *scan,
+		}
+		if scan.Quarantined() {
+			app.logger.Printf("Quarantined upload %s: %s", path, scan.Threat)
+		}
+		media.AttributionsByType["assignment"] = assignmentid
+		media, err = app.services.MediaService.AddAssignmentMedia(media)
+		if err != nil {
+			app.serverError(w, r, err)
+		}
+	}
+	err = app.writeJSON(w, http.StatusOK, nil, nil)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+}
+
+// REQUEST: media id
+// RESPONSE: assignment media
+func (app *application) mediaDownloadHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	mediaid := r.PathValue("mediaId")
+
+	media, err := app.services.MediaService.GetMedia(mediaid)
+	if err != nil {
+		if errors.Is(err, dal.ERR_RECORD_NOT_FOUND) {
+			app.notFoundResponse(w, r)
+			return
+		}
+		app.serverError(w, r, err)
+		return
+	}
+
+	app.serveMedia(w, r, media, "attachment", cacheImmutable)
+}
+
+// Submission handlers
+//
+// REQUEST: assignmentid + userid
+// RESPONSE: submission
+func (app *application) submissionCreateHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	assignmentid := r.PathValue("assignmentId")
+
+	var input struct {
+		Token string `json:"token"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	userId, err := app.services.AuthenticationService.GetNetIdFromToken(input.Token)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	submission := &models.Submission{
+		AssignmentId: assignmentid,
+		User: models.User{
+			Entity: models.Entity{
+				ID: userId,
+			},
+		},
+	}
+
+	app.logger.Printf("Submission create handler, creating submission: %+v...", submission)
+
+	// Add submission into database and return submission with ID
+	submission, err = app.services.SubmissionService.CreateSubmission(submission)
+	if err != nil {
+		app.submissionErrorResponse(w, r, err)
+		return
+	}
+
+	res := jsonWrap{"submission": submission} // Return submission
+	err = app.writeJSON(w, http.StatusOK, res, nil)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+}
+
+// teachersubmissionReadHandler reads a submission from teacher view.
+// Anonymously graded assignments are read by the student's pseudonym
+// until grades are released.
+// REQUEST: netid or pseudonym + assignmentid
+// RESPONSE: submission
+func (app *application) teachersubmissionReadHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	assignmentId := r.PathValue("assignmentId")
+	userId := r.PathValue("userId")
+	app.logger.Printf("Teacher submission read handler, reading student (%s) submission for assignment: %s as teacher...", userId, assignmentId)
+
+	submission, err := app.services.SubmissionService.GradingSubmission(userId, assignmentId)
+	if err != nil {
+		app.submissionErrorResponse(w, r, err)
+		return
+	}
+
+	res := jsonWrap{"submission": submission} // Return submission
+	err = app.writeJSON(w, http.StatusOK, res, nil)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+}
+
+// StudentsubmissionReadHandler reads a submission from student view
+// REQUEST: assignmentid + token
+// RESPONSE: submission
+func (app *application) studentsubmissionReadHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	assignmentId := r.PathValue("assignmentId")
+
+	var input struct {
+		Token string `json:"token"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	app.logger.Printf("Student submission read handler, getting token: %s and assignment id: %s...", input.Token, assignmentId)
+
+	userId, err := app.services.AuthenticationService.GetNetIdFromToken(input.Token)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	submission, err := app.services.SubmissionService.StudentSubmission(userId, assignmentId)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	app.logger.Printf("Student submission read handler, got student's submission: %+v", submission)
+
+	res := jsonWrap{"submission": submission} // Return submission
+	err = app.writeJSON(w, http.StatusOK, res, nil)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+}
+
+// SubmissionUpdateHandler handles multiple submisisons
+// REQUEST: submission id + feedback + grade
+func (app *application) submissionUpdateHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	submissionid := r.PathValue("id")
+	app.logger.Printf("Submission update handler, updating submission: %s...", submissionid)
+	var input struct {
+		Grade    int    `json:"grade"`
+		Feedback string `json:"feedback"`
+	}
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	app.logger.Printf("Submission update handler, grading submission with grade: %d and feedback: %s...", input.Grade, input.Feedback)
+
+	submission, err := app.services.SubmissionService.GradeSubmission(input.Grade, input.Feedback, submissionid)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	res := jsonWrap{"submission": submission} // Return submission
+	err = app.writeJSON(w, http.StatusOK, res, nil)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+}
+
+// SubmissionDeleteHandler deletes a submission
+// REQUEST: submissionid
+// RESPONSE: 200 or 404
+func (app *application) submissionDeleteHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	submissionid := r.PathValue("id")
+
+	err := app.services.SubmissionService.DeleteSubmission(submissionid)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	err = app.writeJSON(w, http.StatusOK, nil, nil)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+}
+
+// attemptListHandler lists a student's attempts at an assignment, with
+// the files of each. Students may list their own attempts, and
+// teachers anyone's.
+//
+// REQUEST: assignmentId, student's netid, token
+// RESPONSE: attempts
+func (app *application) attemptListHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	assignmentId := r.PathValue("assignmentId")
+	studentId := r.PathValue("userId")
+
+	netId, err := app.services.AuthenticationService.GetNetIdFromToken(
+		r.Header.Get("Authorization"),
+	)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	attempts, err := app.services.SubmissionService.ListAttempts(
+		assignmentId,
+		studentId,
+		netId,
+	)
+	if err != nil {
+		app.submissionErrorResponse(w, r, err)
+		return
+	}
+
+	res := jsonWrap{"attempts": attempts}
+
+	err = app.writeJSON(w, http.StatusOK, res, nil)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+}
+
+// attemptDiffHandler compares two of a student's attempts at an
+// assignment, given by their numbers in the from and to query
+// parameters.
+//
+// REQUEST: assignmentId, student's netid, from, to, token
+// RESPONSE: diff
+func (app *application) attemptDiffHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	assignmentId := r.PathValue("assignmentId")
+	studentId := r.PathValue("userId")
+
+	from, err := strconv.Atoi(r.URL.Query().Get("from"))
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	to, err := strconv.Atoi(r.URL.Query().Get("to"))
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	netId, err := app.services.AuthenticationService.GetNetIdFromToken(
+		r.Header.Get("Authorization"),
+	)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	diff, err := app.services.SubmissionService.DiffAttempts(
+		assignmentId,
+		studentId,
+		netId,
+		from,
+		to,
+	)
+	if err != nil {
+		app.submissionErrorResponse(w, r, err)
+		return
+	}
+
+	res := jsonWrap{"diff": diff}
+
+	err = app.writeJSON(w, http.StatusOK, res, nil)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+}
+
+// attemptSelectHandler lets a teacher choose which of a student's
+// attempts counts towards their grade. An attempt of 0 goes back to
+// the assignment's own selection.
+//
+// REQUEST: assignmentId, student's netid, token, attempt
+// RESPONSE: submission
+func (app *application) attemptSelectHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	assignmentId := r.PathValue("assignmentId")
+	studentId := r.PathValue("userId")
+
+	var input struct {
+		Token   string `json:"token"`
+		Attempt int    `json:"attempt"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if input.Attempt < 0 {
+		app.failedValidationResponse(
+			w,
+			r,
+			map[string]string{"attempt": "must not be negative"},
+		)
+		return
+	}
+
+	netId, err := app.services.AuthenticationService.GetNetIdFromToken(input.Token)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	submission, err := app.services.SubmissionService.SelectAttempt(
+		assignmentId,
+		studentId,
+		netId,
+		input.Attempt,
+	)
+	if err != nil {
+		app.submissionErrorResponse(w, r, err)
+		return
+	}
+
+	res := jsonWrap{"submission": submission}
+
+	err = app.writeJSON(w, http.StatusOK, res, nil)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+}
+
+func (app *application) submissionMediaUploadHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	submissionid := r.PathValue("id")
+
+	app.logger.Printf("Submission media upload handler, uploading submission media to submissionid: %s...", submissionid)
+
+	// Parse the multipart form
+	err := r.ParseMultipartForm(10 << 20) // 10 MB maximum form size
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	assignmentId, err := app.services.SubmissionService.GetAssignmentId(submissionid)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	policy, err := app.services.AssignmentService.FilePolicy(assignmentId)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	// Retrieve the file(s) from the form
+	files := r.MultipartForm.File["files"]
+
+	// Every file is checked against the assignment's policy before
+	// any of them are saved, so a rejected upload leaves nothing behind.
+	fileTypes := make([]models.FileType, len(files))
+	for i, fileHeader := range files {
+		file, err := fileHeader.Open()
+		if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+
+		fileTypes[i], err = policy.Inspect(fileHeader.Filename, file, fileHeader.Size)
+		file.Close()
+		if err != nil {
+			app.unsupportedMediaTypeResponse(w, r, err)
+			return
+		}
+	}
+
+	err = app.services.MediaService.CheckQuota(
+		&models.Media{
+			AttributionsByType: map[string]string{"submission": submissionid},
+		},
+		uploadSize(files),
+	)
+	if err != nil {
+		app.quotaResponse(w, r, err)
+		return
+	}
+
+	for i, fileHeader := range files {
+		// Open the uploaded file
+
+		file, err := fileHeader.Open()
+		if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+		defer file.Close()
+		// Files are saved under the submission's ID, so that a file
+		// uploaded again in a later attempt does not replace the
+		// earlier attempt's file.
+		fileName := fmt.Sprintf("%s_%s", submissionid, fileHeader.Filename)
+		path, scan, err := app.services.FileService.Save(fileName, file)
+		if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+		checksum, err := app.services.FileService.Checksum(path)
+		if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+		media := &models.Media{
+			FileName:           fileHeader.Filename,
+			AttributionsByType: make(map[string]string),
+			FileType:           fileTypes[i],
+			FilePath:           path,
+			Size:               fileHeader.Size,
+			Checksum:           checksum,
+			ScanResult:         *scan,
+		}
+		if scan.Quarantined() {
+			app.logger.Printf("Quarantined upload %s: %s", path, scan.Threat)
+		}
+		media.AttributionsByType["submission"] = submissionid
+		media, err = app.services.MediaService.AddSubmissionMedia(media)
+		if err != nil {
+			app.serverError(w, r, err)
+		}
+	}
+
+	// Submissions to assignments with a test harness are graded once
+	// their files are uploaded.
+	_, err = app.services.AutogradeService.Enqueue(submissionid)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, nil, nil)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+}
+
+func (app *application) addStudentHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	var input struct {
+		NetId    string `json:"netid"`
+		CourseId string `json:"courseid"`
+	}
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	user, err := app.services.UserService.GetByID(input.NetId)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	for _, course := range user.Courses {
+		if course == input.CourseId {
+			res := jsonWrap{"response": "User is already enrolled"}
+			err = app.writeJSON(w, http.StatusOK, res, nil)
+			if err != nil {
+				app.serverError(w, r, err)
+				return
+			}
+		}
+	}
+
+	// User is not enrolled in the course
+	_, err = app.services.CourseService.AddToRoster(input.CourseId, input.NetId)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	res := jsonWrap{"response": "user successfully added to course"}
+	err = app.writeJSON(w, http.StatusOK, res, nil)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+}
+
+func (app *application) deleteStudentHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	netId := r.PathValue("netId")
+	courseId := r.PathValue("courseId")
+
+	err := app.services.CourseService.RemoveFromRoster(courseId, netId)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	err = app.writeJSON(w, http.StatusOK, nil, nil)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+}
+
+// sendOfflineTemplate receives a request from a user about
+// an offline grading template. It then prepares the template and sends
+// it back to the client who requested it.
+func (app *application) sendOfflineTemplate(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	var path string
+
+	courseId := r.PathValue("id")
+	assignmentId := r.PathValue("post")
+
+	app.logger.Printf("Send offline template, retrieving submissions with assignment id: %s and course id: %s", assignmentId, courseId)
+
+	// Get submissions of this assignment from database.
+	submissions, err := app.services.SubmissionService.GetSubmissions(assignmentId)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	app.logger.Printf("Send offline template, retrieved submissions: %v", submissions)
+
+	// Generate file name for Excel.
+	fileName := fmt.Sprintf("submissions_%s_%s.xlsx", courseId, assignmentId)
+
+	path = app.services.FileService.Path()
+
+	// Prepare the Excel file for transit. WriteSubmissions
+	// saves the file to where it needs to be saved.
+	path, err = app.services.ExcelService.WriteSubmissions(
+		path,
+		fileName,
+		submissions,
+	)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	app.logger.Printf("Send offline template, saved excel file to %s", path)
+
+	// With this path, send over the file.
+	w.Header().Set(
+		"Content-Type",
+		"application/vnd.openxmlformats-officedocument.spreadsheet",
+	)
+
+	headerValue := fmt.Sprintf(`attachment; filename="%s.xlsx"`, fileName)
+
+	w.Header().Set(
+		"Content-Disposition",
+		headerValue,
+	)
+
+	err = app.services.ExcelService.SendFile(path, w)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+}
+
+// submissionArchiveHandler streams a ZIP archive of every submission's
+// media for an assignment, so that a teacher can grade offline
+// without downloading each file on its own. The grading spreadsheet
+// is bundled into the archive when the spreadsheet query parameter
+// is set to true.
+//
+// REQUEST: course id + assignment id
+// RESPONSE: ZIP archive
+func (app *application) submissionArchiveHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	courseId := r.PathValue("id")
+	assignmentId := r.PathValue("post")
+
+	entries, err := app.services.ArchiveService.SubmissionEntries(assignmentId)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	fileName := fmt.Sprintf("submissions_%s_%s", courseId, assignmentId)
+
+	if r.URL.Query().Get("spreadsheet") == "true" {
+		submissions, err := app.services.SubmissionService.GetSubmissions(assignmentId)
+		if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+
+		path, err := app.services.ExcelService.WriteSubmissions(
+			app.services.FileService.Path(),
+			fileName+".xlsx",
+			submissions,
+		)
+		if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+
+		entries = append(
+			entries, domain.ArchiveEntry{
+				Name: fileName + ".xlsx",
+				Path: path,
+			},
+		)
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set(
+		"Content-Disposition",
+		fmt.Sprintf(`attachment; filename="%s.zip"`, fileName),
+	)
+
+	// The archive is written straight to the response, so once it has
+	// started there is no way to send back an error status. Log it so
+	// that a truncated download can be traced.
+	err = app.services.ArchiveService.Write(w, entries)
+	if err != nil {
+		app.logError(r, err)
+	}
+}
+
+// addOfflineGrading will receive an incoming template and will
+// sort the itemized template submissions and input them into
+// the database
+func (app *application) receiveOfflineGrades(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	app.logger.Printf("Receive offline grades...")
+
+	// Limits the upload size to 10MB.
+	err := r.ParseMultipartForm(10 << 20)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	f, handler, err := r.FormFile("files")
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	defer f.Close()
+
+	// Check file type.
+	_, err = domain.NewFilePolicy(models.XLSX).Inspect(
+		handler.Filename,
+		f,
+		handler.Size,
+	)
+	if err != nil {
+		app.unsupportedMediaTypeResponse(w, r, err)
+		return
+	}
+
+	// Save the file to disk.
+	path, scan, err := app.services.FileService.Save(handler.Filename, f)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	if scan.Quarantined() {
+		app.quarantinedResponse(w, r, scan)
+		return
+	}
+
+	app.logger.Printf("Receive offline grades, saving excel file to disk with path: %s...", path)
+
+	// Get the submissions from the Excel file via path.
+	submissions, err := app.services.ExcelService.ReadSubmissions(path)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	app.logger.Printf("Receive offline grades, retrieving submissions from excel file :%+v", submissions)
+
+	// Update the submission records in the database.
+	err = app.services.SubmissionService.UpdateSubmissions(submissions)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	app.logger.Printf("Receive offline grades, updated submissions...")
+
+	for _, submission := range submissions {
+		sub, err := app.services.SubmissionService.GetSubmission(submission.ID)
+		if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+		app.logger.Printf("Updated submission grade: %f and feedback: %s", sub.Grade, sub.Feedback)
+	}
+	// All is well.
+	err = app.writeJSON(w, http.StatusOK, nil, nil)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+}
+
+// notificationListHandler retrieves the user's notifications, newest
+// first.
+//
+// REQUEST: token
+// RESPONSE: notifications
+func (app *application) notificationListHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	netId, err := app.services.AuthenticationService.GetNetIdFromToken(
+		r.Header.Get("Authorization"),
+	)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	notifications, err := app.services.NotificationService.ListNotifications(netId)
+	if err != nil {
+		app.submissionErrorResponse(w, r, err)
+		return
+	}
+
+	res := jsonWrap{"notifications": notifications}
+
+	err = app.writeJSON(w, http.StatusOK, res, nil)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+}
+
+// notificationReadHandler marks one of the user's notifications as
+// read.
+//
+// REQUEST: notificationId, token
+// RESPONSE: notification id
+func (app *application) notificationReadHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	notificationId := r.PathValue("notificationId")
+
+	var input struct {
+		Token string `json:"token"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	netId, err := app.services.AuthenticationService.GetNetIdFromToken(input.Token)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	err = app.services.NotificationService.MarkRead(notificationId, netId)
+	if err != nil {
+		app.submissionErrorResponse(w, r, err)
+		return
+	}
+
+	res := jsonWrap{"notification_id": notificationId}
+
+	err = app.writeJSON(w, http.StatusOK, res, nil)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+}
+
+// Regrade handlers, for students disputing their grades.
+
+// regradeCreateHandler lets a student request a regrade of one of
+// their graded submissions, saying why.
+//
+// REQUEST: submission ID, token, justification
+// RESPONSE: regrade request
+func (app *application) regradeCreateHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	submissionId := r.PathValue("id")
+
+	var input struct {
+		Token         string `json:"token"`
+		Justification string `json:"justification"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if strings.TrimSpace(input.Justification) == "" {
+		app.failedValidationResponse(w, r, map[string]string{"justification": "must be provided"})
+		return
+	}
+
+	netId, err := app.services.AuthenticationService.GetNetIdFromToken(input.Token)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	regrade, err := app.services.SubmissionService.RequestRegrade(
+		submissionId,
+		netId,
+		input.Justification,
+	)
+	if err != nil {
+		app.submissionErrorResponse(w, r, err)
+		return
+	}
+
+	res := jsonWrap{"regrade": regrade}
+
+	err = app.writeJSON(w, http.StatusCreated, res, nil)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+}
+
+// regradeListHandler sends back the regrade requests for an
+// assignment with their history. Teachers get every request, and
+// students their own.
+//
+// REQUEST: courseId, assignmentId, token
+// RESPONSE: regrade requests
+func (app *application) regradeListHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	assignmentId := r.PathValue("assignmentId")
+
+	netId, err := app.services.AuthenticationService.GetNetIdFromToken(
+		r.Header.Get("Authorization"),
+	)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	regrades, err := app.services.SubmissionService.ListRegrades(
+		assignmentId,
+		netId,
+	)
+	if err != nil {
+		app.submissionErrorResponse(w, r, err)
+		return
+	}
+
+	res := jsonWrap{"regrades": regrades}
+
+	err = app.writeJSON(w, http.StatusOK, res, nil)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+}
+
+// regradeAcceptHandler lets a teacher accept a regrade request,
+// giving the submission a new grade.
+//
+// REQUEST: regradeId, token, grade, note
+// RESPONSE: regrade request
+func (app *application) regradeAcceptHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	regradeId := r.PathValue("regradeId")
+
+	var input struct {
+		Token string `json:"token"`
+		Grade int    `json:"grade"`
+		Note  string `json:"note"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if input.Grade < 0 {
+		app.failedValidationResponse(w, r, map[string]string{"grade": "must not be negative"})
+		return
+	}
+
+	netId, err := app.services.AuthenticationService.GetNetIdFromToken(input.Token)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	regrade, err := app.services.SubmissionService.AcceptRegrade(
+		regradeId,
+		netId,
+		input.Grade,
+		input.Note,
+	)
+	if err != nil {
+		app.submissionErrorResponse(w, r, err)
+		return
+	}
+
+	res := jsonWrap{"regrade": regrade}
+
+	err = app.writeJSON(w, http.StatusOK, res, nil)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+}
+
+// regradeRejectHandler lets a teacher reject a regrade request,
+// saying why.
+//
+// REQUEST: regradeId, token, reason
+// RESPONSE: regrade request
+func (app *application) regradeRejectHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	regradeId := r.PathValue("regradeId")
+
+	var input struct {
+		Token  string `json:"token"`
+		Reason string `json:"reason"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if strings.TrimSpace(input.Reason) == "" {
+		app.failedValidationResponse(w, r, map[string]string{"reason": "must be provided"})
+		return
+	}
+
+	netId, err := app.services.AuthenticationService.GetNetIdFromToken(input.Token)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	regrade, err := app.services.SubmissionService.RejectRegrade(
+		regradeId,
+		netId,
+		input.Reason,
+	)
+	if err != nil {
+		app.submissionErrorResponse(w, r, err)
+		return
+	}
+
+	res := jsonWrap{"regrade": regrade}
+
+	err = app.writeJSON(w, http.StatusOK, res, nil)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+}
+
+// regradeEscalateHandler lets a teacher hand a regrade request to
+// another teacher of the course.
+//
+// REQUEST: regradeId, token, teacher's netid, note
+// RESPONSE: regrade request
+func (app *application) regradeEscalateHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	regradeId := r.PathValue("regradeId")
+
+	var input struct {
+		Token   string `json:"token"`
+		Teacher string `json:"teacher"`
+		Note    string `json:"note"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if input.Teacher == "" {
+		app.failedValidationResponse(w, r, map[string]string{"teacher": "must be provided"})
+		return
+	}
+
+	netId, err := app.services.AuthenticationService.GetNetIdFromToken(input.Token)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	regrade, err := app.services.SubmissionService.EscalateRegrade(
+		regradeId,
+		netId,
+		input.Teacher,
+		input.Note,
+	)
+	if err != nil {
+		app.submissionErrorResponse(w, r, err)
+		return
+	}
+
+	res := jsonWrap{"regrade": regrade}
+
+	err = app.writeJSON(w, http.StatusOK, res, nil)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+}
+
+// assignmentRegradeWindowHandler lets a teacher set how many seconds
+// after grades are released students may request a regrade. A window
+// of 0 has no limit.
+//
+// REQUEST: assignmentId, token, regrade window
+// RESPONSE: assignment
+func (app *application) assignmentRegradeWindowHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	assignmentId := r.PathValue("assignmentId")
+
+	var input struct {
+		Token         string `json:"token"`
+		RegradeWindow int    `json:"regrade_window"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if input.RegradeWindow < 0 {
+		app.failedValidationResponse(w, r, map[string]string{"regrade_window": "must not be negative"})
+		return
+	}
+
+	netId, err := app.services.AuthenticationService.GetNetIdFromToken(input.Token)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	assignment, err := app.services.AssignmentService.SetRegradeWindow(
+		assignmentId,
+		netId,
+		input.RegradeWindow,
+	)
+	if err != nil {
+		app.submissionErrorResponse(w, r, err)
+		return
+	}
+
+	res := jsonWrap{"assignment": assignment}
+
+	err = app.writeJSON(w, http.StatusOK, res, nil)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+}
+
+// Similarity report handlers, for finding copied text across
+// submissions.
+
+// similarityCreateHandler lets a teacher ask for the submissions to an
+// assignment to be compared for similar text, optionally along with
+// those to the assignments of past offerings. The report is made in
+// the background, so it is sent back while still pending.
+//
+// REQUEST: assignmentId, token, compare with
+// RESPONSE: similarity report
+func (app *application) similarityCreateHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	assignmentId := r.PathValue("assignmentId")
+
+	var input struct {
+		Token       string   `json:"token"`
+		CompareWith []string `json:"compare_with"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	netId, err := app.services.AuthenticationService.GetNetIdFromToken(input.Token)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	report, err := app.services.SimilarityService.RequestReport(
+		assignmentId,
+		netId,
+		input.CompareWith,
+	)
+	if err != nil {
+		app.discussionErrorResponse(w, r, err)
+		return
+	}
+
+	res := jsonWrap{"report": report}
+
+	err = app.writeJSON(w, http.StatusAccepted, res, nil)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+}
+
+// similarityListHandler sends back the similarity reports of an
+// assignment, without their pairs.
+//
+// REQUEST: courseId, assignmentId, token
+// RESPONSE: similarity reports
+func (app *application) similarityListHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	assignmentId := r.PathValue("assignmentId")
+
+	netId, err := app.services.AuthenticationService.GetNetIdFromToken(
+		r.Header.Get("Authorization"),
+	)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	reports, err := app.services.SimilarityService.ListReports(
+		assignmentId,
+		netId,
+	)
+	if err != nil {
+		app.discussionErrorResponse(w, r, err)
+		return
+	}
+
+	res := jsonWrap{"reports": reports}
+
+	err = app.writeJSON(w, http.StatusOK, res, nil)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+}
+
+// similarityReadHandler sends back a similarity report with its pairs
+// of submissions, the most similar first, and the passages each pair
+// shares.
+//
+// REQUEST: courseId, assignmentId, reportId, token
+// RESPONSE: similarity report
+func (app *application) similarityReadHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	reportId := r.PathValue("reportId")
+
+	netId, err := app.services.AuthenticationService.GetNetIdFromToken(
+		r.Header.Get("Authorization"),
+	)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	report, err := app.services.SimilarityService.Report(reportId, netId)
+	if err != nil {
+		app.discussionErrorResponse(w, r, err)
+		return
+	}
+
+	res := jsonWrap{"report": report}
+
+	err = app.writeJSON(w, http.StatusOK, res, nil)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+}
+
+// Autograding handlers, for testing programming submissions
+// automatically.
+
+// autogradeHarnessHandler lets a teacher set what an assignment's
+// submissions are tested with: a command run with the shell that
+// reports its tests in TAP on file descriptor 3, the files it needs,
+// which are unzipped when they are ZIP archives, and the limits it is
+// run with. The files of each submission are put in the submission
+// directory of the workspace that the command is run from, and the
+// command must run them with descriptor 3 closed.
+//
+// REQUEST: assignmentId, token, command, image, timeout, memory, files
+// RESPONSE: harness
+func (app *application) autogradeHarnessHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	assignmentId := r.PathValue("assignmentId")
+
+	netId, err := app.services.AuthenticationService.GetNetIdFromToken(
+		r.Header.Get("Authorization"),
+	)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	err = r.ParseMultipartForm(10 << 20) // 10 MB maximum form size
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	harness := &models.Harness{
+		AssignmentId: assignmentId,
+		Command:      r.FormValue("command"),
+		Image:        r.FormValue("image"),
+	}
+
+	errs := make(map[string]string)
+
+	for field, limit := range map[string]*int{
+		"timeout": &harness.Timeout,
+		"memory":  &harness.Memory,
+	} {
+		if v := r.FormValue(field); v != "" {
+			*limit, err = strconv.Atoi(v)
+			if err != nil {
+				errs[field] = "must be a whole number"
+			}
+		}
+	}
+
+	if len(errs) == 0 {
+		errs = harness.Valid()
+	}
+
+	if len(errs) > 0 {
+		app.failedValidationResponse(w, r, errs)
+		return
+	}
+
+	files := r.MultipartForm.File["files"]
+
+	err = app.services.MediaService.CheckQuota(
+		&models.Media{
+			AttributionsByType: map[string]string{"assignment": assignmentId},
+		},
+		uploadSize(files),
+	)
+	if err != nil {
+		app.quotaResponse(w, r, err)
+		return
+	}
+
+	for _, fileHeader := range files {
+		file, err := fileHeader.Open()
+		if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+
+		path, scan, err := app.services.FileService.Save(fileHeader.Filename, file)
+		file.Close()
+		if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+
+		if scan.Quarantined() {
+			app.logger.Printf("Quarantined upload %s: %s", path, scan.Threat)
+		}
+
+		harness.Files = append(
+			harness.Files, &models.Media{
+				FileName:           fileHeader.Filename,
+				AttributionsByType: map[string]string{"harness": assignmentId},
+				FileType:           models.FileTypeFromName(fileHeader.Filename),
+				FilePath:           path,
+				Size:               fileHeader.Size,
+				ScanResult:         *scan,
+			},
+		)
+	}
+
+	harness, err = app.services.AutogradeService.SetHarness(harness, netId)
+	if err != nil {
+		app.autogradeErrorResponse(w, r, err)
+		return
+	}
+
+	res := jsonWrap{"harness": harness}
+
+	err = app.writeJSON(w, http.StatusOK, res, nil)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+}
+
+// autogradeHarnessReadHandler sends back what an assignment's
+// submissions are tested with. Only the course's teachers may read it.
+//
+// REQUEST: courseId, assignmentId, token
+// RESPONSE: harness
+func (app *application) autogradeHarnessReadHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	assignmentId := r.PathValue("assignmentId")
+
+	netId, err := app.services.AuthenticationService.GetNetIdFromToken(
+		r.Header.Get("Authorization"),
+	)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	harness, err := app.services.AutogradeService.Harness(assignmentId, netId)
+	if err != nil {
+		app.autogradeErrorResponse(w, r, err)
+		return
+	}
+
+	res := jsonWrap{"harness": harness}
+
+	err = app.writeJSON(w, http.StatusOK, res, nil)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+}
+
+// autogradeRerunHandler lets a teacher queue a submission to be
+// graded again by its assignment's harness.
+//
+// REQUEST: submission ID, token
+// RESPONSE: job
+func (app *application) autogradeRerunHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	submissionId := r.PathValue("id")
+
+	var input struct {
+		Token string `json:"token"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	netId, err := app.services.AuthenticationService.GetNetIdFromToken(input.Token)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	job, err := app.services.AutogradeService.Rerun(submissionId, netId)
+	if err != nil {
+		app.autogradeErrorResponse(w, r, err)
+		return
+	}
+
+	res := jsonWrap{"job": job}
+
+	err = app.writeJSON(w, http.StatusAccepted, res, nil)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+}
+
+// autogradeResultsHandler sends back every time a submission was
+// graded by its assignment's harness, newest first, with the result
+// and output of each test. Students may read their own results, with
+// their score once grades are released.
+//
+// REQUEST: courseId, assignmentId, submissionId, token
+// RESPONSE: jobs
+func (app *application) autogradeResultsHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	submissionId := r.PathValue("submissionId")
+
+	netId, err := app.services.AuthenticationService.GetNetIdFromToken(
+		r.Header.Get("Authorization"),
+	)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	jobs, err := app.services.AutogradeService.Results(submissionId, netId)
+	if err != nil {
+		app.autogradeErrorResponse(w, r, err)
+		return
+	}
+
+	res := jsonWrap{"jobs": jobs}
+
+	err = app.writeJSON(w, http.StatusOK, res, nil)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+}
+
+// Annotation handlers, for teachers to mark up submitted PDFs.
+
+// annotationInput is what a teacher sends to make or change an
+// annotation.
+type annotationInput struct {
+	Token   string                `json:"token"`
+	MediaId string                `json:"media_id"`
+	Kind    models.AnnotationKind `json:"kind"`
+	Page    int                   `json:"page"`
+	Rects   []models.Rect         `json:"rects"`
+	Point   *models.Point         `json:"point"`
+	Strokes [][]models.Point      `json:"strokes"`
+	Comment string                `json:"comment"`
+	Color   string                `json:"color"`
+}
+
+func (in *annotationInput) annotation() *models.Annotation {
+	return &models.Annotation{
+		MediaId: in.MediaId,
+		Kind:    in.Kind,
+		Page:    in.Page,
+		Rects:   in.Rects,
+		Point:   in.Point,
+		Strokes: in.Strokes,
+		Comment: in.Comment,
+		Color:   in.Color,
+	}
+}
+
+// annotationCreateHandler lets a teacher mark a page of a PDF that was
+// submitted: highlighting text, pinning a comment to a point, or
+// drawing freehand. Positions are in PDF points from the bottom left
+// corner of the page.
+//
+// REQUEST: submission ID, token, media_id, kind, page, rects, point,
+// strokes, comment, color
+// RESPONSE: annotation
+func (app *application) annotationCreateHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	submissionId := r.PathValue("id")
+
+	var input annotationInput
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	netId, err := app.services.AuthenticationService.GetNetIdFromToken(input.Token)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	annotation := input.annotation()
+	annotation.SubmissionId = submissionId
+
+	errs := annotation.Valid()
+	if input.MediaId == "" {
+		errs["media_id"] = "must be provided"
+	}
+
+	if len(errs) > 0 {
+		app.failedValidationResponse(w, r, errs)
+		return
+	}
+
+	annotation, err = app.services.AnnotationService.CreateAnnotation(annotation, netId)
+	if err != nil {
+		app.annotationErrorResponse(w, r, err)
+		return
+	}
+
+	res := jsonWrap{"annotation": annotation}
+
+	err = app.writeJSON(w, http.StatusCreated, res, nil)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+}
+
+// annotationUpdateHandler lets a teacher change an annotation. It
+// stays on the same file.
+//
+// REQUEST: annotation ID, token, kind, page, rects, point, strokes,
+// comment, color
+// RESPONSE: annotation
+func (app *application) annotationUpdateHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	annotationId := r.PathValue("annotationId")
+
+	var input annotationInput
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	netId, err := app.services.AuthenticationService.GetNetIdFromToken(input.Token)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	annotation := input.annotation()
+	annotation.ID = annotationId
+
+	errs := annotation.Valid()
+	if len(errs) > 0 {
+		app.failedValidationResponse(w, r, errs)
+		return
+	}
+
+	annotation, err = app.services.AnnotationService.UpdateAnnotation(annotation, netId)
+	if err != nil {
+		app.annotationErrorResponse(w, r, err)
+		return
+	}
+
+	res := jsonWrap{"annotation": annotation}
+
+	err = app.writeJSON(w, http.StatusOK, res, nil)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+}
+
+// annotationDeleteHandler lets a teacher remove an annotation.
+//
+// REQUEST: annotation ID, token
+// RESPONSE: none
+func (app *application) annotationDeleteHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	annotationId := r.PathValue("annotationId")
+
+	netId, err := app.services.AuthenticationService.GetNetIdFromToken(
+		r.Header.Get("Authorization"),
+	)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	err = app.services.AnnotationService.DeleteAnnotation(annotationId, netId)
+	if err != nil {
+		app.annotationErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, nil, nil)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+}
+
+// annotationListHandler sends back the annotations on a submission's
+// files. Students see those on their own submissions once grades are
+// released.
+//
+// REQUEST: courseId, assignmentId, submissionId, token
+// RESPONSE: annotations
+func (app *application) annotationListHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	submissionId := r.PathValue("submissionId")
+
+	netId, err := app.services.AuthenticationService.GetNetIdFromToken(
+		r.Header.Get("Authorization"),
+	)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	annotations, err := app.services.AnnotationService.Annotations(submissionId, netId)
+	if err != nil {
+		app.annotationErrorResponse(w, r, err)
+		return
+	}
+
+	res := jsonWrap{"annotations": annotations}
+
+	err = app.writeJSON(w, http.StatusOK, res, nil)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+}
+
+// annotatedDownloadHandler sends a copy of a submitted PDF with its
+// annotations on it, to download alongside the original. The copy is
+// made when it is asked for, so it always has the latest annotations.
+//
+// REQUEST: courseId, assignmentId, submissionId, mediaId, token
+// RESPONSE: annotated PDF
+func (app *application) annotatedDownloadHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	submissionId := r.PathValue("submissionId")
+	mediaId := r.PathValue("mediaId")
+
+	netId, err := app.services.AuthenticationService.GetNetIdFromToken(
+		r.Header.Get("Authorization"),
+	)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	media, data, err := app.services.AnnotationService.AnnotatedPDF(
+		submissionId,
+		mediaId,
+		netId,
+	)
+	if err != nil {
+		app.annotationErrorResponse(w, r, err)
+		return
+	}
+
+	if media.Quarantined() {
+		app.quarantinedResponse(w, r, &media.ScanResult)
+		return
+	}
+
+	name := "annotated-" + media.DisplayName()
+
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Cache-Control", cacheRevalidate)
+	w.Header().Set(
+		"Content-Disposition",
+		mime.FormatMediaType("attachment", map[string]string{"filename": name}),
+	)
+
+	http.ServeContent(w, r, name, time.Time{}, bytes.NewReader(data))
+}
+
+// Feedback media handlers, for teachers to attach files to their
+// feedback on a submission.
+
+// feedbackMediaUploadHandler lets a teacher attach files to the
+// feedback on a submission, such as audio comments or marked up
+// documents. Students see them once grades are released.
+//
+// REQUEST: submission ID, token, files
+// RESPONSE: media
+func (app *application) feedbackMediaUploadHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	submissionId := r.PathValue("id")
+
+	netId, err := app.services.AuthenticationService.GetNetIdFromToken(
+		r.Header.Get("Authorization"),
+	)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	err = app.services.MediaService.CheckFeedback(submissionId, netId)
+	if err != nil {
+		app.discussionErrorResponse(w, r, err)
+		return
+	}
+
+	err = r.ParseMultipartForm(10 << 20) // 10 MB maximum form size
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	files := r.MultipartForm.File["files"]
+
+	fileTypes := make([]models.FileType, len(files))
+	for i, fileHeader := range files {
+		file, err := fileHeader.Open()
+		if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+
+		fileTypes[i], err = domain.FeedbackPolicy.Inspect(fileHeader.Filename, file, fileHeader.Size)
+		file.Close()
+		if err != nil {
+			app.unsupportedMediaTypeResponse(w, r, err)
+			return
+		}
+	}
+
+	err = app.services.MediaService.CheckQuota(
+		&models.Media{
+			AttributionsByType: map[string]string{"feedback": submissionId},
+		},
+		uploadSize(files),
+	)
+	if err != nil {
+		app.quotaResponse(w, r, err)
+		return
+	}
+
+	var uploaded []*models.Media
+
+	for i, fileHeader := range files {
+		file, err := fileHeader.Open()
+		if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+
+		fileName := fmt.Sprintf("%s_feedback_%s", submissionId, fileHeader.Filename)
+		path, scan, err := app.services.FileService.Save(fileName, file)
+		file.Close()
+		if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+
+		checksum, err := app.services.FileService.Checksum(path)
+		if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+
+		if scan.Quarantined() {
+			app.logger.Printf("Quarantined upload %s: %s", path, scan.Threat)
+		}
+
+		media := &models.Media{
+			FileName:           fileHeader.Filename,
+			AttributionsByType: map[string]string{"feedback": submissionId},
+			FileType:           fileTypes[i],
+			FilePath:           path,
+			Size:               fileHeader.Size,
+			Checksum:           checksum,
+			ScanResult:         *scan,
+		}
+
+		media, err = app.services.MediaService.AddFeedbackMedia(media, netId)
+		if err != nil {
+			app.discussionErrorResponse(w, r, err)
+			return
+		}
+
+		uploaded = append(uploaded, media)
+	}
+
+	res := jsonWrap{"media": uploaded}
+
+	err = app.writeJSON(w, http.StatusCreated, res, nil)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+}
+
+// feedbackMediaListHandler sends back the files attached to the
+// feedback on a submission. Students see those on their own
+// submissions once grades are released.
+//
+// REQUEST: courseId, assignmentId, submissionId, token
+// RESPONSE: media
+func (app *application) feedbackMediaListHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	submissionId := r.PathValue("submissionId")
+
+	netId, err := app.services.AuthenticationService.GetNetIdFromToken(
+		r.Header.Get("Authorization"),
+	)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	media, err := app.services.MediaService.FeedbackMedia(submissionId, netId)
+	if err != nil {
+		app.discussionErrorResponse(w, r, err)
+		return
 	}
 
-	dueDate, err := time.Parse("2006-01-02", input.DueDate)
+	res := jsonWrap{"media": media}
 
+	err = app.writeJSON(w, http.StatusOK, res, nil)
 	if err != nil {
 		app.serverError(w, r, err)
 		return
 	}
+}
 
-	assignment := &models.Assignment{
-		Post:    post,
-		DueDate: dueDate,
-	}
+// feedbackMediaDownloadHandler sends a file attached to the feedback
+// on a submission. Audio is sent to be played in place, and other
+// files to be downloaded.
+//
+// REQUEST: courseId, assignmentId, submissionId, mediaId, token
+// RESPONSE: feedback media
+func (app *application) feedbackMediaDownloadHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	submissionId := r.PathValue("submissionId")
+	mediaId := r.PathValue("mediaId")
 
-	assignment, err = app.services.AssignmentService.CreateAssignment(assignment)
+	netId, err := app.services.AuthenticationService.GetNetIdFromToken(
+		r.Header.Get("Authorization"),
+	)
 	if err != nil {
 		app.serverError(w, r, err)
 		return
 	}
-	res := jsonWrap{"assignment": assignment}
 
-	err = app.writeJSON(w, http.StatusOK, res, nil)
+	media, err := app.services.MediaService.FeedbackFile(submissionId, mediaId, netId)
 	if err != nil {
-		app.serverError(w, r, err)
+		app.discussionErrorResponse(w, r, err)
 		return
 	}
 
+	disposition := "attachment"
+	if media.FileType == models.M4A || media.FileType == models.MP3 {
+		disposition = "inline"
+	}
+
+	app.serveMedia(w, r, media, disposition, cacheRevalidate)
 }
 
-// assignmentReadHandler relays assignment data back to the requester. To read
-// one specific assignment, one must only request the UUID of an assignment.
+// feedbackMediaDeleteHandler lets a teacher take a file off the
+// feedback on a submission.
 //
-// REQUEST: uuid
-// RESPONSE: assignments
-func (app *application) assignmentReadHandler(
+// REQUEST: submission ID, media ID, token
+// RESPONSE: none
+func (app *application) feedbackMediaDeleteHandler(
 	w http.ResponseWriter,
 	r *http.Request,
 ) {
-	var input struct {
-		AssignmentId string `json:"assignment_id"`
-		Token        string `json:"token"`
-	}
-
-	courseId := r.PathValue("courseId")
-
-	switch r.Method {
-	// Retrieve multiple assignments if its a single GET request.
-	case http.MethodGet:
-		assignmentIds, err := app.services.AssignmentService.RetrieveAssignments(courseId)
-		if err != nil {
-			app.serverError(w, r, err)
-			return
-		}
+	submissionId := r.PathValue("id")
+	mediaId := r.PathValue("mediaId")
 
-		var assignments []models.Assignment
+	netId, err := app.services.AuthenticationService.GetNetIdFromToken(
+		r.Header.Get("Authorization"),
+	)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
 
-		for _, id := range assignmentIds {
-			assignment, err := app.services.AssignmentService.ReadAssignment(id)
-			if err != nil {
-				app.serverError(w, r, err)
-				return
-			}
-			assignments = append(assignments, *assignment)
-		}
+	err = app.services.MediaService.RemoveFeedbackMedia(submissionId, mediaId, netId)
+	if err != nil {
+		app.discussionErrorResponse(w, r, err)
+		return
+	}
 
-		res := jsonWrap{"assignments": assignments}
+	err = app.writeJSON(w, http.StatusOK, nil, nil)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+}
 
-		err = app.writeJSON(w, http.StatusOK, res, nil)
-		if err != nil {
-			app.serverError(w, r, err)
-			return
-		}
+// Module handlers, for teachers to organize a course into modules of
+// materials and assignments, and for students to work through them.
 
-	// If it's a post request, we can expect an input and body. Therefore,
-	// only retrieve a single Assignment.
-	case http.MethodPost:
-		err := app.readJSON(w, r, &input)
-		if err != nil {
-			app.serverError(w, r, err)
-			return
-		}
+// moduleInput is what a teacher sets on a module.
+type moduleInput struct {
+	Token         string   `json:"token"`
+	Title         string   `json:"title"`
+	Published     bool     `json:"published"`
+	Prerequisites []string `json:"prerequisites"`
+}
 
-		assignment, err := app.services.AssignmentService.ReadAssignment(
-			input.
-				AssignmentId,
-		)
-		if err != nil {
-			app.serverError(w, r, err)
-			return
-		}
+func (in *moduleInput) module() *models.Module {
+	prerequisites := in.Prerequisites
+	if prerequisites == nil {
+		prerequisites = []string{}
+	}
 
-		res := jsonWrap{"assignment": assignment}
+	return &models.Module{
+		Title:         in.Title,
+		Published:     in.Published,
+		Prerequisites: prerequisites,
+	}
+}
 
-		err = app.writeJSON(w, http.StatusOK, res, nil)
-		if err != nil {
-			app.serverError(w, r, err)
-			return
-		}
-		return
+// moduleItemInput is what a teacher sets on a module item.
+type moduleItemInput struct {
+	Token       string                `json:"token"`
+	Kind        models.ModuleItemKind `json:"kind"`
+	Title       string                `json:"title"`
+	RefId       string                `json:"ref_id"`
+	URL         string                `json:"url"`
+	Body        string                `json:"body"`
+	Requirement models.Requirement    `json:"requirement"`
+}
 
-	// Bad dog.
-	default:
-		app.serverError(w, r, fmt.Errorf("method %s not allowed", r.Method))
-		return
+func (in *moduleItemInput) item() *models.ModuleItem {
+	return &models.ModuleItem{
+		Kind:        in.Kind,
+		Title:       in.Title,
+		RefId:       in.RefId,
+		URL:         in.URL,
+		Body:        in.Body,
+		Requirement: in.Requirement,
 	}
 }
 
-// assignmentUpdateHandler updates the information of an assignment.
+// moduleCreateHandler lets a teacher add a module to the end of a
+// course.
 //
-// REQUEST: uuid, updated information, type (title, description, duedate)
-// RESPONSE: assignment
-func (app *application) assignmentUpdateHandler(
+// REQUEST: course ID, token, title, published, prerequisites
+// RESPONSE: module
+func (app *application) moduleCreateHandler(
 	w http.ResponseWriter,
 	r *http.Request,
 ) {
-	var input struct {
-		Uuid         string      `json:"uuid"`
-		UpdatedField interface{} `json:"updatedfield"`
-		Action       string      `json:"action"`
-	}
+	var input moduleInput
+
 	err := app.readJSON(w, r, &input)
 	if err != nil {
-		app.serverError(w, r, err)
+		app.badRequestResponse(w, r, err)
 		return
 	}
 
-	assignment, err := app.services.AssignmentService.UpdateAssignment(input.Uuid, input.UpdatedField, input.Action)
+	netId, err := app.services.AuthenticationService.GetNetIdFromToken(input.Token)
 	if err != nil {
 		app.serverError(w, r, err)
 		return
 	}
 
-	res := jsonWrap{"assignment": assignment}
+	m := input.module()
+	m.CourseId = r.PathValue("id")
 
-	err = app.writeJSON(w, http.StatusOK, res, nil)
+	if errs := m.Valid(); len(errs) > 0 {
+		app.failedValidationResponse(w, r, errs)
+		return
+	}
+
+	m, err = app.services.ModuleService.CreateModule(m, netId)
+	if err != nil {
+		app.moduleErrorResponse(w, r, err)
+		return
+	}
+
+	res := jsonWrap{"module": m}
+
+	err = app.writeJSON(w, http.StatusCreated, res, nil)
 	if err != nil {
 		app.serverError(w, r, err)
 		return
 	}
 }
 
-// assignmentDeleteHandler deletes an assignment.
+// moduleListHandler sends back a course's modules and their items, in
+// order. Students get the published modules, with their progress
+// through each.
 //
-// REQUEST: assignmentId
-// RESPONSE: updated list of assignments
-func (app *application) assignmentDeleteHandler(
+// REQUEST: course ID, token
+// RESPONSE: modules
+func (app *application) moduleListHandler(
 	w http.ResponseWriter,
 	r *http.Request,
 ) {
-	assignmentid := r.PathValue("assignmentId")
+	courseId := r.PathValue("id")
 
-	err := app.services.AssignmentService.DeleteAssignment(assignmentid)
+	netId, err := app.services.AuthenticationService.GetNetIdFromToken(
+		r.Header.Get("Authorization"),
+	)
 	if err != nil {
 		app.serverError(w, r, err)
 		return
 	}
 
-	err = app.writeJSON(w, http.StatusOK, nil, nil)
+	modules, err := app.services.ModuleService.Modules(courseId, netId)
 	if err != nil {
-		app.serverError(w, r, err)
+		app.moduleErrorResponse(w, r, err)
 		return
 	}
 
+	res := jsonWrap{"modules": modules}
+
+	err = app.writeJSON(w, http.StatusOK, res, nil)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
 }
 
-func (app *application) assignmentMediaUploadHandler(
+// moduleProgressHandler lets a teacher see how far each student is
+// through each published module of a course.
+//
+// REQUEST: course ID, token
+// RESPONSE: progress
+func (app *application) moduleProgressHandler(
 	w http.ResponseWriter,
 	r *http.Request,
 ) {
-	assignmentid := r.PathValue("id")
-	// Parse the multipart form
-	err := r.ParseMultipartForm(10 << 20) // 10 MB maximum form size
+	courseId := r.PathValue("id")
+
+	netId, err := app.services.AuthenticationService.GetNetIdFromToken(
+		r.Header.Get("Authorization"),
+	)
 	if err != nil {
 		app.serverError(w, r, err)
 		return
 	}
 
-	// Retrieve the file(s) from the form
-	files := r.MultipartForm.File["files"]
-
-	for _, fileHeader := range files {
-		// Open the uploaded file
-		file, err := fileHeader.Open()
-		if err != nil {
-			app.serverError(w, r, err)
-			return
-		}
-		defer file.Close()
-		path, err := app.services.FileService.Save(fileHeader.Filename, file)
-		if err != nil {
-			app.serverError(w, r, err)
-			return
-		}
-		media := &models.Media{
-			FileName:           fileHeader.Filename,
-			AttributionsByType: make(map[string]string),
-			FileType:           GetFileType(fileHeader.Filename),
-			FilePath:           path,
-		}
-		media.AttributionsByType["assignment"] = assignmentid
-		media, err = app.services.MediaService.AddAssignmentMedia(media)
-		if err != nil {
-			app.serverError(w, r, err)
-		}
+	progress, err := app.services.ModuleService.Progress(courseId, netId)
+	if err != nil {
+		app.moduleErrorResponse(w, r, err)
+		return
 	}
-	err = app.writeJSON(w, http.StatusOK, nil, nil)
+
+	res := jsonWrap{"progress": progress}
+
+	err = app.writeJSON(w, http.StatusOK, res, nil)
 	if err != nil {
 		app.serverError(w, r, err)
 		return
 	}
 }
 
-// REQUEST: media id
-// RESPONSE: assignment media
-func (app *application) mediaDownloadHandler(
+// moduleUpdateHandler lets a teacher change a module's title, publish
+// or unpublish it and change its prerequisites.
+//
+// REQUEST: module ID, token, title, published, prerequisites
+// RESPONSE: module
+func (app *application) moduleUpdateHandler(
 	w http.ResponseWriter,
 	r *http.Request,
 ) {
-	mediaid := r.PathValue("mediaId")
+	var input moduleInput
 
-	media, err := app.services.MediaService.GetMedia(mediaid)
+	err := app.readJSON(w, r, &input)
 	if err != nil {
-		app.serverError(w, r, err)
+		app.badRequestResponse(w, r, err)
+		return
 	}
-	file, err := app.services.FileService.GetFile(media.FilePath)
+
+	netId, err := app.services.AuthenticationService.GetNetIdFromToken(input.Token)
 	if err != nil {
 		app.serverError(w, r, err)
+		return
 	}
-	defer file.Close()
 
-	// Get file information (size and name)
-	fileInfo, err := file.Stat()
-	if err != nil {
-		app.serverError(w, r, err)
+	m := input.module()
+	m.ID = r.PathValue("moduleId")
+
+	if errs := m.Valid(); len(errs) > 0 {
+		app.failedValidationResponse(w, r, errs)
 		return
 	}
 
-	// Set Content-Type header based on file extension
-	contentType := mime.TypeByExtension(filepath.Ext(fileInfo.Name()))
-	if contentType == "" {
-		contentType = "application/octet-stream" // Default content type
+	m, err = app.services.ModuleService.UpdateModule(m, netId)
+	if err != nil {
+		app.moduleErrorResponse(w, r, err)
+		return
 	}
-	contentDisposition := fmt.Sprintf(`attachment; filename="%s"`, fileInfo.Name())
-	w.Header().Set("Content-Type", contentType)
-	w.Header().Set("Content-Disposition", contentDisposition)
 
-	// Serve the file's content
-	// http.ServeContent(w, r, fileInfo.Name(), fileInfo.ModTime(), file)
-	http.ServeFile(w, r, media.FilePath)
+	res := jsonWrap{"module": m}
+
+	err = app.writeJSON(w, http.StatusOK, res, nil)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
 }
 
-// Submission handlers
+// moduleMoveHandler lets a teacher move a module to a position in its
+// course, from 0.
 //
-// REQUEST: assignmentid + userid
-// RESPONSE: submission
-func (app *application) submissionCreateHandler(
+// REQUEST: module ID, token, position
+// RESPONSE: modules
+func (app *application) moduleMoveHandler(
 	w http.ResponseWriter,
 	r *http.Request,
 ) {
-	assignmentid := r.PathValue("assignmentId")
+	moduleId := r.PathValue("moduleId")
 
 	var input struct {
-		Token string `json:"token"`
+		Token    string `json:"token"`
+		Position int    `json:"position"`
 	}
 
 	err := app.readJSON(w, r, &input)
 	if err != nil {
-		app.serverError(w, r, err)
+		app.badRequestResponse(w, r, err)
 		return
 	}
 
-	userId, err := app.services.AuthenticationService.GetNetIdFromToken(input.Token)
+	netId, err := app.services.AuthenticationService.GetNetIdFromToken(input.Token)
 	if err != nil {
 		app.serverError(w, r, err)
 		return
 	}
 
-	submission := &models.Submission{
-		AssignmentId: assignmentid,
-		User: models.User{
-			Entity: models.Entity{
-				ID: userId,
-			},
-		},
+	modules, err := app.services.ModuleService.MoveModule(moduleId, input.Position, netId)
+	if err != nil {
+		app.moduleErrorResponse(w, r, err)
+		return
 	}
 
-	assignment, err := app.services.AssignmentService.ReadAssignment(assignmentid)
+	res := jsonWrap{"modules": modules}
+
+	err = app.writeJSON(w, http.StatusOK, res, nil)
 	if err != nil {
 		app.serverError(w, r, err)
 		return
 	}
-	submission.OnTime = submission.IsOnTime(assignment.DueDate)
+}
 
-	app.logger.Printf("Submission create handler, creating submission: %+v...", submission)
+// moduleDeleteHandler lets a teacher delete a module and its items.
+//
+// REQUEST: module ID, token
+// RESPONSE: none
+func (app *application) moduleDeleteHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	moduleId := r.PathValue("moduleId")
 
-	// Add submission into database and return submission with ID
-	submission, err = app.services.SubmissionService.CreateSubmission(submission)
+	netId, err := app.services.AuthenticationService.GetNetIdFromToken(
+		r.Header.Get("Authorization"),
+	)
 	if err != nil {
 		app.serverError(w, r, err)
 		return
 	}
 
-	res := jsonWrap{"submission": submission} // Return submission
-	err = app.writeJSON(w, http.StatusOK, res, nil)
+	err = app.services.ModuleService.DeleteModule(moduleId, netId)
+	if err != nil {
+		app.moduleErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, nil, nil)
 	if err != nil {
 		app.serverError(w, r, err)
 		return
 	}
 }
 
-// teachersubmissionReadHandler reads a submission from teacher view
-// REQUEST: netid + assignmentid
-// RESPONSE: submission
-func (app *application) teachersubmissionReadHandler(
+// moduleItemCreateHandler lets a teacher add an assignment,
+// announcement, link or page to the end of a module.
+//
+// REQUEST: module ID, token, kind, title, ref_id, url, body, requirement
+// RESPONSE: item
+func (app *application) moduleItemCreateHandler(
 	w http.ResponseWriter,
 	r *http.Request,
 ) {
-	assignmentId := r.PathValue("assignmentId")
-	userId := r.PathValue("userId")
-	app.logger.Printf("Teacher submission read handler, reading student (%s) submission for assignment: %s as teacher...", userId, assignmentId)
+	var input moduleItemInput
 
-	submission, err := app.services.SubmissionService.GetUserSubmission(userId, assignmentId)
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	netId, err := app.services.AuthenticationService.GetNetIdFromToken(input.Token)
 	if err != nil {
 		app.serverError(w, r, err)
 		return
 	}
 
-	res := jsonWrap{"submission": submission} // Return submission
-	err = app.writeJSON(w, http.StatusOK, res, nil)
+	item := input.item()
+	item.ModuleId = r.PathValue("moduleId")
+
+	if errs := item.Valid(); len(errs) > 0 {
+		app.failedValidationResponse(w, r, errs)
+		return
+	}
+
+	item, err = app.services.ModuleService.AddItem(item, netId)
+	if err != nil {
+		app.moduleErrorResponse(w, r, err)
+		return
+	}
+
+	res := jsonWrap{"item": item}
+
+	err = app.writeJSON(w, http.StatusCreated, res, nil)
 	if err != nil {
 		app.serverError(w, r, err)
 		return
 	}
-
 }
 
-// StudentsubmissionReadHandler reads a submission from student view
-// REQUEST: assignmentid + token
-// RESPONSE: submission
-func (app *application) studentsubmissionReadHandler(
+// moduleItemUploadHandler lets a teacher upload a file to the end of a
+// module. The item is titled after the file unless given a title.
+//
+// REQUEST: module ID, token, file, title, requirement
+// RESPONSE: item
+func (app *application) moduleItemUploadHandler(
 	w http.ResponseWriter,
 	r *http.Request,
 ) {
-	assignmentId := r.PathValue("assignmentId")
+	moduleId := r.PathValue("moduleId")
 
-	var input struct {
-		Token string `json:"token"`
-	}
-
-	err := app.readJSON(w, r, &input)
+	netId, err := app.services.AuthenticationService.GetNetIdFromToken(
+		r.Header.Get("Authorization"),
+	)
 	if err != nil {
 		app.serverError(w, r, err)
 		return
 	}
-	app.logger.Printf("Student submission read handler, getting token: %s and assignment id: %s...", input.Token, assignmentId)
 
-	userId, err := app.services.AuthenticationService.GetNetIdFromToken(input.Token)
+	courseId, err := app.services.ModuleService.CheckModule(moduleId, netId)
 	if err != nil {
-		app.serverError(w, r, err)
+		app.moduleErrorResponse(w, r, err)
 		return
 	}
 
-	submission, err := app.services.SubmissionService.GetUserSubmission(userId, assignmentId)
+	err = r.ParseMultipartForm(10 << 20) // 10 MB maximum form size
 	if err != nil {
-		app.serverError(w, r, err)
+		app.badRequestResponse(w, r, err)
 		return
 	}
 
-	app.logger.Printf("Student submission read handler, got student's submission: %+v", submission)
+	files := r.MultipartForm.File["file"]
+	if len(files) != 1 {
+		app.failedValidationResponse(w, r, map[string]string{"file": "must be one file"})
+		return
+	}
 
-	res := jsonWrap{"submission": submission} // Return submission
-	err = app.writeJSON(w, http.StatusOK, res, nil)
+	fileHeader := files[0]
+
+	item := &models.ModuleItem{
+		ModuleId:    moduleId,
+		Kind:        models.MODULE_FILE,
+		Title:       r.FormValue("title"),
+		Requirement: models.Requirement(r.FormValue("requirement")),
+	}
+
+	if item.Title == "" {
+		item.Title = fileHeader.Filename
+	}
+
+	if errs := item.Valid(); len(errs) > 0 {
+		app.failedValidationResponse(w, r, errs)
+		return
+	}
+
+	file, err := fileHeader.Open()
 	if err != nil {
 		app.serverError(w, r, err)
 		return
 	}
 
-}
+	fileType, err := domain.ModulePolicy.Inspect(fileHeader.Filename, file, fileHeader.Size)
+	file.Close()
+	if err != nil {
+		app.unsupportedMediaTypeResponse(w, r, err)
+		return
+	}
 
-// SubmissionUpdateHandler handles multiple submisisons
-// REQUEST: submission id + feedback + grade
-func (app *application) submissionUpdateHandler(
-	w http.ResponseWriter,
-	r *http.Request,
-) {
-	submissionid := r.PathValue("id")
-	app.logger.Printf("Submission update handler, updating submission: %s...", submissionid)
-	var input struct {
-		Grade    int    `json:"grade"`
-		Feedback string `json:"feedback"`
+	err = app.services.MediaService.CheckQuota(
+		&models.Media{
+			AttributionsByType: map[string]string{"course": courseId},
+		},
+		fileHeader.Size,
+	)
+	if err != nil {
+		app.quotaResponse(w, r, err)
+		return
 	}
-	err := app.readJSON(w, r, &input)
+
+	file, err = fileHeader.Open()
 	if err != nil {
 		app.serverError(w, r, err)
 		return
 	}
-	app.logger.Printf("Submission update handler, grading submission with grade: %d and feedback: %s...", input.Grade, input.Feedback)
 
-	submission, err := app.services.SubmissionService.GradeSubmission(input.Grade, input.Feedback, submissionid)
+	fileName := fmt.Sprintf("%s_module_%s", moduleId, fileHeader.Filename)
+	path, scan, err := app.services.FileService.Save(fileName, file)
+	file.Close()
 	if err != nil {
 		app.serverError(w, r, err)
 		return
 	}
-	res := jsonWrap{"submission": submission} // Return submission
-	err = app.writeJSON(w, http.StatusOK, res, nil)
+
+	checksum, err := app.services.FileService.Checksum(path)
 	if err != nil {
 		app.serverError(w, r, err)
 		return
 	}
-}
 
-// SubmissionDeleteHandler deletes a submission
-// REQUEST: submissionid
-// RESPONSE: 200 or 404
-func (app *application) submissionDeleteHandler(
-	w http.ResponseWriter,
-	r *http.Request,
-) {
-	submissionid := r.PathValue("id")
+	if scan.Quarantined() {
+		app.logger.Printf("Quarantined upload %s: %s", path, scan.Threat)
+	}
 
-	err := app.services.SubmissionService.DeleteSubmission(submissionid)
+	media := &models.Media{
+		FileName:           fileHeader.Filename,
+		AttributionsByType: map[string]string{"module": moduleId},
+		FileType:           fileType,
+		FilePath:           path,
+		Size:               fileHeader.Size,
+		Checksum:           checksum,
+		ScanResult:         *scan,
+	}
+
+	item, err = app.services.ModuleService.AddFile(media, item, netId)
 	if err != nil {
-		app.serverError(w, r, err)
+		app.moduleErrorResponse(w, r, err)
 		return
 	}
-	err = app.writeJSON(w, http.StatusOK, nil, nil)
+
+	res := jsonWrap{"item": item}
+
+	err = app.writeJSON(w, http.StatusCreated, res, nil)
 	if err != nil {
 		app.serverError(w, r, err)
 		return
 	}
 }
 
-func (app *application) submissionMediaUploadHandler(
+// moduleItemUpdateHandler lets a teacher change an item's title,
+// requirement and content. Its kind stays the same.
+//
+// REQUEST: item ID, token, kind, title, ref_id, url, body, requirement
+// RESPONSE: item
+func (app *application) moduleItemUpdateHandler(
 	w http.ResponseWriter,
 	r *http.Request,
 ) {
-	submissionid := r.PathValue("id")
+	var input moduleItemInput
 
-	app.logger.Printf("Submission media upload handler, uploading submission media to submissionid: %s...", submissionid)
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
 
-	// Parse the multipart form
-	err := r.ParseMultipartForm(10 << 20) // 10 MB maximum form size
+	netId, err := app.services.AuthenticationService.GetNetIdFromToken(input.Token)
 	if err != nil {
 		app.serverError(w, r, err)
 		return
 	}
 
-	// Retrieve the file(s) from the form
-	files := r.MultipartForm.File["files"]
-	for _, fileHeader := range files {
-		// Open the uploaded file
+	item := input.item()
+	item.ID = r.PathValue("itemId")
 
-		file, err := fileHeader.Open()
-		if err != nil {
-			app.serverError(w, r, err)
-			return
-		}
-		defer file.Close()
-		fileName := fileHeader.Filename
-		path, err := app.services.FileService.Save(fileName, file)
-		if err != nil {
-			app.serverError(w, r, err)
-			return
-		}
-		media := &models.Media{
-			FileName:           fileHeader.Filename,
-			AttributionsByType: make(map[string]string),
-			FileType:           GetFileType(fileHeader.Filename),
-			FilePath:           path,
-		}
-		media.AttributionsByType["submission"] = submissionid
-		media, err = app.services.MediaService.AddSubmissionMedia(media)
-		if err != nil {
-			app.serverError(w, r, err)
-		}
+	if errs := item.Valid(); len(errs) > 0 {
+		app.failedValidationResponse(w, r, errs)
+		return
 	}
-	err = app.writeJSON(w, http.StatusOK, nil, nil)
+
+	item, err = app.services.ModuleService.UpdateItem(item, netId)
+	if err != nil {
+		app.moduleErrorResponse(w, r, err)
+		return
+	}
+
+	res := jsonWrap{"item": item}
+
+	err = app.writeJSON(w, http.StatusOK, res, nil)
 	if err != nil {
 		app.serverError(w, r, err)
 		return
 	}
 }
 
-func (app *application) addStudentHandler(
+// moduleItemMoveHandler lets a teacher move an item to a position in
+// its module, or in another module of the course.
+//
+// REQUEST: item ID, token, module_id, position
+// RESPONSE: module
+func (app *application) moduleItemMoveHandler(
 	w http.ResponseWriter,
 	r *http.Request,
 ) {
+	itemId := r.PathValue("itemId")
+
 	var input struct {
-		NetId    string `json:"netid"`
-		CourseId string `json:"courseid"`
+		Token    string `json:"token"`
+		ModuleId string `json:"module_id"`
+		Position int    `json:"position"`
 	}
+
 	err := app.readJSON(w, r, &input)
 	if err != nil {
-		app.serverError(w, r, err)
+		app.badRequestResponse(w, r, err)
 		return
 	}
-	user, err := app.services.UserService.GetByID(input.NetId)
+
+	netId, err := app.services.AuthenticationService.GetNetIdFromToken(input.Token)
 	if err != nil {
 		app.serverError(w, r, err)
 		return
 	}
 
-	for _, course := range user.Courses {
-		if course == input.CourseId {
-			res := jsonWrap{"response": "User is already enrolled"}
-			err = app.writeJSON(w, http.StatusOK, res, nil)
-			if err != nil {
-				app.serverError(w, r, err)
-				return
-			}
-		}
-	}
-
-	// User is not enrolled in the course
-	_, err = app.services.CourseService.AddToRoster(input.CourseId, input.NetId)
+	m, err := app.services.ModuleService.MoveItem(
+		itemId,
+		input.ModuleId,
+		input.Position,
+		netId,
+	)
 	if err != nil {
-		app.serverError(w, r, err)
+		app.moduleErrorResponse(w, r, err)
 		return
 	}
 
-	res := jsonWrap{"response": "user successfully added to course"}
+	res := jsonWrap{"module": m}
+
 	err = app.writeJSON(w, http.StatusOK, res, nil)
 	if err != nil {
 		app.serverError(w, r, err)
@@ -1232,18 +6821,31 @@ func (app *application) addStudentHandler(
 	}
 }
 
-func (app *application) deleteStudentHandler(
+// moduleItemDeleteHandler lets a teacher take an item out of its
+// module.
+//
+// REQUEST: item ID, token
+// RESPONSE: none
+func (app *application) moduleItemDeleteHandler(
 	w http.ResponseWriter,
 	r *http.Request,
 ) {
-	netId := r.PathValue("netId")
-	courseId := r.PathValue("courseId")
+	itemId := r.PathValue("itemId")
 
-	err := app.services.CourseService.RemoveFromRoster(courseId, netId)
+	netId, err := app.services.AuthenticationService.GetNetIdFromToken(
+		r.Header.Get("Authorization"),
+	)
 	if err != nil {
 		app.serverError(w, r, err)
 		return
 	}
+
+	err = app.services.ModuleService.RemoveItem(itemId, netId)
+	if err != nil {
+		app.moduleErrorResponse(w, r, err)
+		return
+	}
+
 	err = app.writeJSON(w, http.StatusOK, nil, nil)
 	if err != nil {
 		app.serverError(w, r, err)
@@ -1251,132 +6853,113 @@ func (app *application) deleteStudentHandler(
 	}
 }
 
-// sendOfflineTemplate receives a request from a user about
-// an offline grading template. It then prepares the template and sends
-// it back to the client who requested it.
-func (app *application) sendOfflineTemplate(
+// moduleItemViewHandler opens an item of a module, which completes it
+// for students when it only has to be viewed.
+//
+// REQUEST: item ID, token
+// RESPONSE: item
+func (app *application) moduleItemViewHandler(
 	w http.ResponseWriter,
 	r *http.Request,
 ) {
-	var path string
+	itemId := r.PathValue("itemId")
 
-	courseId := r.PathValue("id")
-	assignmentId := r.PathValue("post")
-
-	app.logger.Printf("Send offline template, retrieving submissions with assignment id: %s and course id: %s", assignmentId, courseId)
+	var input struct {
+		Token string `json:"token"`
+	}
 
-	// Get submissions of this assignment from database.
-	submissions, err := app.services.SubmissionService.GetSubmissions(assignmentId)
+	err := app.readJSON(w, r, &input)
 	if err != nil {
-		app.serverError(w, r, err)
+		app.badRequestResponse(w, r, err)
 		return
 	}
-	app.logger.Printf("Send offline template, retrieved submissions: %v", submissions)
 
-	// Generate file name for Excel.
-	fileName := fmt.Sprintf("submissions_%s_%s.xlsx", courseId, assignmentId)
-
-	path = app.services.FileService.Path()
-
-	// Prepare the Excel file for transit. WriteSubmissions
-	// saves the file to where it needs to be saved.
-	path, err = app.services.ExcelService.WriteSubmissions(
-		path,
-		fileName,
-		submissions,
-	)
+	netId, err := app.services.AuthenticationService.GetNetIdFromToken(input.Token)
 	if err != nil {
 		app.serverError(w, r, err)
 		return
 	}
 
-	app.logger.Printf("Send offline template, saved excel file to %s", path)
-
-	// With this path, send over the file.
-	w.Header().Set(
-		"Content-Type",
-		"application/vnd.openxmlformats-officedocument.spreadsheet",
-	)
-
-	headerValue := fmt.Sprintf(`attachment; filename="%s.xlsx"`, fileName)
+	item, err := app.services.ModuleService.ViewItem(itemId, netId)
+	if err != nil {
+		app.moduleErrorResponse(w, r, err)
+		return
+	}
 
-	w.Header().Set(
-		"Content-Disposition",
-		headerValue,
-	)
+	res := jsonWrap{"item": item}
 
-	err = app.services.ExcelService.SendFile(path, w)
+	err = app.writeJSON(w, http.StatusOK, res, nil)
 	if err != nil {
 		app.serverError(w, r, err)
 		return
 	}
 }
 
-// addOfflineGrading will receive an incoming template and will
-// sort the itemized template submissions and input them into
-// the database
-func (app *application) receiveOfflineGrades(
+// moduleItemDoneHandler lets a student mark an item as done.
+//
+// REQUEST: item ID, token
+// RESPONSE: item
+func (app *application) moduleItemDoneHandler(
 	w http.ResponseWriter,
 	r *http.Request,
 ) {
-	app.logger.Printf("Receive offline grades...")
+	itemId := r.PathValue("itemId")
 
-	// Limits the upload size to 10MB.
-	err := r.ParseMultipartForm(10 << 20)
+	var input struct {
+		Token string `json:"token"`
+	}
+
+	err := app.readJSON(w, r, &input)
 	if err != nil {
-		app.serverError(w, r, err)
+		app.badRequestResponse(w, r, err)
 		return
 	}
 
-	f, handler, err := r.FormFile("files")
+	netId, err := app.services.AuthenticationService.GetNetIdFromToken(input.Token)
 	if err != nil {
 		app.serverError(w, r, err)
 		return
 	}
 
-	defer f.Close()
-
-	// Check file type.
-
-	// Save the file to disk.
-	path, err := app.services.FileService.Save(handler.Filename, f)
+	item, err := app.services.ModuleService.MarkDone(itemId, netId)
 	if err != nil {
-		app.serverError(w, r, err)
+		app.moduleErrorResponse(w, r, err)
 		return
 	}
 
-	app.logger.Printf("Receive offline grades, saving excel file to disk with path: %s...", path)
+	res := jsonWrap{"item": item}
 
-	// Get the submissions from the Excel file via path.
-	submissions, err := app.services.ExcelService.ReadSubmissions(path)
+	err = app.writeJSON(w, http.StatusOK, res, nil)
 	if err != nil {
 		app.serverError(w, r, err)
 		return
 	}
+}
 
-	app.logger.Printf("Receive offline grades, retrieving submissions from excel file :%+v", submissions)
+// moduleItemFileHandler sends the file of a module item, which views
+// the item.
+//
+// REQUEST: item ID, token
+// RESPONSE: file
+func (app *application) moduleItemFileHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	itemId := r.PathValue("itemId")
 
-	// Update the submission records in the database.
-	err = app.services.SubmissionService.UpdateSubmissions(submissions)
+	netId, err := app.services.AuthenticationService.GetNetIdFromToken(
+		r.Header.Get("Authorization"),
+	)
 	if err != nil {
 		app.serverError(w, r, err)
 		return
 	}
 
-	app.logger.Printf("Receive offline grades, updated submissions...")
-
-	for _, submission := range submissions {
-		sub, err := app.services.SubmissionService.GetSubmission(submission.ID)
-		if err != nil {
-			app.serverError(w, r, err)
-			return
-		}
-		app.logger.Printf("Updated submission grade: %f and feedback: %s", sub.Grade, sub.Feedback)
-	}
-	// All is well.
-	err = app.writeJSON(w, http.StatusOK, nil, nil)
+	media, err := app.services.ModuleService.ItemFile(itemId, netId)
 	if err != nil {
-		app.serverError(w, r, err)
+		app.moduleErrorResponse(w, r, err)
 		return
 	}
+
+	app.serveMedia(w, r, media, "attachment", cacheRevalidate)
 }
@@ -1,6 +1,18 @@
 package main
 
-import "testing"
+import (
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/n30w/Darkspace/internal/dal"
+	"github.com/n30w/Darkspace/internal/domain"
+	"github.com/n30w/Darkspace/internal/models"
+)
 
 func TestJsonBuilder(t *testing.T) {
 
@@ -82,3 +94,135 @@ func TestJsonBuilder(t *testing.T) {
 		}
 	})
 }
+
+func TestServeMedia(t *testing.T) {
+	volume := dal.NewLocalVolume(t.TempDir())
+
+	err := os.MkdirAll(volume.String(), 0o755)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+
+	content := "0123456789abcdefghij"
+	p := filepath.Join(volume.String(), "lecture.mp4")
+
+	err = os.WriteFile(p, []byte(content), 0o644)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+
+	app := &application{
+		logger: log.New(io.Discard, "", 0),
+		services: &domain.Service{
+			FileService: domain.NewFileService(volume),
+		},
+	}
+
+	media := &models.Media{FileType: models.MP4, FilePath: p}
+	media.ID = "m1"
+
+	serve := func(header http.Header) *http.Response {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		for k, v := range header {
+			r.Header[k] = v
+		}
+
+		w := httptest.NewRecorder()
+		app.serveMedia(w, r, media, "attachment", cacheImmutable)
+
+		return w.Result()
+	}
+
+	full := serve(nil)
+	etag := full.Header.Get("ETag")
+
+	t.Run("full content", func(t *testing.T) {
+		body, _ := io.ReadAll(full.Body)
+
+		if full.StatusCode != http.StatusOK || string(body) != content {
+			t.Errorf("got %d %q, want %d %q", full.StatusCode, body, http.StatusOK, content)
+		}
+
+		if etag == "" || etag[0] != '"' {
+			t.Errorf("got ETag %q, want a strong ETag", etag)
+		}
+
+		headers := map[string]string{
+			"Content-Type":        "video/mp4",
+			"Cache-Control":       cacheImmutable,
+			"Content-Disposition": `attachment; filename=lecture.mp4`,
+			"Accept-Ranges":       "bytes",
+		}
+		for k, want := range headers {
+			if got := full.Header.Get(k); got != want {
+				t.Errorf("%s: got %q, want %q", k, got, want)
+			}
+		}
+	})
+
+	t.Run("byte range", func(t *testing.T) {
+		res := serve(http.Header{"Range": {"bytes=5-9"}})
+		body, _ := io.ReadAll(res.Body)
+
+		if res.StatusCode != http.StatusPartialContent || string(body) != "56789" {
+			t.Errorf("got %d %q, want %d %q", res.StatusCode, body, http.StatusPartialContent, "56789")
+		}
+
+		want := "bytes 5-9/20"
+		if got := res.Header.Get("Content-Range"); got != want {
+			t.Errorf("got Content-Range %q, want %q", got, want)
+		}
+	})
+
+	t.Run("if-none-match", func(t *testing.T) {
+		res := serve(http.Header{"If-None-Match": {etag}})
+
+		if res.StatusCode != http.StatusNotModified {
+			t.Errorf("got %d, want %d", res.StatusCode, http.StatusNotModified)
+		}
+	})
+
+	t.Run("if-modified-since", func(t *testing.T) {
+		res := serve(http.Header{"If-Modified-Since": {full.Header.Get("Last-Modified")}})
+
+		if res.StatusCode != http.StatusNotModified {
+			t.Errorf("got %d, want %d", res.StatusCode, http.StatusNotModified)
+		}
+	})
+
+	t.Run("stale if-range", func(t *testing.T) {
+		res := serve(
+			http.Header{
+				"Range":    {"bytes=0-4"},
+				"If-Range": {`"stale"`},
+			},
+		)
+
+		if res.StatusCode != http.StatusOK {
+			t.Errorf("got %d, want %d", res.StatusCode, http.StatusOK)
+		}
+	})
+
+	t.Run("quarantined", func(t *testing.T) {
+		media := *media
+		media.ScanResult = models.ScanResult{Status: models.QUARANTINED, Threat: "Eicar-Signature"}
+
+		w := httptest.NewRecorder()
+		app.serveMedia(w, httptest.NewRequest(http.MethodGet, "/", nil), &media, "attachment", cacheImmutable)
+
+		if w.Code != http.StatusForbidden {
+			t.Errorf("got %d, want %d", w.Code, http.StatusForbidden)
+		}
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		media := &models.Media{FilePath: filepath.Join(volume.String(), "gone.mp4")}
+
+		w := httptest.NewRecorder()
+		app.serveMedia(w, httptest.NewRequest(http.MethodGet, "/", nil), media, "inline", cacheRevalidate)
+
+		if w.Code != http.StatusNotFound {
+			t.Errorf("got %d, want %d", w.Code, http.StatusNotFound)
+		}
+	})
+}
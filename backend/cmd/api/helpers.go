@@ -1,15 +1,21 @@
 package main
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"mime"
+	"mime/multipart"
 	"net/http"
-	"strings"
-	"github.com/n30w/Darkspace/internal/models"
 	"path/filepath"
+	"strconv"
+	"strings"
 
+	"github.com/n30w/Darkspace/internal/dal"
+	"github.com/n30w/Darkspace/internal/domain"
+	"github.com/n30w/Darkspace/internal/models"
 )
 
 // jsonWrap wraps a json message response before it gets sent out.
@@ -160,24 +166,211 @@ func jsonBuilder(data any) ([]byte, error) {
 }
 
 // File Helpers
+
+// optionalNetId returns the Net ID of the user whose token is in
+// the Authorization header, or an empty string if there is none.
+func (app *application) optionalNetId(r *http.Request) (string, error) {
+	token := r.Header.Get("Authorization")
+	if token == "" {
+		return "", nil
+	}
+
+	return app.services.AuthenticationService.GetNetIdFromToken(token)
+}
+
+// readFilters reads the page and page_size query parameters of a
+// request for a list, falling back to the defaults for missing
+// parameters. Invalid parameters are reported keyed by name.
+func readFilters(r *http.Request) (models.Filters, map[string]string) {
+	f := models.NewFilters()
+	errs := make(map[string]string)
+
+	qs := r.URL.Query()
+
+	for key, dst := range map[string]*int{
+		"page":      &f.Page,
+		"page_size": &f.PageSize,
+	} {
+		s := qs.Get(key)
+		if s == "" {
+			continue
+		}
+
+		i, err := strconv.Atoi(s)
+		if err != nil {
+			errs[key] = "must be an integer"
+			continue
+		}
+
+		*dst = i
+	}
+
+	for key, msg := range f.Valid() {
+		if _, ok := errs[key]; !ok {
+			errs[key] = msg
+		}
+	}
+
+	return f, errs
+}
+
+// uploadSize is the total size of a set of uploaded files in bytes.
+func uploadSize(files []*multipart.FileHeader) int64 {
+	var size int64
+	for _, f := range files {
+		size += f.Size
+	}
+	return size
+}
+
+// GetFileType returns the declared file type of a file name using
+// its extension. The content of the file is not inspected.
 func GetFileType(filename string) models.FileType {
-    extension := strings.ToLower(filepath.Ext(filename))
-    switch extension {
-    case ".jpg", ".jpeg":
-        return models.JPG
-    case ".png":
-        return models.PNG
-    case ".pdf":
-        return models.PDF
-    case ".m4a":
-        return models.M4A
-    case ".mp3":
-        return models.MP3
-    case ".txt":
-        return models.TXT
-    case ".xlsx":
-        return models.XLSX
-    default:
-        return models.NULL
-    }
-}
\ No newline at end of file
+	return models.FileTypeFromName(filename)
+}
+
+// saveImage writes every variant of a processed image to disk. The
+// full size variant is recorded using add, such as
+// MediaService.AddBanner, then the smaller variants are recorded as
+// media related to it. The full size media record is returned.
+func (app *application) saveImage(
+	name string,
+	img *domain.ProcessedImage,
+	media *models.Media,
+	add func(*models.Media) (*models.Media, error),
+) (*models.Media, error) {
+	ext := "." + img.FileType.String()
+
+	path, scan, err := app.services.FileService.Save(
+		name+ext,
+		bytes.NewReader(img.Variants[models.FULL]),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	media.ScanResult = *scan
+
+	media.FileType = img.FileType
+	media.FilePath = path
+	media.Variant = models.FULL
+	media.Size = int64(len(img.Variants[models.FULL]))
+
+	media, err = add(media)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, size := range []models.ImageSize{models.THUMBNAIL, models.CARD} {
+		path, scan, err := app.services.FileService.Save(
+			name+"_"+string(size)+ext,
+			bytes.NewReader(img.Variants[size]),
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		variant := &models.Media{
+			FileName:   media.FileName,
+			FileType:   img.FileType,
+			FilePath:   path,
+			Variant:    size,
+			Size:       int64(len(img.Variants[size])),
+			ScanResult: *scan,
+		}
+
+		_, err = app.services.MediaService.AddImageVariant(media, variant)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return media, nil
+}
+
+// Cache policies for media responses. Media requested by its own ID
+// never changes, so clients may keep it. Media requested through
+// something that can point elsewhere, such as a user's profile
+// picture, must be revalidated with its ETag before reuse.
+const (
+	cacheImmutable  = "private, max-age=31536000, immutable"
+	cacheRevalidate = "private, no-cache"
+)
+
+// serveImage writes an image to the response, to be displayed inline.
+// Media without a path is served from the volume's defaults.
+func (app *application) serveImage(
+	w http.ResponseWriter,
+	r *http.Request,
+	media *models.Media,
+	cacheControl string,
+) {
+	if media.FilePath == "" {
+		media.FilePath = app.services.FileService.Path() + "/defaults/" + media.FileName + "." + media.FileType.String()
+	}
+
+	app.logger.Printf("Serving image with file path: %s...", media.FilePath)
+
+	app.serveMedia(w, r, media, "inline", cacheControl)
+}
+
+// serveMedia streams media from the file store to the response.
+// Byte ranges, If-None-Match, If-Modified-Since and If-Range are
+// handled by http.ServeContent, using a strong ETag derived from
+// the media's identity. disposition is either "inline" or
+// "attachment".
+func (app *application) serveMedia(
+	w http.ResponseWriter,
+	r *http.Request,
+	media *models.Media,
+	disposition, cacheControl string,
+) {
+	if media.Quarantined() {
+		app.quarantinedResponse(w, r, &media.ScanResult)
+		return
+	}
+
+	f, blob, err := app.services.FileService.Open(media.FilePath)
+	if err != nil {
+		if errors.Is(err, dal.ERR_RECORD_NOT_FOUND) {
+			app.notFoundResponse(w, r)
+			return
+		}
+		app.serverError(w, r, err)
+		return
+	}
+
+	defer f.Close()
+
+	name := media.DisplayName()
+
+	// Without a Content-Type, ServeContent sniffs the content.
+	contentType := mime.TypeByExtension("." + media.FileType.String())
+	if contentType == "" {
+		contentType = mime.TypeByExtension(filepath.Ext(name))
+	}
+	if contentType != "" {
+		w.Header().Set("Content-Type", contentType)
+	}
+
+	w.Header().Set("ETag", mediaETag(media, blob))
+	w.Header().Set("Cache-Control", cacheControl)
+	w.Header().Set(
+		"Content-Disposition",
+		mime.FormatMediaType(disposition, map[string]string{"filename": name}),
+	)
+
+	http.ServeContent(w, r, name, blob.ModTime, f)
+}
+
+// mediaETag returns a strong ETag for media. The size and modification
+// time of the stored file are part of the tag, so that a file
+// rewritten under the same media ID is not mistaken for the old one.
+func mediaETag(media *models.Media, blob *models.Blob) string {
+	return fmt.Sprintf(
+		`"%s-%x-%x"`,
+		media.ID,
+		blob.ModTime.UnixNano(),
+		blob.Size,
+	)
+}
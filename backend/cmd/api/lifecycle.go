@@ -0,0 +1,33 @@
+package main
+
+import (
+	"time"
+)
+
+// advanceAssignments publishes and locks assignments on their
+// schedules, checking every interval. It is meant to be run in its own
+// goroutine, like releaseGrades.
+func (app *application) advanceAssignments() {
+	for {
+		time.Sleep(app.config.lifecycle.interval)
+
+		app.runLifecycle()
+	}
+}
+
+// runLifecycle publishes the drafts and locks the assignments that
+// are due, and logs how many of each there were.
+func (app *application) runLifecycle() {
+	published, locked, err := app.services.AssignmentService.AdvanceDue(time.Now())
+	if err != nil {
+		app.logger.Printf("Assignment schedule failed: %v", err)
+	}
+
+	if published > 0 || locked > 0 {
+		app.logger.Printf(
+			"Assignment schedule, published: %d, locked: %d",
+			published,
+			locked,
+		)
+	}
+}
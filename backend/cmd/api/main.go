@@ -4,10 +4,12 @@ import (
 	"flag"
 	"log"
 	"os"
+	"time"
 
 	"github.com/joho/godotenv"
 	"github.com/n30w/Darkspace/internal/dal"
 	"github.com/n30w/Darkspace/internal/domain"
+	"github.com/n30w/Darkspace/internal/models"
 )
 
 const version = "1.0.0"
@@ -64,10 +66,158 @@ func main() {
 		"Enable rate limiter",
 	)
 
+	// Storage garbage collector configurations.
+	flag.DurationVar(
+		&cfg.gc.interval,
+		"gc-interval",
+		24*time.Hour,
+		"Storage garbage collection interval",
+	)
+	flag.DurationVar(
+		&cfg.gc.grace,
+		"gc-grace",
+		72*time.Hour,
+		"Age unreferenced media must reach before it is collected",
+	)
+	flag.BoolVar(
+		&cfg.gc.dryRun,
+		"gc-dry-run",
+		false,
+		"Report storage garbage without deleting it",
+	)
+	flag.BoolVar(
+		&cfg.gc.enabled,
+		"gc-enabled",
+		true,
+		"Enable background storage garbage collection",
+	)
+	flag.BoolVar(
+		&cfg.gc.once,
+		"gc",
+		false,
+		"Run storage garbage collection once and exit",
+	)
+
+	// Scheduled grade release configurations.
+	flag.DurationVar(
+		&cfg.release.interval,
+		"release-interval",
+		time.Minute,
+		"How often scheduled grade releases are checked",
+	)
+	flag.BoolVar(
+		&cfg.release.enabled,
+		"release-enabled",
+		true,
+		"Enable scheduled grade releases",
+	)
+
+	// Scheduled assignment publishing and locking configurations.
+	flag.DurationVar(
+		&cfg.lifecycle.interval,
+		"lifecycle-interval",
+		time.Minute,
+		"How often assignments are checked for being due to be published or locked",
+	)
+	flag.BoolVar(
+		&cfg.lifecycle.enabled,
+		"lifecycle-enabled",
+		true,
+		"Enable scheduled publishing and locking of assignments",
+	)
+
+	// Peer review allocation configurations.
+	flag.DurationVar(
+		&cfg.peerReview.interval,
+		"peer-review-interval",
+		time.Minute,
+		"How often due assignments are checked for peer reviewers to allocate",
+	)
+	flag.BoolVar(
+		&cfg.peerReview.enabled,
+		"peer-review-enabled",
+		true,
+		"Enable peer reviewer allocation",
+	)
+
+	// Similarity report configurations.
+	flag.DurationVar(
+		&cfg.similarity.interval,
+		"similarity-interval",
+		time.Minute,
+		"How often similarity reports waiting to be made are checked",
+	)
+	flag.BoolVar(
+		&cfg.similarity.enabled,
+		"similarity-enabled",
+		true,
+		"Enable similarity reports",
+	)
+
+	// Autograder configurations.
+	flag.DurationVar(
+		&cfg.autograde.interval,
+		"autograde-interval",
+		10*time.Second,
+		"How often queued submissions are checked for grading",
+	)
+	flag.BoolVar(
+		&cfg.autograde.enabled,
+		"autograde-enabled",
+		false,
+		"Enable the autograder",
+	)
+	flag.StringVar(
+		&cfg.autograde.runtime,
+		"autograde-runtime",
+		"",
+		"Container runtime to run submissions with, such as docker. The autograder needs one",
+	)
+	flag.StringVar(
+		&cfg.autograde.image,
+		"autograde-image",
+		"alpine:3",
+		"Container image for harnesses that do not name one",
+	)
+
+	// Storage quota configurations.
+	flag.Int64Var(
+		&cfg.quota.user,
+		"quota-user",
+		512<<20,
+		"Storage quota per user in bytes, 0 for unlimited",
+	)
+	flag.Int64Var(
+		&cfg.quota.course,
+		"quota-course",
+		10<<30,
+		"Storage quota per course in bytes, 0 for unlimited",
+	)
+
+	// Malware scanner configurations.
+	flag.StringVar(
+		&cfg.scanner.clamd,
+		"clamd-address",
+		os.Getenv("CLAMD_ADDRESS"),
+		"ClamAV daemon host:port or socket path, empty to disable scanning",
+	)
+	flag.DurationVar(
+		&cfg.scanner.timeout,
+		"clamd-timeout",
+		30*time.Second,
+		"ClamAV daemon scan timeout",
+	)
+
 	flag.Parse()
 
 	logger := log.New(os.Stdout, "[DKSE] ", log.Ldate|log.Ltime)
 
+	// Submissions are only run in containers, since they are code
+	// that cannot be trusted with the server's files.
+	if cfg.autograde.enabled && cfg.autograde.runtime == "" {
+		logger.Fatal("the autograder needs a container runtime, set with -autograde-runtime")
+	}
+
 	cfg.db.Driver = "postgres"
 
 	// Set config database parameters via environment variables.
@@ -100,6 +250,58 @@ func main() {
 		logger:   logger,
 		services: domain.NewServices(store, excelStore, fileStore),
 	}
+
+	app.services.MediaService.SetQuota(
+		models.StorageQuota{
+			User:   cfg.quota.user,
+			Course: cfg.quota.course,
+		},
+	)
+
+	if cfg.scanner.clamd != "" {
+		clamd := dal.NewClamd(cfg.scanner.clamd, cfg.scanner.timeout)
+
+		err = clamd.Ping()
+		if err != nil {
+			logger.Printf("ClamAV daemon at %s is unreachable: %v", cfg.scanner.clamd, err)
+		}
+
+		app.services.FileService.SetScanner(clamd)
+	}
+
+	if cfg.gc.once {
+		app.runGarbageCollection()
+		return
+	}
+
+	if cfg.gc.enabled {
+		go app.collectGarbage()
+	}
+
+	if cfg.release.enabled {
+		go app.releaseGrades()
+	}
+
+	if cfg.lifecycle.enabled {
+		go app.advanceAssignments()
+	}
+
+	if cfg.peerReview.enabled {
+		go app.allocateReviewers()
+	}
+
+	if cfg.similarity.enabled {
+		go app.compareSubmissions()
+	}
+
+	if cfg.autograde.enabled {
+		app.services.AutogradeService.SetRunner(
+			dal.NewContainer(cfg.autograde.runtime, cfg.autograde.image),
+		)
+
+		go app.gradeSubmissions()
+	}
+
 	err = app.server()
 
 	logger.Fatal(err)
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"time"
+)
+
+// allocateReviewers allocates peer reviewers to the submissions of
+// assignments that are due, checking every interval. It is meant to
+// be run in its own goroutine, like collectGarbage.
+func (app *application) allocateReviewers() {
+	for {
+		time.Sleep(app.config.peerReview.interval)
+
+		app.runReviewerAllocation()
+	}
+}
+
+// runReviewerAllocation allocates the reviewers that are due and logs
+// how many assignments had reviewers allocated.
+func (app *application) runReviewerAllocation() {
+	allocated, err := app.services.PeerReviewService.AllocateDue(time.Now())
+	if err != nil {
+		app.logger.Printf("Peer reviewer allocation failed: %v", err)
+	}
+
+	if allocated > 0 {
+		app.logger.Printf("Peer reviewer allocation, allocated assignments: %d", allocated)
+	}
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"time"
+)
+
+// releaseGrades releases the grades of assignments whose release was
+// scheduled, checking every interval. It is meant to be run in its own
+// goroutine, like collectGarbage.
+func (app *application) releaseGrades() {
+	for {
+		time.Sleep(app.config.release.interval)
+
+		app.runGradeRelease()
+	}
+}
+
+// runGradeRelease releases the grades that are due and logs how many
+// assignments were released.
+func (app *application) runGradeRelease() {
+	released, err := app.services.AssignmentService.ReleaseDue(time.Now())
+	if err != nil {
+		app.logger.Printf("Grade release failed: %v", err)
+	}
+
+	if released > 0 {
+		app.logger.Printf("Grade release, released assignments: %d", released)
+	}
+}
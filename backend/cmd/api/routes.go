@@ -31,6 +31,196 @@ func (app *application) routes() *http.ServeMux {
 		"GET /v1/course/{id}/announcement/read",
 		app.announcementReadHandler,
 	)
+
+	// Discussion operations
+	router.HandleFunc(
+		"POST /v1/course/{id}/discussion/create",
+		app.discussionCreateHandler,
+	)
+	router.HandleFunc(
+		"GET /v1/course/{id}/discussion/read",
+		app.discussionListHandler,
+	)
+	router.HandleFunc(
+		"GET /v1/course/{id}/discussion/{discussionId}/read",
+		app.discussionReadHandler,
+	)
+	router.HandleFunc(
+		"PATCH /v1/course/{id}/discussion/{discussionId}/update",
+		app.discussionUpdateHandler,
+	)
+	router.HandleFunc(
+		"DELETE /v1/course/{id}/discussion/{discussionId}/delete",
+		app.discussionDeleteHandler,
+	)
+	router.HandleFunc(
+		"PATCH /v1/course/{id}/discussion/{discussionId}/moderate",
+		app.discussionModerateHandler,
+	)
+	router.HandleFunc(
+		"POST /v1/course/{id}/discussion/{discussionId}/comment/create",
+		app.commentCreateHandler,
+	)
+	router.HandleFunc(
+		"PATCH /v1/course/{id}/discussion/{discussionId}/comment/{commentId}/update",
+		app.commentUpdateHandler,
+	)
+	router.HandleFunc(
+		"DELETE /v1/course/{id}/discussion/{discussionId}/comment/{commentId}/delete",
+		app.commentDeleteHandler,
+	)
+	router.HandleFunc(
+		"PATCH /v1/course/{id}/discussion/{discussionId}/comment/{commentId}/moderate",
+		app.commentModerateHandler,
+	)
+
+	// Project operations
+	router.HandleFunc(
+		"POST /v1/course/{id}/project/create",
+		app.projectCreateHandler,
+	)
+	router.HandleFunc(
+		"GET /v1/course/{id}/project/read",
+		app.projectListHandler,
+	)
+	router.HandleFunc(
+		"GET /v1/project/{projectId}/read",
+		app.projectReadHandler,
+	)
+	router.HandleFunc(
+		"DELETE /v1/project/{projectId}/delete",
+		app.projectDeleteHandler,
+	)
+	router.HandleFunc(
+		"POST /v1/project/{projectId}/team/create",
+		app.teamCreateHandler,
+	)
+	router.HandleFunc(
+		"POST /v1/project/team/{teamId}/member/add",
+		app.teamMemberAddHandler,
+	)
+	router.HandleFunc(
+		"POST /v1/project/team/{teamId}/join",
+		app.teamJoinHandler,
+	)
+	router.HandleFunc(
+		"DELETE /v1/project/team/{teamId}/member/{netId}/delete",
+		app.teamMemberDeleteHandler,
+	)
+	router.HandleFunc(
+		"GET /v1/project/team/{teamId}/discussion/read",
+		app.teamDiscussionReadHandler,
+	)
+	router.HandleFunc(
+		"POST /v1/project/team/{teamId}/discussion/comment/create",
+		app.teamCommentCreateHandler,
+	)
+	router.HandleFunc(
+		"POST /v1/project/team/{teamId}/media/upload",
+		app.teamMediaUploadHandler,
+	)
+	router.HandleFunc(
+		"GET /v1/project/team/{teamId}/media/read",
+		app.teamMediaReadHandler,
+	)
+	router.HandleFunc(
+		"POST /v1/project/team/{teamId}/submission/create",
+		app.teamSubmissionCreateHandler,
+	)
+	router.HandleFunc(
+		"POST /v1/project/team/{teamId}/grade",
+		app.teamGradeHandler,
+	)
+
+	// Quiz operations
+	router.HandleFunc(
+		"POST /v1/course/{id}/quiz/create",
+		app.quizCreateHandler,
+	)
+	router.HandleFunc(
+		"GET /v1/course/{id}/quiz/read",
+		app.quizListHandler,
+	)
+	router.HandleFunc("GET /v1/quiz/{quizId}/read", app.quizReadHandler)
+	router.HandleFunc("DELETE /v1/quiz/{quizId}/delete", app.quizDeleteHandler)
+	router.HandleFunc(
+		"POST /v1/quiz/{quizId}/attempt/create",
+		app.quizAttemptCreateHandler,
+	)
+	router.HandleFunc(
+		"GET /v1/quiz/{quizId}/attempts",
+		app.quizAttemptListHandler,
+	)
+	router.HandleFunc(
+		"GET /v1/quiz/attempt/{attemptId}/read",
+		app.quizAttemptReadHandler,
+	)
+	router.HandleFunc(
+		"PATCH /v1/quiz/attempt/{attemptId}/update",
+		app.quizAttemptUpdateHandler,
+	)
+	router.HandleFunc(
+		"POST /v1/quiz/attempt/{attemptId}/submit",
+		app.quizAttemptSubmitHandler,
+	)
+	router.HandleFunc(
+		"PATCH /v1/quiz/attempt/{attemptId}/response/{questionId}/grade",
+		app.quizResponseGradeHandler,
+	)
+
+	// Rubric operations
+	router.HandleFunc(
+		"POST /v1/course/{id}/rubric/create",
+		app.rubricCreateHandler,
+	)
+	router.HandleFunc(
+		"GET /v1/course/{id}/rubric/read",
+		app.rubricListHandler,
+	)
+	router.HandleFunc("GET /v1/rubric/{rubricId}/read", app.rubricReadHandler)
+	router.HandleFunc(
+		"DELETE /v1/rubric/{rubricId}/delete",
+		app.rubricDeleteHandler,
+	)
+
+	// Gradebook operations
+	router.HandleFunc(
+		"GET /v1/course/{id}/gradebook/read",
+		app.gradebookReadHandler,
+	)
+	router.HandleFunc(
+		"GET /v1/course/{id}/gradebook/student",
+		app.gradebookStudentReadHandler,
+	)
+	router.HandleFunc(
+		"PATCH /v1/course/{id}/gradebook/scheme",
+		app.gradeSchemeUpdateHandler,
+	)
+	router.HandleFunc(
+		"POST /v1/course/{id}/gradebook/category/create",
+		app.gradeCategoryCreateHandler,
+	)
+	router.HandleFunc(
+		"PATCH /v1/gradebook/category/{categoryId}/update",
+		app.gradeCategoryUpdateHandler,
+	)
+	router.HandleFunc(
+		"DELETE /v1/gradebook/category/{categoryId}/delete",
+		app.gradeCategoryDeleteHandler,
+	)
+	router.HandleFunc(
+		"PATCH /v1/course/{id}/accommodation/update",
+		app.accommodationUpdateHandler,
+	)
+	router.HandleFunc(
+		"GET /v1/course/{id}/accommodation/read",
+		app.accommodationListHandler,
+	)
+	router.HandleFunc(
+		"DELETE /v1/course/{id}/accommodation/{netId}/delete",
+		app.accommodationDeleteHandler,
+	)
+
 	router.HandleFunc("POST /v1/course/addstudent", app.addStudentHandler)
 	router.HandleFunc("DELETE /v1/course/{courseId}/{netId}/deletestudent", app.deleteStudentHandler)
 
@@ -38,6 +228,7 @@ func (app *application) routes() *http.ServeMux {
 	router.HandleFunc("POST /v1/course/create", app.courseCreateHandler)
 	router.HandleFunc("GET /v1/course/{id}/read/", app.courseReadHandler)
 	router.HandleFunc("DELETE /v1/course/{id}/delete", app.courseDeleteHandler)
+	router.HandleFunc("GET /v1/course/{id}/storage", app.courseStorageHandler)
 
 	router.HandleFunc(
 		"POST /v1/course/{mediaId}/banner/create",
@@ -53,6 +244,23 @@ func (app *application) routes() *http.ServeMux {
 	router.HandleFunc("GET /v1/user/read/{id}", app.userReadHandler)
 	router.HandleFunc("PATCH /v1/user/update/{id}", app.userUpdateHandler)
 	router.HandleFunc("DELETE /v1/user/delete/{id}", app.userDeleteHandler)
+	router.HandleFunc(
+		"POST /v1/user/picture/create",
+		app.profilePictureCreateHandler,
+	)
+	router.HandleFunc(
+		"GET /v1/user/{id}/picture/read",
+		app.profilePictureReadHandler,
+	)
+	router.HandleFunc("POST /v1/user/quota/read", app.userQuotaHandler)
+	router.HandleFunc(
+		"GET /v1/user/notifications/read",
+		app.notificationListHandler,
+	)
+	router.HandleFunc(
+		"PATCH /v1/user/notifications/{notificationId}/read",
+		app.notificationReadHandler,
+	)
 
 	// Login will require authorization, body will contain the credential info
 	router.HandleFunc("POST /v1/user/login", app.userLoginHandler)
@@ -70,6 +278,80 @@ func (app *application) routes() *http.ServeMux {
 		"PATCH /v1/course/assignment/update",
 		app.assignmentUpdateHandler,
 	)
+	router.HandleFunc(
+		"PATCH /v1/course/assignment/{assignmentId}/filetypes",
+		app.assignmentFileTypesHandler,
+	)
+	router.HandleFunc(
+		"PATCH /v1/course/assignment/{assignmentId}/latepolicy",
+		app.assignmentLatePolicyHandler,
+	)
+	router.HandleFunc(
+		"PATCH /v1/course/assignment/{assignmentId}/attempts",
+		app.assignmentAttemptPolicyHandler,
+	)
+	router.HandleFunc(
+		"PATCH /v1/course/assignment/{assignmentId}/anonymous",
+		app.assignmentAnonymousHandler,
+	)
+	router.HandleFunc(
+		"POST /v1/course/assignment/{assignmentId}/grades/release",
+		app.assignmentReleaseGradesHandler,
+	)
+	router.HandleFunc(
+		"PATCH /v1/course/assignment/{assignmentId}/grades/schedule",
+		app.assignmentScheduleReleaseHandler,
+	)
+	router.HandleFunc(
+		"PATCH /v1/course/assignment/{assignmentId}/schedule",
+		app.assignmentScheduleHandler,
+	)
+
+	// Peer review operations
+	router.HandleFunc(
+		"PATCH /v1/course/assignment/{assignmentId}/peerreview",
+		app.peerReviewPolicyHandler,
+	)
+	router.HandleFunc(
+		"POST /v1/course/assignment/{assignmentId}/peerreview/allocate",
+		app.peerReviewAllocateHandler,
+	)
+	router.HandleFunc(
+		"GET /v1/course/{courseId}/assignment/{assignmentId}/peerreview/read",
+		app.peerReviewListHandler,
+	)
+	router.HandleFunc(
+		"GET /v1/course/{courseId}/assignment/{assignmentId}/peerreview/todo",
+		app.peerReviewTodoHandler,
+	)
+	router.HandleFunc(
+		"GET /v1/course/{courseId}/assignment/{assignmentId}/peerreview/received",
+		app.peerReviewReceivedHandler,
+	)
+	router.HandleFunc(
+		"PATCH /v1/course/assignment/peerreview/{reviewId}/submit",
+		app.peerReviewSubmitHandler,
+	)
+	router.HandleFunc(
+		"POST /v1/course/assignment/{assignmentId}/extension/create",
+		app.extensionCreateHandler,
+	)
+	router.HandleFunc(
+		"GET /v1/course/{courseId}/assignment/{assignmentId}/extension/read",
+		app.extensionListHandler,
+	)
+	router.HandleFunc(
+		"DELETE /v1/course/assignment/{assignmentId}/extension/{netId}/delete",
+		app.extensionDeleteHandler,
+	)
+	router.HandleFunc(
+		"PATCH /v1/course/assignment/{assignmentId}/rubric",
+		app.assignmentRubricHandler,
+	)
+	router.HandleFunc(
+		"PATCH /v1/course/assignment/{assignmentId}/gradebook",
+		app.assignmentGradebookHandler,
+	)
 	router.HandleFunc(
 		"DELETE /v1/course/assignment/{assignmentId}/delete",
 		app.assignmentDeleteHandler,
@@ -99,6 +381,10 @@ func (app *application) routes() *http.ServeMux {
 		"POST /v1/course/assignment/submission/{id}/update",
 		app.submissionUpdateHandler,
 	)
+	router.HandleFunc(
+		"POST /v1/course/assignment/submission/{id}/grade",
+		app.submissionRubricGradeHandler,
+	)
 	router.HandleFunc(
 		"DELETE /v1/course/assignment/submission/{id}/delete",
 		app.submissionDeleteHandler,
@@ -113,6 +399,18 @@ func (app *application) routes() *http.ServeMux {
 		"POST /v1/course/{courseId}/assignment/{assignmentId}/submission/read",
 		app.studentsubmissionReadHandler,
 	)
+	router.HandleFunc(
+		"GET /v1/course/{courseId}/assignment/{assignmentId}/submission/{userId}/attempts",
+		app.attemptListHandler,
+	)
+	router.HandleFunc(
+		"GET /v1/course/{courseId}/assignment/{assignmentId}/submission/{userId}/diff",
+		app.attemptDiffHandler,
+	)
+	router.HandleFunc(
+		"PATCH /v1/course/assignment/{assignmentId}/submission/{userId}/attempt",
+		app.attemptSelectHandler,
+	)
 
 	// Image operations
 	// router.HandlerFunc("POST /v1/course/image", app.courseImageHandler)
@@ -130,10 +428,170 @@ func (app *application) routes() *http.ServeMux {
 	router.HandleFunc(
 		"POST /v1/course/{id}/assignment/{post}/offline",
 		app.receiveOfflineGrades)
+	router.HandleFunc(
+		"GET /v1/course/{id}/assignment/{post}/archive",
+		app.submissionArchiveHandler,
+	)
 	router.HandleFunc(
 		"POST /v1/course/assignment/submission/{id}/upload",
 		app.submissionMediaUploadHandler,
 	)
 
+	// Regrade requests
+	router.HandleFunc(
+		"POST /v1/course/assignment/submission/{id}/regrade",
+		app.regradeCreateHandler,
+	)
+	router.HandleFunc(
+		"GET /v1/course/{courseId}/assignment/{assignmentId}/regrade/read",
+		app.regradeListHandler,
+	)
+	router.HandleFunc(
+		"PATCH /v1/course/assignment/regrade/{regradeId}/accept",
+		app.regradeAcceptHandler,
+	)
+	router.HandleFunc(
+		"PATCH /v1/course/assignment/regrade/{regradeId}/reject",
+		app.regradeRejectHandler,
+	)
+	router.HandleFunc(
+		"PATCH /v1/course/assignment/regrade/{regradeId}/escalate",
+		app.regradeEscalateHandler,
+	)
+	router.HandleFunc(
+		"PATCH /v1/course/assignment/{assignmentId}/regradewindow",
+		app.assignmentRegradeWindowHandler,
+	)
+
+	// Similarity reports
+	router.HandleFunc(
+		"POST /v1/course/assignment/{assignmentId}/similarity/create",
+		app.similarityCreateHandler,
+	)
+	router.HandleFunc(
+		"GET /v1/course/{courseId}/assignment/{assignmentId}/similarity/read",
+		app.similarityListHandler,
+	)
+	router.HandleFunc(
+		"GET /v1/course/{courseId}/assignment/{assignmentId}/similarity/{reportId}",
+		app.similarityReadHandler,
+	)
+
+	// Autograding
+	router.HandleFunc(
+		"POST /v1/course/assignment/{assignmentId}/harness",
+		app.autogradeHarnessHandler,
+	)
+	router.HandleFunc(
+		"GET /v1/course/{courseId}/assignment/{assignmentId}/harness/read",
+		app.autogradeHarnessReadHandler,
+	)
+	router.HandleFunc(
+		"POST /v1/course/assignment/submission/{id}/autograde",
+		app.autogradeRerunHandler,
+	)
+	router.HandleFunc(
+		"GET /v1/course/{courseId}/assignment/{assignmentId}/autograde/{submissionId}",
+		app.autogradeResultsHandler,
+	)
+
+	// Annotations
+	router.HandleFunc(
+		"POST /v1/course/assignment/submission/{id}/annotation/create",
+		app.annotationCreateHandler,
+	)
+	router.HandleFunc(
+		"PATCH /v1/course/assignment/submission/annotation/{annotationId}/update",
+		app.annotationUpdateHandler,
+	)
+	router.HandleFunc(
+		"DELETE /v1/course/assignment/submission/annotation/{annotationId}/delete",
+		app.annotationDeleteHandler,
+	)
+	router.HandleFunc(
+		"GET /v1/course/{courseId}/assignment/{assignmentId}/submission/{submissionId}/annotations",
+		app.annotationListHandler,
+	)
+	router.HandleFunc(
+		"GET /v1/course/{courseId}/assignment/{assignmentId}/submission/{submissionId}/annotated/{mediaId}",
+		app.annotatedDownloadHandler,
+	)
+
+	// Feedback media
+	router.HandleFunc(
+		"POST /v1/course/assignment/submission/{id}/feedback/upload",
+		app.feedbackMediaUploadHandler,
+	)
+	router.HandleFunc(
+		"GET /v1/course/{courseId}/assignment/{assignmentId}/submission/{submissionId}/feedback",
+		app.feedbackMediaListHandler,
+	)
+	router.HandleFunc(
+		"GET /v1/course/{courseId}/assignment/{assignmentId}/submission/{submissionId}/feedback/{mediaId}",
+		app.feedbackMediaDownloadHandler,
+	)
+	router.HandleFunc(
+		"DELETE /v1/course/assignment/submission/{id}/feedback/{mediaId}/delete",
+		app.feedbackMediaDeleteHandler,
+	)
+
+	// Module operations
+	router.HandleFunc(
+		"POST /v1/course/{id}/module/create",
+		app.moduleCreateHandler,
+	)
+	router.HandleFunc(
+		"GET /v1/course/{id}/module/read",
+		app.moduleListHandler,
+	)
+	router.HandleFunc(
+		"GET /v1/course/{id}/module/progress",
+		app.moduleProgressHandler,
+	)
+	router.HandleFunc(
+		"PATCH /v1/module/{moduleId}/update",
+		app.moduleUpdateHandler,
+	)
+	router.HandleFunc(
+		"PATCH /v1/module/{moduleId}/move",
+		app.moduleMoveHandler,
+	)
+	router.HandleFunc(
+		"DELETE /v1/module/{moduleId}/delete",
+		app.moduleDeleteHandler,
+	)
+	router.HandleFunc(
+		"POST /v1/module/{moduleId}/item/create",
+		app.moduleItemCreateHandler,
+	)
+	router.HandleFunc(
+		"POST /v1/module/{moduleId}/item/upload",
+		app.moduleItemUploadHandler,
+	)
+	router.HandleFunc(
+		"PATCH /v1/module/item/{itemId}/update",
+		app.moduleItemUpdateHandler,
+	)
+	router.HandleFunc(
+		"PATCH /v1/module/item/{itemId}/move",
+		app.moduleItemMoveHandler,
+	)
+	router.HandleFunc(
+		"DELETE /v1/module/item/{itemId}/delete",
+		app.moduleItemDeleteHandler,
+	)
+	router.HandleFunc(
+		"POST /v1/module/item/{itemId}/view",
+		app.moduleItemViewHandler,
+	)
+	router.HandleFunc(
+		"POST /v1/module/item/{itemId}/done",
+		app.moduleItemDoneHandler,
+	)
+	router.HandleFunc(
+		"GET /v1/module/item/{itemId}/file",
+		app.moduleItemFileHandler,
+	)
+
 	return router
 }
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"time"
+)
+
+// compareSubmissions makes the similarity reports that teachers asked
+// for, checking every interval. It is meant to be run in its own
+// goroutine, like collectGarbage.
+func (app *application) compareSubmissions() {
+	for {
+		time.Sleep(app.config.similarity.interval)
+
+		app.runSimilarityReports()
+	}
+}
+
+// runSimilarityReports makes the reports that are waiting and logs how
+// many were made.
+func (app *application) runSimilarityReports() {
+	made, err := app.services.SimilarityService.RunPending(time.Now())
+	if err != nil {
+		app.logger.Printf("Similarity reports failed: %v", err)
+	}
+
+	if made > 0 {
+		app.logger.Printf("Similarity reports, reports made: %d", made)
+	}
+}
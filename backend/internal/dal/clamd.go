@@ -0,0 +1,159 @@
+package dal
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/n30w/Darkspace/internal/models"
+)
+
+// clamdChunkSize is the size of each chunk streamed to clamd. It
+// must be smaller than clamd's StreamMaxLength.
+const clamdChunkSize = 64 << 10
+
+// Clamd scans files with a ClamAV daemon, using the clamd protocol
+// over TCP or a Unix socket.
+type Clamd struct {
+	network string
+	address string
+	timeout time.Duration
+}
+
+// NewClamd creates a scanner for the clamd listening at address. An
+// address starting with a slash is a Unix socket, anything else is
+// a TCP host and port, such as "localhost:3310".
+func NewClamd(address string, timeout time.Duration) *Clamd {
+	network := "tcp"
+	if strings.HasPrefix(address, "/") {
+		network = "unix"
+	}
+
+	return &Clamd{
+		network: network,
+		address: address,
+		timeout: timeout,
+	}
+}
+
+// Ping checks that clamd is reachable.
+func (c *Clamd) Ping() error {
+	reply, err := c.command("PING", nil)
+	if err != nil {
+		return err
+	}
+
+	if reply != "PONG" {
+		return fmt.Errorf("clamd: unexpected reply to PING: %s", reply)
+	}
+
+	return nil
+}
+
+// Scan streams r to clamd with the INSTREAM command and reads back
+// its verdict.
+func (c *Clamd) Scan(r io.Reader) (*models.ScanResult, error) {
+	reply, err := c.command("INSTREAM", r)
+	if err != nil {
+		return nil, err
+	}
+
+	// Replies look like "stream: OK", "stream: <threat> FOUND" or
+	// "<reason> ERROR".
+	reply = strings.TrimPrefix(reply, "stream: ")
+
+	switch {
+	case reply == "OK":
+		return &models.ScanResult{Status: models.CLEAN}, nil
+
+	case strings.HasSuffix(reply, " FOUND"):
+		return &models.ScanResult{
+			Status: models.QUARANTINED,
+			Threat: strings.TrimSuffix(reply, " FOUND"),
+		}, nil
+
+	default:
+		return nil, fmt.Errorf("clamd: %s", reply)
+	}
+}
+
+// command sends a single command to clamd and returns its reply.
+// If body is not nil, it is streamed in length-prefixed chunks after
+// the command, as INSTREAM expects.
+func (c *Clamd) command(name string, body io.Reader) (string, error) {
+	conn, err := net.DialTimeout(c.network, c.address, c.timeout)
+	if err != nil {
+		return "", err
+	}
+
+	defer conn.Close()
+
+	if c.timeout > 0 {
+		err = conn.SetDeadline(time.Now().Add(c.timeout))
+		if err != nil {
+			return "", err
+		}
+	}
+
+	// The "z" prefix asks for null terminated replies.
+	_, err = conn.Write([]byte("z" + name + "\x00"))
+	if err != nil {
+		return "", err
+	}
+
+	if body != nil {
+		err = writeChunks(conn, body)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	reply, err := bufio.NewReader(conn).ReadString('\x00')
+	if err != nil && !(err == io.EOF && reply != "") {
+		return "", err
+	}
+
+	return strings.TrimRight(reply, "\x00\n"), nil
+}
+
+// writeChunks writes r to w as chunks prefixed with their length,
+// ending with a chunk of length zero.
+func writeChunks(w io.Writer, r io.Reader) error {
+	buf := make([]byte, clamdChunkSize)
+	size := make([]byte, 4)
+
+	for {
+		n, err := r.Read(buf)
+		if n > 0 {
+			binary.BigEndian.PutUint32(size, uint32(n))
+
+			_, werr := w.Write(size)
+			if werr != nil {
+				return werr
+			}
+
+			_, werr = w.Write(buf[:n])
+			if werr != nil {
+				return werr
+			}
+		}
+
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	binary.BigEndian.PutUint32(size, 0)
+
+	_, err := w.Write(size)
+
+	return err
+}
@@ -0,0 +1,180 @@
+package dal
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/n30w/Darkspace/internal/models"
+)
+
+// eicar is the EICAR anti-virus test file, which every scanner
+// reports as a threat.
+const eicar = `X5O!P%@AP[4\PZX54(P^)7CC)7}$EICAR-STANDARD-ANTIVIRUS-TEST-FILE!$H+H*`
+
+func TestClamd_Scan(t *testing.T) {
+	address := fakeClamd(t)
+
+	tests := []struct {
+		name    string
+		content string
+		want    models.ScanResult
+	}{
+		{
+			name:    "clean",
+			content: "just an essay",
+			want:    models.ScanResult{Status: models.CLEAN},
+		},
+		{
+			name:    "infected",
+			content: "before " + eicar + " after",
+			want: models.ScanResult{
+				Status: models.QUARANTINED,
+				Threat: "Eicar-Signature",
+			},
+		},
+		{
+			name:    "larger than a chunk",
+			content: strings.Repeat("a", clamdChunkSize*2+10) + eicar,
+			want: models.ScanResult{
+				Status: models.QUARANTINED,
+				Threat: "Eicar-Signature",
+			},
+		},
+	}
+
+	c := NewClamd(address, 5*time.Second)
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				got, err := c.Scan(strings.NewReader(tt.content))
+				if err != nil {
+					t.Fatalf("%+v", err)
+				}
+
+				if *got != tt.want {
+					t.Errorf("got %+v, want %+v", *got, tt.want)
+				}
+			},
+		)
+	}
+}
+
+func TestClamd_Ping(t *testing.T) {
+	err := NewClamd(fakeClamd(t), 5*time.Second).Ping()
+	if err != nil {
+		t.Errorf("%+v", err)
+	}
+}
+
+// TestClamd_Daemon scans against a real ClamAV daemon, such as the
+// one in remote/test/compose.yaml. It only runs when CLAMD_ADDRESS
+// is set, for example to "localhost:3310".
+func TestClamd_Daemon(t *testing.T) {
+	address := os.Getenv("CLAMD_ADDRESS")
+	if address == "" {
+		t.Skip("CLAMD_ADDRESS is not set")
+	}
+
+	c := NewClamd(address, 30*time.Second)
+
+	err := c.Ping()
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+
+	got, err := c.Scan(strings.NewReader("just an essay"))
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if got.Status != models.CLEAN {
+		t.Errorf("got %+v, want clean", *got)
+	}
+
+	got, err = c.Scan(strings.NewReader(eicar))
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if !got.Quarantined() || got.Threat == "" {
+		t.Errorf("got %+v, want quarantined with a threat", *got)
+	}
+}
+
+// fakeClamd starts a server that speaks enough of the clamd protocol
+// to answer PING and INSTREAM, and returns its address.
+func fakeClamd(t *testing.T) string {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+
+	t.Cleanup(func() { l.Close() })
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go serveFakeClamd(conn)
+		}
+	}()
+
+	return l.Addr().String()
+}
+
+func serveFakeClamd(conn net.Conn) {
+	defer conn.Close()
+
+	r := bufio.NewReader(conn)
+
+	command, err := r.ReadString('\x00')
+	if err != nil {
+		return
+	}
+
+	switch strings.TrimSuffix(command, "\x00") {
+	case "zPING":
+		conn.Write([]byte("PONG\x00"))
+
+	case "zINSTREAM":
+		var content bytes.Buffer
+		size := make([]byte, 4)
+
+		for {
+			_, err := io.ReadFull(r, size)
+			if err != nil {
+				return
+			}
+
+			n := binary.BigEndian.Uint32(size)
+			if n == 0 {
+				break
+			}
+
+			_, err = io.CopyN(&content, r, int64(n))
+			if err != nil {
+				return
+			}
+		}
+
+		if strings.Contains(content.String(), eicar) {
+			conn.Write([]byte("stream: Eicar-Signature FOUND\x00"))
+			return
+		}
+
+		conn.Write([]byte("stream: OK\x00"))
+
+	default:
+		conn.Write([]byte("UNKNOWN COMMAND ERROR\x00"))
+	}
+}
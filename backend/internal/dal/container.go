@@ -0,0 +1,133 @@
+package dal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/n30w/Darkspace/internal/models"
+)
+
+// containerProcesses is the most processes a run may have.
+const containerProcesses = 256
+
+// Container runs harnesses in containers, with a runtime that takes
+// Docker's command line, such as docker or podman. Containers have no
+// network, no capabilities and only the workspace mounted, at /work.
+type Container struct {
+	runtime string
+
+	// image is what harnesses that do not name an image are run in.
+	image string
+}
+
+// NewContainer creates a runner for the container runtime at runtime.
+func NewContainer(runtime, image string) *Container {
+	return &Container{runtime: runtime, image: image}
+}
+
+// Run runs a harness's command with the shell, in a container that is
+// removed once it exits. A run that times out has its container killed,
+// since stopping the runtime's command would leave it running.
+//
+// The command's file descriptor 3 is the container's standard output,
+// which is kept as the run's TAP, and its own standard output and
+// error both go to the container's standard error, which is kept as
+// its log. Processes the command starts inherit descriptor 3, so it
+// must close it for the submission.
+func (c *Container) Run(
+	ctx context.Context,
+	spec *models.RunSpec,
+) (*models.RunResult, error) {
+	ctx, cancel := context.WithTimeout(ctx, spec.Timeout)
+	defer cancel()
+
+	image := spec.Image
+	if image == "" {
+		image = c.image
+	}
+
+	name := "darkspace-grade-" + uuid.NewString()
+
+	cmd := exec.CommandContext(
+		ctx,
+		c.runtime,
+		"run",
+		"--rm",
+		"--name", name,
+		"--network", "none",
+		"--memory", fmt.Sprintf("%d", spec.Memory),
+		"--memory-swap", fmt.Sprintf("%d", spec.Memory),
+		"--pids-limit", fmt.Sprintf("%d", containerProcesses),
+		"--cpus", "1",
+		"--cap-drop", "ALL",
+		"--security-opt", "no-new-privileges",
+		"--volume", spec.Dir+":/work",
+		"--workdir", "/work",
+		image,
+		"/bin/sh", "-c", "exec 3>&1 1>&2; exec /bin/sh -c \"$0\"", spec.Command,
+	)
+	cmd.Cancel = func() error {
+		exec.Command(c.runtime, "kill", name).Run()
+		return cmd.Process.Kill()
+	}
+	cmd.WaitDelay = 5 * time.Second
+
+	return run(ctx, cmd)
+}
+
+// run runs a command that is stopped when ctx is done, keeping its
+// standard output as the run's TAP and its standard error as its log.
+func run(ctx context.Context, cmd *exec.Cmd) (*models.RunResult, error) {
+	tap := &limitedBuffer{max: models.MaxLogSize}
+	out := &limitedBuffer{max: models.MaxLogSize}
+	cmd.Stdout = tap
+	cmd.Stderr = out
+
+	err := cmd.Run()
+
+	res := &models.RunResult{
+		Output:   out.String(),
+		TAP:      tap.String(),
+		TimedOut: errors.Is(ctx.Err(), context.DeadlineExceeded),
+	}
+
+	var exitErr *exec.ExitError
+
+	switch {
+	case err == nil:
+	case errors.As(err, &exitErr):
+		res.ExitCode = exitErr.ExitCode()
+	case res.TimedOut:
+		res.ExitCode = -1
+	default:
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// limitedBuffer keeps the first max bytes written to it, and drops the
+// rest.
+type limitedBuffer struct {
+	max int
+	buf []byte
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if room := b.max - len(b.buf); room > 0 {
+		b.buf = append(b.buf, p[:min(room, len(p))]...)
+	}
+
+	return len(p), nil
+}
+
+// String returns what was kept as valid UTF-8 without NUL bytes, so
+// that it can be stored as text.
+func (b *limitedBuffer) String() string {
+	return strings.ReplaceAll(strings.ToValidUTF8(string(b.buf), "\uFFFD"), "\x00", "")
+}
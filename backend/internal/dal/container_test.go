@@ -0,0 +1,104 @@
+package dal
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/n30w/Darkspace/internal/models"
+)
+
+func TestContainer_Run(t *testing.T) {
+	tests := []struct {
+		name    string
+		command string
+		tap     string
+		output  string
+	}{
+		{
+			name:    "harness reports",
+			command: `echo "ok 1 - passes" >&3; echo done`,
+			tap:     "ok 1 - passes\n",
+			output:  "done\n",
+		},
+		{
+			name:    "submission prints tests",
+			command: `sh submission/print.sh`,
+			output:  "ok 1 - printed\n",
+		},
+		{
+			name:    "submission writes to the closed descriptor",
+			command: `echo "not ok 1 - fails" >&3; sh submission/forge.sh 3>&-`,
+			tap:     "not ok 1 - fails\n",
+		},
+	}
+
+	runtime := fakeRuntime(t)
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				dir := t.TempDir()
+				writeFile(t, filepath.Join(dir, "submission", "print.sh"), `echo "ok 1 - printed"`)
+				writeFile(t, filepath.Join(dir, "submission", "forge.sh"), `{ echo "ok 1 - forged" >&3; } 2>/dev/null`)
+
+				res, err := NewContainer(runtime, "test-image").Run(
+					context.Background(),
+					&models.RunSpec{Dir: dir, Command: tt.command, Timeout: 10 * time.Second, Memory: 64 << 20},
+				)
+				if err != nil {
+					t.Fatalf("%+v", err)
+				}
+
+				if res.TAP != tt.tap {
+					t.Errorf("got TAP %q, want %q", res.TAP, tt.tap)
+				}
+
+				if res.Output != tt.output {
+					t.Errorf("got output %q, want %q", res.Output, tt.output)
+				}
+			},
+		)
+	}
+}
+
+// fakeRuntime writes a stand-in for a container runtime, which runs
+// the command after the image name on the host, from the mounted
+// workspace.
+func fakeRuntime(t *testing.T) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "runtime")
+	writeFile(t, path, `#!/bin/sh
+while [ "$#" -gt 0 ]; do
+	case "$1" in
+	--volume) cd "${2%%:*}"; shift 2 ;;
+	test-image) shift; exec "$@" ;;
+	*) shift ;;
+	esac
+done
+`)
+
+	err := os.Chmod(path, 0o755)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+
+	return path
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+
+	err := os.MkdirAll(filepath.Dir(path), 0o755)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+
+	err = os.WriteFile(path, []byte(content+"\n"), 0o644)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+}
@@ -7,4 +7,5 @@ import (
 var (
 	ERR_RECORD_NOT_FOUND = errors.New("record not found")
 	ERR_INVALID_BY       = errors.New("invalid get type received")
+	ERR_ATTEMPT_LIMIT    = errors.New("attempt limit reached")
 )
@@ -112,6 +112,33 @@ func (es *ExcelStore) AddRow(
 	return nil
 }
 
+// AddSheetRow adds a row to a named sheet of an Excel file, creating
+// the sheet if the file does not have it yet. Start works like it does
+// for AddRow.
+func (es *ExcelStore) AddSheetRow(
+	f *excelize.File, sheet string, row *[]interface{},
+	start string,
+) error {
+	idx, err := f.GetSheetIndex(sheet)
+	if err != nil {
+		return err
+	}
+
+	if idx == -1 {
+		_, err = f.NewSheet(sheet)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = f.SetSheetRow(sheet, start, row)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
 // ========================================================================== //
 // CSV defines access operations for accessing data from a CSV file.
 // This exists because we currently do not have a functioning database just yet.
@@ -5,9 +5,16 @@
 package dal
 
 import (
+	"errors"
+	"fmt"
 	"io"
+	"io/fs"
 	"os"
 	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/n30w/Darkspace/internal/models"
 )
 
 const darkspaceDirectory = "darkspace_volume"
@@ -67,3 +74,92 @@ func (lv *LocalVolume) CopyFile(f1 io.Writer, f2 io.Reader) error {
 
 	return nil
 }
+
+// ListFiles lists every file in the volume, except for defaults and
+// templates, which are managed by hand.
+func (lv *LocalVolume) ListFiles() ([]models.Blob, error) {
+	var blobs []models.Blob
+
+	err := filepath.WalkDir(
+		lv.path, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+
+			if d.IsDir() {
+				if p == lv.defaults || p == lv.templates {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+
+			blobs = append(
+				blobs, models.Blob{
+					Path:    p,
+					Size:    info.Size(),
+					ModTime: info.ModTime(),
+				},
+			)
+
+			return nil
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return blobs, nil
+}
+
+// OpenFile opens a file in the volume for reading. If the file does
+// not exist, ERR_RECORD_NOT_FOUND is returned.
+func (lv *LocalVolume) OpenFile(p string) (io.ReadSeekCloser, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, ERR_RECORD_NOT_FOUND
+		}
+		return nil, err
+	}
+
+	return f, nil
+}
+
+// StatFile returns information about a file in the volume. If the
+// file does not exist, ERR_RECORD_NOT_FOUND is returned.
+func (lv *LocalVolume) StatFile(p string) (*models.Blob, error) {
+	info, err := os.Stat(p)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, ERR_RECORD_NOT_FOUND
+		}
+		return nil, err
+	}
+
+	return &models.Blob{
+		Path:    p,
+		Size:    info.Size(),
+		ModTime: info.ModTime(),
+	}, nil
+}
+
+// RemoveFile deletes a file from the volume. Files outside of the
+// volume are never removed.
+func (lv *LocalVolume) RemoveFile(p string) error {
+	rel, err := filepath.Rel(lv.path, p)
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return fmt.Errorf("%s is not inside of volume %s", p, lv.path)
+	}
+
+	err = os.Remove(p)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	return nil
+}
@@ -3,9 +3,11 @@ package dal
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"mime/multipart"
+	"strings"
 	"time"
 
 	"github.com/n30w/Darkspace/internal/models"
@@ -43,6 +45,23 @@ type Store struct {
 
 var err error
 
+// nullString converts an empty string into a SQL NULL value.
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+func nullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t, Valid: !t.IsZero()}
+}
+
+// nullTimePtr is nullTime for a time that may not be set.
+func nullTimePtr(t *time.Time) sql.NullTime {
+	if t == nil {
+		return sql.NullTime{}
+	}
+	return nullTime(*t)
+}
+
 func NewStore(db *sql.DB) *Store {
 	return &Store{
 		db: db,
@@ -89,63 +108,73 @@ func (s *Store) GetSubmissionById(submissionId string) (
 ) {
 	sub := models.NewSubmission()
 	fmt.Printf("getting submission by id %s \n", submissionId)
-	query := `SELECT id, submission_time, on_time, grade, feedback, user_id 
+	var peerScore sql.NullFloat64
+	query := `SELECT id, submission_time, on_time, grade, COALESCE(raw_grade, grade), late_penalty, feedback, user_id, attempt, graded, counts, pinned, peer_score 
 FROM submissions WHERE id=$1`
 
 	row := s.db.QueryRow(query, submissionId)
 
 	err = row.Scan(
 		&sub.ID, &sub.SubmissionTime, &sub.OnTime, &sub.Grade,
-		&sub.Feedback, &sub.User.ID,
+		&sub.RawGrade, &sub.LatePenalty, &sub.Feedback, &sub.User.ID,
+		&sub.Attempt, &sub.Graded, &sub.Counts, &sub.Pinned, &peerScore,
 	)
 	if err != nil {
 		return nil, err
 	}
+
+	if peerScore.Valid {
+		sub.PeerScore = &peerScore.Float64
+	}
+
 	return sub, nil
 }
 
+// GetSubmissionIdByUserAndAssignment retrieves the ID of the attempt
+// a user made at an assignment that counts, or their latest attempt
+// when none is marked as counting. The ID is empty when the user has
+// made no attempts.
 func (s *Store) GetSubmissionIdByUserAndAssignment(userId string, assignmentId string) (string, error) {
-	// Retrieve list of submissions by user
-	query := `SELECT submission_id FROM user_submissions WHERE user_net_id=$1`
+	var submissionid string
+
+	query := `SELECT s.id FROM submissions s
+JOIN assignment_submissions a ON a.submission_id = s.id
+WHERE s.user_id = $1 AND a.assignment_id = $2
+ORDER BY s.counts DESC, s.attempt DESC LIMIT 1`
 
-	rows, err := s.db.Query(query, userId)
+	row := s.db.QueryRow(query, userId, assignmentId)
+
+	err := row.Scan(&submissionid)
 	if err != nil {
-		return "", err
-	}
-	defer rows.Close()
-	// List of submissions from user
-	var submissions []string
-	for rows.Next() {
-		var submission string
-		err := rows.Scan(
-			&submission,
-		)
-		if err != nil {
-			return "", fmt.Errorf("error scanning row: %v", err)
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", nil
 		}
-		submissions = append(submissions, submission)
+		return "", err
 	}
-	fmt.Printf("Submission ids related to use: %s\n", submissions)
-	fmt.Printf("Assignment id:%s\n", assignmentId)
 
-	if err = rows.Err(); err != nil {
-		return "", fmt.Errorf("error iterating rows: %v", err)
-	}
-	var submissionid string
-	for _, id := range submissions {
-		query = `SELECT submission_id FROM assignment_submissions WHERE assignment_id=$1 AND submission_id=$2`
-		row := s.db.QueryRow(query, assignmentId, id)
-		err = row.Scan(&submissionid)
-		if err != nil {
-			switch err {
-			case sql.ErrNoRows:
-				continue
-			default:
-				return "", err
-			}
+	return submissionid, nil
+}
+
+// GetAssignmentIdBySubmission retrieves the ID of the assignment a
+// submission was made for.
+func (s *Store) GetAssignmentIdBySubmission(submissionId string) (
+	string,
+	error,
+) {
+	var assignmentId string
+
+	query := `SELECT assignment_id FROM assignment_submissions WHERE submission_id = $1`
+	row := s.db.QueryRow(query, submissionId)
+
+	err := row.Scan(&assignmentId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", ERR_RECORD_NOT_FOUND
 		}
+		return "", err
 	}
-	return submissionid, nil
+
+	return assignmentId, nil
 }
 
 // GetSubmissions queries a junction table to retrieve all related
@@ -156,11 +185,12 @@ func (s *Store) GetSubmissions(assignmentId string) (
 ) {
 	var submissions []*models.Submission
 	query := `  
-		SELECT s.id, s.grade, s.feedback, u.full_name, u.net_id
+		SELECT s.id, s.grade, COALESCE(s.raw_grade, s.grade), s.late_penalty, s.feedback, u.full_name, u.net_id, s.attempt, s.graded, s.counts, s.pinned, s.peer_score
 		FROM submissions s
 		JOIN assignment_submissions a ON s.id = a.submission_id
 		JOIN users u ON s.user_id = u.net_id
 		WHERE a.assignment_id = $1
+		ORDER BY u.net_id, s.attempt
 	`
 
 	rows, err := s.db.Query(query, assignmentId)
@@ -172,16 +202,30 @@ func (s *Store) GetSubmissions(assignmentId string) (
 
 	for rows.Next() {
 		sub := models.NewSubmission()
+		var peerScore sql.NullFloat64
+
 		err := rows.Scan(
 			&sub.ID,
 			&sub.Grade,
+			&sub.RawGrade,
+			&sub.LatePenalty,
 			&sub.Feedback,
 			&sub.User.FullName,
 			&sub.User.ID,
+			&sub.Attempt,
+			&sub.Graded,
+			&sub.Counts,
+			&sub.Pinned,
+			&peerScore,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("error scanning row: %v", err)
 		}
+
+		if peerScore.Valid {
+			sub.PeerScore = &peerScore.Float64
+		}
+
 		submissions = append(submissions, sub)
 	}
 
@@ -192,14 +236,216 @@ func (s *Store) GetSubmissions(assignmentId string) (
 	return submissions, nil
 }
 
+// GetAttempts retrieves a student's attempts at an assignment, in the
+// order they were made. An empty Net ID retrieves every student's
+// attempts, ordered by student.
+func (s *Store) GetAttempts(assignmentId, netId string) (
+	[]*models.Submission,
+	error,
+) {
+	query := `
+		SELECT s.id, s.submission_time, s.on_time, s.grade, COALESCE(s.raw_grade, s.grade), s.late_penalty, s.feedback, s.user_id, s.attempt, s.graded, s.counts, s.pinned, s.peer_score
+		FROM submissions s
+		JOIN assignment_submissions a ON a.submission_id = s.id
+		WHERE a.assignment_id = $1 AND ($2 = '' OR s.user_id = $2)
+		ORDER BY s.user_id, s.attempt
+	`
+
+	rows, err := s.db.Query(query, assignmentId, netId)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var attempts []*models.Submission
+
+	for rows.Next() {
+		sub := models.NewSubmission()
+		var userId sql.NullString
+		var peerScore sql.NullFloat64
+
+		err := rows.Scan(
+			&sub.ID,
+			&sub.SubmissionTime,
+			&sub.OnTime,
+			&sub.Grade,
+			&sub.RawGrade,
+			&sub.LatePenalty,
+			&sub.Feedback,
+			&userId,
+			&sub.Attempt,
+			&sub.Graded,
+			&sub.Counts,
+			&sub.Pinned,
+			&peerScore,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning row: %v", err)
+		}
+
+		sub.AssignmentId = assignmentId
+		sub.User.ID = userId.String
+
+		if peerScore.Valid {
+			sub.PeerScore = &peerScore.Float64
+		}
+		attempts = append(attempts, sub)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %v", err)
+	}
+
+	return attempts, nil
+}
+
+// SetCountedAttempt marks one of a student's attempts at an assignment
+// as the one that counts, and every other attempt as not counting.
+func (s *Store) SetCountedAttempt(
+	assignmentId, netId, submissionId string,
+) error {
+	query := `UPDATE submissions SET counts = (id = $3)
+WHERE user_id = $2 AND id IN (SELECT submission_id FROM assignment_submissions WHERE assignment_id = $1)`
+
+	_, err := s.db.Exec(query, assignmentId, netId, submissionId)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// SetPinnedAttempt pins one of a student's attempts at an assignment,
+// unpinning the rest. An empty submission ID unpins every attempt.
+func (s *Store) SetPinnedAttempt(
+	assignmentId, netId, submissionId string,
+) error {
+	query := `UPDATE submissions SET pinned = (id::text = $3)
+WHERE user_id = $2 AND id IN (SELECT submission_id FROM assignment_submissions WHERE assignment_id = $1)`
+
+	_, err := s.db.Exec(query, assignmentId, netId, submissionId)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// GetSubmissionFiles retrieves the files uploaded with a submission,
+// without their variants.
+func (s *Store) GetSubmissionFiles(submissionId string) (
+	[]*models.Media,
+	error,
+) {
+	query := `
+		SELECT m.id, m.type, m.path, COALESCE(m.name, ''), m.size, COALESCE(m.checksum, ''), m.created_at, m.scan_status
+		FROM submission_media sm
+		JOIN media m ON m.id = sm.media_id
+		WHERE sm.submission_id = $1
+		ORDER BY m.created_at
+	`
+
+	rows, err := s.db.Query(query, submissionId)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var files []*models.Media
+
+	for rows.Next() {
+		m := &models.Media{}
+
+		err := rows.Scan(
+			&m.ID,
+			&m.FileType,
+			&m.FilePath,
+			&m.FileName,
+			&m.Size,
+			&m.Checksum,
+			&m.CreatedAt,
+			&m.Status,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning row: %v", err)
+		}
+
+		m.AttributionsByType = map[string]string{"submission": submissionId}
+		files = append(files, m)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %v", err)
+	}
+
+	return files, nil
+}
+
+// GetAssignmentSubmissionMedia retrieves the media of every submission
+// made for an assignment, keyed by submission ID. Only original uploads
+// are returned, not their variants, and only for the attempts that
+// count.
+func (s *Store) GetAssignmentSubmissionMedia(assignmentId string) (
+	map[string][]*models.Media,
+	error,
+) {
+	query := `
+		SELECT sm.submission_id, m.id, m.type, m.path, COALESCE(m.name, ''), m.created_at, m.scan_status
+		FROM submission_media sm
+		JOIN media m ON m.id = sm.media_id
+		JOIN assignment_submissions a ON a.submission_id = sm.submission_id
+		JOIN submissions s ON s.id = sm.submission_id
+		WHERE a.assignment_id = $1 AND s.counts
+		ORDER BY m.created_at
+	`
+
+	rows, err := s.db.Query(query, assignmentId)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	media := make(map[string][]*models.Media)
+
+	for rows.Next() {
+		var submissionId string
+		m := &models.Media{}
+
+		err := rows.Scan(
+			&submissionId,
+			&m.ID,
+			&m.FileType,
+			&m.FilePath,
+			&m.FileName,
+			&m.CreatedAt,
+			&m.Status,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning row: %v", err)
+		}
+
+		m.AttributionsByType = map[string]string{"submission": submissionId}
+		media[submissionId] = append(media[submissionId], m)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %v", err)
+	}
+
+	return media, nil
+}
+
 // UpdateSubmission returns the submission model that was input.
 func (s *Store) UpdateSubmission(submission *models.Submission) error {
 	// Change the submission data in the database using the submission ID.
 	query := `UPDATE submissions SET grade = $1, 
-feedback = $2 WHERE id = $3 AND user_id = $4`
+feedback = $2, raw_grade = $3, late_penalty = $4, graded = TRUE WHERE id = $5 AND user_id = $6`
 	_, err := s.db.Exec(
-		query, submission.Grade, submission.Feedback, submission.ID,
-		submission.User.ID,
+		query, submission.Grade, submission.Feedback, submission.RawGrade,
+		submission.LatePenalty, submission.ID, submission.User.ID,
 	)
 	if err != nil {
 		return err
@@ -251,14 +497,15 @@ func (s *Store) InsertUser(u *models.User) error {
 func (s *Store) GetUserByID(u *models.User) (*models.User, error) {
 	// First retrieve the user using their Net ID.
 	var (
-		p, e string
-		m    int
+		p, e    string
+		m       int
+		picture sql.NullString
 	)
 
-	query := `SELECT net_id, full_name, password, email, membership FROM users WHERE net_id = $1`
+	query := `SELECT net_id, full_name, password, email, membership, profile_picture_id FROM users WHERE net_id = $1`
 
 	row := s.db.QueryRow(query, u.ID)
-	if err := row.Scan(&u.ID, &u.FullName, &p, &e, &m); err != nil {
+	if err := row.Scan(&u.ID, &u.FullName, &p, &e, &m, &picture); err != nil {
 		switch {
 		case errors.Is(err, sql.ErrNoRows):
 			return nil, ERR_RECORD_NOT_FOUND
@@ -271,6 +518,12 @@ func (s *Store) GetUserByID(u *models.User) (*models.User, error) {
 	u.Email = email(e)
 	u.Membership = Membership(m)
 
+	if picture.Valid {
+		u.ProfilePicture.ID = picture.String
+	} else {
+		u.ProfilePicture.ID = models.DefaultImageId
+	}
+
 	// Now get their courses.
 
 	var courses []string
@@ -770,14 +1023,15 @@ func (s *Store) InsertMessage(
 	m *models.Message,
 	courseid string,
 ) error {
-	query := `INSERT INTO messages (title, description, type, date) VALUES ($1, 
-$2, $3, $4) RETURNING id`
+	query := `INSERT INTO messages (title, description, type, date, owner, updated_at) VALUES ($1, 
+$2, $3, $4, $5, $4) RETURNING id`
 	row := s.db.QueryRow(
 		query,
 		m.Title,
 		m.Description,
 		m.Type,
 		m.CreatedAt,
+		nullString(m.Owner),
 	)
 
 	err := row.Scan(&m.ID)
@@ -803,9 +1057,12 @@ func (s *Store) GetMessageById(messageid string) (
 	error,
 ) {
 	message := &models.Message{}
+	var owner, course sql.NullString
 
-	query := `SELECT id, title, description, type, 
-date FROM messages WHERE id = $1`
+	query := `SELECT m.id, m.title, m.description, m.type, 
+m.date, m.owner, cm.course_id, m.locked, m.pinned, m.hidden
+FROM messages m LEFT JOIN course_messages cm ON cm.message_id = m.id
+WHERE m.id = $1`
 	row := s.db.QueryRow(query, messageid)
 
 	err := row.Scan(
@@ -814,6 +1071,11 @@ date FROM messages WHERE id = $1`
 		&message.Description,
 		&message.Type,
 		&message.Date,
+		&owner,
+		&course,
+		&message.Locked,
+		&message.Pinned,
+		&message.Hidden,
 	)
 
 	if err != nil {
@@ -823,8 +1085,12 @@ date FROM messages WHERE id = $1`
 		return nil, err
 	}
 
+	message.Owner = owner.String
+	message.Course = course.String
+
 	return message, nil
 }
+
 func (s *Store) DeleteMessageByID(id string) error {
 	query := `DELETE FROM messages WHERE id = $1`
 	var err error
@@ -884,530 +1150,4972 @@ func (s *Store) ChangeMessageBody(m *models.Message) (*models.Message, error) {
 	return updatedMessage, nil
 }
 
-func (s *Store) GetAssignmentById(assignmentid string) (
-	*models.Assignment,
-	error,
-) {
-	assignment := models.NewAssignment()
-
-	query := `SELECT id, title, description, due_date FROM assignments WHERE id = $1`
-	row := s.db.QueryRow(query, assignmentid)
+// UpdateMessage changes the title and description of a message.
+func (s *Store) UpdateMessage(m *models.Message) error {
+	query := `UPDATE messages SET title = $1, description = $2, updated_at = CURRENT_TIMESTAMP WHERE id = $3`
 
-	err := row.Scan(
-		&assignment.ID,
-		&assignment.Title,
-		&assignment.Description,
-		&assignment.DueDate,
-	)
+	_, err := s.db.Exec(query, m.Title, m.Description, m.ID)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return nil, ERR_RECORD_NOT_FOUND
-		}
-		return nil, err
+		return err
 	}
 
-	return assignment, nil
+	return nil
 }
 
-func (s *Store) InsertAssignment(a *models.Assignment) (
-	*models.Assignment,
-	error,
-) {
-	query := `INSERT INTO assignments (title, description, due_date) VALUES ($1, $2, $3) RETURNING id`
+// UpdateMessageModeration changes whether a message is locked,
+// pinned or hidden.
+func (s *Store) UpdateMessageModeration(m *models.Message) error {
+	query := `UPDATE messages SET locked = $1, pinned = $2, hidden = $3 WHERE id = $4`
 
-	row := s.db.QueryRow(query, a.Title, a.Description, a.DueDate)
+	_, err := s.db.Exec(query, m.Locked, m.Pinned, m.Hidden, m.ID)
 	if err != nil {
-		return nil, err
+		return err
 	}
-	err = row.Scan(&a.ID)
+
+	return nil
+}
+
+// GetDiscussionsByCourse retrieves a page of a course's discussions,
+// pinned discussions first and then the newest. Hidden discussions
+// are only included if includeHidden is true. The total number of
+// discussions is returned in the metadata.
+func (s *Store) GetDiscussionsByCourse(
+	courseId string,
+	includeHidden bool,
+	f models.Filters,
+) ([]*models.Message, models.Metadata, error) {
+	query := `
+		SELECT count(*) OVER(), m.id, m.title, m.description, m.date,
+		       m.owner, m.locked, m.pinned, m.hidden
+		FROM messages m
+		JOIN course_messages cm ON cm.message_id = m.id
+		WHERE cm.course_id = $1
+		  AND m.type = FALSE
+		  AND ($2 OR NOT m.hidden)
+		ORDER BY m.pinned DESC, m.date DESC, m.id
+		LIMIT $3 OFFSET $4
+	`
+
+	rows, err := s.db.Query(query, courseId, includeHidden, f.Limit(), f.Offset())
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, ERR_RECORD_NOT_FOUND
+		return nil, models.Metadata{}, err
+	}
+
+	defer rows.Close()
+
+	totalRecords := 0
+	var discussions []*models.Message
+
+	for rows.Next() {
+		var owner sql.NullString
+		m := &models.Message{}
+
+		err := rows.Scan(
+			&totalRecords,
+			&m.ID,
+			&m.Title,
+			&m.Description,
+			&m.Date,
+			&owner,
+			&m.Locked,
+			&m.Pinned,
+			&m.Hidden,
+		)
+		if err != nil {
+			return nil, models.Metadata{}, fmt.Errorf("error scanning row: %v", err)
 		}
-		return nil, err
+
+		m.Owner = owner.String
+		m.Course = courseId
+		discussions = append(discussions, m)
 	}
-	return a, err
-}
 
-func (s *Store) InsertIntoCourseAssignments(a *models.Assignment) (
-	*models.Assignment,
-	error,
-) {
-	coursequery := `INSERT INTO course_assignments (course_id, assignment_id) VALUES ($1, $2)`
-	_, err = s.db.Exec(coursequery, a.Course, a.ID)
-	if err != nil {
-		return nil, err
+	if err = rows.Err(); err != nil {
+		return nil, models.Metadata{}, fmt.Errorf("error iterating rows: %v", err)
 	}
-	return a, err
+
+	return discussions, models.NewMetadata(totalRecords, f), nil
 }
 
-func (s *Store) InsertAssignmentIntoUser(a *models.Assignment) (
-	*models.Assignment,
-	error,
-) {
-	userquery := `INSERT INTO user_assignments (user_net_id, assignment_id) VALUES ($1, $2)`
-	_, err = s.db.Exec(userquery, a.Owner, a.ID)
+// IsCourseTeacher checks whether a user teaches a course.
+func (s *Store) IsCourseTeacher(courseId, netId string) (bool, error) {
+	var exists bool
+
+	query := `SELECT EXISTS (SELECT 1 FROM course_teachers WHERE course_id = $1 AND teacher_id = $2)`
+
+	err := s.db.QueryRow(query, courseId, netId).Scan(&exists)
 	if err != nil {
-		return nil, err
+		return false, err
 	}
-	return a, err
+
+	return exists, nil
 }
 
-func (s *Store) DeleteAssignment(a *models.Assignment) error {
-	query := `DELETE FROM assignments WHERE id = $1`
+// InsertComment inserts a comment, or a reply to a comment, on a
+// discussion.
+func (s *Store) InsertComment(c *models.Comment) error {
+	query := `INSERT INTO comments (message_id, parent_id, owner, body, created_at, updated_at) VALUES ($1, $2, $3, $4, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP) RETURNING id, created_at`
 
-	_, err := s.db.Exec(query, a.ID)
+	row := s.db.QueryRow(
+		query,
+		c.MessageId,
+		nullString(c.ParentId),
+		nullString(c.Owner),
+		c.Description,
+	)
+
+	err := row.Scan(&c.ID, &c.CreatedAt)
 	if err != nil {
 		return err
 	}
 
 	return nil
 }
-func (s *Store) GetAssignmentsByCourse(courseid string) ([]string, error) {
-	query := `SELECT assignment_id FROM course_assignments WHERE course_id = $1`
-	rows, err := s.db.Query(query, courseid)
+
+// commentColumns are the columns scanned by scanComment.
+const commentColumns = `id, message_id, parent_id, owner, body, created_at, deleted_at, hidden`
+
+// rowScanner is either a *sql.Row or *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanComment(row rowScanner) (*models.Comment, error) {
+	var parent, owner sql.NullString
+	c := &models.Comment{}
+
+	err := row.Scan(
+		&c.ID,
+		&c.MessageId,
+		&parent,
+		&owner,
+		&c.Description,
+		&c.CreatedAt,
+		&c.DeletedAt,
+		&c.Hidden,
+	)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
 
-	var assignmentIds []string
+	c.ParentId = parent.String
+	c.Owner = owner.String
+	c.Deleted = c.DeletedAt.Valid
 
-	for rows.Next() {
-		var assignmentId string
-		if err := rows.Scan(&assignmentId); err != nil {
-			return nil, err
+	return c, nil
+}
+
+// GetCommentById retrieves a single comment, without its replies.
+func (s *Store) GetCommentById(id string) (*models.Comment, error) {
+	query := `SELECT ` + commentColumns + ` FROM comments WHERE id = $1`
+
+	c, err := scanComment(s.db.QueryRow(query, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ERR_RECORD_NOT_FOUND
 		}
-		assignmentIds = append(assignmentIds, assignmentId)
-	}
-	if err := rows.Err(); err != nil {
 		return nil, err
 	}
-	return assignmentIds, nil
+
+	return c, nil
 }
 
-func (s *Store) ChangeAssignmentTitle(
-	assignment *models.Assignment,
-	title string,
-) (*models.Assignment, error) {
-	query := `UPDATE assignments SET title = $1 WHERE id = $2 RETURNING id, title, description, due_date, course_id`
+// GetComments retrieves a page of a discussion's top level comments,
+// oldest first, along with every reply beneath them. The comments
+// are returned flat, parents before their replies. Hidden comments,
+// and the replies beneath them, are only included if includeHidden
+// is true. The metadata counts top level comments.
+func (s *Store) GetComments(
+	messageId string,
+	includeHidden bool,
+	f models.Filters,
+) ([]*models.Comment, models.Metadata, error) {
+	var totalRecords int
+
+	countQuery := `SELECT count(*) FROM comments WHERE message_id = $1 AND parent_id IS NULL AND ($2 OR NOT hidden)`
+
+	err := s.db.QueryRow(countQuery, messageId, includeHidden).Scan(&totalRecords)
+	if err != nil {
+		return nil, models.Metadata{}, err
+	}
 
-	row := s.db.QueryRow(query, title, assignment.ID)
+	query := `
+		WITH RECURSIVE thread AS (
+			(
+				SELECT ` + commentColumns + `, 0 AS depth
+				FROM comments
+				WHERE message_id = $1 AND parent_id IS NULL AND ($2 OR NOT hidden)
+				ORDER BY created_at, id
+				LIMIT $3 OFFSET $4
+			)
+			UNION ALL
+			SELECT c.id, c.message_id, c.parent_id, c.owner, c.body,
+			       c.created_at, c.deleted_at, c.hidden, t.depth + 1
+			FROM comments c
+			JOIN thread t ON c.parent_id = t.id
+			WHERE $2 OR NOT c.hidden
+		)
+		SELECT ` + commentColumns + ` FROM thread ORDER BY depth, created_at, id
+	`
 
-	updatedAssignment := &models.Assignment{}
-	err := row.Scan(
-		&updatedAssignment.ID,
-		&updatedAssignment.Title,
-		&updatedAssignment.Description,
-		&updatedAssignment.DueDate,
-		&updatedAssignment.Course,
-	)
+	rows, err := s.db.Query(query, messageId, includeHidden, f.Limit(), f.Offset())
 	if err != nil {
-		return nil, err
+		return nil, models.Metadata{}, err
 	}
 
-	return updatedAssignment, nil
-}
+	defer rows.Close()
 
-func (s *Store) ChangeAssignmentBody(
-	assignment *models.Assignment,
-	body string,
-) (*models.Assignment, error) {
-	query := `UPDATE assignments SET description = $1 WHERE id = $2 RETURNING id, title, description, due_date, course_id`
+	var comments []*models.Comment
 
-	row := s.db.QueryRow(query, body, assignment.ID)
+	for rows.Next() {
+		c, err := scanComment(rows)
+		if err != nil {
+			return nil, models.Metadata{}, fmt.Errorf("error scanning row: %v", err)
+		}
+		comments = append(comments, c)
+	}
 
-	updatedAssignment := &models.Assignment{}
-	err := row.Scan(
-		&updatedAssignment.ID,
-		&updatedAssignment.Title,
-		&updatedAssignment.Description,
-		&updatedAssignment.DueDate,
-		&updatedAssignment.Course,
-	)
+	if err = rows.Err(); err != nil {
+		return nil, models.Metadata{}, fmt.Errorf("error iterating rows: %v", err)
+	}
+
+	return comments, models.NewMetadata(totalRecords, f), nil
+}
+
+// UpdateCommentBody changes the text of a comment.
+func (s *Store) UpdateCommentBody(c *models.Comment) error {
+	query := `UPDATE comments SET body = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2`
+
+	_, err := s.db.Exec(query, c.Description, c.ID)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	return updatedAssignment, nil
+	return nil
 }
 
-// InsertToken inserts a created token for a user.
-func (s *Store) InsertToken(t *models.Token) error {
-	query := `INSERT INTO tokens (hash, net_id, expiry, scope) VALUES ($1, $2, $3, $4)`
-	args := []any{t.Hash, t.NetID, t.Expiry, t.Scope}
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-	defer cancel()
-	_, err := s.db.ExecContext(ctx, query, args...)
+// UpdateCommentHidden hides or unhides a comment.
+func (s *Store) UpdateCommentHidden(c *models.Comment) error {
+	query := `UPDATE comments SET hidden = $1 WHERE id = $2`
 
-	return err
+	_, err := s.db.Exec(query, c.Hidden, c.ID)
+	if err != nil {
+		return err
+	}
+
+	return nil
 }
 
-func (s *Store) GetTokenFromNetId(t *models.Token) (*models.Token, error) {
-	query := `SELECT hash, expiry, scope FROM tokens WHERE net_id = $1`
+// DeleteComment removes the text of a comment and marks it deleted.
+// The row is kept so that its replies stay in the thread.
+func (s *Store) DeleteComment(id string) error {
+	query := `UPDATE comments SET body = '', deleted_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP WHERE id = $1`
 
-	row := s.db.QueryRow(query, t.NetID)
+	_, err := s.db.Exec(query, id)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (s *Store) GetAssignmentById(assignmentid string) (
+	*models.Assignment,
+	error,
+) {
+	assignment := models.NewAssignment()
+	var fileTypes, rubricId, interval, countedAttempt, anonymousKey sql.NullString
+	var grace, maxLateness sql.NullInt64
+	var cutoff sql.NullBool
+	var releaseAt, allocatedAt sql.NullTime
+	var publishAt, availableFrom, lockAt sql.NullTime
+	var deduction, peerWeight sql.NullFloat64
+	var peerReviewers sql.NullInt64
+
+	query := `SELECT a.id, a.title, a.description, a.due_date, a.allowed_file_types, a.rubric_id, a.max_attempts, a.counted_attempt, a.anonymous, a.anonymous_key, a.grades_released, a.release_at, a.regrade_window, a.state, a.publish_at, a.available_from, a.lock_at, lp.grace_period, lp.hard_cutoff, lp.deduction, lp.deduction_interval, lp.max_lateness, pr.reviewers, pr.weight, pr.allocated_at FROM assignments a LEFT JOIN late_policies lp ON lp.assignment_id = a.id LEFT JOIN peer_review_policies pr ON pr.assignment_id = a.id WHERE a.id = $1`
+	row := s.db.QueryRow(query, assignmentid)
 
 	err := row.Scan(
-		&t.Hash,
-		&t.Expiry,
-		&t.Scope,
+		&assignment.ID,
+		&assignment.Title,
+		&assignment.Description,
+		&assignment.DueDate,
+		&fileTypes,
+		&rubricId,
+		&assignment.MaxAttempts,
+		&countedAttempt,
+		&assignment.Anonymous,
+		&anonymousKey,
+		&assignment.GradesReleased,
+		&releaseAt,
+		&assignment.RegradeWindow,
+		&assignment.State,
+		&publishAt,
+		&availableFrom,
+		&lockAt,
+		&grace,
+		&cutoff,
+		&deduction,
+		&interval,
+		&maxLateness,
+		&peerReviewers,
+		&peerWeight,
+		&allocatedAt,
 	)
 	if err != nil {
-		switch err {
-		case sql.ErrNoRows:
+		if errors.Is(err, sql.ErrNoRows) {
 			return nil, ERR_RECORD_NOT_FOUND
-		default:
+		}
+		return nil, err
+	}
+
+	if fileTypes.Valid {
+		assignment.FileTypes, err = models.ParseFileTypes(
+			strings.Split(fileTypes.String, ",")...,
+		)
+		if err != nil {
 			return nil, err
 		}
 	}
 
-	return t, nil
-}
+	assignment.RubricId = rubricId.String
+	assignment.CountedAttempt = models.AttemptSelection(countedAttempt.String)
+	assignment.AnonymousKey = anonymousKey.String
 
-// DeleteTokenFrom deletes a user's authentication Token using their
-// Net ID.
-func (s *Store) DeleteTokenFrom(netId, scope string) error {
-	query := `DELETE FROM tokens WHERE scope = $1 AND net_id = $2`
+	if releaseAt.Valid {
+		assignment.ReleaseAt = &releaseAt.Time
+	}
 
-	args := []any{scope, netId}
+	if publishAt.Valid {
+		assignment.PublishAt = &publishAt.Time
+	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-	defer cancel()
+	if availableFrom.Valid {
+		assignment.AvailableFrom = &availableFrom.Time
+	}
 
-	_, err := s.db.ExecContext(ctx, query, args...)
-	return err
+	if lockAt.Valid {
+		assignment.LockAt = &lockAt.Time
+	}
+
+	// The policy's columns are all null when the assignment has none.
+	if grace.Valid {
+		assignment.LatePolicy = &models.LatePolicy{
+			AssignmentId: assignment.ID,
+			GracePeriod:  int(grace.Int64),
+			HardCutoff:   cutoff.Bool,
+			Deduction:    deduction.Float64,
+			Interval:     models.LateInterval(interval.String),
+			MaxLateness:  int(maxLateness.Int64),
+		}
+	}
+
+	if peerReviewers.Valid {
+		assignment.PeerReview = &models.PeerReviewPolicy{
+			AssignmentId: assignment.ID,
+			Reviewers:    int(peerReviewers.Int64),
+			Weight:       peerWeight.Float64,
+		}
+
+		if allocatedAt.Valid {
+			assignment.PeerReview.AllocatedAt = &allocatedAt.Time
+		}
+	}
+
+	return assignment, nil
 }
 
-// ##################
-//  JUNCTION METHODS
-// ##################
-//
-// Junction methods function upon junction tables. They change
-// the relationships between database objects.
+// SaveLatePolicy sets an assignment's late policy, replacing the
+// policy it had before.
+func (s *Store) SaveLatePolicy(p *models.LatePolicy) error {
+	query := `INSERT INTO late_policies (assignment_id, grace_period, hard_cutoff, deduction, deduction_interval, max_lateness) VALUES ($1, $2, $3, $4, $5, $6) ON CONFLICT (assignment_id) DO UPDATE SET grace_period = EXCLUDED.grace_period, hard_cutoff = EXCLUDED.hard_cutoff, deduction = EXCLUDED.deduction, deduction_interval = EXCLUDED.deduction_interval, max_lateness = EXCLUDED.max_lateness`
 
-// AddTeacher adds a teacher to a specified course, using the teacher's
-// userId. This method uses junction tables to assign relationships.
-func (s *Store) AddTeacher(courseId string, userId string) error {
-	// Start a transaction
-	tx, err := s.db.Begin()
+	_, err := s.db.Exec(
+		query,
+		p.AssignmentId,
+		p.GracePeriod,
+		p.HardCutoff,
+		p.Deduction,
+		nullString(string(p.Interval)),
+		p.MaxLateness,
+	)
 	if err != nil {
-		return fmt.Errorf("error starting transaction: %v", err)
+		return err
 	}
 
-	// Check if the course exists
-	var exists bool
-	err = tx.QueryRow(
-		"SELECT EXISTS(SELECT 1 FROM courses WHERE id = $1)",
-		courseId,
-	).Scan(&exists)
+	return nil
+}
+
+// SaveAttemptPolicy sets how many attempts an assignment allows and
+// which of them counts.
+func (s *Store) SaveAttemptPolicy(
+	assignmentId string,
+	maxAttempts int,
+	selection models.AttemptSelection,
+) error {
+	query := `UPDATE assignments SET max_attempts = $1, counted_attempt = $2 WHERE id = $3`
 
+	_, err := s.db.Exec(
+		query,
+		maxAttempts,
+		nullString(string(selection)),
+		assignmentId,
+	)
 	if err != nil {
-		tx.Rollback()
-		return fmt.Errorf("error checking course existence: %v", err)
+		return err
 	}
 
-	if !exists {
-		tx.Rollback()
-		return fmt.Errorf("course with ID %s does not exist", courseId)
-	}
+	return nil
+}
 
-	// Check if the teacher exists
-	err = tx.QueryRow(
-		"SELECT EXISTS(SELECT 1 FROM users WHERE net_id = $1)",
-		userId,
-	).Scan(&exists)
+// SaveAnonymity sets whether an assignment is graded anonymously, and
+// the key its pseudonyms are derived from.
+func (s *Store) SaveAnonymity(a *models.Assignment) error {
+	query := `UPDATE assignments SET anonymous = $1, anonymous_key = $2 WHERE id = $3`
 
+	_, err := s.db.Exec(
+		query,
+		a.Anonymous,
+		nullString(a.AnonymousKey),
+		a.ID,
+	)
 	if err != nil {
-		tx.Rollback()
-		return fmt.Errorf("error checking teacher existence: %v", err)
+		return err
 	}
 
-	if !exists {
-		tx.Rollback()
-		return fmt.Errorf("teacher with ID %s does not exist", userId)
-	}
+	return nil
+}
 
-	// Insert the new relationship into the junction table
-	_, err = tx.Exec(
-		"INSERT INTO course_teachers (course_id, teacher_id) VALUES ($1, $2)",
-		courseId,
-		userId,
-	)
+// SaveGradeRelease sets whether an assignment's grades are released,
+// and when they are scheduled to be.
+func (s *Store) SaveGradeRelease(a *models.Assignment) error {
+	query := `UPDATE assignments SET grades_released = $1, release_at = $2 WHERE id = $3`
 
-	if err != nil {
-		tx.Rollback()
-		return fmt.Errorf("error inserting into course_teachers: %v", err)
+	var releaseAt sql.NullTime
+	if a.ReleaseAt != nil {
+		releaseAt = nullTime(*a.ReleaseAt)
 	}
 
-	// Commit the transaction
-	if err = tx.Commit(); err != nil {
-		tx.Rollback()
-		return fmt.Errorf("error committing transaction: %v", err)
+	_, err := s.db.Exec(query, a.GradesReleased, releaseAt, a.ID)
+	if err != nil {
+		return err
 	}
 
 	return nil
 }
 
-func (s *Store) ChangeAssignmentDueDate(
-	assignment *models.Assignment,
-	duedate time.Time,
-) (*models.Assignment, error) {
-	return nil, nil
+// SaveRegradeWindow sets how many seconds after grades are released
+// students may request a regrade.
+func (s *Store) SaveRegradeWindow(assignmentId string, window int) error {
+	query := `UPDATE assignments SET regrade_window = $1 WHERE id = $2`
+
+	_, err := s.db.Exec(query, window, assignmentId)
+	if err != nil {
+		return err
+	}
+
+	return nil
 }
 
-func (s *Store) GetMediaReferenceById(media *models.Media) error {
+// SaveSchedule sets an assignment's state, and when it is published,
+// available from and locked.
+func (s *Store) SaveSchedule(a *models.Assignment) error {
+	query := `UPDATE assignments SET state = $1, publish_at = $2, available_from = $3, lock_at = $4 WHERE id = $5`
+
+	_, err := s.db.Exec(
+		query,
+		a.State,
+		nullTimePtr(a.PublishAt),
+		nullTimePtr(a.AvailableFrom),
+		nullTimePtr(a.LockAt),
+		a.ID,
+	)
+	if err != nil {
+		return err
+	}
+
 	return nil
 }
 
-// AddStudent uses junction tables to insert a new student
-// into a course.
-func (s *Store) AddStudent(c *models.Course, userid string) (
-	*models.Course,
-	error,
-) {
-	query := `INSERT INTO course_roster (course_id, student_id) VALUES ($1, $2)`
+// GetAssignmentsDueForSchedule retrieves the IDs of the drafts that
+// are scheduled to be published by a time, and of the published
+// assignments that are scheduled to be locked by it.
+func (s *Store) GetAssignmentsDueForSchedule(now time.Time) (
+	[]string,
+	error,
+) {
+	query := `SELECT id FROM assignments WHERE (state = 'draft' AND publish_at <= $1) OR (state = 'published' AND lock_at <= $1)`
+
+	rows, err := s.db.Query(query, now.UTC())
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var ids []string
+
+	for rows.Next() {
+		var id string
+
+		err := rows.Scan(&id)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning row: %v", err)
+		}
+		ids = append(ids, id)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %v", err)
+	}
+
+	return ids, nil
+}
+
+// GetAssignmentsDueForRelease retrieves the IDs of the assignments
+// whose grades are scheduled to be released by a time, but have not
+// been released yet.
+func (s *Store) GetAssignmentsDueForRelease(now time.Time) ([]string, error) {
+	query := `SELECT id FROM assignments WHERE NOT grades_released AND release_at <= $1`
+
+	rows, err := s.db.Query(query, now.UTC())
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var ids []string
+
+	for rows.Next() {
+		var id string
+
+		err := rows.Scan(&id)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning row: %v", err)
+		}
+		ids = append(ids, id)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %v", err)
+	}
+
+	return ids, nil
+}
+
+// DeleteLatePolicy removes an assignment's late policy, so that late
+// submissions are accepted without a penalty.
+func (s *Store) DeleteLatePolicy(assignmentId string) error {
+	query := `DELETE FROM late_policies WHERE assignment_id = $1`
+
+	_, err := s.db.Exec(query, assignmentId)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (s *Store) InsertAssignment(a *models.Assignment) (
+	*models.Assignment,
+	error,
+) {
+	query := `INSERT INTO assignments (title, description, due_date, allowed_file_types, state, publish_at, available_from, lock_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id`
+
+	row := s.db.QueryRow(
+		query,
+		a.Title,
+		a.Description,
+		a.DueDate,
+		a.FileTypes.String(),
+		a.State,
+		nullTimePtr(a.PublishAt),
+		nullTimePtr(a.AvailableFrom),
+		nullTimePtr(a.LockAt),
+	)
+	if err != nil {
+		return nil, err
+	}
+	err = row.Scan(&a.ID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ERR_RECORD_NOT_FOUND
+		}
+		return nil, err
+	}
+	return a, err
+}
+
+func (s *Store) InsertIntoCourseAssignments(a *models.Assignment) (
+	*models.Assignment,
+	error,
+) {
+	coursequery := `INSERT INTO course_assignments (course_id, assignment_id) VALUES ($1, $2)`
+	_, err = s.db.Exec(coursequery, a.Course, a.ID)
+	if err != nil {
+		return nil, err
+	}
+	return a, err
+}
+
+func (s *Store) InsertAssignmentIntoUser(a *models.Assignment) (
+	*models.Assignment,
+	error,
+) {
+	userquery := `INSERT INTO user_assignments (user_net_id, assignment_id) VALUES ($1, $2)`
+	_, err = s.db.Exec(userquery, a.Owner, a.ID)
+	if err != nil {
+		return nil, err
+	}
+	return a, err
+}
+
+func (s *Store) DeleteAssignment(a *models.Assignment) error {
+	query := `DELETE FROM assignments WHERE id = $1`
+
+	_, err := s.db.Exec(query, a.ID)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+func (s *Store) GetAssignmentsByCourse(courseid string) ([]string, error) {
+	query := `SELECT assignment_id FROM course_assignments WHERE course_id = $1`
+	rows, err := s.db.Query(query, courseid)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var assignmentIds []string
+
+	for rows.Next() {
+		var assignmentId string
+		if err := rows.Scan(&assignmentId); err != nil {
+			return nil, err
+		}
+		assignmentIds = append(assignmentIds, assignmentId)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return assignmentIds, nil
+}
+
+func (s *Store) ChangeAssignmentTitle(
+	assignment *models.Assignment,
+	title string,
+) (*models.Assignment, error) {
+	query := `UPDATE assignments SET title = $1 WHERE id = $2 RETURNING id, title, description, due_date, course_id`
+
+	row := s.db.QueryRow(query, title, assignment.ID)
+
+	updatedAssignment := &models.Assignment{}
+	err := row.Scan(
+		&updatedAssignment.ID,
+		&updatedAssignment.Title,
+		&updatedAssignment.Description,
+		&updatedAssignment.DueDate,
+		&updatedAssignment.Course,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return updatedAssignment, nil
+}
+
+// ChangeAssignmentFileTypes replaces the types of files accepted for
+// an assignment's submissions.
+func (s *Store) ChangeAssignmentFileTypes(
+	assignment *models.Assignment,
+) (*models.Assignment, error) {
+	query := `UPDATE assignments SET allowed_file_types = $1 WHERE id = $2`
+
+	_, err := s.db.Exec(query, assignment.FileTypes.String(), assignment.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return assignment, nil
+}
+
+// ChangeAssignmentRubric sets the rubric an assignment's submissions
+// are graded against. An empty rubric ID removes the rubric.
+func (s *Store) ChangeAssignmentRubric(
+	assignment *models.Assignment,
+) (*models.Assignment, error) {
+	query := `UPDATE assignments SET rubric_id = $1 WHERE id = $2`
+
+	_, err := s.db.Exec(query, nullString(assignment.RubricId), assignment.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return assignment, nil
+}
+
+func (s *Store) ChangeAssignmentBody(
+	assignment *models.Assignment,
+	body string,
+) (*models.Assignment, error) {
+	query := `UPDATE assignments SET description = $1 WHERE id = $2 RETURNING id, title, description, due_date, course_id`
+
+	row := s.db.QueryRow(query, body, assignment.ID)
+
+	updatedAssignment := &models.Assignment{}
+	err := row.Scan(
+		&updatedAssignment.ID,
+		&updatedAssignment.Title,
+		&updatedAssignment.Description,
+		&updatedAssignment.DueDate,
+		&updatedAssignment.Course,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return updatedAssignment, nil
+}
+
+// InsertToken inserts a created token for a user.
+func (s *Store) InsertToken(t *models.Token) error {
+	query := `INSERT INTO tokens (hash, net_id, expiry, scope) VALUES ($1, $2, $3, $4)`
+	args := []any{t.Hash, t.NetID, t.Expiry, t.Scope}
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	_, err := s.db.ExecContext(ctx, query, args...)
+
+	return err
+}
+
+func (s *Store) GetTokenFromNetId(t *models.Token) (*models.Token, error) {
+	query := `SELECT hash, expiry, scope FROM tokens WHERE net_id = $1`
+
+	row := s.db.QueryRow(query, t.NetID)
+
+	err := row.Scan(
+		&t.Hash,
+		&t.Expiry,
+		&t.Scope,
+	)
+	if err != nil {
+		switch err {
+		case sql.ErrNoRows:
+			return nil, ERR_RECORD_NOT_FOUND
+		default:
+			return nil, err
+		}
+	}
+
+	return t, nil
+}
+
+// DeleteTokenFrom deletes a user's authentication Token using their
+// Net ID.
+func (s *Store) DeleteTokenFrom(netId, scope string) error {
+	query := `DELETE FROM tokens WHERE scope = $1 AND net_id = $2`
+
+	args := []any{scope, netId}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx, query, args...)
+	return err
+}
+
+// ##################
+//  JUNCTION METHODS
+// ##################
+//
+// Junction methods function upon junction tables. They change
+// the relationships between database objects.
+
+// AddTeacher adds a teacher to a specified course, using the teacher's
+// userId. This method uses junction tables to assign relationships.
+func (s *Store) AddTeacher(courseId string, userId string) error {
+	// Start a transaction
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %v", err)
+	}
+
+	// Check if the course exists
+	var exists bool
+	err = tx.QueryRow(
+		"SELECT EXISTS(SELECT 1 FROM courses WHERE id = $1)",
+		courseId,
+	).Scan(&exists)
+
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("error checking course existence: %v", err)
+	}
+
+	if !exists {
+		tx.Rollback()
+		return fmt.Errorf("course with ID %s does not exist", courseId)
+	}
+
+	// Check if the teacher exists
+	err = tx.QueryRow(
+		"SELECT EXISTS(SELECT 1 FROM users WHERE net_id = $1)",
+		userId,
+	).Scan(&exists)
+
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("error checking teacher existence: %v", err)
+	}
+
+	if !exists {
+		tx.Rollback()
+		return fmt.Errorf("teacher with ID %s does not exist", userId)
+	}
+
+	// Insert the new relationship into the junction table
+	_, err = tx.Exec(
+		"INSERT INTO course_teachers (course_id, teacher_id) VALUES ($1, $2)",
+		courseId,
+		userId,
+	)
+
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("error inserting into course_teachers: %v", err)
+	}
+
+	// Commit the transaction
+	if err = tx.Commit(); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("error committing transaction: %v", err)
+	}
+
+	return nil
+}
+
+func (s *Store) ChangeAssignmentDueDate(
+	assignment *models.Assignment,
+	duedate time.Time,
+) (*models.Assignment, error) {
+	return nil, nil
+}
+
+func (s *Store) GetMediaReferenceById(media *models.Media) error {
+	return nil
+}
+
+// AddStudent uses junction tables to insert a new student
+// into a course.
+func (s *Store) AddStudent(c *models.Course, userid string) (
+	*models.Course,
+	error,
+) {
+	query := `INSERT INTO course_roster (course_id, student_id) VALUES ($1, $2)`
+
+	_, err := s.db.Exec(query, c.ID, userid)
+	if err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+func (s *Store) RemoveStudent(c *models.Course, userid string) (
+	*models.Course,
+	error,
+) {
+	query := `DELETE FROM course_roster WHERE student_id=$1 AND course_id=$2`
+
+	_, err := s.db.Exec(query, userid, c.ID)
+	if err != nil {
+		return nil, err
+	}
+	query = `DELETE FROM user_courses WHERE user_net_id=$1 AND course_id=$2`
+
+	_, err = s.db.Exec(query, userid, c.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+func (s *Store) InsertSubmission(
+	sub *models.Submission,
+) (
+	*models.Submission,
+	error,
+) {
+	query := `INSERT INTO submissions (submission_time, on_time, grade, feedback, user_id, attempt, counts) VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id`
+
+	row := s.db.QueryRow(
+		query,
+		&sub.SubmissionTime,
+		&sub.OnTime,
+		&sub.Grade,
+		&sub.Feedback,
+		nullString(sub.User.ID),
+		sub.Attempt,
+		sub.Counts,
+	)
+	err := row.Scan(
+		&sub.ID,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ERR_RECORD_NOT_FOUND
+		}
+		return nil, err
+	}
+	return sub, nil
+}
+
+// InsertAttempt inserts a submission as its student's next attempt at
+// its assignment, numbering it after their last one. The assignment is
+// locked while attempts are counted, so that attempts made at the same
+// time cannot together go over maxAttempts. ERR_ATTEMPT_LIMIT is
+// returned when the student has none left. A maxAttempts of zero
+// allows any number of attempts.
+func (s *Store) InsertAttempt(sub *models.Submission, maxAttempts int) (
+	*models.Submission,
+	error,
+) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`SELECT id FROM assignments WHERE id = $1 FOR UPDATE`, sub.AssignmentId)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT count(*), COALESCE(max(s.attempt), 0)
+		FROM submissions s
+		JOIN assignment_submissions a ON a.submission_id = s.id
+		WHERE a.assignment_id = $1 AND s.user_id = $2
+	`
+
+	var count, last int
+
+	err = tx.QueryRow(query, sub.AssignmentId, sub.User.ID).Scan(&count, &last)
+	if err != nil {
+		return nil, err
+	}
+
+	if maxAttempts > 0 && count >= maxAttempts {
+		return nil, ERR_ATTEMPT_LIMIT
+	}
+
+	sub.Attempt = last + 1
+
+	query = `INSERT INTO submissions (submission_time, on_time, grade, feedback, user_id, attempt, counts) VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id`
+
+	err = tx.QueryRow(
+		query,
+		sub.SubmissionTime,
+		sub.OnTime,
+		sub.Grade,
+		sub.Feedback,
+		nullString(sub.User.ID),
+		sub.Attempt,
+		sub.Counts,
+	).Scan(&sub.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.Exec(
+		`INSERT INTO assignment_submissions (assignment_id, submission_id) VALUES ($1, $2)`,
+		sub.AssignmentId,
+		sub.ID,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.Exec(
+		`INSERT INTO user_submissions (user_net_id, submission_id) VALUES ($1, $2)`,
+		sub.User.ID,
+		sub.ID,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	return sub, nil
+}
+
+func (s *Store) InsertSubmissionIntoAssignment(sub *models.Submission) (*models.Submission, error) {
+	query := `INSERT INTO assignment_submissions (assignment_id, submission_id) VALUES ($1, $2)`
+
+	_, err := s.db.Exec(query, sub.AssignmentId, sub.ID)
+	if err != nil {
+		return nil, err
+	}
+	return sub, nil
+}
+func (s *Store) InsertSubmissionIntoUser(sub *models.Submission) (*models.Submission, error) {
+	query := `INSERT INTO user_submissions (user_net_id, submission_id) VALUES ($1, $2)`
+
+	_, err := s.db.Exec(query, sub.User.ID, sub.ID)
+	if err != nil {
+		return nil, err
+	}
+	return sub, nil
+}
+
+func (s *Store) GradeSubmission(
+	grade float64,
+	submission *models.Submission,
+) error {
+	return nil
+}
+
+func (s *Store) InsertSubmissionFeedback(
+	feedback string,
+	submission *models.Submission,
+) error {
+	return nil
+}
+
+func (s *Store) GetMembershipById(userid string) (
+	*models.Credential,
+	error,
+) {
+	u := &models.User{}
+
+	var m int
+
+	query := `SELECT id, membership FROM users WHERE net_id = $1`
+	row := s.db.QueryRow(query, userid)
+
+	err := row.Scan(
+		&u.ID,
+		&m,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ERR_RECORD_NOT_FOUND
+		}
+		return nil, err
+	}
+	u.Membership = Membership(m)
+	return &u.Membership, nil
+}
+
+func (s *Store) GetNetIdFromHash(hash []byte) (
+	string,
+	error,
+) {
+	u := &models.User{}
+	query := `SELECT net_id FROM tokens WHERE hash = $1`
+	row := s.db.QueryRow(query, hash)
+
+	err = row.Scan(
+		&u.ID,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", ERR_RECORD_NOT_FOUND
+		}
+		return "", err
+	}
+	return u.ID, nil
+}
+
+func (s *Store) GetNameById(userid string) (
+	*models.Credential,
+	error,
+) {
+	u := &models.User{}
+
+	var m int
+
+	query := `SELECT id, membership FROM users WHERE net_id = $1`
+	row := s.db.QueryRow(query, userid)
+
+	err := row.Scan(
+		&u.ID,
+		&m,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ERR_RECORD_NOT_FOUND
+		}
+		return nil, err
+	}
+	u.Membership = Membership(m)
+	return &u.Membership, nil
+}
+
+func (s *Store) InsertMedia(
+	m *models.Media,
+) (
+	*models.Media,
+	error,
+) {
+	query := `INSERT INTO media (type, path, created_at, updated_at, parent_id, variant, size, scan_status, threat, name, checksum) VALUES ($1, $2, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP, $3, $4, $5, $6, $7, $8, $9) RETURNING id, created_at`
+
+	if m.Status == "" {
+		m.Status = models.UNSCANNED
+	}
+
+	row := s.db.QueryRow(
+		query,
+		m.FileType,
+		m.FilePath,
+		nullString(m.ParentId),
+		nullString(string(m.Variant)),
+		m.Size,
+		m.Status,
+		nullString(m.Threat),
+		nullString(m.FileName),
+		nullString(m.Checksum),
+	)
+	err := row.Scan(&m.ID, &m.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+func (s *Store) GetMediaById(mediaId string) (
+	*models.Media,
+	error,
+) {
+	media := &models.Media{}
+
+	var threat, name, checksum sql.NullString
+
+	query := `SELECT id, type, path, scan_status, threat, name, checksum FROM media WHERE id = $1`
+	row := s.db.QueryRow(query, mediaId)
+
+	err := row.Scan(
+		&media.ID,
+		&media.FileType,
+		&media.FilePath,
+		&media.Status,
+		&threat,
+		&name,
+		&checksum,
+	)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ERR_RECORD_NOT_FOUND
+		}
+		return nil, err
+	}
+
+	media.Threat = threat.String
+	media.FileName = name.String
+	media.Checksum = checksum.String
+
+	return media, nil
+}
+
+// mediaReferences lists every table column that refers to a media
+// record. Media that none of these refer to, either directly or
+// through its parent, is garbage.
+var mediaReferences = []string{
+	"course_media.media_id",
+	"courses.banner_id",
+	"users.profile_picture_id",
+	"assignment_media.media_id",
+	"submission_media.media_id",
+	"message_media.media_id",
+	"team_media.media_id",
+	"harness_media.media_id",
+	"feedback_media.media_id",
+	"module_items.media_id",
+}
+
+// GetStoredMedia retrieves every media record, along with whether
+// it is still referenced by anything in the database.
+func (s *Store) GetStoredMedia() ([]*models.StoredMedia, error) {
+	exists := make([]string, len(mediaReferences))
+	for i, ref := range mediaReferences {
+		table, column, _ := strings.Cut(ref, ".")
+		exists[i] = fmt.Sprintf(
+			"EXISTS (SELECT 1 FROM %s r WHERE r.%s = COALESCE(m.parent_id, m.id))",
+			table,
+			column,
+		)
+	}
+
+	query := `SELECT m.id, m.type, m.path, m.created_at, m.parent_id, ` +
+		strings.Join(exists, " OR ") + ` FROM media m`
+
+	rows, err := s.db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var media []*models.StoredMedia
+
+	for rows.Next() {
+		var parentId sql.NullString
+		m := &models.StoredMedia{}
+
+		err := rows.Scan(
+			&m.ID,
+			&m.FileType,
+			&m.FilePath,
+			&m.CreatedAt,
+			&parentId,
+			&m.Referenced,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning row: %v", err)
+		}
+
+		m.ParentId = parentId.String
+		media = append(media, m)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %v", err)
+	}
+
+	return media, nil
+}
+
+// GetCourseMedia retrieves every piece of media stored for a course,
+// including its banners, assignment media, submission media, feedback
+// media and team media. Each media's AttributionsByType records what
+// it belongs to.
+func (s *Store) GetCourseMedia(courseId string) ([]*models.Media, error) {
+	query := `
+		SELECT m.id, m.type, m.path, 'course', cm.course_id
+		FROM media m
+		JOIN course_media cm ON cm.media_id = COALESCE(m.parent_id, m.id)
+		WHERE cm.course_id = $1
+		UNION ALL
+		SELECT m.id, m.type, m.path, 'assignment', am.assignment_id
+		FROM media m
+		JOIN assignment_media am ON am.media_id = COALESCE(m.parent_id, m.id)
+		JOIN course_assignments ca ON ca.assignment_id = am.assignment_id
+		WHERE ca.course_id = $1
+		UNION ALL
+		SELECT m.id, m.type, m.path, 'submission', sm.submission_id
+		FROM media m
+		JOIN submission_media sm ON sm.media_id = COALESCE(m.parent_id, m.id)
+		JOIN assignment_submissions asub ON asub.submission_id = sm.submission_id
+		JOIN course_assignments ca ON ca.assignment_id = asub.assignment_id
+		WHERE ca.course_id = $1
+		UNION ALL
+		SELECT m.id, m.type, m.path, 'feedback', fm.submission_id
+		FROM media m
+		JOIN feedback_media fm ON fm.media_id = COALESCE(m.parent_id, m.id)
+		JOIN assignment_submissions asub ON asub.submission_id = fm.submission_id
+		JOIN course_assignments ca ON ca.assignment_id = asub.assignment_id
+		WHERE ca.course_id = $1
+		UNION ALL
+		SELECT m.id, m.type, m.path, 'team', tm.team_id
+		FROM media m
+		JOIN team_media tm ON tm.media_id = COALESCE(m.parent_id, m.id)
+		JOIN teams t ON t.id = tm.team_id
+		JOIN projects p ON p.id = t.project_id
+		WHERE p.course_id = $1
+		UNION ALL
+		SELECT m.id, m.type, m.path, 'harness', hm.assignment_id
+		FROM media m
+		JOIN harness_media hm ON hm.media_id = COALESCE(m.parent_id, m.id)
+		JOIN course_assignments ca ON ca.assignment_id = hm.assignment_id
+		WHERE ca.course_id = $1
+		UNION ALL
+		SELECT m.id, m.type, m.path, 'module', mi.module_id
+		FROM media m
+		JOIN module_items mi ON mi.media_id = COALESCE(m.parent_id, m.id)
+		JOIN modules mo ON mo.id = mi.module_id
+		WHERE mo.course_id = $1
+	`
+
+	rows, err := s.db.Query(query, courseId)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var media []*models.Media
+
+	for rows.Next() {
+		var kind, owner string
+		m := &models.Media{}
+
+		err := rows.Scan(&m.ID, &m.FileType, &m.FilePath, &kind, &owner)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning row: %v", err)
+		}
+
+		m.AttributionsByType = map[string]string{kind: owner}
+		media = append(media, m)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %v", err)
+	}
+
+	return media, nil
+}
+
+// GetCourseBannerId retrieves the ID of a course's current banner. An
+// empty string is returned if the course has no banner.
+func (s *Store) GetCourseBannerId(courseId string) (string, error) {
+	var bannerId sql.NullString
+
+	query := `SELECT banner_id FROM courses WHERE id = $1`
+
+	err := s.db.QueryRow(query, courseId).Scan(&bannerId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", ERR_RECORD_NOT_FOUND
+		}
+		return "", err
+	}
+
+	return bannerId.String, nil
+}
+
+// GetCourseStorageUsage sums the size of every piece of media a
+// course refers to, including its assignments', submissions',
+// feedback and project teams' media and their variants.
+func (s *Store) GetCourseStorageUsage(courseId string) (int64, error) {
+	var used int64
+
+	query := `
+		SELECT COALESCE(SUM(m.size), 0)
+		FROM media m
+		WHERE m.id IN (
+			SELECT m.id
+			FROM media m
+			JOIN course_media cm ON cm.media_id = COALESCE(m.parent_id, m.id)
+			WHERE cm.course_id = $1
+			UNION
+			SELECT m.id
+			FROM media m
+			JOIN assignment_media am ON am.media_id = COALESCE(m.parent_id, m.id)
+			JOIN course_assignments ca ON ca.assignment_id = am.assignment_id
+			WHERE ca.course_id = $1
+			UNION
+			SELECT m.id
+			FROM media m
+			JOIN submission_media sm ON sm.media_id = COALESCE(m.parent_id, m.id)
+			JOIN assignment_submissions asub ON asub.submission_id = sm.submission_id
+			JOIN course_assignments ca ON ca.assignment_id = asub.assignment_id
+			WHERE ca.course_id = $1
+			UNION
+			SELECT m.id
+			FROM media m
+			JOIN feedback_media fm ON fm.media_id = COALESCE(m.parent_id, m.id)
+			JOIN assignment_submissions asub ON asub.submission_id = fm.submission_id
+			JOIN course_assignments ca ON ca.assignment_id = asub.assignment_id
+			WHERE ca.course_id = $1
+			UNION
+			SELECT m.id
+			FROM media m
+			JOIN team_media tm ON tm.media_id = COALESCE(m.parent_id, m.id)
+			JOIN teams t ON t.id = tm.team_id
+			JOIN projects p ON p.id = t.project_id
+			WHERE p.course_id = $1
+			UNION
+			SELECT m.id
+			FROM media m
+			JOIN harness_media hm ON hm.media_id = COALESCE(m.parent_id, m.id)
+			JOIN course_assignments ca ON ca.assignment_id = hm.assignment_id
+			WHERE ca.course_id = $1
+			UNION
+			SELECT m.id
+			FROM media m
+			JOIN module_items mi ON mi.media_id = COALESCE(m.parent_id, m.id)
+			JOIN modules mo ON mo.id = mi.module_id
+			WHERE mo.course_id = $1
+		)
+	`
+
+	err := s.db.QueryRow(query, courseId).Scan(&used)
+	if err != nil {
+		return 0, err
+	}
+
+	return used, nil
+}
+
+// GetUserStorageUsage sums the size of every piece of media a user
+// owns, which are their submissions' media and their profile picture.
+func (s *Store) GetUserStorageUsage(netId string) (int64, error) {
+	var used int64
+
+	query := `
+		SELECT COALESCE(SUM(m.size), 0)
+		FROM media m
+		WHERE m.id IN (
+			SELECT m.id
+			FROM media m
+			JOIN submission_media sm ON sm.media_id = COALESCE(m.parent_id, m.id)
+			JOIN submissions sub ON sub.id = sm.submission_id
+			WHERE sub.user_id = $1
+			UNION
+			SELECT m.id
+			FROM media m
+			JOIN users u ON u.profile_picture_id = COALESCE(m.parent_id, m.id)
+			WHERE u.net_id = $1
+		)
+	`
+
+	err := s.db.QueryRow(query, netId).Scan(&used)
+	if err != nil {
+		return 0, err
+	}
+
+	return used, nil
+}
+
+// GetCourseIdByAssignment retrieves the ID of the course an
+// assignment belongs to.
+func (s *Store) GetCourseIdByAssignment(assignmentId string) (string, error) {
+	var courseId string
+
+	query := `SELECT course_id FROM course_assignments WHERE assignment_id = $1`
+
+	err := s.db.QueryRow(query, assignmentId).Scan(&courseId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", ERR_RECORD_NOT_FOUND
+		}
+		return "", err
+	}
+
+	return courseId, nil
+}
+
+// DeleteMediaFromCourse removes the relationship between a course
+// and a piece of media. The media record itself is kept.
+func (s *Store) DeleteMediaFromCourse(courseId, mediaId string) error {
+	query := `DELETE FROM course_media WHERE course_id = $1 AND media_id = $2`
+
+	_, err := s.db.Exec(query, courseId, mediaId)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// GetMediaVariant retrieves a resized variant of a piece of media.
+func (s *Store) GetMediaVariant(
+	parentId string,
+	size models.ImageSize,
+) (*models.Media, error) {
+	media := &models.Media{}
+
+	var threat sql.NullString
+
+	query := `SELECT id, type, path, scan_status, threat FROM media WHERE parent_id = $1 AND variant = $2`
+	row := s.db.QueryRow(query, parentId, size)
+
+	err := row.Scan(
+		&media.ID,
+		&media.FileType,
+		&media.FilePath,
+		&media.Status,
+		&threat,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ERR_RECORD_NOT_FOUND
+		}
+		return nil, err
+	}
+
+	media.ParentId = parentId
+	media.Variant = size
+	media.Threat = threat.String
+
+	return media, nil
+}
+
+// UpdateUserProfilePicture sets the media used as a user's profile
+// picture.
+func (s *Store) UpdateUserProfilePicture(netId string, mediaId string) error {
+	query := `UPDATE users SET profile_picture_id = $1, updated_at = CURRENT_TIMESTAMP WHERE net_id = $2`
+
+	_, err := s.db.Exec(query, mediaId, netId)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (s *Store) InsertMediaIntoCourse(
+	m *models.Media,
+) error {
+	query := `INSERT INTO course_media (course_id, media_id, media_path) VALUES ($1, $2, $3)`
+
+	_, err := s.db.Exec(query, m.AttributionsByType["course"], m.ID, m.FilePath)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+func (s *Store) InsertMediaIntoCourseBanner(
+	m *models.Media,
+) error {
+
+	query := `UPDATE courses SET banner_id = $2 WHERE id = $1;`
+	_, err = s.db.Exec(query, m.AttributionsByType["course"], m.ID)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (s *Store) InsertMediaIntoAssignment(
+	m *models.Media,
+) error {
+	query := `INSERT INTO assignment_media (assignment_id, media_id, media_path) VALUES ($1, $2, $3)`
+
+	_, err := s.db.Exec(
+		query,
+		m.AttributionsByType["assignment"],
+		m.ID,
+		m.FilePath,
+	)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (s *Store) InsertMediaIntoSubmission(
+	m *models.Media,
+) error {
+	query := `INSERT INTO submission_media (submission_id, media_id, media_path) VALUES ($1, $2, $3)`
+
+	_, err := s.db.Exec(
+		query,
+		m.AttributionsByType["submission"],
+		m.ID,
+		m.FilePath,
+	)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// InsertMediaIntoFeedback attaches media to the feedback of the
+// submission it is attributed to.
+func (s *Store) InsertMediaIntoFeedback(m *models.Media) error {
+	query := `INSERT INTO feedback_media (submission_id, media_id, media_path) VALUES ($1, $2, $3)`
+
+	_, err := s.db.Exec(
+		query,
+		m.AttributionsByType["feedback"],
+		m.ID,
+		m.FilePath,
+	)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (s *Store) InsertMediaIntoTeam(
+	m *models.Media,
+) error {
+	query := `INSERT INTO team_media (team_id, media_id, media_path) VALUES ($1, $2, $3)`
+
+	_, err := s.db.Exec(
+		query,
+		m.AttributionsByType["team"],
+		m.ID,
+		m.FilePath,
+	)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// GetTeamMedia retrieves the files shared by a team, newest first.
+// Image variants are not included.
+func (s *Store) GetTeamMedia(teamId string) ([]*models.Media, error) {
+	query := `SELECT m.id, m.type, m.path, m.size, m.created_at, m.scan_status, m.threat
+FROM media m JOIN team_media tm ON tm.media_id = m.id
+WHERE tm.team_id = $1 ORDER BY m.created_at DESC`
+
+	rows, err := s.db.Query(query, teamId)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var media []*models.Media
+
+	for rows.Next() {
+		var threat sql.NullString
+		m := &models.Media{}
+
+		err := rows.Scan(
+			&m.ID,
+			&m.FileType,
+			&m.FilePath,
+			&m.Size,
+			&m.CreatedAt,
+			&m.Status,
+			&threat,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning row: %v", err)
+		}
+
+		m.Threat = threat.String
+		m.AttributionsByType = map[string]string{"team": teamId}
+		media = append(media, m)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %v", err)
+	}
+
+	return media, nil
+}
+
+// GetCourseIdByTeam retrieves the ID of the course a team's project
+// belongs to.
+func (s *Store) GetCourseIdByTeam(teamId string) (string, error) {
+	var courseId sql.NullString
+
+	query := `SELECT p.course_id FROM teams t JOIN projects p ON p.id = t.project_id WHERE t.id = $1`
+
+	err := s.db.QueryRow(query, teamId).Scan(&courseId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", ERR_RECORD_NOT_FOUND
+		}
+		return "", err
+	}
+
+	return courseId.String, nil
+}
+
+// IsCourseStudent checks whether a user is on a course's roster.
+func (s *Store) IsCourseStudent(courseId, netId string) (bool, error) {
+	var exists bool
+
+	query := `SELECT EXISTS (SELECT 1 FROM course_roster WHERE course_id = $1 AND student_id = $2)`
+
+	err := s.db.QueryRow(query, courseId, netId).Scan(&exists)
+	if err != nil {
+		return false, err
+	}
+
+	return exists, nil
+}
+
+func (s *Store) InsertProject(p *models.Project) error {
+	query := `INSERT INTO projects (name, description, created_at, updated_at, user_net_id, course_id, assignment_id, team_size, self_enroll) VALUES ($1, $2, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP, $3, $4, $5, $6, $7) RETURNING id, created_at`
+
+	row := s.db.QueryRow(
+		query,
+		p.Name,
+		p.Description,
+		nullString(p.Owner),
+		p.CourseId,
+		nullString(p.AssignmentId),
+		p.TeamSize,
+		p.SelfEnroll,
+	)
+
+	err := row.Scan(&p.ID, &p.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// projectColumns are the columns scanned by scanProject.
+const projectColumns = `id, name, description, created_at, user_net_id, course_id, assignment_id, team_size, self_enroll`
+
+func scanProject(row rowScanner) (*models.Project, error) {
+	var description, owner, course, assignment sql.NullString
+	p := &models.Project{}
+
+	err := row.Scan(
+		&p.ID,
+		&p.Name,
+		&description,
+		&p.CreatedAt,
+		&owner,
+		&course,
+		&assignment,
+		&p.TeamSize,
+		&p.SelfEnroll,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	p.Description = description.String
+	p.Owner = owner.String
+	p.CourseId = course.String
+	p.AssignmentId = assignment.String
+
+	return p, nil
+}
+
+// GetProjectById retrieves a project, without its teams.
+func (s *Store) GetProjectById(id string) (*models.Project, error) {
+	query := `SELECT ` + projectColumns + ` FROM projects WHERE id = $1`
+
+	p, err := scanProject(s.db.QueryRow(query, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ERR_RECORD_NOT_FOUND
+		}
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// GetProjectsByCourse retrieves a course's projects, oldest first,
+// without their teams.
+func (s *Store) GetProjectsByCourse(courseId string) (
+	[]*models.Project,
+	error,
+) {
+	query := `SELECT ` + projectColumns + ` FROM projects WHERE course_id = $1 ORDER BY created_at, id`
+
+	rows, err := s.db.Query(query, courseId)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var projects []*models.Project
+
+	for rows.Next() {
+		p, err := scanProject(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning row: %v", err)
+		}
+		projects = append(projects, p)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %v", err)
+	}
+
+	return projects, nil
+}
+
+// DeleteProject deletes a project, along with its teams and their
+// discussions.
+func (s *Store) DeleteProject(id string) error {
+	query := `DELETE FROM messages WHERE id IN (SELECT message_id FROM teams WHERE project_id = $1)`
+
+	_, err := s.db.Exec(query, id)
+	if err != nil {
+		return err
+	}
+
+	query = `DELETE FROM projects WHERE id = $1`
+
+	_, err = s.db.Exec(query, id)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// InsertTeam inserts a team along with its discussion. The
+// discussion is not linked to the project's course, so it is not
+// listed with the course's discussions.
+func (s *Store) InsertTeam(t *models.Team) error {
+	query := `INSERT INTO messages (title, description, type, date, updated_at) VALUES ($1, '', FALSE, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP) RETURNING id`
+
+	err := s.db.QueryRow(query, t.Name).Scan(&t.DiscussionId)
+	if err != nil {
+		return err
+	}
+
+	query = `INSERT INTO teams (project_id, name, message_id, created_at) VALUES ($1, $2, $3, CURRENT_TIMESTAMP) RETURNING id, created_at`
+
+	err = s.db.QueryRow(query, t.ProjectId, t.Name, t.DiscussionId).
+		Scan(&t.ID, &t.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// GetTeamById retrieves a team and its members.
+func (s *Store) GetTeamById(id string) (*models.Team, error) {
+	var discussion, submission sql.NullString
+	t := &models.Team{}
+
+	query := `SELECT id, project_id, name, message_id, submission_id, created_at FROM teams WHERE id = $1`
+
+	err := s.db.QueryRow(query, id).Scan(
+		&t.ID,
+		&t.ProjectId,
+		&t.Name,
+		&discussion,
+		&submission,
+		&t.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ERR_RECORD_NOT_FOUND
+		}
+		return nil, err
+	}
+
+	t.DiscussionId = discussion.String
+	t.SubmissionId = submission.String
+
+	t.Members, err = s.getTeamMembers(t.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+// GetTeamsByProject retrieves a project's teams and their members,
+// oldest first.
+func (s *Store) GetTeamsByProject(projectId string) ([]*models.Team, error) {
+	query := `SELECT id FROM teams WHERE project_id = $1 ORDER BY created_at, id`
+
+	rows, err := s.db.Query(query, projectId)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var ids []string
+
+	for rows.Next() {
+		var id string
+		err := rows.Scan(&id)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning row: %v", err)
+		}
+		ids = append(ids, id)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %v", err)
+	}
+
+	teams := make([]*models.Team, 0, len(ids))
+
+	for _, id := range ids {
+		t, err := s.GetTeamById(id)
+		if err != nil {
+			return nil, err
+		}
+		teams = append(teams, t)
+	}
+
+	return teams, nil
+}
+
+func (s *Store) getTeamMembers(teamId string) ([]string, error) {
+	query := `SELECT user_net_id FROM team_members WHERE team_id = $1 ORDER BY user_net_id`
+
+	rows, err := s.db.Query(query, teamId)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	members := []string{}
+
+	for rows.Next() {
+		var netId string
+		err := rows.Scan(&netId)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning row: %v", err)
+		}
+		members = append(members, netId)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %v", err)
+	}
+
+	return members, nil
+}
+
+// GetTeamIdByMember retrieves the ID of the team a user is on within
+// a project. ERR_RECORD_NOT_FOUND is returned if they are on none.
+func (s *Store) GetTeamIdByMember(projectId, netId string) (string, error) {
+	var teamId string
+
+	query := `SELECT team_id FROM team_members WHERE project_id = $1 AND user_net_id = $2`
+
+	err := s.db.QueryRow(query, projectId, netId).Scan(&teamId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", ERR_RECORD_NOT_FOUND
+		}
+		return "", err
+	}
+
+	return teamId, nil
+}
+
+func (s *Store) InsertTeamMember(t *models.Team, netId string) error {
+	query := `INSERT INTO team_members (team_id, project_id, user_net_id) VALUES ($1, $2, $3)`
+
+	_, err := s.db.Exec(query, t.ID, t.ProjectId, netId)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (s *Store) DeleteTeamMember(teamId, netId string) error {
+	query := `DELETE FROM team_members WHERE team_id = $1 AND user_net_id = $2`
+
+	_, err := s.db.Exec(query, teamId, netId)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// UpdateTeamSubmission records the team's submission.
+func (s *Store) UpdateTeamSubmission(t *models.Team) error {
+	query := `UPDATE teams SET submission_id = $1 WHERE id = $2`
+
+	_, err := s.db.Exec(query, nullString(t.SubmissionId), t.ID)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// UpdateSubmissionGrade sets the grade, raw grade, late penalty and
+// feedback of a submission, marking it as graded.
+func (s *Store) UpdateSubmissionGrade(sub *models.Submission) error {
+	query := `UPDATE submissions SET grade = $1, raw_grade = $2, late_penalty = $3, feedback = $4, graded = TRUE WHERE id = $5`
+
+	_, err := s.db.Exec(
+		query,
+		sub.Grade,
+		sub.RawGrade,
+		sub.LatePenalty,
+		sub.Feedback,
+		sub.ID,
+	)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// InsertQuiz inserts a quiz along with its questions.
+func (s *Store) InsertQuiz(q *models.Quiz) error {
+	query := `INSERT INTO quizzes (course_id, assignment_id, title, description, owner, time_limit, opens_at, closes_at, max_attempts, shuffle_questions, shuffle_choices, created_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, CURRENT_TIMESTAMP) RETURNING id, created_at`
+
+	row := s.db.QueryRow(
+		query,
+		q.CourseId,
+		nullString(q.AssignmentId),
+		q.Title,
+		q.Description,
+		nullString(q.Owner),
+		q.TimeLimit,
+		nullTime(q.OpensAt),
+		nullTime(q.ClosesAt),
+		q.MaxAttempts,
+		q.ShuffleQuestions,
+		q.ShuffleChoices,
+	)
+
+	err := row.Scan(&q.ID, &q.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	query = `INSERT INTO quiz_questions (quiz_id, position, type, prompt, points, choices, answer_key) VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id`
+
+	for i, question := range q.Questions {
+		choices, err := json.Marshal(question.Choices)
+		if err != nil {
+			return err
+		}
+
+		key, err := json.Marshal(question.Key)
+		if err != nil {
+			return err
+		}
+
+		question.QuizId = q.ID
+		question.Position = i
+
+		err = s.db.QueryRow(
+			query,
+			q.ID,
+			question.Position,
+			question.Type,
+			question.Prompt,
+			question.Points,
+			choices,
+			key,
+		).Scan(&question.ID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// quizColumns are the columns scanned by scanQuiz.
+const quizColumns = `id, course_id, assignment_id, title, description, owner, time_limit, opens_at, closes_at, max_attempts, shuffle_questions, shuffle_choices, created_at`
+
+func scanQuiz(row rowScanner) (*models.Quiz, error) {
+	var assignment, description, owner sql.NullString
+	var opens, closes sql.NullTime
+	q := &models.Quiz{}
+
+	err := row.Scan(
+		&q.ID,
+		&q.CourseId,
+		&assignment,
+		&q.Title,
+		&description,
+		&owner,
+		&q.TimeLimit,
+		&opens,
+		&closes,
+		&q.MaxAttempts,
+		&q.ShuffleQuestions,
+		&q.ShuffleChoices,
+		&q.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	q.AssignmentId = assignment.String
+	q.Description = description.String
+	q.Owner = owner.String
+	q.OpensAt = opens.Time
+	q.ClosesAt = closes.Time
+
+	return q, nil
+}
+
+// GetQuizById retrieves a quiz along with its questions and their
+// answer keys, in order.
+func (s *Store) GetQuizById(id string) (*models.Quiz, error) {
+	query := `SELECT ` + quizColumns + ` FROM quizzes WHERE id = $1`
+
+	q, err := scanQuiz(s.db.QueryRow(query, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ERR_RECORD_NOT_FOUND
+		}
+		return nil, err
+	}
+
+	query = `SELECT id, position, type, prompt, points, choices, answer_key FROM quiz_questions WHERE quiz_id = $1 ORDER BY position`
+
+	rows, err := s.db.Query(query, q.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var choices, key []byte
+		question := &models.Question{QuizId: q.ID}
+
+		err := rows.Scan(
+			&question.ID,
+			&question.Position,
+			&question.Type,
+			&question.Prompt,
+			&question.Points,
+			&choices,
+			&key,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning row: %v", err)
+		}
+
+		err = unmarshalNullable(choices, &question.Choices)
+		if err != nil {
+			return nil, err
+		}
+
+		err = unmarshalNullable(key, &question.Key)
+		if err != nil {
+			return nil, err
+		}
+
+		q.Questions = append(q.Questions, question)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %v", err)
+	}
+
+	return q, nil
+}
+
+// unmarshalNullable decodes a JSON column, leaving v as it is if the
+// column is NULL.
+func unmarshalNullable(data []byte, v any) error {
+	if data == nil {
+		return nil
+	}
+	return json.Unmarshal(data, v)
+}
+
+// GetQuizzesByCourse retrieves a course's quizzes, without their
+// questions, oldest first.
+func (s *Store) GetQuizzesByCourse(courseId string) ([]*models.Quiz, error) {
+	query := `SELECT ` + quizColumns + ` FROM quizzes WHERE course_id = $1 ORDER BY created_at, id`
+
+	rows, err := s.db.Query(query, courseId)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var quizzes []*models.Quiz
+
+	for rows.Next() {
+		q, err := scanQuiz(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning row: %v", err)
+		}
+		quizzes = append(quizzes, q)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %v", err)
+	}
+
+	return quizzes, nil
+}
+
+// DeleteQuiz deletes a quiz, its questions and its attempts. The
+// submissions its attempts were graded into are kept.
+func (s *Store) DeleteQuiz(id string) error {
+	query := `DELETE FROM quizzes WHERE id = $1`
+
+	_, err := s.db.Exec(query, id)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (s *Store) InsertQuizAttempt(a *models.QuizAttempt) error {
+	query := `INSERT INTO quiz_attempts (quiz_id, user_net_id, started_at, deadline, seed) VALUES ($1, $2, $3, $4, $5) RETURNING id`
+
+	err := s.db.QueryRow(
+		query,
+		a.QuizId,
+		a.NetId,
+		a.StartedAt,
+		nullTime(a.Deadline),
+		a.Seed,
+	).Scan(&a.ID)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// quizAttemptColumns are the columns scanned by scanQuizAttempt.
+const quizAttemptColumns = `id, quiz_id, user_net_id, started_at, deadline, submitted_at, seed, score, submission_id`
+
+func scanQuizAttempt(row rowScanner) (*models.QuizAttempt, error) {
+	var deadline, submitted sql.NullTime
+	var submission sql.NullString
+	a := &models.QuizAttempt{}
+
+	err := row.Scan(
+		&a.ID,
+		&a.QuizId,
+		&a.NetId,
+		&a.StartedAt,
+		&deadline,
+		&submitted,
+		&a.Seed,
+		&a.Score,
+		&submission,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	a.Deadline = deadline.Time
+	a.SubmittedAt = submitted.Time
+	a.SubmissionId = submission.String
+
+	return a, nil
+}
+
+// GetQuizAttemptById retrieves an attempt along with its responses.
+func (s *Store) GetQuizAttemptById(id string) (*models.QuizAttempt, error) {
+	query := `SELECT ` + quizAttemptColumns + ` FROM quiz_attempts WHERE id = $1`
+
+	a, err := scanQuizAttempt(s.db.QueryRow(query, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ERR_RECORD_NOT_FOUND
+		}
+		return nil, err
+	}
+
+	a.Responses, err = s.getQuizResponses(a.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return a, nil
+}
+
+// GetQuizAttempts retrieves the attempts at a quiz, along with their
+// responses, oldest first. If netId is not empty, only that user's
+// attempts are retrieved.
+func (s *Store) GetQuizAttempts(quizId, netId string) (
+	[]*models.QuizAttempt,
+	error,
+) {
+	query := `SELECT ` + quizAttemptColumns + ` FROM quiz_attempts WHERE quiz_id = $1 AND ($2 = '' OR user_net_id = $2) ORDER BY started_at, id`
+
+	rows, err := s.db.Query(query, quizId, netId)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var attempts []*models.QuizAttempt
+
+	for rows.Next() {
+		a, err := scanQuizAttempt(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning row: %v", err)
+		}
+		attempts = append(attempts, a)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %v", err)
+	}
+
+	for _, a := range attempts {
+		a.Responses, err = s.getQuizResponses(a.ID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return attempts, nil
+}
+
+func (s *Store) getQuizResponses(attemptId string) ([]*models.Response, error) {
+	query := `SELECT r.question_id, r.answer, r.points, r.graded
+FROM quiz_responses r JOIN quiz_questions q ON q.id = r.question_id
+WHERE r.attempt_id = $1 ORDER BY q.position`
+
+	rows, err := s.db.Query(query, attemptId)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	responses := []*models.Response{}
+
+	for rows.Next() {
+		var answer []byte
+		r := &models.Response{}
+
+		err := rows.Scan(&r.QuestionId, &answer, &r.Points, &r.Graded)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning row: %v", err)
+		}
+
+		err = json.Unmarshal(answer, &r.Answer)
+		if err != nil {
+			return nil, err
+		}
+
+		responses = append(responses, r)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %v", err)
+	}
+
+	return responses, nil
+}
+
+// SaveQuizResponses inserts an attempt's responses, replacing any
+// earlier response to the same question.
+func (s *Store) SaveQuizResponses(
+	attemptId string,
+	responses []*models.Response,
+) error {
+	query := `INSERT INTO quiz_responses (attempt_id, question_id, answer, points, graded) VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (attempt_id, question_id) DO UPDATE SET answer = EXCLUDED.answer, points = EXCLUDED.points, graded = EXCLUDED.graded`
+
+	for _, r := range responses {
+		answer, err := json.Marshal(r.Answer)
+		if err != nil {
+			return err
+		}
+
+		_, err = s.db.Exec(query, attemptId, r.QuestionId, answer, r.Points, r.Graded)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// UpdateQuizAttempt records an attempt's submission and score.
+func (s *Store) UpdateQuizAttempt(a *models.QuizAttempt) error {
+	query := `UPDATE quiz_attempts SET submitted_at = $1, score = $2, submission_id = $3 WHERE id = $4`
+
+	_, err := s.db.Exec(
+		query,
+		nullTime(a.SubmittedAt),
+		a.Score,
+		nullString(a.SubmissionId),
+		a.ID,
+	)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// InsertRubric inserts a rubric along with its criteria.
+func (s *Store) InsertRubric(r *models.Rubric) error {
+	query := `INSERT INTO rubrics (course_id, title, description, owner, created_at) VALUES ($1, $2, $3, $4, CURRENT_TIMESTAMP) RETURNING id, created_at`
+
+	err := s.db.QueryRow(
+		query,
+		r.CourseId,
+		r.Title,
+		r.Description,
+		nullString(r.Owner),
+	).Scan(&r.ID, &r.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	query = `INSERT INTO rubric_criteria (rubric_id, position, name, description, levels) VALUES ($1, $2, $3, $4, $5) RETURNING id`
+
+	for i, c := range r.Criteria {
+		levels, err := json.Marshal(c.Levels)
+		if err != nil {
+			return err
+		}
+
+		c.RubricId = r.ID
+		c.Position = i
+
+		err = s.db.QueryRow(
+			query,
+			r.ID,
+			c.Position,
+			c.Name,
+			c.Description,
+			levels,
+		).Scan(&c.ID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// rubricColumns are the columns scanned by scanRubric.
+const rubricColumns = `id, course_id, title, description, owner, created_at`
+
+func scanRubric(row rowScanner) (*models.Rubric, error) {
+	var description, owner sql.NullString
+	r := &models.Rubric{}
+
+	err := row.Scan(
+		&r.ID,
+		&r.CourseId,
+		&r.Title,
+		&description,
+		&owner,
+		&r.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	r.Description = description.String
+	r.Owner = owner.String
+
+	return r, nil
+}
+
+// GetRubricById retrieves a rubric along with its criteria, in order.
+func (s *Store) GetRubricById(id string) (*models.Rubric, error) {
+	query := `SELECT ` + rubricColumns + ` FROM rubrics WHERE id = $1`
+
+	r, err := scanRubric(s.db.QueryRow(query, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ERR_RECORD_NOT_FOUND
+		}
+		return nil, err
+	}
+
+	query = `SELECT id, position, name, description, levels FROM rubric_criteria WHERE rubric_id = $1 ORDER BY position`
+
+	rows, err := s.db.Query(query, r.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var description sql.NullString
+		var levels []byte
+		c := &models.Criterion{RubricId: r.ID}
+
+		err := rows.Scan(
+			&c.ID,
+			&c.Position,
+			&c.Name,
+			&description,
+			&levels,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning row: %v", err)
+		}
+
+		c.Description = description.String
+
+		err = json.Unmarshal(levels, &c.Levels)
+		if err != nil {
+			return nil, err
+		}
+
+		r.Criteria = append(r.Criteria, c)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %v", err)
+	}
+
+	return r, nil
+}
+
+// GetRubricsByCourse retrieves a course's rubrics, without their
+// criteria, oldest first.
+func (s *Store) GetRubricsByCourse(courseId string) ([]*models.Rubric, error) {
+	query := `SELECT ` + rubricColumns + ` FROM rubrics WHERE course_id = $1 ORDER BY created_at, id`
+
+	rows, err := s.db.Query(query, courseId)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var rubrics []*models.Rubric
+
+	for rows.Next() {
+		r, err := scanRubric(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning row: %v", err)
+		}
+		rubrics = append(rubrics, r)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %v", err)
+	}
+
+	return rubrics, nil
+}
+
+// IsRubricInUse checks if any assignment is graded against a rubric.
+func (s *Store) IsRubricInUse(id string) (bool, error) {
+	var used bool
+
+	query := `SELECT EXISTS (SELECT 1 FROM assignments WHERE rubric_id = $1)`
+
+	err := s.db.QueryRow(query, id).Scan(&used)
+	if err != nil {
+		return false, err
+	}
+
+	return used, nil
+}
+
+// DeleteRubric deletes a rubric and its criteria.
+func (s *Store) DeleteRubric(id string) error {
+	query := `DELETE FROM rubrics WHERE id = $1`
+
+	_, err := s.db.Exec(query, id)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// SaveRubricScores replaces a submission's rubric scores.
+func (s *Store) SaveRubricScores(
+	submissionId string,
+	scores []*models.CriterionScore,
+) error {
+	query := `DELETE FROM rubric_scores WHERE submission_id = $1`
+
+	_, err := s.db.Exec(query, submissionId)
+	if err != nil {
+		return err
+	}
+
+	query = `INSERT INTO rubric_scores (submission_id, criterion_id, level, points, comment) VALUES ($1, $2, $3, $4, $5)`
+
+	for _, score := range scores {
+		_, err = s.db.Exec(
+			query,
+			submissionId,
+			score.CriterionId,
+			nullString(score.Level),
+			score.Points,
+			nullString(score.Comment),
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// rubricScoreColumns are the columns scanned by scanRubricScore. The
+// query must join rubric_scores as rs with rubric_criteria as rc.
+const rubricScoreColumns = `rs.criterion_id, rc.name, rs.level, rs.points, rs.comment`
+
+func scanRubricScore(row rowScanner) (*models.CriterionScore, error) {
+	var level, comment sql.NullString
+	score := &models.CriterionScore{}
+
+	err := row.Scan(
+		&score.CriterionId,
+		&score.Criterion,
+		&level,
+		&score.Points,
+		&comment,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	score.Level = level.String
+	score.Comment = comment.String
+
+	return score, nil
+}
+
+// GetRubricScores retrieves a submission's rubric scores, in the
+// order of the rubric's criteria.
+func (s *Store) GetRubricScores(submissionId string) (
+	[]*models.CriterionScore,
+	error,
+) {
+	query := `SELECT ` + rubricScoreColumns + `
+		FROM rubric_scores rs
+		JOIN rubric_criteria rc ON rc.id = rs.criterion_id
+		WHERE rs.submission_id = $1
+		ORDER BY rc.position`
+
+	rows, err := s.db.Query(query, submissionId)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var scores []*models.CriterionScore
+
+	for rows.Next() {
+		score, err := scanRubricScore(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning row: %v", err)
+		}
+		scores = append(scores, score)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %v", err)
+	}
+
+	return scores, nil
+}
+
+// GetAssignmentRubricScores retrieves the rubric scores of every
+// submission made for an assignment, keyed by submission ID.
+func (s *Store) GetAssignmentRubricScores(assignmentId string) (
+	map[string][]*models.CriterionScore,
+	error,
+) {
+	query := `SELECT rs.submission_id, ` + rubricScoreColumns + `
+		FROM rubric_scores rs
+		JOIN rubric_criteria rc ON rc.id = rs.criterion_id
+		JOIN assignment_submissions a ON a.submission_id = rs.submission_id
+		WHERE a.assignment_id = $1
+		ORDER BY rc.position`
+
+	rows, err := s.db.Query(query, assignmentId)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	scores := make(map[string][]*models.CriterionScore)
+
+	for rows.Next() {
+		var submissionId string
+		score := &models.CriterionScore{}
+		var level, comment sql.NullString
+
+		err := rows.Scan(
+			&submissionId,
+			&score.CriterionId,
+			&score.Criterion,
+			&level,
+			&score.Points,
+			&comment,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning row: %v", err)
+		}
+
+		score.Level = level.String
+		score.Comment = comment.String
+
+		scores[submissionId] = append(scores[submissionId], score)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %v", err)
+	}
+
+	return scores, nil
+}
+
+func (s *Store) InsertGradeCategory(c *models.GradeCategory) error {
+	query := `INSERT INTO grade_categories (course_id, name, weight, drop_lowest, created_at) VALUES ($1, $2, $3, $4, CURRENT_TIMESTAMP) RETURNING id, created_at`
+
+	err := s.db.QueryRow(
+		query,
+		c.CourseId,
+		c.Name,
+		c.Weight,
+		c.DropLowest,
+	).Scan(&c.ID, &c.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// gradeCategoryColumns are the columns scanned by scanGradeCategory.
+const gradeCategoryColumns = `id, course_id, name, weight, drop_lowest, created_at`
+
+func scanGradeCategory(row rowScanner) (*models.GradeCategory, error) {
+	c := &models.GradeCategory{}
+
+	err := row.Scan(
+		&c.ID,
+		&c.CourseId,
+		&c.Name,
+		&c.Weight,
+		&c.DropLowest,
+		&c.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+func (s *Store) GetGradeCategoryById(id string) (*models.GradeCategory, error) {
+	query := `SELECT ` + gradeCategoryColumns + ` FROM grade_categories WHERE id = $1`
+
+	c, err := scanGradeCategory(s.db.QueryRow(query, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ERR_RECORD_NOT_FOUND
+		}
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// GetGradeCategories retrieves a course's grade categories, oldest
+// first.
+func (s *Store) GetGradeCategories(courseId string) (
+	[]*models.GradeCategory,
+	error,
+) {
+	query := `SELECT ` + gradeCategoryColumns + ` FROM grade_categories WHERE course_id = $1 ORDER BY created_at, id`
+
+	rows, err := s.db.Query(query, courseId)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var categories []*models.GradeCategory
+
+	for rows.Next() {
+		c, err := scanGradeCategory(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning row: %v", err)
+		}
+		categories = append(categories, c)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %v", err)
+	}
+
+	return categories, nil
+}
+
+func (s *Store) UpdateGradeCategory(c *models.GradeCategory) error {
+	query := `UPDATE grade_categories SET name = $1, weight = $2, drop_lowest = $3 WHERE id = $4`
+
+	_, err := s.db.Exec(query, c.Name, c.Weight, c.DropLowest, c.ID)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// DeleteGradeCategory deletes a grade category. Its assignments are
+// kept, but no longer count towards the course grade.
+func (s *Store) DeleteGradeCategory(id string) error {
+	query := `DELETE FROM grade_categories WHERE id = $1`
+
+	_, err := s.db.Exec(query, id)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// GetGradeItems retrieves how each of a course's assignments counts
+// in the gradebook, in order of due date. Assignments that have not
+// been set up in the gradebook have no category and are out of 100
+// points.
+func (s *Store) GetGradeItems(courseId string) ([]*models.GradeItem, error) {
+	query := `
+		SELECT a.id, a.title, gi.category_id, COALESCE(gi.points, 100), COALESCE(gi.extra_credit, FALSE)
+		FROM course_assignments ca
+		JOIN assignments a ON a.id = ca.assignment_id
+		LEFT JOIN gradebook_items gi ON gi.assignment_id = a.id
+		WHERE ca.course_id = $1
+		ORDER BY a.due_date, a.id
+	`
+
+	rows, err := s.db.Query(query, courseId)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var items []*models.GradeItem
+
+	for rows.Next() {
+		var category sql.NullString
+		item := &models.GradeItem{}
+
+		err := rows.Scan(
+			&item.AssignmentId,
+			&item.Title,
+			&category,
+			&item.Points,
+			&item.ExtraCredit,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning row: %v", err)
+		}
+
+		item.CategoryId = category.String
+		items = append(items, item)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %v", err)
+	}
+
+	return items, nil
+}
+
+// SaveGradeItem sets how an assignment counts in the gradebook.
+func (s *Store) SaveGradeItem(item *models.GradeItem) error {
+	query := `INSERT INTO gradebook_items (assignment_id, category_id, points, extra_credit) VALUES ($1, $2, $3, $4)
+ON CONFLICT (assignment_id) DO UPDATE SET category_id = EXCLUDED.category_id, points = EXCLUDED.points, extra_credit = EXCLUDED.extra_credit`
+
+	_, err := s.db.Exec(
+		query,
+		item.AssignmentId,
+		nullString(item.CategoryId),
+		item.Points,
+		item.ExtraCredit,
+	)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// GetAssignmentPoints retrieves what an assignment is graded out of.
+// Assignments that are not in the gradebook are out of 100 points.
+func (s *Store) GetAssignmentPoints(assignmentId string) (float64, error) {
+	query := `SELECT COALESCE((SELECT points FROM gradebook_items WHERE assignment_id = $1), 100)`
+
+	var points float64
+
+	err := s.db.QueryRow(query, assignmentId).Scan(&points)
+	if err != nil {
+		return 0, err
+	}
+
+	return points, nil
+}
+
+// GetGradeScheme retrieves a course's letter grade scheme. It is nil
+// when the course has not set one.
+func (s *Store) GetGradeScheme(courseId string) (models.GradeScheme, error) {
+	var data []byte
+
+	query := `SELECT scheme FROM grade_schemes WHERE course_id = $1`
+
+	err := s.db.QueryRow(query, courseId).Scan(&data)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var scheme models.GradeScheme
+
+	err = json.Unmarshal(data, &scheme)
+	if err != nil {
+		return nil, err
+	}
+
+	return scheme, nil
+}
+
+func (s *Store) SaveGradeScheme(courseId string, scheme models.GradeScheme) error {
+	data, err := json.Marshal(scheme)
+	if err != nil {
+		return err
+	}
+
+	query := `INSERT INTO grade_schemes (course_id, scheme) VALUES ($1, $2)
+ON CONFLICT (course_id) DO UPDATE SET scheme = EXCLUDED.scheme`
+
+	_, err = s.db.Exec(query, courseId, data)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// GetCourseGrades retrieves the grades given on a course's
+// assignments, keyed by Net ID and then by assignment ID. Only the
+// attempts that count are used, and submissions that have not been
+// graded are left out. When releasedOnly is set, so are the grades of
+// assignments whose grades have not been released.
+func (s *Store) GetCourseGrades(courseId string, releasedOnly bool) (
+	map[string]map[string]float64,
+	error,
+) {
+	query := `
+		SELECT s.user_id, asub.assignment_id, MAX(s.grade)
+		FROM submissions s
+		JOIN assignment_submissions asub ON asub.submission_id = s.id
+		JOIN course_assignments ca ON ca.assignment_id = asub.assignment_id
+		JOIN assignments a ON a.id = asub.assignment_id
+		WHERE ca.course_id = $1 AND s.graded AND s.counts AND s.user_id IS NOT NULL
+		AND (NOT $2 OR a.grades_released)
+		GROUP BY s.user_id, asub.assignment_id
+	`
+
+	rows, err := s.db.Query(query, courseId, releasedOnly)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	grades := make(map[string]map[string]float64)
+
+	for rows.Next() {
+		var netId, assignmentId string
+		var grade float64
+
+		err := rows.Scan(&netId, &assignmentId, &grade)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning row: %v", err)
+		}
+
+		if grades[netId] == nil {
+			grades[netId] = make(map[string]float64)
+		}
+		grades[netId][assignmentId] = grade
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %v", err)
+	}
+
+	return grades, nil
+}
+
+// SaveExtension sets a student's due date for an assignment, replacing
+// any extension they had before.
+func (s *Store) SaveExtension(e *models.Extension) error {
+	query := `INSERT INTO extensions (assignment_id, net_id, due_date, reason, granted_by) VALUES ($1, $2, $3, $4, $5) ON CONFLICT (assignment_id, net_id) DO UPDATE SET due_date = EXCLUDED.due_date, reason = EXCLUDED.reason, granted_by = EXCLUDED.granted_by`
+
+	_, err := s.db.Exec(
+		query,
+		e.AssignmentId,
+		e.NetId,
+		e.DueDate,
+		nullString(e.Reason),
+		nullString(e.GrantedBy),
+	)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+const extensionColumns = `assignment_id, net_id, due_date, reason, granted_by`
+
+func scanExtension(row rowScanner) (*models.Extension, error) {
+	var reason, grantedBy sql.NullString
+	e := &models.Extension{}
+
+	err := row.Scan(
+		&e.AssignmentId,
+		&e.NetId,
+		&e.DueDate,
+		&reason,
+		&grantedBy,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	e.Reason = reason.String
+	e.GrantedBy = grantedBy.String
+
+	return e, nil
+}
+
+// GetExtension retrieves a student's extension for an assignment. It
+// returns nil when they have none.
+func (s *Store) GetExtension(assignmentId, netId string) (
+	*models.Extension,
+	error,
+) {
+	query := `SELECT ` + extensionColumns + ` FROM extensions WHERE assignment_id = $1 AND net_id = $2`
+
+	e, err := scanExtension(s.db.QueryRow(query, assignmentId, netId))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return e, nil
+}
+
+// GetExtensions retrieves every extension granted for an assignment.
+func (s *Store) GetExtensions(assignmentId string) (
+	[]*models.Extension,
+	error,
+) {
+	query := `SELECT ` + extensionColumns + ` FROM extensions WHERE assignment_id = $1 ORDER BY net_id`
+
+	rows, err := s.db.Query(query, assignmentId)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var extensions []*models.Extension
+
+	for rows.Next() {
+		e, err := scanExtension(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning row: %v", err)
+		}
+		extensions = append(extensions, e)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %v", err)
+	}
+
+	return extensions, nil
+}
+
+func (s *Store) DeleteExtension(assignmentId, netId string) error {
+	query := `DELETE FROM extensions WHERE assignment_id = $1 AND net_id = $2`
+
+	_, err := s.db.Exec(query, assignmentId, netId)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// SaveAccommodation sets a student's accommodation in a course,
+// replacing any they had before.
+func (s *Store) SaveAccommodation(a *models.Accommodation) error {
+	query := `INSERT INTO accommodations (course_id, net_id, extra_time, time_multiplier, note) VALUES ($1, $2, $3, $4, $5) ON CONFLICT (course_id, net_id) DO UPDATE SET extra_time = EXCLUDED.extra_time, time_multiplier = EXCLUDED.time_multiplier, note = EXCLUDED.note`
+
+	_, err := s.db.Exec(
+		query,
+		a.CourseId,
+		a.NetId,
+		a.ExtraTime,
+		a.TimeMultiplier,
+		nullString(a.Note),
+	)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+const accommodationColumns = `course_id, net_id, extra_time, time_multiplier, note`
+
+func scanAccommodation(row rowScanner) (*models.Accommodation, error) {
+	var note sql.NullString
+	a := &models.Accommodation{}
+
+	err := row.Scan(
+		&a.CourseId,
+		&a.NetId,
+		&a.ExtraTime,
+		&a.TimeMultiplier,
+		&note,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	a.Note = note.String
+
+	return a, nil
+}
+
+// GetAccommodation retrieves a student's accommodation in a course. It
+// returns nil when they have none.
+func (s *Store) GetAccommodation(courseId, netId string) (
+	*models.Accommodation,
+	error,
+) {
+	query := `SELECT ` + accommodationColumns + ` FROM accommodations WHERE course_id = $1 AND net_id = $2`
+
+	a, err := scanAccommodation(s.db.QueryRow(query, courseId, netId))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return a, nil
+}
+
+// GetAccommodations retrieves the accommodations of a course's
+// students.
+func (s *Store) GetAccommodations(courseId string) (
+	[]*models.Accommodation,
+	error,
+) {
+	query := `SELECT ` + accommodationColumns + ` FROM accommodations WHERE course_id = $1 ORDER BY net_id`
+
+	rows, err := s.db.Query(query, courseId)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var accommodations []*models.Accommodation
+
+	for rows.Next() {
+		a, err := scanAccommodation(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning row: %v", err)
+		}
+		accommodations = append(accommodations, a)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %v", err)
+	}
+
+	return accommodations, nil
+}
+
+func (s *Store) DeleteAccommodation(courseId, netId string) error {
+	query := `DELETE FROM accommodations WHERE course_id = $1 AND net_id = $2`
+
+	_, err := s.db.Exec(query, courseId, netId)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+const notificationColumns = `id, net_id, kind, message, ref, created_at, read_at`
+
+func scanNotification(row rowScanner) (*models.Notification, error) {
+	var ref sql.NullString
+	var readAt sql.NullTime
+	n := &models.Notification{}
+
+	err := row.Scan(
+		&n.ID,
+		&n.NetId,
+		&n.Kind,
+		&n.Message,
+		&ref,
+		&n.CreatedAt,
+		&readAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	n.Ref = ref.String
+
+	if readAt.Valid {
+		n.ReadAt = &readAt.Time
+	}
+
+	return n, nil
+}
+
+func (s *Store) InsertNotification(n *models.Notification) error {
+	query := `INSERT INTO notifications (net_id, kind, message, ref, created_at) VALUES ($1, $2, $3, $4, CURRENT_TIMESTAMP) RETURNING id, created_at`
+
+	row := s.db.QueryRow(query, n.NetId, n.Kind, n.Message, nullString(n.Ref))
+
+	err := row.Scan(&n.ID, &n.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// GetNotifications retrieves a user's notifications, newest first.
+func (s *Store) GetNotifications(netId string) ([]*models.Notification, error) {
+	query := `SELECT ` + notificationColumns + ` FROM notifications WHERE net_id = $1 ORDER BY created_at DESC`
+
+	rows, err := s.db.Query(query, netId)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var notifications []*models.Notification
+
+	for rows.Next() {
+		n, err := scanNotification(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning row: %v", err)
+		}
+		notifications = append(notifications, n)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %v", err)
+	}
+
+	return notifications, nil
+}
+
+// MarkNotificationRead marks one of a user's notifications as read.
+// Notifications that were already read keep the time they were first
+// read.
+func (s *Store) MarkNotificationRead(id, netId string) error {
+	query := `UPDATE notifications SET read_at = COALESCE(read_at, CURRENT_TIMESTAMP) WHERE id = $1 AND net_id = $2`
+
+	result, err := s.db.Exec(query, id, netId)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return ERR_RECORD_NOT_FOUND
+	}
+
+	return nil
+}
+
+// SavePeerReviewPolicy sets an assignment's peer review policy,
+// replacing the policy it had before. When reviewers were allocated
+// is kept.
+func (s *Store) SavePeerReviewPolicy(p *models.PeerReviewPolicy) error {
+	query := `INSERT INTO peer_review_policies (assignment_id, reviewers, weight) VALUES ($1, $2, $3) ON CONFLICT (assignment_id) DO UPDATE SET reviewers = EXCLUDED.reviewers, weight = EXCLUDED.weight`
+
+	_, err := s.db.Exec(query, p.AssignmentId, p.Reviewers, p.Weight)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// SetReviewersAllocated records when the reviewers of an assignment's
+// submissions were allocated.
+func (s *Store) SetReviewersAllocated(assignmentId string, at time.Time) error {
+	query := `UPDATE peer_review_policies SET allocated_at = $1 WHERE assignment_id = $2`
+
+	_, err := s.db.Exec(query, at.UTC(), assignmentId)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// GetAssignmentsDueForAllocation retrieves the IDs of the peer reviewed
+// assignments that were due by a time, but whose reviewers have not
+// been allocated yet.
+func (s *Store) GetAssignmentsDueForAllocation(now time.Time) ([]string, error) {
+	query := `SELECT a.id FROM assignments a
+JOIN peer_review_policies pr ON pr.assignment_id = a.id
+WHERE pr.allocated_at IS NULL AND a.due_date <= $1`
+
+	rows, err := s.db.Query(query, now.UTC())
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var ids []string
+
+	for rows.Next() {
+		var id string
+
+		err := rows.Scan(&id)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning row: %v", err)
+		}
+		ids = append(ids, id)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %v", err)
+	}
+
+	return ids, nil
+}
+
+// InsertPeerReviews inserts the empty reviews that reviewers were
+// allocated, all at once.
+func (s *Store) InsertPeerReviews(reviews []*models.PeerReview) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	query := `INSERT INTO peer_reviews (assignment_id, submission_id, reviewer) VALUES ($1, $2, $3) RETURNING id`
+
+	for _, r := range reviews {
+		row := tx.QueryRow(query, r.AssignmentId, r.SubmissionId, r.Reviewer)
+
+		err = row.Scan(&r.ID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+const peerReviewColumns = `id, assignment_id, submission_id, reviewer, score, comments, submitted_at`
+
+func scanPeerReview(row rowScanner) (*models.PeerReview, error) {
+	var score sql.NullFloat64
+	var comments sql.NullString
+	var submittedAt sql.NullTime
+	r := &models.PeerReview{}
+
+	err := row.Scan(
+		&r.ID,
+		&r.AssignmentId,
+		&r.SubmissionId,
+		&r.Reviewer,
+		&score,
+		&comments,
+		&submittedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if score.Valid {
+		r.Score = &score.Float64
+	}
+
+	r.Comments = comments.String
+
+	if submittedAt.Valid {
+		r.SubmittedAt = &submittedAt.Time
+	}
+
+	return r, nil
+}
+
+func (s *Store) GetPeerReviewById(id string) (*models.PeerReview, error) {
+	query := `SELECT ` + peerReviewColumns + ` FROM peer_reviews WHERE id = $1`
+
+	r, err := scanPeerReview(s.db.QueryRow(query, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ERR_RECORD_NOT_FOUND
+		}
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// GetPeerReviews retrieves an assignment's peer reviews. When a
+// submission ID is given, only the reviews of that submission are
+// retrieved, and when a reviewer is given, only the reviews they
+// were allocated.
+func (s *Store) GetPeerReviews(assignmentId, submissionId, reviewer string) (
+	[]*models.PeerReview,
+	error,
+) {
+	query := `SELECT ` + peerReviewColumns + ` FROM peer_reviews
+WHERE assignment_id = $1 AND ($2 = '' OR submission_id::text = $2) AND ($3 = '' OR reviewer = $3)
+ORDER BY submission_id, reviewer`
+
+	rows, err := s.db.Query(query, assignmentId, submissionId, reviewer)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var reviews []*models.PeerReview
+
+	for rows.Next() {
+		r, err := scanPeerReview(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning row: %v", err)
+		}
+		reviews = append(reviews, r)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %v", err)
+	}
+
+	return reviews, nil
+}
+
+// SavePeerReview saves what a reviewer wrote, along with their rubric
+// scores if they scored against a rubric.
+func (s *Store) SavePeerReview(r *models.PeerReview) error {
+	query := `UPDATE peer_reviews SET score = $1, comments = $2, submitted_at = $3 WHERE id = $4`
+
+	var submittedAt sql.NullTime
+	if r.SubmittedAt != nil {
+		submittedAt = nullTime(*r.SubmittedAt)
+	}
+
+	_, err := s.db.Exec(query, r.Score, nullString(r.Comments), submittedAt, r.ID)
+	if err != nil {
+		return err
+	}
+
+	query = `DELETE FROM peer_review_scores WHERE review_id = $1`
+
+	_, err = s.db.Exec(query, r.ID)
+	if err != nil {
+		return err
+	}
+
+	query = `INSERT INTO peer_review_scores (review_id, criterion_id, level, points, comment) VALUES ($1, $2, $3, $4, $5)`
+
+	for _, score := range r.Rubric {
+		_, err = s.db.Exec(
+			query,
+			r.ID,
+			score.CriterionId,
+			nullString(score.Level),
+			score.Points,
+			nullString(score.Comment),
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GetPeerReviewScores retrieves a peer review's rubric scores, in the
+// order of the rubric's criteria.
+func (s *Store) GetPeerReviewScores(reviewId string) (
+	[]*models.CriterionScore,
+	error,
+) {
+	query := `SELECT ` + rubricScoreColumns + `
+		FROM peer_review_scores rs
+		JOIN rubric_criteria rc ON rc.id = rs.criterion_id
+		WHERE rs.review_id = $1
+		ORDER BY rc.position`
+
+	rows, err := s.db.Query(query, reviewId)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var scores []*models.CriterionScore
+
+	for rows.Next() {
+		score, err := scanRubricScore(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning row: %v", err)
+		}
+		scores = append(scores, score)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %v", err)
+	}
+
+	return scores, nil
+}
+
+// SavePeerScore sets a submission's peer score.
+func (s *Store) SavePeerScore(sub *models.Submission) error {
+	query := `UPDATE submissions SET peer_score = $1 WHERE id = $2`
+
+	_, err := s.db.Exec(query, sub.PeerScore, sub.ID)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// SaveOnTime saves whether a submission was made on time, after the
+// student's due date changes.
+func (s *Store) SaveOnTime(sub *models.Submission) error {
+	query := `UPDATE submissions SET on_time = $1 WHERE id = $2`
+
+	_, err := s.db.Exec(query, sub.OnTime, sub.ID)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (s *Store) InsertRegradeRequest(r *models.RegradeRequest) error {
+	query := `INSERT INTO regrade_requests (submission_id, assignment_id, net_id, justification, state, old_grade, created_at) VALUES ($1, $2, $3, $4, $5, $6, CURRENT_TIMESTAMP) RETURNING id, created_at`
+
+	row := s.db.QueryRow(
+		query,
+		r.SubmissionId,
+		r.AssignmentId,
+		r.NetId,
+		r.Justification,
+		r.State,
+		r.OldGrade,
+	)
+
+	err := row.Scan(&r.ID, &r.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+const regradeRequestColumns = `id, submission_id, assignment_id, net_id, justification, state, assigned_to, old_grade, new_grade, reason, resolved_by, resolved_at, created_at`
+
+func scanRegradeRequest(row rowScanner) (*models.RegradeRequest, error) {
+	var assignedTo, reason, resolvedBy sql.NullString
+	var newGrade sql.NullFloat64
+	var resolvedAt sql.NullTime
+	r := &models.RegradeRequest{}
+
+	err := row.Scan(
+		&r.ID,
+		&r.SubmissionId,
+		&r.AssignmentId,
+		&r.NetId,
+		&r.Justification,
+		&r.State,
+		&assignedTo,
+		&r.OldGrade,
+		&newGrade,
+		&reason,
+		&resolvedBy,
+		&resolvedAt,
+		&r.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	r.AssignedTo = assignedTo.String
+	r.Reason = reason.String
+	r.ResolvedBy = resolvedBy.String
+
+	if newGrade.Valid {
+		r.NewGrade = &newGrade.Float64
+	}
+
+	if resolvedAt.Valid {
+		r.ResolvedAt = &resolvedAt.Time
+	}
+
+	return r, nil
+}
+
+func (s *Store) GetRegradeRequestById(id string) (*models.RegradeRequest, error) {
+	query := `SELECT ` + regradeRequestColumns + ` FROM regrade_requests WHERE id = $1`
+
+	r, err := scanRegradeRequest(s.db.QueryRow(query, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ERR_RECORD_NOT_FOUND
+		}
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// GetRegradeRequests retrieves an assignment's regrade requests,
+// oldest first. When a submission ID is given, only the requests for
+// that submission are retrieved.
+func (s *Store) GetRegradeRequests(assignmentId, submissionId string) (
+	[]*models.RegradeRequest,
+	error,
+) {
+	query := `SELECT ` + regradeRequestColumns + ` FROM regrade_requests
+WHERE assignment_id = $1 AND ($2 = '' OR submission_id::text = $2)
+ORDER BY created_at`
+
+	rows, err := s.db.Query(query, assignmentId, submissionId)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var requests []*models.RegradeRequest
+
+	for rows.Next() {
+		r, err := scanRegradeRequest(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning row: %v", err)
+		}
+		requests = append(requests, r)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %v", err)
+	}
+
+	return requests, nil
+}
+
+// UpdateRegradeRequest saves the state of a regrade request, along
+// with how it was decided.
+func (s *Store) UpdateRegradeRequest(r *models.RegradeRequest) error {
+	query := `UPDATE regrade_requests SET state = $1, assigned_to = $2, new_grade = $3, reason = $4, resolved_by = $5, resolved_at = $6 WHERE id = $7`
+
+	var resolvedAt sql.NullTime
+	if r.ResolvedAt != nil {
+		resolvedAt = nullTime(*r.ResolvedAt)
+	}
+
+	_, err := s.db.Exec(
+		query,
+		r.State,
+		nullString(r.AssignedTo),
+		r.NewGrade,
+		nullString(r.Reason),
+		nullString(r.ResolvedBy),
+		resolvedAt,
+		r.ID,
+	)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (s *Store) InsertRegradeEvent(e *models.RegradeEvent) error {
+	query := `INSERT INTO regrade_events (request_id, actor, state, note, created_at) VALUES ($1, $2, $3, $4, $5)`
+
+	_, err := s.db.Exec(
+		query,
+		e.RequestId,
+		e.Actor,
+		e.State,
+		nullString(e.Note),
+		e.At.UTC(),
+	)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// GetRegradeEvents retrieves the history of a regrade request, oldest
+// first.
+func (s *Store) GetRegradeEvents(requestId string) ([]*models.RegradeEvent, error) {
+	query := `SELECT request_id, actor, state, note, created_at FROM regrade_events WHERE request_id = $1 ORDER BY created_at`
+
+	rows, err := s.db.Query(query, requestId)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var events []*models.RegradeEvent
+
+	for rows.Next() {
+		var note sql.NullString
+		e := &models.RegradeEvent{}
+
+		err := rows.Scan(&e.RequestId, &e.Actor, &e.State, &note, &e.At)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning row: %v", err)
+		}
+
+		e.Note = note.String
+		events = append(events, e)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %v", err)
+	}
+
+	return events, nil
+}
+
+func (s *Store) InsertSimilarityReport(r *models.SimilarityReport) error {
+	compareWith, err := json.Marshal(r.CompareWith)
+	if err != nil {
+		return err
+	}
+
+	query := `INSERT INTO similarity_reports (assignment_id, requested_by, compare_with, status, created_at) VALUES ($1, $2, $3, $4, CURRENT_TIMESTAMP) RETURNING id, created_at`
+
+	row := s.db.QueryRow(
+		query,
+		r.AssignmentId,
+		r.RequestedBy,
+		compareWith,
+		r.Status,
+	)
+
+	err = row.Scan(&r.ID, &r.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+const similarityReportColumns = `id, assignment_id, requested_by, compare_with, status, error, created_at, finished_at`
+
+func scanSimilarityReport(row rowScanner) (*models.SimilarityReport, error) {
+	var compareWith []byte
+	var reportErr sql.NullString
+	var finishedAt sql.NullTime
+	r := &models.SimilarityReport{}
+
+	err := row.Scan(
+		&r.ID,
+		&r.AssignmentId,
+		&r.RequestedBy,
+		&compareWith,
+		&r.Status,
+		&reportErr,
+		&r.CreatedAt,
+		&finishedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(compareWith, &r.CompareWith)
+	if err != nil {
+		return nil, err
+	}
+
+	r.Error = reportErr.String
+
+	if finishedAt.Valid {
+		r.FinishedAt = &finishedAt.Time
+	}
+
+	return r, nil
+}
+
+func (s *Store) GetSimilarityReportById(id string) (
+	*models.SimilarityReport,
+	error,
+) {
+	query := `SELECT ` + similarityReportColumns + ` FROM similarity_reports WHERE id = $1`
+
+	r, err := scanSimilarityReport(s.db.QueryRow(query, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ERR_RECORD_NOT_FOUND
+		}
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// GetSimilarityReports retrieves an assignment's similarity reports,
+// newest first.
+func (s *Store) GetSimilarityReports(assignmentId string) (
+	[]*models.SimilarityReport,
+	error,
+) {
+	query := `SELECT ` + similarityReportColumns + ` FROM similarity_reports WHERE assignment_id = $1 ORDER BY created_at DESC`
+
+	rows, err := s.db.Query(query, assignmentId)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	reports := []*models.SimilarityReport{}
+
+	for rows.Next() {
+		r, err := scanSimilarityReport(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning row: %v", err)
+		}
+		reports = append(reports, r)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %v", err)
+	}
+
+	return reports, nil
+}
+
+// GetPendingSimilarityReports retrieves the IDs of the similarity
+// reports that are waiting to be made, oldest first.
+func (s *Store) GetPendingSimilarityReports() ([]string, error) {
+	query := `SELECT id FROM similarity_reports WHERE status = $1 ORDER BY created_at`
+
+	rows, err := s.db.Query(query, models.SIMILARITY_PENDING)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var ids []string
+
+	for rows.Next() {
+		var id string
+
+		err := rows.Scan(&id)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning row: %v", err)
+		}
+
+		ids = append(ids, id)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %v", err)
+	}
+
+	return ids, nil
+}
+
+// SaveSimilarityReport saves how a similarity report was made, along
+// with the pairs it found, all at once.
+func (s *Store) SaveSimilarityReport(r *models.SimilarityReport) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	var finishedAt sql.NullTime
+	if r.FinishedAt != nil {
+		finishedAt = nullTime(*r.FinishedAt)
+	}
+
+	query := `UPDATE similarity_reports SET status = $1, error = $2, finished_at = $3 WHERE id = $4`
+
+	_, err = tx.Exec(query, r.Status, nullString(r.Error), finishedAt, r.ID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`DELETE FROM similarity_pairs WHERE report_id = $1`, r.ID)
+	if err != nil {
+		return err
+	}
+
+	query = `INSERT INTO similarity_pairs (report_id, submission_a, netid_a, submission_b, netid_b, assignment_b, score, matches) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+
+	for _, p := range r.Pairs {
+		matches, err := json.Marshal(p.Matches)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(
+			query,
+			r.ID,
+			p.SubmissionA,
+			p.NetIdA,
+			p.SubmissionB,
+			p.NetIdB,
+			p.AssignmentB,
+			p.Score,
+			matches,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetSimilarityPairs retrieves the pairs a similarity report found,
+// the most similar first.
+func (s *Store) GetSimilarityPairs(reportId string) (
+	[]*models.SimilarityPair,
+	error,
+) {
+	query := `SELECT report_id, submission_a, netid_a, submission_b, netid_b, assignment_b, score, matches FROM similarity_pairs WHERE report_id = $1 ORDER BY score DESC`
+
+	rows, err := s.db.Query(query, reportId)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	pairs := []*models.SimilarityPair{}
+
+	for rows.Next() {
+		var matches []byte
+		p := &models.SimilarityPair{}
+
+		err := rows.Scan(
+			&p.ReportId,
+			&p.SubmissionA,
+			&p.NetIdA,
+			&p.SubmissionB,
+			&p.NetIdB,
+			&p.AssignmentB,
+			&p.Score,
+			&matches,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning row: %v", err)
+		}
+
+		err = json.Unmarshal(matches, &p.Matches)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning row: %v", err)
+		}
+
+		pairs = append(pairs, p)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %v", err)
+	}
+
+	return pairs, nil
+}
+
+// SaveHarness saves what an assignment's submissions are tested with,
+// replacing its files, all at once.
+func (s *Store) SaveHarness(h *models.Harness) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	query := `INSERT INTO harnesses (assignment_id, command, image, timeout, memory) VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (assignment_id) DO UPDATE SET command = EXCLUDED.command, image = EXCLUDED.image, timeout = EXCLUDED.timeout, memory = EXCLUDED.memory`
+
+	_, err = tx.Exec(
+		query,
+		h.AssignmentId,
+		h.Command,
+		nullString(h.Image),
+		h.Timeout,
+		h.Memory,
+	)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`DELETE FROM harness_media WHERE assignment_id = $1`, h.AssignmentId)
+	if err != nil {
+		return err
+	}
+
+	for _, m := range h.Files {
+		_, err = tx.Exec(
+			`INSERT INTO harness_media (assignment_id, media_id) VALUES ($1, $2)`,
+			h.AssignmentId,
+			m.ID,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetHarness retrieves what an assignment's submissions are tested
+// with, along with its files.
+func (s *Store) GetHarness(assignmentId string) (*models.Harness, error) {
+	var image sql.NullString
+	h := &models.Harness{AssignmentId: assignmentId}
+
+	query := `SELECT command, image, timeout, memory FROM harnesses WHERE assignment_id = $1`
+
+	err := s.db.QueryRow(query, assignmentId).Scan(
+		&h.Command,
+		&image,
+		&h.Timeout,
+		&h.Memory,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ERR_RECORD_NOT_FOUND
+		}
+		return nil, err
+	}
+
+	h.Image = image.String
+
+	query = `
+		SELECT m.id, m.type, m.path, COALESCE(m.name, ''), m.size, COALESCE(m.checksum, ''), m.created_at, m.scan_status
+		FROM harness_media hm
+		JOIN media m ON m.id = hm.media_id
+		WHERE hm.assignment_id = $1
+		ORDER BY m.created_at
+	`
+
+	rows, err := s.db.Query(query, assignmentId)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		m := &models.Media{}
+
+		err := rows.Scan(
+			&m.ID,
+			&m.FileType,
+			&m.FilePath,
+			&m.FileName,
+			&m.Size,
+			&m.Checksum,
+			&m.CreatedAt,
+			&m.Status,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning row: %v", err)
+		}
+
+		m.AttributionsByType = map[string]string{"harness": assignmentId}
+		h.Files = append(h.Files, m)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %v", err)
+	}
+
+	return h, nil
+}
+
+func (s *Store) InsertGradeJob(j *models.GradeJob) error {
+	query := `INSERT INTO grade_jobs (submission_id, assignment_id, status, created_at) VALUES ($1, $2, $3, CURRENT_TIMESTAMP) RETURNING id, created_at`
+
+	row := s.db.QueryRow(query, j.SubmissionId, j.AssignmentId, j.Status)
+
+	err := row.Scan(&j.ID, &j.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+const gradeJobColumns = `id, submission_id, assignment_id, status, score, passed, total, tests, log, error, created_at, started_at, finished_at`
+
+func scanGradeJob(row rowScanner) (*models.GradeJob, error) {
+	var score sql.NullFloat64
+	var tests []byte
+	var log, jobErr sql.NullString
+	var startedAt, finishedAt sql.NullTime
+	j := &models.GradeJob{}
+
+	err := row.Scan(
+		&j.ID,
+		&j.SubmissionId,
+		&j.AssignmentId,
+		&j.Status,
+		&score,
+		&j.Passed,
+		&j.Total,
+		&tests,
+		&log,
+		&jobErr,
+		&j.CreatedAt,
+		&startedAt,
+		&finishedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(tests, &j.Tests)
+	if err != nil {
+		return nil, err
+	}
+
+	j.Log = log.String
+	j.Error = jobErr.String
+
+	if score.Valid {
+		j.Score = &score.Float64
+	}
+
+	if startedAt.Valid {
+		j.StartedAt = &startedAt.Time
+	}
+
+	if finishedAt.Valid {
+		j.FinishedAt = &finishedAt.Time
+	}
+
+	return j, nil
+}
+
+func (s *Store) GetGradeJobById(id string) (*models.GradeJob, error) {
+	query := `SELECT ` + gradeJobColumns + ` FROM grade_jobs WHERE id = $1`
+
+	j, err := scanGradeJob(s.db.QueryRow(query, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ERR_RECORD_NOT_FOUND
+		}
+		return nil, err
+	}
+
+	return j, nil
+}
+
+// GetGradeJobs retrieves the grading jobs of a submission, newest
+// first.
+func (s *Store) GetGradeJobs(submissionId string) ([]*models.GradeJob, error) {
+	query := `SELECT ` + gradeJobColumns + ` FROM grade_jobs WHERE submission_id = $1 ORDER BY created_at DESC`
+
+	rows, err := s.db.Query(query, submissionId)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	jobs := []*models.GradeJob{}
+
+	for rows.Next() {
+		j, err := scanGradeJob(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning row: %v", err)
+		}
+		jobs = append(jobs, j)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %v", err)
+	}
+
+	return jobs, nil
+}
+
+// GetQueuedGradeJobs retrieves the IDs of the grading jobs that are
+// waiting to be run, oldest first.
+func (s *Store) GetQueuedGradeJobs() ([]string, error) {
+	query := `SELECT id FROM grade_jobs WHERE status = $1 ORDER BY created_at`
+
+	rows, err := s.db.Query(query, models.GRADE_JOB_QUEUED)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var ids []string
+
+	for rows.Next() {
+		var id string
+
+		err := rows.Scan(&id)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning row: %v", err)
+		}
+
+		ids = append(ids, id)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %v", err)
+	}
+
+	return ids, nil
+}
+
+// UpdateGradeJob saves where a grading job is, along with its results.
+func (s *Store) UpdateGradeJob(j *models.GradeJob) error {
+	tests, err := json.Marshal(j.Tests)
+	if err != nil {
+		return err
+	}
+
+	if j.Tests == nil {
+		tests = []byte("[]")
+	}
+
+	var startedAt, finishedAt sql.NullTime
+	if j.StartedAt != nil {
+		startedAt = nullTime(*j.StartedAt)
+	}
+	if j.FinishedAt != nil {
+		finishedAt = nullTime(*j.FinishedAt)
+	}
+
+	query := `UPDATE grade_jobs SET status = $1, score = $2, passed = $3, total = $4, tests = $5, log = $6, error = $7, started_at = $8, finished_at = $9 WHERE id = $10`
+
+	_, err = s.db.Exec(
+		query,
+		j.Status,
+		j.Score,
+		j.Passed,
+		j.Total,
+		tests,
+		nullString(j.Log),
+		nullString(j.Error),
+		startedAt,
+		finishedAt,
+		j.ID,
+	)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// InsertAnnotation adds an annotation to a submitted file.
+func (s *Store) InsertAnnotation(a *models.Annotation) error {
+	rects, strokes, err := annotationGeometry(a)
+	if err != nil {
+		return err
+	}
+
+	query := `INSERT INTO annotations (submission_id, media_id, author, kind, page, rects, point, strokes, comment, color, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP) RETURNING id, created_at, updated_at`
+
+	row := s.db.QueryRow(
+		query,
+		a.SubmissionId,
+		a.MediaId,
+		a.Author,
+		a.Kind,
+		a.Page,
+		rects,
+		annotationPoint(a),
+		strokes,
+		nullString(a.Comment),
+		a.Color,
+	)
+
+	err = row.Scan(&a.ID, &a.CreatedAt, &a.UpdatedAt)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// annotationGeometry encodes the rectangles and strokes of an
+// annotation, which are kept as JSON.
+func annotationGeometry(a *models.Annotation) ([]byte, []byte, error) {
+	rects, err := json.Marshal(a.Rects)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	strokes, err := json.Marshal(a.Strokes)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return rects, strokes, nil
+}
+
+func annotationPoint(a *models.Annotation) []byte {
+	if a.Point == nil {
+		return nil
+	}
+
+	point, _ := json.Marshal(a.Point)
+
+	return point
+}
+
+const annotationColumns = `id, submission_id, media_id, author, kind, page, rects, point, strokes, comment, color, created_at, updated_at`
+
+func scanAnnotation(row rowScanner) (*models.Annotation, error) {
+	var rects, point, strokes []byte
+	var comment sql.NullString
+	a := &models.Annotation{}
+
+	err := row.Scan(
+		&a.ID,
+		&a.SubmissionId,
+		&a.MediaId,
+		&a.Author,
+		&a.Kind,
+		&a.Page,
+		&rects,
+		&point,
+		&strokes,
+		&comment,
+		&a.Color,
+		&a.CreatedAt,
+		&a.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	a.Comment = comment.String
+
+	err = json.Unmarshal(rects, &a.Rects)
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(strokes, &a.Strokes)
+	if err != nil {
+		return nil, err
+	}
+
+	if point != nil {
+		err = json.Unmarshal(point, &a.Point)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return a, nil
+}
+
+func (s *Store) GetAnnotationById(id string) (*models.Annotation, error) {
+	query := `SELECT ` + annotationColumns + ` FROM annotations WHERE id = $1`
+
+	a, err := scanAnnotation(s.db.QueryRow(query, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ERR_RECORD_NOT_FOUND
+		}
+		return nil, err
+	}
+
+	return a, nil
+}
+
+// GetAnnotations retrieves the annotations of a submission's files,
+// oldest first.
+func (s *Store) GetAnnotations(submissionId string) ([]*models.Annotation, error) {
+	query := `SELECT ` + annotationColumns + ` FROM annotations WHERE submission_id = $1 ORDER BY created_at`
+
+	rows, err := s.db.Query(query, submissionId)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	annotations := []*models.Annotation{}
+
+	for rows.Next() {
+		a, err := scanAnnotation(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning row: %v", err)
+		}
+		annotations = append(annotations, a)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %v", err)
+	}
+
+	return annotations, nil
+}
+
+// UpdateAnnotation saves where an annotation is, what it looks like and
+// what it says.
+func (s *Store) UpdateAnnotation(a *models.Annotation) error {
+	rects, strokes, err := annotationGeometry(a)
+	if err != nil {
+		return err
+	}
+
+	query := `UPDATE annotations SET kind = $1, page = $2, rects = $3, point = $4, strokes = $5, comment = $6, color = $7, updated_at = CURRENT_TIMESTAMP WHERE id = $8 RETURNING updated_at`
+
+	row := s.db.QueryRow(
+		query,
+		a.Kind,
+		a.Page,
+		rects,
+		annotationPoint(a),
+		strokes,
+		nullString(a.Comment),
+		a.Color,
+		a.ID,
+	)
+
+	err = row.Scan(&a.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ERR_RECORD_NOT_FOUND
+		}
+		return err
+	}
+
+	return nil
+}
+
+func (s *Store) DeleteAnnotation(id string) error {
+	query := `DELETE FROM annotations WHERE id = $1`
+
+	_, err := s.db.Exec(query, id)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// GetFeedbackMedia retrieves the media attached to the feedback of a
+// submission, oldest first, without their variants.
+func (s *Store) GetFeedbackMedia(submissionId string) ([]*models.Media, error) {
+	query := `
+		SELECT m.id, m.type, m.path, COALESCE(m.name, ''), m.size, COALESCE(m.checksum, ''), m.created_at, m.scan_status
+		FROM feedback_media fm
+		JOIN media m ON m.id = fm.media_id
+		WHERE fm.submission_id = $1
+		ORDER BY m.created_at
+	`
+
+	rows, err := s.db.Query(query, submissionId)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	media := []*models.Media{}
+
+	for rows.Next() {
+		m := &models.Media{}
+
+		err := rows.Scan(
+			&m.ID,
+			&m.FileType,
+			&m.FilePath,
+			&m.FileName,
+			&m.Size,
+			&m.Checksum,
+			&m.CreatedAt,
+			&m.Status,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning row: %v", err)
+		}
+
+		m.AttributionsByType = map[string]string{"feedback": submissionId}
+		media = append(media, m)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %v", err)
+	}
+
+	return media, nil
+}
+
+// DeleteFeedbackMedia takes media off the feedback of a submission.
+func (s *Store) DeleteFeedbackMedia(submissionId, mediaId string) error {
+	query := `DELETE FROM feedback_media WHERE submission_id = $1 AND media_id = $2`
+
+	_, err := s.db.Exec(query, submissionId, mediaId)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// InsertModule adds a module to a course, along with its
+// prerequisites.
+func (s *Store) InsertModule(m *models.Module) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	query := `INSERT INTO modules (course_id, title, position, published, created_at, updated_at) VALUES ($1, $2, $3, $4, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP) RETURNING id, created_at, updated_at`
+
+	row := tx.QueryRow(query, m.CourseId, m.Title, m.Position, m.Published)
+
+	err = row.Scan(&m.ID, &m.CreatedAt, &m.UpdatedAt)
+	if err != nil {
+		return err
+	}
+
+	err = insertModulePrerequisites(tx, m)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func insertModulePrerequisites(tx *sql.Tx, m *models.Module) error {
+	query := `INSERT INTO module_prerequisites (module_id, prerequisite_id) VALUES ($1, $2)`
+
+	for _, id := range m.Prerequisites {
+		_, err := tx.Exec(query, m.ID, id)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// moduleColumns are the columns scanned by scanModule.
+const moduleColumns = `id, course_id, title, position, published, created_at, updated_at`
+
+func scanModule(row rowScanner) (*models.Module, error) {
+	m := &models.Module{
+		Prerequisites: []string{},
+		Items:         []*models.ModuleItem{},
+	}
+
+	err := row.Scan(
+		&m.ID,
+		&m.CourseId,
+		&m.Title,
+		&m.Position,
+		&m.Published,
+		&m.CreatedAt,
+		&m.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// GetModuleById retrieves a module along with its prerequisites and
+// its items, in order.
+func (s *Store) GetModuleById(id string) (*models.Module, error) {
+	query := `SELECT ` + moduleColumns + ` FROM modules WHERE id = $1`
+
+	m, err := scanModule(s.db.QueryRow(query, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ERR_RECORD_NOT_FOUND
+		}
+		return nil, err
+	}
+
+	err = s.getModuleContents([]*models.Module{m}, "mp.module_id = $1", "mi.module_id = $1", m.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// GetModules retrieves a course's modules in order, along with their
+// prerequisites and their items.
+func (s *Store) GetModules(courseId string) ([]*models.Module, error) {
+	query := `SELECT ` + moduleColumns + ` FROM modules WHERE course_id = $1 ORDER BY position`
+
+	rows, err := s.db.Query(query, courseId)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	modules := []*models.Module{}
+
+	for rows.Next() {
+		m, err := scanModule(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning row: %v", err)
+		}
+
+		modules = append(modules, m)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %v", err)
+	}
+
+	err = s.getModuleContents(
+		modules,
+		"mp.module_id IN (SELECT id FROM modules WHERE course_id = $1)",
+		"mi.module_id IN (SELECT id FROM modules WHERE course_id = $1)",
+		courseId,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return modules, nil
+}
+
+// getModuleContents fills in the prerequisites and items of modules,
+// found by conditions on module_prerequisites mp and module_items mi
+// that both take the same argument.
+func (s *Store) getModuleContents(
+	modules []*models.Module,
+	prerequisitesWhere, itemsWhere string,
+	arg string,
+) error {
+	byId := make(map[string]*models.Module)
+	for _, m := range modules {
+		byId[m.ID] = m
+	}
+
+	query := `SELECT mp.module_id, mp.prerequisite_id FROM module_prerequisites mp JOIN modules p ON p.id = mp.prerequisite_id WHERE ` + prerequisitesWhere + ` ORDER BY p.position`
+
+	rows, err := s.db.Query(query, arg)
+	if err != nil {
+		return err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var moduleId, prerequisiteId string
+
+		err := rows.Scan(&moduleId, &prerequisiteId)
+		if err != nil {
+			return fmt.Errorf("error scanning row: %v", err)
+		}
+
+		if m, ok := byId[moduleId]; ok {
+			m.Prerequisites = append(m.Prerequisites, prerequisiteId)
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		return fmt.Errorf("error iterating rows: %v", err)
+	}
+
+	query = `SELECT ` + moduleItemColumns + ` FROM module_items mi WHERE ` + itemsWhere + ` ORDER BY mi.position`
 
-	_, err := s.db.Exec(query, c.ID, userid)
+	rows, err = s.db.Query(query, arg)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	return c, nil
-}
+	defer rows.Close()
 
-func (s *Store) RemoveStudent(c *models.Course, userid string) (
-	*models.Course,
-	error,
-) {
-	query := `DELETE FROM course_roster WHERE student_id=$1 AND course_id=$2`
+	for rows.Next() {
+		item, err := scanModuleItem(rows)
+		if err != nil {
+			return fmt.Errorf("error scanning row: %v", err)
+		}
 
-	_, err := s.db.Exec(query, userid, c.ID)
-	if err != nil {
-		return nil, err
+		if m, ok := byId[item.ModuleId]; ok {
+			m.Items = append(m.Items, item)
+		}
 	}
-	query = `DELETE FROM user_courses WHERE user_net_id=$1 AND course_id=$2`
 
-	_, err = s.db.Exec(query, userid, c.ID)
-	if err != nil {
-		return nil, err
+	if err = rows.Err(); err != nil {
+		return fmt.Errorf("error iterating rows: %v", err)
 	}
 
-	return c, nil
+	return nil
 }
 
-func (s *Store) InsertSubmission(
-	sub *models.Submission,
-) (
-	*models.Submission,
-	error,
-) {
-	query := `INSERT INTO submissions (submission_time, on_time, grade, feedback) VALUES ($1, $2, $3, $4) RETURNING id`
+// UpdateModule saves a module's title, whether it is published and its
+// prerequisites.
+func (s *Store) UpdateModule(m *models.Module) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
 
-	row := s.db.QueryRow(
-		query,
-		&sub.SubmissionTime,
-		&sub.OnTime,
-		&sub.Grade,
-		&sub.Feedback,
-	)
-	err := row.Scan(
-		&sub.ID,
-	)
+	defer tx.Rollback()
+
+	query := `UPDATE modules SET title = $1, published = $2, updated_at = CURRENT_TIMESTAMP WHERE id = $3 RETURNING updated_at`
+
+	err = tx.QueryRow(query, m.Title, m.Published, m.ID).Scan(&m.UpdatedAt)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, ERR_RECORD_NOT_FOUND
+		if errors.Is(err, sql.ErrNoRows) {
+			return ERR_RECORD_NOT_FOUND
 		}
-		return nil, err
+		return err
 	}
-	return sub, nil
-}
-
-func (s *Store) InsertSubmissionIntoAssignment(sub *models.Submission) (*models.Submission, error) {
-	query := `INSERT INTO assignment_submissions (assignment_id, submission_id) VALUES ($1, $2)`
 
-	_, err := s.db.Exec(query, sub.AssignmentId, sub.ID)
+	_, err = tx.Exec(`DELETE FROM module_prerequisites WHERE module_id = $1`, m.ID)
 	if err != nil {
-		return nil, err
+		return err
 	}
-	return sub, nil
-}
-func (s *Store) InsertSubmissionIntoUser(sub *models.Submission) (*models.Submission, error) {
-	query := `INSERT INTO user_submissions (user_net_id, submission_id) VALUES ($1, $2)`
 
-	_, err := s.db.Exec(query, sub.User.ID, sub.ID)
+	err = insertModulePrerequisites(tx, m)
 	if err != nil {
-		return nil, err
+		return err
 	}
-	return sub, nil
-}
 
-func (s *Store) GradeSubmission(
-	grade float64,
-	submission *models.Submission,
-) error {
-	return nil
+	return tx.Commit()
 }
 
-func (s *Store) InsertSubmissionFeedback(
-	feedback string,
-	submission *models.Submission,
-) error {
+// DeleteModule deletes a module along with its items.
+func (s *Store) DeleteModule(id string) error {
+	query := `DELETE FROM modules WHERE id = $1`
+
+	_, err := s.db.Exec(query, id)
+	if err != nil {
+		return err
+	}
+
 	return nil
 }
 
-func (s *Store) GetMembershipById(userid string) (
-	*models.Credential,
-	error,
-) {
-	u := &models.User{}
+// SaveModuleOrder numbers a course's modules in the order of their
+// IDs.
+func (s *Store) SaveModuleOrder(courseId string, ids []string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
 
-	var m int
+	defer tx.Rollback()
 
-	query := `SELECT id, membership FROM users WHERE net_id = $1`
-	row := s.db.QueryRow(query, userid)
+	query := `UPDATE modules SET position = $1 WHERE id = $2 AND course_id = $3`
 
-	err := row.Scan(
-		&u.ID,
-		&m,
-	)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, ERR_RECORD_NOT_FOUND
+	for i, id := range ids {
+		_, err = tx.Exec(query, i, id, courseId)
+		if err != nil {
+			return err
 		}
-		return nil, err
 	}
-	u.Membership = Membership(m)
-	return &u.Membership, nil
+
+	return tx.Commit()
 }
 
-func (s *Store) GetNetIdFromHash(hash []byte) (
-	string,
-	error,
+// moduleItemRefs splits the reference of an item into the column
+// matching its kind, leaving the others NULL.
+func moduleItemRefs(i *models.ModuleItem) (
+	assignment, message, media sql.NullString,
 ) {
-	u := &models.User{}
-	query := `SELECT net_id FROM tokens WHERE hash = $1`
-	row := s.db.QueryRow(query, hash)
+	switch i.Kind {
+	case models.MODULE_ASSIGNMENT:
+		assignment = nullString(i.RefId)
+	case models.MODULE_ANNOUNCEMENT:
+		message = nullString(i.RefId)
+	case models.MODULE_FILE:
+		media = nullString(i.RefId)
+	}
+	return assignment, message, media
+}
 
-	err = row.Scan(
-		&u.ID,
+// InsertModuleItem adds an item to a module.
+func (s *Store) InsertModuleItem(i *models.ModuleItem) error {
+	assignment, message, media := moduleItemRefs(i)
+
+	query := `INSERT INTO module_items (module_id, kind, title, position, assignment_id, message_id, media_id, url, body, requirement, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP) RETURNING id, created_at, updated_at`
+
+	row := s.db.QueryRow(
+		query,
+		i.ModuleId,
+		i.Kind,
+		i.Title,
+		i.Position,
+		assignment,
+		message,
+		media,
+		nullString(i.URL),
+		nullString(i.Body),
+		nullString(string(i.Requirement)),
 	)
+
+	err := row.Scan(&i.ID, &i.CreatedAt, &i.UpdatedAt)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return "", ERR_RECORD_NOT_FOUND
-		}
-		return "", err
+		return err
 	}
-	return u.ID, nil
-}
 
-func (s *Store) GetNameById(userid string) (
-	*models.Credential,
-	error,
-) {
-	u := &models.User{}
+	return nil
+}
 
-	var m int
+// moduleItemColumns are the columns scanned by scanModuleItem.
+const moduleItemColumns = `mi.id, mi.module_id, mi.kind, mi.title, mi.position, COALESCE(mi.assignment_id, mi.message_id, mi.media_id), mi.url, mi.body, mi.requirement, mi.created_at, mi.updated_at`
 
-	query := `SELECT id, membership FROM users WHERE net_id = $1`
-	row := s.db.QueryRow(query, userid)
+func scanModuleItem(row rowScanner) (*models.ModuleItem, error) {
+	var ref, url, body, requirement sql.NullString
+	i := &models.ModuleItem{}
 
 	err := row.Scan(
-		&u.ID,
-		&m,
+		&i.ID,
+		&i.ModuleId,
+		&i.Kind,
+		&i.Title,
+		&i.Position,
+		&ref,
+		&url,
+		&body,
+		&requirement,
+		&i.CreatedAt,
+		&i.UpdatedAt,
 	)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, ERR_RECORD_NOT_FOUND
-		}
 		return nil, err
 	}
-	u.Membership = Membership(m)
-	return &u.Membership, nil
+
+	i.RefId = ref.String
+	i.URL = url.String
+	i.Body = body.String
+	i.Requirement = models.Requirement(requirement.String)
+
+	return i, nil
 }
 
-func (s *Store) InsertMedia(
-	m *models.Media,
-) (
-	*models.Media,
-	error,
-) {
-	query := `INSERT INTO media (type, path, created_at, updated_at) VALUES ($1, $2, $3, $4) RETURNING id`
+// GetModuleItemById retrieves a module item.
+func (s *Store) GetModuleItemById(id string) (*models.ModuleItem, error) {
+	query := `SELECT ` + moduleItemColumns + ` FROM module_items mi WHERE mi.id = $1`
 
-	row := s.db.QueryRow(
-		query,
-		m.FileType,
-		m.FilePath,
-		m.CreatedAt,
-		m.UpdatedAt,
-	)
-	err := row.Scan(&m.ID)
+	i, err := scanModuleItem(s.db.QueryRow(query, id))
 	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ERR_RECORD_NOT_FOUND
+		}
 		return nil, err
 	}
 
-	return m, nil
+	return i, nil
 }
 
-func (s *Store) GetMediaById(mediaId string) (
-	*models.Media,
-	error,
-) {
-	media := &models.Media{}
+// UpdateModuleItem saves an item's title, what it refers to, where it
+// links, the text of its page and its requirement.
+func (s *Store) UpdateModuleItem(i *models.ModuleItem) error {
+	assignment, message, media := moduleItemRefs(i)
 
-	query := `SELECT id, type, path FROM media WHERE id = $1`
-	row := s.db.QueryRow(query, mediaId)
+	query := `UPDATE module_items SET title = $1, assignment_id = $2, message_id = $3, media_id = $4, url = $5, body = $6, requirement = $7, updated_at = CURRENT_TIMESTAMP WHERE id = $8 RETURNING updated_at`
 
-	err := row.Scan(
-		&media.ID,
-		&media.FileType,
-		&media.FilePath,
+	row := s.db.QueryRow(
+		query,
+		i.Title,
+		assignment,
+		message,
+		media,
+		nullString(i.URL),
+		nullString(i.Body),
+		nullString(string(i.Requirement)),
+		i.ID,
 	)
 
+	err := row.Scan(&i.UpdatedAt)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return nil, ERR_RECORD_NOT_FOUND
+			return ERR_RECORD_NOT_FOUND
 		}
-		return nil, err
+		return err
 	}
 
-	return media, nil
+	return nil
 }
 
-func (s *Store) InsertMediaIntoCourse(
-	m *models.Media,
-) error {
-	query := `INSERT INTO course_media (course_id, media_id, media_path) VALUES ($1, $2, $3)`
+func (s *Store) DeleteModuleItem(id string) error {
+	query := `DELETE FROM module_items WHERE id = $1`
 
-	_, err := s.db.Exec(query, m.AttributionsByType["course"], m.ID, m.FilePath)
+	_, err := s.db.Exec(query, id)
 	if err != nil {
 		return err
 	}
+
 	return nil
 }
-func (s *Store) InsertMediaIntoCourseBanner(
-	m *models.Media,
-) error {
 
-	query := `UPDATE courses SET banner_id = $2 WHERE id = $1;`
-	_, err = s.db.Exec(query, m.AttributionsByType["course"], m.ID)
+// SaveModuleItemOrder puts items in a module, numbered in the order of
+// their IDs. Items may be moved into the module from another.
+func (s *Store) SaveModuleItemOrder(moduleId string, ids []string) error {
+	tx, err := s.db.Begin()
 	if err != nil {
 		return err
 	}
-	return nil
+
+	defer tx.Rollback()
+
+	query := `UPDATE module_items SET module_id = $1, position = $2, updated_at = CURRENT_TIMESTAMP WHERE id = $3`
+
+	for i, id := range ids {
+		_, err = tx.Exec(query, moduleId, i, id)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
 }
 
-func (s *Store) InsertMediaIntoAssignment(
-	m *models.Media,
-) error {
-	query := `INSERT INTO assignment_media (assignment_id, media_id, media_path) VALUES ($1, $2, $3)`
+// InsertModuleCompletion records that a student completed a module
+// item in a way. Completing it the same way again changes nothing.
+func (s *Store) InsertModuleCompletion(c *models.ModuleCompletion) error {
+	query := `INSERT INTO module_completions (item_id, net_id, requirement, completed_at) VALUES ($1, $2, $3, CURRENT_TIMESTAMP) ON CONFLICT (item_id, net_id, requirement) DO NOTHING`
 
-	_, err := s.db.Exec(
-		query,
-		m.AttributionsByType["assignment"],
-		m.ID,
-		m.FilePath,
-	)
+	_, err := s.db.Exec(query, c.ItemId, c.NetId, c.Requirement)
 	if err != nil {
 		return err
 	}
@@ -1415,20 +6123,43 @@ func (s *Store) InsertMediaIntoAssignment(
 	return nil
 }
 
-func (s *Store) InsertMediaIntoSubmission(
-	m *models.Media,
-) error {
-	query := `INSERT INTO submission_media (submission_id, media_id, media_path) VALUES ($1, $2, $3)`
+// GetModuleCompletions retrieves how every student completed the items
+// of a course's modules.
+func (s *Store) GetModuleCompletions(courseId string) (
+	[]*models.ModuleCompletion,
+	error,
+) {
+	query := `
+		SELECT mc.item_id, mc.net_id, mc.requirement
+		FROM module_completions mc
+		JOIN module_items mi ON mi.id = mc.item_id
+		JOIN modules m ON m.id = mi.module_id
+		WHERE m.course_id = $1
+	`
 
-	_, err := s.db.Exec(
-		query,
-		m.AttributionsByType["submission"],
-		m.ID,
-		m.FilePath,
-	)
+	rows, err := s.db.Query(query, courseId)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	return nil
+	defer rows.Close()
+
+	var completions []*models.ModuleCompletion
+
+	for rows.Next() {
+		c := &models.ModuleCompletion{}
+
+		err := rows.Scan(&c.ItemId, &c.NetId, &c.Requirement)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning row: %v", err)
+		}
+
+		completions = append(completions, c)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %v", err)
+	}
+
+	return completions, nil
 }
@@ -0,0 +1,322 @@
+package domain
+
+import (
+	"io"
+
+	"github.com/n30w/Darkspace/internal/models"
+)
+
+// maxAnnotatedSize is the largest PDF that is read to be annotated.
+const maxAnnotatedSize = 128 << 20
+
+type AnnotationStore interface {
+	GetAssignmentById(assignmentid string) (*models.Assignment, error)
+	GetCourseIdByAssignment(assignmentId string) (string, error)
+	IsCourseTeacher(courseId, netId string) (bool, error)
+
+	GetSubmissionById(submissionId string) (*models.Submission, error)
+	GetAssignmentIdBySubmission(submissionId string) (string, error)
+	GetSubmissionFiles(submissionId string) ([]*models.Media, error)
+
+	InsertAnnotation(a *models.Annotation) error
+	GetAnnotationById(id string) (*models.Annotation, error)
+	GetAnnotations(submissionId string) ([]*models.Annotation, error)
+	UpdateAnnotation(a *models.Annotation) error
+	DeleteAnnotation(id string) error
+}
+
+type AnnotationService struct {
+	store AnnotationStore
+	files FileStore
+}
+
+func NewAnnotationService(s AnnotationStore, f FileStore) *AnnotationService {
+	return &AnnotationService{store: s, files: f}
+}
+
+// CreateAnnotation marks a page of a PDF that was submitted. Only the
+// course's teachers may annotate submissions, and only their PDFs.
+func (as *AnnotationService) CreateAnnotation(
+	a *models.Annotation,
+	netId string,
+) (*models.Annotation, error) {
+	_, _, err := as.teacherSubmission(a.SubmissionId, netId)
+	if err != nil {
+		return nil, err
+	}
+
+	err = as.checkPage(a)
+	if err != nil {
+		return nil, err
+	}
+
+	a.Author = netId
+
+	err = as.store.InsertAnnotation(a)
+	if err != nil {
+		return nil, err
+	}
+
+	return a, nil
+}
+
+// UpdateAnnotation changes where an annotation is, what it looks like
+// and what it says. It stays on the same file, and keeps its author.
+// Any of the course's teachers may change it.
+func (as *AnnotationService) UpdateAnnotation(
+	a *models.Annotation,
+	netId string,
+) (*models.Annotation, error) {
+	old, err := as.store.GetAnnotationById(a.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	_, _, err = as.teacherSubmission(old.SubmissionId, netId)
+	if err != nil {
+		return nil, err
+	}
+
+	a.SubmissionId = old.SubmissionId
+	a.MediaId = old.MediaId
+	a.Author = old.Author
+	a.CreatedAt = old.CreatedAt
+
+	err = as.checkPage(a)
+	if err != nil {
+		return nil, err
+	}
+
+	err = as.store.UpdateAnnotation(a)
+	if err != nil {
+		return nil, err
+	}
+
+	return a, nil
+}
+
+// DeleteAnnotation removes an annotation. Any of the course's teachers
+// may remove it.
+func (as *AnnotationService) DeleteAnnotation(id, netId string) error {
+	a, err := as.store.GetAnnotationById(id)
+	if err != nil {
+		return err
+	}
+
+	_, _, err = as.teacherSubmission(a.SubmissionId, netId)
+	if err != nil {
+		return err
+	}
+
+	return as.store.DeleteAnnotation(a.ID)
+}
+
+// Annotations retrieves the annotations of a submission, oldest first.
+// The course's teachers may see those of any submission, and students
+// those of their own once grades are released, as with the rest of
+// their feedback.
+func (as *AnnotationService) Annotations(submissionId, netId string) (
+	[]*models.Annotation,
+	error,
+) {
+	sub, a, teacher, err := as.readableSubmission(submissionId, netId)
+	if err != nil {
+		return nil, err
+	}
+
+	if !teacher && !a.GradesReleased {
+		return []*models.Annotation{}, nil
+	}
+
+	return as.store.GetAnnotations(sub.ID)
+}
+
+// AnnotatedPDF makes a copy of a PDF that was submitted, with the
+// annotations on it that the user may see. The copy is not kept, so
+// that it always has the latest annotations. Quarantined files are
+// not read, and are returned without a copy.
+func (as *AnnotationService) AnnotatedPDF(
+	submissionId, mediaId, netId string,
+) (*models.Media, []byte, error) {
+	annotations, err := as.Annotations(submissionId, netId)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	m, err := as.submissionPDF(submissionId, mediaId)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if m.Quarantined() {
+		return m, nil, nil
+	}
+
+	data, err := as.read(m)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var marks []*models.Annotation
+	for _, a := range annotations {
+		if a.MediaId == m.ID {
+			marks = append(marks, a)
+		}
+	}
+
+	data, err = AnnotatePDF(data, marks)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return m, data, nil
+}
+
+// checkPage checks that an annotation is on a PDF of its submission,
+// and on a page the PDF has. PDFs whose pages cannot be counted may
+// still be annotated, though their annotated copies may leave out the
+// annotations.
+func (as *AnnotationService) checkPage(a *models.Annotation) error {
+	m, err := as.submissionPDF(a.SubmissionId, a.MediaId)
+	if err != nil {
+		return err
+	}
+
+	if m.Quarantined() {
+		return nil
+	}
+
+	data, err := as.read(m)
+	if err != nil {
+		return nil
+	}
+
+	pages, err := PDFPageCount(data)
+	if err == nil && a.Page > pages {
+		return ERR_UNKNOWN_PAGE
+	}
+
+	return nil
+}
+
+// submissionPDF finds a PDF among the files of a submission.
+func (as *AnnotationService) submissionPDF(submissionId, mediaId string) (
+	*models.Media,
+	error,
+) {
+	files, err := as.store.GetSubmissionFiles(submissionId)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, m := range files {
+		if m.ID != mediaId {
+			continue
+		}
+
+		if m.FileType != models.PDF {
+			return nil, ERR_NOT_PDF
+		}
+
+		return m, nil
+	}
+
+	return nil, ERR_UNKNOWN_MEDIA
+}
+
+func (as *AnnotationService) read(m *models.Media) ([]byte, error) {
+	f, err := as.files.OpenFile(m.FilePath)
+	if err != nil {
+		return nil, err
+	}
+
+	defer f.Close()
+
+	data, err := io.ReadAll(io.LimitReader(f, maxAnnotatedSize+1))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) > maxAnnotatedSize {
+		return nil, ERR_PDF_UNREADABLE
+	}
+
+	return data, nil
+}
+
+// readableSubmission retrieves a submission that the user may read the
+// feedback of, being one of the course's teachers or the student who
+// made it.
+func (as *AnnotationService) readableSubmission(submissionId, netId string) (
+	*models.Submission,
+	*models.Assignment,
+	bool,
+	error,
+) {
+	if netId == "" {
+		return nil, nil, false, ERR_NOT_PERMITTED
+	}
+
+	sub, a, err := as.submission(submissionId)
+	if err != nil {
+		return nil, nil, false, err
+	}
+
+	courseId, err := as.store.GetCourseIdByAssignment(a.ID)
+	if err != nil {
+		return nil, nil, false, err
+	}
+
+	teacher, err := as.store.IsCourseTeacher(courseId, netId)
+	if err != nil {
+		return nil, nil, false, err
+	}
+
+	if !teacher && sub.User.ID != netId {
+		return nil, nil, false, ERR_NOT_PERMITTED
+	}
+
+	return sub, a, teacher, nil
+}
+
+// teacherSubmission retrieves a submission for a change that only the
+// course's teachers may make.
+func (as *AnnotationService) teacherSubmission(submissionId, netId string) (
+	*models.Submission,
+	*models.Assignment,
+	error,
+) {
+	sub, a, teacher, err := as.readableSubmission(submissionId, netId)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if !teacher {
+		return nil, nil, ERR_NOT_PERMITTED
+	}
+
+	return sub, a, nil
+}
+
+func (as *AnnotationService) submission(submissionId string) (
+	*models.Submission,
+	*models.Assignment,
+	error,
+) {
+	sub, err := as.store.GetSubmissionById(submissionId)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	assignmentId, err := as.store.GetAssignmentIdBySubmission(sub.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	a, err := as.store.GetAssignmentById(assignmentId)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return sub, a, nil
+}
@@ -0,0 +1,527 @@
+package domain
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"testing"
+
+	"github.com/n30w/Darkspace/internal/dal"
+	"github.com/n30w/Darkspace/internal/models"
+)
+
+// pagedPDF builds a PDF with a page for each of contents, with a
+// cross-reference table and trailer. The first page has an annotation
+// of its own.
+func pagedPDF(contents ...string) []byte {
+	var objects []string
+
+	kids := ""
+	for i := range contents {
+		kids += fmt.Sprintf("%d 0 R ", 4+2*i)
+	}
+
+	objects = append(
+		objects,
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d /MediaBox [0 0 612 792] >>", kids, len(contents)),
+		"<< /Type /Annot /Subtype /Text /Rect [0 0 10 10] /Contents (Mine) >>",
+	)
+
+	for i, content := range contents {
+		annots := ""
+		if i == 0 {
+			annots = " /Annots [3 0 R]"
+		}
+
+		objects = append(
+			objects,
+			fmt.Sprintf("<< /Type /Page /Parent 2 0 R /Contents %d 0 R%s >>", 5+2*i, annots),
+			fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(content), content),
+		)
+	}
+
+	var b bytes.Buffer
+	b.WriteString("%PDF-1.4\n")
+
+	offsets := make([]int, len(objects))
+	for i, o := range objects {
+		offsets[i] = b.Len()
+		fmt.Fprintf(&b, "%d 0 obj\n%s\nendobj\n", i+1, o)
+	}
+
+	xref := b.Len()
+	fmt.Fprintf(&b, "xref\n0 %d\n0000000000 65535 f\r\n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&b, "%010d 00000 n\r\n", offset)
+	}
+	fmt.Fprintf(&b, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+
+	return b.Bytes()
+}
+
+// streamedPDF builds a PDF whose pages are kept in a compressed object
+// stream, found through a cross-reference stream.
+func streamedPDF(pages int) []byte {
+	var objects []string
+
+	kids := ""
+	for i := 0; i < pages; i++ {
+		kids += fmt.Sprintf("%d 0 R ", 3+i)
+	}
+
+	objects = append(
+		objects,
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", kids, pages),
+	)
+
+	for i := 0; i < pages; i++ {
+		objects = append(objects, "<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] >>")
+	}
+
+	var header, body bytes.Buffer
+	for i, o := range objects {
+		fmt.Fprintf(&header, "%d %d ", i+1, body.Len())
+		body.WriteString(o)
+		body.WriteByte('\n')
+	}
+
+	var stream bytes.Buffer
+	zw := zlib.NewWriter(&stream)
+	zw.Write(header.Bytes())
+	zw.Write(body.Bytes())
+	zw.Close()
+
+	num := len(objects) + 1
+
+	var b bytes.Buffer
+	b.WriteString("%PDF-1.5\n")
+	fmt.Fprintf(
+		&b, "%d 0 obj\n<< /Type /ObjStm /N %d /First %d /Filter /FlateDecode /Length %d >>\nstream\n",
+		num, len(objects), header.Len(), stream.Len(),
+	)
+	b.Write(stream.Bytes())
+	b.WriteString("\nendstream\nendobj\n")
+
+	xref := b.Len()
+	fmt.Fprintf(
+		&b, "%d 0 obj\n<< /Type /XRef /Size %d /Root 1 0 R /W [1 4 2] /Length 0 >>\nstream\n\nendstream\nendobj\n",
+		num+1, num+2,
+	)
+	fmt.Fprintf(&b, "startxref\n%d\n%%%%EOF\n", xref)
+
+	return b.Bytes()
+}
+
+// objectStreamPDF builds a PDF with nothing but an uncompressed object
+// stream holding header and body, found through a cross-reference
+// stream.
+func objectStreamPDF(n int, header, body string) []byte {
+	var b bytes.Buffer
+	b.WriteString("%PDF-1.5\n")
+	fmt.Fprintf(
+		&b, "1 0 obj\n<< /Type /ObjStm /N %d /First %d /Length %d >>\nstream\n%s%s\nendstream\nendobj\n",
+		n, len(header), len(header)+len(body), header, body,
+	)
+
+	xref := b.Len()
+	b.WriteString("2 0 obj\n<< /Type /XRef /Size 3 /Root 3 0 R /W [1 4 2] /Length 0 >>\nstream\n\nendstream\nendobj\n")
+	fmt.Fprintf(&b, "startxref\n%d\n%%%%EOF\n", xref)
+
+	return b.Bytes()
+}
+
+func TestPDFPageCount(t *testing.T) {
+	tests := []struct {
+		name  string
+		data  []byte
+		pages int
+		err   error
+	}{
+		{name: "table", data: pagedPDF("BT (one) Tj ET", "BT (two) Tj ET", "BT (three) Tj ET"), pages: 3},
+		{name: "object stream", data: streamedPDF(4), pages: 4},
+		{name: "not a PDF", data: []byte("hello"), err: ERR_PDF_UNREADABLE},
+		{name: "no trailer", data: pdf("BT (x) Tj ET", false), err: ERR_PDF_UNREADABLE},
+		{name: "negative object offset", data: objectStreamPDF(1, "5 -9  ", "<< >>"), err: ERR_PDF_UNREADABLE},
+		{name: "object offsets out of order", data: objectStreamPDF(2, "3 6 4 0 ", "<< >> << >>"), err: ERR_PDF_UNREADABLE},
+	}
+
+	for _, tt := range tests {
+		pages, err := PDFPageCount(tt.data)
+
+		if !errors.Is(err, tt.err) {
+			t.Errorf("%s: got %v, want %v", tt.name, err, tt.err)
+			continue
+		}
+
+		if pages != tt.pages {
+			t.Errorf("%s: got %d pages, want %d", tt.name, pages, tt.pages)
+		}
+	}
+}
+
+func TestAnnotatePDF(t *testing.T) {
+	annotations := []*models.Annotation{
+		{
+			Entity:  models.Entity{ID: "n1"},
+			Kind:    models.ANNOTATION_INK,
+			Page:    1,
+			Strokes: [][]models.Point{{{X: 100, Y: 100}, {X: 150, Y: 120}}},
+			Color:   "#E53935",
+			Author:  "prof",
+		},
+		{
+			Entity:  models.Entity{ID: "n2"},
+			Kind:    models.ANNOTATION_HIGHLIGHT,
+			Page:    2,
+			Rects:   []models.Rect{{X: 72, Y: 700, Width: 200, Height: 12}},
+			Comment: "Résumé (of) the point",
+			Color:   "#FFEB3B",
+			Author:  "prof",
+		},
+		{
+			Entity:  models.Entity{ID: "n3"},
+			Kind:    models.ANNOTATION_COMMENT,
+			Page:    2,
+			Point:   &models.Point{X: 300, Y: 400},
+			Comment: "Cite this",
+			Color:   "#FFC107",
+			Author:  "prof",
+		},
+		{Kind: models.ANNOTATION_COMMENT, Page: 9, Point: &models.Point{}, Comment: "Lost"},
+	}
+
+	for name, original := range map[string][]byte{
+		"table":         pagedPDF("BT (one) Tj ET", "BT (two) Tj ET"),
+		"object stream": streamedPDF(2),
+	} {
+		out, err := AnnotatePDF(original, annotations)
+		if err != nil {
+			t.Fatalf("%s: %+v", name, err)
+		}
+
+		if !bytes.HasPrefix(out, original) {
+			t.Errorf("%s: got the original changed, want it added to", name)
+		}
+
+		checkXref(t, name, out)
+
+		doc, err := readPDF(out)
+		if err != nil {
+			t.Fatalf("%s: %+v", name, err)
+		}
+
+		pages, err := doc.pages()
+		if err != nil {
+			t.Fatalf("%s: %+v", name, err)
+		}
+
+		if len(pages) != 2 {
+			t.Fatalf("%s: got %d pages, want 2", name, len(pages))
+		}
+
+		want := map[int][]string{1: {"Ink"}, 2: {"Highlight", "Text"}}
+		if name == "table" {
+			// The page's own annotation is kept.
+			want[1] = []string{"Text", "Ink"}
+		}
+
+		for n, subtypes := range want {
+			annots, _ := doc.resolve(pages[n-1].dict["Annots"], 0).([]any)
+
+			if len(annots) != len(subtypes) {
+				t.Fatalf("%s: got %d annotations on page %d, want %d", name, len(annots), n, len(subtypes))
+			}
+
+			for i, subtype := range subtypes {
+				annot, _ := doc.resolve(annots[i], 0).(pdfDict)
+
+				if annot["Subtype"] != pdfName(subtype) {
+					t.Errorf("%s: got %v on page %d, want %s", name, annot["Subtype"], n, subtype)
+				}
+
+				if annot["NM"] == nil {
+					continue
+				}
+
+				ap, _ := annot["AP"].(pdfDict)
+				form, _ := doc.resolve(ap["N"], 0).(pdfDict)
+
+				if form["Subtype"] != pdfName("Form") || form["BBox"] == nil {
+					t.Errorf("%s: got appearance %v, want a form", name, form)
+				}
+			}
+		}
+
+		if !bytes.Contains(out, []byte("<FEFF005200E9")) {
+			t.Errorf("%s: got no UTF-16 comment, want the accented comment written as UTF-16", name)
+		}
+	}
+
+	unchanged, err := AnnotatePDF(pagedPDF("BT (one) Tj ET"), nil)
+	if err != nil || !bytes.Equal(unchanged, pagedPDF("BT (one) Tj ET")) {
+		t.Errorf("got %v, want a PDF without annotations left as it was", err)
+	}
+}
+
+// checkXref checks that each entry of the last cross-reference table
+// points to the object it is for.
+func checkXref(t *testing.T, name string, data []byte) {
+	t.Helper()
+
+	i := bytes.LastIndex(data, []byte("\nxref\n"))
+	if i < 0 {
+		t.Fatalf("%s: got no cross-reference table", name)
+	}
+
+	section := data[i+len("\nxref\n") : bytes.LastIndex(data, []byte("trailer"))]
+	subsection := regexp.MustCompile(`(\d+) (\d+)\n((?:\d{10} \d{5} n\r\n)+)`)
+
+	for _, m := range subsection.FindAllSubmatch(section, -1) {
+		first, _ := strconv.Atoi(string(m[1]))
+
+		for j, entry := range bytes.Split(bytes.TrimSuffix(m[3], []byte("\r\n")), []byte("\r\n")) {
+			offset, _ := strconv.Atoi(string(entry[:10]))
+			gen, _ := strconv.Atoi(string(entry[11:16]))
+
+			want := fmt.Sprintf("%d %d obj", first+j, gen)
+
+			if !bytes.HasPrefix(data[offset:], []byte(want)) {
+				t.Errorf("%s: got %q at %d, want %q", name, data[offset:offset+len(want)], offset, want)
+			}
+		}
+	}
+}
+
+func TestAnnotationService(t *testing.T) {
+	dir := t.TempDir()
+
+	write := func(name string, data []byte) string {
+		p := filepath.Join(dir, name)
+		err := os.WriteFile(p, data, 0o644)
+		if err != nil {
+			t.Fatalf("%+v", err)
+		}
+		return p
+	}
+
+	store := newMockAnnotationStore()
+	store.files = []*models.Media{
+		{Entity: models.Entity{ID: "m1"}, FileName: "essay.pdf", FileType: models.PDF, FilePath: write("essay.pdf", pagedPDF("BT (one) Tj ET", "BT (two) Tj ET"))},
+		{Entity: models.Entity{ID: "m2"}, FileName: "notes.txt", FileType: models.TXT, FilePath: write("notes.txt", []byte("notes"))},
+	}
+
+	as := NewAnnotationService(store, &mockFileStore{})
+
+	comment := func(mediaId string, page int) *models.Annotation {
+		return &models.Annotation{
+			SubmissionId: "s1",
+			MediaId:      mediaId,
+			Kind:         models.ANNOTATION_COMMENT,
+			Page:         page,
+			Point:        &models.Point{X: 100, Y: 700},
+			Comment:      "Explain this step",
+			Color:        "#FFC107",
+		}
+	}
+
+	tests := []struct {
+		name       string
+		annotation *models.Annotation
+		netId      string
+		err        error
+	}{
+		{name: "student", annotation: comment("m1", 1), netId: "stu1", err: ERR_NOT_PERMITTED},
+		{name: "not a PDF", annotation: comment("m2", 1), netId: "prof", err: ERR_NOT_PDF},
+		{name: "another submission's file", annotation: comment("m9", 1), netId: "prof", err: ERR_UNKNOWN_MEDIA},
+		{name: "missing page", annotation: comment("m1", 3), netId: "prof", err: ERR_UNKNOWN_PAGE},
+		{name: "annotated", annotation: comment("m1", 2), netId: "prof"},
+	}
+
+	for _, tt := range tests {
+		_, err := as.CreateAnnotation(tt.annotation, tt.netId)
+		if !errors.Is(err, tt.err) {
+			t.Errorf("%s: got %v, want %v", tt.name, err, tt.err)
+		}
+	}
+
+	if len(store.annotations) != 1 || store.annotations[0].Author != "prof" {
+		t.Fatalf("got %v, want one annotation by prof", store.annotations)
+	}
+
+	// Students see annotations once grades are released, like the rest
+	// of their feedback.
+	annotations, err := as.Annotations("s1", "stu1")
+	if err != nil || len(annotations) != 0 {
+		t.Errorf("got %v and %v, want no annotations before grades are released", annotations, err)
+	}
+
+	_, data, err := as.AnnotatedPDF("s1", "m1", "stu1")
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+
+	if bytes.Contains(data, []byte("Explain this step")) {
+		t.Errorf("got the comment in the student's copy before grades are released")
+	}
+
+	store.assignment.GradesReleased = true
+
+	_, data, err = as.AnnotatedPDF("s1", "m1", "stu1")
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+
+	if !bytes.Contains(data, []byte("(Explain this step)")) {
+		t.Errorf("got no comment in the student's copy, want it once grades are released")
+	}
+
+	_, _, err = as.AnnotatedPDF("s1", "m1", "stu2")
+	if !errors.Is(err, ERR_NOT_PERMITTED) {
+		t.Errorf("got %v, want %v", err, ERR_NOT_PERMITTED)
+	}
+
+	id := store.annotations[0].ID
+
+	moved := comment("", 1)
+	moved.ID = id
+
+	_, err = as.UpdateAnnotation(moved, "prof")
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+
+	if moved.MediaId != "m1" || moved.Author != "prof" {
+		t.Errorf("got media %s by %s, want it kept on m1 by prof", moved.MediaId, moved.Author)
+	}
+
+	err = as.DeleteAnnotation(id, "stu1")
+	if !errors.Is(err, ERR_NOT_PERMITTED) {
+		t.Errorf("got %v, want %v", err, ERR_NOT_PERMITTED)
+	}
+
+	err = as.DeleteAnnotation(id, "prof")
+	if err != nil || len(store.annotations) != 0 {
+		t.Errorf("got %v with %d annotations left, want it removed", err, len(store.annotations))
+	}
+}
+
+// ========= //
+//   MOCKS   //
+// ========= //
+
+// mockAnnotationStore has submission "s1" by "stu1" to assignment "a1"
+// in course "c1", taught by "prof".
+type mockAnnotationStore struct {
+	AnnotationStore
+
+	assignment  *models.Assignment
+	files       []*models.Media
+	annotations []*models.Annotation
+}
+
+func newMockAnnotationStore() *mockAnnotationStore {
+	return &mockAnnotationStore{
+		assignment: &models.Assignment{Post: models.Post{Entity: models.Entity{ID: "a1"}}},
+	}
+}
+
+func (m *mockAnnotationStore) GetAssignmentById(assignmentid string) (
+	*models.Assignment,
+	error,
+) {
+	return m.assignment, nil
+}
+
+func (m *mockAnnotationStore) GetCourseIdByAssignment(assignmentId string) (
+	string,
+	error,
+) {
+	return "c1", nil
+}
+
+func (m *mockAnnotationStore) IsCourseTeacher(courseId, netId string) (
+	bool,
+	error,
+) {
+	return netId == "prof", nil
+}
+
+func (m *mockAnnotationStore) GetSubmissionById(submissionId string) (
+	*models.Submission,
+	error,
+) {
+	if submissionId != "s1" {
+		return nil, dal.ERR_RECORD_NOT_FOUND
+	}
+
+	sub := models.NewSubmission()
+	sub.ID = "s1"
+	sub.User.ID = "stu1"
+	return sub, nil
+}
+
+func (m *mockAnnotationStore) GetAssignmentIdBySubmission(submissionId string) (
+	string,
+	error,
+) {
+	return "a1", nil
+}
+
+func (m *mockAnnotationStore) GetSubmissionFiles(submissionId string) (
+	[]*models.Media,
+	error,
+) {
+	return m.files, nil
+}
+
+func (m *mockAnnotationStore) InsertAnnotation(a *models.Annotation) error {
+	a.ID = fmt.Sprintf("n%d", len(m.annotations)+1)
+	m.annotations = append(m.annotations, a)
+	return nil
+}
+
+func (m *mockAnnotationStore) GetAnnotationById(id string) (
+	*models.Annotation,
+	error,
+) {
+	for _, a := range m.annotations {
+		if a.ID == id {
+			copied := *a
+			return &copied, nil
+		}
+	}
+	return nil, dal.ERR_RECORD_NOT_FOUND
+}
+
+func (m *mockAnnotationStore) GetAnnotations(submissionId string) (
+	[]*models.Annotation,
+	error,
+) {
+	return m.annotations, nil
+}
+
+func (m *mockAnnotationStore) UpdateAnnotation(a *models.Annotation) error {
+	for i, old := range m.annotations {
+		if old.ID == a.ID {
+			m.annotations[i] = a
+		}
+	}
+	return nil
+}
+
+func (m *mockAnnotationStore) DeleteAnnotation(id string) error {
+	for i, a := range m.annotations {
+		if a.ID == id {
+			m.annotations = append(m.annotations[:i], m.annotations[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package domain
+
+import (
+	"github.com/n30w/Darkspace/internal/dal"
+	"github.com/n30w/Darkspace/internal/models"
+)
+
+// SetAnonymous turns anonymous grading on or off for an assignment.
+// While it is on, graders see submissions under pseudonyms until
+// grades are released. It cannot be turned off before then, since
+// that would reveal who made each submission. Only the course's
+// teachers may change it.
+func (as *AssignmentService) SetAnonymous(
+	assignmentid, netId string,
+	anonymous bool,
+) (*models.Assignment, error) {
+	assignment, err := teacherAssignment(as.store, assignmentid, netId)
+	if err != nil {
+		return nil, err
+	}
+
+	if !anonymous && assignment.Blind() {
+		return nil, ERR_ANONYMITY_LOCKED
+	}
+
+	assignment.Anonymous = anonymous
+
+	// The key is kept when anonymous grading is turned off, so that
+	// students keep their pseudonyms if it is turned back on.
+	if anonymous && assignment.AnonymousKey == "" {
+		assignment.AnonymousKey, err = models.NewAnonymousKey()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = as.store.SaveAnonymity(assignment)
+	if err != nil {
+		return nil, err
+	}
+
+	return assignment, nil
+}
+
+// GradingSubmission retrieves a student's submission for an
+// assignment as its graders see it, along with its regrade requests.
+// While the assignment is graded blind, the student must be given by
+// their pseudonym, and the submission is returned under it.
+func (ss *SubmissionService) GradingSubmission(
+	studentId, assignmentId string,
+) (*models.Submission, error) {
+	a, err := ss.store.GetAssignmentById(assignmentId)
+	if err != nil {
+		return nil, err
+	}
+
+	netId, err := ss.student(a, studentId)
+	if err != nil {
+		return nil, err
+	}
+
+	submission, err := ss.GetUserSubmission(netId, assignmentId)
+	if err != nil {
+		return nil, err
+	}
+
+	submission.Regrades, err = ss.regrades(a.ID, submission.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	anonymize(a, submission)
+
+	return submission, nil
+}
+
+// student finds the Net ID of a student that a grader asked for.
+// While an assignment is graded blind, graders must ask by pseudonym,
+// so that they cannot look up a student they know.
+func (ss *SubmissionService) student(a *models.Assignment, id string) (
+	string,
+	error,
+) {
+	if !a.Blind() {
+		return id, nil
+	}
+
+	if !models.IsPseudonym(id) {
+		return "", ERR_NOT_PERMITTED
+	}
+
+	attempts, err := ss.store.GetAttempts(a.ID, "")
+	if err != nil {
+		return "", err
+	}
+
+	for _, attempt := range attempts {
+		if models.Pseudonym(a.AnonymousKey, attempt.User.ID) == id {
+			return attempt.User.ID, nil
+		}
+	}
+
+	return "", dal.ERR_RECORD_NOT_FOUND
+}
+
+// anonymize hides the students who made submissions when an
+// assignment is graded blind.
+func anonymize(a *models.Assignment, submissions ...*models.Submission) {
+	if !a.Blind() {
+		return
+	}
+
+	for _, s := range submissions {
+		s.Anonymize(a.AnonymousKey)
+	}
+}
@@ -0,0 +1,193 @@
+package domain
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"unicode"
+
+	"github.com/n30w/Darkspace/internal/models"
+)
+
+type ArchiveStore interface {
+	GetAssignmentById(assignmentid string) (*models.Assignment, error)
+	GetSubmissions(assignmentId string) ([]*models.Submission, error)
+	GetAssignmentSubmissionMedia(assignmentId string) (
+		map[string][]*models.Media,
+		error,
+	)
+}
+
+// ArchiveEntry is a file to be written into an archive. Name is
+// the path of the file inside the archive, Path is where the file
+// is kept on the volume.
+type ArchiveEntry struct {
+	Name string
+	Path string
+}
+
+type ArchiveService struct {
+	store ArchiveStore
+	files FileStore
+}
+
+func NewArchiveService(s ArchiveStore, f FileStore) *ArchiveService {
+	return &ArchiveService{store: s, files: f}
+}
+
+// SubmissionEntries lists the media of every submission made for an
+// assignment, each under a directory named after the student who
+// submitted it, as in `<netid>_<full_name>/<file>`, or under their
+// pseudonym while the assignment is graded blind. Students who
+// submitted no media are left out, as is quarantined media.
+func (as *ArchiveService) SubmissionEntries(assignmentId string) (
+	[]ArchiveEntry,
+	error,
+) {
+	assignment, err := as.store.GetAssignmentById(assignmentId)
+	if err != nil {
+		return nil, err
+	}
+
+	submissions, err := as.store.GetSubmissions(assignmentId)
+	if err != nil {
+		return nil, err
+	}
+
+	media, err := as.store.GetAssignmentSubmissionMedia(assignmentId)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []ArchiveEntry
+
+	for _, submission := range submissions {
+		// Only the attempt that counts is archived.
+		if !submission.Counts {
+			continue
+		}
+
+		dir := sanitizeName(
+			fmt.Sprintf(
+				"%s_%s",
+				submission.User.ID,
+				submission.User.FullName,
+			),
+		)
+
+		if assignment.Blind() {
+			dir = models.Pseudonym(assignment.AnonymousKey, submission.User.ID)
+		}
+
+		// Two uploads can share a file name, so keep track of the
+		// names used in this directory.
+		used := make(map[string]bool)
+
+		for _, m := range media[submission.ID] {
+			if m.Quarantined() {
+				continue
+			}
+
+			name := uniqueName(sanitizeName(m.DisplayName()), used)
+			entries = append(
+				entries, ArchiveEntry{
+					Name: path.Join(dir, name),
+					Path: m.FilePath,
+				},
+			)
+		}
+	}
+
+	return entries, nil
+}
+
+// Write streams a ZIP archive of entries to w. Files are read from
+// the volume one at a time and compressed as they are copied, so
+// the archive is never held in memory as a whole.
+func (as *ArchiveService) Write(w io.Writer, entries []ArchiveEntry) error {
+	zw := zip.NewWriter(w)
+
+	for _, entry := range entries {
+		err := as.writeEntry(zw, entry)
+		if err != nil {
+			return fmt.Errorf("%s: %v", entry.Name, err)
+		}
+	}
+
+	return zw.Close()
+}
+
+func (as *ArchiveService) writeEntry(zw *zip.Writer, entry ArchiveEntry) error {
+	blob, err := as.files.StatFile(entry.Path)
+	if err != nil {
+		return err
+	}
+
+	f, err := as.files.OpenFile(entry.Path)
+	if err != nil {
+		return err
+	}
+
+	defer f.Close()
+
+	header := &zip.FileHeader{
+		Name:     entry.Name,
+		Method:   zip.Deflate,
+		Modified: blob.ModTime,
+	}
+
+	dst, err := zw.CreateHeader(header)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(dst, f)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// sanitizeName makes a name safe to use as a single path element
+// inside an archive. Whitespace becomes an underscore and anything
+// that could be read as a path separator is dropped.
+func sanitizeName(name string) string {
+	var b strings.Builder
+
+	for _, r := range name {
+		switch {
+		case unicode.IsSpace(r):
+			b.WriteRune('_')
+		case r == '/' || r == '\\' || r == ':' || unicode.IsControl(r):
+			continue
+		default:
+			b.WriteRune(r)
+		}
+	}
+
+	s := strings.Trim(b.String(), ".")
+	if s == "" {
+		return "_"
+	}
+
+	return s
+}
+
+// uniqueName returns name, or name with a counter before its
+// extension if name has been used already.
+func uniqueName(name string, used map[string]bool) string {
+	ext := path.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+
+	candidate := name
+	for i := 2; used[candidate]; i++ {
+		candidate = fmt.Sprintf("%s_%d%s", base, i, ext)
+	}
+
+	used[candidate] = true
+
+	return candidate
+}
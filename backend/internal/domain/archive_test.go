@@ -0,0 +1,204 @@
+package domain
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/n30w/Darkspace/internal/models"
+)
+
+func TestArchiveService_SubmissionEntries(t *testing.T) {
+	store := &mockArchiveStore{
+		submissions: []*models.Submission{
+			newArchiveSubmission("s1", "abc123", "Ada Lovelace"),
+			newArchiveSubmission("s2", "xyz789", "../Eve"),
+			newArchiveSubmission("s3", "def456", "No Files"),
+		},
+		media: map[string][]*models.Media{
+			"s1": {
+				{FilePath: "/v/essay.pdf"},
+				{FilePath: "/v/other/essay.pdf"},
+				{FilePath: "/v/main.go"},
+				{
+					FilePath:   "/v/virus.exe",
+					ScanResult: models.ScanResult{Status: models.QUARANTINED},
+				},
+			},
+			"s2": {
+				{FilePath: "/v/notes.txt"},
+			},
+		},
+	}
+
+	entries, err := NewArchiveService(store, &mockFileStore{}).
+		SubmissionEntries("a1")
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+
+	want := []ArchiveEntry{
+		{Name: "abc123_Ada_Lovelace/essay.pdf", Path: "/v/essay.pdf"},
+		{Name: "abc123_Ada_Lovelace/essay_2.pdf", Path: "/v/other/essay.pdf"},
+		{Name: "abc123_Ada_Lovelace/main.go", Path: "/v/main.go"},
+		{Name: "xyz789_..Eve/notes.txt", Path: "/v/notes.txt"},
+	}
+
+	if len(entries) != len(want) {
+		t.Fatalf("got %v, want %v", entries, want)
+	}
+
+	for i := range want {
+		if entries[i] != want[i] {
+			t.Errorf("got %v, want %v", entries[i], want[i])
+		}
+	}
+}
+
+func TestArchiveService_BlindEntries(t *testing.T) {
+	store := &mockArchiveStore{
+		assignment: &models.Assignment{Anonymous: true, AnonymousKey: "key"},
+		submissions: []*models.Submission{
+			newArchiveSubmission("s1", "abc123", "Ada Lovelace"),
+		},
+		media: map[string][]*models.Media{
+			"s1": {{FilePath: "/v/s1_essay.pdf", FileName: "essay.pdf"}},
+		},
+	}
+
+	as := NewArchiveService(store, &mockFileStore{})
+
+	entries, err := as.SubmissionEntries("a1")
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+
+	want := models.Pseudonym("key", "abc123") + "/essay.pdf"
+
+	if len(entries) != 1 || entries[0].Name != want {
+		t.Fatalf("got %v, want %s", entries, want)
+	}
+
+	store.assignment.GradesReleased = true
+
+	entries, err = as.SubmissionEntries("a1")
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+
+	if entries[0].Name != "abc123_Ada_Lovelace/essay.pdf" {
+		t.Errorf("got %s, want the student's name once grades are released", entries[0].Name)
+	}
+}
+
+func TestArchiveService_Write(t *testing.T) {
+	dir := t.TempDir()
+
+	files := map[string]string{
+		"abc123_Ada_Lovelace/essay.pdf": "%PDF-1.7 essay",
+		"xyz789_Eve/notes.txt":          "some notes",
+	}
+
+	store := &mockFileStore{blobs: make(map[string]models.Blob)}
+
+	var entries []ArchiveEntry
+	for name, content := range files {
+		p := filepath.Join(dir, filepath.Base(name))
+
+		err := os.WriteFile(p, []byte(content), 0o644)
+		if err != nil {
+			t.Fatalf("%+v", err)
+		}
+
+		store.blobs[p] = models.Blob{Path: p, Size: int64(len(content))}
+		entries = append(entries, ArchiveEntry{Name: name, Path: p})
+	}
+
+	var buf bytes.Buffer
+
+	err := NewArchiveService(&mockArchiveStore{}, store).
+		Write(&buf, entries)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+
+	if len(zr.File) != len(files) {
+		t.Fatalf("got %d files, want %d", len(zr.File), len(files))
+	}
+
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatalf("%+v", err)
+		}
+
+		got, _ := io.ReadAll(rc)
+		rc.Close()
+
+		if string(got) != files[f.Name] {
+			t.Errorf("%s: got %q, want %q", f.Name, got, files[f.Name])
+		}
+	}
+}
+
+func TestArchiveService_WriteMissingFile(t *testing.T) {
+	entries := []ArchiveEntry{
+		{Name: "abc123_Ada/essay.pdf", Path: filepath.Join(t.TempDir(), "gone")},
+	}
+
+	err := NewArchiveService(&mockArchiveStore{}, &mockFileStore{}).
+		Write(io.Discard, entries)
+	if err == nil {
+		t.Errorf("expected an error for a missing file")
+	}
+}
+
+// ========= //
+//   MOCKS   //
+// ========= //
+
+func newArchiveSubmission(id, netId, fullName string) *models.Submission {
+	s := models.NewSubmission()
+	s.ID = id
+	s.User.ID = netId
+	s.User.FullName = fullName
+	return s
+}
+
+type mockArchiveStore struct {
+	assignment  *models.Assignment
+	submissions []*models.Submission
+	media       map[string][]*models.Media
+}
+
+func (m *mockArchiveStore) GetAssignmentById(assignmentid string) (
+	*models.Assignment,
+	error,
+) {
+	if m.assignment == nil {
+		return &models.Assignment{}, nil
+	}
+	return m.assignment, nil
+}
+
+func (m *mockArchiveStore) GetSubmissions(assignmentId string) (
+	[]*models.Submission,
+	error,
+) {
+	return m.submissions, nil
+}
+
+func (m *mockArchiveStore) GetAssignmentSubmissionMedia(assignmentId string) (
+	map[string][]*models.Media,
+	error,
+) {
+	return m.media, nil
+}
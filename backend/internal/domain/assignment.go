@@ -2,6 +2,7 @@ package domain
 
 import (
 	"fmt"
+	"time"
 
 	// "github.com/google/uuid"
 	"github.com/n30w/Darkspace/internal/models"
@@ -17,18 +18,44 @@ type AssignmentStore interface {
 	InsertAssignmentIntoUser(a *models.Assignment) (*models.Assignment, error)
 	InsertAssignment(assignment *models.Assignment) (*models.Assignment, error)
 	DeleteAssignmentByID(assignmentid string) error
+	ChangeAssignmentFileTypes(assignment *models.Assignment) (
+		*models.Assignment,
+		error,
+	)
 	ChangeAssignment(
 		assignment *models.Assignment,
 		updatedfield string,
 		action string,
 	) (*models.Assignment, error)
+	SaveLatePolicy(p *models.LatePolicy) error
+	DeleteLatePolicy(assignmentId string) error
+	SaveAttemptPolicy(assignmentId string, maxAttempts int, selection models.AttemptSelection) error
+	SaveAnonymity(a *models.Assignment) error
+	SaveGradeRelease(a *models.Assignment) error
+	SaveRegradeWindow(assignmentId string, window int) error
+	GetAssignmentsDueForRelease(now time.Time) ([]string, error)
+	SaveSchedule(a *models.Assignment) error
+	GetAssignmentsDueForSchedule(now time.Time) ([]string, error)
+	GetExtensions(assignmentId string) ([]*models.Extension, error)
+	GetAccommodations(courseId string) ([]*models.Accommodation, error)
+	GetRoster(courseid string) ([]models.User, error)
+	AttemptStore
+	NotificationStore
+	GetCourseIdByAssignment(assignmentId string) (string, error)
+	IsCourseTeacher(courseId, netId string) (bool, error)
 }
 
 type AssignmentService struct {
 	store AssignmentStore
+
+	// now is the clock that grades are released, and assignments
+	// published and locked, by.
+	now func() time.Time
 }
 
-func NewAssignmentService(a AssignmentStore) *AssignmentService { return &AssignmentService{store: a} }
+func NewAssignmentService(a AssignmentStore) *AssignmentService {
+	return &AssignmentService{store: a, now: time.Now}
+}
 
 // ReadAssignment uses an Assignment's ID to retrieve it from
 // the database. Options can also be passed in that specify
@@ -71,10 +98,19 @@ func (as *AssignmentService) RetrieveAssignments(courseid string) (
 	return assignmentIds, nil
 }
 
+// CreateAssignment makes an assignment in a course. It is published
+// unless it is made as a draft, and a schedule that has already passed
+// takes effect at once.
 func (as *AssignmentService) CreateAssignment(assignment *models.Assignment) (
 	*models.Assignment,
 	error,
 ) {
+	if assignment.State == "" {
+		assignment.State = models.ASSIGNMENT_PUBLISHED
+	}
+
+	advance(assignment, as.now(), nil)
+
 	assignment, err := as.store.InsertAssignment(assignment)
 	if err != nil {
 		return nil, err
@@ -125,6 +161,120 @@ func (as *AssignmentService) UpdateAssignment(
 	}
 }
 
+// SetFileTypes changes the types of files students may submit for an
+// assignment. An empty list accepts any known file type. Only the
+// course's teachers may change them.
+func (as *AssignmentService) SetFileTypes(
+	assignmentid, netId string,
+	fileTypes models.FileTypes,
+) (*models.Assignment, error) {
+	assignment, err := teacherAssignment(as.store, assignmentid, netId)
+	if err != nil {
+		return nil, err
+	}
+
+	assignment.FileTypes = fileTypes
+
+	assignment, err = as.store.ChangeAssignmentFileTypes(assignment)
+	if err != nil {
+		return nil, err
+	}
+
+	return assignment, nil
+}
+
+// SetLatePolicy changes how an assignment treats late submissions.
+// A nil policy removes the assignment's policy, so that late
+// submissions are accepted without a penalty. Only the course's
+// teachers may change it. Grades that were already given keep the
+// penalty they were given with.
+func (as *AssignmentService) SetLatePolicy(
+	assignmentid, netId string,
+	policy *models.LatePolicy,
+) (*models.Assignment, error) {
+	assignment, err := as.store.GetAssignmentById(assignmentid)
+	if err != nil {
+		return nil, err
+	}
+
+	courseId, err := as.store.GetCourseIdByAssignment(assignment.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = teacherOnly(as.store, courseId, netId)
+	if err != nil {
+		return nil, err
+	}
+
+	if policy == nil {
+		err = as.store.DeleteLatePolicy(assignment.ID)
+	} else {
+		policy.AssignmentId = assignment.ID
+		err = as.store.SaveLatePolicy(policy)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	assignment.LatePolicy = policy
+
+	return assignment, nil
+}
+
+// SetAttemptPolicy changes how many attempts an assignment allows and
+// which attempt counts towards each student's grade. A max of zero
+// allows any number of attempts. Lowering the max does not remove
+// attempts already made. Only the course's teachers may change it.
+func (as *AssignmentService) SetAttemptPolicy(
+	assignmentid, netId string,
+	maxAttempts int,
+	selection models.AttemptSelection,
+) (*models.Assignment, error) {
+	assignment, err := as.store.GetAssignmentById(assignmentid)
+	if err != nil {
+		return nil, err
+	}
+
+	courseId, err := as.store.GetCourseIdByAssignment(assignment.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = teacherOnly(as.store, courseId, netId)
+	if err != nil {
+		return nil, err
+	}
+
+	err = as.store.SaveAttemptPolicy(assignment.ID, maxAttempts, selection)
+	if err != nil {
+		return nil, err
+	}
+
+	assignment.MaxAttempts = maxAttempts
+	assignment.CountedAttempt = selection
+
+	err = recountAssignment(as.store, assignment)
+	if err != nil {
+		return nil, err
+	}
+
+	return assignment, nil
+}
+
+// FilePolicy returns the upload policy for an assignment's submissions.
+func (as *AssignmentService) FilePolicy(assignmentid string) (
+	*FilePolicy,
+	error,
+) {
+	assignment, err := as.store.GetAssignmentById(assignmentid)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewFilePolicy(assignment.FileTypes...), nil
+}
+
 func (as *AssignmentService) DeleteAssignment(assignmentid string) error {
 	err := as.store.DeleteAssignmentByID(assignmentid)
 	if err != nil {
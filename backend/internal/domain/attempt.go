@@ -0,0 +1,209 @@
+package domain
+
+import (
+	"github.com/n30w/Darkspace/internal/models"
+)
+
+// AttemptStore keeps track of which of a student's attempts at an
+// assignment counts. Services that grade submissions embed it in
+// their stores, because a new grade can change the best attempt.
+type AttemptStore interface {
+	GetAttempts(assignmentId, netId string) ([]*models.Submission, error)
+	SetCountedAttempt(assignmentId, netId, submissionId string) error
+}
+
+// ListAttempts retrieves every attempt a student made at an
+// assignment, oldest first. Students may only see their own attempts,
+// without grades until they are released, while the course's teachers
+// may see anyone's, by pseudonym while the assignment is graded blind.
+func (ss *SubmissionService) ListAttempts(
+	assignmentId, studentId, netId string,
+) ([]*models.Submission, error) {
+	assignment, err := ss.store.GetAssignmentById(assignmentId)
+	if err != nil {
+		return nil, err
+	}
+
+	own := netId != "" && netId == studentId
+
+	if !own {
+		studentId, err = ss.grader(assignment, studentId, netId)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	attempts, err := ss.store.GetAttempts(assignmentId, studentId)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, a := range attempts {
+		a.Files, err = ss.store.GetSubmissionFiles(a.ID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if own {
+		hideGrades(assignment, attempts...)
+	} else {
+		anonymize(assignment, attempts...)
+	}
+
+	return attempts, nil
+}
+
+// DiffAttempts compares two of a student's attempts at an assignment
+// by their attempt numbers.
+func (ss *SubmissionService) DiffAttempts(
+	assignmentId, studentId, netId string,
+	from, to int,
+) (*models.AttemptDiff, error) {
+	attempts, err := ss.ListAttempts(assignmentId, studentId, netId)
+	if err != nil {
+		return nil, err
+	}
+
+	before := findAttempt(attempts, from)
+	after := findAttempt(attempts, to)
+
+	if before == nil || after == nil {
+		return nil, ERR_UNKNOWN_ATTEMPT
+	}
+
+	return models.DiffAttempts(before, after), nil
+}
+
+// SelectAttempt pins the attempt of a student's that counts towards
+// their grade, whatever the assignment's selection. Attempt 0 removes
+// the pin, so that the assignment's selection decides again. Only the
+// course's teachers may choose. The attempt that now counts is
+// returned.
+func (ss *SubmissionService) SelectAttempt(
+	assignmentId, studentId, netId string,
+	attempt int,
+) (*models.Submission, error) {
+	assignment, err := ss.store.GetAssignmentById(assignmentId)
+	if err != nil {
+		return nil, err
+	}
+
+	studentId, err = ss.grader(assignment, studentId, netId)
+	if err != nil {
+		return nil, err
+	}
+
+	attempts, err := ss.store.GetAttempts(assignmentId, studentId)
+	if err != nil {
+		return nil, err
+	}
+
+	var pinned string
+
+	if attempt != 0 {
+		a := findAttempt(attempts, attempt)
+		if a == nil {
+			return nil, ERR_UNKNOWN_ATTEMPT
+		}
+		pinned = a.ID
+	}
+
+	err = ss.store.SetPinnedAttempt(assignmentId, studentId, pinned)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, a := range attempts {
+		a.Pinned = a.ID == pinned
+	}
+
+	counted := models.CountedAttempt(attempts, assignment.CountedAttempt)
+	if counted == nil {
+		return nil, ERR_UNKNOWN_ATTEMPT
+	}
+
+	err = ss.store.SetCountedAttempt(assignmentId, studentId, counted.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	counted.Counts = true
+
+	anonymize(assignment, counted)
+
+	return counted, nil
+}
+
+// grader checks that netId teaches the assignment's course, and finds
+// the Net ID of the student they asked for.
+func (ss *SubmissionService) grader(
+	a *models.Assignment,
+	studentId, netId string,
+) (string, error) {
+	courseId, err := ss.store.GetCourseIdByAssignment(a.ID)
+	if err != nil {
+		return "", err
+	}
+
+	err = teacherOnly(ss.store, courseId, netId)
+	if err != nil {
+		return "", err
+	}
+
+	return ss.student(a, studentId)
+}
+
+// recountAttempts marks which of a student's attempts at an
+// assignment counts, after an attempt is made or graded.
+func recountAttempts(
+	s AttemptStore,
+	a *models.Assignment,
+	netId string,
+) error {
+	attempts, err := s.GetAttempts(a.ID, netId)
+	if err != nil {
+		return err
+	}
+
+	counted := models.CountedAttempt(attempts, a.CountedAttempt)
+	if counted == nil {
+		return nil
+	}
+
+	return s.SetCountedAttempt(a.ID, netId, counted.ID)
+}
+
+// recountAssignment marks which attempt counts for every student who
+// made an attempt at an assignment, after its selection changes.
+func recountAssignment(s AttemptStore, a *models.Assignment) error {
+	attempts, err := s.GetAttempts(a.ID, "")
+	if err != nil {
+		return err
+	}
+
+	byStudent := make(map[string][]*models.Submission)
+	for _, attempt := range attempts {
+		byStudent[attempt.User.ID] = append(byStudent[attempt.User.ID], attempt)
+	}
+
+	for netId, mine := range byStudent {
+		counted := models.CountedAttempt(mine, a.CountedAttempt)
+
+		err = s.SetCountedAttempt(a.ID, netId, counted.ID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func findAttempt(attempts []*models.Submission, attempt int) *models.Submission {
+	for _, a := range attempts {
+		if a.Attempt == attempt {
+			return a
+		}
+	}
+	return nil
+}
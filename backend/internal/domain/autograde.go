@@ -0,0 +1,535 @@
+package domain
+
+import (
+	"archive/zip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/n30w/Darkspace/internal/dal"
+	"github.com/n30w/Darkspace/internal/models"
+)
+
+// maxWorkspaceSize is the most that the files of a harness and a
+// submission, once unzipped, may take up in a workspace.
+const maxWorkspaceSize = 256 << 20
+
+// submissionDir is the directory of a workspace that holds the files
+// of the submission being graded.
+const submissionDir = "submission"
+
+// Runner runs a harness's command over a workspace, limiting the time,
+// memory and other resources it may use. A run whose command fails or
+// times out still has a result; an error means the command could not
+// be run at all.
+type Runner interface {
+	Run(ctx context.Context, spec *models.RunSpec) (*models.RunResult, error)
+}
+
+type AutogradeStore interface {
+	DueDateStore
+	AttemptStore
+
+	GetAssignmentById(assignmentid string) (*models.Assignment, error)
+	IsCourseTeacher(courseId, netId string) (bool, error)
+
+	GetSubmissionById(submissionId string) (*models.Submission, error)
+	GetAssignmentIdBySubmission(submissionId string) (string, error)
+	GetSubmissionFiles(submissionId string) ([]*models.Media, error)
+	UpdateSubmissionGrade(sub *models.Submission) error
+
+	InsertMedia(m *models.Media) (*models.Media, error)
+	SaveHarness(h *models.Harness) error
+	GetHarness(assignmentId string) (*models.Harness, error)
+
+	InsertGradeJob(j *models.GradeJob) error
+	GetGradeJobById(id string) (*models.GradeJob, error)
+	GetGradeJobs(submissionId string) ([]*models.GradeJob, error)
+	GetQueuedGradeJobs() ([]string, error)
+	UpdateGradeJob(j *models.GradeJob) error
+}
+
+type AutogradeService struct {
+	store  AutogradeStore
+	files  FileStore
+	runner Runner
+
+	// now is the clock that jobs are timed by.
+	now func() time.Time
+}
+
+func NewAutogradeService(s AutogradeStore, f FileStore) *AutogradeService {
+	return &AutogradeService{store: s, files: f, now: time.Now}
+}
+
+// SetRunner sets what submissions are run with. Until a runner is
+// set, jobs are queued but never run.
+func (as *AutogradeService) SetRunner(r Runner) {
+	as.runner = r
+}
+
+// SetHarness sets what an assignment's submissions are tested with.
+// The harness's files are kept as media. A harness set without files
+// keeps the files it had. Only the course's teachers may set it.
+func (as *AutogradeService) SetHarness(
+	h *models.Harness,
+	netId string,
+) (*models.Harness, error) {
+	a, err := teacherAssignment(as.store, h.AssignmentId, netId)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(h.Files) == 0 {
+		old, err := as.store.GetHarness(a.ID)
+		if err != nil && !errors.Is(err, dal.ERR_RECORD_NOT_FOUND) {
+			return nil, err
+		}
+
+		if old != nil {
+			h.Files = old.Files
+		}
+	} else {
+		for i, m := range h.Files {
+			h.Files[i], err = as.store.InsertMedia(m)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	err = as.store.SaveHarness(h)
+	if err != nil {
+		return nil, err
+	}
+
+	return h, nil
+}
+
+// Harness retrieves what an assignment's submissions are tested with.
+// Only the course's teachers may see it, since it may hold tests that
+// students are not meant to see.
+func (as *AutogradeService) Harness(assignmentId, netId string) (
+	*models.Harness,
+	error,
+) {
+	a, err := teacherAssignment(as.store, assignmentId, netId)
+	if err != nil {
+		return nil, err
+	}
+
+	h, err := as.store.GetHarness(a.ID)
+	if errors.Is(err, dal.ERR_RECORD_NOT_FOUND) {
+		return nil, ERR_NO_HARNESS
+	}
+
+	return h, err
+}
+
+// Enqueue queues a submission to be graded, when its assignment has a
+// harness. A submission that is already queued is not queued again,
+// so that uploading several files grades it once. It returns nil when
+// there is nothing to grade the submission with.
+func (as *AutogradeService) Enqueue(submissionId string) (
+	*models.GradeJob,
+	error,
+) {
+	assignmentId, err := as.store.GetAssignmentIdBySubmission(submissionId)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = as.store.GetHarness(assignmentId)
+	if errors.Is(err, dal.ERR_RECORD_NOT_FOUND) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return as.enqueue(assignmentId, submissionId)
+}
+
+// Rerun queues a submission to be graded again, such as after its
+// assignment's harness was fixed. Only the course's teachers may rerun
+// submissions.
+func (as *AutogradeService) Rerun(submissionId, netId string) (
+	*models.GradeJob,
+	error,
+) {
+	assignmentId, err := as.store.GetAssignmentIdBySubmission(submissionId)
+	if err != nil {
+		return nil, err
+	}
+
+	a, err := teacherAssignment(as.store, assignmentId, netId)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = as.store.GetHarness(a.ID)
+	if errors.Is(err, dal.ERR_RECORD_NOT_FOUND) {
+		return nil, ERR_NO_HARNESS
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return as.enqueue(a.ID, submissionId)
+}
+
+func (as *AutogradeService) enqueue(assignmentId, submissionId string) (
+	*models.GradeJob,
+	error,
+) {
+	jobs, err := as.store.GetGradeJobs(submissionId)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, j := range jobs {
+		if j.Status == models.GRADE_JOB_QUEUED {
+			return j, nil
+		}
+	}
+
+	j := &models.GradeJob{
+		SubmissionId: submissionId,
+		AssignmentId: assignmentId,
+		Status:       models.GRADE_JOB_QUEUED,
+	}
+
+	err = as.store.InsertGradeJob(j)
+	if err != nil {
+		return nil, err
+	}
+
+	return j, nil
+}
+
+// RunQueued runs every queued job, oldest first, and grades the
+// submissions whose harness reported their tests. A job that cannot
+// be run is marked as failed, along with why, rather than stopping
+// the others. It returns how many jobs were run.
+func (as *AutogradeService) RunQueued(ctx context.Context) (int, error) {
+	if as.runner == nil {
+		return 0, nil
+	}
+
+	ids, err := as.store.GetQueuedGradeJobs()
+	if err != nil {
+		return 0, err
+	}
+
+	ran := 0
+
+	for _, id := range ids {
+		j, err := as.store.GetGradeJobById(id)
+		if err != nil {
+			return ran, err
+		}
+
+		err = as.run(ctx, j)
+		if err != nil {
+			return ran, err
+		}
+
+		ran++
+	}
+
+	return ran, nil
+}
+
+// run runs a job, saving its results and grading its submission.
+func (as *AutogradeService) run(ctx context.Context, j *models.GradeJob) error {
+	started := as.now().UTC()
+
+	j.Status = models.GRADE_JOB_RUNNING
+	j.StartedAt = &started
+
+	err := as.store.UpdateGradeJob(j)
+	if err != nil {
+		return err
+	}
+
+	res, err := as.execute(ctx, j)
+
+	finished := as.now().UTC()
+
+	switch {
+	case err != nil:
+		j.Fail(err.Error(), finished)
+	case res.TimedOut:
+		j.Log = res.Output
+		j.Fail("the run took too long and was stopped", finished)
+	default:
+		j.Finish(models.ParseTAP(res.TAP), res.Output, finished)
+	}
+
+	err = as.store.UpdateGradeJob(j)
+	if err != nil {
+		return err
+	}
+
+	if j.Status != models.GRADE_JOB_DONE {
+		return nil
+	}
+
+	return as.grade(j)
+}
+
+// execute runs a job's harness in a workspace of its own, which is
+// removed once the run is over.
+func (as *AutogradeService) execute(
+	ctx context.Context,
+	j *models.GradeJob,
+) (*models.RunResult, error) {
+	h, err := as.store.GetHarness(j.AssignmentId)
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := as.store.GetSubmissionFiles(j.SubmissionId)
+	if err != nil {
+		return nil, err
+	}
+
+	dir, err := os.MkdirTemp("", "darkspace-grade-")
+	if err != nil {
+		return nil, err
+	}
+
+	defer os.RemoveAll(dir)
+
+	w := &workspace{dir: dir, files: as.files}
+
+	err = w.add("", h.Files)
+	if err != nil {
+		return nil, err
+	}
+
+	err = w.add(submissionDir, files)
+	if err != nil {
+		return nil, err
+	}
+
+	return as.runner.Run(
+		ctx, &models.RunSpec{
+			Dir:     dir,
+			Command: h.Command,
+			Image:   h.Image,
+			Timeout: time.Duration(h.Timeout) * time.Second,
+			Memory:  int64(h.Memory) << 20,
+		},
+	)
+}
+
+// grade writes a job's score into its submission as the grade, taking
+// off the assignment's late penalty like any other grade.
+func (as *AutogradeService) grade(j *models.GradeJob) error {
+	sub, err := as.store.GetSubmissionById(j.SubmissionId)
+	if err != nil {
+		return err
+	}
+
+	a, err := as.store.GetAssignmentById(j.AssignmentId)
+	if err != nil {
+		return err
+	}
+
+	due, err := studentAssignment(as.store, a, sub.User.ID)
+	if err != nil {
+		return err
+	}
+
+	sub.SetGrade(*j.Score, due)
+
+	err = as.store.UpdateSubmissionGrade(sub)
+	if err != nil {
+		return err
+	}
+
+	return recountAttempts(as.store, a, sub.User.ID)
+}
+
+// Results retrieves the grading jobs of a submission, newest first,
+// with how it did on each test. The course's teachers may see any
+// submission's results, and students their own, without their score
+// until grades are released.
+func (as *AutogradeService) Results(submissionId, netId string) (
+	[]*models.GradeJob,
+	error,
+) {
+	if netId == "" {
+		return nil, ERR_NOT_PERMITTED
+	}
+
+	sub, err := as.store.GetSubmissionById(submissionId)
+	if err != nil {
+		return nil, err
+	}
+
+	assignmentId, err := as.store.GetAssignmentIdBySubmission(sub.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	a, err := as.store.GetAssignmentById(assignmentId)
+	if err != nil {
+		return nil, err
+	}
+
+	courseId, err := as.store.GetCourseIdByAssignment(a.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	teacher, err := isTeacher(as.store, courseId, netId)
+	if err != nil {
+		return nil, err
+	}
+
+	if !teacher && sub.User.ID != netId {
+		return nil, ERR_NOT_PERMITTED
+	}
+
+	jobs, err := as.store.GetGradeJobs(sub.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !teacher && !a.GradesReleased {
+		for _, j := range jobs {
+			j.HideScore()
+		}
+	}
+
+	return jobs, nil
+}
+
+// workspace is a directory that the files of a harness and a
+// submission are copied into, to be run over.
+type workspace struct {
+	dir   string
+	files FileStore
+
+	// size is how much has been written so far.
+	size int64
+}
+
+// add copies files into a directory of the workspace. ZIP archives are
+// unzipped in place of the archive, and quarantined media is left out.
+func (w *workspace) add(dir string, files []*models.Media) error {
+	for _, m := range files {
+		if m.Quarantined() {
+			continue
+		}
+
+		var err error
+
+		if m.FileType == models.ZIP {
+			err = w.unzip(dir, m)
+		} else {
+			err = w.copy(filepath.Join(dir, sanitizeName(m.DisplayName())), m)
+		}
+
+		if err != nil {
+			return fmt.Errorf("%s: %v", m.DisplayName(), err)
+		}
+	}
+
+	return nil
+}
+
+func (w *workspace) copy(name string, m *models.Media) error {
+	f, err := w.files.OpenFile(m.FilePath)
+	if err != nil {
+		return err
+	}
+
+	defer f.Close()
+
+	return w.write(name, f, 0o644)
+}
+
+// unzip extracts a ZIP archive into a directory of the workspace.
+// Entries whose path would leave the directory are refused.
+func (w *workspace) unzip(dir string, m *models.Media) error {
+	f, err := w.files.OpenFile(m.FilePath)
+	if err != nil {
+		return err
+	}
+
+	defer f.Close()
+
+	size, err := f.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+
+	ra, ok := f.(io.ReaderAt)
+	if !ok {
+		return fmt.Errorf("archive cannot be read")
+	}
+
+	zr, err := zip.NewReader(ra, size)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range zr.File {
+		if entry.FileInfo().IsDir() {
+			continue
+		}
+
+		if !filepath.IsLocal(entry.Name) {
+			return fmt.Errorf("%s: path leaves the workspace", entry.Name)
+		}
+
+		rc, err := entry.Open()
+		if err != nil {
+			return err
+		}
+
+		// Executable bits are kept, so that scripts can be run.
+		err = w.write(filepath.Join(dir, entry.Name), rc, entry.Mode().Perm()|0o600)
+		rc.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (w *workspace) write(name string, r io.Reader, perm os.FileMode) error {
+	p := filepath.Join(w.dir, name)
+
+	err := os.MkdirAll(filepath.Dir(p), 0o755)
+	if err != nil {
+		return err
+	}
+
+	out, err := os.OpenFile(p, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+
+	defer out.Close()
+
+	n, err := io.Copy(out, io.LimitReader(r, maxWorkspaceSize-w.size+1))
+	w.size += n
+	if err != nil {
+		return err
+	}
+
+	if w.size > maxWorkspaceSize {
+		return fmt.Errorf("files are too large to be graded")
+	}
+
+	return nil
+}
@@ -0,0 +1,415 @@
+package domain
+
+import (
+	"archive/zip"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/n30w/Darkspace/internal/dal"
+	"github.com/n30w/Darkspace/internal/models"
+)
+
+func TestAutogradeService_RunQueued(t *testing.T) {
+	dir := t.TempDir()
+
+	write := func(name string, data []byte) string {
+		p := filepath.Join(dir, name)
+		err := os.WriteFile(p, data, 0o644)
+		if err != nil {
+			t.Fatalf("%+v", err)
+		}
+		return p
+	}
+
+	archive := func(name string, files map[string]string) string {
+		f, err := os.Create(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatalf("%+v", err)
+		}
+		defer f.Close()
+
+		zw := zip.NewWriter(f)
+		for n, content := range files {
+			w, _ := zw.Create(n)
+			w.Write([]byte(content))
+		}
+		zw.Close()
+
+		return f.Name()
+	}
+
+	store := newMockAutogradeStore()
+	store.files["s1"] = []*models.Media{
+		{FileName: "main.py", FileType: models.TXT, FilePath: write("main.py", []byte("print(1)"))},
+		{FileName: "lib.zip", FileType: models.ZIP, FilePath: archive("lib.zip", map[string]string{"lib/util.py": "pass"})},
+	}
+
+	runner := &mockRunner{tap: "1..3\nok 1 - runs\nnot ok 2 - prints\nok 3 - imports\n"}
+
+	as := NewAutogradeService(store, &mockFileStore{})
+
+	j, err := as.Enqueue("s1")
+	if err != nil || j != nil {
+		t.Fatalf("got %v and %v, want nothing queued without a harness", j, err)
+	}
+
+	_, err = as.SetHarness(&models.Harness{AssignmentId: "a1", Command: "./test.sh"}, "stu1")
+	if !errors.Is(err, ERR_NOT_PERMITTED) {
+		t.Fatalf("got %v, want %v", err, ERR_NOT_PERMITTED)
+	}
+
+	_, err = as.SetHarness(
+		&models.Harness{
+			AssignmentId: "a1",
+			Command:      "./test.sh",
+			Timeout:      5,
+			Memory:       64,
+			Files: []*models.Media{
+				{FileName: "test.sh", FileType: models.TXT, FilePath: write("test.sh", []byte("#!/bin/sh"))},
+			},
+		}, "prof",
+	)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+
+	j, err = as.Enqueue("s1")
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+
+	again, err := as.Enqueue("s1")
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+
+	if again.ID != j.ID {
+		t.Errorf("got job %s, want queued job %s reused", again.ID, j.ID)
+	}
+
+	ran, err := as.RunQueued(context.Background())
+	if err != nil || ran != 0 {
+		t.Fatalf("got %d jobs run and %v, want none run without a runner", ran, err)
+	}
+
+	as.SetRunner(runner)
+
+	ran, err = as.RunQueued(context.Background())
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+
+	if ran != 1 {
+		t.Errorf("got %d jobs run, want 1", ran)
+	}
+
+	for _, name := range []string{"test.sh", "submission/main.py", "submission/lib/util.py"} {
+		if !runner.files[name] {
+			t.Errorf("got workspace %v, want %s", runner.files, name)
+		}
+	}
+
+	if runner.spec.Memory != 64<<20 || runner.spec.Timeout.Seconds() != 5 {
+		t.Errorf("got %d bytes and %v, want the harness's limits", runner.spec.Memory, runner.spec.Timeout)
+	}
+
+	if _, err := os.Stat(runner.spec.Dir); !os.IsNotExist(err) {
+		t.Errorf("got %v, want the workspace removed", err)
+	}
+
+	if j.Status != models.GRADE_JOB_DONE || j.Passed != 2 || j.Total != 3 {
+		t.Fatalf("got %s with %d of %d passed, want 2 of 3 done", j.Status, j.Passed, j.Total)
+	}
+
+	sub := store.submissions["s1"]
+	if !sub.Graded || sub.RawGrade != *j.Score {
+		t.Errorf("got grade %v, want %v", sub.RawGrade, *j.Score)
+	}
+
+	// Students see how they did on each test, but not their score
+	// until grades are released.
+	jobs, err := as.Results("s1", "stu1")
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+
+	if len(jobs) != 1 || jobs[0].Score != nil || len(jobs[0].Tests) != 3 {
+		t.Errorf("got %+v, want the tests without the score", jobs)
+	}
+
+	_, err = as.Results("s1", "stu2")
+	if !errors.Is(err, ERR_NOT_PERMITTED) {
+		t.Errorf("got %v, want %v", err, ERR_NOT_PERMITTED)
+	}
+
+	_, err = as.Rerun("s1", "stu1")
+	if !errors.Is(err, ERR_NOT_PERMITTED) {
+		t.Errorf("got %v, want %v", err, ERR_NOT_PERMITTED)
+	}
+}
+
+func TestAutogradeService_RunQueuedFailed(t *testing.T) {
+	tests := []struct {
+		name   string
+		files  []*models.Media
+		result *models.RunResult
+		reason string
+	}{
+		{
+			name:   "timed out",
+			result: &models.RunResult{Output: "ok 1\n", TimedOut: true},
+			reason: "too long",
+		},
+		{
+			name:   "tests printed by the submission",
+			result: &models.RunResult{Output: "1..1\nok 1 - passes\n"},
+			reason: "no tests",
+		},
+		{
+			name:   "no tests",
+			result: &models.RunResult{Output: "Segmentation fault\n", ExitCode: 139},
+			reason: "no tests",
+		},
+		{
+			name:   "missing file",
+			files:  []*models.Media{{FileName: "gone.py", FilePath: filepath.Join(t.TempDir(), "gone.py")}},
+			reason: "gone.py",
+		},
+	}
+
+	for _, tt := range tests {
+		store := newMockAutogradeStore()
+		store.files["s1"] = tt.files
+		store.harnesses["a1"] = &models.Harness{AssignmentId: "a1", Command: "true"}
+
+		as := NewAutogradeService(store, &mockFileStore{})
+		as.SetRunner(&mockRunner{result: tt.result})
+
+		j, err := as.Rerun("s1", "prof")
+		if err != nil {
+			t.Fatalf("%s: %+v", tt.name, err)
+		}
+
+		_, err = as.RunQueued(context.Background())
+		if err != nil {
+			t.Fatalf("%s: %+v", tt.name, err)
+		}
+
+		if j.Status != models.GRADE_JOB_FAILED || !strings.Contains(j.Error, tt.reason) {
+			t.Errorf("%s: got %s with error %q, want failed with %q", tt.name, j.Status, j.Error, tt.reason)
+		}
+
+		if store.submissions["s1"].Graded {
+			t.Errorf("%s: got the submission graded, want it left alone", tt.name)
+		}
+	}
+}
+
+// ========= //
+//   MOCKS   //
+// ========= //
+
+// mockRunner records the spec and workspace of the last run, and
+// reports its result, or else its TAP.
+type mockRunner struct {
+	tap    string
+	result *models.RunResult
+
+	spec  *models.RunSpec
+	files map[string]bool
+}
+
+func (m *mockRunner) Run(ctx context.Context, spec *models.RunSpec) (
+	*models.RunResult,
+	error,
+) {
+	m.spec = spec
+	m.files = make(map[string]bool)
+
+	filepath.WalkDir(
+		spec.Dir, func(p string, d os.DirEntry, err error) error {
+			if err == nil && !d.IsDir() {
+				rel, _ := filepath.Rel(spec.Dir, p)
+				m.files[filepath.ToSlash(rel)] = true
+			}
+			return err
+		},
+	)
+
+	if m.result != nil {
+		return m.result, nil
+	}
+
+	return &models.RunResult{TAP: m.tap}, nil
+}
+
+// mockAutogradeStore has assignment "a1" in course "c1", taught by
+// "prof", with submission "s1" made by "stu1".
+type mockAutogradeStore struct {
+	AutogradeStore
+
+	submissions map[string]*models.Submission
+	files       map[string][]*models.Media
+	harnesses   map[string]*models.Harness
+	jobs        []*models.GradeJob
+}
+
+func newMockAutogradeStore() *mockAutogradeStore {
+	sub := models.NewSubmission()
+	sub.ID = "s1"
+	sub.User.ID = "stu1"
+
+	return &mockAutogradeStore{
+		submissions: map[string]*models.Submission{"s1": sub},
+		files:       make(map[string][]*models.Media),
+		harnesses:   make(map[string]*models.Harness),
+	}
+}
+
+func (m *mockAutogradeStore) GetAssignmentById(assignmentid string) (
+	*models.Assignment,
+	error,
+) {
+	if assignmentid != "a1" {
+		return nil, dal.ERR_RECORD_NOT_FOUND
+	}
+	return &models.Assignment{Post: models.Post{Entity: models.Entity{ID: "a1"}}}, nil
+}
+
+func (m *mockAutogradeStore) GetCourseIdByAssignment(assignmentId string) (
+	string,
+	error,
+) {
+	return "c1", nil
+}
+
+func (m *mockAutogradeStore) IsCourseTeacher(courseId, netId string) (
+	bool,
+	error,
+) {
+	return netId == "prof", nil
+}
+
+func (m *mockAutogradeStore) GetExtension(assignmentId, netId string) (
+	*models.Extension,
+	error,
+) {
+	return nil, nil
+}
+
+func (m *mockAutogradeStore) GetAccommodation(courseId, netId string) (
+	*models.Accommodation,
+	error,
+) {
+	return nil, nil
+}
+
+func (m *mockAutogradeStore) GetAttempts(assignmentId, netId string) (
+	[]*models.Submission,
+	error,
+) {
+	return nil, nil
+}
+
+func (m *mockAutogradeStore) GetSubmissionById(submissionId string) (
+	*models.Submission,
+	error,
+) {
+	sub, ok := m.submissions[submissionId]
+	if !ok {
+		return nil, dal.ERR_RECORD_NOT_FOUND
+	}
+	return sub, nil
+}
+
+func (m *mockAutogradeStore) GetAssignmentIdBySubmission(submissionId string) (
+	string,
+	error,
+) {
+	return "a1", nil
+}
+
+func (m *mockAutogradeStore) GetSubmissionFiles(submissionId string) (
+	[]*models.Media,
+	error,
+) {
+	return m.files[submissionId], nil
+}
+
+func (m *mockAutogradeStore) UpdateSubmissionGrade(sub *models.Submission) error {
+	return nil
+}
+
+func (m *mockAutogradeStore) InsertMedia(media *models.Media) (
+	*models.Media,
+	error,
+) {
+	return media, nil
+}
+
+func (m *mockAutogradeStore) SaveHarness(h *models.Harness) error {
+	m.harnesses[h.AssignmentId] = h
+	return nil
+}
+
+func (m *mockAutogradeStore) GetHarness(assignmentId string) (
+	*models.Harness,
+	error,
+) {
+	h, ok := m.harnesses[assignmentId]
+	if !ok {
+		return nil, dal.ERR_RECORD_NOT_FOUND
+	}
+	return h, nil
+}
+
+func (m *mockAutogradeStore) InsertGradeJob(j *models.GradeJob) error {
+	j.ID = fmt.Sprintf("j%d", len(m.jobs)+1)
+	m.jobs = append(m.jobs, j)
+	return nil
+}
+
+func (m *mockAutogradeStore) GetGradeJobById(id string) (
+	*models.GradeJob,
+	error,
+) {
+	for _, j := range m.jobs {
+		if j.ID == id {
+			return j, nil
+		}
+	}
+	return nil, dal.ERR_RECORD_NOT_FOUND
+}
+
+func (m *mockAutogradeStore) GetGradeJobs(submissionId string) (
+	[]*models.GradeJob,
+	error,
+) {
+	var jobs []*models.GradeJob
+	for i := len(m.jobs) - 1; i >= 0; i-- {
+		if m.jobs[i].SubmissionId == submissionId {
+			copied := *m.jobs[i]
+			jobs = append(jobs, &copied)
+		}
+	}
+	return jobs, nil
+}
+
+func (m *mockAutogradeStore) GetQueuedGradeJobs() ([]string, error) {
+	var ids []string
+	for _, j := range m.jobs {
+		if j.Status == models.GRADE_JOB_QUEUED {
+			ids = append(ids, j.ID)
+		}
+	}
+	return ids, nil
+}
+
+func (m *mockAutogradeStore) UpdateGradeJob(j *models.GradeJob) error {
+	return nil
+}
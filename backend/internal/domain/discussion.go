@@ -0,0 +1,367 @@
+package domain
+
+import (
+	"errors"
+	"time"
+
+	"github.com/n30w/Darkspace/internal/dal"
+	"github.com/n30w/Darkspace/internal/models"
+)
+
+// Moderation changes the moderation flags of a discussion or a
+// comment. Nil fields are left as they are.
+type Moderation struct {
+	Locked *bool `json:"locked"`
+	Pinned *bool `json:"pinned"`
+	Hidden *bool `json:"hidden"`
+}
+
+// CreateDiscussion starts a new discussion thread in a course.
+func (ms *MessageService) CreateDiscussion(
+	title, description,
+	owner, courseId string,
+) (*models.Message, error) {
+	msg := models.NewMessage(title, description, owner, false)
+
+	msg.CreatedAt = time.Now()
+	msg.Course = courseId
+
+	err := ms.store.InsertMessage(msg, courseId)
+	if err != nil {
+		return nil, err
+	}
+
+	return msg, nil
+}
+
+// ListDiscussions retrieves a page of a course's discussions. Hidden
+// discussions are only listed for the course's teachers.
+func (ms *MessageService) ListDiscussions(
+	courseId, viewer string,
+	f models.Filters,
+) ([]*models.Message, models.Metadata, error) {
+	teacher, err := isTeacher(ms.store, courseId, viewer)
+	if err != nil {
+		return nil, models.Metadata{}, err
+	}
+
+	return ms.store.GetDiscussionsByCourse(courseId, teacher, f)
+}
+
+// ReadDiscussion retrieves a discussion along with a page of its
+// comments, each with its replies nested beneath it.
+func (ms *MessageService) ReadDiscussion(
+	courseId, discussionId, viewer string,
+	f models.Filters,
+) (*models.Message, []*models.Comment, models.Metadata, error) {
+	msg, err := ms.discussion(courseId, discussionId)
+	if err != nil {
+		return nil, nil, models.Metadata{}, err
+	}
+
+	teacher, err := isTeacher(ms.store, courseId, viewer)
+	if err != nil {
+		return nil, nil, models.Metadata{}, err
+	}
+
+	if msg.Hidden && !teacher {
+		return nil, nil, models.Metadata{}, dal.ERR_RECORD_NOT_FOUND
+	}
+
+	comments, metadata, err := ms.store.GetComments(msg.ID, teacher, f)
+	if err != nil {
+		return nil, nil, models.Metadata{}, err
+	}
+
+	return msg, buildCommentTree(comments), metadata, nil
+}
+
+// UpdateDiscussion changes the title and description of a discussion.
+// Only the discussion's author may edit it. Empty fields are left as
+// they are.
+func (ms *MessageService) UpdateDiscussion(
+	courseId, discussionId, netId string,
+	title, description string,
+) (*models.Message, error) {
+	msg, err := ms.discussion(courseId, discussionId)
+	if err != nil {
+		return nil, err
+	}
+
+	if msg.Owner != netId {
+		return nil, ERR_NOT_PERMITTED
+	}
+
+	if title != "" {
+		msg.Title = title
+	}
+
+	if description != "" {
+		msg.Description = description
+	}
+
+	err = ms.store.UpdateMessage(msg)
+	if err != nil {
+		return nil, err
+	}
+
+	return msg, nil
+}
+
+// DeleteDiscussion deletes a discussion and all of its comments. A
+// discussion may be deleted by its author or by a teacher.
+func (ms *MessageService) DeleteDiscussion(
+	courseId, discussionId, netId string,
+) error {
+	msg, err := ms.discussion(courseId, discussionId)
+	if err != nil {
+		return err
+	}
+
+	err = ms.authorOrTeacher(courseId, msg.Owner, netId)
+	if err != nil {
+		return err
+	}
+
+	return ms.store.DeleteMessageByID(msg.ID)
+}
+
+// ModerateDiscussion locks, pins or hides a discussion. Only the
+// course's teachers may moderate.
+func (ms *MessageService) ModerateDiscussion(
+	courseId, discussionId, netId string,
+	m Moderation,
+) (*models.Message, error) {
+	msg, err := ms.discussion(courseId, discussionId)
+	if err != nil {
+		return nil, err
+	}
+
+	err = teacherOnly(ms.store, courseId, netId)
+	if err != nil {
+		return nil, err
+	}
+
+	if m.Locked != nil {
+		msg.Locked = *m.Locked
+	}
+
+	if m.Pinned != nil {
+		msg.Pinned = *m.Pinned
+	}
+
+	if m.Hidden != nil {
+		msg.Hidden = *m.Hidden
+	}
+
+	err = ms.store.UpdateMessageModeration(msg)
+	if err != nil {
+		return nil, err
+	}
+
+	return msg, nil
+}
+
+// AddComment comments on a discussion, or replies to one of its
+// comments when parentId is set. Locked discussions only take
+// comments from teachers.
+func (ms *MessageService) AddComment(
+	courseId, discussionId, parentId, netId, body string,
+) (*models.Comment, error) {
+	msg, err := ms.discussion(courseId, discussionId)
+	if err != nil {
+		return nil, err
+	}
+
+	err = ms.unlocked(msg, netId)
+	if err != nil {
+		return nil, err
+	}
+
+	if parentId != "" {
+		parent, err := ms.store.GetCommentById(parentId)
+		if err != nil {
+			return nil, err
+		}
+
+		if parent.MessageId != msg.ID {
+			return nil, dal.ERR_RECORD_NOT_FOUND
+		}
+
+		if parent.Deleted {
+			return nil, ERR_COMMENT_DELETED
+		}
+	}
+
+	c := models.NewComment(msg.ID, parentId, body, netId)
+
+	err = ms.store.InsertComment(c)
+	if err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// EditComment changes the text of a comment. Only the comment's
+// author may edit it, and not once the discussion is locked.
+func (ms *MessageService) EditComment(
+	courseId, discussionId, commentId, netId, body string,
+) (*models.Comment, error) {
+	msg, c, err := ms.comment(courseId, discussionId, commentId)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.Owner != netId {
+		return nil, ERR_NOT_PERMITTED
+	}
+
+	if c.Deleted {
+		return nil, ERR_COMMENT_DELETED
+	}
+
+	err = ms.unlocked(msg, netId)
+	if err != nil {
+		return nil, err
+	}
+
+	c.Description = body
+
+	err = ms.store.UpdateCommentBody(c)
+	if err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// DeleteComment deletes a comment. A comment may be deleted by its
+// author or by a teacher. Its replies are kept.
+func (ms *MessageService) DeleteComment(
+	courseId, discussionId, commentId, netId string,
+) error {
+	_, c, err := ms.comment(courseId, discussionId, commentId)
+	if err != nil {
+		return err
+	}
+
+	err = ms.authorOrTeacher(courseId, c.Owner, netId)
+	if err != nil {
+		return err
+	}
+
+	return ms.store.DeleteComment(c.ID)
+}
+
+// ModerateComment hides or unhides a comment, along with its
+// replies. Only the course's teachers may moderate.
+func (ms *MessageService) ModerateComment(
+	courseId, discussionId, commentId, netId string,
+	m Moderation,
+) (*models.Comment, error) {
+	_, c, err := ms.comment(courseId, discussionId, commentId)
+	if err != nil {
+		return nil, err
+	}
+
+	err = teacherOnly(ms.store, courseId, netId)
+	if err != nil {
+		return nil, err
+	}
+
+	if m.Hidden != nil {
+		c.Hidden = *m.Hidden
+	}
+
+	err = ms.store.UpdateCommentHidden(c)
+	if err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// discussion retrieves a discussion of a course. Announcements and
+// discussions of other courses are not found.
+func (ms *MessageService) discussion(courseId, discussionId string) (
+	*models.Message,
+	error,
+) {
+	msg, err := ms.store.GetMessageById(discussionId)
+	if err != nil {
+		return nil, err
+	}
+
+	if msg.Type || msg.Course != courseId {
+		return nil, dal.ERR_RECORD_NOT_FOUND
+	}
+
+	return msg, nil
+}
+
+// comment retrieves a comment on a discussion of a course.
+func (ms *MessageService) comment(
+	courseId, discussionId, commentId string,
+) (*models.Message, *models.Comment, error) {
+	msg, err := ms.discussion(courseId, discussionId)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	c, err := ms.store.GetCommentById(commentId)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if c.MessageId != msg.ID {
+		return nil, nil, dal.ERR_RECORD_NOT_FOUND
+	}
+
+	return msg, c, nil
+}
+
+func (ms *MessageService) authorOrTeacher(courseId, owner, netId string) error {
+	if owner != "" && owner == netId {
+		return nil
+	}
+
+	return teacherOnly(ms.store, courseId, netId)
+}
+
+// unlocked returns ERR_DISCUSSION_LOCKED if a discussion is locked and
+// netId is not one of the course's teachers.
+func (ms *MessageService) unlocked(msg *models.Message, netId string) error {
+	if !msg.Locked {
+		return nil
+	}
+
+	err := teacherOnly(ms.store, msg.Course, netId)
+	if errors.Is(err, ERR_NOT_PERMITTED) {
+		return ERR_DISCUSSION_LOCKED
+	}
+
+	return err
+}
+
+// buildCommentTree nests replies beneath the comments they reply to.
+// comments must list parents before their replies. Comments whose
+// parent is not in the list are treated as top level.
+func buildCommentTree(comments []*models.Comment) []*models.Comment {
+	byId := make(map[string]*models.Comment, len(comments))
+	var roots []*models.Comment
+
+	for _, c := range comments {
+		byId[c.ID] = c
+
+		parent, ok := byId[c.ParentId]
+		if c.ParentId == "" || !ok {
+			roots = append(roots, c)
+			continue
+		}
+
+		parent.Replies = append(parent.Replies, c)
+	}
+
+	return roots
+}
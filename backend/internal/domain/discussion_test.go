@@ -0,0 +1,320 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/n30w/Darkspace/internal/dal"
+	"github.com/n30w/Darkspace/internal/models"
+)
+
+func TestBuildCommentTree(t *testing.T) {
+	comments := []*models.Comment{
+		newTestComment("1", ""),
+		newTestComment("2", ""),
+		newTestComment("3", "1"),
+		newTestComment("4", "3"),
+		newTestComment("5", "1"),
+		// The parent of an orphan is not part of the page.
+		newTestComment("6", "missing"),
+	}
+
+	roots := buildCommentTree(comments)
+
+	if len(roots) != 3 {
+		t.Fatalf("got %d roots, want 3", len(roots))
+	}
+
+	first := roots[0]
+	if first.ID != "1" || len(first.Replies) != 2 {
+		t.Fatalf("got %s with %d replies, want 1 with 2", first.ID, len(first.Replies))
+	}
+
+	if first.Replies[0].ID != "3" || first.Replies[1].ID != "5" {
+		t.Errorf("got replies %s and %s, want 3 and 5", first.Replies[0].ID, first.Replies[1].ID)
+	}
+
+	nested := first.Replies[0].Replies
+	if len(nested) != 1 || nested[0].ID != "4" {
+		t.Errorf("got %v, want a single nested reply 4", nested)
+	}
+
+	if roots[2].ID != "6" {
+		t.Errorf("got %s, want orphan 6 at the top level", roots[2].ID)
+	}
+}
+
+func TestMessageService_Discussions(t *testing.T) {
+	newService := func() (*MessageService, *mockMessageStore) {
+		store := &mockMessageStore{
+			messages: map[string]*models.Message{
+				"d1": {
+					Post: models.Post{
+						Entity: models.Entity{ID: "d1"},
+						Owner:  "student1",
+						Course: "c1",
+					},
+				},
+				"locked": {
+					Post: models.Post{
+						Entity: models.Entity{ID: "locked"},
+						Owner:  "student1",
+						Course: "c1",
+					},
+					Locked: true,
+				},
+				"announcement": {
+					Post: models.Post{
+						Entity: models.Entity{ID: "announcement"},
+						Course: "c1",
+					},
+					Type: true,
+				},
+			},
+			comments: map[string]*models.Comment{
+				"k1": {
+					Post: models.Post{
+						Entity: models.Entity{ID: "k1"},
+						Owner:  "student2",
+					},
+					MessageId: "d1",
+				},
+				"gone": {
+					Post: models.Post{
+						Entity: models.Entity{ID: "gone"},
+						Owner:  "student2",
+					},
+					MessageId: "d1",
+					Deleted:   true,
+				},
+			},
+			teachers: map[string]bool{"teacher1": true},
+		}
+		return NewMessageService(store), store
+	}
+
+	yes := true
+
+	tests := []struct {
+		name string
+		call func(ms *MessageService) error
+		want error
+	}{
+		{
+			name: "author edits discussion",
+			call: func(ms *MessageService) error {
+				_, err := ms.UpdateDiscussion("c1", "d1", "student1", "new title", "")
+				return err
+			},
+		},
+		{
+			name: "someone else edits discussion",
+			call: func(ms *MessageService) error {
+				_, err := ms.UpdateDiscussion("c1", "d1", "student2", "new title", "")
+				return err
+			},
+			want: ERR_NOT_PERMITTED,
+		},
+		{
+			name: "discussion of another course",
+			call: func(ms *MessageService) error {
+				_, err := ms.UpdateDiscussion("c2", "d1", "student1", "new title", "")
+				return err
+			},
+			want: dal.ERR_RECORD_NOT_FOUND,
+		},
+		{
+			name: "announcement is not a discussion",
+			call: func(ms *MessageService) error {
+				_, err := ms.AddComment("c1", "announcement", "", "student1", "hi")
+				return err
+			},
+			want: dal.ERR_RECORD_NOT_FOUND,
+		},
+		{
+			name: "teacher deletes discussion",
+			call: func(ms *MessageService) error {
+				return ms.DeleteDiscussion("c1", "d1", "teacher1")
+			},
+		},
+		{
+			name: "student moderates discussion",
+			call: func(ms *MessageService) error {
+				_, err := ms.ModerateDiscussion("c1", "d1", "student1", Moderation{Locked: &yes})
+				return err
+			},
+			want: ERR_NOT_PERMITTED,
+		},
+		{
+			name: "student comments on locked discussion",
+			call: func(ms *MessageService) error {
+				_, err := ms.AddComment("c1", "locked", "", "student2", "hi")
+				return err
+			},
+			want: ERR_DISCUSSION_LOCKED,
+		},
+		{
+			name: "teacher comments on locked discussion",
+			call: func(ms *MessageService) error {
+				_, err := ms.AddComment("c1", "locked", "", "teacher1", "hi")
+				return err
+			},
+		},
+		{
+			name: "reply to comment on another discussion",
+			call: func(ms *MessageService) error {
+				_, err := ms.AddComment("c1", "locked", "k1", "teacher1", "hi")
+				return err
+			},
+			want: dal.ERR_RECORD_NOT_FOUND,
+		},
+		{
+			name: "reply to deleted comment",
+			call: func(ms *MessageService) error {
+				_, err := ms.AddComment("c1", "d1", "gone", "student1", "hi")
+				return err
+			},
+			want: ERR_COMMENT_DELETED,
+		},
+		{
+			name: "author edits comment",
+			call: func(ms *MessageService) error {
+				_, err := ms.EditComment("c1", "d1", "k1", "student2", "edited")
+				return err
+			},
+		},
+		{
+			name: "discussion author edits someone else's comment",
+			call: func(ms *MessageService) error {
+				_, err := ms.EditComment("c1", "d1", "k1", "student1", "edited")
+				return err
+			},
+			want: ERR_NOT_PERMITTED,
+		},
+		{
+			name: "teacher hides comment",
+			call: func(ms *MessageService) error {
+				_, err := ms.ModerateComment("c1", "d1", "k1", "teacher1", Moderation{Hidden: &yes})
+				return err
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				ms, _ := newService()
+
+				err := tt.call(ms)
+				if !errors.Is(err, tt.want) {
+					t.Errorf("got %v, want %v", err, tt.want)
+				}
+			},
+		)
+	}
+
+	t.Run(
+		"hidden discussion is not found by students", func(t *testing.T) {
+			ms, store := newService()
+			store.messages["d1"].Hidden = true
+
+			_, _, _, err := ms.ReadDiscussion("c1", "d1", "student1", models.NewFilters())
+			if !errors.Is(err, dal.ERR_RECORD_NOT_FOUND) {
+				t.Errorf("got %v, want %v", err, dal.ERR_RECORD_NOT_FOUND)
+			}
+
+			_, _, _, err = ms.ReadDiscussion("c1", "d1", "teacher1", models.NewFilters())
+			if err != nil {
+				t.Errorf("got %v, want teachers to see hidden discussions", err)
+			}
+
+			if !store.includedHidden {
+				t.Errorf("hidden comments were not requested for a teacher")
+			}
+		},
+	)
+}
+
+func newTestComment(id, parentId string) *models.Comment {
+	c := models.NewComment("d1", parentId, "body "+id, "student1")
+	c.ID = id
+	return c
+}
+
+// ========= //
+//   MOCKS   //
+// ========= //
+
+// mockMessageStore implements the parts of MessageStore that
+// discussions use. Calling anything else panics.
+type mockMessageStore struct {
+	MessageStore
+
+	messages map[string]*models.Message
+	comments map[string]*models.Comment
+	teachers map[string]bool
+
+	includedHidden bool
+}
+
+func (m *mockMessageStore) GetMessageById(id string) (*models.Message, error) {
+	msg, ok := m.messages[id]
+	if !ok {
+		return nil, dal.ERR_RECORD_NOT_FOUND
+	}
+	return msg, nil
+}
+
+func (m *mockMessageStore) InsertMessage(msg *models.Message, courseId string) error {
+	return nil
+}
+
+func (m *mockMessageStore) DeleteMessageByID(id string) error {
+	delete(m.messages, id)
+	return nil
+}
+
+func (m *mockMessageStore) UpdateMessage(msg *models.Message) error {
+	return nil
+}
+
+func (m *mockMessageStore) UpdateMessageModeration(msg *models.Message) error {
+	return nil
+}
+
+func (m *mockMessageStore) IsCourseTeacher(courseId, netId string) (bool, error) {
+	return m.teachers[netId], nil
+}
+
+func (m *mockMessageStore) InsertComment(c *models.Comment) error {
+	return nil
+}
+
+func (m *mockMessageStore) GetCommentById(id string) (*models.Comment, error) {
+	c, ok := m.comments[id]
+	if !ok {
+		return nil, dal.ERR_RECORD_NOT_FOUND
+	}
+	return c, nil
+}
+
+func (m *mockMessageStore) GetComments(
+	messageId string,
+	includeHidden bool,
+	f models.Filters,
+) ([]*models.Comment, models.Metadata, error) {
+	m.includedHidden = includeHidden
+	return nil, models.Metadata{}, nil
+}
+
+func (m *mockMessageStore) UpdateCommentBody(c *models.Comment) error {
+	return nil
+}
+
+func (m *mockMessageStore) UpdateCommentHidden(c *models.Comment) error {
+	return nil
+}
+
+func (m *mockMessageStore) DeleteComment(id string) error {
+	return nil
+}
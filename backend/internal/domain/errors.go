@@ -0,0 +1,56 @@
+package domain
+
+import "errors"
+
+var (
+	ERR_UNKNOWN_FILE_TYPE     = errors.New("unknown file type")
+	ERR_FILE_TYPE_NOT_ALLOWED = errors.New("file type not allowed")
+	ERR_FILE_TYPE_MISMATCH    = errors.New("file content does not match its declared type")
+	ERR_INVALID_IMAGE         = errors.New("invalid image")
+	ERR_QUOTA_EXCEEDED        = errors.New("storage quota exceeded")
+	ERR_NOT_PERMITTED         = errors.New("not permitted")
+	ERR_DISCUSSION_LOCKED     = errors.New("discussion is locked")
+	ERR_COMMENT_DELETED       = errors.New("comment has been deleted")
+	ERR_TEAM_FULL             = errors.New("team is full")
+	ERR_ALREADY_ON_TEAM       = errors.New("already on a team for this project")
+	ERR_NOT_ENROLLED          = errors.New("not enrolled in the course")
+	ERR_PROJECT_UNGRADED      = errors.New("project has no assignment")
+	ERR_QUIZ_CLOSED           = errors.New("quiz is not open")
+	ERR_NO_ATTEMPTS_LEFT      = errors.New("no attempts left")
+	ERR_ATTEMPT_EXPIRED       = errors.New("attempt deadline has passed")
+	ERR_ATTEMPT_SUBMITTED     = errors.New("attempt has already been submitted")
+	ERR_ATTEMPT_NOT_SUBMITTED = errors.New("attempt has not been submitted")
+	ERR_UNKNOWN_QUESTION      = errors.New("question is not part of the quiz")
+	ERR_POINTS_OUT_OF_RANGE   = errors.New("points must be between zero and the question's points")
+	ERR_RUBRIC_IN_USE         = errors.New("rubric is attached to an assignment")
+	ERR_NO_RUBRIC             = errors.New("assignment has no rubric")
+	ERR_UNKNOWN_CRITERION     = errors.New("criterion is not part of the rubric")
+	ERR_UNKNOWN_LEVEL         = errors.New("level is not part of the criterion")
+	ERR_RUBRIC_INCOMPLETE     = errors.New("every criterion must be scored once")
+	ERR_SCORE_OUT_OF_RANGE    = errors.New("points must be between zero and the points available")
+	ERR_SUBMISSION_CLOSED     = errors.New("assignment no longer accepts submissions")
+	ERR_UNKNOWN_ATTEMPT       = errors.New("no such attempt at the assignment")
+	ERR_ANONYMITY_LOCKED      = errors.New("anonymous grading ends when grades are released")
+	ERR_GRADES_RELEASED       = errors.New("grades have already been released")
+	ERR_NO_PEER_REVIEW        = errors.New("assignment is not peer reviewed")
+	ERR_NOT_DUE               = errors.New("assignment is not due yet")
+	ERR_ALREADY_ALLOCATED     = errors.New("reviewers have already been allocated")
+	ERR_TOO_FEW_SUBMISSIONS   = errors.New("too few submissions for students to review each other")
+	ERR_NO_SCORE              = errors.New("review must have a score")
+	ERR_NOT_GRADED            = errors.New("submission has not been graded")
+	ERR_REGRADE_CLOSED        = errors.New("regrades can no longer be requested for this assignment")
+	ERR_REGRADE_PENDING       = errors.New("submission already has a regrade request open")
+	ERR_REGRADE_RESOLVED      = errors.New("regrade request has already been decided")
+	ERR_UNKNOWN_TEACHER       = errors.New("can only escalate to another teacher of the course")
+	ERR_NO_HARNESS            = errors.New("assignment has no test harness")
+	ERR_NOT_PDF               = errors.New("only PDFs can be annotated")
+	ERR_PDF_UNREADABLE        = errors.New("PDF could not be read to annotate it")
+	ERR_UNKNOWN_PAGE          = errors.New("page is not part of the PDF")
+	ERR_UNKNOWN_MEDIA         = errors.New("file is not part of the submission")
+	ERR_NOT_AVAILABLE         = errors.New("assignment does not take submissions yet")
+	ERR_MODULE_LOCKED         = errors.New("module's prerequisites have not been completed")
+	ERR_INVALID_PREREQUISITE  = errors.New("prerequisites must be earlier modules of the course")
+	ERR_UNKNOWN_REFERENCE     = errors.New("item must refer to an assignment or announcement of the course")
+	ERR_KIND_CHANGED          = errors.New("an item's kind cannot be changed")
+	ERR_NOT_MARKABLE          = errors.New("item is not completed by marking it as done")
+)
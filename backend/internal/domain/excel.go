@@ -16,8 +16,13 @@ type ExcelStore interface {
 	Save(file *excelize.File, to string) (string, error)
 	Open(path ...string) (*excelize.File, error)
 	AddRow(f *excelize.File, row *[]interface{}, start string) error
+	AddSheetRow(f *excelize.File, sheet string, row *[]interface{}, start string) error
 }
 
+// rubricSheetName is the sheet that rubric breakdowns are written to,
+// one row per criterion score.
+const rubricSheetName = "rubric"
+
 type ExcelService struct {
 	store ExcelStore
 }
@@ -93,9 +98,11 @@ func (es *ExcelService) WriteSubmissions(
 
 	err = es.store.AddRow(f, row, "G2")
 
-	// Write rows to template.
+	// Write rows to template. Grades are written before any late
+	// penalty, since penalties are taken off again when the file is
+	// read back.
 	for i, submission := range submissions {
-		row := &[]interface{}{submission.User.FullName, submission.User.ID, submission.Grade, submission.Feedback, submission.ID}
+		row := &[]interface{}{submission.User.FullName, submission.User.ID, submission.RawGrade, submission.Feedback, submission.ID}
 
 		// Start in column A, increment downward. i+2 because
 		// i starts at 0, Excel rows start at 1, and the first
@@ -108,6 +115,11 @@ func (es *ExcelService) WriteSubmissions(
 		}
 	}
 
+	err = es.writeRubricScores(f, submissions)
+	if err != nil {
+		return "", err
+	}
+
 	fmt.Printf("Saving to path: %s \n", savePath)
 	// Save the file to disk.
 	s, err := es.store.Save(f, savePath)
@@ -119,6 +131,56 @@ func (es *ExcelService) WriteSubmissions(
 	return s, nil
 }
 
+// writeRubricScores writes the rubric breakdown of each submission to
+// its own sheet, so that the submissions sheet keeps the layout that
+// ReadSubmissions expects. Nothing is written when no submission was
+// graded with a rubric.
+func (es *ExcelService) writeRubricScores(
+	f *excelize.File,
+	submissions []*models.Submission,
+) error {
+	line := 1
+
+	for _, submission := range submissions {
+		for _, score := range submission.Rubric {
+			if line == 1 {
+				header := &[]interface{}{
+					"Name", "Net ID", "Criterion", "Level", "Points",
+					"Comment", "Submission ID",
+				}
+
+				err := es.store.AddSheetRow(f, rubricSheetName, header, "A1")
+				if err != nil {
+					return err
+				}
+
+				line++
+			}
+
+			row := &[]interface{}{
+				submission.User.FullName,
+				submission.User.ID,
+				score.Criterion,
+				score.Level,
+				score.Points,
+				score.Comment,
+				submission.ID,
+			}
+
+			start := "A" + strconv.Itoa(line)
+
+			err := es.store.AddSheetRow(f, rubricSheetName, row, start)
+			if err != nil {
+				return err
+			}
+
+			line++
+		}
+	}
+
+	return nil
+}
+
 // Save takes an Excelize Excel file and saves it to a specified
 // path, via to.
 func (es *ExcelService) Save(f *excelize.File, to string) (string, error) {
@@ -6,8 +6,63 @@ import (
 	"testing"
 
 	"github.com/n30w/Darkspace/internal/models"
+	"github.com/xuri/excelize/v2"
 )
 
+func TestExcelService_WriteRubricScores(t *testing.T) {
+	store := &mockSheetStore{}
+	es := NewExcelService(store)
+
+	submissions := []*models.Submission{
+		{
+			Entity: models.Entity{ID: "s1"},
+			User:   models.User{Entity: models.Entity{ID: "stu1"}},
+			Rubric: []*models.CriterionScore{
+				{Criterion: "Clarity", Level: "Excellent", Points: 4},
+				{Criterion: "Evidence", Points: 1.5, Comment: "cite more"},
+			},
+		},
+		{
+			// Graded without a rubric.
+			Entity: models.Entity{ID: "s2"},
+			User:   models.User{Entity: models.Entity{ID: "stu2"}},
+		},
+	}
+
+	err := es.writeRubricScores(nil, submissions)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+
+	want := []string{"A1", "A2", "A3"}
+
+	if len(store.starts) != len(want) {
+		t.Fatalf("got rows at %v, want %v", store.starts, want)
+	}
+
+	for i, start := range want {
+		if store.starts[i] != start || store.sheets[i] != rubricSheetName {
+			t.Errorf("got row %s on %s, want %s on %s", store.starts[i], store.sheets[i], start, rubricSheetName)
+		}
+	}
+
+	if got := (*store.rows[2])[5]; got != "cite more" {
+		t.Errorf("got comment %v, want %q", got, "cite more")
+	}
+
+	store = &mockSheetStore{}
+	es = NewExcelService(store)
+
+	err = es.writeRubricScores(nil, submissions[1:])
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+
+	if len(store.rows) != 0 {
+		t.Errorf("got %d rows, want no rubric sheet without rubric scores", len(store.rows))
+	}
+}
+
 // ========= //
 //   MOCKS   //
 // ========= //
@@ -157,3 +212,24 @@ func (mus *mockExcelStore) InsertSubmissionFeedback(
 
 	return fmt.Errorf("No such submission")
 }
+
+// mockSheetStore records the rows added to named sheets.
+type mockSheetStore struct {
+	ExcelStore
+
+	sheets []string
+	starts []string
+	rows   []*[]interface{}
+}
+
+func (m *mockSheetStore) AddSheetRow(
+	f *excelize.File,
+	sheet string,
+	row *[]interface{},
+	start string,
+) error {
+	m.sheets = append(m.sheets, sheet)
+	m.starts = append(m.starts, start)
+	m.rows = append(m.rows, row)
+	return nil
+}
@@ -0,0 +1,338 @@
+package domain
+
+import (
+	"github.com/n30w/Darkspace/internal/models"
+)
+
+// DueDateStore looks up what moves a student's due dates. Services
+// that check submissions against due dates embed it in their stores.
+type DueDateStore interface {
+	GetExtension(assignmentId, netId string) (*models.Extension, error)
+	GetAccommodation(courseId, netId string) (*models.Accommodation, error)
+	GetCourseIdByAssignment(assignmentId string) (string, error)
+}
+
+type ExtensionStore interface {
+	DueDateStore
+	AttemptStore
+
+	GetAssignmentById(assignmentid string) (*models.Assignment, error)
+	GetAssignmentsByCourse(courseid string) ([]string, error)
+	SaveOnTime(sub *models.Submission) error
+	UpdateSubmissionGrade(sub *models.Submission) error
+
+	SaveExtension(e *models.Extension) error
+	GetExtensions(assignmentId string) ([]*models.Extension, error)
+	DeleteExtension(assignmentId, netId string) error
+
+	SaveAccommodation(a *models.Accommodation) error
+	GetAccommodations(courseId string) ([]*models.Accommodation, error)
+	DeleteAccommodation(courseId, netId string) error
+
+	IsCourseTeacher(courseId, netId string) (bool, error)
+	IsCourseStudent(courseId, netId string) (bool, error)
+}
+
+type ExtensionService struct {
+	store ExtensionStore
+}
+
+func NewExtensionService(s ExtensionStore) *ExtensionService {
+	return &ExtensionService{store: s}
+}
+
+// GrantExtension gives a student of the assignment's course their own
+// due date for it, replacing any extension they had. What they already
+// submitted is marked late or on time by it, and regraded if graded.
+// Only the course's teachers may grant extensions.
+func (es *ExtensionService) GrantExtension(
+	e *models.Extension,
+	netId string,
+) (*models.Extension, error) {
+	courseId, err := es.store.GetCourseIdByAssignment(e.AssignmentId)
+	if err != nil {
+		return nil, err
+	}
+
+	err = es.teacherAndStudent(courseId, netId, e.NetId)
+	if err != nil {
+		return nil, err
+	}
+
+	e.DueDate = e.DueDate.UTC()
+	e.GrantedBy = netId
+
+	err = es.store.SaveExtension(e)
+	if err != nil {
+		return nil, err
+	}
+
+	err = es.restamp(e.AssignmentId, e.NetId)
+	if err != nil {
+		return nil, err
+	}
+
+	return e, nil
+}
+
+// ListExtensions retrieves the extensions granted for an assignment.
+// Only the course's teachers may see them.
+func (es *ExtensionService) ListExtensions(assignmentId, netId string) (
+	[]*models.Extension,
+	error,
+) {
+	courseId, err := es.store.GetCourseIdByAssignment(assignmentId)
+	if err != nil {
+		return nil, err
+	}
+
+	err = teacherOnly(es.store, courseId, netId)
+	if err != nil {
+		return nil, err
+	}
+
+	return es.store.GetExtensions(assignmentId)
+}
+
+// RevokeExtension removes a student's extension for an assignment, so
+// that their due date goes back to the class's, along with any
+// accommodation they have.
+func (es *ExtensionService) RevokeExtension(
+	assignmentId, studentId, netId string,
+) error {
+	courseId, err := es.store.GetCourseIdByAssignment(assignmentId)
+	if err != nil {
+		return err
+	}
+
+	err = teacherOnly(es.store, courseId, netId)
+	if err != nil {
+		return err
+	}
+
+	err = es.store.DeleteExtension(assignmentId, studentId)
+	if err != nil {
+		return err
+	}
+
+	return es.restamp(assignmentId, studentId)
+}
+
+// SetAccommodation sets a student's standing accommodation in a
+// course, replacing any they had. What they already submitted is
+// marked late or on time by it, and regraded if graded. Only the
+// course's teachers may set accommodations.
+func (es *ExtensionService) SetAccommodation(
+	a *models.Accommodation,
+	netId string,
+) (*models.Accommodation, error) {
+	err := es.teacherAndStudent(a.CourseId, netId, a.NetId)
+	if err != nil {
+		return nil, err
+	}
+
+	err = es.store.SaveAccommodation(a)
+	if err != nil {
+		return nil, err
+	}
+
+	err = es.restampCourse(a.CourseId, a.NetId)
+	if err != nil {
+		return nil, err
+	}
+
+	return a, nil
+}
+
+// ListAccommodations retrieves the accommodations of a course's
+// students. Only the course's teachers may see them.
+func (es *ExtensionService) ListAccommodations(courseId, netId string) (
+	[]*models.Accommodation,
+	error,
+) {
+	err := teacherOnly(es.store, courseId, netId)
+	if err != nil {
+		return nil, err
+	}
+
+	return es.store.GetAccommodations(courseId)
+}
+
+// RemoveAccommodation removes a student's accommodation in a course,
+// so that their due dates go back to the class's, along with any
+// extensions they have.
+func (es *ExtensionService) RemoveAccommodation(
+	courseId, studentId, netId string,
+) error {
+	err := teacherOnly(es.store, courseId, netId)
+	if err != nil {
+		return err
+	}
+
+	err = es.store.DeleteAccommodation(courseId, studentId)
+	if err != nil {
+		return err
+	}
+
+	return es.restampCourse(courseId, studentId)
+}
+
+// ForStudent is an assignment as a student sees it, with their own due
+// date.
+func (es *ExtensionService) ForStudent(
+	a *models.Assignment,
+	netId string,
+) (*models.Assignment, error) {
+	return studentAssignment(es.store, a, netId)
+}
+
+// restamp marks a student's attempts at an assignment as on time or
+// late again after their due date changes, and regrades those that
+// were graded, so that their late penalty follows the new due date.
+func (es *ExtensionService) restamp(assignmentId, studentId string) error {
+	a, err := es.store.GetAssignmentById(assignmentId)
+	if err != nil {
+		return err
+	}
+
+	due, err := studentAssignment(es.store, a, studentId)
+	if err != nil {
+		return err
+	}
+
+	attempts, err := es.store.GetAttempts(a.ID, studentId)
+	if err != nil {
+		return err
+	}
+
+	graded := false
+
+	for _, sub := range attempts {
+		sub.OnTime = !due.IsLate(sub.SubmissionTime)
+
+		err = es.store.SaveOnTime(sub)
+		if err != nil {
+			return err
+		}
+
+		if !sub.Graded {
+			continue
+		}
+
+		sub.SetGrade(sub.RawGrade, due)
+
+		err = es.store.UpdateSubmissionGrade(sub)
+		if err != nil {
+			return err
+		}
+
+		graded = true
+	}
+
+	if !graded {
+		return nil
+	}
+
+	return recountAttempts(es.store, a, studentId)
+}
+
+// restampCourse restamps a student's attempts at every assignment of a
+// course, after their accommodation changes.
+func (es *ExtensionService) restampCourse(courseId, studentId string) error {
+	assignmentIds, err := es.store.GetAssignmentsByCourse(courseId)
+	if err != nil {
+		return err
+	}
+
+	for _, id := range assignmentIds {
+		err = es.restamp(id, studentId)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// teacherAndStudent checks that netId teaches the course and that
+// studentId is enrolled in it.
+func (es *ExtensionService) teacherAndStudent(
+	courseId, netId, studentId string,
+) error {
+	err := teacherOnly(es.store, courseId, netId)
+	if err != nil {
+		return err
+	}
+
+	student, err := es.store.IsCourseStudent(courseId, studentId)
+	if err != nil {
+		return err
+	}
+
+	if !student {
+		return ERR_NOT_ENROLLED
+	}
+
+	return nil
+}
+
+// studentAssignment is an assignment as it applies to a student, with
+// their extension or accommodation applied to its due date. On time
+// checks and late penalties for the student's submissions must use it.
+func studentAssignment(
+	s DueDateStore,
+	a *models.Assignment,
+	netId string,
+) (*models.Assignment, error) {
+	courseId, err := s.GetCourseIdByAssignment(a.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	ext, acc, err := dueDateChanges(s, a.ID, courseId, netId)
+	if err != nil {
+		return nil, err
+	}
+
+	return a.For(ext, acc), nil
+}
+
+// studentQuiz is a quiz as it applies to a student, with their
+// extension or accommodation applied to when it closes and to its time
+// limit.
+func studentQuiz(
+	s DueDateStore,
+	q *models.Quiz,
+	netId string,
+) (*models.Quiz, error) {
+	ext, acc, err := dueDateChanges(s, q.AssignmentId, q.CourseId, netId)
+	if err != nil {
+		return nil, err
+	}
+
+	return q.For(ext, acc), nil
+}
+
+// dueDateChanges retrieves a student's extension for an assignment and
+// their accommodation in its course. Either may be nil.
+func dueDateChanges(
+	s DueDateStore,
+	assignmentId, courseId, netId string,
+) (*models.Extension, *models.Accommodation, error) {
+	var ext *models.Extension
+	var err error
+
+	if assignmentId != "" {
+		ext, err = s.GetExtension(assignmentId, netId)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	acc, err := s.GetAccommodation(courseId, netId)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return ext, acc, nil
+}
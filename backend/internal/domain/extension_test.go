@@ -0,0 +1,196 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/n30w/Darkspace/internal/models"
+)
+
+func TestExtensionService_Regrades(t *testing.T) {
+	due := time.Date(2024, 5, 1, 23, 59, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		change func(es *ExtensionService) error
+	}{
+		{
+			name: "extension",
+			change: func(es *ExtensionService) error {
+				_, err := es.GrantExtension(
+					&models.Extension{AssignmentId: "a1", NetId: "stu1", DueDate: due.Add(48 * time.Hour)},
+					"prof",
+				)
+				return err
+			},
+		},
+		{
+			name: "accommodation",
+			change: func(es *ExtensionService) error {
+				_, err := es.SetAccommodation(
+					&models.Accommodation{CourseId: "c1", NetId: "stu1", ExtraTime: 24 * 60 * 60},
+					"prof",
+				)
+				return err
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				store := newMockExtensionStore(due)
+				es := NewExtensionService(store)
+
+				err := tt.change(es)
+				if err != nil {
+					t.Fatalf("%+v", err)
+				}
+
+				graded := store.attempts[0]
+				if !graded.OnTime || graded.LatePenalty != 0 || graded.Grade != 80 {
+					t.Errorf("got on time %v, penalty %v and grade %v, want on time with 80", graded.OnTime, graded.LatePenalty, graded.Grade)
+				}
+
+				ungraded := store.attempts[1]
+				if !ungraded.OnTime || ungraded.Graded {
+					t.Errorf("got on time %v and graded %v, want on time and still ungraded", ungraded.OnTime, ungraded.Graded)
+				}
+
+				if store.counted == "" {
+					t.Errorf("got no attempt counted, want the attempts recounted")
+				}
+			},
+		)
+	}
+}
+
+// ========= //
+//   MOCKS   //
+// ========= //
+
+// mockExtensionStore has assignment "a1" of course "c1", taught by
+// "prof", with a 10% a day late penalty. Student "stu1" made a graded
+// attempt "s1" and an ungraded attempt "s2", both a few hours late.
+type mockExtensionStore struct {
+	ExtensionStore
+
+	assignment    *models.Assignment
+	extension     *models.Extension
+	accommodation *models.Accommodation
+	attempts      []*models.Submission
+	counted       string
+}
+
+func newMockExtensionStore(due time.Time) *mockExtensionStore {
+	a := &models.Assignment{
+		Post:    models.Post{Entity: models.Entity{ID: "a1"}},
+		DueDate: due,
+		LatePolicy: &models.LatePolicy{
+			AssignmentId: "a1",
+			Deduction:    10,
+			Interval:     models.PER_DAY,
+		},
+	}
+
+	graded := &models.Submission{
+		Entity:         models.Entity{ID: "s1"},
+		SubmissionTime: due.Add(2 * time.Hour),
+		Attempt:        1,
+	}
+	graded.User.ID = "stu1"
+	graded.SetGrade(80, a)
+
+	ungraded := &models.Submission{
+		Entity:         models.Entity{ID: "s2"},
+		SubmissionTime: due.Add(3 * time.Hour),
+		Attempt:        2,
+	}
+	ungraded.User.ID = "stu1"
+
+	return &mockExtensionStore{
+		assignment: a,
+		attempts:   []*models.Submission{graded, ungraded},
+	}
+}
+
+func (m *mockExtensionStore) GetAssignmentById(assignmentid string) (
+	*models.Assignment,
+	error,
+) {
+	return m.assignment, nil
+}
+
+func (m *mockExtensionStore) GetAssignmentsByCourse(courseid string) (
+	[]string,
+	error,
+) {
+	return []string{"a1"}, nil
+}
+
+func (m *mockExtensionStore) GetCourseIdByAssignment(assignmentId string) (
+	string,
+	error,
+) {
+	return "c1", nil
+}
+
+func (m *mockExtensionStore) IsCourseTeacher(courseId, netId string) (
+	bool,
+	error,
+) {
+	return netId == "prof", nil
+}
+
+func (m *mockExtensionStore) IsCourseStudent(courseId, netId string) (
+	bool,
+	error,
+) {
+	return netId == "stu1", nil
+}
+
+func (m *mockExtensionStore) SaveExtension(e *models.Extension) error {
+	m.extension = e
+	return nil
+}
+
+func (m *mockExtensionStore) GetExtension(assignmentId, netId string) (
+	*models.Extension,
+	error,
+) {
+	return m.extension, nil
+}
+
+func (m *mockExtensionStore) SaveAccommodation(a *models.Accommodation) error {
+	m.accommodation = a
+	return nil
+}
+
+func (m *mockExtensionStore) GetAccommodation(courseId, netId string) (
+	*models.Accommodation,
+	error,
+) {
+	return m.accommodation, nil
+}
+
+func (m *mockExtensionStore) GetAttempts(assignmentId, netId string) (
+	[]*models.Submission,
+	error,
+) {
+	return m.attempts, nil
+}
+
+func (m *mockExtensionStore) SetCountedAttempt(
+	assignmentId, netId, submissionId string,
+) error {
+	m.counted = submissionId
+	return nil
+}
+
+func (m *mockExtensionStore) SaveOnTime(sub *models.Submission) error {
+	return nil
+}
+
+func (m *mockExtensionStore) UpdateSubmissionGrade(sub *models.Submission) error {
+	return nil
+}
@@ -0,0 +1,146 @@
+package domain
+
+import (
+	"github.com/n30w/Darkspace/internal/dal"
+	"github.com/n30w/Darkspace/internal/models"
+)
+
+// CheckFeedback checks that a user may attach media to the feedback of
+// a submission, which only the course's teachers may do. It lets an
+// upload be refused before its files are saved.
+func (ms *MediaService) CheckFeedback(submissionId, netId string) error {
+	_, teacher, err := ms.feedbackSubmission(submissionId, netId)
+	if err != nil {
+		return err
+	}
+
+	if !teacher {
+		return ERR_NOT_PERMITTED
+	}
+
+	return nil
+}
+
+// AddFeedbackMedia records media attached to the feedback of the
+// submission it is attributed to, such as an audio comment or a
+// marked up copy of the submission. Only the course's teachers may
+// attach feedback media.
+func (ms *MediaService) AddFeedbackMedia(
+	media *models.Media,
+	netId string,
+) (*models.Media, error) {
+	err := ms.CheckFeedback(media.AttributionsByType["feedback"], netId)
+	if err != nil {
+		return nil, err
+	}
+
+	media, err = ms.store.InsertMedia(media)
+	if err != nil {
+		return nil, err
+	}
+
+	err = ms.store.InsertMediaIntoFeedback(media)
+	if err != nil {
+		return nil, err
+	}
+
+	return media, nil
+}
+
+// FeedbackMedia retrieves the media attached to the feedback of a
+// submission, oldest first. The course's teachers may see the feedback
+// media of any submission, and students that of their own once grades
+// are released, as with the rest of their feedback.
+func (ms *MediaService) FeedbackMedia(submissionId, netId string) (
+	[]*models.Media,
+	error,
+) {
+	a, teacher, err := ms.feedbackSubmission(submissionId, netId)
+	if err != nil {
+		return nil, err
+	}
+
+	if !teacher && !a.GradesReleased {
+		return []*models.Media{}, nil
+	}
+
+	return ms.store.GetFeedbackMedia(submissionId)
+}
+
+// FeedbackFile retrieves one piece of media attached to the feedback
+// of a submission, if the user may see it.
+func (ms *MediaService) FeedbackFile(submissionId, mediaId, netId string) (
+	*models.Media,
+	error,
+) {
+	media, err := ms.FeedbackMedia(submissionId, netId)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, m := range media {
+		if m.ID == mediaId {
+			return m, nil
+		}
+	}
+
+	return nil, dal.ERR_RECORD_NOT_FOUND
+}
+
+// RemoveFeedbackMedia takes media off the feedback of a submission.
+// The media is left to be garbage collected. Only the course's
+// teachers may remove it.
+func (ms *MediaService) RemoveFeedbackMedia(
+	submissionId, mediaId, netId string,
+) error {
+	err := ms.CheckFeedback(submissionId, netId)
+	if err != nil {
+		return err
+	}
+
+	return ms.store.DeleteFeedbackMedia(submissionId, mediaId)
+}
+
+// feedbackSubmission retrieves the assignment of a submission whose
+// feedback the user may see, being one of the course's teachers or the
+// student who made it, and whether they are a teacher.
+func (ms *MediaService) feedbackSubmission(submissionId, netId string) (
+	*models.Assignment,
+	bool,
+	error,
+) {
+	if netId == "" {
+		return nil, false, ERR_NOT_PERMITTED
+	}
+
+	sub, err := ms.store.GetSubmissionById(submissionId)
+	if err != nil {
+		return nil, false, err
+	}
+
+	assignmentId, err := ms.store.GetAssignmentIdBySubmission(sub.ID)
+	if err != nil {
+		return nil, false, err
+	}
+
+	a, err := ms.store.GetAssignmentById(assignmentId)
+	if err != nil {
+		return nil, false, err
+	}
+
+	courseId, err := ms.store.GetCourseIdByAssignment(a.ID)
+	if err != nil {
+		return nil, false, err
+	}
+
+	teacher, err := ms.store.IsCourseTeacher(courseId, netId)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if !teacher && sub.User.ID != netId {
+		return nil, false, ERR_NOT_PERMITTED
+	}
+
+	return a, teacher, nil
+}
@@ -0,0 +1,146 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/n30w/Darkspace/internal/dal"
+	"github.com/n30w/Darkspace/internal/models"
+)
+
+func TestMediaService_FeedbackMedia(t *testing.T) {
+	store := newMockFeedbackStore()
+	ms := NewMediaService(store)
+
+	audio := func() *models.Media {
+		return &models.Media{
+			FileName:           "comments.m4a",
+			FileType:           models.M4A,
+			AttributionsByType: map[string]string{"feedback": "s1"},
+		}
+	}
+
+	_, err := ms.AddFeedbackMedia(audio(), "abc123")
+	if !errors.Is(err, ERR_NOT_PERMITTED) {
+		t.Fatalf("got %v, want %v for the student", err, ERR_NOT_PERMITTED)
+	}
+
+	m, err := ms.AddFeedbackMedia(audio(), "prof")
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+
+	tests := []struct {
+		name     string
+		netId    string
+		released bool
+		want     int
+		err      error
+	}{
+		{name: "teacher", netId: "prof", want: 1},
+		{name: "student before release", netId: "abc123", want: 0},
+		{name: "student after release", netId: "abc123", released: true, want: 1},
+		{name: "another student", netId: "xyz789", released: true, err: ERR_NOT_PERMITTED},
+		{name: "nobody", netId: "", released: true, err: ERR_NOT_PERMITTED},
+	}
+
+	for _, tt := range tests {
+		store.assignment.GradesReleased = tt.released
+
+		media, err := ms.FeedbackMedia("s1", tt.netId)
+		if !errors.Is(err, tt.err) {
+			t.Errorf("%s: got %v, want %v", tt.name, err, tt.err)
+			continue
+		}
+
+		if err == nil && len(media) != tt.want {
+			t.Errorf("%s: got %d files, want %d", tt.name, len(media), tt.want)
+		}
+
+		_, err = ms.FeedbackFile("s1", m.ID, tt.netId)
+		if tt.err == nil && tt.want == 0 && !errors.Is(err, dal.ERR_RECORD_NOT_FOUND) {
+			t.Errorf("%s: got %v, want the file hidden", tt.name, err)
+		}
+	}
+
+	err = ms.RemoveFeedbackMedia("s1", m.ID, "abc123")
+	if !errors.Is(err, ERR_NOT_PERMITTED) {
+		t.Errorf("got %v, want %v for the student", err, ERR_NOT_PERMITTED)
+	}
+
+	err = ms.RemoveFeedbackMedia("s1", m.ID, "prof")
+	if err != nil || len(store.feedback["s1"]) != 0 {
+		t.Errorf("got %v with %d files left, want it removed", err, len(store.feedback["s1"]))
+	}
+}
+
+// ========= //
+//   MOCKS   //
+// ========= //
+
+// mockFeedbackStore has submission "s1" by "abc123" to assignment "a1"
+// in course "c1", taught by "prof".
+type mockFeedbackStore struct {
+	mockMediaStore
+
+	assignment *models.Assignment
+	feedback   map[string][]*models.Media
+}
+
+func newMockFeedbackStore() *mockFeedbackStore {
+	return &mockFeedbackStore{
+		mockMediaStore: mockMediaStore{
+			assignments: map[string]string{"a1": "c1"},
+			submissions: map[string]string{"s1": "a1"},
+			submitters:  map[string]string{"s1": "abc123"},
+		},
+		assignment: &models.Assignment{Post: models.Post{Entity: models.Entity{ID: "a1"}}},
+		feedback:   make(map[string][]*models.Media),
+	}
+}
+
+func (m *mockFeedbackStore) GetAssignmentById(assignmentid string) (
+	*models.Assignment,
+	error,
+) {
+	return m.assignment, nil
+}
+
+func (m *mockFeedbackStore) IsCourseTeacher(courseId, netId string) (
+	bool,
+	error,
+) {
+	return courseId == "c1" && netId == "prof", nil
+}
+
+func (m *mockFeedbackStore) InsertMedia(media *models.Media) (
+	*models.Media,
+	error,
+) {
+	media.ID = "m1"
+	return media, nil
+}
+
+func (m *mockFeedbackStore) InsertMediaIntoFeedback(media *models.Media) error {
+	submissionId := media.AttributionsByType["feedback"]
+	m.feedback[submissionId] = append(m.feedback[submissionId], media)
+	return nil
+}
+
+func (m *mockFeedbackStore) GetFeedbackMedia(submissionId string) (
+	[]*models.Media,
+	error,
+) {
+	return m.feedback[submissionId], nil
+}
+
+func (m *mockFeedbackStore) DeleteFeedbackMedia(submissionId, mediaId string) error {
+	var kept []*models.Media
+	for _, media := range m.feedback[submissionId] {
+		if media.ID != mediaId {
+			kept = append(kept, media)
+		}
+	}
+	m.feedback[submissionId] = kept
+	return nil
+}
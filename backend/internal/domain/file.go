@@ -1,52 +1,125 @@
 package domain
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"io"
-	"mime/multipart"
 	"os"
+
+	"github.com/n30w/Darkspace/internal/models"
 )
 
 type FileStore interface {
 	CreateFile(path string) (*os.File, string, error)
 	CopyFile(f1 io.Writer, f2 io.Reader) error
+	OpenFile(path string) (io.ReadSeekCloser, error)
+	ListFiles() ([]models.Blob, error)
+	StatFile(path string) (*models.Blob, error)
+	RemoveFile(path string) error
 	fmt.Stringer
 }
 
 type FileService struct {
-	store FileStore
+	store   FileStore
+	scanner Scanner
 }
 
 func NewFileService(store FileStore) *FileService { return &FileService{store: store} }
 
+// SetScanner sets the scanner that saved files are checked with.
+// Without a scanner, files are saved as unscanned.
+func (fs *FileService) SetScanner(s Scanner) {
+	fs.scanner = s
+}
+
 // Save saves a file to disk. This is used for incoming
-// files from the handlers. It returns a path to where the
-// file was saved and an error.
-func (fs *FileService) Save(name string, in multipart.File) (string, error) {
+// files from the handlers, such as a multipart.File, or
+// files generated by the server. Once written, the file is scanned
+// for malware. It returns a path to where the file was saved, the
+// outcome of the scan and an error. A file that cannot be scanned
+// is removed and not saved at all.
+func (fs *FileService) Save(name string, in io.Reader) (
+	string,
+	*models.ScanResult,
+	error,
+) {
 	f, p, err := fs.store.CreateFile(name)
+	if err != nil {
+		return "", nil, err
+	}
+
+	err = fs.store.CopyFile(f, in)
+	f.Close()
+	if err != nil {
+		return "", nil, err
+	}
+
+	result, err := fs.scan(p)
+	if err != nil {
+		_ = fs.store.RemoveFile(p)
+		return "", nil, err
+	}
+
+	return p, result, nil
+}
+
+// scan scans a saved file, reading it back from the store so that
+// it is never held in memory as a whole.
+func (fs *FileService) scan(p string) (*models.ScanResult, error) {
+	if fs.scanner == nil {
+		return &models.ScanResult{Status: models.UNSCANNED}, nil
+	}
+
+	f, err := fs.store.OpenFile(p)
+	if err != nil {
+		return nil, err
+	}
+
+	defer f.Close()
+
+	return fs.scanner.Scan(f)
+}
+
+// Checksum is the SHA-256 of a saved file, in hex. The file is read
+// back from the store as it is hashed.
+func (fs *FileService) Checksum(p string) (string, error) {
+	f, err := fs.store.OpenFile(p)
 	if err != nil {
 		return "", err
 	}
 
 	defer f.Close()
 
-	err = fs.store.CopyFile(f, in)
+	h := sha256.New()
+
+	_, err = io.Copy(h, f)
 	if err != nil {
 		return "", err
 	}
 
-	return p, nil
+	return hex.EncodeToString(h.Sum(nil)), nil
 }
 
-// GetFile opens a file at the specified path and returns it.
-func (fs *FileService) GetFile(path string) (*os.File, error) {
-	// Open the file at the specified path
-	file, err := os.Open(path)
+// Open opens a file at the specified path for reading, along with
+// its size and modification time. The file must be closed by
+// the caller.
+func (fs *FileService) Open(path string) (
+	io.ReadSeekCloser,
+	*models.Blob,
+	error,
+) {
+	blob, err := fs.store.StatFile(path)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+
+	f, err := fs.store.OpenFile(path)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	return file, nil
+	return f, blob, nil
 }
 
 func (fs *FileService) Path() string {
@@ -0,0 +1,117 @@
+package domain
+
+import (
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/n30w/Darkspace/internal/dal"
+	"github.com/n30w/Darkspace/internal/models"
+)
+
+func TestFileService_Save(t *testing.T) {
+	tests := []struct {
+		name    string
+		scanner Scanner
+		want    models.ScanStatus
+		wantErr bool
+	}{
+		{
+			name: "no scanner",
+			want: models.UNSCANNED,
+		},
+		{
+			name:    "clean",
+			scanner: &mockScanner{},
+			want:    models.CLEAN,
+		},
+		{
+			name:    "infected",
+			scanner: &mockScanner{threat: "Eicar-Signature"},
+			want:    models.QUARANTINED,
+		},
+		{
+			name:    "scanner unavailable",
+			scanner: &mockScanner{err: errors.New("connection refused")},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				volume := dal.NewLocalVolume(t.TempDir())
+
+				err := os.MkdirAll(volume.String(), 0o755)
+				if err != nil {
+					t.Fatalf("%+v", err)
+				}
+
+				fs := NewFileService(volume)
+				if tt.scanner != nil {
+					fs.SetScanner(tt.scanner)
+				}
+
+				p, scan, err := fs.Save("essay.txt", strings.NewReader("an essay"))
+
+				if tt.wantErr {
+					if err == nil {
+						t.Fatalf("expected an error")
+					}
+
+					// A file that could not be scanned is not kept.
+					files, _ := volume.ListFiles()
+					if len(files) != 0 {
+						t.Errorf("got %v, want no files", files)
+					}
+					return
+				}
+
+				if err != nil {
+					t.Fatalf("%+v", err)
+				}
+
+				if scan.Status != tt.want {
+					t.Errorf("got %s, want %s", scan.Status, tt.want)
+				}
+
+				// Quarantined files are kept for inspection.
+				_, err = os.Stat(p)
+				if err != nil {
+					t.Errorf("%+v", err)
+				}
+			},
+		)
+	}
+}
+
+// ========= //
+//   MOCKS   //
+// ========= //
+
+type mockScanner struct {
+	threat string
+	err    error
+}
+
+func (m *mockScanner) Scan(r io.Reader) (*models.ScanResult, error) {
+	_, err := io.Copy(io.Discard, r)
+	if err != nil {
+		return nil, err
+	}
+
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	if m.threat != "" {
+		return &models.ScanResult{
+			Status: models.QUARANTINED,
+			Threat: m.threat,
+		}, nil
+	}
+
+	return &models.ScanResult{Status: models.CLEAN}, nil
+}
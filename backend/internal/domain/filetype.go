@@ -0,0 +1,215 @@
+package domain
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/n30w/Darkspace/internal/models"
+)
+
+// sniffLength is the amount of bytes read from the start of a file
+// to determine its type.
+const sniffLength = 512
+
+// maxNotebookSize is the largest Jupyter notebook that will be decoded
+// when validating its structure.
+const maxNotebookSize = 32 << 20
+
+// SniffFileType determines the type of file using its content rather
+// than its name. Zip based documents, such as DOCX, PPTX and XLSX, are
+// told apart by their archive entries, which is why the full file is
+// required via an io.ReaderAt. Text that cannot be placed in a more
+// specific type is reported as TXT.
+func SniffFileType(r io.ReaderAt, size int64) models.FileType {
+	header := make([]byte, sniffLength)
+
+	n, err := r.ReadAt(header, 0)
+	if err != nil && err != io.EOF {
+		return models.NULL
+	}
+
+	header = header[:n]
+
+	switch {
+	case bytes.HasPrefix(header, []byte{0xFF, 0xD8, 0xFF}):
+		return models.JPG
+	case bytes.HasPrefix(header, []byte("\x89PNG\r\n\x1a\n")):
+		return models.PNG
+	case bytes.HasPrefix(header, []byte("%PDF-")):
+		return models.PDF
+	case bytes.HasPrefix(header, []byte("ID3")),
+		len(header) > 1 && header[0] == 0xFF && header[1]&0xE0 == 0xE0:
+		return models.MP3
+	case len(header) >= 12 && string(header[4:8]) == "ftyp":
+		brand := string(header[8:12])
+		if brand == "M4A " || brand == "M4B " {
+			return models.M4A
+		}
+		return models.MP4
+	case bytes.HasPrefix(header, []byte("PK\x03\x04")),
+		bytes.HasPrefix(header, []byte("PK\x05\x06")):
+		return sniffArchive(r, size)
+	case isText(header):
+		trimmed := bytes.TrimLeft(header, " \t\r\n")
+		if bytes.HasPrefix(trimmed, []byte("{")) && isNotebook(r, size) {
+			return models.IPYNB
+		}
+		return models.TXT
+	}
+
+	return models.NULL
+}
+
+// sniffArchive looks at the entries of a zip archive to determine
+// whether it is an Office Open XML document or a plain archive.
+func sniffArchive(r io.ReaderAt, size int64) models.FileType {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return models.NULL
+	}
+
+	for _, f := range zr.File {
+		switch {
+		case strings.HasPrefix(f.Name, "word/"):
+			return models.DOCX
+		case strings.HasPrefix(f.Name, "ppt/"):
+			return models.PPTX
+		case strings.HasPrefix(f.Name, "xl/"):
+			return models.XLSX
+		}
+	}
+
+	return models.ZIP
+}
+
+// isText reports whether a sample of bytes looks like UTF-8 text. A
+// multibyte rune cut off at the end of the sample is tolerated.
+func isText(sample []byte) bool {
+	if bytes.IndexByte(sample, 0) != -1 {
+		return false
+	}
+
+	for len(sample) > 0 {
+		r, size := utf8.DecodeRune(sample)
+		if r == utf8.RuneError && size == 1 {
+			return len(sample) < utf8.UTFMax && !utf8.FullRune(sample)
+		}
+		sample = sample[size:]
+	}
+
+	return true
+}
+
+// isNotebook reports whether the content is a Jupyter notebook, which
+// is a JSON document with an nbformat version and a list of cells.
+func isNotebook(r io.ReaderAt, size int64) bool {
+	if size > maxNotebookSize {
+		return false
+	}
+
+	var nb struct {
+		Format *int              `json:"nbformat"`
+		Cells  []json.RawMessage `json:"cells"`
+	}
+
+	err := json.NewDecoder(io.NewSectionReader(r, 0, size)).Decode(&nb)
+	if err != nil {
+		return false
+	}
+
+	return nb.Format != nil && nb.Cells != nil
+}
+
+// contentMatches reports whether sniffed content is acceptable for a
+// declared file type. Plain text content is accepted for any text
+// based type, since source code has no signature of its own.
+func contentMatches(declared, sniffed models.FileType) bool {
+	switch {
+	case declared == sniffed:
+		return true
+	case declared == models.IPYNB:
+		return false
+	case declared.IsText():
+		return sniffed == models.TXT || sniffed == models.IPYNB
+	}
+
+	return false
+}
+
+// FilePolicy decides which types of files are accepted for an upload.
+// A policy with no allowed types accepts every known file type.
+type FilePolicy struct {
+	allowed models.FileTypes
+}
+
+// NewFilePolicy creates a policy that accepts only the given types.
+func NewFilePolicy(allowed ...models.FileType) *FilePolicy {
+	return &FilePolicy{allowed: allowed}
+}
+
+// ImagePolicy accepts only image uploads, such as course banners.
+var ImagePolicy = NewFilePolicy(models.JPG, models.PNG)
+
+// FeedbackPolicy accepts any known type of file that teachers attach
+// to their feedback, such as audio comments and marked up documents.
+var FeedbackPolicy = NewFilePolicy()
+
+// ModulePolicy accepts any known type of file that teachers add to a
+// course's modules, such as slides and readings.
+var ModulePolicy = NewFilePolicy()
+
+// Allows reports whether the policy accepts a file type.
+func (p *FilePolicy) Allows(ft models.FileType) bool {
+	if ft == models.NULL {
+		return false
+	}
+
+	return len(p.allowed) == 0 || p.allowed.Contains(ft)
+}
+
+// Inspect validates an uploaded file against the policy. The declared
+// type is taken from the file name, checked against the allowed types,
+// then checked against the type sniffed from the file's content. The
+// validated type is returned.
+func (p *FilePolicy) Inspect(
+	name string,
+	r io.ReaderAt,
+	size int64,
+) (models.FileType, error) {
+	declared := models.FileTypeFromName(name)
+	if declared == models.NULL {
+		return models.NULL, fmt.Errorf("%w: %s", ERR_UNKNOWN_FILE_TYPE, name)
+	}
+
+	if !p.Allows(declared) {
+		return models.NULL, fmt.Errorf(
+			"%w: %s, allowed types are %s",
+			ERR_FILE_TYPE_NOT_ALLOWED,
+			declared,
+			p.allowed,
+		)
+	}
+
+	sniffed := SniffFileType(r, size)
+	if !contentMatches(declared, sniffed) {
+		detected := sniffed.String()
+		if sniffed == models.NULL {
+			detected = "unknown"
+		}
+
+		return models.NULL, fmt.Errorf(
+			"%w: %s declared as %s, detected %s",
+			ERR_FILE_TYPE_MISMATCH,
+			name,
+			declared,
+			detected,
+		)
+	}
+
+	return declared, nil
+}
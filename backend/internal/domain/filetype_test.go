@@ -0,0 +1,176 @@
+package domain
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/n30w/Darkspace/internal/models"
+)
+
+// zipWith creates an in memory zip archive containing empty files
+// with the given names.
+func zipWith(t *testing.T, names ...string) []byte {
+	t.Helper()
+
+	buf := &bytes.Buffer{}
+	zw := zip.NewWriter(buf)
+
+	for _, name := range names {
+		_, err := zw.Create(name)
+		if err != nil {
+			t.Fatalf("%+v", err)
+		}
+	}
+
+	err := zw.Close()
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+
+	return buf.Bytes()
+}
+
+func TestSniffFileType(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want models.FileType
+	}{
+		{"jpg", []byte{0xFF, 0xD8, 0xFF, 0xE0, 0x00}, models.JPG},
+		{"png", []byte("\x89PNG\r\n\x1a\n\x00\x00"), models.PNG},
+		{"pdf", []byte("%PDF-1.7\n"), models.PDF},
+		{"mp3", []byte("ID3\x04\x00"), models.MP3},
+		{"m4a", []byte("\x00\x00\x00\x20ftypM4A \x00\x00"), models.M4A},
+		{"mp4", []byte("\x00\x00\x00\x20ftypisom\x00\x00"), models.MP4},
+		{"docx", zipWith(t, "[Content_Types].xml", "word/document.xml"), models.DOCX},
+		{"pptx", zipWith(t, "ppt/presentation.xml"), models.PPTX},
+		{"xlsx", zipWith(t, "xl/workbook.xml"), models.XLSX},
+		{"zip", zipWith(t, "main.go"), models.ZIP},
+		{"text", []byte("package main\n\nfunc main() {}\n"), models.TXT},
+		{"utf8", []byte("héllo wörld"), models.TXT},
+		{"notebook", []byte(`{"cells": [], "nbformat": 4}`), models.IPYNB},
+		{"json", []byte(`{"cells": []}`), models.TXT},
+		{"binary", []byte{0x00, 0x01, 0x02, 0x03}, models.NULL},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				got := SniffFileType(bytes.NewReader(tt.data), int64(len(tt.data)))
+				if got != tt.want {
+					t.Errorf("got %s, want %s", got, tt.want)
+				}
+			},
+		)
+	}
+}
+
+func TestFilePolicy_Inspect(t *testing.T) {
+	pdf := []byte("%PDF-1.7\n")
+	source := []byte("print('hello')\n")
+
+	tests := []struct {
+		name     string
+		policy   *FilePolicy
+		fileName string
+		data     []byte
+		want     models.FileType
+		wantErr  error
+	}{
+		{
+			name:     "allowed",
+			policy:   NewFilePolicy(models.PDF),
+			fileName: "essay.pdf",
+			data:     pdf,
+			want:     models.PDF,
+		},
+		{
+			name:     "any known type",
+			policy:   NewFilePolicy(),
+			fileName: "hello.py",
+			data:     source,
+			want:     models.PY,
+		},
+		{
+			name:     "unknown extension",
+			policy:   NewFilePolicy(),
+			fileName: "essay.exe",
+			data:     pdf,
+			want:     models.NULL,
+			wantErr:  ERR_UNKNOWN_FILE_TYPE,
+		},
+		{
+			name:     "not allowed",
+			policy:   NewFilePolicy(models.DOCX),
+			fileName: "essay.pdf",
+			data:     pdf,
+			want:     models.NULL,
+			wantErr:  ERR_FILE_TYPE_NOT_ALLOWED,
+		},
+		{
+			name:     "renamed file",
+			policy:   NewFilePolicy(models.PDF),
+			fileName: "essay.pdf",
+			data:     source,
+			want:     models.NULL,
+			wantErr:  ERR_FILE_TYPE_MISMATCH,
+		},
+		{
+			name:     "image policy",
+			policy:   ImagePolicy,
+			fileName: "banner.png",
+			data:     pdf,
+			want:     models.NULL,
+			wantErr:  ERR_FILE_TYPE_MISMATCH,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				got, err := tt.policy.Inspect(
+					tt.fileName,
+					bytes.NewReader(tt.data),
+					int64(len(tt.data)),
+				)
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("got error %v, want %v", err, tt.wantErr)
+				}
+
+				if got != tt.want {
+					t.Errorf("got %s, want %s", got, tt.want)
+				}
+			},
+		)
+	}
+}
+
+func TestAssignmentService_SetFileTypes(t *testing.T) {
+	store := newMockAssignmentStore()
+	as := NewAssignmentService(store)
+
+	fileTypes := models.FileTypes{models.PDF}
+
+	tests := []struct {
+		name  string
+		netId string
+		want  error
+		types int
+	}{
+		{name: "student", netId: "stu1", want: ERR_NOT_PERMITTED},
+		{name: "teacher", netId: "prof", types: 1},
+	}
+
+	for _, tt := range tests {
+		_, err := as.SetFileTypes("a1", tt.netId, fileTypes)
+		if !errors.Is(err, tt.want) {
+			t.Fatalf("%s: got %v, want %v", tt.name, err, tt.want)
+		}
+
+		if got := len(store.assignments["a1"].FileTypes); got != tt.types {
+			t.Errorf("%s: got %d file types, want %d", tt.name, got, tt.types)
+		}
+	}
+}
@@ -0,0 +1,292 @@
+package domain
+
+import (
+	"github.com/n30w/Darkspace/internal/models"
+)
+
+type GradebookStore interface {
+	InsertGradeCategory(c *models.GradeCategory) error
+	GetGradeCategoryById(id string) (*models.GradeCategory, error)
+	GetGradeCategories(courseId string) ([]*models.GradeCategory, error)
+	UpdateGradeCategory(c *models.GradeCategory) error
+	DeleteGradeCategory(id string) error
+
+	GetGradeItems(courseId string) ([]*models.GradeItem, error)
+	SaveGradeItem(item *models.GradeItem) error
+	GetGradeScheme(courseId string) (models.GradeScheme, error)
+	SaveGradeScheme(courseId string, scheme models.GradeScheme) error
+	GetCourseGrades(courseId string, releasedOnly bool) (map[string]map[string]float64, error)
+
+	GetAssignmentById(assignmentid string) (*models.Assignment, error)
+	GetCourseIdByAssignment(assignmentId string) (string, error)
+	GetRoster(courseid string) ([]models.User, error)
+	IsCourseTeacher(courseId, netId string) (bool, error)
+	IsCourseStudent(courseId, netId string) (bool, error)
+}
+
+type GradebookService struct {
+	store GradebookStore
+}
+
+func NewGradebookService(s GradebookStore) *GradebookService {
+	return &GradebookService{store: s}
+}
+
+// CreateCategory creates a grade category in a course. Only the
+// course's teachers may change the gradebook.
+func (gs *GradebookService) CreateCategory(
+	c *models.GradeCategory,
+	netId string,
+) (*models.GradeCategory, error) {
+	err := teacherOnly(gs.store, c.CourseId, netId)
+	if err != nil {
+		return nil, err
+	}
+
+	err = gs.store.InsertGradeCategory(c)
+	if err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// UpdateCategory changes a grade category's name, weight and how
+// many of its lowest scores are dropped.
+func (gs *GradebookService) UpdateCategory(
+	c *models.GradeCategory,
+	netId string,
+) (*models.GradeCategory, error) {
+	existing, err := gs.store.GetGradeCategoryById(c.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = teacherOnly(gs.store, existing.CourseId, netId)
+	if err != nil {
+		return nil, err
+	}
+
+	existing.Name = c.Name
+	existing.Weight = c.Weight
+	existing.DropLowest = c.DropLowest
+
+	err = gs.store.UpdateGradeCategory(existing)
+	if err != nil {
+		return nil, err
+	}
+
+	return existing, nil
+}
+
+// DeleteCategory deletes a grade category. Its assignments no longer
+// count towards the course grade until they are given a new category.
+func (gs *GradebookService) DeleteCategory(categoryId, netId string) error {
+	c, err := gs.store.GetGradeCategoryById(categoryId)
+	if err != nil {
+		return err
+	}
+
+	err = teacherOnly(gs.store, c.CourseId, netId)
+	if err != nil {
+		return err
+	}
+
+	return gs.store.DeleteGradeCategory(c.ID)
+}
+
+// SetItem sets the category, points and extra credit of an
+// assignment in the gradebook. The category must belong to the
+// assignment's course. An empty category leaves the assignment out
+// of the course grade.
+func (gs *GradebookService) SetItem(
+	item *models.GradeItem,
+	netId string,
+) (*models.GradeItem, error) {
+	courseId, err := gs.store.GetCourseIdByAssignment(item.AssignmentId)
+	if err != nil {
+		return nil, err
+	}
+
+	err = teacherOnly(gs.store, courseId, netId)
+	if err != nil {
+		return nil, err
+	}
+
+	if item.CategoryId != "" {
+		c, err := gs.store.GetGradeCategoryById(item.CategoryId)
+		if err != nil {
+			return nil, err
+		}
+
+		if c.CourseId != courseId {
+			return nil, ERR_NOT_PERMITTED
+		}
+	}
+
+	err = gs.store.SaveGradeItem(item)
+	if err != nil {
+		return nil, err
+	}
+
+	return item, nil
+}
+
+// SetScheme sets a course's letter grade scheme.
+func (gs *GradebookService) SetScheme(
+	courseId, netId string,
+	scheme models.GradeScheme,
+) error {
+	err := teacherOnly(gs.store, courseId, netId)
+	if err != nil {
+		return err
+	}
+
+	return gs.store.SaveGradeScheme(courseId, scheme)
+}
+
+// ClassGradebook retrieves the grades of every student on a course's
+// roster. Assignments graded blind are left out until their grades are
+// released. Only the course's teachers may see the whole class.
+func (gs *GradebookService) ClassGradebook(courseId, netId string) (
+	*models.Gradebook,
+	error,
+) {
+	err := teacherOnly(gs.store, courseId, netId)
+	if err != nil {
+		return nil, err
+	}
+
+	g, err := gs.gradebook(courseId)
+	if err != nil {
+		return nil, err
+	}
+
+	roster, err := gs.store.GetRoster(courseId)
+	if err != nil {
+		return nil, err
+	}
+
+	grades, err := gs.store.GetCourseGrades(courseId, false)
+	if err != nil {
+		return nil, err
+	}
+
+	err = gs.withoutBlind(grades)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, student := range roster {
+		sg := g.Grade(student.ID, scoresOrEmpty(grades[student.ID]))
+		sg.FullName = student.FullName
+		g.Students = append(g.Students, sg)
+	}
+
+	return g, nil
+}
+
+// StudentGradebook retrieves a student's own grades in a course.
+func (gs *GradebookService) StudentGradebook(courseId, netId string) (
+	*models.Gradebook,
+	error,
+) {
+	if netId == "" {
+		return nil, ERR_NOT_PERMITTED
+	}
+
+	student, err := gs.store.IsCourseStudent(courseId, netId)
+	if err != nil {
+		return nil, err
+	}
+
+	if !student {
+		return nil, ERR_NOT_ENROLLED
+	}
+
+	g, err := gs.gradebook(courseId)
+	if err != nil {
+		return nil, err
+	}
+
+	// Students only see the grades that have been released to them.
+	grades, err := gs.store.GetCourseGrades(courseId, true)
+	if err != nil {
+		return nil, err
+	}
+
+	g.Students = []*models.StudentGrade{
+		g.Grade(netId, scoresOrEmpty(grades[netId])),
+	}
+
+	return g, nil
+}
+
+// withoutBlind takes the scores of assignments that are graded blind
+// out of a course's grades, so that a teacher cannot match the grade
+// they gave a pseudonym to a named student before grades are released.
+func (gs *GradebookService) withoutBlind(
+	grades map[string]map[string]float64,
+) error {
+	blind := make(map[string]bool)
+
+	for _, scores := range grades {
+		for assignmentId := range scores {
+			if _, ok := blind[assignmentId]; !ok {
+				a, err := gs.store.GetAssignmentById(assignmentId)
+				if err != nil {
+					return err
+				}
+
+				blind[assignmentId] = a.Blind()
+			}
+
+			if blind[assignmentId] {
+				delete(scores, assignmentId)
+			}
+		}
+	}
+
+	return nil
+}
+
+// gradebook retrieves a course's grading setup, without any grades.
+func (gs *GradebookService) gradebook(courseId string) (
+	*models.Gradebook,
+	error,
+) {
+	categories, err := gs.store.GetGradeCategories(courseId)
+	if err != nil {
+		return nil, err
+	}
+
+	items, err := gs.store.GetGradeItems(courseId)
+	if err != nil {
+		return nil, err
+	}
+
+	scheme, err := gs.store.GetGradeScheme(courseId)
+	if err != nil {
+		return nil, err
+	}
+
+	if scheme == nil {
+		scheme = models.DefaultGradeScheme()
+	}
+
+	return &models.Gradebook{
+		CourseId:   courseId,
+		Categories: categories,
+		Items:      items,
+		Scheme:     scheme,
+	}, nil
+}
+
+// scoresOrEmpty keeps students without any grades from being sent
+// back with null scores.
+func scoresOrEmpty(scores map[string]float64) map[string]float64 {
+	if scores == nil {
+		return make(map[string]float64)
+	}
+	return scores
+}
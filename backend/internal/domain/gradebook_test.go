@@ -0,0 +1,264 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/n30w/Darkspace/internal/dal"
+	"github.com/n30w/Darkspace/internal/models"
+)
+
+func TestGradebookService_ClassGradebook(t *testing.T) {
+	store := newMockGradebookStore()
+	gs := NewGradebookService(store)
+
+	_, err := gs.ClassGradebook("c1", "stu1")
+	if !errors.Is(err, ERR_NOT_PERMITTED) {
+		t.Errorf("got %v, want %v for a student", err, ERR_NOT_PERMITTED)
+	}
+
+	g, err := gs.ClassGradebook("c1", "prof")
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+
+	if len(g.Students) != 2 {
+		t.Fatalf("got %d students, want 2", len(g.Students))
+	}
+
+	if len(g.Scheme) == 0 {
+		t.Errorf("want the default scheme when the course has none")
+	}
+
+	stu1, stu2 := g.Students[0], g.Students[1]
+
+	if stu1.Final == nil || *stu1.Final != 85 || stu1.FullName != "Student One" {
+		t.Errorf("got %s with final %v, want Student One with 85", stu1.FullName, stu1.Final)
+	}
+
+	// stu2 has no grades yet.
+	if stu2.Running != nil || stu2.Scores == nil {
+		t.Errorf("got running %v and scores %v, want nil and empty", stu2.Running, stu2.Scores)
+	}
+}
+
+func TestGradebookService_ClassGradebookBlind(t *testing.T) {
+	store := newMockGradebookStore()
+	store.grades["stu1"]["a2"] = 40
+	store.assignments["a2"].Anonymous = true
+
+	gs := NewGradebookService(store)
+
+	g, err := gs.ClassGradebook("c1", "prof")
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+
+	if _, ok := g.Students[0].Scores["a2"]; ok {
+		t.Errorf("got %v, want the blind assignment left out", g.Students[0].Scores)
+	}
+
+	store.assignments["a2"].GradesReleased = true
+
+	g, err = gs.ClassGradebook("c1", "prof")
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+
+	if got := g.Students[0].Scores["a2"]; got != 40 {
+		t.Errorf("got %v, want 40 once grades are released", got)
+	}
+}
+
+func TestGradebookService_StudentGradebook(t *testing.T) {
+	tests := []struct {
+		name  string
+		netId string
+		want  error
+	}{
+		{name: "student", netId: "stu1"},
+		{name: "not enrolled", netId: "outsider", want: ERR_NOT_ENROLLED},
+		{name: "anonymous", netId: "", want: ERR_NOT_PERMITTED},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				g, err := NewGradebookService(newMockGradebookStore()).StudentGradebook("c1", tt.netId)
+
+				if !errors.Is(err, tt.want) {
+					t.Fatalf("got %v, want %v", err, tt.want)
+				}
+
+				if tt.want == nil && (len(g.Students) != 1 || g.Students[0].NetId != tt.netId) {
+					t.Errorf("got students %v, want only %s", g.Students, tt.netId)
+				}
+			},
+		)
+	}
+}
+
+func TestGradebookService_SetItem(t *testing.T) {
+	tests := []struct {
+		name       string
+		categoryId string
+		netId      string
+		want       error
+	}{
+		{name: "set category", categoryId: "hw", netId: "prof"},
+		{name: "remove category", categoryId: "", netId: "prof"},
+		{name: "student", categoryId: "hw", netId: "stu1", want: ERR_NOT_PERMITTED},
+		{name: "category from another course", categoryId: "other", netId: "prof", want: ERR_NOT_PERMITTED},
+		{name: "missing category", categoryId: "gone", netId: "prof", want: dal.ERR_RECORD_NOT_FOUND},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				store := newMockGradebookStore()
+
+				item := &models.GradeItem{
+					AssignmentId: "a1",
+					CategoryId:   tt.categoryId,
+					Points:       20,
+				}
+
+				_, err := NewGradebookService(store).SetItem(item, tt.netId)
+
+				if !errors.Is(err, tt.want) {
+					t.Fatalf("got %v, want %v", err, tt.want)
+				}
+
+				if tt.want == nil && store.items[0].CategoryId != tt.categoryId {
+					t.Errorf("got category %q, want %q", store.items[0].CategoryId, tt.categoryId)
+				}
+			},
+		)
+	}
+}
+
+// ========= //
+//   MOCKS   //
+// ========= //
+
+// mockGradebookStore has a course "c1" taught by "prof" with students
+// stu1 and stu2. Its only category "hw" holds assignment "a1", which
+// stu1 scored 85 out of 100 on. Assignment "a2" is not in the
+// gradebook. Category "other" belongs to another course.
+type mockGradebookStore struct {
+	GradebookStore
+
+	categories  map[string]*models.GradeCategory
+	items       []*models.GradeItem
+	assignments map[string]*models.Assignment
+	grades      map[string]map[string]float64
+}
+
+func newMockGradebookStore() *mockGradebookStore {
+	assignments := make(map[string]*models.Assignment)
+	for _, id := range []string{"a1", "a2"} {
+		assignments[id] = &models.Assignment{Post: models.Post{Entity: models.Entity{ID: id}}}
+	}
+
+	return &mockGradebookStore{
+		assignments: assignments,
+		grades: map[string]map[string]float64{
+			"stu1": {"a1": 85},
+		},
+		categories: map[string]*models.GradeCategory{
+			"hw": {
+				Entity:   models.Entity{ID: "hw"},
+				CourseId: "c1",
+				Name:     "Homework",
+				Weight:   100,
+			},
+			"other": {
+				Entity:   models.Entity{ID: "other"},
+				CourseId: "c2",
+			},
+		},
+		items: []*models.GradeItem{
+			{AssignmentId: "a1", CategoryId: "hw", Points: 100},
+		},
+	}
+}
+
+func (m *mockGradebookStore) GetGradeCategoryById(id string) (
+	*models.GradeCategory,
+	error,
+) {
+	c, ok := m.categories[id]
+	if !ok {
+		return nil, dal.ERR_RECORD_NOT_FOUND
+	}
+	return c, nil
+}
+
+func (m *mockGradebookStore) GetGradeCategories(courseId string) (
+	[]*models.GradeCategory,
+	error,
+) {
+	return []*models.GradeCategory{m.categories["hw"]}, nil
+}
+
+func (m *mockGradebookStore) GetGradeItems(courseId string) (
+	[]*models.GradeItem,
+	error,
+) {
+	return m.items, nil
+}
+
+func (m *mockGradebookStore) SaveGradeItem(item *models.GradeItem) error {
+	m.items = []*models.GradeItem{item}
+	return nil
+}
+
+func (m *mockGradebookStore) GetGradeScheme(courseId string) (
+	models.GradeScheme,
+	error,
+) {
+	return nil, nil
+}
+
+func (m *mockGradebookStore) GetCourseGrades(courseId string, releasedOnly bool) (
+	map[string]map[string]float64,
+	error,
+) {
+	grades := make(map[string]map[string]float64)
+	for netId, scores := range m.grades {
+		grades[netId] = make(map[string]float64)
+		for id, score := range scores {
+			grades[netId][id] = score
+		}
+	}
+	return grades, nil
+}
+
+func (m *mockGradebookStore) GetAssignmentById(assignmentid string) (
+	*models.Assignment,
+	error,
+) {
+	return m.assignments[assignmentid], nil
+}
+
+func (m *mockGradebookStore) GetCourseIdByAssignment(assignmentId string) (
+	string,
+	error,
+) {
+	return "c1", nil
+}
+
+func (m *mockGradebookStore) GetRoster(courseid string) ([]models.User, error) {
+	return []models.User{
+		{Entity: models.Entity{ID: "stu1"}, FullName: "Student One"},
+		{Entity: models.Entity{ID: "stu2"}, FullName: "Student Two"},
+	}, nil
+}
+
+func (m *mockGradebookStore) IsCourseTeacher(courseId, netId string) (bool, error) {
+	return courseId == "c1" && netId == "prof", nil
+}
+
+func (m *mockGradebookStore) IsCourseStudent(courseId, netId string) (bool, error) {
+	return courseId == "c1" && (netId == "stu1" || netId == "stu2"), nil
+}
@@ -0,0 +1,350 @@
+package domain
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"io"
+
+	"github.com/n30w/Darkspace/internal/models"
+)
+
+// maxImageDimension is the largest width or height of an image that
+// will be decoded. Checking this before decoding prevents small files
+// from expanding into enormous images in memory.
+const maxImageDimension = 8000
+
+// jpegQuality is the quality used when re-encoding JPEG variants.
+const jpegQuality = 85
+
+// imageSpec describes the bounding box of a resized image variant.
+// When crop is set, the image is cropped around its center to fill the
+// box exactly. Otherwise, the image is scaled to fit inside of it.
+// Images are never scaled up.
+type imageSpec struct {
+	width, height int
+	crop          bool
+}
+
+// ImagePipeline validates and resizes uploaded images. Every image
+// that passes through the pipeline is decoded and re-encoded, which
+// strips EXIF and any other metadata from the original file.
+type ImagePipeline struct {
+	minWidth, minHeight int
+	variants            map[models.ImageSize]imageSpec
+}
+
+// BannerPipeline processes course banners, keeping their aspect ratio.
+var BannerPipeline = &ImagePipeline{
+	minWidth:  640,
+	minHeight: 160,
+	variants: map[models.ImageSize]imageSpec{
+		models.THUMBNAIL: {width: 320, height: 180},
+		models.CARD:      {width: 640, height: 360},
+		models.FULL:      {width: 1920, height: 1080},
+	},
+}
+
+// ProfilePicturePipeline processes profile pictures into squares.
+var ProfilePicturePipeline = &ImagePipeline{
+	minWidth:  64,
+	minHeight: 64,
+	variants: map[models.ImageSize]imageSpec{
+		models.THUMBNAIL: {width: 64, height: 64, crop: true},
+		models.CARD:      {width: 256, height: 256, crop: true},
+		models.FULL:      {width: 1024, height: 1024, crop: true},
+	},
+}
+
+// ProcessedImage holds the encoded variants of an image, all of which
+// share the same file type.
+type ProcessedImage struct {
+	FileType models.FileType
+	Variants map[models.ImageSize][]byte
+}
+
+// Size is the total size of every variant in bytes.
+func (pi *ProcessedImage) Size() int64 {
+	var size int64
+	for _, b := range pi.Variants {
+		size += int64(len(b))
+	}
+	return size
+}
+
+// Process reads an image of a given type, validates its dimensions,
+// applies its EXIF orientation and produces every variant of the
+// pipeline.
+func (ip *ImagePipeline) Process(
+	r io.Reader,
+	ft models.FileType,
+) (*ProcessedImage, error) {
+	if !ft.IsImage() {
+		return nil, fmt.Errorf("%w: %s", ERR_FILE_TYPE_NOT_ALLOWED, ft)
+	}
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ERR_INVALID_IMAGE, err)
+	}
+
+	if cfg.Width > maxImageDimension || cfg.Height > maxImageDimension {
+		return nil, fmt.Errorf(
+			"%w: %dx%d exceeds %dx%d",
+			ERR_INVALID_IMAGE,
+			cfg.Width, cfg.Height,
+			maxImageDimension, maxImageDimension,
+		)
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ERR_INVALID_IMAGE, err)
+	}
+
+	if ft == models.JPG {
+		src = orient(src, jpegOrientation(data))
+	}
+
+	b := src.Bounds()
+	if b.Dx() < ip.minWidth || b.Dy() < ip.minHeight {
+		return nil, fmt.Errorf(
+			"%w: %dx%d is smaller than %dx%d",
+			ERR_INVALID_IMAGE,
+			b.Dx(), b.Dy(),
+			ip.minWidth, ip.minHeight,
+		)
+	}
+
+	processed := &ProcessedImage{
+		FileType: ft,
+		Variants: make(map[models.ImageSize][]byte),
+	}
+
+	for size, spec := range ip.variants {
+		buf := &bytes.Buffer{}
+
+		err = encodeImage(buf, spec.apply(src), ft)
+		if err != nil {
+			return nil, err
+		}
+
+		processed.Variants[size] = buf.Bytes()
+	}
+
+	return processed, nil
+}
+
+// encodeImage writes an image to w in the format of a file type.
+func encodeImage(w io.Writer, img image.Image, ft models.FileType) error {
+	switch ft {
+	case models.JPG:
+		return jpeg.Encode(w, img, &jpeg.Options{Quality: jpegQuality})
+	case models.PNG:
+		return png.Encode(w, img)
+	}
+
+	return fmt.Errorf("%w: %s", ERR_FILE_TYPE_NOT_ALLOWED, ft)
+}
+
+// apply crops and scales an image according to the spec.
+func (s imageSpec) apply(src image.Image) image.Image {
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+
+	if s.crop {
+		// Crop the largest centered region with the spec's aspect ratio.
+		cw, ch := w, w*s.height/s.width
+		if ch > h {
+			cw, ch = h*s.width/s.height, h
+		}
+
+		x0 := b.Min.X + (w-cw)/2
+		y0 := b.Min.Y + (h-ch)/2
+		b = image.Rect(x0, y0, x0+cw, y0+ch)
+		w, h = cw, ch
+	}
+
+	// Fit inside of the bounding box, without scaling up.
+	dw, dh := w, h
+	if dw > s.width {
+		dw, dh = s.width, h*s.width/w
+	}
+	if dh > s.height {
+		dw, dh = w*s.height/h, s.height
+	}
+
+	return resize(src, b, max(dw, 1), max(dh, 1))
+}
+
+// resize scales the region r of src to a new image of w by h pixels.
+// Each destination pixel is the average of the source pixels it
+// covers, which gives smooth results when shrinking an image.
+func resize(src image.Image, r image.Rectangle, w, h int) *image.RGBA {
+	rgba := image.NewRGBA(image.Rect(0, 0, r.Dx(), r.Dy()))
+	draw.Draw(rgba, rgba.Bounds(), src, r.Min, draw.Src)
+
+	if w == r.Dx() && h == r.Dy() {
+		return rgba
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	sw, sh := r.Dx(), r.Dy()
+
+	for y := 0; y < h; y++ {
+		y0, y1 := y*sh/h, max((y+1)*sh/h, y*sh/h+1)
+
+		for x := 0; x < w; x++ {
+			x0, x1 := x*sw/w, max((x+1)*sw/w, x*sw/w+1)
+
+			var sum [4]int
+			for sy := y0; sy < y1; sy++ {
+				row := rgba.Pix[sy*rgba.Stride:]
+				for sx := x0; sx < x1; sx++ {
+					for c := 0; c < 4; c++ {
+						sum[c] += int(row[sx*4+c])
+					}
+				}
+			}
+
+			n := (y1 - y0) * (x1 - x0)
+			i := dst.PixOffset(x, y)
+			for c := 0; c < 4; c++ {
+				dst.Pix[i+c] = uint8(sum[c] / n)
+			}
+		}
+	}
+
+	return dst
+}
+
+// jpegOrientation finds the EXIF orientation tag of a JPEG file. It
+// returns 1, the default orientation, if there is no such tag.
+func jpegOrientation(data []byte) int {
+	const (
+		markerSOI      = 0xD8
+		markerAPP1     = 0xE1
+		markerSOS      = 0xDA
+		tagOrientation = 0x0112
+	)
+
+	if len(data) < 4 || data[0] != 0xFF || data[1] != markerSOI {
+		return 1
+	}
+
+	// Walk the JPEG segments until the EXIF segment is found.
+	for i := 2; i+4 <= len(data); {
+		if data[i] != 0xFF {
+			return 1
+		}
+
+		marker := data[i+1]
+		length := int(binary.BigEndian.Uint16(data[i+2:]))
+
+		if marker == markerSOS || i+2+length > len(data) {
+			return 1
+		}
+
+		segment := data[i+4 : i+2+length]
+		i += 2 + length
+
+		if marker != markerAPP1 || !bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			continue
+		}
+
+		tiff := segment[6:]
+		if len(tiff) < 8 {
+			return 1
+		}
+
+		var order binary.ByteOrder
+		switch string(tiff[:2]) {
+		case "II":
+			order = binary.LittleEndian
+		case "MM":
+			order = binary.BigEndian
+		default:
+			return 1
+		}
+
+		ifd := int(order.Uint32(tiff[4:]))
+		if ifd+2 > len(tiff) {
+			return 1
+		}
+
+		entries := int(order.Uint16(tiff[ifd:]))
+		for e := 0; e < entries; e++ {
+			entry := ifd + 2 + e*12
+			if entry+12 > len(tiff) {
+				return 1
+			}
+
+			if order.Uint16(tiff[entry:]) == tagOrientation {
+				o := int(order.Uint16(tiff[entry+8:]))
+				if o < 1 || o > 8 {
+					return 1
+				}
+				return o
+			}
+		}
+
+		return 1
+	}
+
+	return 1
+}
+
+// orient transforms an image so that it is displayed upright, using
+// an EXIF orientation value from 1 to 8.
+func orient(src image.Image, orientation int) image.Image {
+	if orientation <= 1 || orientation > 8 {
+		return src
+	}
+
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+
+	// Orientations 5 through 8 swap the width and height.
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var dx, dy int
+
+			switch orientation {
+			case 2: // Mirrored horizontally.
+				dx, dy = w-1-x, y
+			case 3: // Rotated 180 degrees.
+				dx, dy = w-1-x, h-1-y
+			case 4: // Mirrored vertically.
+				dx, dy = x, h-1-y
+			case 5: // Mirrored along the top-left diagonal.
+				dx, dy = y, x
+			case 6: // Rotated 90 degrees clockwise.
+				dx, dy = h-1-y, x
+			case 7: // Mirrored along the top-right diagonal.
+				dx, dy = h-1-y, w-1-x
+			case 8: // Rotated 90 degrees counterclockwise.
+				dx, dy = y, w-1-x
+			}
+
+			dst.Set(dx, dy, src.At(b.Min.X+x, b.Min.Y+y))
+		}
+	}
+
+	return dst
+}
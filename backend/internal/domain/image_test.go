@@ -0,0 +1,145 @@
+package domain
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"github.com/n30w/Darkspace/internal/models"
+)
+
+// newTestImage creates an image with a red left half and a blue
+// right half, to make orientation changes visible.
+func newTestImage(w, h int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			c := color.RGBA{R: 255, A: 255}
+			if x >= w/2 {
+				c = color.RGBA{B: 255, A: 255}
+			}
+			img.Set(x, y, c)
+		}
+	}
+	return img
+}
+
+func TestImagePipeline_Process(t *testing.T) {
+	buf := &bytes.Buffer{}
+	err := png.Encode(buf, newTestImage(2000, 1000))
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+
+	got, err := BannerPipeline.Process(buf, models.PNG)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+
+	want := map[models.ImageSize]image.Point{
+		models.THUMBNAIL: {320, 160},
+		models.CARD:      {640, 320},
+		models.FULL:      {1920, 960},
+	}
+
+	for size, dims := range want {
+		cfg, err := png.DecodeConfig(bytes.NewReader(got.Variants[size]))
+		if err != nil {
+			t.Fatalf("%s: %+v", size, err)
+		}
+
+		if cfg.Width != dims.X || cfg.Height != dims.Y {
+			t.Errorf(
+				"%s: got %dx%d, want %dx%d",
+				size, cfg.Width, cfg.Height, dims.X, dims.Y,
+			)
+		}
+	}
+}
+
+func TestImagePipeline_ProcessCrop(t *testing.T) {
+	buf := &bytes.Buffer{}
+	err := jpeg.Encode(buf, newTestImage(300, 200), nil)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+
+	got, err := ProfilePicturePipeline.Process(buf, models.JPG)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+
+	// The full variant is never scaled up, so it is the largest
+	// centered square of the original.
+	cfg, err := jpeg.DecodeConfig(bytes.NewReader(got.Variants[models.FULL]))
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+
+	if cfg.Width != 200 || cfg.Height != 200 {
+		t.Errorf("got %dx%d, want 200x200", cfg.Width, cfg.Height)
+	}
+}
+
+func TestImagePipeline_ProcessTooSmall(t *testing.T) {
+	buf := &bytes.Buffer{}
+	err := png.Encode(buf, newTestImage(100, 100))
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+
+	_, err = BannerPipeline.Process(buf, models.PNG)
+	if !errors.Is(err, ERR_INVALID_IMAGE) {
+		t.Errorf("got %v, want %v", err, ERR_INVALID_IMAGE)
+	}
+}
+
+func TestJpegOrientation(t *testing.T) {
+	// A minimal JPEG header with a big endian EXIF segment holding
+	// a single orientation entry of 6.
+	exif := []byte{
+		'E', 'x', 'i', 'f', 0, 0,
+		'M', 'M', 0, 42, 0, 0, 0, 8,
+		0, 1,
+		0x01, 0x12, 0, 3, 0, 0, 0, 1, 0, 6, 0, 0,
+		0, 0, 0, 0,
+	}
+	length := len(exif) + 2
+
+	data := []byte{0xFF, 0xD8, 0xFF, 0xE1, byte(length >> 8), byte(length)}
+	data = append(data, exif...)
+	data = append(data, 0xFF, 0xDA, 0, 2)
+
+	if got := jpegOrientation(data); got != 6 {
+		t.Errorf("got %d, want 6", got)
+	}
+
+	if got := jpegOrientation([]byte{0xFF, 0xD8, 0xFF, 0xDA, 0, 2}); got != 1 {
+		t.Errorf("got %d, want 1", got)
+	}
+}
+
+func TestOrient(t *testing.T) {
+	src := newTestImage(4, 2)
+
+	// Rotating 90 degrees clockwise moves the red left half to the top.
+	got := orient(src, 6)
+
+	if b := got.Bounds(); b.Dx() != 2 || b.Dy() != 4 {
+		t.Fatalf("got %dx%d, want 2x4", b.Dx(), b.Dy())
+	}
+
+	r, _, b, _ := got.At(0, 0).RGBA()
+	if r == 0 || b != 0 {
+		t.Errorf("top left is not red")
+	}
+
+	r, _, b, _ = got.At(0, 3).RGBA()
+	if r != 0 || b == 0 {
+		t.Errorf("bottom left is not blue")
+	}
+}
@@ -0,0 +1,231 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/n30w/Darkspace/internal/models"
+)
+
+// SetSchedule changes an assignment's state, and when it is published,
+// available from and locked. Publishing a draft by hand publishes it
+// now, and a schedule that has already passed takes effect at once.
+// The course's students are notified when a draft is published. Only
+// the course's teachers may change the schedule.
+func (as *AssignmentService) SetSchedule(
+	assignmentid, netId string,
+	schedule models.Schedule,
+) (*models.Assignment, error) {
+	assignment, err := teacherAssignment(as.store, assignmentid, netId)
+	if err != nil {
+		return nil, err
+	}
+
+	now := as.now()
+	wasDraft := assignment.IsDraft()
+
+	assignment.Schedule = schedule
+	assignment.PublishAt = utc(schedule.PublishAt)
+	assignment.AvailableFrom = utc(schedule.AvailableFrom)
+	assignment.LockAt = utc(schedule.LockAt)
+
+	if wasDraft && !assignment.IsDraft() {
+		published := now.UTC()
+		assignment.PublishAt = &published
+	}
+
+	lastLock, err := as.lastLock(assignment, now)
+	if err != nil {
+		return nil, err
+	}
+
+	advance(assignment, now, lastLock)
+
+	err = as.saveSchedule(assignment, wasDraft)
+	if err != nil {
+		return nil, err
+	}
+
+	return assignment, nil
+}
+
+// AdvanceDue publishes the drafts whose publish time has come, and
+// locks the assignments whose lock time has. It returns how many
+// assignments were published and how many were locked.
+func (as *AssignmentService) AdvanceDue(now time.Time) (
+	published, locked int,
+	err error,
+) {
+	ids, err := as.store.GetAssignmentsDueForSchedule(now)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	for _, id := range ids {
+		assignment, err := as.store.GetAssignmentById(id)
+		if err != nil {
+			return published, locked, err
+		}
+
+		state := assignment.State
+		wasDraft := assignment.IsDraft()
+
+		lastLock, err := as.lastLock(assignment, now)
+		if err != nil {
+			return published, locked, err
+		}
+
+		advance(assignment, now, lastLock)
+
+		if assignment.State == state {
+			continue
+		}
+
+		err = as.saveSchedule(assignment, wasDraft)
+		if err != nil {
+			return published, locked, err
+		}
+
+		if wasDraft {
+			published++
+		}
+
+		if assignment.State == models.ASSIGNMENT_LOCKED {
+			locked++
+		}
+	}
+
+	return published, locked, nil
+}
+
+// Hidden checks if an assignment is hidden from a user. Drafts are
+// hidden from everyone but the course's teachers.
+func (as *AssignmentService) Hidden(a *models.Assignment, netId string) (
+	bool,
+	error,
+) {
+	if !a.IsDraft() {
+		return false, nil
+	}
+
+	courseId, err := as.store.GetCourseIdByAssignment(a.ID)
+	if err != nil {
+		return false, err
+	}
+
+	teacher, err := isTeacher(as.store, courseId, netId)
+	if err != nil {
+		return false, err
+	}
+
+	return !teacher, nil
+}
+
+// saveSchedule keeps an assignment's schedule, and tells the students
+// of its course about it if it was a draft that has been published.
+func (as *AssignmentService) saveSchedule(
+	assignment *models.Assignment,
+	wasDraft bool,
+) error {
+	err := as.store.SaveSchedule(assignment)
+	if err != nil {
+		return err
+	}
+
+	if !wasDraft || assignment.IsDraft() {
+		return nil
+	}
+
+	courseId, err := as.store.GetCourseIdByAssignment(assignment.ID)
+	if err != nil {
+		return err
+	}
+
+	roster, err := as.store.GetRoster(courseId)
+	if err != nil {
+		return err
+	}
+
+	students := make([]string, len(roster))
+	for i, student := range roster {
+		students[i] = student.ID
+	}
+
+	return notify(
+		as.store,
+		students,
+		models.ASSIGNMENT_POSTED,
+		fmt.Sprintf("%s has been published", assignment.Title),
+		assignment.ID,
+	)
+}
+
+// advance publishes an assignment and locks it, if its schedule says
+// it should be by a time. It is not locked before lastLock, the latest
+// lock date of any of its students, when there is one. The time it was
+// published at is kept.
+func advance(a *models.Assignment, now time.Time, lastLock *time.Time) {
+	if a.PublishDue(now) {
+		published := now.UTC()
+		a.State = models.ASSIGNMENT_PUBLISHED
+		a.PublishAt = &published
+	}
+
+	if a.LockDue(now) && (lastLock == nil || !now.Before(*lastLock)) {
+		a.State = models.ASSIGNMENT_LOCKED
+	}
+}
+
+// lastLock is the latest lock date of an assignment's students, as
+// moved by their extensions and accommodations. Until it passes, the
+// assignment is not locked, and each student is held to their own lock
+// date instead. It is only looked up once the assignment's lock date
+// has passed.
+func (as *AssignmentService) lastLock(a *models.Assignment, now time.Time) (
+	*time.Time,
+	error,
+) {
+	if !a.LockDue(now) {
+		return nil, nil
+	}
+
+	courseId, err := as.store.GetCourseIdByAssignment(a.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	extensions, err := as.store.GetExtensions(a.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	accommodations, err := as.store.GetAccommodations(courseId)
+	if err != nil {
+		return nil, err
+	}
+
+	last := *a.LockAt
+
+	for _, e := range extensions {
+		if lockAt := a.For(e, nil).LockAt; lockAt.After(last) {
+			last = *lockAt
+		}
+	}
+
+	for _, acc := range accommodations {
+		if lockAt := a.For(nil, acc).LockAt; lockAt.After(last) {
+			last = *lockAt
+		}
+	}
+
+	return &last, nil
+}
+
+func utc(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+
+	u := t.UTC()
+	return &u
+}
@@ -0,0 +1,228 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/n30w/Darkspace/internal/models"
+)
+
+func TestAssignmentService_AdvanceDue(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	past := now.Add(-time.Hour)
+	future := now.Add(time.Hour)
+
+	store := newMockAssignmentStore()
+	store.assignments["a1"].Schedule = models.Schedule{
+		State:     models.ASSIGNMENT_DRAFT,
+		PublishAt: &past,
+		LockAt:    &future,
+	}
+	store.assignments["a2"].Schedule = models.Schedule{
+		State:  models.ASSIGNMENT_PUBLISHED,
+		LockAt: &past,
+	}
+
+	published, locked, err := NewAssignmentService(store).AdvanceDue(now)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+
+	if published != 1 || locked != 1 {
+		t.Errorf("got %d published and %d locked, want 1 and 1", published, locked)
+	}
+
+	if got := store.assignments["a1"].State; got != models.ASSIGNMENT_PUBLISHED {
+		t.Errorf("got a1 %s, want %s", got, models.ASSIGNMENT_PUBLISHED)
+	}
+
+	if got := store.assignments["a2"].State; got != models.ASSIGNMENT_LOCKED {
+		t.Errorf("got a2 %s, want %s", got, models.ASSIGNMENT_LOCKED)
+	}
+
+	if len(store.notifications) != 2 {
+		t.Fatalf("got %d notifications, want one per student", len(store.notifications))
+	}
+
+	for _, n := range store.notifications {
+		if n.Kind != models.ASSIGNMENT_POSTED || n.Ref != "a1" {
+			t.Errorf("got %s notification for %s, want %s for a1", n.Kind, n.Ref, models.ASSIGNMENT_POSTED)
+		}
+	}
+
+	published, locked, err = NewAssignmentService(store).AdvanceDue(now)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+
+	if published != 0 || locked != 0 {
+		t.Errorf("got %d published and %d locked again, want none", published, locked)
+	}
+}
+
+func TestAssignmentService_AdvanceDueWithExtension(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	due := now.Add(-2 * time.Hour)
+	lock := now.Add(-time.Hour)
+
+	store := newMockAssignmentStore()
+	store.assignments["a1"].DueDate = due
+	store.assignments["a1"].Schedule = models.Schedule{
+		State:  models.ASSIGNMENT_PUBLISHED,
+		LockAt: &lock,
+	}
+	store.extensions = []*models.Extension{
+		{AssignmentId: "a1", NetId: "stu1", DueDate: due.Add(3 * time.Hour)},
+	}
+
+	as := NewAssignmentService(store)
+
+	_, locked, err := as.AdvanceDue(now)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+
+	if locked != 0 {
+		t.Errorf("got %d locked, want none while stu1's lock date is ahead", locked)
+	}
+
+	_, locked, err = as.AdvanceDue(now.Add(2 * time.Hour))
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+
+	if locked != 1 || store.assignments["a1"].State != models.ASSIGNMENT_LOCKED {
+		t.Errorf("got %d locked, want a1 locked once stu1's lock date passed", locked)
+	}
+}
+
+func TestAssignmentService_SetSchedule(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	later := time.Date(2024, 5, 2, 8, 0, 0, 0, time.FixedZone("EDT", -4*60*60))
+
+	store := newMockAssignmentStore()
+	as := NewAssignmentService(store)
+	as.now = func() time.Time { return now }
+
+	draft := models.Schedule{State: models.ASSIGNMENT_DRAFT, PublishAt: &later}
+
+	_, err := as.SetSchedule("a1", "stu1", draft)
+	if !errors.Is(err, ERR_NOT_PERMITTED) {
+		t.Fatalf("got %v, want %v", err, ERR_NOT_PERMITTED)
+	}
+
+	a, err := as.SetSchedule("a1", "prof", draft)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+
+	if !a.IsDraft() || a.PublishAt.Location() != time.UTC || !a.PublishAt.Equal(later) {
+		t.Errorf("got %s at %v, want a draft published at %v in UTC", a.State, a.PublishAt, later)
+	}
+
+	if len(store.notifications) != 0 {
+		t.Errorf("got %d notifications, want none for a draft", len(store.notifications))
+	}
+
+	a, err = as.SetSchedule("a1", "prof", models.Schedule{State: models.ASSIGNMENT_PUBLISHED})
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+
+	if a.IsDraft() || a.PublishAt == nil || !a.PublishAt.Equal(now) {
+		t.Errorf("got %s at %v, want published now", a.State, a.PublishAt)
+	}
+
+	if len(store.notifications) != 2 {
+		t.Errorf("got %d notifications, want one per student", len(store.notifications))
+	}
+
+	past := now.Add(-time.Minute)
+
+	a, err = as.SetSchedule("a1", "prof", models.Schedule{State: models.ASSIGNMENT_PUBLISHED, LockAt: &past})
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+
+	if a.State != models.ASSIGNMENT_LOCKED {
+		t.Errorf("got %s, want a lock date that passed to lock it", a.State)
+	}
+
+	if len(store.notifications) != 2 {
+		t.Errorf("got %d notifications, want students notified once", len(store.notifications))
+	}
+}
+
+func TestAssignmentService_Hidden(t *testing.T) {
+	store := newMockAssignmentStore()
+	store.assignments["a1"].State = models.ASSIGNMENT_DRAFT
+
+	as := NewAssignmentService(store)
+
+	tests := []struct {
+		id    string
+		netId string
+		want  bool
+	}{
+		{id: "a1", netId: "prof", want: false},
+		{id: "a1", netId: "stu1", want: true},
+		{id: "a1", netId: "", want: true},
+		{id: "a2", netId: "stu1", want: false},
+		{id: "a2", netId: "", want: false},
+	}
+
+	for _, tt := range tests {
+		hidden, err := as.Hidden(store.assignments[tt.id], tt.netId)
+		if err != nil {
+			t.Fatalf("%+v", err)
+		}
+
+		if hidden != tt.want {
+			t.Errorf("got %s hidden from %q %v, want %v", tt.id, tt.netId, hidden, tt.want)
+		}
+	}
+}
+
+// ========= //
+//   MOCKS   //
+// ========= //
+
+func (m *mockAssignmentStore) SaveSchedule(a *models.Assignment) error {
+	m.assignments[a.ID] = a
+	return nil
+}
+
+func (m *mockAssignmentStore) GetAssignmentsDueForSchedule(now time.Time) (
+	[]string,
+	error,
+) {
+	var ids []string
+	for _, id := range []string{"a1", "a2"} {
+		a := m.assignments[id]
+		if a.PublishDue(now) || a.LockDue(now) {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+func (m *mockAssignmentStore) GetExtensions(assignmentId string) (
+	[]*models.Extension,
+	error,
+) {
+	var extensions []*models.Extension
+	for _, e := range m.extensions {
+		if e.AssignmentId == assignmentId {
+			extensions = append(extensions, e)
+		}
+	}
+	return extensions, nil
+}
+
+func (m *mockAssignmentStore) GetAccommodations(courseId string) (
+	[]*models.Accommodation,
+	error,
+) {
+	return nil, nil
+}
@@ -1,6 +1,9 @@
 package domain
 
 import (
+	"errors"
+
+	"github.com/n30w/Darkspace/internal/dal"
 	"github.com/n30w/Darkspace/internal/models"
 )
 
@@ -11,19 +14,46 @@ type MediaStore interface {
 	InsertMediaIntoCourse(m *models.Media) error
 	InsertMediaIntoAssignment(m *models.Media) error
 	InsertMediaIntoSubmission(m *models.Media) error
+	InsertMediaIntoTeam(m *models.Media) error
 	InsertMediaIntoCourseBanner(m *models.Media) error
+	GetMediaVariant(parentId string, size models.ImageSize) (*models.Media, error)
+	UpdateUserProfilePicture(netId string, mediaId string) error
+	GetCourseBannerId(courseId string) (string, error)
+	DeleteMediaFromCourse(courseId, mediaId string) error
+	GetCourseStorageUsage(courseId string) (int64, error)
+	GetUserStorageUsage(netId string) (int64, error)
+	GetCourseIdByAssignment(assignmentId string) (string, error)
+	GetCourseIdByTeam(teamId string) (string, error)
+	GetAssignmentIdBySubmission(submissionId string) (string, error)
+	GetSubmissionById(submissionId string) (*models.Submission, error)
+	GetAssignmentById(assignmentid string) (*models.Assignment, error)
+	IsCourseTeacher(courseId, netId string) (bool, error)
+	InsertMediaIntoFeedback(m *models.Media) error
+	GetFeedbackMedia(submissionId string) ([]*models.Media, error)
+	DeleteFeedbackMedia(submissionId, mediaId string) error
 }
 
 type MediaService struct {
 	store MediaStore
+	quota models.StorageQuota
 }
 
 func NewMediaService(m MediaStore) *MediaService { return &MediaService{store: m} }
 
+// AddBanner records a new banner for a course, replacing the old
+// one. The old banner is unlinked from the course so that it can be
+// garbage collected.
 func (ms *MediaService) AddBanner(
 	media *models.Media,
 ) (*models.Media, error) {
-	media, err := ms.store.InsertMedia(media)
+	courseId := media.AttributionsByType["course"]
+
+	previous, err := ms.store.GetCourseBannerId(courseId)
+	if err != nil {
+		return nil, err
+	}
+
+	media, err = ms.store.InsertMedia(media)
 	if err != nil {
 		return nil, err
 	}
@@ -37,9 +67,51 @@ func (ms *MediaService) AddBanner(
 		return nil, err
 	}
 
+	if previous != "" {
+		err = ms.store.DeleteMediaFromCourse(courseId, previous)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return media, nil
+}
+
+// AddProfilePicture records a new piece of media and sets it as
+// a user's profile picture.
+func (ms *MediaService) AddProfilePicture(
+	media *models.Media,
+	netId string,
+) (*models.Media, error) {
+	media, err := ms.store.InsertMedia(media)
+	if err != nil {
+		return nil, err
+	}
+
+	err = ms.store.UpdateUserProfilePicture(netId, media.ID)
+	if err != nil {
+		return nil, err
+	}
+
 	return media, nil
 }
 
+// AddImageVariant records a resized variant of an image that has
+// already been added.
+func (ms *MediaService) AddImageVariant(
+	parent *models.Media,
+	variant *models.Media,
+) (*models.Media, error) {
+	variant.ParentId = parent.ID
+
+	variant, err := ms.store.InsertMedia(variant)
+	if err != nil {
+		return nil, err
+	}
+
+	return variant, nil
+}
+
 func (ms *MediaService) AddAssignmentMedia(
 	media *models.Media,
 ) (*models.Media, error) {
@@ -68,6 +140,23 @@ func (ms *MediaService) AddSubmissionMedia(
 	return media, nil
 }
 
+// AddTeamMedia records a file shared with a project team.
+func (ms *MediaService) AddTeamMedia(
+	media *models.Media,
+) (*models.Media, error) {
+	media, err := ms.store.InsertMedia(media)
+	if err != nil {
+		return nil, err
+	}
+
+	err = ms.store.InsertMediaIntoTeam(media)
+	if err != nil {
+		return nil, err
+	}
+
+	return media, nil
+}
+
 // GetMedia retrieves a piece of media from a file system given a path.
 // It does two things: finds a piece of media in the database by its
 // path and, if it does find it, returns it as a struct representation.
@@ -87,3 +176,25 @@ func (ms *MediaService) GetMedia(id string) (*models.Media, error) {
 
 	return media, nil
 }
+
+// GetImage retrieves a specific size of an image. The full size is the
+// original media record. Images uploaded before variants existed, or
+// default images, fall back to the full size.
+func (ms *MediaService) GetImage(
+	id string,
+	size models.ImageSize,
+) (*models.Media, error) {
+	if size == models.FULL || id == models.DefaultImageId {
+		return ms.GetMedia(id)
+	}
+
+	media, err := ms.store.GetMediaVariant(id, size)
+	if err != nil {
+		if errors.Is(err, dal.ERR_RECORD_NOT_FOUND) {
+			return ms.GetMedia(id)
+		}
+		return nil, err
+	}
+
+	return media, nil
+}
@@ -15,6 +15,16 @@ type MessageStore interface {
 	ChangeMessageTitle(m *models.Message) (*models.Message, error)
 	ChangeMessageBody(m *models.Message) (*models.Message, error)
 	GetMessagesByCourse(courseid string) ([]string, error)
+	UpdateMessage(m *models.Message) error
+	UpdateMessageModeration(m *models.Message) error
+	GetDiscussionsByCourse(courseId string, includeHidden bool, f models.Filters) ([]*models.Message, models.Metadata, error)
+	IsCourseTeacher(courseId, netId string) (bool, error)
+	InsertComment(c *models.Comment) error
+	GetCommentById(id string) (*models.Comment, error)
+	GetComments(messageId string, includeHidden bool, f models.Filters) ([]*models.Comment, models.Metadata, error)
+	UpdateCommentBody(c *models.Comment) error
+	UpdateCommentHidden(c *models.Comment) error
+	DeleteComment(id string) error
 }
 
 type MessageService struct {
@@ -0,0 +1,855 @@
+package domain
+
+import (
+	"github.com/n30w/Darkspace/internal/dal"
+	"github.com/n30w/Darkspace/internal/models"
+)
+
+type ModuleStore interface {
+	GetAssignmentById(assignmentid string) (*models.Assignment, error)
+	GetCourseIdByAssignment(assignmentId string) (string, error)
+	GetMessageById(messageid string) (*models.Message, error)
+	GetSubmissions(assignmentId string) ([]*models.Submission, error)
+	GetRoster(courseid string) ([]models.User, error)
+	IsCourseTeacher(courseId, netId string) (bool, error)
+	IsCourseStudent(courseId, netId string) (bool, error)
+	InsertMedia(m *models.Media) (*models.Media, error)
+	GetMediaById(mediaId string) (*models.Media, error)
+
+	InsertModule(m *models.Module) error
+	GetModuleById(id string) (*models.Module, error)
+	GetModules(courseId string) ([]*models.Module, error)
+	UpdateModule(m *models.Module) error
+	DeleteModule(id string) error
+	SaveModuleOrder(courseId string, ids []string) error
+
+	InsertModuleItem(i *models.ModuleItem) error
+	GetModuleItemById(id string) (*models.ModuleItem, error)
+	UpdateModuleItem(i *models.ModuleItem) error
+	DeleteModuleItem(id string) error
+	SaveModuleItemOrder(moduleId string, ids []string) error
+
+	InsertModuleCompletion(c *models.ModuleCompletion) error
+	GetModuleCompletions(courseId string) ([]*models.ModuleCompletion, error)
+}
+
+type ModuleService struct {
+	store ModuleStore
+}
+
+func NewModuleService(s ModuleStore) *ModuleService {
+	return &ModuleService{store: s}
+}
+
+// CreateModule adds a module to the end of its course. Its
+// prerequisites must be modules of the course. Only the course's
+// teachers may create modules.
+func (ms *ModuleService) CreateModule(m *models.Module, netId string) (
+	*models.Module,
+	error,
+) {
+	err := teacherOnly(ms.store, m.CourseId, netId)
+	if err != nil {
+		return nil, err
+	}
+
+	modules, err := ms.store.GetModules(m.CourseId)
+	if err != nil {
+		return nil, err
+	}
+
+	m.Position = len(modules)
+	m.Items = []*models.ModuleItem{}
+
+	err = checkPrerequisites(m, modules)
+	if err != nil {
+		return nil, err
+	}
+
+	err = ms.store.InsertModule(m)
+	if err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// UpdateModule changes a module's title, whether it is published and
+// its prerequisites, which must come before it in the course. Only the
+// course's teachers may change it.
+func (ms *ModuleService) UpdateModule(m *models.Module, netId string) (
+	*models.Module,
+	error,
+) {
+	old, err := ms.teacherModule(m.ID, netId)
+	if err != nil {
+		return nil, err
+	}
+
+	modules, err := ms.store.GetModules(old.CourseId)
+	if err != nil {
+		return nil, err
+	}
+
+	old.Title = m.Title
+	old.Published = m.Published
+	old.Prerequisites = m.Prerequisites
+
+	err = checkPrerequisites(old, modules)
+	if err != nil {
+		return nil, err
+	}
+
+	err = ms.store.UpdateModule(old)
+	if err != nil {
+		return nil, err
+	}
+
+	return old, nil
+}
+
+// DeleteModule removes a module along with its items. The items'
+// assignments, announcements and files are kept, and modules that
+// required it no longer do. Only the course's teachers may delete it.
+func (ms *ModuleService) DeleteModule(id, netId string) error {
+	m, err := ms.teacherModule(id, netId)
+	if err != nil {
+		return err
+	}
+
+	err = ms.store.DeleteModule(m.ID)
+	if err != nil {
+		return err
+	}
+
+	modules, err := ms.store.GetModules(m.CourseId)
+	if err != nil {
+		return err
+	}
+
+	return ms.store.SaveModuleOrder(m.CourseId, moduleIds(modules))
+}
+
+// MoveModule moves a module to a position in its course, returning the
+// course's modules in their new order. Modules must stay after their
+// prerequisites. Only the course's teachers may move modules.
+func (ms *ModuleService) MoveModule(id string, position int, netId string) (
+	[]*models.Module,
+	error,
+) {
+	m, err := ms.teacherModule(id, netId)
+	if err != nil {
+		return nil, err
+	}
+
+	modules, err := ms.store.GetModules(m.CourseId)
+	if err != nil {
+		return nil, err
+	}
+
+	modules = moveTo(modules, m.ID, position, func(m *models.Module) string {
+		return m.ID
+	})
+
+	for i, module := range modules {
+		module.Position = i
+	}
+
+	for _, module := range modules {
+		err = checkPrerequisites(module, modules)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = ms.store.SaveModuleOrder(m.CourseId, moduleIds(modules))
+	if err != nil {
+		return nil, err
+	}
+
+	return modules, nil
+}
+
+// AddItem adds an item to the end of a module. Assignments and
+// announcements must be of the module's course. Files are added by
+// uploading them, with AddFile. Only the course's teachers may add
+// items.
+func (ms *ModuleService) AddItem(item *models.ModuleItem, netId string) (
+	*models.ModuleItem,
+	error,
+) {
+	m, err := ms.teacherModule(item.ModuleId, netId)
+	if err != nil {
+		return nil, err
+	}
+
+	err = ms.checkRef(m.CourseId, item)
+	if err != nil {
+		return nil, err
+	}
+
+	item.Position = len(m.Items)
+
+	err = ms.store.InsertModuleItem(item)
+	if err != nil {
+		return nil, err
+	}
+
+	return item, nil
+}
+
+// CheckModule checks that a user may add to a module, which only the
+// course's teachers may do, returning the module's course. It lets an
+// upload be refused before its file is saved.
+func (ms *ModuleService) CheckModule(moduleId, netId string) (string, error) {
+	m, err := ms.teacherModule(moduleId, netId)
+	if err != nil {
+		return "", err
+	}
+
+	return m.CourseId, nil
+}
+
+// AddFile records a file uploaded to a module, and adds it to the end
+// of the module as an item. Only the course's teachers may add files.
+func (ms *ModuleService) AddFile(
+	media *models.Media,
+	item *models.ModuleItem,
+	netId string,
+) (*models.ModuleItem, error) {
+	m, err := ms.teacherModule(item.ModuleId, netId)
+	if err != nil {
+		return nil, err
+	}
+
+	media, err = ms.store.InsertMedia(media)
+	if err != nil {
+		return nil, err
+	}
+
+	item.Kind = models.MODULE_FILE
+	item.RefId = media.ID
+	item.Position = len(m.Items)
+
+	err = ms.store.InsertModuleItem(item)
+	if err != nil {
+		return nil, err
+	}
+
+	return item, nil
+}
+
+// UpdateItem changes an item's title, requirement and what it refers
+// to, but not its kind. Files keep their file, since a new file is
+// uploaded as a new item. Only the course's teachers may change items.
+func (ms *ModuleService) UpdateItem(item *models.ModuleItem, netId string) (
+	*models.ModuleItem,
+	error,
+) {
+	old, err := ms.store.GetModuleItemById(item.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	m, err := ms.teacherModule(old.ModuleId, netId)
+	if err != nil {
+		return nil, err
+	}
+
+	item.ModuleId = old.ModuleId
+	item.Position = old.Position
+	item.CreatedAt = old.CreatedAt
+
+	if item.Kind != old.Kind {
+		return nil, ERR_KIND_CHANGED
+	}
+
+	if old.Kind == models.MODULE_FILE {
+		item.RefId = old.RefId
+	} else {
+		err = ms.checkRef(m.CourseId, item)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = ms.store.UpdateModuleItem(item)
+	if err != nil {
+		return nil, err
+	}
+
+	return item, nil
+}
+
+// RemoveItem takes an item out of its module. What it refers to is
+// kept, except for files, which are left to be garbage collected. Only
+// the course's teachers may remove items.
+func (ms *ModuleService) RemoveItem(id, netId string) error {
+	item, err := ms.store.GetModuleItemById(id)
+	if err != nil {
+		return err
+	}
+
+	m, err := ms.teacherModule(item.ModuleId, netId)
+	if err != nil {
+		return err
+	}
+
+	err = ms.store.DeleteModuleItem(item.ID)
+	if err != nil {
+		return err
+	}
+
+	var ids []string
+	for _, i := range m.Items {
+		if i.ID != item.ID {
+			ids = append(ids, i.ID)
+		}
+	}
+
+	return ms.store.SaveModuleItemOrder(m.ID, ids)
+}
+
+// MoveItem moves an item to a position in a module of the same course,
+// which may be the module it is in, returning the module it was moved
+// to. Only the course's teachers may move items.
+func (ms *ModuleService) MoveItem(
+	id, moduleId string,
+	position int,
+	netId string,
+) (*models.Module, error) {
+	item, err := ms.store.GetModuleItemById(id)
+	if err != nil {
+		return nil, err
+	}
+
+	from, err := ms.teacherModule(item.ModuleId, netId)
+	if err != nil {
+		return nil, err
+	}
+
+	to := from
+
+	if moduleId != "" && moduleId != from.ID {
+		to, err = ms.store.GetModuleById(moduleId)
+		if err != nil {
+			return nil, err
+		}
+
+		if to.CourseId != from.CourseId {
+			return nil, ERR_NOT_PERMITTED
+		}
+
+		var rest []*models.ModuleItem
+		for _, i := range from.Items {
+			if i.ID != item.ID {
+				rest = append(rest, i)
+			}
+		}
+		from.Items = rest
+
+		item.ModuleId = to.ID
+		to.Items = append(to.Items, item)
+
+		err = ms.store.SaveModuleItemOrder(from.ID, itemIds(from.Items))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	to.Items = moveTo(to.Items, item.ID, position, func(i *models.ModuleItem) string {
+		return i.ID
+	})
+
+	for i, moved := range to.Items {
+		moved.ModuleId = to.ID
+		moved.Position = i
+	}
+
+	err = ms.store.SaveModuleItemOrder(to.ID, itemIds(to.Items))
+	if err != nil {
+		return nil, err
+	}
+
+	return to, nil
+}
+
+// Modules retrieves a course's modules with their items, in order. The
+// course's teachers see every module. Students see the published ones,
+// without draft assignments or hidden announcements, along with how far
+// they are through each. The items of modules they have not unlocked
+// are listed without their content.
+func (ms *ModuleService) Modules(courseId, netId string) (
+	[]*models.Module,
+	error,
+) {
+	teacher, err := isTeacher(ms.store, courseId, netId)
+	if err != nil {
+		return nil, err
+	}
+
+	modules, err := ms.store.GetModules(courseId)
+	if err != nil {
+		return nil, err
+	}
+
+	if teacher {
+		return modules, nil
+	}
+
+	modules, done, err := ms.studentModules(courseId, netId, modules)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, m := range modules {
+		for _, item := range m.Items {
+			item.Completed = done[item.ID]
+
+			if m.Progress.State == models.MODULE_LOCKED {
+				item.RefId, item.URL, item.Body = "", "", ""
+			}
+		}
+	}
+
+	return modules, nil
+}
+
+// Progress retrieves how far each of a course's students is through
+// each of its published modules. Only the course's teachers may see
+// everyone's progress.
+func (ms *ModuleService) Progress(courseId, netId string) (
+	[]*models.ModuleProgress,
+	error,
+) {
+	err := teacherOnly(ms.store, courseId, netId)
+	if err != nil {
+		return nil, err
+	}
+
+	modules, err := ms.store.GetModules(courseId)
+	if err != nil {
+		return nil, err
+	}
+
+	modules, err = ms.visible(modules)
+	if err != nil {
+		return nil, err
+	}
+
+	done, err := ms.completed(courseId, modules)
+	if err != nil {
+		return nil, err
+	}
+
+	roster, err := ms.store.GetRoster(courseId)
+	if err != nil {
+		return nil, err
+	}
+
+	progress := []*models.ModuleProgress{}
+
+	for _, student := range roster {
+		for _, m := range progressOf(modules, student.ID, done[student.ID]) {
+			progress = append(progress, m.Progress)
+		}
+	}
+
+	return progress, nil
+}
+
+// ViewItem retrieves an item for a user to open. Students may only
+// open the items of modules they have unlocked, and opening one
+// completes it if it only has to be viewed.
+func (ms *ModuleService) ViewItem(id, netId string) (
+	*models.ModuleItem,
+	error,
+) {
+	item, teacher, err := ms.openItem(id, netId)
+	if err != nil {
+		return nil, err
+	}
+
+	if teacher {
+		return item, nil
+	}
+
+	err = ms.store.InsertModuleCompletion(
+		&models.ModuleCompletion{
+			ItemId:      item.ID,
+			NetId:       netId,
+			Requirement: models.REQUIRE_VIEW,
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return item, nil
+}
+
+// ItemFile retrieves the file of an item, viewing the item.
+func (ms *ModuleService) ItemFile(id, netId string) (*models.Media, error) {
+	item, err := ms.ViewItem(id, netId)
+	if err != nil {
+		return nil, err
+	}
+
+	if item.Kind != models.MODULE_FILE {
+		return nil, dal.ERR_RECORD_NOT_FOUND
+	}
+
+	return ms.store.GetMediaById(item.RefId)
+}
+
+// MarkDone lets a student mark an item as done, for items that are
+// completed that way. Students may only mark the items of modules they
+// have unlocked.
+func (ms *ModuleService) MarkDone(id, netId string) (
+	*models.ModuleItem,
+	error,
+) {
+	item, teacher, err := ms.openItem(id, netId)
+	if err != nil {
+		return nil, err
+	}
+
+	if teacher || item.Requirement != models.REQUIRE_MARK_DONE {
+		return nil, ERR_NOT_MARKABLE
+	}
+
+	err = ms.store.InsertModuleCompletion(
+		&models.ModuleCompletion{
+			ItemId:      item.ID,
+			NetId:       netId,
+			Requirement: models.REQUIRE_MARK_DONE,
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	item.Completed = true
+
+	return item, nil
+}
+
+// openItem retrieves an item that a user may open, and whether they
+// are one of the course's teachers, who may open any item.
+func (ms *ModuleService) openItem(id, netId string) (
+	*models.ModuleItem,
+	bool,
+	error,
+) {
+	item, err := ms.store.GetModuleItemById(id)
+	if err != nil {
+		return nil, false, err
+	}
+
+	m, err := ms.store.GetModuleById(item.ModuleId)
+	if err != nil {
+		return nil, false, err
+	}
+
+	teacher, err := isTeacher(ms.store, m.CourseId, netId)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if teacher {
+		return item, true, nil
+	}
+
+	modules, err := ms.store.GetModules(m.CourseId)
+	if err != nil {
+		return nil, false, err
+	}
+
+	modules, _, err = ms.studentModules(m.CourseId, netId, modules)
+	if err != nil {
+		return nil, false, err
+	}
+
+	for _, module := range modules {
+		if module.ID != m.ID || module.Item(item.ID) == nil {
+			continue
+		}
+
+		if module.Progress.State == models.MODULE_LOCKED {
+			return nil, false, ERR_MODULE_LOCKED
+		}
+
+		return item, false, nil
+	}
+
+	return nil, false, dal.ERR_RECORD_NOT_FOUND
+}
+
+// studentModules narrows a course's modules down to those a student
+// may see, with their progress through each, and the items they have
+// completed.
+func (ms *ModuleService) studentModules(
+	courseId, netId string,
+	modules []*models.Module,
+) ([]*models.Module, map[string]bool, error) {
+	student, err := ms.store.IsCourseStudent(courseId, netId)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if !student {
+		return nil, nil, ERR_NOT_PERMITTED
+	}
+
+	modules, err = ms.visible(modules)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	done, err := ms.completed(courseId, modules)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return progressOf(modules, netId, done[netId]), done[netId], nil
+}
+
+// visible narrows modules down to what students may see: the published
+// modules, without draft assignments or hidden announcements.
+func (ms *ModuleService) visible(modules []*models.Module) (
+	[]*models.Module,
+	error,
+) {
+	var published []*models.Module
+
+	for _, m := range modules {
+		if !m.Published {
+			continue
+		}
+
+		var items []*models.ModuleItem
+
+		for _, item := range m.Items {
+			switch item.Kind {
+			case models.MODULE_ASSIGNMENT:
+				a, err := ms.store.GetAssignmentById(item.RefId)
+				if err != nil {
+					return nil, err
+				}
+
+				if a.IsDraft() {
+					continue
+				}
+			case models.MODULE_ANNOUNCEMENT:
+				msg, err := ms.store.GetMessageById(item.RefId)
+				if err != nil {
+					return nil, err
+				}
+
+				if msg.Hidden {
+					continue
+				}
+			}
+
+			items = append(items, item)
+		}
+
+		m.Items = items
+		published = append(published, m)
+	}
+
+	return published, nil
+}
+
+// completed finds the items of modules each student has completed, by
+// the student's Net ID and then the item's ID. Items that require a
+// submission are completed by any attempt at their assignment.
+func (ms *ModuleService) completed(
+	courseId string,
+	modules []*models.Module,
+) (map[string]map[string]bool, error) {
+	done := make(map[string]map[string]bool)
+
+	mark := func(netId, itemId string) {
+		if done[netId] == nil {
+			done[netId] = make(map[string]bool)
+		}
+		done[netId][itemId] = true
+	}
+
+	completions, err := ms.store.GetModuleCompletions(courseId)
+	if err != nil {
+		return nil, err
+	}
+
+	requirements := make(map[string]models.Requirement)
+
+	for _, m := range modules {
+		for _, item := range m.Items {
+			requirements[item.ID] = item.Requirement
+
+			if item.Requirement != models.REQUIRE_SUBMIT {
+				continue
+			}
+
+			submissions, err := ms.store.GetSubmissions(item.RefId)
+			if err != nil {
+				return nil, err
+			}
+
+			for _, s := range submissions {
+				mark(s.User.ID, item.ID)
+			}
+		}
+	}
+
+	for _, c := range completions {
+		if r, ok := requirements[c.ItemId]; ok && c.Meets(r) {
+			mark(c.NetId, c.ItemId)
+		}
+	}
+
+	return done, nil
+}
+
+// progressOf works out a student's progress through modules in order.
+// A module is unlocked once its prerequisites are completed.
+// Prerequisites that the student cannot see do not hold them back.
+func progressOf(
+	modules []*models.Module,
+	netId string,
+	done map[string]bool,
+) []*models.Module {
+	states := make(map[string]models.ModuleState)
+
+	for _, m := range modules {
+		unlocked := true
+
+		for _, id := range m.Prerequisites {
+			state, ok := states[id]
+			if ok && state != models.MODULE_COMPLETED {
+				unlocked = false
+			}
+		}
+
+		m.Progress = m.ProgressOf(netId, done, unlocked)
+		states[m.ID] = m.Progress.State
+	}
+
+	return modules
+}
+
+// checkRef checks that an item refers to an assignment or announcement
+// of the course.
+func (ms *ModuleService) checkRef(courseId string, item *models.ModuleItem) error {
+	switch item.Kind {
+	case models.MODULE_ASSIGNMENT:
+		assignmentCourse, err := ms.store.GetCourseIdByAssignment(item.RefId)
+		if err != nil {
+			return ERR_UNKNOWN_REFERENCE
+		}
+
+		if assignmentCourse != courseId {
+			return ERR_UNKNOWN_REFERENCE
+		}
+	case models.MODULE_ANNOUNCEMENT:
+		msg, err := ms.store.GetMessageById(item.RefId)
+		if err != nil {
+			return ERR_UNKNOWN_REFERENCE
+		}
+
+		if msg.Course != courseId || !msg.Type {
+			return ERR_UNKNOWN_REFERENCE
+		}
+	case models.MODULE_FILE:
+		return ERR_UNKNOWN_REFERENCE
+	}
+
+	return nil
+}
+
+// checkPrerequisites checks that a module's prerequisites are modules
+// of the course that come before it.
+func checkPrerequisites(m *models.Module, modules []*models.Module) error {
+	positions := make(map[string]int)
+	for _, module := range modules {
+		positions[module.ID] = module.Position
+	}
+
+	seen := make(map[string]bool)
+
+	for _, id := range m.Prerequisites {
+		position, ok := positions[id]
+		if !ok || position >= m.Position || seen[id] {
+			return ERR_INVALID_PREREQUISITE
+		}
+
+		seen[id] = true
+	}
+
+	return nil
+}
+
+// teacherModule retrieves a module for a change that only the course's
+// teachers may make.
+func (ms *ModuleService) teacherModule(id, netId string) (
+	*models.Module,
+	error,
+) {
+	m, err := ms.store.GetModuleById(id)
+	if err != nil {
+		return nil, err
+	}
+
+	err = teacherOnly(ms.store, m.CourseId, netId)
+	if err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// moveTo moves the element with an ID to a position in a list, keeping
+// the order of the others. Positions past either end of the list move
+// it to that end.
+func moveTo[T any](list []T, id string, position int, idOf func(T) string) []T {
+	var moved T
+	rest := make([]T, 0, len(list))
+
+	for _, e := range list {
+		if idOf(e) == id {
+			moved = e
+			continue
+		}
+		rest = append(rest, e)
+	}
+
+	if len(rest) == len(list) {
+		return list
+	}
+
+	position = max(0, min(position, len(rest)))
+
+	out := make([]T, 0, len(list))
+	out = append(out, rest[:position]...)
+	out = append(out, moved)
+	out = append(out, rest[position:]...)
+
+	return out
+}
+
+func moduleIds(modules []*models.Module) []string {
+	ids := make([]string, len(modules))
+	for i, m := range modules {
+		ids[i] = m.ID
+	}
+	return ids
+}
+
+func itemIds(items []*models.ModuleItem) []string {
+	ids := make([]string, len(items))
+	for i, item := range items {
+		ids[i] = item.ID
+	}
+	return ids
+}
@@ -0,0 +1,477 @@
+package domain
+
+import (
+	"errors"
+	"sort"
+	"testing"
+
+	"github.com/n30w/Darkspace/internal/dal"
+	"github.com/n30w/Darkspace/internal/models"
+)
+
+func TestModuleService_Manage(t *testing.T) {
+	store := newMockModuleStore()
+	ms := NewModuleService(store)
+
+	_, err := ms.CreateModule(&models.Module{CourseId: "c1", Title: "Week 1"}, "stu1")
+	if !errors.Is(err, ERR_NOT_PERMITTED) {
+		t.Fatalf("got %v, want %v", err, ERR_NOT_PERMITTED)
+	}
+
+	week1, err := ms.CreateModule(&models.Module{CourseId: "c1", Title: "Week 1"}, "prof")
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+
+	week2, err := ms.CreateModule(
+		&models.Module{CourseId: "c1", Title: "Week 2", Prerequisites: []string{week1.ID}},
+		"prof",
+	)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+
+	if week1.Position != 0 || week2.Position != 1 {
+		t.Errorf("got positions %d and %d, want 0 and 1", week1.Position, week2.Position)
+	}
+
+	_, err = ms.UpdateModule(
+		&models.Module{Entity: models.Entity{ID: week1.ID}, Title: "Week 1", Prerequisites: []string{week2.ID}},
+		"prof",
+	)
+	if !errors.Is(err, ERR_INVALID_PREREQUISITE) {
+		t.Errorf("got %v, want %v for a later prerequisite", err, ERR_INVALID_PREREQUISITE)
+	}
+
+	_, err = ms.MoveModule(week2.ID, 0, "prof")
+	if !errors.Is(err, ERR_INVALID_PREREQUISITE) {
+		t.Errorf("got %v, want %v moving before a prerequisite", err, ERR_INVALID_PREREQUISITE)
+	}
+
+	tests := []struct {
+		name string
+		item *models.ModuleItem
+		err  error
+	}{
+		{name: "assignment", item: &models.ModuleItem{Kind: models.MODULE_ASSIGNMENT, RefId: "a1"}},
+		{name: "another course's assignment", item: &models.ModuleItem{Kind: models.MODULE_ASSIGNMENT, RefId: "a9"}, err: ERR_UNKNOWN_REFERENCE},
+		{name: "announcement", item: &models.ModuleItem{Kind: models.MODULE_ANNOUNCEMENT, RefId: "msg1"}},
+		{name: "discussion", item: &models.ModuleItem{Kind: models.MODULE_ANNOUNCEMENT, RefId: "msg2"}, err: ERR_UNKNOWN_REFERENCE},
+		{name: "file without an upload", item: &models.ModuleItem{Kind: models.MODULE_FILE, RefId: "f1"}, err: ERR_UNKNOWN_REFERENCE},
+		{name: "page", item: &models.ModuleItem{Kind: models.MODULE_PAGE, Body: "Read chapter 1"}},
+	}
+
+	for _, tt := range tests {
+		tt.item.ModuleId = week1.ID
+		tt.item.Title = tt.name
+
+		_, err := ms.AddItem(tt.item, "prof")
+		if !errors.Is(err, tt.err) {
+			t.Errorf("%s: got %v, want %v", tt.name, err, tt.err)
+		}
+	}
+
+	m, err := store.GetModuleById(week1.ID)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+
+	if len(m.Items) != 3 || m.Items[2].Kind != models.MODULE_PAGE {
+		t.Fatalf("got %d items, want the assignment, announcement and page in order", len(m.Items))
+	}
+
+	page := m.Items[2].ID
+
+	moved, err := ms.MoveItem(page, week2.ID, 0, "prof")
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+
+	if moved.ID != week2.ID || len(moved.Items) != 1 || moved.Items[0].ID != page {
+		t.Errorf("got %+v, want the page moved to week 2", moved)
+	}
+
+	m, _ = store.GetModuleById(week1.ID)
+	for i, item := range m.Items {
+		if item.Position != i {
+			t.Errorf("got item %d at %d, want week 1 renumbered", i, item.Position)
+		}
+	}
+
+	err = ms.DeleteModule(week1.ID, "prof")
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+
+	m, _ = store.GetModuleById(week2.ID)
+	if m.Position != 0 || len(m.Prerequisites) != 0 {
+		t.Errorf("got week 2 at %d requiring %v, want it first without prerequisites", m.Position, m.Prerequisites)
+	}
+}
+
+func TestModuleService_Progress(t *testing.T) {
+	store := newMockModuleStore()
+	ms := NewModuleService(store)
+
+	store.addModule("m1", true)
+	store.addItem("m1", "read", models.MODULE_PAGE, "", models.REQUIRE_VIEW)
+	store.addItem("m1", "lab", models.MODULE_ASSIGNMENT, "a1", models.REQUIRE_SUBMIT)
+	store.addItem("m1", "draft", models.MODULE_ASSIGNMENT, "a2", models.REQUIRE_SUBMIT)
+
+	store.addModule("m2", true, "m1")
+	store.addItem("m2", "reflect", models.MODULE_PAGE, "", models.REQUIRE_MARK_DONE)
+
+	store.addModule("m3", false)
+
+	store.assignments["a2"].State = models.ASSIGNMENT_DRAFT
+
+	state := func(netId, moduleId string) models.ModuleState {
+		t.Helper()
+
+		modules, err := ms.Modules("c1", netId)
+		if err != nil {
+			t.Fatalf("%+v", err)
+		}
+
+		for _, m := range modules {
+			if m.ID == moduleId {
+				return m.Progress.State
+			}
+		}
+
+		t.Fatalf("got no %s for %s", moduleId, netId)
+		return ""
+	}
+
+	modules, err := ms.Modules("c1", "stu1")
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+
+	if len(modules) != 2 || len(modules[0].Items) != 2 {
+		t.Fatalf("got %d modules, want the published ones without the draft", len(modules))
+	}
+
+	if modules[1].Items[0].Body != "" {
+		t.Errorf("got %q, want a locked module's page hidden", modules[1].Items[0].Body)
+	}
+
+	_, err = ms.MarkDone("reflect", "stu1")
+	if !errors.Is(err, ERR_MODULE_LOCKED) {
+		t.Errorf("got %v, want %v", err, ERR_MODULE_LOCKED)
+	}
+
+	_, err = ms.MarkDone("read", "stu1")
+	if !errors.Is(err, ERR_NOT_MARKABLE) {
+		t.Errorf("got %v, want %v", err, ERR_NOT_MARKABLE)
+	}
+
+	_, err = ms.ViewItem("read", "stu1")
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+
+	if got := state("stu1", "m1"); got != models.MODULE_STARTED {
+		t.Errorf("got %s, want %s", got, models.MODULE_STARTED)
+	}
+
+	store.submissions["a1"] = []string{"stu1"}
+
+	if got := state("stu1", "m1"); got != models.MODULE_COMPLETED {
+		t.Errorf("got %s, want %s", got, models.MODULE_COMPLETED)
+	}
+
+	if got := state("stu1", "m2"); got != models.MODULE_UNLOCKED {
+		t.Errorf("got %s, want %s", got, models.MODULE_UNLOCKED)
+	}
+
+	_, err = ms.MarkDone("reflect", "stu1")
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+
+	_, err = ms.Progress("c1", "stu1")
+	if !errors.Is(err, ERR_NOT_PERMITTED) {
+		t.Errorf("got %v, want %v", err, ERR_NOT_PERMITTED)
+	}
+
+	progress, err := ms.Progress("c1", "prof")
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+
+	want := map[string]models.ModuleState{
+		"stu1/m1": models.MODULE_COMPLETED,
+		"stu1/m2": models.MODULE_COMPLETED,
+		"stu2/m1": models.MODULE_UNLOCKED,
+		"stu2/m2": models.MODULE_LOCKED,
+	}
+
+	if len(progress) != len(want) {
+		t.Fatalf("got %d entries, want %d", len(progress), len(want))
+	}
+
+	for _, p := range progress {
+		if got := p.State; got != want[p.NetId+"/"+p.ModuleId] {
+			t.Errorf("got %s for %s in %s, want %s", got, p.NetId, p.ModuleId, want[p.NetId+"/"+p.ModuleId])
+		}
+	}
+}
+
+// ========= //
+//   MOCKS   //
+// ========= //
+
+// mockModuleStore has course "c1", taught by "prof" to "stu1" and
+// "stu2", with assignment "a1" and "a2", announcement "msg1" and
+// discussion "msg2". Assignment "a9" is of another course.
+type mockModuleStore struct {
+	ModuleStore
+
+	modules     map[string]*models.Module
+	items       map[string]*models.ModuleItem
+	assignments map[string]*models.Assignment
+	completions []*models.ModuleCompletion
+	submissions map[string][]string
+	next        int
+}
+
+func newMockModuleStore() *mockModuleStore {
+	assignments := make(map[string]*models.Assignment)
+	for _, id := range []string{"a1", "a2", "a9"} {
+		assignments[id] = &models.Assignment{Post: models.Post{Entity: models.Entity{ID: id}}}
+	}
+
+	return &mockModuleStore{
+		modules:     make(map[string]*models.Module),
+		items:       make(map[string]*models.ModuleItem),
+		assignments: assignments,
+		submissions: make(map[string][]string),
+	}
+}
+
+func (m *mockModuleStore) addModule(id string, published bool, prerequisites ...string) {
+	m.modules[id] = &models.Module{
+		Entity:        models.Entity{ID: id},
+		CourseId:      "c1",
+		Title:         id,
+		Position:      len(m.modules),
+		Published:     published,
+		Prerequisites: prerequisites,
+	}
+}
+
+func (m *mockModuleStore) addItem(
+	moduleId, id string,
+	kind models.ModuleItemKind,
+	ref string,
+	r models.Requirement,
+) {
+	position := 0
+	for _, item := range m.items {
+		if item.ModuleId == moduleId {
+			position++
+		}
+	}
+
+	m.items[id] = &models.ModuleItem{
+		Entity:      models.Entity{ID: id},
+		ModuleId:    moduleId,
+		Kind:        kind,
+		Title:       id,
+		Position:    position,
+		RefId:       ref,
+		Body:        "text of " + id,
+		Requirement: r,
+	}
+}
+
+func (m *mockModuleStore) GetAssignmentById(assignmentid string) (
+	*models.Assignment,
+	error,
+) {
+	a, ok := m.assignments[assignmentid]
+	if !ok {
+		return nil, dal.ERR_RECORD_NOT_FOUND
+	}
+	return a, nil
+}
+
+func (m *mockModuleStore) GetCourseIdByAssignment(assignmentId string) (
+	string,
+	error,
+) {
+	if assignmentId == "a9" {
+		return "c9", nil
+	}
+	return "c1", nil
+}
+
+func (m *mockModuleStore) GetMessageById(messageid string) (
+	*models.Message,
+	error,
+) {
+	msg := &models.Message{Type: messageid == "msg1"}
+	msg.ID = messageid
+	msg.Course = "c1"
+	return msg, nil
+}
+
+func (m *mockModuleStore) GetSubmissions(assignmentId string) (
+	[]*models.Submission,
+	error,
+) {
+	var submissions []*models.Submission
+	for _, netId := range m.submissions[assignmentId] {
+		sub := models.NewSubmission()
+		sub.User.ID = netId
+		submissions = append(submissions, sub)
+	}
+	return submissions, nil
+}
+
+func (m *mockModuleStore) GetRoster(courseid string) ([]models.User, error) {
+	var roster []models.User
+	for _, netId := range []string{"stu1", "stu2"} {
+		u := models.User{}
+		u.ID = netId
+		roster = append(roster, u)
+	}
+	return roster, nil
+}
+
+func (m *mockModuleStore) IsCourseTeacher(courseId, netId string) (
+	bool,
+	error,
+) {
+	return netId == "prof", nil
+}
+
+func (m *mockModuleStore) IsCourseStudent(courseId, netId string) (
+	bool,
+	error,
+) {
+	return netId == "stu1" || netId == "stu2", nil
+}
+
+func (m *mockModuleStore) InsertModule(module *models.Module) error {
+	m.next++
+	module.ID = "new" + string(rune('0'+m.next))
+	saved := *module
+	m.modules[module.ID] = &saved
+	return nil
+}
+
+// GetModuleById returns a copy of a module, since the service changes
+// what it reads.
+func (m *mockModuleStore) GetModuleById(id string) (*models.Module, error) {
+	module, ok := m.modules[id]
+	if !ok {
+		return nil, dal.ERR_RECORD_NOT_FOUND
+	}
+
+	c := *module
+	c.Prerequisites = append([]string{}, module.Prerequisites...)
+	c.Items = []*models.ModuleItem{}
+
+	for _, item := range m.items {
+		if item.ModuleId == id {
+			copied := *item
+			c.Items = append(c.Items, &copied)
+		}
+	}
+
+	sort.Slice(
+		c.Items, func(i, j int) bool {
+			return c.Items[i].Position < c.Items[j].Position
+		},
+	)
+
+	return &c, nil
+}
+
+func (m *mockModuleStore) GetModules(courseId string) ([]*models.Module, error) {
+	modules := []*models.Module{}
+
+	for id := range m.modules {
+		module, _ := m.GetModuleById(id)
+		modules = append(modules, module)
+	}
+
+	sort.Slice(
+		modules, func(i, j int) bool {
+			return modules[i].Position < modules[j].Position
+		},
+	)
+
+	return modules, nil
+}
+
+func (m *mockModuleStore) UpdateModule(module *models.Module) error {
+	saved := *module
+	m.modules[module.ID] = &saved
+	return nil
+}
+
+func (m *mockModuleStore) DeleteModule(id string) error {
+	delete(m.modules, id)
+
+	for _, module := range m.modules {
+		var kept []string
+		for _, p := range module.Prerequisites {
+			if p != id {
+				kept = append(kept, p)
+			}
+		}
+		module.Prerequisites = kept
+	}
+
+	return nil
+}
+
+func (m *mockModuleStore) SaveModuleOrder(courseId string, ids []string) error {
+	for i, id := range ids {
+		m.modules[id].Position = i
+	}
+	return nil
+}
+
+func (m *mockModuleStore) InsertModuleItem(i *models.ModuleItem) error {
+	m.next++
+	i.ID = "item" + string(rune('0'+m.next))
+	saved := *i
+	m.items[i.ID] = &saved
+	return nil
+}
+
+func (m *mockModuleStore) GetModuleItemById(id string) (
+	*models.ModuleItem,
+	error,
+) {
+	item, ok := m.items[id]
+	if !ok {
+		return nil, dal.ERR_RECORD_NOT_FOUND
+	}
+	copied := *item
+	return &copied, nil
+}
+
+func (m *mockModuleStore) SaveModuleItemOrder(moduleId string, ids []string) error {
+	for i, id := range ids {
+		m.items[id].ModuleId = moduleId
+		m.items[id].Position = i
+	}
+	return nil
+}
+
+func (m *mockModuleStore) InsertModuleCompletion(c *models.ModuleCompletion) error {
+	m.completions = append(m.completions, c)
+	return nil
+}
+
+func (m *mockModuleStore) GetModuleCompletions(courseId string) (
+	[]*models.ModuleCompletion,
+	error,
+) {
+	return m.completions, nil
+}
@@ -0,0 +1,65 @@
+package domain
+
+import (
+	"github.com/n30w/Darkspace/internal/models"
+)
+
+type NotificationStore interface {
+	InsertNotification(n *models.Notification) error
+	GetNotifications(netId string) ([]*models.Notification, error)
+	MarkNotificationRead(id, netId string) error
+}
+
+type NotificationService struct {
+	store NotificationStore
+}
+
+func NewNotificationService(s NotificationStore) *NotificationService {
+	return &NotificationService{store: s}
+}
+
+// ListNotifications retrieves a user's notifications, newest first.
+func (ns *NotificationService) ListNotifications(netId string) (
+	[]*models.Notification,
+	error,
+) {
+	if netId == "" {
+		return nil, ERR_NOT_PERMITTED
+	}
+
+	return ns.store.GetNotifications(netId)
+}
+
+// MarkRead marks one of a user's notifications as read. Users may only
+// mark their own notifications.
+func (ns *NotificationService) MarkRead(id, netId string) error {
+	if netId == "" {
+		return ERR_NOT_PERMITTED
+	}
+
+	return ns.store.MarkNotificationRead(id, netId)
+}
+
+// notify sends the same notification to each of a number of users.
+func notify(
+	s NotificationStore,
+	netIds []string,
+	kind models.NotificationKind,
+	message, ref string,
+) error {
+	for _, netId := range netIds {
+		n := &models.Notification{
+			NetId:   netId,
+			Kind:    kind,
+			Message: message,
+			Ref:     ref,
+		}
+
+		err := s.InsertNotification(n)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,863 @@
+package domain
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"unicode/utf16"
+
+	"github.com/n30w/Darkspace/internal/models"
+)
+
+// maxPDFDepth is how deeply a PDF's page tree and values may nest.
+const maxPDFDepth = 64
+
+// commentIconSize is how many points wide and high a comment's icon is.
+const commentIconSize = 20
+
+// pdfRef is a reference to an indirect object, as in "12 0 R".
+type pdfRef struct {
+	num, gen int
+}
+
+// pdfDict is a dictionary, keyed by name without the slash.
+type pdfDict map[string]any
+
+// pdfRaw is a number, string, boolean or null, kept as it was written.
+type pdfRaw string
+
+// pdfDoc is a PDF read just enough to find its pages and add to it.
+type pdfDoc struct {
+	data []byte
+
+	// objects are where each object's value is written, after "obj".
+	// Objects in object streams are found as well, and an object that
+	// is written more than once, as with incremental updates, is the
+	// last one in the file.
+	objects map[int]pdfObject
+
+	trailer pdfDict
+
+	// xref is where the last cross-reference section starts, which
+	// an update points back to.
+	xref int
+}
+
+type pdfObject struct {
+	gen  int
+	body []byte
+}
+
+// pdfPage is a page of a PDF, in the order it is shown.
+type pdfPage struct {
+	ref  pdfRef
+	dict pdfDict
+}
+
+var (
+	objRX       = regexp.MustCompile(`(\d+)\s+(\d+)\s+obj\b`)
+	startxrefRX = regexp.MustCompile(`startxref\s+(\d+)`)
+)
+
+// PDFPageCount counts the pages of a PDF.
+func PDFPageCount(data []byte) (int, error) {
+	doc, err := readPDF(data)
+	if err != nil {
+		return 0, err
+	}
+
+	pages, err := doc.pages()
+	if err != nil {
+		return 0, err
+	}
+
+	return len(pages), nil
+}
+
+// AnnotatePDF adds annotations to a copy of a PDF, as the annotations
+// of PDF itself with appearances of their own, so that they show and
+// print the same in any viewer and can still be opened to read their
+// comments. They are added in an incremental update, which leaves the
+// original bytes of the PDF as they were. Annotations on pages the PDF
+// does not have are left out.
+func AnnotatePDF(data []byte, annotations []*models.Annotation) ([]byte, error) {
+	doc, err := readPDF(data)
+	if err != nil {
+		return nil, err
+	}
+
+	pages, err := doc.pages()
+	if err != nil {
+		return nil, err
+	}
+
+	next := doc.size()
+
+	var out bytes.Buffer
+	out.Write(data)
+	if !bytes.HasSuffix(data, []byte("\n")) {
+		out.WriteByte('\n')
+	}
+
+	// offsets are where each object that is written starts.
+	offsets := make(map[int]int)
+	gens := make(map[int]int)
+
+	write := func(ref pdfRef, v any, stream []byte) {
+		offsets[ref.num] = out.Len()
+		gens[ref.num] = ref.gen
+
+		fmt.Fprintf(&out, "%d %d obj\n", ref.num, ref.gen)
+		writePDFValue(&out, v)
+
+		if stream != nil {
+			out.WriteString("\nstream\n")
+			out.Write(stream)
+			out.WriteString("\nendstream")
+		}
+
+		out.WriteString("\nendobj\n")
+	}
+
+	added := make(map[int][]any)
+
+	for _, a := range annotations {
+		if a.Page < 1 || a.Page > len(pages) {
+			continue
+		}
+
+		page := pages[a.Page-1]
+
+		annot, form, content := annotationObject(a)
+
+		formRef := pdfRef{num: next}
+		annotRef := pdfRef{num: next + 1}
+		next += 2
+
+		form["Length"] = len(content)
+		annot["AP"] = pdfDict{"N": formRef}
+		annot["P"] = page.ref
+
+		write(formRef, form, content)
+		write(annotRef, annot, nil)
+
+		added[a.Page] = append(added[a.Page], annotRef)
+	}
+
+	// Pages are written again with the annotations added to those they
+	// had.
+	for n, refs := range added {
+		page := pages[n-1]
+
+		dict := make(pdfDict, len(page.dict)+1)
+		for k, v := range page.dict {
+			dict[k] = v
+		}
+
+		var annots []any
+		if old, ok := doc.resolve(page.dict["Annots"], 0).([]any); ok {
+			annots = append(annots, old...)
+		}
+
+		dict["Annots"] = append(annots, refs...)
+
+		write(page.ref, dict, nil)
+	}
+
+	if len(offsets) == 0 {
+		return data, nil
+	}
+
+	xref := out.Len()
+
+	nums := make([]int, 0, len(offsets))
+	for num := range offsets {
+		nums = append(nums, num)
+	}
+	sort.Ints(nums)
+
+	out.WriteString("xref\n")
+
+	for i := 0; i < len(nums); {
+		j := i + 1
+		for j < len(nums) && nums[j] == nums[j-1]+1 {
+			j++
+		}
+
+		fmt.Fprintf(&out, "%d %d\n", nums[i], j-i)
+		for _, num := range nums[i:j] {
+			fmt.Fprintf(&out, "%010d %05d n\r\n", offsets[num], gens[num])
+		}
+
+		i = j
+	}
+
+	trailer := pdfDict{"Size": next, "Root": doc.trailer["Root"]}
+	for _, key := range []string{"Info", "ID"} {
+		if v, ok := doc.trailer[key]; ok {
+			trailer[key] = v
+		}
+	}
+	if doc.xref >= 0 {
+		trailer["Prev"] = doc.xref
+	}
+
+	out.WriteString("trailer\n")
+	writePDFValue(&out, trailer)
+	fmt.Fprintf(&out, "\nstartxref\n%d\n%%%%EOF\n", xref)
+
+	return out.Bytes(), nil
+}
+
+// annotationObject builds the dictionary of an annotation, along with
+// the form and content stream of its appearance.
+func annotationObject(a *models.Annotation) (pdfDict, pdfDict, []byte) {
+	r, g, b := a.RGB()
+	color := []any{r, g, b}
+
+	annot := pdfDict{
+		"Type": pdfName("Annot"),
+		"C":    color,
+		"T":    a.Author,
+		"NM":   a.ID,
+
+		// Annotations are printed along with the page.
+		"F": 4,
+	}
+
+	if a.Comment != "" {
+		annot["Contents"] = a.Comment
+	}
+
+	modified := a.UpdatedAt
+	if modified.IsZero() {
+		modified = a.CreatedAt
+	}
+	if !modified.IsZero() {
+		annot["M"] = modified.UTC().Format("D:20060102150405Z")
+	}
+
+	var content bytes.Buffer
+	bounds := a.Bounds()
+
+	switch a.Kind {
+	case models.ANNOTATION_HIGHLIGHT:
+		annot["Subtype"] = pdfName("Highlight")
+
+		var quads []any
+		content.WriteString("/GS0 gs ")
+		fmt.Fprintf(&content, "%s rg\n", pdfNumbers(r, g, b))
+
+		for _, rect := range a.Rects {
+			x1, y1 := rect.X, rect.Y
+			x2, y2 := rect.X+rect.Width, rect.Y+rect.Height
+
+			quads = append(quads, x1, y2, x2, y2, x1, y1, x2, y1)
+			fmt.Fprintf(&content, "%s re f\n", pdfNumbers(rect.X, rect.Y, rect.Width, rect.Height))
+		}
+
+		annot["QuadPoints"] = quads
+	case models.ANNOTATION_COMMENT:
+		annot["Subtype"] = pdfName("Text")
+		annot["Name"] = pdfName("Comment")
+		annot["Open"] = pdfRaw("false")
+
+		// The comment's point is the top left corner of its icon.
+		bounds = models.Rect{
+			X:      a.Point.X,
+			Y:      a.Point.Y - commentIconSize,
+			Width:  commentIconSize,
+			Height: commentIconSize,
+		}
+
+		fmt.Fprintf(&content, "%s rg 0 0 0 RG 1 w\n", pdfNumbers(r, g, b))
+		fmt.Fprintf(
+			&content, "%s re B\n",
+			pdfNumbers(bounds.X+0.5, bounds.Y+0.5, bounds.Width-1, bounds.Height-1),
+		)
+
+		// Lines of text on the note.
+		for i := 1; i <= 3; i++ {
+			y := bounds.Y + bounds.Height - float64(i)*5
+			fmt.Fprintf(
+				&content, "%s m %s l S\n",
+				pdfNumbers(bounds.X+4, y),
+				pdfNumbers(bounds.X+bounds.Width-4, y),
+			)
+		}
+	case models.ANNOTATION_INK:
+		const width = 2
+
+		annot["Subtype"] = pdfName("Ink")
+		annot["BS"] = pdfDict{"W": width}
+
+		var inkList []any
+
+		fmt.Fprintf(&content, "%s RG %d w 1 J 1 j\n", pdfNumbers(r, g, b), width)
+
+		for _, stroke := range a.Strokes {
+			if len(stroke) == 0 {
+				continue
+			}
+
+			var points []any
+			for i, p := range stroke {
+				points = append(points, p.X, p.Y)
+
+				op := "l"
+				if i == 0 {
+					op = "m"
+				}
+				fmt.Fprintf(&content, "%s %s ", pdfNumbers(p.X, p.Y), op)
+			}
+
+			// A stroke of one point is drawn as a dot.
+			if len(stroke) == 1 {
+				fmt.Fprintf(&content, "%s l ", pdfNumbers(stroke[0].X, stroke[0].Y))
+			}
+
+			content.WriteString("S\n")
+			inkList = append(inkList, points)
+		}
+
+		annot["InkList"] = inkList
+
+		bounds.X -= width
+		bounds.Y -= width
+		bounds.Width += 2 * width
+		bounds.Height += 2 * width
+	}
+
+	rect := []any{bounds.X, bounds.Y, bounds.X + bounds.Width, bounds.Y + bounds.Height}
+	annot["Rect"] = rect
+
+	form := pdfDict{
+		"Type":    pdfName("XObject"),
+		"Subtype": pdfName("Form"),
+		"BBox":    rect,
+	}
+
+	if a.Kind == models.ANNOTATION_HIGHLIGHT {
+		// Highlights are see-through, and darken what is under them,
+		// like a highlighter.
+		form["Resources"] = pdfDict{
+			"ExtGState": pdfDict{
+				"GS0": pdfDict{
+					"Type": pdfName("ExtGState"),
+					"CA":   0.5,
+					"ca":   0.5,
+					"BM":   pdfName("Multiply"),
+				},
+			},
+		}
+	}
+
+	return annot, form, bytes.TrimSpace(content.Bytes())
+}
+
+// readPDF finds the objects and trailer of a PDF.
+func readPDF(data []byte) (*pdfDoc, error) {
+	if !bytes.HasPrefix(bytes.TrimLeft(data, " \t\r\n"), []byte("%PDF-")) {
+		return nil, ERR_PDF_UNREADABLE
+	}
+
+	doc := &pdfDoc{data: data, objects: make(map[int]pdfObject), xref: -1}
+
+	for pos := 0; pos < len(data); {
+		loc := objRX.FindSubmatchIndex(data[pos:])
+		if loc == nil {
+			break
+		}
+
+		num, _ := strconv.Atoi(string(data[pos+loc[2] : pos+loc[3]]))
+		gen, _ := strconv.Atoi(string(data[pos+loc[4] : pos+loc[5]]))
+		start := pos + loc[1]
+
+		end := bytes.Index(data[start:], []byte("endobj"))
+		s := bytes.Index(data[start:], []byte("stream"))
+
+		if s < 0 || (end >= 0 && end < s) {
+			if end < 0 {
+				end = len(data) - start
+			}
+
+			doc.objects[num] = pdfObject{gen: gen, body: data[start : start+end]}
+			pos = start + end
+			continue
+		}
+
+		// The data of a stream is skipped, since it may hold anything,
+		// though object streams are read for the objects they hold.
+		body := data[start : start+s]
+		doc.objects[num] = pdfObject{gen: gen, body: body}
+
+		content := data[start+s+len("stream"):]
+		content = bytes.TrimPrefix(content, []byte("\r"))
+		content = bytes.TrimPrefix(content, []byte("\n"))
+
+		e := bytes.Index(content, []byte("endstream"))
+		if e < 0 {
+			break
+		}
+
+		pos = len(data) - len(content) + e + len("endstream")
+
+		dict, ok := parsePDF(body).(pdfDict)
+		if ok && dict["Type"] == pdfName("ObjStm") {
+			doc.readObjectStream(dict, content[:e])
+		}
+	}
+
+	err := doc.readTrailer()
+	if err != nil {
+		return nil, err
+	}
+
+	return doc, nil
+}
+
+// readObjectStream finds the objects held in an object stream.
+func (doc *pdfDoc) readObjectStream(dict pdfDict, content []byte) {
+	if dict["Filter"] == pdfName("FlateDecode") {
+		zr, err := zlib.NewReader(bytes.NewReader(content))
+		if err != nil {
+			return
+		}
+
+		content, _ = io.ReadAll(io.LimitReader(zr, maxTextSize))
+		zr.Close()
+	} else if dict["Filter"] != nil {
+		return
+	}
+
+	n, _ := pdfInt(dict["N"])
+	first, _ := pdfInt(dict["First"])
+
+	if first <= 0 || first > len(content) {
+		return
+	}
+
+	// The stream starts with pairs of object numbers and where each
+	// object starts, after the first byte of the objects. Objects are
+	// in the order they start in, so a stream whose offsets do not
+	// increase is broken.
+	p := &pdfParser{data: content[:first]}
+
+	var nums, starts []int
+	for i := 0; i < n; i++ {
+		num, ok1 := pdfInt(p.value(0))
+		start, ok2 := pdfInt(p.value(0))
+		if !ok1 || !ok2 || start < 0 || first+start > len(content) {
+			return
+		}
+
+		if len(starts) > 0 && first+start <= starts[len(starts)-1] {
+			return
+		}
+
+		nums = append(nums, num)
+		starts = append(starts, first+start)
+	}
+
+	for i, num := range nums {
+		end := len(content)
+		if i+1 < len(starts) {
+			end = starts[i+1]
+		}
+
+		doc.objects[num] = pdfObject{body: content[starts[i]:end]}
+	}
+}
+
+// readTrailer reads the trailer of the last cross-reference section,
+// which may be a cross-reference stream.
+func (doc *pdfDoc) readTrailer() error {
+	matches := startxrefRX.FindAllSubmatch(doc.data, -1)
+	if len(matches) > 0 {
+		offset, err := strconv.Atoi(string(matches[len(matches)-1][1]))
+		if err == nil && offset < len(doc.data) {
+			doc.xref = offset
+
+			at := doc.data[offset:]
+			if bytes.HasPrefix(at, []byte("xref")) {
+				i := bytes.Index(at, []byte("trailer"))
+				if i >= 0 {
+					doc.trailer, _ = parsePDF(at[i+len("trailer"):]).(pdfDict)
+				}
+			} else if loc := objRX.FindIndex(at); loc != nil && loc[0] == 0 {
+				doc.trailer, _ = parsePDF(at[loc[1]:]).(pdfDict)
+			}
+		}
+	}
+
+	// A PDF whose cross-reference offsets are broken may still have a
+	// trailer to go by.
+	if doc.trailer["Root"] == nil {
+		doc.xref = -1
+
+		i := bytes.LastIndex(doc.data, []byte("trailer"))
+		if i < 0 {
+			return ERR_PDF_UNREADABLE
+		}
+
+		doc.trailer, _ = parsePDF(doc.data[i+len("trailer"):]).(pdfDict)
+	}
+
+	if _, ok := doc.trailer["Root"].(pdfRef); !ok {
+		return ERR_PDF_UNREADABLE
+	}
+
+	// The strings of an encrypted PDF would have to be encrypted too.
+	if doc.trailer["Encrypt"] != nil {
+		return ERR_PDF_UNREADABLE
+	}
+
+	return nil
+}
+
+// size is the number that the next new object is given.
+func (doc *pdfDoc) size() int {
+	size, _ := pdfInt(doc.trailer["Size"])
+
+	for num := range doc.objects {
+		size = max(size, num+1)
+	}
+
+	return size
+}
+
+// resolve looks up the value of a reference, returning other values as
+// they are.
+func (doc *pdfDoc) resolve(v any, depth int) any {
+	ref, ok := v.(pdfRef)
+	if !ok {
+		return v
+	}
+
+	if depth > maxPDFDepth {
+		return nil
+	}
+
+	o, ok := doc.objects[ref.num]
+	if !ok {
+		return nil
+	}
+
+	return doc.resolve(parsePDF(o.body), depth+1)
+}
+
+// pages finds the pages of the PDF by walking its page tree.
+func (doc *pdfDoc) pages() ([]pdfPage, error) {
+	catalog, ok := doc.resolve(doc.trailer["Root"], 0).(pdfDict)
+	if !ok {
+		return nil, ERR_PDF_UNREADABLE
+	}
+
+	root, ok := catalog["Pages"].(pdfRef)
+	if !ok {
+		return nil, ERR_PDF_UNREADABLE
+	}
+
+	var pages []pdfPage
+	seen := make(map[int]bool)
+
+	var walk func(ref pdfRef, depth int) error
+	walk = func(ref pdfRef, depth int) error {
+		if depth > maxPDFDepth || seen[ref.num] {
+			return ERR_PDF_UNREADABLE
+		}
+		seen[ref.num] = true
+
+		node, ok := doc.resolve(ref, 0).(pdfDict)
+		if !ok {
+			return ERR_PDF_UNREADABLE
+		}
+
+		kids, ok := doc.resolve(node["Kids"], 0).([]any)
+		if !ok {
+			if node["Type"] != pdfName("Pages") {
+				pages = append(pages, pdfPage{ref: ref, dict: node})
+			}
+			return nil
+		}
+
+		for _, kid := range kids {
+			kidRef, ok := kid.(pdfRef)
+			if !ok {
+				return ERR_PDF_UNREADABLE
+			}
+
+			err := walk(kidRef, depth+1)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	err := walk(root, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	return pages, nil
+}
+
+// parsePDF reads the value that data starts with.
+func parsePDF(data []byte) any {
+	p := &pdfParser{data: data}
+	return p.value(0)
+}
+
+// pdfParser reads the values of a PDF's objects, which are all that
+// is needed to find its pages and write them back.
+type pdfParser struct {
+	data []byte
+	i    int
+}
+
+func (p *pdfParser) skip() {
+	for p.i < len(p.data) {
+		c := p.data[p.i]
+
+		switch {
+		case isPDFSpace(c):
+			p.i++
+		case c == '%':
+			for p.i < len(p.data) && p.data[p.i] != '\n' && p.data[p.i] != '\r' {
+				p.i++
+			}
+		default:
+			return
+		}
+	}
+}
+
+func (p *pdfParser) value(depth int) any {
+	p.skip()
+
+	if p.i >= len(p.data) || depth > maxPDFDepth {
+		return nil
+	}
+
+	rest := p.data[p.i:]
+
+	switch c := rest[0]; {
+	case bytes.HasPrefix(rest, []byte("<<")):
+		p.i += 2
+
+		dict := make(pdfDict)
+
+		for {
+			p.skip()
+
+			if p.i >= len(p.data) {
+				return dict
+			}
+
+			if bytes.HasPrefix(p.data[p.i:], []byte(">>")) {
+				p.i += 2
+				return dict
+			}
+
+			key, ok := p.value(depth + 1).(pdfName)
+			if !ok {
+				return dict
+			}
+
+			dict[string(key)] = p.value(depth + 1)
+		}
+	case c == '[':
+		p.i++
+
+		array := []any{}
+
+		for {
+			p.skip()
+
+			if p.i >= len(p.data) {
+				return array
+			}
+
+			if p.data[p.i] == ']' {
+				p.i++
+				return array
+			}
+
+			start := p.i
+			array = append(array, p.value(depth+1))
+
+			if p.i == start {
+				p.i++
+			}
+		}
+	case c == '(':
+		_, n := literalString(rest)
+		p.i += n
+		return pdfRaw(rest[:n])
+	case c == '<':
+		_, n := hexString(rest)
+		p.i += n
+		return pdfRaw(rest[:n])
+	case c == '/':
+		n := tokenEnd(rest, 1)
+		p.i += n
+		return pdfName(rest[1:n])
+	case isPDFDelimiter(c):
+		p.i++
+		return nil
+	}
+
+	n := tokenEnd(rest, 0)
+	token := string(rest[:n])
+	p.i += n
+
+	// A whole number may be the start of a reference.
+	num, err := strconv.Atoi(token)
+	if err == nil {
+		save := p.i
+
+		p.skip()
+		gn := tokenEnd(p.data, p.i)
+		gen, err := strconv.Atoi(string(p.data[p.i:gn]))
+
+		if err == nil {
+			p.i = gn
+			p.skip()
+
+			if p.i < len(p.data) && p.data[p.i] == 'R' && tokenEnd(p.data, p.i) == p.i+1 {
+				p.i++
+				return pdfRef{num: num, gen: gen}
+			}
+		}
+
+		p.i = save
+	}
+
+	return pdfRaw(token)
+}
+
+// pdfInt reads a whole number.
+func pdfInt(v any) (int, bool) {
+	switch n := v.(type) {
+	case int:
+		return n, true
+	case pdfRaw:
+		i, err := strconv.Atoi(string(n))
+		return i, err == nil
+	}
+
+	return 0, false
+}
+
+// writePDFValue writes a value as PDF. Go strings are written as text
+// strings.
+func writePDFValue(b *bytes.Buffer, v any) {
+	switch v := v.(type) {
+	case pdfDict:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		b.WriteString("<<")
+		for _, k := range keys {
+			b.WriteString(" /")
+			b.WriteString(k)
+			b.WriteByte(' ')
+			writePDFValue(b, v[k])
+		}
+		b.WriteString(" >>")
+	case []any:
+		b.WriteByte('[')
+		for i, e := range v {
+			if i > 0 {
+				b.WriteByte(' ')
+			}
+			writePDFValue(b, e)
+		}
+		b.WriteByte(']')
+	case pdfName:
+		b.WriteByte('/')
+		b.WriteString(string(v))
+	case pdfRef:
+		fmt.Fprintf(b, "%d %d R", v.num, v.gen)
+	case pdfRaw:
+		b.WriteString(string(v))
+	case int:
+		b.WriteString(strconv.Itoa(v))
+	case float64:
+		b.WriteString(pdfNumbers(v))
+	case string:
+		b.WriteString(pdfTextString(v))
+	case nil:
+		b.WriteString("null")
+	}
+}
+
+// pdfNumbers writes numbers as PDF does, to three decimal places and
+// never in exponent form.
+func pdfNumbers(fs ...float64) string {
+	var b []byte
+
+	for i, f := range fs {
+		if i > 0 {
+			b = append(b, ' ')
+		}
+
+		f = math.Round(f*1000) / 1000
+		if f == 0 {
+			f = 0 // Not negative zero.
+		}
+
+		b = strconv.AppendFloat(b, f, 'f', -1, 64)
+	}
+
+	return string(b)
+}
+
+// pdfTextString writes a string as a PDF text string, in UTF-16 when it
+// is not plain ASCII.
+func pdfTextString(s string) string {
+	ascii := true
+	for i := 0; i < len(s); i++ {
+		if s[i] >= 0x80 {
+			ascii = false
+			break
+		}
+	}
+
+	if !ascii {
+		var b bytes.Buffer
+		b.WriteString("<FEFF")
+		for _, u := range utf16.Encode([]rune(s)) {
+			fmt.Fprintf(&b, "%04X", u)
+		}
+		b.WriteByte('>')
+		return b.String()
+	}
+
+	var b bytes.Buffer
+	b.WriteByte('(')
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '(', ')', '\\':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case '\r':
+			b.WriteString(`\r`)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	b.WriteByte(')')
+
+	return b.String()
+}
@@ -0,0 +1,376 @@
+package domain
+
+import (
+	"bytes"
+	"compress/zlib"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// PDFText extracts the text drawn by the content streams of a PDF.
+// Streams are read as they are laid out in the file, uncompressed or
+// compressed with FlateDecode, and the strings shown by text operators
+// are written out with line breaks where the text moves to a new line.
+// Strings are read as UTF-16 when they start with a byte order mark
+// and as Latin-1 otherwise, so text drawn through a font's own
+// encoding, as with most CID fonts, comes out garbled. That is good
+// enough to compare submissions, but not to show their text as is.
+func PDFText(data []byte) string {
+	var b strings.Builder
+
+	rest := data
+
+	for {
+		i := bytes.Index(rest, []byte("stream"))
+		if i < 0 {
+			break
+		}
+
+		dict := streamDict(rest[:i])
+		body := rest[i+len("stream"):]
+
+		// The stream's data starts after the end of line that follows
+		// the keyword.
+		switch {
+		case bytes.HasPrefix(body, []byte("\r\n")):
+			body = body[2:]
+		case bytes.HasPrefix(body, []byte("\n")):
+			body = body[1:]
+		default:
+			rest = body
+			continue
+		}
+
+		end := bytes.Index(body, []byte("endstream"))
+		if end < 0 {
+			break
+		}
+
+		rest = body[end+len("endstream"):]
+
+		if !contentDict(dict) {
+			continue
+		}
+
+		content := body[:end]
+
+		if bytes.Contains(dict, []byte("/FlateDecode")) {
+			zr, err := zlib.NewReader(bytes.NewReader(content))
+			if err != nil {
+				continue
+			}
+
+			// A truncated stream still gives the text read so far.
+			content, _ = io.ReadAll(io.LimitReader(zr, maxTextSize))
+			zr.Close()
+		} else if bytes.Contains(dict, []byte("/Filter")) {
+			continue
+		}
+
+		showText(&b, content)
+	}
+
+	return b.String()
+}
+
+// streamDict is the dictionary of the stream whose keyword ends
+// before, which is everything since the start of its object.
+func streamDict(before []byte) []byte {
+	start := bytes.LastIndex(before, []byte("obj"))
+	if start < 0 {
+		return before
+	}
+
+	return before[start:]
+}
+
+// contentDict checks if a stream's dictionary could be that of a
+// content stream, leaving out fonts, images, object streams and
+// cross-reference streams.
+func contentDict(dict []byte) bool {
+	for _, key := range []string{
+		"/Length1",
+		"/Length2",
+		"/Length3",
+		"/Image",
+		"/ObjStm",
+		"/XRef",
+		"/Metadata",
+	} {
+		if bytes.Contains(dict, []byte(key)) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// pdfName is a name operand, such as a font's, which is never text.
+type pdfName string
+
+// pdfArray marks where an array operand starts.
+type pdfArray struct{}
+
+// showText writes the strings shown by the text operators of a
+// content stream.
+func showText(b *strings.Builder, content []byte) {
+	var operands []any
+
+	newline := func() {
+		if b.Len() > 0 && !strings.HasSuffix(b.String(), "\n") {
+			b.WriteByte('\n')
+		}
+	}
+
+	for i := 0; i < len(content); {
+		c := content[i]
+
+		switch {
+		case isPDFSpace(c):
+			i++
+		case c == '%':
+			for i < len(content) && content[i] != '\n' && content[i] != '\r' {
+				i++
+			}
+		case c == '(':
+			s, n := literalString(content[i:])
+			operands = append(operands, s)
+			i += n
+		case c == '<' && i+1 < len(content) && content[i+1] == '<':
+			i += 2
+		case c == '>' && i+1 < len(content) && content[i+1] == '>':
+			i += 2
+		case c == '<':
+			s, n := hexString(content[i:])
+			operands = append(operands, s)
+			i += n
+		case c == '[':
+			operands = append(operands, pdfArray{})
+			i++
+		case c == ']':
+			i++
+		case c == '/':
+			n := tokenEnd(content, i+1)
+			operands = append(operands, pdfName(content[i+1:n]))
+			i = n
+		case c == '-' || c == '+' || c == '.' || (c >= '0' && c <= '9'):
+			n := tokenEnd(content, i+1)
+			f, _ := strconv.ParseFloat(string(content[i:n]), 64)
+			operands = append(operands, f)
+			i = n
+		default:
+			n := tokenEnd(content, i+1)
+			op := string(content[i:n])
+			i = n
+
+			switch op {
+			case "Tj":
+				writeLast(b, operands)
+			case "'", "\"":
+				newline()
+				writeLast(b, operands)
+			case "TJ":
+				writeArray(b, operands)
+			case "T*", "ET":
+				newline()
+			case "Td", "TD":
+				if len(operands) > 0 {
+					if ty, ok := operands[len(operands)-1].(float64); ok && ty != 0 {
+						newline()
+						break
+					}
+				}
+				b.WriteByte(' ')
+			case "Tm":
+				newline()
+			case "ID":
+				// Inline image data is skipped up to its end.
+				end := bytes.Index(content[i:], []byte("EI"))
+				if end < 0 {
+					return
+				}
+				i += end + len("EI")
+			}
+
+			operands = operands[:0]
+		}
+	}
+
+	newline()
+}
+
+// writeLast writes the last operand, if it is a string.
+func writeLast(b *strings.Builder, operands []any) {
+	if len(operands) == 0 {
+		return
+	}
+
+	if s, ok := operands[len(operands)-1].(string); ok {
+		b.WriteString(s)
+	}
+}
+
+// writeArray writes the strings of a TJ array. Large enough gaps
+// between strings are read as spaces between words.
+func writeArray(b *strings.Builder, operands []any) {
+	start := -1
+	for i := len(operands) - 1; i >= 0; i-- {
+		if _, ok := operands[i].(pdfArray); ok {
+			start = i
+			break
+		}
+	}
+
+	if start < 0 {
+		return
+	}
+
+	for _, o := range operands[start+1:] {
+		switch v := o.(type) {
+		case string:
+			b.WriteString(v)
+		case float64:
+			if v < -200 {
+				b.WriteByte(' ')
+			}
+		}
+	}
+}
+
+// literalString reads a string in parentheses, returning it and how
+// many bytes it took up.
+func literalString(data []byte) (string, int) {
+	var raw []byte
+
+	depth := 0
+
+	i := 0
+	for ; i < len(data); i++ {
+		c := data[i]
+
+		switch {
+		case c == '\\' && i+1 < len(data):
+			i++
+			switch e := data[i]; e {
+			case 'n':
+				raw = append(raw, '\n')
+			case 'r':
+				raw = append(raw, '\r')
+			case 't':
+				raw = append(raw, '\t')
+			case 'b':
+				raw = append(raw, '\b')
+			case 'f':
+				raw = append(raw, '\f')
+			case '\r', '\n':
+				// A line continuation.
+				if e == '\r' && i+1 < len(data) && data[i+1] == '\n' {
+					i++
+				}
+			default:
+				if e >= '0' && e <= '7' {
+					n := 0
+					j := i
+					for ; j < len(data) && j < i+3 && data[j] >= '0' && data[j] <= '7'; j++ {
+						n = n*8 + int(data[j]-'0')
+					}
+					raw = append(raw, byte(n))
+					i = j - 1
+				} else {
+					raw = append(raw, e)
+				}
+			}
+		case c == '(':
+			if depth > 0 {
+				raw = append(raw, c)
+			}
+			depth++
+		case c == ')':
+			depth--
+			if depth == 0 {
+				return decodePDFString(raw), i + 1
+			}
+			raw = append(raw, c)
+		default:
+			raw = append(raw, c)
+		}
+	}
+
+	return decodePDFString(raw), i
+}
+
+// hexString reads a string in angle brackets, returning it and how
+// many bytes it took up.
+func hexString(data []byte) (string, int) {
+	end := bytes.IndexByte(data, '>')
+	if end < 0 {
+		end = len(data)
+	}
+
+	var digits []byte
+	for _, c := range data[1:end] {
+		if !isPDFSpace(c) {
+			digits = append(digits, c)
+		}
+	}
+
+	if len(digits)%2 == 1 {
+		digits = append(digits, '0')
+	}
+
+	raw := make([]byte, 0, len(digits)/2)
+	for i := 0; i < len(digits); i += 2 {
+		n, err := strconv.ParseUint(string(digits[i:i+2]), 16, 8)
+		if err != nil {
+			break
+		}
+		raw = append(raw, byte(n))
+	}
+
+	return decodePDFString(raw), min(end+1, len(data))
+}
+
+// decodePDFString decodes the bytes of a string as UTF-16 when they
+// start with a byte order mark, and as Latin-1 otherwise.
+func decodePDFString(raw []byte) string {
+	if len(raw) >= 2 && raw[0] == 0xFE && raw[1] == 0xFF {
+		units := make([]uint16, 0, len(raw)/2)
+		for i := 2; i+1 < len(raw); i += 2 {
+			units = append(units, uint16(raw[i])<<8|uint16(raw[i+1]))
+		}
+		return string(utf16.Decode(units))
+	}
+
+	runes := make([]rune, len(raw))
+	for i, c := range raw {
+		runes[i] = rune(c)
+	}
+
+	return string(runes)
+}
+
+// tokenEnd is where the token that continues at i ends.
+func tokenEnd(data []byte, i int) int {
+	for i < len(data) && !isPDFSpace(data[i]) && !isPDFDelimiter(data[i]) {
+		i++
+	}
+	return i
+}
+
+func isPDFSpace(c byte) bool {
+	switch c {
+	case ' ', '\t', '\r', '\n', '\f', 0:
+		return true
+	}
+	return false
+}
+
+func isPDFDelimiter(c byte) bool {
+	switch c {
+	case '(', ')', '<', '>', '[', ']', '{', '}', '/', '%':
+		return true
+	}
+	return false
+}
@@ -0,0 +1,463 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/n30w/Darkspace/internal/models"
+)
+
+type PeerReviewStore interface {
+	DueDateStore
+	AttemptStore
+	NotificationStore
+
+	GetAssignmentById(assignmentid string) (*models.Assignment, error)
+	GetAssignmentPoints(assignmentId string) (float64, error)
+	SavePeerReviewPolicy(p *models.PeerReviewPolicy) error
+	SetReviewersAllocated(assignmentId string, at time.Time) error
+	GetAssignmentsDueForAllocation(now time.Time) ([]string, error)
+
+	InsertPeerReviews(reviews []*models.PeerReview) error
+	GetPeerReviewById(id string) (*models.PeerReview, error)
+	GetPeerReviews(assignmentId, submissionId, reviewer string) ([]*models.PeerReview, error)
+	SavePeerReview(r *models.PeerReview) error
+	GetPeerReviewScores(reviewId string) ([]*models.CriterionScore, error)
+
+	GetRubricById(id string) (*models.Rubric, error)
+	GetSubmissionById(submissionId string) (*models.Submission, error)
+	GetSubmissionFiles(submissionId string) ([]*models.Media, error)
+	SavePeerScore(sub *models.Submission) error
+	UpdateSubmissionGrade(sub *models.Submission) error
+
+	IsCourseTeacher(courseId, netId string) (bool, error)
+}
+
+type PeerReviewService struct {
+	store PeerReviewStore
+
+	// now is the clock that reviews are timed by.
+	now func() time.Time
+}
+
+func NewPeerReviewService(s PeerReviewStore) *PeerReviewService {
+	return &PeerReviewService{store: s, now: time.Now}
+}
+
+// SetPolicy sets how an assignment is peer reviewed. A new weight
+// regrades the submissions that already have a peer score. Only the
+// course's teachers may change the policy.
+func (ps *PeerReviewService) SetPolicy(
+	p *models.PeerReviewPolicy,
+	netId string,
+) (*models.Assignment, error) {
+	a, err := teacherAssignment(ps.store, p.AssignmentId, netId)
+	if err != nil {
+		return nil, err
+	}
+
+	if a.PeerReview != nil {
+		p.AllocatedAt = a.PeerReview.AllocatedAt
+	}
+
+	err = ps.store.SavePeerReviewPolicy(p)
+	if err != nil {
+		return nil, err
+	}
+
+	a.PeerReview = p
+
+	attempts, err := ps.store.GetAttempts(a.ID, "")
+	if err != nil {
+		return nil, err
+	}
+
+	for _, sub := range attempts {
+		if sub.PeerScore == nil {
+			continue
+		}
+
+		err = ps.regrade(a, sub)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return a, nil
+}
+
+// Allocate allocates reviewers to the submissions of an assignment
+// that is due, without waiting for them to be allocated on schedule.
+// Only the course's teachers may allocate reviewers.
+func (ps *PeerReviewService) Allocate(assignmentId, netId string) (
+	[]*models.PeerReview,
+	error,
+) {
+	a, err := teacherAssignment(ps.store, assignmentId, netId)
+	if err != nil {
+		return nil, err
+	}
+
+	if a.PeerReview == nil {
+		return nil, ERR_NO_PEER_REVIEW
+	}
+
+	if a.PeerReview.AllocatedAt != nil {
+		return nil, ERR_ALREADY_ALLOCATED
+	}
+
+	now := ps.now()
+
+	if now.Before(a.DueDate) {
+		return nil, ERR_NOT_DUE
+	}
+
+	return ps.allocate(a, now)
+}
+
+// AllocateDue allocates reviewers for every peer reviewed assignment
+// that is due. Assignments with too few submissions are left until
+// more are made. It returns how many assignments had reviewers
+// allocated.
+func (ps *PeerReviewService) AllocateDue(now time.Time) (int, error) {
+	ids, err := ps.store.GetAssignmentsDueForAllocation(now)
+	if err != nil {
+		return 0, err
+	}
+
+	allocated := 0
+
+	for _, id := range ids {
+		a, err := ps.store.GetAssignmentById(id)
+		if err != nil {
+			return allocated, err
+		}
+
+		_, err = ps.allocate(a, now)
+		if errors.Is(err, ERR_TOO_FEW_SUBMISSIONS) {
+			continue
+		}
+		if err != nil {
+			return allocated, err
+		}
+
+		allocated++
+	}
+
+	return allocated, nil
+}
+
+// allocate gives each submission that counts the assignment's number
+// of reviewers, and notifies the reviewers.
+func (ps *PeerReviewService) allocate(a *models.Assignment, now time.Time) (
+	[]*models.PeerReview,
+	error,
+) {
+	attempts, err := ps.store.GetAttempts(a.ID, "")
+	if err != nil {
+		return nil, err
+	}
+
+	var counted []*models.Submission
+	for _, sub := range attempts {
+		if sub.Counts {
+			counted = append(counted, sub)
+		}
+	}
+
+	if len(counted) < 2 {
+		return nil, ERR_TOO_FEW_SUBMISSIONS
+	}
+
+	reviews := models.AllocateReviewers(
+		counted,
+		a.PeerReview.Reviewers,
+		rand.Int63(),
+	)
+
+	err = ps.store.InsertPeerReviews(reviews)
+	if err != nil {
+		return nil, err
+	}
+
+	err = ps.store.SetReviewersAllocated(a.ID, now)
+	if err != nil {
+		return nil, err
+	}
+
+	reviewers := make([]string, len(counted))
+	for i, sub := range counted {
+		reviewers[i] = sub.User.ID
+	}
+
+	err = notify(
+		ps.store,
+		reviewers,
+		models.PEER_REVIEWS_ALLOCATED,
+		fmt.Sprintf("You have peer reviews to do for %s", a.Title),
+		a.ID,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return reviews, nil
+}
+
+// ReviewsToDo retrieves the reviews a student was allocated for an
+// assignment, along with the submissions to review. Submissions are
+// shown without their author or their grade.
+func (ps *PeerReviewService) ReviewsToDo(assignmentId, netId string) (
+	[]*models.PeerReview,
+	error,
+) {
+	if netId == "" {
+		return nil, ERR_NOT_PERMITTED
+	}
+
+	reviews, err := ps.store.GetPeerReviews(assignmentId, "", netId)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, r := range reviews {
+		r.Rubric, err = ps.store.GetPeerReviewScores(r.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		sub, err := ps.store.GetSubmissionById(r.SubmissionId)
+		if err != nil {
+			return nil, err
+		}
+
+		sub.Files, err = ps.store.GetSubmissionFiles(sub.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		sub.User = models.User{}
+		sub.HideGrade()
+		sub.PeerScore = nil
+
+		r.Submission = sub
+	}
+
+	return reviews, nil
+}
+
+// SubmitReview fills in one of a reviewer's reviews. Assignments with
+// a rubric are scored against it, and the rest are given a score
+// directly. Reviews can be changed until grades are released, and
+// each change updates the submission's peer score.
+func (ps *PeerReviewService) SubmitReview(
+	r *models.PeerReview,
+	netId string,
+) (*models.PeerReview, error) {
+	review, err := ps.store.GetPeerReviewById(r.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if netId == "" || review.Reviewer != netId {
+		return nil, ERR_NOT_PERMITTED
+	}
+
+	a, err := ps.store.GetAssignmentById(review.AssignmentId)
+	if err != nil {
+		return nil, err
+	}
+
+	if a.GradesReleased {
+		return nil, ERR_GRADES_RELEASED
+	}
+
+	if a.RubricId != "" {
+		rubric, err := ps.store.GetRubricById(a.RubricId)
+		if err != nil {
+			return nil, err
+		}
+
+		total, err := scoreRubric(rubric, r.Rubric)
+		if err != nil {
+			return nil, err
+		}
+
+		review.Score = &total
+		review.Rubric = r.Rubric
+	} else {
+		if r.Score == nil {
+			return nil, ERR_NO_SCORE
+		}
+
+		points, err := ps.store.GetAssignmentPoints(a.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		if *r.Score < 0 || *r.Score > points {
+			return nil, ERR_SCORE_OUT_OF_RANGE
+		}
+
+		review.Score = r.Score
+		review.Rubric = nil
+	}
+
+	now := ps.now()
+
+	review.Comments = r.Comments
+	review.SubmittedAt = &now
+
+	err = ps.store.SavePeerReview(review)
+	if err != nil {
+		return nil, err
+	}
+
+	err = ps.rescore(a, review.SubmissionId)
+	if err != nil {
+		return nil, err
+	}
+
+	return review, nil
+}
+
+// AssignmentReviews retrieves every review of an assignment's
+// submissions, along with who wrote them. While the assignment is
+// graded blind, reviewers are given by their pseudonyms, since knowing
+// who reviewed a submission tells who did not write it. Only the
+// course's teachers may see them.
+func (ps *PeerReviewService) AssignmentReviews(assignmentId, netId string) (
+	[]*models.PeerReview,
+	error,
+) {
+	a, err := teacherAssignment(ps.store, assignmentId, netId)
+	if err != nil {
+		return nil, err
+	}
+
+	reviews, err := ps.store.GetPeerReviews(a.ID, "", "")
+	if err != nil {
+		return nil, err
+	}
+
+	for _, r := range reviews {
+		r.Rubric, err = ps.store.GetPeerReviewScores(r.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		if a.Blind() {
+			r.Reviewer = models.Pseudonym(a.AnonymousKey, r.Reviewer)
+		}
+	}
+
+	return reviews, nil
+}
+
+// ReceivedReviews retrieves the finished reviews of a student's
+// submission to an assignment, without who wrote them. Scores are left
+// out until grades are released.
+func (ps *PeerReviewService) ReceivedReviews(assignmentId, netId string) (
+	[]*models.PeerReview,
+	error,
+) {
+	if netId == "" {
+		return nil, ERR_NOT_PERMITTED
+	}
+
+	a, err := ps.store.GetAssignmentById(assignmentId)
+	if err != nil {
+		return nil, err
+	}
+
+	attempts, err := ps.store.GetAttempts(a.ID, netId)
+	if err != nil {
+		return nil, err
+	}
+
+	counted := models.CountedAttempt(attempts, a.CountedAttempt)
+	if counted == nil {
+		return []*models.PeerReview{}, nil
+	}
+
+	reviews, err := ps.store.GetPeerReviews(a.ID, counted.ID, "")
+	if err != nil {
+		return nil, err
+	}
+
+	received := []*models.PeerReview{}
+
+	for _, r := range reviews {
+		if !r.Done() {
+			continue
+		}
+
+		r.Reviewer = ""
+
+		if a.GradesReleased {
+			r.Rubric, err = ps.store.GetPeerReviewScores(r.ID)
+			if err != nil {
+				return nil, err
+			}
+		} else {
+			r.Score = nil
+		}
+
+		received = append(received, r)
+	}
+
+	return received, nil
+}
+
+// rescore updates a submission's peer score from its finished reviews.
+func (ps *PeerReviewService) rescore(
+	a *models.Assignment,
+	submissionId string,
+) error {
+	reviews, err := ps.store.GetPeerReviews(a.ID, submissionId, "")
+	if err != nil {
+		return err
+	}
+
+	sub, err := ps.store.GetSubmissionById(submissionId)
+	if err != nil {
+		return err
+	}
+
+	sub.PeerScore = models.PeerScore(reviews)
+
+	err = ps.store.SavePeerScore(sub)
+	if err != nil {
+		return err
+	}
+
+	return ps.regrade(a, sub)
+}
+
+// regrade blends a submission's peer score into its grade. Submissions
+// are only graded by their peers alone when the peer score is all
+// that counts, and otherwise wait for a teacher's grade.
+func (ps *PeerReviewService) regrade(
+	a *models.Assignment,
+	sub *models.Submission,
+) error {
+	if !sub.Graded && a.PeerReview.Weight < 100 {
+		return nil
+	}
+
+	due, err := studentAssignment(ps.store, a, sub.User.ID)
+	if err != nil {
+		return err
+	}
+
+	sub.SetGrade(sub.RawGrade, due)
+
+	err = ps.store.UpdateSubmissionGrade(sub)
+	if err != nil {
+		return err
+	}
+
+	return recountAttempts(ps.store, a, sub.User.ID)
+}
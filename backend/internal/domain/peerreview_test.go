@@ -0,0 +1,345 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/n30w/Darkspace/internal/dal"
+	"github.com/n30w/Darkspace/internal/models"
+)
+
+func TestPeerReviewService_Allocate(t *testing.T) {
+	due := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	store := newMockPeerReviewStore(due)
+	ps := NewPeerReviewService(store)
+
+	tests := []struct {
+		name  string
+		netId string
+		now   time.Time
+		want  error
+	}{
+		{name: "student", netId: "stu1", now: due, want: ERR_NOT_PERMITTED},
+		{name: "before the due date", netId: "prof", now: due.Add(-time.Hour), want: ERR_NOT_DUE},
+		{name: "once due", netId: "prof", now: due},
+		{name: "again", netId: "prof", now: due, want: ERR_ALREADY_ALLOCATED},
+	}
+
+	for _, tt := range tests {
+		ps.now = func() time.Time { return tt.now }
+
+		reviews, err := ps.Allocate("a1", tt.netId)
+		if !errors.Is(err, tt.want) {
+			t.Fatalf("%s: got %v, want %v", tt.name, err, tt.want)
+		}
+
+		if tt.want != nil {
+			continue
+		}
+
+		// Three submissions with two reviewers each.
+		if len(reviews) != 6 {
+			t.Errorf("%s: got %d reviews, want 6", tt.name, len(reviews))
+		}
+
+		if len(store.notifications) != 3 {
+			t.Errorf("%s: got %d notifications, want one per reviewer", tt.name, len(store.notifications))
+		}
+	}
+}
+
+func TestPeerReviewService_SubmitReview(t *testing.T) {
+	due := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	store := newMockPeerReviewStore(due)
+	store.submissions["s1"].SetGrade(90, store.assignment)
+	store.reviews = []*models.PeerReview{
+		{Entity: models.Entity{ID: "r1"}, AssignmentId: "a1", SubmissionId: "s1", Reviewer: "stu2"},
+		{Entity: models.Entity{ID: "r2"}, AssignmentId: "a1", SubmissionId: "s1", Reviewer: "stu3"},
+	}
+
+	ps := NewPeerReviewService(store)
+	ps.now = func() time.Time { return due.Add(time.Hour) }
+
+	score := func(f float64) *float64 { return &f }
+
+	tests := []struct {
+		name   string
+		review string
+		netId  string
+		score  *float64
+		want   error
+		grade  float64
+	}{
+		{name: "someone else's review", review: "r1", netId: "stu3", score: score(60), want: ERR_NOT_PERMITTED, grade: 90},
+		{name: "without a score", review: "r1", netId: "stu2", want: ERR_NO_SCORE, grade: 90},
+		{name: "negative score", review: "r1", netId: "stu2", score: score(-1), want: ERR_SCORE_OUT_OF_RANGE, grade: 90},
+		{name: "score above the points", review: "r1", netId: "stu2", score: score(101), want: ERR_SCORE_OUT_OF_RANGE, grade: 90},
+		{name: "first review", review: "r1", netId: "stu2", score: score(60), grade: 75},
+		{name: "second review", review: "r2", netId: "stu3", score: score(80), grade: 80},
+	}
+
+	for _, tt := range tests {
+		r := &models.PeerReview{Entity: models.Entity{ID: tt.review}, Score: tt.score}
+
+		_, err := ps.SubmitReview(r, tt.netId)
+		if !errors.Is(err, tt.want) {
+			t.Fatalf("%s: got %v, want %v", tt.name, err, tt.want)
+		}
+
+		// Half of the grade comes from the average peer score.
+		if got := store.submissions["s1"].Grade; got != tt.grade {
+			t.Errorf("%s: got grade %v, want %v", tt.name, got, tt.grade)
+		}
+	}
+
+	received, err := ps.ReceivedReviews("a1", "stu1")
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+
+	if len(received) != 2 {
+		t.Fatalf("got %d reviews, want 2", len(received))
+	}
+
+	for _, r := range received {
+		if r.Reviewer != "" || r.Score != nil {
+			t.Errorf("got reviewer %q and score %v, want both hidden before release", r.Reviewer, r.Score)
+		}
+	}
+}
+
+func TestPeerReviewService_AssignmentReviews(t *testing.T) {
+	due := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		anonymous bool
+		released  bool
+		blind     bool
+	}{
+		{name: "named", anonymous: false},
+		{name: "blind", anonymous: true, blind: true},
+		{name: "released", anonymous: true, released: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				store := newMockPeerReviewStore(due)
+				store.assignment.Anonymous = tt.anonymous
+				store.assignment.AnonymousKey = "key"
+				store.assignment.GradesReleased = tt.released
+				store.reviews = []*models.PeerReview{
+					{Entity: models.Entity{ID: "r1"}, AssignmentId: "a1", SubmissionId: "s1", Reviewer: "stu2"},
+				}
+
+				ps := NewPeerReviewService(store)
+
+				_, err := ps.AssignmentReviews("a1", "stu1")
+				if !errors.Is(err, ERR_NOT_PERMITTED) {
+					t.Fatalf("got %v, want %v", err, ERR_NOT_PERMITTED)
+				}
+
+				reviews, err := ps.AssignmentReviews("a1", "prof")
+				if err != nil {
+					t.Fatalf("%+v", err)
+				}
+
+				want := "stu2"
+				if tt.blind {
+					want = models.Pseudonym("key", "stu2")
+				}
+
+				if len(reviews) != 1 || reviews[0].Reviewer != want {
+					t.Errorf("got %+v, want reviewer %s", reviews, want)
+				}
+			},
+		)
+	}
+}
+
+// ========= //
+//   MOCKS   //
+// ========= //
+
+// mockPeerReviewStore has a single assignment "a1" out of 100 points,
+// in course "c1" taught by "prof", reviewed by two students per
+// submission. Students "stu1" to "stu3" made submissions "s1" to "s3",
+// which all count.
+type mockPeerReviewStore struct {
+	PeerReviewStore
+
+	assignment    *models.Assignment
+	submissions   map[string]*models.Submission
+	reviews       []*models.PeerReview
+	notifications []*models.Notification
+}
+
+func newMockPeerReviewStore(due time.Time) *mockPeerReviewStore {
+	m := &mockPeerReviewStore{
+		assignment: &models.Assignment{
+			Post:       models.Post{Entity: models.Entity{ID: "a1"}},
+			DueDate:    due,
+			PeerReview: &models.PeerReviewPolicy{AssignmentId: "a1", Reviewers: 2, Weight: 50},
+		},
+		submissions: make(map[string]*models.Submission),
+	}
+
+	for i := 1; i <= 3; i++ {
+		sub := models.NewSubmission()
+		sub.ID = fmt.Sprintf("s%d", i)
+		sub.AssignmentId = "a1"
+		sub.User.ID = fmt.Sprintf("stu%d", i)
+		sub.SubmissionTime = due
+		m.submissions[sub.ID] = sub
+	}
+
+	return m
+}
+
+func (m *mockPeerReviewStore) GetAssignmentById(assignmentid string) (
+	*models.Assignment,
+	error,
+) {
+	return m.assignment, nil
+}
+
+func (m *mockPeerReviewStore) GetAssignmentPoints(assignmentId string) (
+	float64,
+	error,
+) {
+	return 100, nil
+}
+
+func (m *mockPeerReviewStore) GetCourseIdByAssignment(assignmentId string) (
+	string,
+	error,
+) {
+	return "c1", nil
+}
+
+func (m *mockPeerReviewStore) IsCourseTeacher(courseId, netId string) (
+	bool,
+	error,
+) {
+	return netId == "prof", nil
+}
+
+func (m *mockPeerReviewStore) GetExtension(assignmentId, netId string) (
+	*models.Extension,
+	error,
+) {
+	return nil, nil
+}
+
+func (m *mockPeerReviewStore) GetAccommodation(courseId, netId string) (
+	*models.Accommodation,
+	error,
+) {
+	return nil, nil
+}
+
+func (m *mockPeerReviewStore) GetAttempts(assignmentId, netId string) (
+	[]*models.Submission,
+	error,
+) {
+	var attempts []*models.Submission
+	for i := 1; i <= len(m.submissions); i++ {
+		sub := m.submissions[fmt.Sprintf("s%d", i)]
+		if netId == "" || sub.User.ID == netId {
+			attempts = append(attempts, sub)
+		}
+	}
+	return attempts, nil
+}
+
+func (m *mockPeerReviewStore) SetCountedAttempt(
+	assignmentId, netId, submissionId string,
+) error {
+	return nil
+}
+
+func (m *mockPeerReviewStore) InsertPeerReviews(reviews []*models.PeerReview) error {
+	for _, r := range reviews {
+		r.ID = fmt.Sprintf("r%d", len(m.reviews)+1)
+		m.reviews = append(m.reviews, r)
+	}
+	return nil
+}
+
+func (m *mockPeerReviewStore) SetReviewersAllocated(
+	assignmentId string,
+	at time.Time,
+) error {
+	m.assignment.PeerReview.AllocatedAt = &at
+	return nil
+}
+
+func (m *mockPeerReviewStore) InsertNotification(n *models.Notification) error {
+	m.notifications = append(m.notifications, n)
+	return nil
+}
+
+func (m *mockPeerReviewStore) GetPeerReviewById(id string) (
+	*models.PeerReview,
+	error,
+) {
+	for _, r := range m.reviews {
+		if r.ID == id {
+			copied := *r
+			return &copied, nil
+		}
+	}
+	return nil, dal.ERR_RECORD_NOT_FOUND
+}
+
+func (m *mockPeerReviewStore) GetPeerReviews(
+	assignmentId, submissionId, reviewer string,
+) ([]*models.PeerReview, error) {
+	var reviews []*models.PeerReview
+	for _, r := range m.reviews {
+		if (submissionId == "" || r.SubmissionId == submissionId) &&
+			(reviewer == "" || r.Reviewer == reviewer) {
+			copied := *r
+			reviews = append(reviews, &copied)
+		}
+	}
+	return reviews, nil
+}
+
+func (m *mockPeerReviewStore) SavePeerReview(r *models.PeerReview) error {
+	for i, saved := range m.reviews {
+		if saved.ID == r.ID {
+			m.reviews[i] = r
+		}
+	}
+	return nil
+}
+
+func (m *mockPeerReviewStore) GetPeerReviewScores(reviewId string) (
+	[]*models.CriterionScore,
+	error,
+) {
+	return nil, nil
+}
+
+func (m *mockPeerReviewStore) GetSubmissionById(submissionId string) (
+	*models.Submission,
+	error,
+) {
+	return m.submissions[submissionId], nil
+}
+
+func (m *mockPeerReviewStore) SavePeerScore(sub *models.Submission) error {
+	m.submissions[sub.ID].PeerScore = sub.PeerScore
+	return nil
+}
+
+func (m *mockPeerReviewStore) UpdateSubmissionGrade(sub *models.Submission) error {
+	m.submissions[sub.ID] = sub
+	return nil
+}
@@ -0,0 +1,551 @@
+package domain
+
+import (
+	"errors"
+	"time"
+
+	"github.com/n30w/Darkspace/internal/dal"
+	"github.com/n30w/Darkspace/internal/models"
+)
+
+type ProjectStore interface {
+	DueDateStore
+
+	InsertProject(p *models.Project) error
+	GetProjectById(id string) (*models.Project, error)
+	GetProjectsByCourse(courseId string) ([]*models.Project, error)
+	DeleteProject(id string) error
+
+	InsertTeam(t *models.Team) error
+	GetTeamById(id string) (*models.Team, error)
+	GetTeamsByProject(projectId string) ([]*models.Team, error)
+	GetTeamIdByMember(projectId, netId string) (string, error)
+	InsertTeamMember(t *models.Team, netId string) error
+	DeleteTeamMember(teamId, netId string) error
+	UpdateTeamSubmission(t *models.Team) error
+	GetTeamMedia(teamId string) ([]*models.Media, error)
+
+	IsCourseTeacher(courseId, netId string) (bool, error)
+	IsCourseStudent(courseId, netId string) (bool, error)
+
+	InsertComment(c *models.Comment) error
+	GetCommentById(id string) (*models.Comment, error)
+	GetComments(messageId string, includeHidden bool, f models.Filters) (
+		[]*models.Comment,
+		models.Metadata,
+		error,
+	)
+
+	GetAssignmentById(assignmentId string) (*models.Assignment, error)
+	GetSubmissionById(submissionId string) (*models.Submission, error)
+	GetSubmissionIdByUserAndAssignment(netId string, assignmentId string) (string, error)
+	InsertSubmission(sub *models.Submission) (*models.Submission, error)
+	InsertSubmissionIntoAssignment(sub *models.Submission) (*models.Submission, error)
+	InsertSubmissionIntoUser(sub *models.Submission) (*models.Submission, error)
+	UpdateSubmissionGrade(sub *models.Submission) error
+}
+
+type ProjectService struct {
+	store ProjectStore
+}
+
+func NewProjectService(s ProjectStore) *ProjectService {
+	return &ProjectService{store: s}
+}
+
+// CreateProject creates a project in a course. Only the course's
+// teachers may create projects.
+func (ps *ProjectService) CreateProject(p *models.Project) (
+	*models.Project,
+	error,
+) {
+	err := teacherOnly(ps.store, p.CourseId, p.Owner)
+	if err != nil {
+		return nil, err
+	}
+
+	err = ps.store.InsertProject(p)
+	if err != nil {
+		return nil, err
+	}
+
+	p.Teams = []*models.Team{}
+
+	return p, nil
+}
+
+// ListProjects retrieves a course's projects along with their teams.
+func (ps *ProjectService) ListProjects(courseId string) (
+	[]*models.Project,
+	error,
+) {
+	projects, err := ps.store.GetProjectsByCourse(courseId)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, p := range projects {
+		p.Teams, err = ps.store.GetTeamsByProject(p.ID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return projects, nil
+}
+
+// ReadProject retrieves a project along with its teams.
+func (ps *ProjectService) ReadProject(projectId string) (
+	*models.Project,
+	error,
+) {
+	p, err := ps.store.GetProjectById(projectId)
+	if err != nil {
+		return nil, err
+	}
+
+	p.Teams, err = ps.store.GetTeamsByProject(p.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// DeleteProject deletes a project and its teams. Submissions the
+// teams made are kept. Only the course's teachers may delete projects.
+func (ps *ProjectService) DeleteProject(projectId, netId string) error {
+	p, err := ps.store.GetProjectById(projectId)
+	if err != nil {
+		return err
+	}
+
+	err = teacherOnly(ps.store, p.CourseId, netId)
+	if err != nil {
+		return err
+	}
+
+	return ps.store.DeleteProject(p.ID)
+}
+
+// CreateTeam adds an empty team to a project. Only the course's
+// teachers may create teams.
+func (ps *ProjectService) CreateTeam(projectId, name, netId string) (
+	*models.Team,
+	error,
+) {
+	p, err := ps.store.GetProjectById(projectId)
+	if err != nil {
+		return nil, err
+	}
+
+	err = teacherOnly(ps.store, p.CourseId, netId)
+	if err != nil {
+		return nil, err
+	}
+
+	t := &models.Team{
+		ProjectId: p.ID,
+		Name:      name,
+		Members:   []string{},
+	}
+
+	err = ps.store.InsertTeam(t)
+	if err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+// AssignMember puts a student on a team. Only the course's teachers
+// may assign students.
+func (ps *ProjectService) AssignMember(teamId, member, netId string) (
+	*models.Team,
+	error,
+) {
+	p, t, err := ps.team(teamId)
+	if err != nil {
+		return nil, err
+	}
+
+	err = teacherOnly(ps.store, p.CourseId, netId)
+	if err != nil {
+		return nil, err
+	}
+
+	return ps.addMember(p, t, member)
+}
+
+// Join puts a student on a team of a project that allows students to
+// enroll themselves.
+func (ps *ProjectService) Join(teamId, netId string) (*models.Team, error) {
+	p, t, err := ps.team(teamId)
+	if err != nil {
+		return nil, err
+	}
+
+	if !p.SelfEnroll {
+		return nil, ERR_NOT_PERMITTED
+	}
+
+	return ps.addMember(p, t, netId)
+}
+
+// RemoveMember takes a student off a team. Teachers may remove anyone,
+// and students may leave a team of a project that allows students to
+// enroll themselves.
+func (ps *ProjectService) RemoveMember(teamId, member, netId string) error {
+	p, t, err := ps.team(teamId)
+	if err != nil {
+		return err
+	}
+
+	if member != netId || !p.SelfEnroll {
+		err = teacherOnly(ps.store, p.CourseId, netId)
+		if err != nil {
+			return err
+		}
+	}
+
+	if !t.HasMember(member) {
+		return dal.ERR_RECORD_NOT_FOUND
+	}
+
+	return ps.store.DeleteTeamMember(t.ID, member)
+}
+
+// AccessTeam retrieves a team that netId may see, which is any team
+// they are on or any team of a course they teach.
+func (ps *ProjectService) AccessTeam(teamId, netId string) (
+	*models.Team,
+	error,
+) {
+	_, t, err := ps.memberOrTeacher(teamId, netId)
+	if err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+// ReadTeamDiscussion retrieves a page of the comments on a team's
+// discussion, each with its replies nested beneath it. Only the team
+// and the course's teachers may read it.
+func (ps *ProjectService) ReadTeamDiscussion(
+	teamId, netId string,
+	f models.Filters,
+) ([]*models.Comment, models.Metadata, error) {
+	_, t, err := ps.memberOrTeacher(teamId, netId)
+	if err != nil {
+		return nil, models.Metadata{}, err
+	}
+
+	comments, metadata, err := ps.store.GetComments(t.DiscussionId, false, f)
+	if err != nil {
+		return nil, models.Metadata{}, err
+	}
+
+	return buildCommentTree(comments), metadata, nil
+}
+
+// AddTeamComment comments on a team's discussion, or replies to one
+// of its comments when parentId is set.
+func (ps *ProjectService) AddTeamComment(
+	teamId, parentId, netId, body string,
+) (*models.Comment, error) {
+	_, t, err := ps.memberOrTeacher(teamId, netId)
+	if err != nil {
+		return nil, err
+	}
+
+	if parentId != "" {
+		parent, err := ps.store.GetCommentById(parentId)
+		if err != nil {
+			return nil, err
+		}
+
+		if parent.MessageId != t.DiscussionId {
+			return nil, dal.ERR_RECORD_NOT_FOUND
+		}
+
+		if parent.Deleted {
+			return nil, ERR_COMMENT_DELETED
+		}
+	}
+
+	c := models.NewComment(t.DiscussionId, parentId, body, netId)
+
+	err = ps.store.InsertComment(c)
+	if err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// TeamMedia retrieves the files shared by a team.
+func (ps *ProjectService) TeamMedia(teamId, netId string) (
+	[]*models.Media,
+	error,
+) {
+	_, t, err := ps.memberOrTeacher(teamId, netId)
+	if err != nil {
+		return nil, err
+	}
+
+	return ps.store.GetTeamMedia(t.ID)
+}
+
+// Submit creates the team's submission for the project's assignment,
+// made by one of its members. A team has a single submission, so if
+// it already has one that is returned instead. Files are uploaded to
+// the submission like any other. ERR_NOT_AVAILABLE is returned before
+// the assignment takes submissions, and ERR_SUBMISSION_CLOSED when it
+// is locked or its late policy no longer accepts submissions.
+func (ps *ProjectService) Submit(teamId, netId string) (
+	*models.Submission,
+	error,
+) {
+	p, t, err := ps.team(teamId)
+	if err != nil {
+		return nil, err
+	}
+
+	if !t.HasMember(netId) {
+		return nil, ERR_NOT_PERMITTED
+	}
+
+	if p.AssignmentId == "" {
+		return nil, ERR_PROJECT_UNGRADED
+	}
+
+	if t.SubmissionId != "" {
+		return ps.store.GetSubmissionById(t.SubmissionId)
+	}
+
+	assignment, err := ps.store.GetAssignmentById(p.AssignmentId)
+	if err != nil {
+		return nil, err
+	}
+
+	// The team's submission is timed against the due date of the
+	// member who makes it.
+	assignment, err = studentAssignment(ps.store, assignment, netId)
+	if err != nil {
+		return nil, err
+	}
+
+	sub := models.NewSubmission()
+	sub.AssignmentId = p.AssignmentId
+	sub.User.ID = netId
+
+	err = stampSubmission(sub, assignment, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	sub, err = ps.insertSubmission(sub)
+	if err != nil {
+		return nil, err
+	}
+
+	t.SubmissionId = sub.ID
+
+	err = ps.store.UpdateTeamSubmission(t)
+	if err != nil {
+		return nil, err
+	}
+
+	return sub, nil
+}
+
+// GradeTeam grades a team once, applying the grade and feedback to
+// the project assignment's submission of every member. Members who
+// have no submission of their own are given one, copied from the
+// team's submission if there is one. Each member's late penalty comes
+// from when their submission was made. Only the course's teachers may
+// grade. The members' graded submissions are returned.
+func (ps *ProjectService) GradeTeam(
+	teamId, netId string,
+	grade float64,
+	feedback string,
+) ([]*models.Submission, error) {
+	p, t, err := ps.team(teamId)
+	if err != nil {
+		return nil, err
+	}
+
+	err = teacherOnly(ps.store, p.CourseId, netId)
+	if err != nil {
+		return nil, err
+	}
+
+	if p.AssignmentId == "" {
+		return nil, ERR_PROJECT_UNGRADED
+	}
+
+	assignment, err := ps.store.GetAssignmentById(p.AssignmentId)
+	if err != nil {
+		return nil, err
+	}
+
+	var teamSubmission *models.Submission
+
+	if t.SubmissionId != "" {
+		teamSubmission, err = ps.store.GetSubmissionById(t.SubmissionId)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	graded := make([]*models.Submission, 0, len(t.Members))
+
+	for _, member := range t.Members {
+		sub, err := ps.memberSubmission(p.AssignmentId, member, teamSubmission)
+		if err != nil {
+			return nil, err
+		}
+
+		due, err := studentAssignment(ps.store, assignment, member)
+		if err != nil {
+			return nil, err
+		}
+
+		sub.SetGrade(grade, due)
+		sub.Feedback = feedback
+
+		err = ps.store.UpdateSubmissionGrade(sub)
+		if err != nil {
+			return nil, err
+		}
+
+		graded = append(graded, sub)
+	}
+
+	return graded, nil
+}
+
+// memberSubmission retrieves a member's submission for an
+// assignment, creating one if they have none.
+func (ps *ProjectService) memberSubmission(
+	assignmentId, netId string,
+	teamSubmission *models.Submission,
+) (*models.Submission, error) {
+	id, err := ps.store.GetSubmissionIdByUserAndAssignment(netId, assignmentId)
+	if err != nil {
+		return nil, err
+	}
+
+	if id != "" {
+		return ps.store.GetSubmissionById(id)
+	}
+
+	sub := models.NewSubmission()
+	sub.AssignmentId = assignmentId
+	sub.User.ID = netId
+
+	if teamSubmission != nil {
+		sub.SubmissionTime = teamSubmission.SubmissionTime
+		sub.OnTime = teamSubmission.OnTime
+	}
+
+	return ps.insertSubmission(sub)
+}
+
+func (ps *ProjectService) insertSubmission(sub *models.Submission) (
+	*models.Submission,
+	error,
+) {
+	sub, err := ps.store.InsertSubmission(sub)
+	if err != nil {
+		return nil, err
+	}
+
+	sub, err = ps.store.InsertSubmissionIntoAssignment(sub)
+	if err != nil {
+		return nil, err
+	}
+
+	return ps.store.InsertSubmissionIntoUser(sub)
+}
+
+// addMember puts a student of the project's course on a team, as long
+// as the team has room and they are not on another team already.
+func (ps *ProjectService) addMember(
+	p *models.Project,
+	t *models.Team,
+	netId string,
+) (*models.Team, error) {
+	student, err := ps.store.IsCourseStudent(p.CourseId, netId)
+	if err != nil {
+		return nil, err
+	}
+
+	if !student {
+		return nil, ERR_NOT_ENROLLED
+	}
+
+	_, err = ps.store.GetTeamIdByMember(p.ID, netId)
+	if err == nil {
+		return nil, ERR_ALREADY_ON_TEAM
+	}
+
+	if !errors.Is(err, dal.ERR_RECORD_NOT_FOUND) {
+		return nil, err
+	}
+
+	if len(t.Members) >= p.TeamSize {
+		return nil, ERR_TEAM_FULL
+	}
+
+	err = ps.store.InsertTeamMember(t, netId)
+	if err != nil {
+		return nil, err
+	}
+
+	t.Members = append(t.Members, netId)
+
+	return t, nil
+}
+
+// team retrieves a team along with the project it belongs to.
+func (ps *ProjectService) team(teamId string) (
+	*models.Project,
+	*models.Team,
+	error,
+) {
+	t, err := ps.store.GetTeamById(teamId)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	p, err := ps.store.GetProjectById(t.ProjectId)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return p, t, nil
+}
+
+func (ps *ProjectService) memberOrTeacher(teamId, netId string) (
+	*models.Project,
+	*models.Team,
+	error,
+) {
+	p, t, err := ps.team(teamId)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if t.HasMember(netId) {
+		return p, t, nil
+	}
+
+	err = teacherOnly(ps.store, p.CourseId, netId)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return p, t, nil
+}
@@ -0,0 +1,462 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/n30w/Darkspace/internal/dal"
+	"github.com/n30w/Darkspace/internal/models"
+)
+
+func TestProjectService_Join(t *testing.T) {
+	tests := []struct {
+		name       string
+		selfEnroll bool
+		teamId     string
+		netId      string
+		want       error
+	}{
+		{
+			name:       "joins a team with room",
+			selfEnroll: true,
+			teamId:     "t1",
+			netId:      "stu3",
+		},
+		{
+			name:       "team is full",
+			selfEnroll: true,
+			teamId:     "t2",
+			netId:      "stu3",
+			want:       ERR_TEAM_FULL,
+		},
+		{
+			name:       "already on another team",
+			selfEnroll: true,
+			teamId:     "t2",
+			netId:      "stu1",
+			want:       ERR_ALREADY_ON_TEAM,
+		},
+		{
+			name:       "not on the course roster",
+			selfEnroll: true,
+			teamId:     "t1",
+			netId:      "outsider",
+			want:       ERR_NOT_ENROLLED,
+		},
+		{
+			name:   "self enrollment disabled",
+			teamId: "t1",
+			netId:  "stu3",
+			want:   ERR_NOT_PERMITTED,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				store := newMockProjectStore()
+				store.projects["p1"].SelfEnroll = tt.selfEnroll
+
+				team, err := NewProjectService(store).Join(tt.teamId, tt.netId)
+
+				if !errors.Is(err, tt.want) {
+					t.Fatalf("got %v, want %v", err, tt.want)
+				}
+
+				if tt.want == nil && !team.HasMember(tt.netId) {
+					t.Errorf("got members %v, want %s", team.Members, tt.netId)
+				}
+			},
+		)
+	}
+}
+
+func TestProjectService_AssignMember(t *testing.T) {
+	store := newMockProjectStore()
+	ps := NewProjectService(store)
+
+	_, err := ps.AssignMember("t1", "stu3", "stu1")
+	if !errors.Is(err, ERR_NOT_PERMITTED) {
+		t.Errorf("got %v, want %v for a student", err, ERR_NOT_PERMITTED)
+	}
+
+	team, err := ps.AssignMember("t1", "stu3", "prof")
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+
+	if !team.HasMember("stu3") {
+		t.Errorf("got members %v, want stu3", team.Members)
+	}
+}
+
+func TestProjectService_RemoveMember(t *testing.T) {
+	tests := []struct {
+		name       string
+		selfEnroll bool
+		member     string
+		netId      string
+		want       error
+	}{
+		{
+			name:       "student leaves",
+			selfEnroll: true,
+			member:     "stu1",
+			netId:      "stu1",
+		},
+		{
+			name:   "student cannot leave assigned team",
+			member: "stu1",
+			netId:  "stu1",
+			want:   ERR_NOT_PERMITTED,
+		},
+		{
+			name:       "student cannot remove someone else",
+			selfEnroll: true,
+			member:     "stu1",
+			netId:      "stu2",
+			want:       ERR_NOT_PERMITTED,
+		},
+		{
+			name:   "teacher removes a student",
+			member: "stu1",
+			netId:  "prof",
+		},
+		{
+			name:   "not on the team",
+			member: "stu3",
+			netId:  "prof",
+			want:   dal.ERR_RECORD_NOT_FOUND,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				store := newMockProjectStore()
+				store.projects["p1"].SelfEnroll = tt.selfEnroll
+
+				err := NewProjectService(store).RemoveMember("t1", tt.member, tt.netId)
+
+				if !errors.Is(err, tt.want) {
+					t.Fatalf("got %v, want %v", err, tt.want)
+				}
+
+				if tt.want == nil && store.teams["t1"].HasMember(tt.member) {
+					t.Errorf("%s is still on the team", tt.member)
+				}
+			},
+		)
+	}
+}
+
+func TestProjectService_GradeTeam(t *testing.T) {
+	store := newMockProjectStore()
+	ps := NewProjectService(store)
+
+	// stu2 submits for the team, stu4 has no submission of their own.
+	teamSubmission, err := ps.Submit("t2", "stu2")
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+
+	again, err := ps.Submit("t2", "stu4")
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+
+	if again.ID != teamSubmission.ID {
+		t.Errorf("got submission %s, want the team's %s", again.ID, teamSubmission.ID)
+	}
+
+	_, err = ps.GradeTeam("t2", "stu2", 90, "")
+	if !errors.Is(err, ERR_NOT_PERMITTED) {
+		t.Errorf("got %v, want %v for a student", err, ERR_NOT_PERMITTED)
+	}
+
+	graded, err := ps.GradeTeam("t2", "prof", 92.5, "well done")
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+
+	if len(graded) != 2 {
+		t.Fatalf("got %d graded submissions, want 2", len(graded))
+	}
+
+	for _, member := range []string{"stu2", "stu4"} {
+		id := store.userSubmissions[member+"/a1"]
+
+		sub, ok := store.submissions[id]
+		if !ok {
+			t.Errorf("%s has no submission", member)
+			continue
+		}
+
+		if sub.Grade != 92.5 || sub.Feedback != "well done" {
+			t.Errorf(
+				"%s: got %v %q, want 92.5 %q",
+				member,
+				sub.Grade,
+				sub.Feedback,
+				"well done",
+			)
+		}
+	}
+}
+
+func TestProjectService_Ungraded(t *testing.T) {
+	store := newMockProjectStore()
+	store.projects["p1"].AssignmentId = ""
+
+	_, err := NewProjectService(store).Submit("t1", "stu1")
+	if !errors.Is(err, ERR_PROJECT_UNGRADED) {
+		t.Errorf("got %v, want %v", err, ERR_PROJECT_UNGRADED)
+	}
+}
+
+func TestProjectService_TeamDiscussion(t *testing.T) {
+	store := newMockProjectStore()
+	ps := NewProjectService(store)
+
+	tests := []struct {
+		name  string
+		netId string
+		want  error
+	}{
+		{name: "member", netId: "stu1"},
+		{name: "teacher", netId: "prof"},
+		{name: "student on another team", netId: "stu2", want: ERR_NOT_PERMITTED},
+		{name: "anonymous", netId: "", want: ERR_NOT_PERMITTED},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				_, err := ps.AddTeamComment("t1", "", tt.netId, "hello")
+				if !errors.Is(err, tt.want) {
+					t.Errorf("add: got %v, want %v", err, tt.want)
+				}
+
+				_, _, err = ps.ReadTeamDiscussion("t1", tt.netId, models.NewFilters())
+				if !errors.Is(err, tt.want) {
+					t.Errorf("read: got %v, want %v", err, tt.want)
+				}
+			},
+		)
+	}
+
+	if len(store.comments) != 2 {
+		t.Errorf("got %d comments, want 2", len(store.comments))
+	}
+
+	for _, c := range store.comments {
+		if c.MessageId != "m1" {
+			t.Errorf("got comment on %s, want the team discussion m1", c.MessageId)
+		}
+	}
+}
+
+// ========= //
+//   MOCKS   //
+// ========= //
+
+// mockProjectStore has a course "c1" taught by "prof", with students
+// stu1 to stu4. Its project "p1" has teams of at most two: "t1" with
+// stu1, and "t2" with stu2 and stu4. stu3 is on no team.
+type mockProjectStore struct {
+	ProjectStore
+
+	projects map[string]*models.Project
+	teams    map[string]*models.Team
+	teachers map[string]bool
+	students map[string]bool
+	comments []*models.Comment
+
+	submissions map[string]*models.Submission
+
+	// userSubmissions maps "<net id>/<assignment id>" to submission
+	// IDs.
+	userSubmissions map[string]string
+}
+
+func newMockProjectStore() *mockProjectStore {
+	return &mockProjectStore{
+		projects: map[string]*models.Project{
+			"p1": {
+				Entity:       models.Entity{ID: "p1"},
+				CourseId:     "c1",
+				AssignmentId: "a1",
+				TeamSize:     2,
+			},
+		},
+		teams: map[string]*models.Team{
+			"t1": {
+				Entity:       models.Entity{ID: "t1"},
+				ProjectId:    "p1",
+				Members:      []string{"stu1"},
+				DiscussionId: "m1",
+			},
+			"t2": {
+				Entity:       models.Entity{ID: "t2"},
+				ProjectId:    "p1",
+				Members:      []string{"stu2", "stu4"},
+				DiscussionId: "m2",
+			},
+		},
+		teachers: map[string]bool{"prof": true},
+		students: map[string]bool{
+			"stu1": true,
+			"stu2": true,
+			"stu3": true,
+			"stu4": true,
+		},
+		submissions:     make(map[string]*models.Submission),
+		userSubmissions: make(map[string]string),
+	}
+}
+
+func (m *mockProjectStore) GetProjectById(id string) (*models.Project, error) {
+	p, ok := m.projects[id]
+	if !ok {
+		return nil, dal.ERR_RECORD_NOT_FOUND
+	}
+	return p, nil
+}
+
+func (m *mockProjectStore) GetTeamById(id string) (*models.Team, error) {
+	t, ok := m.teams[id]
+	if !ok {
+		return nil, dal.ERR_RECORD_NOT_FOUND
+	}
+	return t, nil
+}
+
+func (m *mockProjectStore) GetTeamIdByMember(projectId, netId string) (
+	string,
+	error,
+) {
+	for _, t := range m.teams {
+		if t.ProjectId == projectId && t.HasMember(netId) {
+			return t.ID, nil
+		}
+	}
+	return "", dal.ERR_RECORD_NOT_FOUND
+}
+
+func (m *mockProjectStore) InsertTeamMember(t *models.Team, netId string) error {
+	return nil
+}
+
+func (m *mockProjectStore) DeleteTeamMember(teamId, netId string) error {
+	t := m.teams[teamId]
+
+	var members []string
+	for _, member := range t.Members {
+		if member != netId {
+			members = append(members, member)
+		}
+	}
+	t.Members = members
+
+	return nil
+}
+
+func (m *mockProjectStore) UpdateTeamSubmission(t *models.Team) error {
+	return nil
+}
+
+func (m *mockProjectStore) IsCourseTeacher(courseId, netId string) (bool, error) {
+	return courseId == "c1" && m.teachers[netId], nil
+}
+
+func (m *mockProjectStore) IsCourseStudent(courseId, netId string) (bool, error) {
+	return courseId == "c1" && m.students[netId], nil
+}
+
+func (m *mockProjectStore) InsertComment(c *models.Comment) error {
+	m.comments = append(m.comments, c)
+	return nil
+}
+
+func (m *mockProjectStore) GetComments(
+	messageId string,
+	includeHidden bool,
+	f models.Filters,
+) ([]*models.Comment, models.Metadata, error) {
+	return nil, models.Metadata{}, nil
+}
+
+func (m *mockProjectStore) GetExtension(assignmentId, netId string) (
+	*models.Extension,
+	error,
+) {
+	return nil, nil
+}
+
+func (m *mockProjectStore) GetAccommodation(courseId, netId string) (
+	*models.Accommodation,
+	error,
+) {
+	return nil, nil
+}
+
+func (m *mockProjectStore) GetCourseIdByAssignment(assignmentId string) (
+	string,
+	error,
+) {
+	return "c1", nil
+}
+
+func (m *mockProjectStore) GetAssignmentById(assignmentId string) (
+	*models.Assignment,
+	error,
+) {
+	a := models.NewAssignment()
+	a.ID = assignmentId
+	return a, nil
+}
+
+func (m *mockProjectStore) GetSubmissionById(submissionId string) (
+	*models.Submission,
+	error,
+) {
+	s, ok := m.submissions[submissionId]
+	if !ok {
+		return nil, dal.ERR_RECORD_NOT_FOUND
+	}
+	return s, nil
+}
+
+func (m *mockProjectStore) GetSubmissionIdByUserAndAssignment(
+	netId string,
+	assignmentId string,
+) (string, error) {
+	return m.userSubmissions[netId+"/"+assignmentId], nil
+}
+
+func (m *mockProjectStore) InsertSubmission(sub *models.Submission) (
+	*models.Submission,
+	error,
+) {
+	sub.ID = "s" + sub.User.ID
+	m.submissions[sub.ID] = sub
+	return sub, nil
+}
+
+func (m *mockProjectStore) InsertSubmissionIntoAssignment(
+	sub *models.Submission,
+) (*models.Submission, error) {
+	return sub, nil
+}
+
+func (m *mockProjectStore) InsertSubmissionIntoUser(
+	sub *models.Submission,
+) (*models.Submission, error) {
+	m.userSubmissions[sub.User.ID+"/"+sub.AssignmentId] = sub.ID
+	return sub, nil
+}
+
+func (m *mockProjectStore) UpdateSubmissionGrade(sub *models.Submission) error {
+	m.submissions[sub.ID] = sub
+	return nil
+}
@@ -0,0 +1,636 @@
+package domain
+
+import (
+	"math/rand"
+	"time"
+
+	"github.com/n30w/Darkspace/internal/dal"
+	"github.com/n30w/Darkspace/internal/models"
+)
+
+type QuizStore interface {
+	DueDateStore
+
+	InsertQuiz(q *models.Quiz) error
+	GetQuizById(id string) (*models.Quiz, error)
+	GetQuizzesByCourse(courseId string) ([]*models.Quiz, error)
+	DeleteQuiz(id string) error
+
+	InsertQuizAttempt(a *models.QuizAttempt) error
+	GetQuizAttemptById(id string) (*models.QuizAttempt, error)
+	GetQuizAttempts(quizId, netId string) ([]*models.QuizAttempt, error)
+	SaveQuizResponses(attemptId string, responses []*models.Response) error
+	UpdateQuizAttempt(a *models.QuizAttempt) error
+
+	IsCourseTeacher(courseId, netId string) (bool, error)
+	IsCourseStudent(courseId, netId string) (bool, error)
+
+	InsertAssignment(a *models.Assignment) (*models.Assignment, error)
+	InsertIntoCourseAssignments(a *models.Assignment) (*models.Assignment, error)
+	InsertAssignmentIntoUser(a *models.Assignment) (*models.Assignment, error)
+	GetAssignmentById(assignmentId string) (*models.Assignment, error)
+
+	GetSubmissionById(submissionId string) (*models.Submission, error)
+	GetSubmissionIdByUserAndAssignment(netId string, assignmentId string) (string, error)
+	InsertSubmission(sub *models.Submission) (*models.Submission, error)
+	InsertSubmissionIntoAssignment(sub *models.Submission) (*models.Submission, error)
+	InsertSubmissionIntoUser(sub *models.Submission) (*models.Submission, error)
+	UpdateSubmissionGrade(sub *models.Submission) error
+}
+
+type QuizService struct {
+	store QuizStore
+
+	// now is the clock that deadlines are checked against.
+	now func() time.Time
+}
+
+func NewQuizService(s QuizStore) *QuizService {
+	return &QuizService{store: s, now: time.Now}
+}
+
+// CreateQuiz creates a quiz in a course, along with the assignment
+// that its attempts are graded into. Only the course's teachers may
+// create quizzes.
+func (qs *QuizService) CreateQuiz(q *models.Quiz) (*models.Quiz, error) {
+	err := teacherOnly(qs.store, q.CourseId, q.Owner)
+	if err != nil {
+		return nil, err
+	}
+
+	a := models.NewAssignment()
+	a.Title = q.Title
+	a.Description = q.Description
+	a.Course = q.CourseId
+	a.Owner = q.Owner
+	a.DueDate = q.ClosesAt
+
+	a, err = qs.store.InsertAssignment(a)
+	if err != nil {
+		return nil, err
+	}
+
+	a, err = qs.store.InsertIntoCourseAssignments(a)
+	if err != nil {
+		return nil, err
+	}
+
+	a, err = qs.store.InsertAssignmentIntoUser(a)
+	if err != nil {
+		return nil, err
+	}
+
+	q.AssignmentId = a.ID
+
+	err = qs.store.InsertQuiz(q)
+	if err != nil {
+		return nil, err
+	}
+
+	return q, nil
+}
+
+// ListQuizzes retrieves a course's quizzes, without their questions.
+func (qs *QuizService) ListQuizzes(courseId string) ([]*models.Quiz, error) {
+	return qs.store.GetQuizzesByCourse(courseId)
+}
+
+// ReadQuiz retrieves a quiz. Teachers of the course get its questions
+// and their answer keys, everyone else only sees questions through
+// their attempts. Students see when the quiz closes for them, and
+// their own time limit.
+func (qs *QuizService) ReadQuiz(quizId, netId string) (*models.Quiz, error) {
+	q, err := qs.store.GetQuizById(quizId)
+	if err != nil {
+		return nil, err
+	}
+
+	teacher, err := isTeacher(qs.store, q.CourseId, netId)
+	if err != nil {
+		return nil, err
+	}
+
+	if !teacher {
+		q.Questions = nil
+
+		if netId != "" {
+			return studentQuiz(qs.store, q, netId)
+		}
+	}
+
+	return q, nil
+}
+
+// DeleteQuiz deletes a quiz and its attempts. Grades already given
+// for it are kept. Only the course's teachers may delete quizzes.
+func (qs *QuizService) DeleteQuiz(quizId, netId string) error {
+	q, err := qs.store.GetQuizById(quizId)
+	if err != nil {
+		return err
+	}
+
+	err = teacherOnly(qs.store, q.CourseId, netId)
+	if err != nil {
+		return err
+	}
+
+	return qs.store.DeleteQuiz(q.ID)
+}
+
+// StartAttempt starts a student's attempt at a quiz, fixing its
+// deadline. If the student has an attempt in progress, that is
+// returned instead.
+func (qs *QuizService) StartAttempt(quizId, netId string) (
+	*models.QuizAttempt,
+	error,
+) {
+	q, err := qs.store.GetQuizById(quizId)
+	if err != nil {
+		return nil, err
+	}
+
+	student, err := qs.store.IsCourseStudent(q.CourseId, netId)
+	if err != nil {
+		return nil, err
+	}
+
+	if !student {
+		return nil, ERR_NOT_ENROLLED
+	}
+
+	// The student's own closing time and time limit decide if they
+	// can start, and their attempt's deadline.
+	q, err = studentQuiz(qs.store, q, netId)
+	if err != nil {
+		return nil, err
+	}
+
+	attempts, err := qs.store.GetQuizAttempts(q.ID, netId)
+	if err != nil {
+		return nil, err
+	}
+
+	now := qs.now()
+
+	for _, a := range attempts {
+		if a.Submitted() {
+			continue
+		}
+
+		if !a.Expired(now) {
+			return qs.view(q, a), nil
+		}
+
+		// Attempts that ran out of time are submitted as they were
+		// left.
+		err = qs.finish(q, a)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if len(attempts) >= q.MaxAttempts {
+		return nil, ERR_NO_ATTEMPTS_LEFT
+	}
+
+	if !q.Open(now) {
+		return nil, ERR_QUIZ_CLOSED
+	}
+
+	a := &models.QuizAttempt{
+		QuizId:    q.ID,
+		NetId:     netId,
+		StartedAt: now,
+		Deadline:  q.Deadline(now),
+		Seed:      rand.Int63(),
+		Responses: []*models.Response{},
+	}
+
+	err = qs.store.InsertQuizAttempt(a)
+	if err != nil {
+		return nil, err
+	}
+
+	return qs.view(q, a), nil
+}
+
+// ReadAttempt retrieves an attempt. Students get their own attempts
+// with the questions in the order they were shown, without answer
+// keys. Teachers of the course get any attempt, with answer keys.
+func (qs *QuizService) ReadAttempt(attemptId, netId string) (
+	*models.QuizAttempt,
+	error,
+) {
+	q, a, err := qs.attempt(attemptId)
+	if err != nil {
+		return nil, err
+	}
+
+	if a.NetId == netId {
+		if !a.Submitted() && a.Expired(qs.now()) {
+			err = qs.finish(q, a)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		return qs.view(q, a), nil
+	}
+
+	err = teacherOnly(qs.store, q.CourseId, netId)
+	if err != nil {
+		return nil, err
+	}
+
+	a.Questions = q.Questions
+
+	return a, nil
+}
+
+// ListAttempts retrieves the attempts at a quiz. Teachers of the
+// course get every attempt, students only their own.
+func (qs *QuizService) ListAttempts(quizId, netId string) (
+	[]*models.QuizAttempt,
+	error,
+) {
+	q, err := qs.store.GetQuizById(quizId)
+	if err != nil {
+		return nil, err
+	}
+
+	teacher, err := isTeacher(qs.store, q.CourseId, netId)
+	if err != nil {
+		return nil, err
+	}
+
+	if teacher {
+		return qs.store.GetQuizAttempts(q.ID, "")
+	}
+
+	return qs.store.GetQuizAttempts(q.ID, netId)
+}
+
+// SaveResponses saves a student's answers to an attempt in progress,
+// without grading them. Answers can be changed until the attempt is
+// submitted or its deadline passes.
+func (qs *QuizService) SaveResponses(
+	attemptId, netId string,
+	responses []*models.Response,
+) (*models.QuizAttempt, error) {
+	q, a, err := qs.ownAttempt(attemptId, netId)
+	if err != nil {
+		return nil, err
+	}
+
+	if a.Submitted() {
+		return nil, ERR_ATTEMPT_SUBMITTED
+	}
+
+	if a.Expired(qs.now()) {
+		return nil, ERR_ATTEMPT_EXPIRED
+	}
+
+	err = qs.save(q, a, responses)
+	if err != nil {
+		return nil, err
+	}
+
+	return qs.view(q, a), nil
+}
+
+// SubmitAttempt submits an attempt, saving any final answers first,
+// then grades it. Answers sent after the deadline are ignored, and
+// the attempt is graded as it was when time ran out.
+func (qs *QuizService) SubmitAttempt(
+	attemptId, netId string,
+	responses []*models.Response,
+) (*models.QuizAttempt, error) {
+	q, a, err := qs.ownAttempt(attemptId, netId)
+	if err != nil {
+		return nil, err
+	}
+
+	if a.Submitted() {
+		return nil, ERR_ATTEMPT_SUBMITTED
+	}
+
+	if !a.Expired(qs.now()) {
+		err = qs.save(q, a, responses)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = qs.finish(q, a)
+	if err != nil {
+		return nil, err
+	}
+
+	return qs.view(q, a), nil
+}
+
+// GradeResponse lets a teacher set the points of a response, such as
+// a short answer that could not be graded automatically. The
+// attempt's score and the student's grade are updated to match.
+func (qs *QuizService) GradeResponse(
+	attemptId, questionId, netId string,
+	points float64,
+) (*models.QuizAttempt, error) {
+	q, a, err := qs.attempt(attemptId)
+	if err != nil {
+		return nil, err
+	}
+
+	err = teacherOnly(qs.store, q.CourseId, netId)
+	if err != nil {
+		return nil, err
+	}
+
+	if !a.Submitted() {
+		return nil, ERR_ATTEMPT_NOT_SUBMITTED
+	}
+
+	question := findQuestion(q, questionId)
+	if question == nil {
+		return nil, dal.ERR_RECORD_NOT_FOUND
+	}
+
+	if points < 0 || points > question.Points {
+		return nil, ERR_POINTS_OUT_OF_RANGE
+	}
+
+	r := findResponse(a, questionId)
+	if r == nil {
+		r = &models.Response{QuestionId: questionId}
+		a.Responses = append(a.Responses, r)
+	}
+
+	r.Points = points
+	r.Graded = true
+
+	err = qs.store.SaveQuizResponses(a.ID, []*models.Response{r})
+	if err != nil {
+		return nil, err
+	}
+
+	a.Score = score(a)
+
+	err = qs.record(q, a)
+	if err != nil {
+		return nil, err
+	}
+
+	a.Questions = q.Questions
+
+	return a, nil
+}
+
+// save checks that responses answer the quiz's questions, then saves
+// them ungraded.
+func (qs *QuizService) save(
+	q *models.Quiz,
+	a *models.QuizAttempt,
+	responses []*models.Response,
+) error {
+	for _, r := range responses {
+		if findQuestion(q, r.QuestionId) == nil {
+			return ERR_UNKNOWN_QUESTION
+		}
+
+		r.Points = 0
+		r.Graded = false
+	}
+
+	err := qs.store.SaveQuizResponses(a.ID, responses)
+	if err != nil {
+		return err
+	}
+
+	for _, r := range responses {
+		if existing := findResponse(a, r.QuestionId); existing != nil {
+			*existing = *r
+			continue
+		}
+		a.Responses = append(a.Responses, r)
+	}
+
+	return nil
+}
+
+// finish grades every question of an attempt, marks it submitted and
+// records its grade. Unanswered questions score nothing. Attempts
+// finished after their deadline are recorded as submitted at the
+// deadline.
+func (qs *QuizService) finish(q *models.Quiz, a *models.QuizAttempt) error {
+	graded := make([]*models.Response, 0, len(q.Questions))
+
+	for _, question := range q.Questions {
+		r := findResponse(a, question.ID)
+		if r == nil {
+			r = &models.Response{QuestionId: question.ID}
+		}
+
+		r.Points, r.Graded = question.Grade(r.Answer)
+		graded = append(graded, r)
+	}
+
+	err := qs.store.SaveQuizResponses(a.ID, graded)
+	if err != nil {
+		return err
+	}
+
+	a.Responses = graded
+	a.Score = score(a)
+	a.SubmittedAt = qs.now()
+
+	if a.Expired(a.SubmittedAt) {
+		a.SubmittedAt = a.Deadline
+	}
+
+	return qs.record(q, a)
+}
+
+// record saves an attempt's score, and grades the student's
+// submission for the quiz's assignment with their best submitted
+// attempt.
+func (qs *QuizService) record(q *models.Quiz, a *models.QuizAttempt) error {
+	best := a.Score
+
+	attempts, err := qs.store.GetQuizAttempts(q.ID, a.NetId)
+	if err != nil {
+		return err
+	}
+
+	for _, other := range attempts {
+		if other.ID != a.ID && other.Submitted() && other.Score > best {
+			best = other.Score
+		}
+	}
+
+	sub, err := qs.submission(q, a)
+	if err != nil {
+		return err
+	}
+
+	assignment, err := qs.store.GetAssignmentById(q.AssignmentId)
+	if err != nil {
+		return err
+	}
+
+	assignment, err = studentAssignment(qs.store, assignment, a.NetId)
+	if err != nil {
+		return err
+	}
+
+	sub.SetGrade(best, assignment)
+
+	err = qs.store.UpdateSubmissionGrade(sub)
+	if err != nil {
+		return err
+	}
+
+	a.SubmissionId = sub.ID
+
+	return qs.store.UpdateQuizAttempt(a)
+}
+
+// submission retrieves the student's submission for the quiz's
+// assignment, creating one if they have none.
+func (qs *QuizService) submission(
+	q *models.Quiz,
+	a *models.QuizAttempt,
+) (*models.Submission, error) {
+	id, err := qs.store.GetSubmissionIdByUserAndAssignment(a.NetId, q.AssignmentId)
+	if err != nil {
+		return nil, err
+	}
+
+	if id != "" {
+		return qs.store.GetSubmissionById(id)
+	}
+
+	sub := models.NewSubmission()
+	sub.AssignmentId = q.AssignmentId
+	sub.User.ID = a.NetId
+	sub.SubmissionTime = a.SubmittedAt
+
+	// Attempts cannot be submitted after their deadline, so a quiz is
+	// always on time.
+	sub.OnTime = true
+
+	sub, err = qs.store.InsertSubmission(sub)
+	if err != nil {
+		return nil, err
+	}
+
+	sub, err = qs.store.InsertSubmissionIntoAssignment(sub)
+	if err != nil {
+		return nil, err
+	}
+
+	return qs.store.InsertSubmissionIntoUser(sub)
+}
+
+// view sets the questions an attempt shows to its student, in the
+// attempt's order and without answer keys.
+func (qs *QuizService) view(
+	q *models.Quiz,
+	a *models.QuizAttempt,
+) *models.QuizAttempt {
+	a.Questions = shuffleQuestions(q, a.Seed)
+	return a
+}
+
+// shuffleQuestions copies a quiz's questions without their keys,
+// shuffling the questions and their choices if the quiz asks for it.
+// The same seed always gives the same order.
+func shuffleQuestions(q *models.Quiz, seed int64) []*models.Question {
+	rng := rand.New(rand.NewSource(seed))
+
+	questions := make([]*models.Question, len(q.Questions))
+
+	for i, question := range q.Questions {
+		c := *question
+		c.Key = nil
+		c.Choices = append([]models.Choice(nil), question.Choices...)
+
+		if q.ShuffleChoices {
+			rng.Shuffle(len(c.Choices), func(i, j int) {
+				c.Choices[i], c.Choices[j] = c.Choices[j], c.Choices[i]
+			})
+		}
+
+		questions[i] = &c
+	}
+
+	if q.ShuffleQuestions {
+		rng.Shuffle(len(questions), func(i, j int) {
+			questions[i], questions[j] = questions[j], questions[i]
+		})
+	}
+
+	return questions
+}
+
+// attempt retrieves an attempt along with its quiz.
+func (qs *QuizService) attempt(attemptId string) (
+	*models.Quiz,
+	*models.QuizAttempt,
+	error,
+) {
+	a, err := qs.store.GetQuizAttemptById(attemptId)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	q, err := qs.store.GetQuizById(a.QuizId)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return q, a, nil
+}
+
+// ownAttempt retrieves an attempt made by netId.
+func (qs *QuizService) ownAttempt(attemptId, netId string) (
+	*models.Quiz,
+	*models.QuizAttempt,
+	error,
+) {
+	q, a, err := qs.attempt(attemptId)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if a.NetId != netId {
+		return nil, nil, ERR_NOT_PERMITTED
+	}
+
+	return q, a, nil
+}
+
+func findQuestion(q *models.Quiz, questionId string) *models.Question {
+	for _, question := range q.Questions {
+		if question.ID == questionId {
+			return question
+		}
+	}
+	return nil
+}
+
+func findResponse(a *models.QuizAttempt, questionId string) *models.Response {
+	for _, r := range a.Responses {
+		if r.QuestionId == questionId {
+			return r
+		}
+	}
+	return nil
+}
+
+func score(a *models.QuizAttempt) float64 {
+	var total float64
+	for _, r := range a.Responses {
+		total += r.Points
+	}
+	return total
+}
@@ -0,0 +1,517 @@
+package domain
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/n30w/Darkspace/internal/dal"
+	"github.com/n30w/Darkspace/internal/models"
+)
+
+func TestQuizService_Attempt(t *testing.T) {
+	store := newMockQuizStore()
+	qs := NewQuizService(store)
+
+	clock := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	qs.now = func() time.Time { return clock }
+
+	a, err := qs.StartAttempt("q1", "stu1")
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+
+	if want := clock.Add(10 * time.Minute); !a.Deadline.Equal(want) {
+		t.Errorf("got deadline %v, want %v", a.Deadline, want)
+	}
+
+	for _, question := range a.Questions {
+		if question.Key != nil {
+			t.Errorf("question %s was sent with its key", question.ID)
+		}
+	}
+
+	clock = clock.Add(time.Minute)
+
+	resumed, err := qs.StartAttempt("q1", "stu1")
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+
+	if resumed.ID != a.ID {
+		t.Errorf("got attempt %s, want to resume %s", resumed.ID, a.ID)
+	}
+
+	_, err = qs.SaveResponses(a.ID, "stu1", []*models.Response{
+		{QuestionId: "mc", Answer: models.Answer{Choices: []int{2}}},
+	})
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+
+	_, err = qs.SaveResponses(a.ID, "stu2", nil)
+	if !errors.Is(err, ERR_NOT_PERMITTED) {
+		t.Errorf("got %v, want %v for another student", err, ERR_NOT_PERMITTED)
+	}
+
+	_, err = qs.SaveResponses(a.ID, "stu1", []*models.Response{
+		{QuestionId: "elsewhere"},
+	})
+	if !errors.Is(err, ERR_UNKNOWN_QUESTION) {
+		t.Errorf("got %v, want %v", err, ERR_UNKNOWN_QUESTION)
+	}
+
+	yes := true
+
+	a, err = qs.SubmitAttempt(a.ID, "stu1", []*models.Response{
+		{QuestionId: "tf", Answer: models.Answer{Bool: &yes}},
+		{QuestionId: "sa", Answer: models.Answer{Text: "because"}},
+	})
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+
+	// mc is right for 2 points, tf is right for 1 point and sa is
+	// left for review.
+	if a.Score != 3 {
+		t.Errorf("got score %v, want 3", a.Score)
+	}
+
+	if !a.NeedsReview() {
+		t.Errorf("want the short answer to need review")
+	}
+
+	if got := store.grade("stu1"); got != 3 {
+		t.Errorf("got grade %v, want 3", got)
+	}
+
+	_, err = qs.SubmitAttempt(a.ID, "stu1", nil)
+	if !errors.Is(err, ERR_ATTEMPT_SUBMITTED) {
+		t.Errorf("got %v, want %v", err, ERR_ATTEMPT_SUBMITTED)
+	}
+
+	_, err = qs.StartAttempt("q1", "stu1")
+	if !errors.Is(err, ERR_NO_ATTEMPTS_LEFT) {
+		t.Errorf("got %v, want %v", err, ERR_NO_ATTEMPTS_LEFT)
+	}
+}
+
+func TestQuizService_Deadline(t *testing.T) {
+	store := newMockQuizStore()
+	qs := NewQuizService(store)
+
+	clock := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	qs.now = func() time.Time { return clock }
+
+	a, err := qs.StartAttempt("q1", "stu1")
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+
+	_, err = qs.SaveResponses(a.ID, "stu1", []*models.Response{
+		{QuestionId: "mc", Answer: models.Answer{Choices: []int{2}}},
+	})
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+
+	clock = clock.Add(11 * time.Minute)
+
+	yes := true
+
+	_, err = qs.SaveResponses(a.ID, "stu1", []*models.Response{
+		{QuestionId: "tf", Answer: models.Answer{Bool: &yes}},
+	})
+	if !errors.Is(err, ERR_ATTEMPT_EXPIRED) {
+		t.Errorf("got %v, want %v", err, ERR_ATTEMPT_EXPIRED)
+	}
+
+	// Answers sent with a late submission are ignored.
+	a, err = qs.SubmitAttempt(a.ID, "stu1", []*models.Response{
+		{QuestionId: "tf", Answer: models.Answer{Bool: &yes}},
+	})
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+
+	if a.Score != 2 {
+		t.Errorf("got score %v, want 2", a.Score)
+	}
+
+	if !a.SubmittedAt.Equal(a.Deadline) {
+		t.Errorf("got submitted at %v, want the deadline %v", a.SubmittedAt, a.Deadline)
+	}
+}
+
+func TestQuizService_Closed(t *testing.T) {
+	store := newMockQuizStore()
+	qs := NewQuizService(store)
+
+	qs.now = func() time.Time {
+		return store.quizzes["q1"].ClosesAt
+	}
+
+	_, err := qs.StartAttempt("q1", "stu1")
+	if !errors.Is(err, ERR_QUIZ_CLOSED) {
+		t.Errorf("got %v, want %v", err, ERR_QUIZ_CLOSED)
+	}
+
+	_, err = qs.StartAttempt("q1", "outsider")
+	if !errors.Is(err, ERR_NOT_ENROLLED) {
+		t.Errorf("got %v, want %v", err, ERR_NOT_ENROLLED)
+	}
+}
+
+func TestQuizService_Accommodations(t *testing.T) {
+	store := newMockQuizStore()
+	closes := store.quizzes["q1"].ClosesAt
+
+	store.accommodations = map[string]*models.Accommodation{
+		"stu1": {NetId: "stu1", TimeMultiplier: 1.5},
+	}
+	store.extensions = map[string]*models.Extension{
+		"stu2": {NetId: "stu2", DueDate: closes.Add(48 * time.Hour)},
+	}
+
+	qs := NewQuizService(store)
+
+	clock := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	qs.now = func() time.Time { return clock }
+
+	a, err := qs.StartAttempt("q1", "stu1")
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+
+	// Time and a half on a 10 minute quiz.
+	if want := clock.Add(15 * time.Minute); !a.Deadline.Equal(want) {
+		t.Errorf("got deadline %v, want %v", a.Deadline, want)
+	}
+
+	q, err := qs.ReadQuiz("q1", "stu2")
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+
+	if !q.ClosesAt.Equal(closes.Add(48*time.Hour)) || q.TimeLimit != 600 {
+		t.Errorf("got closes at %v with limit %d, want the extension", q.ClosesAt, q.TimeLimit)
+	}
+
+	// stu2 can still start after the quiz has closed for the class.
+	clock = closes.Add(time.Hour)
+
+	a, err = qs.StartAttempt("q1", "stu2")
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+
+	if want := clock.Add(10 * time.Minute); !a.Deadline.Equal(want) {
+		t.Errorf("got deadline %v, want %v", a.Deadline, want)
+	}
+
+	if store.quizzes["q1"].ClosesAt != closes {
+		t.Errorf("the class's quiz was changed")
+	}
+}
+
+func TestQuizService_GradeResponse(t *testing.T) {
+	store := newMockQuizStore()
+	store.quizzes["q1"].MaxAttempts = 2
+
+	qs := NewQuizService(store)
+
+	clock := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	qs.now = func() time.Time { return clock }
+
+	first, err := qs.StartAttempt("q1", "stu1")
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+
+	first, err = qs.SubmitAttempt(first.ID, "stu1", []*models.Response{
+		{QuestionId: "mc", Answer: models.Answer{Choices: []int{2}}},
+		{QuestionId: "sa", Answer: models.Answer{Text: "because"}},
+	})
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+
+	second, err := qs.StartAttempt("q1", "stu1")
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+
+	_, err = qs.SubmitAttempt(second.ID, "stu1", nil)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+
+	// The best attempt counts.
+	if got := store.grade("stu1"); got != 2 {
+		t.Errorf("got grade %v, want 2", got)
+	}
+
+	_, err = qs.GradeResponse(first.ID, "sa", "stu1", 3)
+	if !errors.Is(err, ERR_NOT_PERMITTED) {
+		t.Errorf("got %v, want %v for a student", err, ERR_NOT_PERMITTED)
+	}
+
+	_, err = qs.GradeResponse(first.ID, "sa", "prof", 4)
+	if !errors.Is(err, ERR_POINTS_OUT_OF_RANGE) {
+		t.Errorf("got %v, want %v", err, ERR_POINTS_OUT_OF_RANGE)
+	}
+
+	_, err = qs.GradeResponse(first.ID, "missing", "prof", 1)
+	if !errors.Is(err, dal.ERR_RECORD_NOT_FOUND) {
+		t.Errorf("got %v, want %v", err, dal.ERR_RECORD_NOT_FOUND)
+	}
+
+	first, err = qs.GradeResponse(first.ID, "sa", "prof", 2.5)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+
+	if first.Score != 4.5 || first.NeedsReview() {
+		t.Errorf("got score %v, review %v, want 4.5 and reviewed", first.Score, first.NeedsReview())
+	}
+
+	if got := store.grade("stu1"); got != 4.5 {
+		t.Errorf("got grade %v, want 4.5", got)
+	}
+}
+
+func TestShuffleQuestions(t *testing.T) {
+	quiz := newMockQuizStore().quizzes["q1"]
+	quiz.ShuffleQuestions = true
+	quiz.ShuffleChoices = true
+
+	ids := func(questions []*models.Question) []string {
+		var ids []string
+		for _, q := range questions {
+			ids = append(ids, q.ID)
+		}
+		return ids
+	}
+
+	a := shuffleQuestions(quiz, 42)
+	b := shuffleQuestions(quiz, 42)
+
+	if !slices.Equal(ids(a), ids(b)) {
+		t.Errorf("got %v and %v, want the same order for the same seed", ids(a), ids(b))
+	}
+
+	if quiz.Questions[0].Key == nil {
+		t.Errorf("shuffling removed the key from the quiz itself")
+	}
+
+	orders := make(map[string]bool)
+	for seed := int64(0); seed < 50; seed++ {
+		orders[strings.Join(ids(shuffleQuestions(quiz, seed)), ",")] = true
+	}
+
+	if len(orders) < 2 {
+		t.Errorf("got %d question orders over 50 seeds, want several", len(orders))
+	}
+}
+
+// ========= //
+//   MOCKS   //
+// ========= //
+
+// mockQuizStore has a course "c1" taught by "prof" with students stu1
+// and stu2. Its quiz "q1" has a 10 minute limit, a single attempt and
+// three questions: "mc" worth 2 points, "tf" worth 1 and "sa" worth 3
+// that must be graded by hand.
+type mockQuizStore struct {
+	QuizStore
+
+	quizzes     map[string]*models.Quiz
+	attempts    map[string]*models.QuizAttempt
+	submissions map[string]*models.Submission
+
+	// extensions and accommodations are keyed by NetID.
+	extensions     map[string]*models.Extension
+	accommodations map[string]*models.Accommodation
+}
+
+func newMockQuizStore() *mockQuizStore {
+	return &mockQuizStore{
+		quizzes: map[string]*models.Quiz{
+			"q1": {
+				Entity:       models.Entity{ID: "q1"},
+				CourseId:     "c1",
+				AssignmentId: "a1",
+				TimeLimit:    600,
+				ClosesAt:     time.Date(2024, 5, 8, 0, 0, 0, 0, time.UTC),
+				MaxAttempts:  1,
+				Questions: []*models.Question{
+					{
+						Entity: models.Entity{ID: "mc"},
+						Type:   models.MULTIPLE_CHOICE,
+						Points: 2,
+						Choices: []models.Choice{
+							{Id: 1, Text: "a"},
+							{Id: 2, Text: "b"},
+							{Id: 3, Text: "c"},
+						},
+						Key: &models.AnswerKey{Choices: []int{2}},
+					},
+					{
+						Entity: models.Entity{ID: "tf"},
+						Type:   models.TRUE_FALSE,
+						Points: 1,
+						Key:    &models.AnswerKey{Bool: true},
+					},
+					{
+						Entity: models.Entity{ID: "sa"},
+						Type:   models.SHORT_ANSWER,
+						Points: 3,
+					},
+				},
+			},
+		},
+		attempts:    make(map[string]*models.QuizAttempt),
+		submissions: make(map[string]*models.Submission),
+	}
+}
+
+// grade is the grade of a student's submission for the quiz.
+func (m *mockQuizStore) grade(netId string) float64 {
+	return m.submissions[netId].Grade
+}
+
+func (m *mockQuizStore) GetQuizById(id string) (*models.Quiz, error) {
+	q, ok := m.quizzes[id]
+	if !ok {
+		return nil, dal.ERR_RECORD_NOT_FOUND
+	}
+	return q, nil
+}
+
+func (m *mockQuizStore) InsertQuizAttempt(a *models.QuizAttempt) error {
+	a.ID = "attempt" + string(rune('0'+len(m.attempts)))
+	m.attempts[a.ID] = a
+	return nil
+}
+
+func (m *mockQuizStore) GetQuizAttemptById(id string) (
+	*models.QuizAttempt,
+	error,
+) {
+	a, ok := m.attempts[id]
+	if !ok {
+		return nil, dal.ERR_RECORD_NOT_FOUND
+	}
+	return a, nil
+}
+
+func (m *mockQuizStore) GetQuizAttempts(quizId, netId string) (
+	[]*models.QuizAttempt,
+	error,
+) {
+	var attempts []*models.QuizAttempt
+	for _, a := range m.attempts {
+		if a.QuizId == quizId && (netId == "" || a.NetId == netId) {
+			attempts = append(attempts, a)
+		}
+	}
+	return attempts, nil
+}
+
+func (m *mockQuizStore) SaveQuizResponses(
+	attemptId string,
+	responses []*models.Response,
+) error {
+	return nil
+}
+
+func (m *mockQuizStore) UpdateQuizAttempt(a *models.QuizAttempt) error {
+	return nil
+}
+
+func (m *mockQuizStore) IsCourseTeacher(courseId, netId string) (bool, error) {
+	return courseId == "c1" && netId == "prof", nil
+}
+
+func (m *mockQuizStore) IsCourseStudent(courseId, netId string) (bool, error) {
+	return courseId == "c1" && (netId == "stu1" || netId == "stu2"), nil
+}
+
+func (m *mockQuizStore) GetAssignmentById(assignmentId string) (
+	*models.Assignment,
+	error,
+) {
+	a := models.NewAssignment()
+	a.ID = assignmentId
+	return a, nil
+}
+
+func (m *mockQuizStore) GetExtension(assignmentId, netId string) (
+	*models.Extension,
+	error,
+) {
+	return m.extensions[netId], nil
+}
+
+func (m *mockQuizStore) GetAccommodation(courseId, netId string) (
+	*models.Accommodation,
+	error,
+) {
+	return m.accommodations[netId], nil
+}
+
+func (m *mockQuizStore) GetCourseIdByAssignment(assignmentId string) (
+	string,
+	error,
+) {
+	return "c1", nil
+}
+
+func (m *mockQuizStore) GetSubmissionIdByUserAndAssignment(
+	netId string,
+	assignmentId string,
+) (string, error) {
+	if s, ok := m.submissions[netId]; ok {
+		return s.ID, nil
+	}
+	return "", nil
+}
+
+func (m *mockQuizStore) GetSubmissionById(submissionId string) (
+	*models.Submission,
+	error,
+) {
+	for _, s := range m.submissions {
+		if s.ID == submissionId {
+			return s, nil
+		}
+	}
+	return nil, dal.ERR_RECORD_NOT_FOUND
+}
+
+func (m *mockQuizStore) InsertSubmission(sub *models.Submission) (
+	*models.Submission,
+	error,
+) {
+	sub.ID = "s" + sub.User.ID
+	m.submissions[sub.User.ID] = sub
+	return sub, nil
+}
+
+func (m *mockQuizStore) InsertSubmissionIntoAssignment(
+	sub *models.Submission,
+) (*models.Submission, error) {
+	return sub, nil
+}
+
+func (m *mockQuizStore) InsertSubmissionIntoUser(
+	sub *models.Submission,
+) (*models.Submission, error) {
+	return sub, nil
+}
+
+func (m *mockQuizStore) UpdateSubmissionGrade(sub *models.Submission) error {
+	return nil
+}
@@ -0,0 +1,157 @@
+package domain
+
+import (
+	"fmt"
+
+	"github.com/n30w/Darkspace/internal/models"
+)
+
+// SetQuota sets the storage quotas that uploads are checked against.
+func (ms *MediaService) SetQuota(q models.StorageQuota) {
+	ms.quota = q
+}
+
+// UserQuota reports the storage used by a user against the user
+// quota. Usage is summed from the media records that exist, so
+// media that is deleted or unlinked stops counting right away.
+func (ms *MediaService) UserQuota(netId string) (*models.QuotaUsage, error) {
+	used, err := ms.store.GetUserStorageUsage(netId)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.QuotaUsage{
+		Kind:  "user",
+		Id:    netId,
+		Used:  used,
+		Limit: ms.quota.User,
+	}, nil
+}
+
+// CourseQuota reports the storage used by a course against the
+// course quota.
+func (ms *MediaService) CourseQuota(courseId string) (
+	*models.QuotaUsage,
+	error,
+) {
+	used, err := ms.store.GetCourseStorageUsage(courseId)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.QuotaUsage{
+		Kind:  "course",
+		Id:    courseId,
+		Used:  used,
+		Limit: ms.quota.Course,
+	}, nil
+}
+
+// CheckQuota checks that size more bytes of media fit within the
+// quotas of whoever the media is attributed to. Course, assignment,
+// submission, feedback, harness and team media count toward the
+// course; submission media and profile pictures count toward the user
+// who owns them. If a quota would be exceeded, an error wrapping
+// ERR_QUOTA_EXCEEDED is returned.
+func (ms *MediaService) CheckQuota(media *models.Media, size int64) error {
+	courseId, netId, err := ms.quotaOwners(media)
+	if err != nil {
+		return err
+	}
+
+	if courseId != "" && ms.quota.Course > 0 {
+		usage, err := ms.CourseQuota(courseId)
+		if err != nil {
+			return err
+		}
+
+		if !usage.Allows(size) {
+			return quotaError(usage, size)
+		}
+	}
+
+	if netId != "" && ms.quota.User > 0 {
+		usage, err := ms.UserQuota(netId)
+		if err != nil {
+			return err
+		}
+
+		if !usage.Allows(size) {
+			return quotaError(usage, size)
+		}
+	}
+
+	return nil
+}
+
+// quotaOwners finds the course and user that media is charged to,
+// using what the media is attributed to.
+func (ms *MediaService) quotaOwners(media *models.Media) (
+	courseId, netId string,
+	err error,
+) {
+	a := media.AttributionsByType
+
+	switch {
+	case a["course"] != "":
+		courseId = a["course"]
+
+	case a["assignment"] != "":
+		courseId, err = ms.store.GetCourseIdByAssignment(a["assignment"])
+
+	case a["submission"] != "":
+		var assignmentId string
+		var submission *models.Submission
+
+		assignmentId, err = ms.store.GetAssignmentIdBySubmission(a["submission"])
+		if err != nil {
+			return "", "", err
+		}
+
+		courseId, err = ms.store.GetCourseIdByAssignment(assignmentId)
+		if err != nil {
+			return "", "", err
+		}
+
+		submission, err = ms.store.GetSubmissionById(a["submission"])
+		if err != nil {
+			return "", "", err
+		}
+
+		netId = submission.User.ID
+
+	case a["feedback"] != "":
+		var assignmentId string
+
+		assignmentId, err = ms.store.GetAssignmentIdBySubmission(a["feedback"])
+		if err != nil {
+			return "", "", err
+		}
+
+		courseId, err = ms.store.GetCourseIdByAssignment(assignmentId)
+
+	case a["team"] != "":
+		courseId, err = ms.store.GetCourseIdByTeam(a["team"])
+
+	case a["user"] != "":
+		netId = a["user"]
+	}
+
+	if err != nil {
+		return "", "", err
+	}
+
+	return courseId, netId, nil
+}
+
+func quotaError(usage *models.QuotaUsage, size int64) error {
+	return fmt.Errorf(
+		"%w: %s %s is using %d of %d bytes, cannot add %d more",
+		ERR_QUOTA_EXCEEDED,
+		usage.Kind,
+		usage.Id,
+		usage.Used,
+		usage.Limit,
+		size,
+	)
+}
@@ -0,0 +1,182 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/n30w/Darkspace/internal/models"
+)
+
+func TestMediaService_CheckQuota(t *testing.T) {
+	store := &mockMediaStore{
+		courseUsage: map[string]int64{"c1": 900, "c2": 100},
+		userUsage:   map[string]int64{"abc123": 40, "xyz789": 0},
+		assignments: map[string]string{"a1": "c1", "a2": "c2"},
+		submissions: map[string]string{"s1": "a2", "s2": "a1"},
+		submitters:  map[string]string{"s1": "abc123", "s2": "xyz789"},
+		teams:       map[string]string{"t1": "c1"},
+	}
+
+	ms := NewMediaService(store)
+	ms.SetQuota(models.StorageQuota{User: 50, Course: 1000})
+
+	tests := []struct {
+		name        string
+		attribution map[string]string
+		size        int64
+		exceeded    bool
+	}{
+		{
+			name:        "banner within course quota",
+			attribution: map[string]string{"course": "c1"},
+			size:        100,
+		},
+		{
+			name:        "banner over course quota",
+			attribution: map[string]string{"course": "c1"},
+			size:        101,
+			exceeded:    true,
+		},
+		{
+			name:        "assignment media charged to its course",
+			attribution: map[string]string{"assignment": "a1"},
+			size:        200,
+			exceeded:    true,
+		},
+		{
+			name:        "submission within both quotas",
+			attribution: map[string]string{"submission": "s1"},
+			size:        10,
+		},
+		{
+			name:        "submission over user quota",
+			attribution: map[string]string{"submission": "s1"},
+			size:        11,
+			exceeded:    true,
+		},
+		{
+			name:        "submission over course quota",
+			attribution: map[string]string{"submission": "s2"},
+			size:        101,
+			exceeded:    true,
+		},
+		{
+			name:        "feedback charged to its course, not the student",
+			attribution: map[string]string{"feedback": "s1"},
+			size:        20,
+		},
+		{
+			name:        "feedback over course quota",
+			attribution: map[string]string{"feedback": "s2"},
+			size:        101,
+			exceeded:    true,
+		},
+		{
+			name:        "team media charged to its course",
+			attribution: map[string]string{"team": "t1"},
+			size:        101,
+			exceeded:    true,
+		},
+		{
+			name:        "profile picture over user quota",
+			attribution: map[string]string{"user": "abc123"},
+			size:        20,
+			exceeded:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				media := &models.Media{AttributionsByType: tt.attribution}
+
+				err := ms.CheckQuota(media, tt.size)
+
+				got := errors.Is(err, ERR_QUOTA_EXCEEDED)
+				if got != tt.exceeded {
+					t.Errorf("got %v, want exceeded %v", err, tt.exceeded)
+				}
+
+				if err != nil && !tt.exceeded {
+					t.Errorf("unexpected error: %v", err)
+				}
+			},
+		)
+	}
+}
+
+func TestMediaService_CheckQuotaUnlimited(t *testing.T) {
+	store := &mockMediaStore{
+		courseUsage: map[string]int64{"c1": 1 << 40},
+	}
+
+	ms := NewMediaService(store)
+
+	media := &models.Media{AttributionsByType: map[string]string{"course": "c1"}}
+
+	err := ms.CheckQuota(media, 1<<40)
+	if err != nil {
+		t.Errorf("got %v, want no error without a quota", err)
+	}
+}
+
+// ========= //
+//   MOCKS   //
+// ========= //
+
+// mockMediaStore implements the parts of MediaStore that quotas use.
+// Calling anything else panics.
+type mockMediaStore struct {
+	MediaStore
+
+	courseUsage map[string]int64
+	userUsage   map[string]int64
+
+	// assignments maps assignment IDs to course IDs.
+	assignments map[string]string
+
+	// submissions maps submission IDs to assignment IDs.
+	submissions map[string]string
+
+	// submitters maps submission IDs to net IDs.
+	submitters map[string]string
+
+	// teams maps team IDs to course IDs.
+	teams map[string]string
+}
+
+func (m *mockMediaStore) GetCourseStorageUsage(courseId string) (int64, error) {
+	return m.courseUsage[courseId], nil
+}
+
+func (m *mockMediaStore) GetUserStorageUsage(netId string) (int64, error) {
+	return m.userUsage[netId], nil
+}
+
+func (m *mockMediaStore) GetCourseIdByAssignment(assignmentId string) (
+	string,
+	error,
+) {
+	return m.assignments[assignmentId], nil
+}
+
+func (m *mockMediaStore) GetCourseIdByTeam(teamId string) (string, error) {
+	return m.teams[teamId], nil
+}
+
+func (m *mockMediaStore) GetAssignmentIdBySubmission(submissionId string) (
+	string,
+	error,
+) {
+	return m.submissions[submissionId], nil
+}
+
+func (m *mockMediaStore) GetSubmissionById(submissionId string) (
+	*models.Submission,
+	error,
+) {
+	s := models.NewSubmission()
+	s.ID = submissionId
+	s.User.ID = m.submitters[submissionId]
+	return s, nil
+}
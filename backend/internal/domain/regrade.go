@@ -0,0 +1,328 @@
+package domain
+
+import (
+	"fmt"
+
+	"github.com/n30w/Darkspace/internal/models"
+)
+
+// RegradeStore keeps regrade requests and their history.
+type RegradeStore interface {
+	InsertRegradeRequest(r *models.RegradeRequest) error
+	GetRegradeRequestById(id string) (*models.RegradeRequest, error)
+	GetRegradeRequests(assignmentId, submissionId string) ([]*models.RegradeRequest, error)
+	UpdateRegradeRequest(r *models.RegradeRequest) error
+	InsertRegradeEvent(e *models.RegradeEvent) error
+	GetRegradeEvents(requestId string) ([]*models.RegradeEvent, error)
+}
+
+// SetRegradeWindow sets how many seconds after grades are released
+// students may request a regrade. Only the course's teachers may
+// change it.
+func (as *AssignmentService) SetRegradeWindow(
+	assignmentid, netId string,
+	window int,
+) (*models.Assignment, error) {
+	assignment, err := teacherAssignment(as.store, assignmentid, netId)
+	if err != nil {
+		return nil, err
+	}
+
+	err = as.store.SaveRegradeWindow(assignment.ID, window)
+	if err != nil {
+		return nil, err
+	}
+
+	assignment.RegradeWindow = window
+
+	return assignment, nil
+}
+
+// RequestRegrade lets a student dispute the grade of one of their
+// submissions. Regrades can be requested once grades are released,
+// until the assignment's regrade deadline, and only one request per
+// submission may be open at a time.
+func (ss *SubmissionService) RequestRegrade(
+	submissionId, netId, justification string,
+) (*models.RegradeRequest, error) {
+	sub, err := ss.store.GetSubmissionById(submissionId)
+	if err != nil {
+		return nil, err
+	}
+
+	if netId == "" || sub.User.ID != netId {
+		return nil, ERR_NOT_PERMITTED
+	}
+
+	a, err := ss.assignment(sub)
+	if err != nil {
+		return nil, err
+	}
+
+	if !sub.Graded {
+		return nil, ERR_NOT_GRADED
+	}
+
+	now := ss.now()
+
+	if !a.AcceptsRegrades(now) {
+		return nil, ERR_REGRADE_CLOSED
+	}
+
+	requests, err := ss.store.GetRegradeRequests(a.ID, sub.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, r := range requests {
+		if !r.State.Resolved() {
+			return nil, ERR_REGRADE_PENDING
+		}
+	}
+
+	r := &models.RegradeRequest{
+		SubmissionId:  sub.ID,
+		AssignmentId:  a.ID,
+		NetId:         netId,
+		Justification: justification,
+		State:         models.REGRADE_OPEN,
+		OldGrade:      sub.Grade,
+	}
+
+	err = ss.store.InsertRegradeRequest(r)
+	if err != nil {
+		return nil, err
+	}
+
+	err = ss.store.InsertRegradeEvent(r.Move(models.REGRADE_OPEN, netId, "", now))
+	if err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// AcceptRegrade accepts a regrade request, giving the submission a
+// new grade. The submission is graded like any other, so its late
+// penalty still applies.
+func (ss *SubmissionService) AcceptRegrade(
+	requestId, netId string,
+	grade int,
+	note string,
+) (*models.RegradeRequest, error) {
+	r, _, err := ss.teacherRegrade(requestId, netId)
+	if err != nil {
+		return nil, err
+	}
+
+	sub, err := ss.store.GetSubmissionById(r.SubmissionId)
+	if err != nil {
+		return nil, err
+	}
+
+	sub, err = ss.GradeSubmission(grade, sub.Feedback, sub.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	r.NewGrade = &sub.Grade
+
+	return ss.decideRegrade(r, models.REGRADE_ACCEPTED, netId, note)
+}
+
+// RejectRegrade rejects a regrade request, leaving the grade as it
+// was. The reason is shown to the student.
+func (ss *SubmissionService) RejectRegrade(
+	requestId, netId, reason string,
+) (*models.RegradeRequest, error) {
+	r, _, err := ss.teacherRegrade(requestId, netId)
+	if err != nil {
+		return nil, err
+	}
+
+	r.Reason = reason
+
+	return ss.decideRegrade(r, models.REGRADE_REJECTED, netId, reason)
+}
+
+// EscalateRegrade hands a regrade request to another teacher of the
+// course, who is then the only one who may decide it.
+func (ss *SubmissionService) EscalateRegrade(
+	requestId, netId, teacherId, note string,
+) (*models.RegradeRequest, error) {
+	r, courseId, err := ss.teacherRegrade(requestId, netId)
+	if err != nil {
+		return nil, err
+	}
+
+	teacher, err := isTeacher(ss.store, courseId, teacherId)
+	if err != nil {
+		return nil, err
+	}
+
+	if !teacher || teacherId == netId {
+		return nil, ERR_UNKNOWN_TEACHER
+	}
+
+	r.AssignedTo = teacherId
+
+	e := r.Move(models.REGRADE_ESCALATED, netId, note, ss.now())
+
+	err = ss.saveRegrade(r, e)
+	if err != nil {
+		return nil, err
+	}
+
+	err = notify(
+		ss.store,
+		[]string{teacherId},
+		models.REGRADE_ASSIGNED,
+		fmt.Sprintf("A regrade request was escalated to you by %s", netId),
+		r.ID,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// ListRegrades retrieves the regrade requests for an assignment, along
+// with their history. The course's teachers see every request, and
+// students only their own.
+func (ss *SubmissionService) ListRegrades(assignmentId, netId string) (
+	[]*models.RegradeRequest,
+	error,
+) {
+	if netId == "" {
+		return nil, ERR_NOT_PERMITTED
+	}
+
+	courseId, err := ss.store.GetCourseIdByAssignment(assignmentId)
+	if err != nil {
+		return nil, err
+	}
+
+	teacher, err := isTeacher(ss.store, courseId, netId)
+	if err != nil {
+		return nil, err
+	}
+
+	requests, err := ss.regrades(assignmentId, "")
+	if err != nil {
+		return nil, err
+	}
+
+	if teacher {
+		return requests, nil
+	}
+
+	own := []*models.RegradeRequest{}
+	for _, r := range requests {
+		if r.NetId == netId {
+			own = append(own, r)
+		}
+	}
+
+	return own, nil
+}
+
+// regrades retrieves the regrade requests for an assignment, or for
+// one of its submissions, along with their history.
+func (ss *SubmissionService) regrades(assignmentId, submissionId string) (
+	[]*models.RegradeRequest,
+	error,
+) {
+	requests, err := ss.store.GetRegradeRequests(assignmentId, submissionId)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, r := range requests {
+		r.History, err = ss.store.GetRegradeEvents(r.ID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return requests, nil
+}
+
+// teacherRegrade retrieves a regrade request that is still to be
+// decided, for a teacher of its course who may decide it. The course's
+// ID is returned along with it.
+func (ss *SubmissionService) teacherRegrade(requestId, netId string) (
+	*models.RegradeRequest,
+	string,
+	error,
+) {
+	r, err := ss.store.GetRegradeRequestById(requestId)
+	if err != nil {
+		return nil, "", err
+	}
+
+	courseId, err := ss.store.GetCourseIdByAssignment(r.AssignmentId)
+	if err != nil {
+		return nil, "", err
+	}
+
+	err = teacherOnly(ss.store, courseId, netId)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if r.State.Resolved() {
+		return nil, "", ERR_REGRADE_RESOLVED
+	}
+
+	if r.AssignedTo != "" && r.AssignedTo != netId {
+		return nil, "", ERR_NOT_PERMITTED
+	}
+
+	r.History, err = ss.store.GetRegradeEvents(r.ID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return r, courseId, nil
+}
+
+// decideRegrade accepts or rejects a regrade request, and notifies the
+// student who made it.
+func (ss *SubmissionService) decideRegrade(
+	r *models.RegradeRequest,
+	state models.RegradeState,
+	netId, note string,
+) (*models.RegradeRequest, error) {
+	e := r.Move(state, netId, note, ss.now())
+
+	err := ss.saveRegrade(r, e)
+	if err != nil {
+		return nil, err
+	}
+
+	err = notify(
+		ss.store,
+		[]string{r.NetId},
+		models.REGRADE_DECIDED,
+		fmt.Sprintf("Your regrade request was %s", state),
+		r.ID,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+func (ss *SubmissionService) saveRegrade(
+	r *models.RegradeRequest,
+	e *models.RegradeEvent,
+) error {
+	err := ss.store.UpdateRegradeRequest(r)
+	if err != nil {
+		return err
+	}
+
+	return ss.store.InsertRegradeEvent(e)
+}
@@ -0,0 +1,178 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/n30w/Darkspace/internal/models"
+)
+
+// ReleaseGrades releases an assignment's grades, so that students can
+// see their grades and feedback. It also reveals who made each
+// submission when the assignment was graded anonymously. The course's
+// students are notified the first time grades are released. Only the
+// course's teachers may release grades.
+func (as *AssignmentService) ReleaseGrades(assignmentid, netId string) (
+	*models.Assignment,
+	error,
+) {
+	assignment, err := teacherAssignment(as.store, assignmentid, netId)
+	if err != nil {
+		return nil, err
+	}
+
+	if assignment.GradesReleased {
+		return assignment, nil
+	}
+
+	err = as.release(assignment, as.now())
+	if err != nil {
+		return nil, err
+	}
+
+	return assignment, nil
+}
+
+// ScheduleRelease sets when an assignment's grades are released. A
+// nil time clears the schedule, leaving grades in draft until they are
+// released by hand. Only the course's teachers may schedule a release,
+// and only before grades are released.
+func (as *AssignmentService) ScheduleRelease(
+	assignmentid, netId string,
+	at *time.Time,
+) (*models.Assignment, error) {
+	assignment, err := teacherAssignment(as.store, assignmentid, netId)
+	if err != nil {
+		return nil, err
+	}
+
+	if assignment.GradesReleased {
+		return nil, ERR_GRADES_RELEASED
+	}
+
+	if at != nil {
+		utc := at.UTC()
+		at = &utc
+	}
+
+	assignment.ReleaseAt = at
+
+	err = as.store.SaveGradeRelease(assignment)
+	if err != nil {
+		return nil, err
+	}
+
+	return assignment, nil
+}
+
+// ReleaseDue releases the grades of every assignment whose release was
+// scheduled for a time at or before now. It returns how many
+// assignments had their grades released.
+func (as *AssignmentService) ReleaseDue(now time.Time) (int, error) {
+	ids, err := as.store.GetAssignmentsDueForRelease(now)
+	if err != nil {
+		return 0, err
+	}
+
+	released := 0
+
+	for _, id := range ids {
+		assignment, err := as.store.GetAssignmentById(id)
+		if err != nil {
+			return released, err
+		}
+
+		if !assignment.ReleaseDue(now) {
+			continue
+		}
+
+		err = as.release(assignment, now)
+		if err != nil {
+			return released, err
+		}
+
+		released++
+	}
+
+	return released, nil
+}
+
+// release marks an assignment's grades as released at a time and
+// notifies the students of its course. The time is kept, since the
+// deadline for regrade requests counts from it.
+func (as *AssignmentService) release(
+	assignment *models.Assignment,
+	now time.Time,
+) error {
+	released := now.UTC()
+
+	assignment.GradesReleased = true
+	assignment.ReleaseAt = &released
+
+	err := as.store.SaveGradeRelease(assignment)
+	if err != nil {
+		return err
+	}
+
+	courseId, err := as.store.GetCourseIdByAssignment(assignment.ID)
+	if err != nil {
+		return err
+	}
+
+	roster, err := as.store.GetRoster(courseId)
+	if err != nil {
+		return err
+	}
+
+	students := make([]string, len(roster))
+	for i, student := range roster {
+		students[i] = student.ID
+	}
+
+	return notify(
+		as.store,
+		students,
+		models.GRADES_RELEASED,
+		fmt.Sprintf("Grades for %s have been released", assignment.Title),
+		assignment.ID,
+	)
+}
+
+// StudentSubmission retrieves a student's own submission for an
+// assignment, along with its regrade requests. Its grade and feedback
+// are left out until the assignment's grades are released.
+func (ss *SubmissionService) StudentSubmission(netId, assignmentId string) (
+	*models.Submission,
+	error,
+) {
+	a, err := ss.store.GetAssignmentById(assignmentId)
+	if err != nil {
+		return nil, err
+	}
+
+	submission, err := ss.GetUserSubmission(netId, assignmentId)
+	if err != nil {
+		return nil, err
+	}
+
+	hideGrades(a, submission)
+
+	submission.Regrades, err = ss.regrades(a.ID, submission.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return submission, nil
+}
+
+// hideGrades takes the grades and feedback off submissions whose
+// assignment's grades are still in draft.
+func hideGrades(a *models.Assignment, submissions ...*models.Submission) {
+	if a.GradesReleased {
+		return
+	}
+
+	for _, s := range submissions {
+		s.HideGrade()
+	}
+}
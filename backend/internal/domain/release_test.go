@@ -0,0 +1,189 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/n30w/Darkspace/internal/models"
+)
+
+func TestAssignmentService_ReleaseDue(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	past := now.Add(-time.Hour)
+	future := now.Add(time.Hour)
+
+	store := newMockAssignmentStore()
+	store.assignments["a1"].ReleaseAt = &past
+	store.assignments["a2"].ReleaseAt = &future
+
+	released, err := NewAssignmentService(store).ReleaseDue(now)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+
+	if released != 1 {
+		t.Errorf("got %d released, want 1", released)
+	}
+
+	if !store.assignments["a1"].GradesReleased {
+		t.Errorf("got a1 unreleased, want released")
+	}
+
+	if store.assignments["a2"].GradesReleased {
+		t.Errorf("got a2 released, want it to wait until %v", future)
+	}
+
+	if len(store.notifications) != 2 {
+		t.Fatalf("got %d notifications, want one per student", len(store.notifications))
+	}
+
+	for _, n := range store.notifications {
+		if n.Kind != models.GRADES_RELEASED || n.Ref != "a1" {
+			t.Errorf("got %s notification for %s, want %s for a1", n.Kind, n.Ref, models.GRADES_RELEASED)
+		}
+	}
+}
+
+func TestAssignmentService_ReleaseGrades(t *testing.T) {
+	store := newMockAssignmentStore()
+	as := NewAssignmentService(store)
+
+	_, err := as.ReleaseGrades("a1", "stu1")
+	if !errors.Is(err, ERR_NOT_PERMITTED) {
+		t.Fatalf("got %v, want %v", err, ERR_NOT_PERMITTED)
+	}
+
+	for i := 0; i < 2; i++ {
+		_, err = as.ReleaseGrades("a1", "prof")
+		if err != nil {
+			t.Fatalf("%+v", err)
+		}
+	}
+
+	if len(store.notifications) != 2 {
+		t.Errorf("got %d notifications, want students notified once", len(store.notifications))
+	}
+
+	at := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	_, err = as.ScheduleRelease("a1", "prof", &at)
+	if !errors.Is(err, ERR_GRADES_RELEASED) {
+		t.Errorf("got %v, want %v", err, ERR_GRADES_RELEASED)
+	}
+}
+
+func TestAssignmentService_ScheduleRelease(t *testing.T) {
+	store := newMockAssignmentStore()
+	as := NewAssignmentService(store)
+
+	at := time.Date(2024, 5, 1, 8, 0, 0, 0, time.FixedZone("EDT", -4*60*60))
+
+	a, err := as.ScheduleRelease("a1", "prof", &at)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+
+	if a.ReleaseAt == nil || a.ReleaseAt.Location() != time.UTC || !a.ReleaseAt.Equal(at) {
+		t.Errorf("got %v, want %v in UTC", a.ReleaseAt, at)
+	}
+
+	a, err = as.ScheduleRelease("a1", "prof", nil)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+
+	if a.ReleaseAt != nil {
+		t.Errorf("got %v, want the schedule cleared", a.ReleaseAt)
+	}
+}
+
+// ========= //
+//   MOCKS   //
+// ========= //
+
+// mockAssignmentStore has assignments "a1" and "a2" in course "c1",
+// which is taught by "prof" and has students "stu1" and "stu2". No
+// student has an extension unless a test grants one.
+type mockAssignmentStore struct {
+	AssignmentStore
+
+	assignments   map[string]*models.Assignment
+	extensions    []*models.Extension
+	notifications []*models.Notification
+}
+
+func newMockAssignmentStore() *mockAssignmentStore {
+	assignment := func(id string) *models.Assignment {
+		return &models.Assignment{
+			Post: models.Post{Entity: models.Entity{ID: id}, Title: id},
+		}
+	}
+
+	return &mockAssignmentStore{
+		assignments: map[string]*models.Assignment{
+			"a1": assignment("a1"),
+			"a2": assignment("a2"),
+		},
+	}
+}
+
+func (m *mockAssignmentStore) GetAssignmentById(assignmentid string) (
+	*models.Assignment,
+	error,
+) {
+	return m.assignments[assignmentid], nil
+}
+
+func (m *mockAssignmentStore) GetAssignmentsDueForRelease(now time.Time) (
+	[]string,
+	error,
+) {
+	var ids []string
+	for _, id := range []string{"a1", "a2"} {
+		a := m.assignments[id]
+		if !a.GradesReleased && a.ReleaseAt != nil && !a.ReleaseAt.After(now) {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+func (m *mockAssignmentStore) SaveGradeRelease(a *models.Assignment) error {
+	m.assignments[a.ID] = a
+	return nil
+}
+
+func (m *mockAssignmentStore) ChangeAssignmentFileTypes(a *models.Assignment) (
+	*models.Assignment,
+	error,
+) {
+	m.assignments[a.ID] = a
+	return a, nil
+}
+
+func (m *mockAssignmentStore) GetCourseIdByAssignment(assignmentId string) (
+	string,
+	error,
+) {
+	return "c1", nil
+}
+
+func (m *mockAssignmentStore) IsCourseTeacher(courseId, netId string) (
+	bool,
+	error,
+) {
+	return netId == "prof", nil
+}
+
+func (m *mockAssignmentStore) GetRoster(courseid string) ([]models.User, error) {
+	return []models.User{
+		{Entity: models.Entity{ID: "stu1"}},
+		{Entity: models.Entity{ID: "stu2"}},
+	}, nil
+}
+
+func (m *mockAssignmentStore) InsertNotification(n *models.Notification) error {
+	m.notifications = append(m.notifications, n)
+	return nil
+}
@@ -0,0 +1,252 @@
+package domain
+
+import (
+	"github.com/n30w/Darkspace/internal/models"
+)
+
+type RubricStore interface {
+	DueDateStore
+	AttemptStore
+
+	InsertRubric(r *models.Rubric) error
+	GetRubricById(id string) (*models.Rubric, error)
+	GetRubricsByCourse(courseId string) ([]*models.Rubric, error)
+	IsRubricInUse(id string) (bool, error)
+	DeleteRubric(id string) error
+
+	GetAssignmentById(assignmentid string) (*models.Assignment, error)
+	ChangeAssignmentRubric(assignment *models.Assignment) (*models.Assignment, error)
+
+	GetSubmissionById(submissionId string) (*models.Submission, error)
+	GetAssignmentIdBySubmission(submissionId string) (string, error)
+	UpdateSubmissionGrade(sub *models.Submission) error
+	SaveRubricScores(submissionId string, scores []*models.CriterionScore) error
+
+	IsCourseTeacher(courseId, netId string) (bool, error)
+}
+
+type RubricService struct {
+	store RubricStore
+}
+
+func NewRubricService(s RubricStore) *RubricService {
+	return &RubricService{store: s}
+}
+
+// CreateRubric creates a rubric in a course. Only the course's
+// teachers may create rubrics.
+func (rs *RubricService) CreateRubric(r *models.Rubric) (*models.Rubric, error) {
+	err := teacherOnly(rs.store, r.CourseId, r.Owner)
+	if err != nil {
+		return nil, err
+	}
+
+	err = rs.store.InsertRubric(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// ListRubrics retrieves a course's rubrics, without their criteria.
+func (rs *RubricService) ListRubrics(courseId string) ([]*models.Rubric, error) {
+	return rs.store.GetRubricsByCourse(courseId)
+}
+
+// ReadRubric retrieves a rubric along with its criteria.
+func (rs *RubricService) ReadRubric(rubricId string) (*models.Rubric, error) {
+	return rs.store.GetRubricById(rubricId)
+}
+
+// DeleteRubric deletes a rubric. Rubrics that are attached to an
+// assignment cannot be deleted, since that would lose the breakdown
+// of grades already given with them.
+func (rs *RubricService) DeleteRubric(rubricId, netId string) error {
+	r, err := rs.store.GetRubricById(rubricId)
+	if err != nil {
+		return err
+	}
+
+	err = teacherOnly(rs.store, r.CourseId, netId)
+	if err != nil {
+		return err
+	}
+
+	used, err := rs.store.IsRubricInUse(r.ID)
+	if err != nil {
+		return err
+	}
+
+	if used {
+		return ERR_RUBRIC_IN_USE
+	}
+
+	return rs.store.DeleteRubric(r.ID)
+}
+
+// AttachRubric sets the rubric an assignment's submissions are graded
+// against. An empty rubric ID removes the assignment's rubric. The
+// rubric must belong to the assignment's course.
+func (rs *RubricService) AttachRubric(assignmentId, rubricId, netId string) (
+	*models.Assignment,
+	error,
+) {
+	a, err := rs.store.GetAssignmentById(assignmentId)
+	if err != nil {
+		return nil, err
+	}
+
+	courseId, err := rs.store.GetCourseIdByAssignment(a.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = teacherOnly(rs.store, courseId, netId)
+	if err != nil {
+		return nil, err
+	}
+
+	if rubricId != "" {
+		r, err := rs.store.GetRubricById(rubricId)
+		if err != nil {
+			return nil, err
+		}
+
+		if r.CourseId != courseId {
+			return nil, ERR_NOT_PERMITTED
+		}
+	}
+
+	a.RubricId = rubricId
+
+	return rs.store.ChangeAssignmentRubric(a)
+}
+
+// GradeSubmission grades a submission against its assignment's
+// rubric. Every criterion must be scored exactly once, and the
+// submission's grade is the total of its scores. A score placed at a
+// level is given that level's points, otherwise its points must be
+// within the criterion's range.
+func (rs *RubricService) GradeSubmission(
+	submissionId, netId string,
+	scores []*models.CriterionScore,
+	feedback string,
+) (*models.Submission, error) {
+	sub, err := rs.store.GetSubmissionById(submissionId)
+	if err != nil {
+		return nil, err
+	}
+
+	assignmentId, err := rs.store.GetAssignmentIdBySubmission(sub.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	a, err := rs.store.GetAssignmentById(assignmentId)
+	if err != nil {
+		return nil, err
+	}
+
+	courseId, err := rs.store.GetCourseIdByAssignment(a.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = teacherOnly(rs.store, courseId, netId)
+	if err != nil {
+		return nil, err
+	}
+
+	if a.RubricId == "" {
+		return nil, ERR_NO_RUBRIC
+	}
+
+	r, err := rs.store.GetRubricById(a.RubricId)
+	if err != nil {
+		return nil, err
+	}
+
+	total, err := scoreRubric(r, scores)
+	if err != nil {
+		return nil, err
+	}
+
+	err = rs.store.SaveRubricScores(sub.ID, scores)
+	if err != nil {
+		return nil, err
+	}
+
+	due, err := studentAssignment(rs.store, a, sub.User.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	sub.AssignmentId = a.ID
+	sub.SetGrade(total, due)
+	sub.Feedback = feedback
+	sub.Rubric = scores
+
+	err = rs.store.UpdateSubmissionGrade(sub)
+	if err != nil {
+		return nil, err
+	}
+
+	err = recountAttempts(rs.store, a, sub.User.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	anonymize(a, sub)
+
+	return sub, nil
+}
+
+// scoreRubric checks scores against a rubric and totals them. Scores
+// are ordered like the rubric's criteria, and filled in with their
+// criterion's name and their level's points.
+func scoreRubric(r *models.Rubric, scores []*models.CriterionScore) (
+	float64,
+	error,
+) {
+	byCriterion := make(map[string]*models.CriterionScore, len(scores))
+
+	for _, score := range scores {
+		c := r.Criterion(score.CriterionId)
+		if c == nil {
+			return 0, ERR_UNKNOWN_CRITERION
+		}
+
+		if _, ok := byCriterion[c.ID]; ok {
+			return 0, ERR_RUBRIC_INCOMPLETE
+		}
+
+		if score.Level != "" {
+			l, ok := c.Level(score.Level)
+			if !ok {
+				return 0, ERR_UNKNOWN_LEVEL
+			}
+			score.Points = l.Points
+		}
+
+		if score.Points < 0 || score.Points > c.Points() {
+			return 0, ERR_SCORE_OUT_OF_RANGE
+		}
+
+		score.Criterion = c.Name
+		byCriterion[c.ID] = score
+	}
+
+	if len(byCriterion) != len(r.Criteria) {
+		return 0, ERR_RUBRIC_INCOMPLETE
+	}
+
+	var total float64
+
+	for i, c := range r.Criteria {
+		scores[i] = byCriterion[c.ID]
+		total += scores[i].Points
+	}
+
+	return total, nil
+}
@@ -0,0 +1,354 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/n30w/Darkspace/internal/dal"
+	"github.com/n30w/Darkspace/internal/models"
+)
+
+func TestRubricService_GradeSubmission(t *testing.T) {
+	tests := []struct {
+		name   string
+		netId  string
+		scores []*models.CriterionScore
+		total  float64
+		want   error
+	}{
+		{
+			name:  "levels and direct points",
+			netId: "prof",
+			scores: []*models.CriterionScore{
+				{CriterionId: "evidence", Points: 1.5, Comment: "cite more"},
+				{CriterionId: "clarity", Level: "Excellent"},
+			},
+			total: 5.5,
+		},
+		{
+			name:  "student cannot grade",
+			netId: "stu1",
+			scores: []*models.CriterionScore{
+				{CriterionId: "clarity", Level: "Excellent"},
+				{CriterionId: "evidence", Level: "Strong"},
+			},
+			want: ERR_NOT_PERMITTED,
+		},
+		{
+			name:  "criterion missing",
+			netId: "prof",
+			scores: []*models.CriterionScore{
+				{CriterionId: "clarity", Level: "Excellent"},
+			},
+			want: ERR_RUBRIC_INCOMPLETE,
+		},
+		{
+			name:  "criterion scored twice",
+			netId: "prof",
+			scores: []*models.CriterionScore{
+				{CriterionId: "clarity", Level: "Excellent"},
+				{CriterionId: "clarity", Level: "Poor"},
+				{CriterionId: "evidence", Level: "Strong"},
+			},
+			want: ERR_RUBRIC_INCOMPLETE,
+		},
+		{
+			name:  "unknown criterion",
+			netId: "prof",
+			scores: []*models.CriterionScore{
+				{CriterionId: "clarity", Level: "Excellent"},
+				{CriterionId: "evidence", Level: "Strong"},
+				{CriterionId: "style", Points: 1},
+			},
+			want: ERR_UNKNOWN_CRITERION,
+		},
+		{
+			name:  "unknown level",
+			netId: "prof",
+			scores: []*models.CriterionScore{
+				{CriterionId: "clarity", Level: "Perfect"},
+				{CriterionId: "evidence", Level: "Strong"},
+			},
+			want: ERR_UNKNOWN_LEVEL,
+		},
+		{
+			name:  "points above the criterion's",
+			netId: "prof",
+			scores: []*models.CriterionScore{
+				{CriterionId: "clarity", Points: 5},
+				{CriterionId: "evidence", Level: "Strong"},
+			},
+			want: ERR_SCORE_OUT_OF_RANGE,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				store := newMockRubricStore()
+
+				sub, err := NewRubricService(store).GradeSubmission(
+					"s1",
+					tt.netId,
+					tt.scores,
+					"good work",
+				)
+
+				if !errors.Is(err, tt.want) {
+					t.Fatalf("got %v, want %v", err, tt.want)
+				}
+
+				if tt.want != nil {
+					if store.saved != nil {
+						t.Errorf("scores were saved for a failed grading")
+					}
+					return
+				}
+
+				if sub.Grade != tt.total || store.graded.Grade != tt.total {
+					t.Errorf("got grade %v, want %v", sub.Grade, tt.total)
+				}
+
+				// Scores follow the rubric's order and carry the
+				// criterion's name.
+				if sub.Rubric[0].Criterion != "Clarity" || sub.Rubric[1].Criterion != "Evidence" {
+					t.Errorf("got scores for %s and %s, want Clarity and Evidence", sub.Rubric[0].Criterion, sub.Rubric[1].Criterion)
+				}
+
+				if sub.Rubric[0].Points != 4 {
+					t.Errorf("got %v points for Excellent, want 4", sub.Rubric[0].Points)
+				}
+			},
+		)
+	}
+}
+
+func TestRubricService_NoRubric(t *testing.T) {
+	store := newMockRubricStore()
+	store.assignment.RubricId = ""
+
+	_, err := NewRubricService(store).GradeSubmission("s1", "prof", nil, "")
+	if !errors.Is(err, ERR_NO_RUBRIC) {
+		t.Errorf("got %v, want %v", err, ERR_NO_RUBRIC)
+	}
+}
+
+func TestRubricService_AttachRubric(t *testing.T) {
+	tests := []struct {
+		name     string
+		rubricId string
+		netId    string
+		want     error
+	}{
+		{name: "attach", rubricId: "r1", netId: "prof"},
+		{name: "detach", rubricId: "", netId: "prof"},
+		{name: "student", rubricId: "r1", netId: "stu1", want: ERR_NOT_PERMITTED},
+		{name: "rubric from another course", rubricId: "r2", netId: "prof", want: ERR_NOT_PERMITTED},
+		{name: "missing rubric", rubricId: "r3", netId: "prof", want: dal.ERR_RECORD_NOT_FOUND},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				store := newMockRubricStore()
+
+				a, err := NewRubricService(store).AttachRubric("a1", tt.rubricId, tt.netId)
+
+				if !errors.Is(err, tt.want) {
+					t.Fatalf("got %v, want %v", err, tt.want)
+				}
+
+				if tt.want == nil && a.RubricId != tt.rubricId {
+					t.Errorf("got rubric %q, want %q", a.RubricId, tt.rubricId)
+				}
+			},
+		)
+	}
+}
+
+func TestRubricService_DeleteRubric(t *testing.T) {
+	store := newMockRubricStore()
+	rs := NewRubricService(store)
+
+	err := rs.DeleteRubric("r1", "prof")
+	if !errors.Is(err, ERR_RUBRIC_IN_USE) {
+		t.Errorf("got %v, want %v", err, ERR_RUBRIC_IN_USE)
+	}
+
+	store.assignment.RubricId = ""
+
+	err = rs.DeleteRubric("r1", "stu1")
+	if !errors.Is(err, ERR_NOT_PERMITTED) {
+		t.Errorf("got %v, want %v for a student", err, ERR_NOT_PERMITTED)
+	}
+
+	err = rs.DeleteRubric("r1", "prof")
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+
+	if _, ok := store.rubrics["r1"]; ok {
+		t.Errorf("rubric was not deleted")
+	}
+}
+
+// ========= //
+//   MOCKS   //
+// ========= //
+
+// mockRubricStore has a course "c1" taught by "prof". Its assignment
+// "a1" is graded with rubric "r1", which has the criteria "clarity"
+// (up to 4 points) and "evidence" (up to 3 points). Submission "s1" is
+// stu1's submission for "a1". Rubric "r2" belongs to another course.
+type mockRubricStore struct {
+	RubricStore
+
+	rubrics    map[string]*models.Rubric
+	assignment *models.Assignment
+	submission *models.Submission
+
+	saved  []*models.CriterionScore
+	graded *models.Submission
+}
+
+func newMockRubricStore() *mockRubricStore {
+	return &mockRubricStore{
+		rubrics: map[string]*models.Rubric{
+			"r1": {
+				Entity:   models.Entity{ID: "r1"},
+				CourseId: "c1",
+				Criteria: []*models.Criterion{
+					{
+						Entity: models.Entity{ID: "clarity"},
+						Name:   "Clarity",
+						Levels: []models.Level{
+							{Name: "Excellent", Points: 4},
+							{Name: "Poor", Points: 1},
+						},
+					},
+					{
+						Entity: models.Entity{ID: "evidence"},
+						Name:   "Evidence",
+						Levels: []models.Level{
+							{Name: "Strong", Points: 3},
+							{Name: "Weak", Points: 0},
+						},
+					},
+				},
+			},
+			"r2": {
+				Entity:   models.Entity{ID: "r2"},
+				CourseId: "c2",
+			},
+		},
+		assignment: &models.Assignment{
+			Post:     models.Post{Entity: models.Entity{ID: "a1"}},
+			RubricId: "r1",
+		},
+		submission: &models.Submission{
+			Entity: models.Entity{ID: "s1"},
+			User:   models.User{Entity: models.Entity{ID: "stu1"}},
+		},
+	}
+}
+
+func (m *mockRubricStore) GetRubricById(id string) (*models.Rubric, error) {
+	r, ok := m.rubrics[id]
+	if !ok {
+		return nil, dal.ERR_RECORD_NOT_FOUND
+	}
+	return r, nil
+}
+
+func (m *mockRubricStore) IsRubricInUse(id string) (bool, error) {
+	return m.assignment.RubricId == id, nil
+}
+
+func (m *mockRubricStore) DeleteRubric(id string) error {
+	delete(m.rubrics, id)
+	return nil
+}
+
+func (m *mockRubricStore) GetAssignmentById(assignmentid string) (
+	*models.Assignment,
+	error,
+) {
+	if assignmentid != m.assignment.ID {
+		return nil, dal.ERR_RECORD_NOT_FOUND
+	}
+	return m.assignment, nil
+}
+
+func (m *mockRubricStore) GetExtension(assignmentId, netId string) (
+	*models.Extension,
+	error,
+) {
+	return nil, nil
+}
+
+func (m *mockRubricStore) GetAccommodation(courseId, netId string) (
+	*models.Accommodation,
+	error,
+) {
+	return nil, nil
+}
+
+func (m *mockRubricStore) GetAttempts(assignmentId, netId string) (
+	[]*models.Submission,
+	error,
+) {
+	return []*models.Submission{m.submission}, nil
+}
+
+func (m *mockRubricStore) SetCountedAttempt(
+	assignmentId, netId, submissionId string,
+) error {
+	return nil
+}
+
+func (m *mockRubricStore) GetCourseIdByAssignment(assignmentId string) (
+	string,
+	error,
+) {
+	return "c1", nil
+}
+
+func (m *mockRubricStore) ChangeAssignmentRubric(
+	assignment *models.Assignment,
+) (*models.Assignment, error) {
+	return assignment, nil
+}
+
+func (m *mockRubricStore) GetSubmissionById(submissionId string) (
+	*models.Submission,
+	error,
+) {
+	if submissionId != m.submission.ID {
+		return nil, dal.ERR_RECORD_NOT_FOUND
+	}
+	return m.submission, nil
+}
+
+func (m *mockRubricStore) GetAssignmentIdBySubmission(submissionId string) (
+	string,
+	error,
+) {
+	return m.assignment.ID, nil
+}
+
+func (m *mockRubricStore) SaveRubricScores(
+	submissionId string,
+	scores []*models.CriterionScore,
+) error {
+	m.saved = scores
+	return nil
+}
+
+func (m *mockRubricStore) UpdateSubmissionGrade(sub *models.Submission) error {
+	m.graded = sub
+	return nil
+}
+
+func (m *mockRubricStore) IsCourseTeacher(courseId, netId string) (bool, error) {
+	return courseId == "c1" && netId == "prof", nil
+}
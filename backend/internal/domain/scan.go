@@ -0,0 +1,15 @@
+package domain
+
+import (
+	"io"
+
+	"github.com/n30w/Darkspace/internal/models"
+)
+
+// Scanner scans the content of a file for malware.
+type Scanner interface {
+	// Scan reads r to the end and reports whether it is clean or
+	// should be quarantined. An error means the content could not
+	// be scanned at all.
+	Scan(r io.Reader) (*models.ScanResult, error)
+}
@@ -12,6 +12,19 @@ type Service struct {
 	MediaService          *MediaService
 	AuthenticationService *AuthenticationService
 	FileService           *FileService
+	StorageService        *StorageService
+	ArchiveService        *ArchiveService
+	ProjectService        *ProjectService
+	QuizService           *QuizService
+	RubricService         *RubricService
+	GradebookService      *GradebookService
+	ExtensionService      *ExtensionService
+	NotificationService   *NotificationService
+	PeerReviewService     *PeerReviewService
+	SimilarityService     *SimilarityService
+	AutogradeService      *AutogradeService
+	AnnotationService     *AnnotationService
+	ModuleService         *ModuleService
 }
 
 func NewServices(s *dal.Store, e *dal.ExcelStore, f *dal.LocalVolume) *Service {
@@ -25,6 +38,19 @@ func NewServices(s *dal.Store, e *dal.ExcelStore, f *dal.LocalVolume) *Service {
 		MediaService:          NewMediaService(s),
 		AuthenticationService: NewAuthenticationService(s),
 		FileService:           NewFileService(f),
+		StorageService:        NewStorageService(s, f),
+		ArchiveService:        NewArchiveService(s, f),
+		ProjectService:        NewProjectService(s),
+		QuizService:           NewQuizService(s),
+		RubricService:         NewRubricService(s),
+		GradebookService:      NewGradebookService(s),
+		ExtensionService:      NewExtensionService(s),
+		NotificationService:   NewNotificationService(s),
+		PeerReviewService:     NewPeerReviewService(s),
+		SimilarityService:     NewSimilarityService(s, f),
+		AutogradeService:      NewAutogradeService(s, f),
+		AnnotationService:     NewAnnotationService(s, f),
+		ModuleService:         NewModuleService(s),
 	}
 }
 
@@ -0,0 +1,325 @@
+package domain
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/n30w/Darkspace/internal/models"
+)
+
+// maxTextSize is the most of a file, or of a PDF's decompressed
+// stream, that is read for its text.
+const maxTextSize = 32 << 20
+
+type SimilarityStore interface {
+	NotificationStore
+
+	GetAssignmentById(assignmentid string) (*models.Assignment, error)
+	GetCourseIdByAssignment(assignmentId string) (string, error)
+	IsCourseTeacher(courseId, netId string) (bool, error)
+
+	GetSubmissions(assignmentId string) ([]*models.Submission, error)
+	GetAssignmentSubmissionMedia(assignmentId string) (
+		map[string][]*models.Media,
+		error,
+	)
+
+	InsertSimilarityReport(r *models.SimilarityReport) error
+	GetSimilarityReportById(id string) (*models.SimilarityReport, error)
+	GetSimilarityReports(assignmentId string) ([]*models.SimilarityReport, error)
+	GetPendingSimilarityReports() ([]string, error)
+	SaveSimilarityReport(r *models.SimilarityReport) error
+	GetSimilarityPairs(reportId string) ([]*models.SimilarityPair, error)
+}
+
+type SimilarityService struct {
+	store SimilarityStore
+	files FileStore
+}
+
+func NewSimilarityService(s SimilarityStore, f FileStore) *SimilarityService {
+	return &SimilarityService{store: s, files: f}
+}
+
+// RequestReport asks for the submissions to an assignment to be
+// compared with each other, and with those to the assignments of past
+// offerings in compareWith. Reports are made in the background, and
+// the teacher who asked is notified once theirs is ready. Only the
+// course's teachers may ask, and only teachers of the past offerings
+// may compare against them.
+func (ss *SimilarityService) RequestReport(
+	assignmentId, netId string,
+	compareWith []string,
+) (*models.SimilarityReport, error) {
+	a, err := teacherAssignment(ss.store, assignmentId, netId)
+	if err != nil {
+		return nil, err
+	}
+
+	past := []string{}
+
+	for _, id := range compareWith {
+		if id == a.ID {
+			continue
+		}
+
+		_, err := teacherAssignment(ss.store, id, netId)
+		if err != nil {
+			return nil, err
+		}
+
+		past = append(past, id)
+	}
+
+	r := &models.SimilarityReport{
+		AssignmentId: a.ID,
+		RequestedBy:  netId,
+		CompareWith:  past,
+		Status:       models.SIMILARITY_PENDING,
+	}
+
+	err = ss.store.InsertSimilarityReport(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// RunPending makes every report that is waiting to be made. A report
+// whose submissions cannot be read is marked as failed, along with
+// why, rather than stopping the others. It returns how many reports
+// were made.
+func (ss *SimilarityService) RunPending(now time.Time) (int, error) {
+	ids, err := ss.store.GetPendingSimilarityReports()
+	if err != nil {
+		return 0, err
+	}
+
+	made := 0
+
+	for _, id := range ids {
+		r, err := ss.store.GetSimilarityReportById(id)
+		if err != nil {
+			return made, err
+		}
+
+		err = ss.run(r, now)
+		if err != nil {
+			return made, err
+		}
+
+		made++
+	}
+
+	return made, nil
+}
+
+// run compares the submissions of a report, saves what was found and
+// notifies the teacher who asked for it.
+func (ss *SimilarityService) run(r *models.SimilarityReport, now time.Time) error {
+	pairs, err := ss.compare(r)
+	if err != nil {
+		r.Status = models.SIMILARITY_FAILED
+		r.Error = err.Error()
+		r.Pairs = nil
+	} else {
+		r.Status = models.SIMILARITY_DONE
+		r.Pairs = pairs
+	}
+
+	finished := now.UTC()
+	r.FinishedAt = &finished
+
+	err = ss.store.SaveSimilarityReport(r)
+	if err != nil {
+		return err
+	}
+
+	a, err := ss.store.GetAssignmentById(r.AssignmentId)
+	if err != nil {
+		return err
+	}
+
+	message := fmt.Sprintf("The similarity report for %s is ready", a.Title)
+	if r.Status == models.SIMILARITY_FAILED {
+		message = fmt.Sprintf("The similarity report for %s failed", a.Title)
+	}
+
+	return notify(
+		ss.store,
+		[]string{r.RequestedBy},
+		models.SIMILARITY_REPORTED,
+		message,
+		r.ID,
+	)
+}
+
+// compare ranks the pairs of submissions of a report by how similar
+// they are.
+func (ss *SimilarityService) compare(r *models.SimilarityReport) (
+	[]*models.SimilarityPair,
+	error,
+) {
+	docs, err := ss.documents(r.AssignmentId)
+	if err != nil {
+		return nil, err
+	}
+
+	var past []*models.Document
+
+	for _, id := range r.CompareWith {
+		d, err := ss.documents(id)
+		if err != nil {
+			return nil, err
+		}
+
+		past = append(past, d...)
+	}
+
+	pairs := models.RankPairs(docs, past)
+
+	for _, p := range pairs {
+		p.ReportId = r.ID
+	}
+
+	return pairs, nil
+}
+
+// documents reads the text of the submissions to an assignment that
+// count. The text of every TXT, PDF and other text file a student
+// submitted is joined into one document. Students whose files have no
+// text that can be read are left out, as is quarantined media.
+func (ss *SimilarityService) documents(assignmentId string) (
+	[]*models.Document,
+	error,
+) {
+	submissions, err := ss.store.GetSubmissions(assignmentId)
+	if err != nil {
+		return nil, err
+	}
+
+	media, err := ss.store.GetAssignmentSubmissionMedia(assignmentId)
+	if err != nil {
+		return nil, err
+	}
+
+	var docs []*models.Document
+
+	for _, sub := range submissions {
+		if !sub.Counts {
+			continue
+		}
+
+		var texts []string
+
+		for _, m := range media[sub.ID] {
+			if m.Quarantined() {
+				continue
+			}
+
+			text, err := ss.text(m)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %v", m.DisplayName(), err)
+			}
+
+			if strings.TrimSpace(text) != "" {
+				texts = append(texts, text)
+			}
+		}
+
+		if len(texts) == 0 {
+			continue
+		}
+
+		docs = append(
+			docs,
+			models.NewDocument(
+				sub.ID,
+				sub.User.ID,
+				assignmentId,
+				strings.Join(texts, "\n"),
+			),
+		)
+	}
+
+	return docs, nil
+}
+
+// text reads the text of a file. Files that are neither PDFs nor
+// text have none.
+func (ss *SimilarityService) text(m *models.Media) (string, error) {
+	if m.FileType != models.PDF && !m.FileType.IsText() {
+		return "", nil
+	}
+
+	f, err := ss.files.OpenFile(m.FilePath)
+	if err != nil {
+		return "", err
+	}
+
+	defer f.Close()
+
+	data, err := io.ReadAll(io.LimitReader(f, maxTextSize))
+	if err != nil {
+		return "", err
+	}
+
+	if m.FileType == models.PDF {
+		return PDFText(data), nil
+	}
+
+	return strings.ToValidUTF8(string(data), ""), nil
+}
+
+// ListReports retrieves the similarity reports of an assignment,
+// newest first, without their pairs. Only the course's teachers may
+// see them.
+func (ss *SimilarityService) ListReports(assignmentId, netId string) (
+	[]*models.SimilarityReport,
+	error,
+) {
+	a, err := teacherAssignment(ss.store, assignmentId, netId)
+	if err != nil {
+		return nil, err
+	}
+
+	return ss.store.GetSimilarityReports(a.ID)
+}
+
+// Report retrieves a similarity report along with its pairs, the most
+// similar first. While the assignment is graded anonymously, its
+// students are shown under their pseudonyms. Only the course's
+// teachers may see it.
+func (ss *SimilarityService) Report(reportId, netId string) (
+	*models.SimilarityReport,
+	error,
+) {
+	r, err := ss.store.GetSimilarityReportById(reportId)
+	if err != nil {
+		return nil, err
+	}
+
+	a, err := teacherAssignment(ss.store, r.AssignmentId, netId)
+	if err != nil {
+		return nil, err
+	}
+
+	r.Pairs, err = ss.store.GetSimilarityPairs(r.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if a.Blind() {
+		for _, p := range r.Pairs {
+			p.NetIdA = models.Pseudonym(a.AnonymousKey, p.NetIdA)
+			if p.AssignmentB == a.ID {
+				p.NetIdB = models.Pseudonym(a.AnonymousKey, p.NetIdB)
+			}
+		}
+	}
+
+	return r, nil
+}
@@ -0,0 +1,365 @@
+package domain
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/n30w/Darkspace/internal/dal"
+	"github.com/n30w/Darkspace/internal/models"
+)
+
+const essay = "The mitochondria is the powerhouse of the cell and it produces most of the chemical energy needed to power the cell's biochemical reactions"
+
+// pdf builds a PDF with a single page whose content stream shows text,
+// compressed with FlateDecode when flate is set.
+func pdf(content string, flate bool) []byte {
+	var stream []byte
+	filter := ""
+
+	if flate {
+		var buf bytes.Buffer
+		zw := zlib.NewWriter(&buf)
+		zw.Write([]byte(content))
+		zw.Close()
+		stream = buf.Bytes()
+		filter = " /Filter /FlateDecode"
+	} else {
+		stream = []byte(content)
+	}
+
+	var b bytes.Buffer
+	b.WriteString("%PDF-1.4\n")
+	b.WriteString("1 0 obj\n<< /Type /Catalog /Pages 2 0 R >>\nendobj\n")
+	b.WriteString("2 0 obj\n<< /Type /Pages /Kids [3 0 R] /Count 1 >>\nendobj\n")
+	b.WriteString("3 0 obj\n<< /Type /Page /Parent 2 0 R /Contents 4 0 R >>\nendobj\n")
+	fmt.Fprintf(&b, "4 0 obj\n<< /Length %d%s >>\nstream\n", len(stream), filter)
+	b.Write(stream)
+	b.WriteString("\nendstream\nendobj\n%%EOF\n")
+
+	return b.Bytes()
+}
+
+func TestPDFText(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		flate   bool
+		want    string
+	}{
+		{
+			name:    "shown strings",
+			content: "BT /F1 12 Tf 72 720 Td (Hello, world) Tj 0 -14 Td (Second line) Tj ET",
+			want:    "Hello, world\nSecond line\n",
+		},
+		{
+			name:    "compressed",
+			content: "BT /F1 12 Tf (Hello) Tj ET",
+			flate:   true,
+			want:    "Hello\n",
+		},
+		{
+			name:    "kerned array",
+			content: "BT [(Hel) 20 (lo) -300 (there)] TJ ET",
+			want:    "Hello there\n",
+		},
+		{
+			name:    "escapes and hex",
+			content: `BT (\(a\) b\\c) Tj T* <48693F> Tj ET`,
+			want:    "(a) b\\c\nHi?\n",
+		},
+		{
+			name:    "UTF-16",
+			content: "BT <FEFF00E9007400E9> Tj ET",
+			want:    "été\n",
+		},
+	}
+
+	for _, tt := range tests {
+		got := PDFText(pdf(tt.content, tt.flate))
+		if got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSimilarityService_RunPending(t *testing.T) {
+	dir := t.TempDir()
+
+	write := func(name string, data []byte) string {
+		p := filepath.Join(dir, name)
+		err := os.WriteFile(p, data, 0o644)
+		if err != nil {
+			t.Fatalf("%+v", err)
+		}
+		return p
+	}
+
+	store := newMockSimilarityStore()
+	store.media = map[string]map[string][]*models.Media{
+		"a1": {
+			"s1": {{FileType: models.TXT, FilePath: write("s1.txt", []byte(essay))}},
+			"s2": {
+				{FileType: models.PDF, FilePath: write("s2.pdf", pdf("BT ("+essay+") Tj ET", true))},
+				{FileType: models.PNG, FilePath: write("s2.png", []byte("\x89PNG"))},
+			},
+			"s3": {{FileType: models.TXT, FilePath: write("s3.txt", []byte("Something else entirely, about the French revolution and what caused it"))}},
+		},
+		"old": {
+			"p1": {{FileType: models.TXT, FilePath: write("p1.txt", []byte(essay))}},
+		},
+	}
+
+	ss := NewSimilarityService(store, &mockFileStore{})
+
+	_, err := ss.RequestReport("a1", "stu1", nil)
+	if !errors.Is(err, ERR_NOT_PERMITTED) {
+		t.Fatalf("got %v, want %v", err, ERR_NOT_PERMITTED)
+	}
+
+	_, err = ss.RequestReport("a1", "prof", []string{"other"})
+	if !errors.Is(err, ERR_NOT_PERMITTED) {
+		t.Fatalf("got %v, want %v for an offering taught by someone else", err, ERR_NOT_PERMITTED)
+	}
+
+	r, err := ss.RequestReport("a1", "prof", []string{"old"})
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+
+	if r.Status != models.SIMILARITY_PENDING {
+		t.Errorf("got %s, want %s", r.Status, models.SIMILARITY_PENDING)
+	}
+
+	made, err := ss.RunPending(time.Now())
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+
+	if made != 1 {
+		t.Errorf("got %d reports made, want 1", made)
+	}
+
+	if r.Status != models.SIMILARITY_DONE {
+		t.Fatalf("got %s, want %s: %s", r.Status, models.SIMILARITY_DONE, r.Error)
+	}
+
+	if len(store.notifications) != 1 || store.notifications[0].NetId != "prof" {
+		t.Errorf("got %v, want the teacher notified", store.notifications)
+	}
+
+	report, err := ss.Report(r.ID, "prof")
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+
+	want := map[string]bool{"s1-s2": true, "s1-p1": true, "s2-p1": true}
+
+	if len(report.Pairs) != len(want) {
+		t.Fatalf("got %d pairs, want %d", len(report.Pairs), len(want))
+	}
+
+	for _, p := range report.Pairs {
+		if !want[p.SubmissionA+"-"+p.SubmissionB] {
+			t.Errorf("got pair %s-%s, want one of %v", p.SubmissionA, p.SubmissionB, want)
+		}
+
+		if p.Score != 1 || len(p.Matches) == 0 {
+			t.Errorf("got score %v and %d matches, want the whole essay matched", p.Score, len(p.Matches))
+		}
+	}
+
+	// Students are shown under their pseudonyms while grading is blind,
+	// except for those of past offerings.
+	store.assignments["a1"].Anonymous = true
+	store.assignments["a1"].AnonymousKey = "key"
+
+	report, err = ss.Report(r.ID, "prof")
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+
+	for _, p := range report.Pairs {
+		if !models.IsPseudonym(p.NetIdA) {
+			t.Errorf("got %s, want a pseudonym", p.NetIdA)
+		}
+
+		if p.AssignmentB == "old" && models.IsPseudonym(p.NetIdB) {
+			t.Errorf("got %s, want the past student's netid", p.NetIdB)
+		}
+	}
+}
+
+func TestSimilarityService_RunPendingFailed(t *testing.T) {
+	store := newMockSimilarityStore()
+	store.media = map[string]map[string][]*models.Media{
+		"a1": {
+			"s1": {{FileType: models.TXT, FilePath: filepath.Join(t.TempDir(), "gone.txt")}},
+		},
+	}
+
+	ss := NewSimilarityService(store, &mockFileStore{})
+
+	r, err := ss.RequestReport("a1", "prof", nil)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+
+	_, err = ss.RunPending(time.Now())
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+
+	if r.Status != models.SIMILARITY_FAILED || r.Error == "" {
+		t.Errorf("got %s with error %q, want a failed report with why", r.Status, r.Error)
+	}
+
+	if len(store.notifications) != 1 || !strings.Contains(store.notifications[0].Message, "failed") {
+		t.Errorf("got %v, want the teacher told the report failed", store.notifications)
+	}
+}
+
+// ========= //
+//   MOCKS   //
+// ========= //
+
+// mockSimilarityStore has assignment "a1" in course "c1" and "old" in
+// course "c0", both taught by "prof", and "other" in course "c2" taught
+// by someone else. Students "stu1" to "stu3" made submissions "s1" to
+// "s3" to "a1", and "old1" made "p1" to "old". Every submission counts.
+type mockSimilarityStore struct {
+	SimilarityStore
+
+	assignments   map[string]*models.Assignment
+	media         map[string]map[string][]*models.Media
+	reports       []*models.SimilarityReport
+	notifications []*models.Notification
+}
+
+func newMockSimilarityStore() *mockSimilarityStore {
+	m := &mockSimilarityStore{assignments: make(map[string]*models.Assignment)}
+
+	for _, id := range []string{"a1", "old", "other"} {
+		m.assignments[id] = &models.Assignment{
+			Post: models.Post{Entity: models.Entity{ID: id}, Title: id},
+		}
+	}
+
+	return m
+}
+
+func (m *mockSimilarityStore) GetAssignmentById(assignmentid string) (
+	*models.Assignment,
+	error,
+) {
+	a, ok := m.assignments[assignmentid]
+	if !ok {
+		return nil, dal.ERR_RECORD_NOT_FOUND
+	}
+	return a, nil
+}
+
+func (m *mockSimilarityStore) GetCourseIdByAssignment(assignmentId string) (
+	string,
+	error,
+) {
+	return map[string]string{"a1": "c1", "old": "c0", "other": "c2"}[assignmentId], nil
+}
+
+func (m *mockSimilarityStore) IsCourseTeacher(courseId, netId string) (
+	bool,
+	error,
+) {
+	return netId == "prof" && courseId != "c2", nil
+}
+
+func (m *mockSimilarityStore) GetSubmissions(assignmentId string) (
+	[]*models.Submission,
+	error,
+) {
+	authors := map[string]string{"s1": "stu1", "s2": "stu2", "s3": "stu3", "p1": "old1"}
+
+	var ids []string
+	for id := range m.media[assignmentId] {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	var submissions []*models.Submission
+	for _, id := range ids {
+		sub := models.NewSubmission()
+		sub.ID = id
+		sub.User.ID = authors[id]
+		sub.Counts = true
+		submissions = append(submissions, sub)
+	}
+	return submissions, nil
+}
+
+func (m *mockSimilarityStore) GetAssignmentSubmissionMedia(assignmentId string) (
+	map[string][]*models.Media,
+	error,
+) {
+	return m.media[assignmentId], nil
+}
+
+func (m *mockSimilarityStore) InsertSimilarityReport(r *models.SimilarityReport) error {
+	r.ID = fmt.Sprintf("r%d", len(m.reports)+1)
+	m.reports = append(m.reports, r)
+	return nil
+}
+
+func (m *mockSimilarityStore) GetSimilarityReportById(id string) (
+	*models.SimilarityReport,
+	error,
+) {
+	for _, r := range m.reports {
+		if r.ID == id {
+			return r, nil
+		}
+	}
+	return nil, dal.ERR_RECORD_NOT_FOUND
+}
+
+func (m *mockSimilarityStore) GetPendingSimilarityReports() ([]string, error) {
+	var ids []string
+	for _, r := range m.reports {
+		if r.Status == models.SIMILARITY_PENDING {
+			ids = append(ids, r.ID)
+		}
+	}
+	return ids, nil
+}
+
+func (m *mockSimilarityStore) SaveSimilarityReport(r *models.SimilarityReport) error {
+	return nil
+}
+
+func (m *mockSimilarityStore) GetSimilarityPairs(reportId string) (
+	[]*models.SimilarityPair,
+	error,
+) {
+	r, err := m.GetSimilarityReportById(reportId)
+	if err != nil {
+		return nil, err
+	}
+
+	var pairs []*models.SimilarityPair
+	for _, p := range r.Pairs {
+		copied := *p
+		pairs = append(pairs, &copied)
+	}
+	return pairs, nil
+}
+
+func (m *mockSimilarityStore) InsertNotification(n *models.Notification) error {
+	m.notifications = append(m.notifications, n)
+	return nil
+}
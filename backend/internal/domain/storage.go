@@ -1,10 +1,26 @@
 package domain
 
+import (
+	"errors"
+	"time"
+
+	"github.com/n30w/Darkspace/internal/dal"
+	"github.com/n30w/Darkspace/internal/models"
+)
+
 type StorageStore interface {
+	GetStoredMedia() ([]*models.StoredMedia, error)
+	GetCourseMedia(courseId string) ([]*models.Media, error)
+	DeleteMediaByID(id string) (int64, error)
 }
 
 type StorageService struct {
 	store StorageStore
+	files FileStore
+}
+
+func NewStorageService(s StorageStore, f FileStore) *StorageService {
+	return &StorageService{store: s, files: f}
 }
 
 // ReadFile reads a file into memory. It returns a slice
@@ -18,3 +34,127 @@ func (s *StorageService) ReadFile(path string) ([]byte, error) {
 func (s *StorageService) WriteFile(path string, data []byte) error {
 	return nil
 }
+
+// CollectGarbage reconciles the media table with the files in storage.
+// Media records that nothing refers to are deleted, then files that no
+// remaining media record refers to are removed. Anything younger than
+// the grace period is left alone, so that uploads still being recorded
+// are not collected. Live media whose files are missing are reported
+// but kept. When dryRun is set, nothing is deleted.
+func (s *StorageService) CollectGarbage(
+	grace time.Duration,
+	dryRun bool,
+) (*models.GarbageReport, error) {
+	cutoff := time.Now().Add(-grace)
+
+	report := &models.GarbageReport{DryRun: dryRun}
+
+	media, err := s.store.GetStoredMedia()
+	if err != nil {
+		return nil, err
+	}
+
+	// keep holds the paths of every media record that survives this
+	// collection. Several records may share a path, such as a banner
+	// that was replaced by a file of the same name.
+	keep := make(map[string]bool)
+
+	for _, m := range media {
+		if m.Referenced || m.CreatedAt.After(cutoff) {
+			keep[m.FilePath] = true
+			continue
+		}
+
+		report.OrphanedMedia = append(report.OrphanedMedia, m.ID)
+
+		if !dryRun {
+			_, err = s.store.DeleteMediaByID(m.ID)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	for _, m := range media {
+		if !m.Referenced {
+			continue
+		}
+
+		_, err = s.files.StatFile(m.FilePath)
+		if err != nil {
+			if errors.Is(err, dal.ERR_RECORD_NOT_FOUND) {
+				report.DanglingMedia = append(report.DanglingMedia, &m.Media)
+				continue
+			}
+			return nil, err
+		}
+	}
+
+	blobs, err := s.files.ListFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, blob := range blobs {
+		if keep[blob.Path] || blob.ModTime.After(cutoff) {
+			continue
+		}
+
+		report.RemovedBlobs = append(report.RemovedBlobs, blob.Path)
+		report.FreedBytes += blob.Size
+
+		if !dryRun {
+			err = s.files.RemoveFile(blob.Path)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return report, nil
+}
+
+// CourseUsage computes how much storage a course uses, including its
+// banners and the media of its assignments and submissions. Files
+// that are missing from storage are not counted.
+func (s *StorageService) CourseUsage(courseId string) (
+	*models.StorageUsage,
+	error,
+) {
+	media, err := s.store.GetCourseMedia(courseId)
+	if err != nil {
+		return nil, err
+	}
+
+	usage := &models.StorageUsage{
+		CourseId: courseId,
+		ByKind:   make(map[string]int64),
+	}
+
+	counted := make(map[string]bool)
+
+	for _, m := range media {
+		if counted[m.FilePath] {
+			continue
+		}
+
+		counted[m.FilePath] = true
+
+		blob, err := s.files.StatFile(m.FilePath)
+		if err != nil {
+			if errors.Is(err, dal.ERR_RECORD_NOT_FOUND) {
+				continue
+			}
+			return nil, err
+		}
+
+		usage.Files++
+		usage.Bytes += blob.Size
+
+		for kind := range m.AttributionsByType {
+			usage.ByKind[kind] += blob.Size
+		}
+	}
+
+	return usage, nil
+}
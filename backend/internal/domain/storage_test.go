@@ -0,0 +1,238 @@
+package domain
+
+import (
+	"io"
+	"os"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/n30w/Darkspace/internal/dal"
+	"github.com/n30w/Darkspace/internal/models"
+)
+
+func TestStorageService_CollectGarbage(t *testing.T) {
+	old := time.Now().Add(-48 * time.Hour)
+	recent := time.Now()
+
+	store := &mockStorageStore{
+		media: []*models.StoredMedia{
+			// A live banner.
+			newStoredMedia("banner", "/v/c1_banner.png", old, true),
+			// A replaced banner sharing the live banner's path.
+			newStoredMedia("replaced", "/v/c1_banner.png", old, false),
+			// Media of a deleted course.
+			newStoredMedia("deleted", "/v/c2_banner.png", old, false),
+			// An upload that has not been linked yet.
+			newStoredMedia("pending", "/v/pending.pdf", recent, false),
+			// Live media whose file is gone.
+			newStoredMedia("dangling", "/v/missing.pdf", old, true),
+		},
+	}
+
+	files := &mockFileStore{
+		blobs: map[string]models.Blob{
+			"/v/c1_banner.png": {Path: "/v/c1_banner.png", Size: 10, ModTime: old},
+			"/v/c2_banner.png": {Path: "/v/c2_banner.png", Size: 20, ModTime: old},
+			"/v/pending.pdf":   {Path: "/v/pending.pdf", Size: 30, ModTime: recent},
+			"/v/stray.txt":     {Path: "/v/stray.txt", Size: 40, ModTime: old},
+			"/v/new.txt":       {Path: "/v/new.txt", Size: 50, ModTime: recent},
+		},
+	}
+
+	ss := NewStorageService(store, files)
+
+	report, err := ss.CollectGarbage(24*time.Hour, false)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+
+	sort.Strings(report.OrphanedMedia)
+	sort.Strings(report.RemovedBlobs)
+
+	assertStrings(t, report.OrphanedMedia, []string{"deleted", "replaced"})
+	assertStrings(t, report.RemovedBlobs, []string{"/v/c2_banner.png", "/v/stray.txt"})
+	assertStrings(t, store.deleted, []string{"replaced", "deleted"})
+
+	if report.FreedBytes != 60 {
+		t.Errorf("got %d freed bytes, want 60", report.FreedBytes)
+	}
+
+	if len(report.DanglingMedia) != 1 || report.DanglingMedia[0].ID != "dangling" {
+		t.Errorf("got dangling media %v, want [dangling]", report.DanglingMedia)
+	}
+
+	for _, p := range []string{"/v/c1_banner.png", "/v/pending.pdf", "/v/new.txt"} {
+		if _, ok := files.blobs[p]; !ok {
+			t.Errorf("%s should not have been removed", p)
+		}
+	}
+}
+
+func TestStorageService_CollectGarbageDryRun(t *testing.T) {
+	old := time.Now().Add(-48 * time.Hour)
+
+	store := &mockStorageStore{
+		media: []*models.StoredMedia{
+			newStoredMedia("deleted", "/v/c2_banner.png", old, false),
+		},
+	}
+
+	files := &mockFileStore{
+		blobs: map[string]models.Blob{
+			"/v/c2_banner.png": {Path: "/v/c2_banner.png", Size: 20, ModTime: old},
+		},
+	}
+
+	report, err := NewStorageService(store, files).CollectGarbage(time.Hour, true)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+
+	if len(report.RemovedBlobs) != 1 || len(report.OrphanedMedia) != 1 {
+		t.Errorf("got %+v, want one orphan and one removed blob", report)
+	}
+
+	if len(store.deleted) != 0 || len(files.blobs) != 1 {
+		t.Errorf("dry run deleted data")
+	}
+}
+
+func TestStorageService_CourseUsage(t *testing.T) {
+	store := &mockStorageStore{
+		course: []*models.Media{
+			newAttributedMedia("/v/banner.png", "course"),
+			newAttributedMedia("/v/banner_card.png", "course"),
+			newAttributedMedia("/v/handout.pdf", "assignment"),
+			newAttributedMedia("/v/essay.pdf", "submission"),
+			newAttributedMedia("/v/missing.pdf", "submission"),
+		},
+	}
+
+	files := &mockFileStore{
+		blobs: map[string]models.Blob{
+			"/v/banner.png":      {Size: 100},
+			"/v/banner_card.png": {Size: 10},
+			"/v/handout.pdf":     {Size: 200},
+			"/v/essay.pdf":       {Size: 300},
+		},
+	}
+
+	usage, err := NewStorageService(store, files).CourseUsage("c1")
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+
+	if usage.Files != 4 || usage.Bytes != 610 {
+		t.Errorf("got %d files and %d bytes, want 4 and 610", usage.Files, usage.Bytes)
+	}
+
+	want := map[string]int64{"course": 110, "assignment": 200, "submission": 300}
+	for kind, bytes := range want {
+		if usage.ByKind[kind] != bytes {
+			t.Errorf("%s: got %d, want %d", kind, usage.ByKind[kind], bytes)
+		}
+	}
+}
+
+func assertStrings(t *testing.T, got, want []string) {
+	t.Helper()
+
+	if len(got) != len(want) {
+		t.Errorf("got %v, want %v", got, want)
+		return
+	}
+
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("got %v, want %v", got, want)
+			return
+		}
+	}
+}
+
+// ========= //
+//   MOCKS   //
+// ========= //
+
+func newStoredMedia(
+	id, path string,
+	created time.Time,
+	referenced bool,
+) *models.StoredMedia {
+	m := &models.StoredMedia{Referenced: referenced}
+	m.ID = id
+	m.FilePath = path
+	m.CreatedAt = created
+	return m
+}
+
+func newAttributedMedia(path, kind string) *models.Media {
+	return &models.Media{
+		FilePath:           path,
+		AttributionsByType: map[string]string{kind: "id"},
+	}
+}
+
+type mockStorageStore struct {
+	media   []*models.StoredMedia
+	course  []*models.Media
+	deleted []string
+}
+
+func (m *mockStorageStore) GetStoredMedia() ([]*models.StoredMedia, error) {
+	return m.media, nil
+}
+
+func (m *mockStorageStore) GetCourseMedia(courseId string) (
+	[]*models.Media,
+	error,
+) {
+	return m.course, nil
+}
+
+func (m *mockStorageStore) DeleteMediaByID(id string) (int64, error) {
+	m.deleted = append(m.deleted, id)
+	return 1, nil
+}
+
+type mockFileStore struct {
+	blobs map[string]models.Blob
+}
+
+func (m *mockFileStore) CreateFile(path string) (*os.File, string, error) {
+	return nil, "", nil
+}
+
+func (m *mockFileStore) CopyFile(f1 io.Writer, f2 io.Reader) error {
+	return nil
+}
+
+func (m *mockFileStore) OpenFile(path string) (io.ReadSeekCloser, error) {
+	return os.Open(path)
+}
+
+func (m *mockFileStore) ListFiles() ([]models.Blob, error) {
+	var blobs []models.Blob
+	for _, blob := range m.blobs {
+		blobs = append(blobs, blob)
+	}
+	return blobs, nil
+}
+
+func (m *mockFileStore) StatFile(path string) (*models.Blob, error) {
+	blob, ok := m.blobs[path]
+	if !ok {
+		return nil, dal.ERR_RECORD_NOT_FOUND
+	}
+	return &blob, nil
+}
+
+func (m *mockFileStore) RemoveFile(path string) error {
+	delete(m.blobs, path)
+	return nil
+}
+
+func (m *mockFileStore) String() string {
+	return "/v"
+}
@@ -1,73 +1,120 @@
 package domain
 
 import (
-	"fmt"
+	"errors"
+	"time"
 
+	"github.com/n30w/Darkspace/internal/dal"
 	"github.com/n30w/Darkspace/internal/models"
 )
 
 type SubmissionStore interface {
+	DueDateStore
+	AttemptStore
+	RegradeStore
+	NotificationStore
+
 	GetSubmissions(assignmentId string) ([]*models.Submission, error)
 	GetSubmissionById(submissionId string) (*models.Submission, error)
 	GetSubmissionMedia(submission *models.Submission) (*models.Submission, error)
 	GetSubmissionIdByUserAndAssignment(netId string, assignmentId string) (string, error)
-	InsertSubmission(sub *models.Submission) (
+	GetAssignmentIdBySubmission(submissionId string) (string, error)
+	GetAssignmentById(assignmentid string) (*models.Assignment, error)
+	InsertAttempt(sub *models.Submission, maxAttempts int) (
 		*models.Submission,
 		error,
 	)
-	InsertSubmissionIntoAssignment(sub *models.Submission) (*models.Submission, error)
-	InsertSubmissionIntoUser(sub *models.Submission) (*models.Submission, error)
 	UpdateSubmission(submission *models.Submission) (*models.Submission, error)
 	DeleteSubmissionByID(id string) error
+	GetRubricScores(submissionId string) ([]*models.CriterionScore, error)
+	GetAssignmentRubricScores(assignmentId string) (map[string][]*models.CriterionScore, error)
+	GetSubmissionFiles(submissionId string) ([]*models.Media, error)
+	SetPinnedAttempt(assignmentId, netId, submissionId string) error
+	IsCourseTeacher(courseId, netId string) (bool, error)
 }
 
 type SubmissionService struct {
 	store SubmissionStore
+
+	// now is the clock that submissions are timed by.
+	now func() time.Time
 }
 
 func NewSubmissionService(s SubmissionStore) *SubmissionService {
-	return &SubmissionService{store: s}
+	return &SubmissionService{store: s, now: time.Now}
 }
 
+// CreateSubmission makes a new attempt at an assignment, timed now
+// against the student's own due date. Earlier attempts are kept.
+// ERR_NOT_AVAILABLE is returned before the assignment takes
+// submissions, ERR_SUBMISSION_CLOSED when it is locked or its late
+// policy no longer accepts submissions, and ERR_NO_ATTEMPTS_LEFT when
+// the student has used all of their attempts.
 func (ss *SubmissionService) CreateSubmission(s *models.Submission) (
 	*models.Submission,
 	error,
 ) {
-	fmt.Printf("Inserting into submissions table...\n")
-	// Insert submission into submission table
-	s, err := ss.store.InsertSubmission(s)
+	assignment, err := ss.store.GetAssignmentById(s.AssignmentId)
+	if err != nil {
+		return nil, err
+	}
+
+	assignment, err = studentAssignment(ss.store, assignment, s.User.ID)
 	if err != nil {
 		return nil, err
 	}
-	fmt.Printf("Inserting into assignment_submissions table...\n")
 
-	// Insert submission into assignment_submissions table
-	s, err = ss.store.InsertSubmissionIntoAssignment(s)
+	err = stampSubmission(s, assignment, ss.now())
 	if err != nil {
 		return nil, err
 	}
-	fmt.Printf("Inserting into user_submissions table...\n")
 
-	// Insert submission into user_submissions table
-	s, err = ss.store.InsertSubmissionIntoUser(s)
+	// Attempts are counted as the submission is inserted, so that two
+	// made at once cannot both take the last one.
+	s, err = ss.store.InsertAttempt(s, assignment.MaxAttempts)
+	if errors.Is(err, dal.ERR_ATTEMPT_LIMIT) {
+		return nil, ERR_NO_ATTEMPTS_LEFT
+	}
 	if err != nil {
 		return nil, err
 	}
+
+	err = recountAttempts(ss.store, assignment, s.User.ID)
+	if err != nil {
+		return nil, err
+	}
+
 	return s, nil
 }
 
+// GradeSubmission grades a submission, taking off its assignment's
+// late penalty.
 func (ss *SubmissionService) GradeSubmission(grade int, feedback string, submissionid string) (*models.Submission, error) {
 	submission, err := ss.store.GetSubmissionById(submissionid)
 	if err != nil {
 		return nil, err
 	}
-	submission.Grade = float64(grade)
+
+	assignment, err := ss.assignment(submission)
+	if err != nil {
+		return nil, err
+	}
+
+	submission.SetGrade(float64(grade), assignment)
 	submission.Feedback = feedback
 
 	_, err = ss.store.UpdateSubmission(submission)
 	if err != nil {
 		return nil, err
 	}
+
+	err = recountAttempts(ss.store, assignment, submission.User.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	anonymize(assignment, submission)
+
 	return submission, nil
 }
 
@@ -98,6 +145,19 @@ func (ss *SubmissionService) UpdateSubmission(id string) (
 	return nil, nil
 }
 
+// GetAssignmentId retrieves the ID of the assignment a submission
+// was made for.
+func (ss *SubmissionService) GetAssignmentId(submissionId string) (
+	string,
+	error,
+) {
+	assignmentId, err := ss.store.GetAssignmentIdBySubmission(submissionId)
+	if err != nil {
+		return "", err
+	}
+	return assignmentId, nil
+}
+
 // GetUserSubmission retrieves the submission by a user for an assignment given
 // a netId and assignmentId
 func (ss *SubmissionService) GetUserSubmission(userId string, assignmentId string) (
@@ -116,48 +176,76 @@ func (ss *SubmissionService) GetUserSubmission(userId string, assignmentId strin
 	if err != nil {
 		return nil, err
 	}
+	submission.Rubric, err = ss.store.GetRubricScores(submission.ID)
+	if err != nil {
+		return nil, err
+	}
 	return submission, nil
 }
 
 // GetSubmissions retrieves the submissions for a specific course given
 // a Course ID and Assignment ID. It returns a slice of submissions
-// for the given assignment.
+// for the given assignment, along with their rubric breakdowns.
+// Submissions are under pseudonyms while the assignment is graded
+// blind.
 func (ss *SubmissionService) GetSubmissions(assignmentId string) (
 	[]*models.Submission,
 	error,
 ) {
+	assignment, err := ss.store.GetAssignmentById(assignmentId)
+	if err != nil {
+		return nil, err
+	}
+
 	// Get all submissions using assignmentId.
 	submissions, err := ss.store.GetSubmissions(assignmentId)
 	if err != nil {
 		return nil, err
 	}
 
+	anonymize(assignment, submissions...)
+
+	scores, err := ss.store.GetAssignmentRubricScores(assignmentId)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, submission := range submissions {
+		submission.Rubric = scores[submission.ID]
+	}
+
 	return submissions, nil
 }
 
 // UpdateSubmissions updates submissions from a slice of
 // submissions. This is used for updating submission entries
-// in the database from an Excel file.
+// in the database from an Excel file. The grades in the file
+// are raw grades, so late penalties are taken off them.
 func (ss *SubmissionService) UpdateSubmissions(
 	submissions []models.Submission,
 ) error {
-	var toDelete []*models.Submission
-
 	// You can technically do this in one go, but not sure
 	// how to write that query...
 	for _, submission := range submissions {
-		s, err := ss.store.UpdateSubmission(&submission)
+		s, err := ss.store.GetSubmissionById(submission.ID)
 		if err != nil {
 			return err
 		}
 
-		toDelete = append(toDelete, s)
-	}
+		assignment, err := ss.assignment(s)
+		if err != nil {
+			return err
+		}
+
+		s.SetGrade(submission.Grade, assignment)
+		s.Feedback = submission.Feedback
 
-	// Since the update creates new rows, delete the old
-	// rows in the database.
-	for _, submission := range toDelete {
-		err := ss.store.DeleteSubmissionByID(submission.ID)
+		_, err = ss.store.UpdateSubmission(s)
+		if err != nil {
+			return err
+		}
+
+		err = recountAttempts(ss.store, assignment, s.User.ID)
 		if err != nil {
 			return err
 		}
@@ -165,3 +253,45 @@ func (ss *SubmissionService) UpdateSubmissions(
 
 	return nil
 }
+
+// assignment retrieves the assignment a submission was made for, as
+// it applies to the student who made it.
+func (ss *SubmissionService) assignment(sub *models.Submission) (
+	*models.Assignment,
+	error,
+) {
+	assignmentId, err := ss.store.GetAssignmentIdBySubmission(sub.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	a, err := ss.store.GetAssignmentById(assignmentId)
+	if err != nil {
+		return nil, err
+	}
+
+	return studentAssignment(ss.store, a, sub.User.ID)
+}
+
+// stampSubmission times a submission made at a time, and marks if it
+// is on time. Times are kept in UTC, which is how they are stored.
+// Submissions are only made while the assignment is available and
+// before it is locked, whether or not the scheduler has caught up.
+func stampSubmission(
+	sub *models.Submission,
+	a *models.Assignment,
+	at time.Time,
+) error {
+	if !a.Opened(at) {
+		return ERR_NOT_AVAILABLE
+	}
+
+	if a.Locked(at) || !a.Accepts(at) {
+		return ERR_SUBMISSION_CLOSED
+	}
+
+	sub.SubmissionTime = at.UTC()
+	sub.OnTime = !a.IsLate(at)
+
+	return nil
+}
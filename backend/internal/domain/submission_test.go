@@ -0,0 +1,823 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/n30w/Darkspace/internal/dal"
+	"github.com/n30w/Darkspace/internal/models"
+)
+
+func TestSubmissionService_CreateSubmission(t *testing.T) {
+	due := time.Date(2024, 5, 1, 23, 59, 59, 0, time.UTC)
+	opens := due.Add(-48 * time.Hour)
+	locks := due.Add(24 * time.Hour)
+
+	tests := []struct {
+		name     string
+		policy   *models.LatePolicy
+		schedule models.Schedule
+		at       time.Time
+		onTime   bool
+		want     error
+	}{
+		{
+			name:   "before the due date",
+			at:     due.Add(-time.Hour),
+			onTime: true,
+		},
+		{
+			name:   "late without a policy",
+			at:     due.Add(72 * time.Hour),
+			onTime: false,
+		},
+		{
+			name:   "within the grace period",
+			policy: &models.LatePolicy{GracePeriod: 600, HardCutoff: true},
+			at:     due.Add(5 * time.Minute),
+			onTime: true,
+		},
+		{
+			name:   "after a hard cutoff",
+			policy: &models.LatePolicy{GracePeriod: 600, HardCutoff: true},
+			at:     due.Add(11 * time.Minute),
+			want:   ERR_SUBMISSION_CLOSED,
+		},
+		{
+			name:   "within the maximum lateness",
+			policy: &models.LatePolicy{MaxLateness: 86400},
+			at:     due.Add(23 * time.Hour),
+			onTime: false,
+		},
+		{
+			name:   "past the maximum lateness",
+			policy: &models.LatePolicy{MaxLateness: 86400},
+			at:     due.Add(25 * time.Hour),
+			want:   ERR_SUBMISSION_CLOSED,
+		},
+		{
+			name:     "draft",
+			schedule: models.Schedule{State: models.ASSIGNMENT_DRAFT},
+			at:       due.Add(-time.Hour),
+			want:     ERR_NOT_AVAILABLE,
+		},
+		{
+			name:     "before it is available",
+			schedule: models.Schedule{State: models.ASSIGNMENT_PUBLISHED, AvailableFrom: &opens},
+			at:       opens.Add(-time.Second),
+			want:     ERR_NOT_AVAILABLE,
+		},
+		{
+			name:     "once it is available",
+			schedule: models.Schedule{State: models.ASSIGNMENT_PUBLISHED, AvailableFrom: &opens},
+			at:       opens,
+			onTime:   true,
+		},
+		{
+			name:     "late before the lock date",
+			schedule: models.Schedule{State: models.ASSIGNMENT_PUBLISHED, LockAt: &locks},
+			at:       locks.Add(-time.Second),
+			onTime:   false,
+		},
+		{
+			name:     "at the lock date before it is locked",
+			schedule: models.Schedule{State: models.ASSIGNMENT_PUBLISHED, LockAt: &locks},
+			at:       locks,
+			want:     ERR_SUBMISSION_CLOSED,
+		},
+		{
+			name:     "locked",
+			schedule: models.Schedule{State: models.ASSIGNMENT_LOCKED},
+			at:       due.Add(-time.Hour),
+			want:     ERR_SUBMISSION_CLOSED,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				store := newMockSubmissionStore(due, tt.policy)
+				store.assignment.Schedule = tt.schedule
+				ss := NewSubmissionService(store)
+
+				// Submitted from New York, which must not change the
+				// outcome.
+				ny, _ := time.LoadLocation("America/New_York")
+				ss.now = func() time.Time { return tt.at.In(ny) }
+
+				sub := &models.Submission{AssignmentId: "a1"}
+				sub.User.ID = "stu1"
+
+				sub, err := ss.CreateSubmission(sub)
+				if !errors.Is(err, tt.want) {
+					t.Fatalf("got %v, want %v", err, tt.want)
+				}
+
+				if tt.want != nil {
+					if len(store.submissions) != 0 {
+						t.Errorf("got %d submissions, want none", len(store.submissions))
+					}
+					return
+				}
+
+				if sub.OnTime != tt.onTime {
+					t.Errorf("got on time %v, want %v", sub.OnTime, tt.onTime)
+				}
+
+				if sub.SubmissionTime.Location() != time.UTC || !sub.SubmissionTime.Equal(tt.at) {
+					t.Errorf("got submission time %v, want %v in UTC", sub.SubmissionTime, tt.at)
+				}
+			},
+		)
+	}
+}
+
+func TestSubmissionService_GradeSubmission(t *testing.T) {
+	due := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	policy := &models.LatePolicy{Deduction: 10, Interval: models.PER_DAY}
+
+	store := newMockSubmissionStore(due, policy)
+	store.submissions["s1"] = &models.Submission{
+		Entity:         models.Entity{ID: "s1"},
+		SubmissionTime: due.Add(30 * time.Hour),
+	}
+
+	sub, err := NewSubmissionService(store).GradeSubmission(80, "late", "s1")
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+
+	// A day and a bit late is two days of deductions.
+	if sub.RawGrade != 80 || sub.LatePenalty != 20 || sub.Grade != 64 {
+		t.Errorf(
+			"got raw %v, penalty %v, grade %v, want 80, 20, 64",
+			sub.RawGrade,
+			sub.LatePenalty,
+			sub.Grade,
+		)
+	}
+}
+
+func TestSubmissionService_UpdateSubmissions(t *testing.T) {
+	due := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	policy := &models.LatePolicy{Deduction: 5, Interval: models.PER_HOUR}
+
+	store := newMockSubmissionStore(due, policy)
+	store.submissions["s1"] = &models.Submission{
+		Entity:         models.Entity{ID: "s1"},
+		SubmissionTime: due.Add(-time.Hour),
+	}
+	store.submissions["s2"] = &models.Submission{
+		Entity:         models.Entity{ID: "s2"},
+		SubmissionTime: due.Add(90 * time.Minute),
+	}
+
+	// The grades read from a spreadsheet are raw grades.
+	err := NewSubmissionService(store).UpdateSubmissions(
+		[]models.Submission{
+			{Entity: models.Entity{ID: "s1"}, Grade: 90, Feedback: "good"},
+			{Entity: models.Entity{ID: "s2"}, Grade: 90, Feedback: "late"},
+		},
+	)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+
+	tests := []struct {
+		id    string
+		grade float64
+	}{
+		{id: "s1", grade: 90},
+		{id: "s2", grade: 81},
+	}
+
+	for _, tt := range tests {
+		sub, ok := store.submissions[tt.id]
+		if !ok {
+			t.Errorf("%s: submission was deleted", tt.id)
+			continue
+		}
+
+		if sub.RawGrade != 90 || sub.Grade != tt.grade {
+			t.Errorf("%s: got raw %v, grade %v, want 90, %v", tt.id, sub.RawGrade, sub.Grade, tt.grade)
+		}
+	}
+}
+
+func TestSubmissionService_DueDateChanges(t *testing.T) {
+	due := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	policy := &models.LatePolicy{
+		Deduction:   10,
+		Interval:    models.PER_DAY,
+		MaxLateness: 86400,
+	}
+
+	tests := []struct {
+		name    string
+		netId   string
+		late    time.Duration
+		onTime  bool
+		penalty float64
+		want    error
+	}{
+		{name: "no changes", netId: "stu1", late: time.Hour, penalty: 10},
+		{name: "no changes past max lateness", netId: "stu1", late: 30 * time.Hour, want: ERR_SUBMISSION_CLOSED},
+		{name: "accommodation", netId: "stu2", late: 47 * time.Hour, onTime: true},
+		{name: "late after accommodation", netId: "stu2", late: 49 * time.Hour, penalty: 10},
+		{name: "extension", netId: "stu3", late: 71 * time.Hour, onTime: true},
+		{name: "extension replaces accommodation", netId: "stu4", late: 30 * time.Minute, penalty: 10},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				store := newMockSubmissionStore(due, policy)
+				store.accommodations = map[string]*models.Accommodation{
+					"stu2": {NetId: "stu2", ExtraTime: 48 * 3600},
+					"stu4": {NetId: "stu4", ExtraTime: 48 * 3600},
+				}
+				store.extensions = map[string]*models.Extension{
+					"stu3": {NetId: "stu3", DueDate: due.Add(72 * time.Hour)},
+					"stu4": {NetId: "stu4", DueDate: due},
+				}
+
+				ss := NewSubmissionService(store)
+				ss.now = func() time.Time { return due.Add(tt.late) }
+
+				sub := &models.Submission{AssignmentId: "a1"}
+				sub.User.ID = tt.netId
+
+				sub, err := ss.CreateSubmission(sub)
+				if !errors.Is(err, tt.want) {
+					t.Fatalf("got %v, want %v", err, tt.want)
+				}
+
+				if tt.want != nil {
+					return
+				}
+
+				if sub.OnTime != tt.onTime {
+					t.Errorf("got on time %v, want %v", sub.OnTime, tt.onTime)
+				}
+
+				sub, err = ss.GradeSubmission(100, "", sub.ID)
+				if err != nil {
+					t.Fatalf("%+v", err)
+				}
+
+				if sub.LatePenalty != tt.penalty {
+					t.Errorf("got penalty %v, want %v", sub.LatePenalty, tt.penalty)
+				}
+			},
+		)
+	}
+}
+
+func TestSubmissionService_Attempts(t *testing.T) {
+	due := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	store := newMockSubmissionStore(due, nil)
+	store.assignment.MaxAttempts = 3
+	store.assignment.CountedAttempt = models.BEST_ATTEMPT
+
+	ss := NewSubmissionService(store)
+	ss.now = func() time.Time { return due.Add(-time.Hour) }
+
+	grades := []int{70, 90, 80}
+
+	for i, grade := range grades {
+		sub := &models.Submission{AssignmentId: "a1"}
+		sub.User.ID = "stu1"
+
+		sub, err := ss.CreateSubmission(sub)
+		if err != nil {
+			t.Fatalf("attempt %d: %+v", i+1, err)
+		}
+
+		if sub.Attempt != i+1 {
+			t.Errorf("got attempt %d, want %d", sub.Attempt, i+1)
+		}
+
+		_, err = ss.GradeSubmission(grade, "", sub.ID)
+		if err != nil {
+			t.Fatalf("%+v", err)
+		}
+	}
+
+	sub := &models.Submission{AssignmentId: "a1"}
+	sub.User.ID = "stu1"
+
+	_, err := ss.CreateSubmission(sub)
+	if !errors.Is(err, ERR_NO_ATTEMPTS_LEFT) {
+		t.Fatalf("got %v, want %v", err, ERR_NO_ATTEMPTS_LEFT)
+	}
+
+	counted := func() int {
+		for _, sub := range store.submissions {
+			if sub.Counts {
+				return sub.Attempt
+			}
+		}
+		return 0
+	}
+
+	if got := counted(); got != 2 {
+		t.Errorf("got attempt %d counted, want the best, 2", got)
+	}
+
+	tests := []struct {
+		name    string
+		netId   string
+		attempt int
+		counted int
+		want    error
+	}{
+		{name: "student cannot choose", netId: "stu1", attempt: 3, counted: 2, want: ERR_NOT_PERMITTED},
+		{name: "pin an attempt", netId: "prof", attempt: 3, counted: 3},
+		{name: "unknown attempt", netId: "prof", attempt: 4, counted: 3, want: ERR_UNKNOWN_ATTEMPT},
+		{name: "remove the pin", netId: "prof", attempt: 0, counted: 2},
+	}
+
+	for _, tt := range tests {
+		_, err := ss.SelectAttempt("a1", "stu1", tt.netId, tt.attempt)
+		if !errors.Is(err, tt.want) {
+			t.Fatalf("%s: got %v, want %v", tt.name, err, tt.want)
+		}
+
+		if got := counted(); got != tt.counted {
+			t.Errorf("%s: got attempt %d counted, want %d", tt.name, got, tt.counted)
+		}
+	}
+
+	_, err = ss.ListAttempts("a1", "stu1", "stu2")
+	if !errors.Is(err, ERR_NOT_PERMITTED) {
+		t.Errorf("got %v, want %v for another student", err, ERR_NOT_PERMITTED)
+	}
+
+	// Students only see how their grade changed once it is released.
+	store.assignment.GradesReleased = true
+
+	diff, err := ss.DiffAttempts("a1", "stu1", "stu1", 1, 2)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+
+	if diff.GradeChange == nil || *diff.GradeChange != 20 {
+		t.Errorf("got grade change %v, want 20", diff.GradeChange)
+	}
+}
+
+func TestSubmissionService_Blind(t *testing.T) {
+	due := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	store := newMockSubmissionStore(due, nil)
+	store.assignment.Anonymous = true
+	store.assignment.AnonymousKey = "key"
+
+	ss := NewSubmissionService(store)
+	ss.now = func() time.Time { return due }
+
+	sub := &models.Submission{AssignmentId: "a1"}
+	sub.User.ID = "stu1"
+
+	_, err := ss.CreateSubmission(sub)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+
+	pseudonym := models.Pseudonym("key", "stu1")
+
+	tests := []struct {
+		name      string
+		studentId string
+		netId     string
+		want      error
+		user      string
+	}{
+		{name: "teacher by Net ID", studentId: "stu1", netId: "prof", want: ERR_NOT_PERMITTED},
+		{name: "student sees their own", studentId: "stu1", netId: "stu1", user: "stu1"},
+		{name: "teacher by pseudonym", studentId: pseudonym, netId: "prof", user: pseudonym},
+	}
+
+	for _, tt := range tests {
+		attempts, err := ss.ListAttempts("a1", tt.studentId, tt.netId)
+		if !errors.Is(err, tt.want) {
+			t.Fatalf("%s: got %v, want %v", tt.name, err, tt.want)
+		}
+
+		if tt.want != nil {
+			continue
+		}
+
+		if len(attempts) != 1 || attempts[0].User.ID != tt.user {
+			t.Errorf("%s: got %v, want one attempt by %s", tt.name, attempts, tt.user)
+		}
+	}
+}
+
+func TestSubmissionService_DraftGrades(t *testing.T) {
+	due := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	store := newMockSubmissionStore(due, nil)
+
+	ss := NewSubmissionService(store)
+	ss.now = func() time.Time { return due }
+
+	sub := &models.Submission{AssignmentId: "a1"}
+	sub.User.ID = "stu1"
+
+	sub, err := ss.CreateSubmission(sub)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+
+	_, err = ss.GradeSubmission(80, "good work", sub.ID)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+
+	// The student's view is checked last, since hiding the grade
+	// changes the stored submission.
+	tests := []struct {
+		name     string
+		netId    string
+		grade    float64
+		feedback string
+	}{
+		{name: "teacher sees the draft", netId: "prof", grade: 80, feedback: "good work"},
+		{name: "student waits for release", netId: "stu1"},
+	}
+
+	for _, tt := range tests {
+		attempts, err := ss.ListAttempts("a1", "stu1", tt.netId)
+		if err != nil {
+			t.Fatalf("%s: %+v", tt.name, err)
+		}
+
+		got := attempts[0]
+		if got.Grade != tt.grade || got.Feedback != tt.feedback {
+			t.Errorf(
+				"%s: got grade %v and feedback %q, want %v and %q",
+				tt.name,
+				got.Grade,
+				got.Feedback,
+				tt.grade,
+				tt.feedback,
+			)
+		}
+	}
+}
+
+func TestSubmissionService_Regrades(t *testing.T) {
+	due := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	store := newMockSubmissionStore(due, nil)
+	store.assignment.RegradeWindow = 24 * 60 * 60
+
+	ss := NewSubmissionService(store)
+	ss.now = func() time.Time { return due }
+
+	sub := &models.Submission{AssignmentId: "a1"}
+	sub.User.ID = "stu1"
+
+	sub, err := ss.CreateSubmission(sub)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+
+	_, err = ss.GradeSubmission(70, "", sub.ID)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+
+	request := func(netId string) func() error {
+		return func() error {
+			_, err := ss.RequestRegrade(sub.ID, netId, "question 2 was right")
+			return err
+		}
+	}
+
+	tests := []struct {
+		name   string
+		action func() error
+		want   error
+	}{
+		{name: "before release", action: request("stu1"), want: ERR_REGRADE_CLOSED},
+		{
+			name: "release grades",
+			action: func() error {
+				store.assignment.GradesReleased = true
+				store.assignment.ReleaseAt = &due
+				return nil
+			},
+		},
+		{name: "someone else's submission", action: request("stu2"), want: ERR_NOT_PERMITTED},
+		{name: "request", action: request("stu1")},
+		{name: "request again", action: request("stu1"), want: ERR_REGRADE_PENDING},
+		{
+			name: "escalate to a student",
+			action: func() error {
+				_, err := ss.EscalateRegrade("r1", "prof", "stu1", "")
+				return err
+			},
+			want: ERR_UNKNOWN_TEACHER,
+		},
+		{
+			name: "escalate",
+			action: func() error {
+				_, err := ss.EscalateRegrade("r1", "prof", "ta", "you graded it")
+				return err
+			},
+		},
+		{
+			name: "decide after escalating",
+			action: func() error {
+				_, err := ss.AcceptRegrade("r1", "prof", 85, "")
+				return err
+			},
+			want: ERR_NOT_PERMITTED,
+		},
+		{
+			name: "accept",
+			action: func() error {
+				_, err := ss.AcceptRegrade("r1", "ta", 85, "fair point")
+				return err
+			},
+		},
+		{
+			name: "reject once accepted",
+			action: func() error {
+				_, err := ss.RejectRegrade("r1", "ta", "changed my mind")
+				return err
+			},
+			want: ERR_REGRADE_RESOLVED,
+		},
+		{
+			name: "after the deadline",
+			action: func() error {
+				ss.now = func() time.Time { return due.Add(48 * time.Hour) }
+				return request("stu1")()
+			},
+			want: ERR_REGRADE_CLOSED,
+		},
+	}
+
+	for _, tt := range tests {
+		err := tt.action()
+		if !errors.Is(err, tt.want) {
+			t.Fatalf("%s: got %v, want %v", tt.name, err, tt.want)
+		}
+	}
+
+	if got := store.submissions[sub.ID].Grade; got != 85 {
+		t.Errorf("got grade %v, want 85", got)
+	}
+
+	regrades, err := ss.ListRegrades("a1", "stu1")
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+
+	if len(regrades) != 1 || len(regrades[0].History) != 3 {
+		t.Fatalf("got %v, want one request with three events", regrades)
+	}
+
+	r := regrades[0]
+	if r.State != models.REGRADE_ACCEPTED || *r.NewGrade != 85 || r.ResolvedBy != "ta" {
+		t.Errorf("got %s to %v by %s, want accepted to 85 by ta", r.State, *r.NewGrade, r.ResolvedBy)
+	}
+
+	if len(store.notifications) != 2 {
+		t.Errorf("got %d notifications, want the escalation and the decision", len(store.notifications))
+	}
+}
+
+// ========= //
+//   MOCKS   //
+// ========= //
+
+// mockSubmissionStore has a single assignment "a1" with a due date and
+// late policy, in course "c1" taught by "prof" and "ta". Every
+// submission belongs to it.
+type mockSubmissionStore struct {
+	SubmissionStore
+
+	assignment  *models.Assignment
+	submissions map[string]*models.Submission
+
+	// extensions and accommodations are keyed by NetID.
+	extensions     map[string]*models.Extension
+	accommodations map[string]*models.Accommodation
+
+	regrades      []*models.RegradeRequest
+	events        []*models.RegradeEvent
+	notifications []*models.Notification
+}
+
+func newMockSubmissionStore(
+	due time.Time,
+	policy *models.LatePolicy,
+) *mockSubmissionStore {
+	return &mockSubmissionStore{
+		assignment: &models.Assignment{
+			Post:       models.Post{Entity: models.Entity{ID: "a1"}},
+			DueDate:    due,
+			LatePolicy: policy,
+		},
+		submissions: make(map[string]*models.Submission),
+	}
+}
+
+func (m *mockSubmissionStore) GetAssignmentById(assignmentid string) (
+	*models.Assignment,
+	error,
+) {
+	return m.assignment, nil
+}
+
+func (m *mockSubmissionStore) GetExtension(assignmentId, netId string) (
+	*models.Extension,
+	error,
+) {
+	return m.extensions[netId], nil
+}
+
+func (m *mockSubmissionStore) GetAccommodation(courseId, netId string) (
+	*models.Accommodation,
+	error,
+) {
+	return m.accommodations[netId], nil
+}
+
+func (m *mockSubmissionStore) GetCourseIdByAssignment(assignmentId string) (
+	string,
+	error,
+) {
+	return "c1", nil
+}
+
+func (m *mockSubmissionStore) GetAssignmentIdBySubmission(submissionId string) (
+	string,
+	error,
+) {
+	return m.assignment.ID, nil
+}
+
+func (m *mockSubmissionStore) GetSubmissionById(submissionId string) (
+	*models.Submission,
+	error,
+) {
+	return m.submissions[submissionId], nil
+}
+
+func (m *mockSubmissionStore) InsertAttempt(
+	sub *models.Submission,
+	maxAttempts int,
+) (*models.Submission, error) {
+	attempts, _ := m.GetAttempts(sub.AssignmentId, sub.User.ID)
+	if maxAttempts > 0 && len(attempts) >= maxAttempts {
+		return nil, dal.ERR_ATTEMPT_LIMIT
+	}
+
+	sub.Attempt = 1
+	for _, a := range attempts {
+		if a.Attempt >= sub.Attempt {
+			sub.Attempt = a.Attempt + 1
+		}
+	}
+
+	sub.ID = fmt.Sprintf("s%d", len(m.submissions)+1)
+	m.submissions[sub.ID] = sub
+	return sub, nil
+}
+
+func (m *mockSubmissionStore) UpdateSubmission(sub *models.Submission) (
+	*models.Submission,
+	error,
+) {
+	m.submissions[sub.ID] = sub
+	return sub, nil
+}
+
+func (m *mockSubmissionStore) DeleteSubmissionByID(id string) error {
+	delete(m.submissions, id)
+	return nil
+}
+
+func (m *mockSubmissionStore) GetAttempts(assignmentId, netId string) (
+	[]*models.Submission,
+	error,
+) {
+	var attempts []*models.Submission
+	for i := 1; i <= len(m.submissions); i++ {
+		sub, ok := m.submissions[fmt.Sprintf("s%d", i)]
+		if ok && (netId == "" || sub.User.ID == netId) {
+			attempts = append(attempts, sub)
+		}
+	}
+	return attempts, nil
+}
+
+func (m *mockSubmissionStore) SetCountedAttempt(
+	assignmentId, netId, submissionId string,
+) error {
+	for _, sub := range m.submissions {
+		if sub.User.ID == netId {
+			sub.Counts = sub.ID == submissionId
+		}
+	}
+	return nil
+}
+
+func (m *mockSubmissionStore) SetPinnedAttempt(
+	assignmentId, netId, submissionId string,
+) error {
+	for _, sub := range m.submissions {
+		if sub.User.ID == netId {
+			sub.Pinned = sub.ID == submissionId
+		}
+	}
+	return nil
+}
+
+func (m *mockSubmissionStore) GetSubmissionFiles(submissionId string) (
+	[]*models.Media,
+	error,
+) {
+	return m.submissions[submissionId].Files, nil
+}
+
+func (m *mockSubmissionStore) IsCourseTeacher(courseId, netId string) (
+	bool,
+	error,
+) {
+	return netId == "prof" || netId == "ta", nil
+}
+
+func (m *mockSubmissionStore) InsertRegradeRequest(r *models.RegradeRequest) error {
+	r.ID = fmt.Sprintf("r%d", len(m.regrades)+1)
+	copied := *r
+	m.regrades = append(m.regrades, &copied)
+	return nil
+}
+
+func (m *mockSubmissionStore) GetRegradeRequestById(id string) (
+	*models.RegradeRequest,
+	error,
+) {
+	for _, r := range m.regrades {
+		if r.ID == id {
+			copied := *r
+			return &copied, nil
+		}
+	}
+	return nil, dal.ERR_RECORD_NOT_FOUND
+}
+
+func (m *mockSubmissionStore) GetRegradeRequests(
+	assignmentId, submissionId string,
+) ([]*models.RegradeRequest, error) {
+	var requests []*models.RegradeRequest
+	for _, r := range m.regrades {
+		if submissionId == "" || r.SubmissionId == submissionId {
+			copied := *r
+			requests = append(requests, &copied)
+		}
+	}
+	return requests, nil
+}
+
+func (m *mockSubmissionStore) UpdateRegradeRequest(r *models.RegradeRequest) error {
+	for i, saved := range m.regrades {
+		if saved.ID == r.ID {
+			copied := *r
+			m.regrades[i] = &copied
+		}
+	}
+	return nil
+}
+
+func (m *mockSubmissionStore) InsertRegradeEvent(e *models.RegradeEvent) error {
+	m.events = append(m.events, e)
+	return nil
+}
+
+func (m *mockSubmissionStore) GetRegradeEvents(requestId string) (
+	[]*models.RegradeEvent,
+	error,
+) {
+	var events []*models.RegradeEvent
+	for _, e := range m.events {
+		if e.RequestId == requestId {
+			events = append(events, e)
+		}
+	}
+	return events, nil
+}
+
+func (m *mockSubmissionStore) InsertNotification(n *models.Notification) error {
+	m.notifications = append(m.notifications, n)
+	return nil
+}
@@ -0,0 +1,67 @@
+package domain
+
+import "github.com/n30w/Darkspace/internal/models"
+
+// TeacherStore looks up who teaches a course. Services that let only
+// a course's teachers do some things embed it in their stores.
+type TeacherStore interface {
+	IsCourseTeacher(courseId, netId string) (bool, error)
+}
+
+// TeacherAssignmentStore looks up an assignment and who teaches its
+// course.
+type TeacherAssignmentStore interface {
+	TeacherStore
+
+	GetAssignmentById(assignmentid string) (*models.Assignment, error)
+	GetCourseIdByAssignment(assignmentId string) (string, error)
+}
+
+// isTeacher checks if a user teaches a course. Users who are not
+// signed in teach nothing.
+func isTeacher(s TeacherStore, courseId, netId string) (bool, error) {
+	if netId == "" {
+		return false, nil
+	}
+
+	return s.IsCourseTeacher(courseId, netId)
+}
+
+// teacherOnly returns ERR_NOT_PERMITTED unless a user teaches a
+// course.
+func teacherOnly(s TeacherStore, courseId, netId string) error {
+	teacher, err := isTeacher(s, courseId, netId)
+	if err != nil {
+		return err
+	}
+
+	if !teacher {
+		return ERR_NOT_PERMITTED
+	}
+
+	return nil
+}
+
+// teacherAssignment retrieves an assignment for something that only
+// the course's teachers may do.
+func teacherAssignment(
+	s TeacherAssignmentStore,
+	assignmentId, netId string,
+) (*models.Assignment, error) {
+	a, err := s.GetAssignmentById(assignmentId)
+	if err != nil {
+		return nil, err
+	}
+
+	courseId, err := s.GetCourseIdByAssignment(a.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = teacherOnly(s, courseId, netId)
+	if err != nil {
+		return nil, err
+	}
+
+	return a, nil
+}
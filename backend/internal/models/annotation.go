@@ -0,0 +1,201 @@
+package models
+
+import (
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// AnnotationKind is the kind of mark an annotation makes on a page.
+type AnnotationKind string
+
+const (
+	// ANNOTATION_HIGHLIGHT marks text, one rectangle for each line it covers.
+	ANNOTATION_HIGHLIGHT AnnotationKind = "highlight"
+
+	// ANNOTATION_COMMENT is a note pinned to a point on the page.
+	ANNOTATION_COMMENT AnnotationKind = "comment"
+
+	// ANNOTATION_INK is a freehand mark, drawn as one or more strokes.
+	ANNOTATION_INK AnnotationKind = "ink"
+)
+
+// Limits on an annotation's size.
+const (
+	MaxAnnotationComment = 5000
+	MaxAnnotationPoints  = 5000
+)
+
+// Default colors of each kind of annotation.
+var annotationColors = map[AnnotationKind]string{
+	ANNOTATION_HIGHLIGHT: "#FFEB3B",
+	ANNOTATION_COMMENT:   "#FFC107",
+	ANNOTATION_INK:       "#E53935",
+}
+
+var colorRX = regexp.MustCompile(`^#[0-9A-Fa-f]{6}$`)
+
+// Point is a position on a page, in PDF points from the bottom left
+// corner of the page, as PDF places things.
+type Point struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+}
+
+// Rect is an area of a page, from its bottom left corner.
+type Rect struct {
+	X      float64 `json:"x"`
+	Y      float64 `json:"y"`
+	Width  float64 `json:"width"`
+	Height float64 `json:"height"`
+}
+
+// Annotation is a teacher's mark on a page of a PDF that was submitted,
+// as feedback on the submission.
+type Annotation struct {
+	Entity
+	SubmissionId string `json:"submission_id"`
+	MediaId      string `json:"media_id"`
+
+	// Author is the teacher who made the annotation.
+	Author string         `json:"author"`
+	Kind   AnnotationKind `json:"kind"`
+
+	// Page is the page the annotation is on, counting from 1.
+	Page int `json:"page"`
+
+	// Rects are the areas a highlight covers.
+	Rects []Rect `json:"rects,omitempty"`
+
+	// Point is where a comment is pinned.
+	Point *Point `json:"point,omitempty"`
+
+	// Strokes are the lines of an ink mark, each a run of points.
+	Strokes [][]Point `json:"strokes,omitempty"`
+
+	// Comment is what the teacher wrote, which a comment must have and
+	// highlights and ink marks may have.
+	Comment string `json:"comment,omitempty"`
+
+	// Color is in hexadecimal, as in "#FFEB3B".
+	Color string `json:"color"`
+}
+
+// Valid checks the annotation, giving it its kind's color when it has
+// none. Only the geometry of its kind is kept.
+func (a *Annotation) Valid() map[string]string {
+	errs := make(map[string]string)
+
+	a.Comment = strings.TrimSpace(a.Comment)
+
+	if a.Page < 1 {
+		errs["page"] = "must be 1 or more"
+	}
+
+	if len(a.Comment) > MaxAnnotationComment {
+		errs["comment"] = "must be no more than 5000 bytes long"
+	}
+
+	switch a.Kind {
+	case ANNOTATION_HIGHLIGHT:
+		a.Point, a.Strokes = nil, nil
+
+		if len(a.Rects) == 0 {
+			errs["rects"] = "must cover at least one area"
+		}
+
+		for _, r := range a.Rects {
+			if r.Width <= 0 || r.Height <= 0 {
+				errs["rects"] = "must have a positive width and height"
+				break
+			}
+		}
+
+		if len(a.Rects) > MaxAnnotationPoints {
+			errs["rects"] = "must be no more than 5000 areas"
+		}
+	case ANNOTATION_COMMENT:
+		a.Rects, a.Strokes = nil, nil
+
+		if a.Point == nil {
+			errs["point"] = "must be provided"
+		}
+
+		if a.Comment == "" {
+			errs["comment"] = "must be provided"
+		}
+	case ANNOTATION_INK:
+		a.Rects, a.Point = nil, nil
+
+		points := 0
+
+		for _, stroke := range a.Strokes {
+			points += len(stroke)
+		}
+
+		if points == 0 {
+			errs["strokes"] = "must have at least one point"
+		}
+
+		if points > MaxAnnotationPoints {
+			errs["strokes"] = "must be no more than 5000 points"
+		}
+	default:
+		errs["kind"] = "must be highlight, comment or ink"
+	}
+
+	if a.Color == "" {
+		a.Color = annotationColors[a.Kind]
+	}
+
+	if !colorRX.MatchString(a.Color) {
+		errs["color"] = "must be a hexadecimal color, like #FFEB3B"
+	}
+
+	return errs
+}
+
+// Bounds is the smallest area that holds all of the annotation.
+func (a *Annotation) Bounds() Rect {
+	var points []Point
+
+	for _, r := range a.Rects {
+		points = append(points, Point{r.X, r.Y}, Point{r.X + r.Width, r.Y + r.Height})
+	}
+
+	if a.Point != nil {
+		points = append(points, *a.Point)
+	}
+
+	for _, stroke := range a.Strokes {
+		points = append(points, stroke...)
+	}
+
+	if len(points) == 0 {
+		return Rect{}
+	}
+
+	lo, hi := points[0], points[0]
+	for _, p := range points[1:] {
+		lo.X, lo.Y = math.Min(lo.X, p.X), math.Min(lo.Y, p.Y)
+		hi.X, hi.Y = math.Max(hi.X, p.X), math.Max(hi.Y, p.Y)
+	}
+
+	return Rect{X: lo.X, Y: lo.Y, Width: hi.X - lo.X, Height: hi.Y - lo.Y}
+}
+
+// RGB is the annotation's color as red, green and blue, each from 0
+// to 1.
+func (a *Annotation) RGB() (r, g, b float64) {
+	if !colorRX.MatchString(a.Color) {
+		return 0, 0, 0
+	}
+
+	channel := func(s string) float64 {
+		n, _ := strconv.ParseUint(s, 16, 8)
+		return float64(n) / 255
+	}
+
+	return channel(a.Color[1:3]), channel(a.Color[3:5]), channel(a.Color[5:7])
+}
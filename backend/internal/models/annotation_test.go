@@ -0,0 +1,102 @@
+package models
+
+import "testing"
+
+func TestAnnotation_Valid(t *testing.T) {
+	tests := []struct {
+		name       string
+		annotation Annotation
+		errs       []string
+	}{
+		{
+			name:       "highlight",
+			annotation: Annotation{Kind: ANNOTATION_HIGHLIGHT, Page: 1, Rects: []Rect{{X: 72, Y: 700, Width: 200, Height: 12}}},
+		},
+		{
+			name:       "comment",
+			annotation: Annotation{Kind: ANNOTATION_COMMENT, Page: 2, Point: &Point{X: 300, Y: 400}, Comment: "Cite this"},
+		},
+		{
+			name:       "ink",
+			annotation: Annotation{Kind: ANNOTATION_INK, Page: 1, Strokes: [][]Point{{{X: 1, Y: 1}, {X: 2, Y: 2}}}, Color: "#00ff00"},
+		},
+		{
+			name:       "empty highlight",
+			annotation: Annotation{Kind: ANNOTATION_HIGHLIGHT, Page: 1, Rects: []Rect{{X: 72, Y: 700}}},
+			errs:       []string{"rects"},
+		},
+		{
+			name:       "comment without text",
+			annotation: Annotation{Kind: ANNOTATION_COMMENT, Page: 1, Point: &Point{}, Comment: "  "},
+			errs:       []string{"comment"},
+		},
+		{
+			name:       "ink without points",
+			annotation: Annotation{Kind: ANNOTATION_INK, Page: 0, Strokes: [][]Point{{}}, Color: "red"},
+			errs:       []string{"page", "strokes", "color"},
+		},
+		{
+			name:       "unknown kind",
+			annotation: Annotation{Kind: "circle", Page: 1, Color: "#000000"},
+			errs:       []string{"kind"},
+		},
+	}
+
+	for _, tt := range tests {
+		errs := tt.annotation.Valid()
+
+		if len(errs) != len(tt.errs) {
+			t.Errorf("%s: got %v, want errors for %v", tt.name, errs, tt.errs)
+		}
+
+		for _, field := range tt.errs {
+			if _, ok := errs[field]; !ok {
+				t.Errorf("%s: got %v, want an error for %s", tt.name, errs, field)
+			}
+		}
+	}
+
+	a := Annotation{
+		Kind:    ANNOTATION_COMMENT,
+		Page:    1,
+		Point:   &Point{X: 1, Y: 2},
+		Rects:   []Rect{{Width: 1, Height: 1}},
+		Comment: "Good",
+	}
+	a.Valid()
+
+	if a.Rects != nil || a.Color != annotationColors[ANNOTATION_COMMENT] {
+		t.Errorf("got rects %v and color %q, want only the comment's point and its default color", a.Rects, a.Color)
+	}
+}
+
+func TestAnnotation_Bounds(t *testing.T) {
+	a := Annotation{
+		Kind: ANNOTATION_INK,
+		Strokes: [][]Point{
+			{{X: 10, Y: 50}, {X: 30, Y: 20}},
+			{{X: 5, Y: 40}},
+		},
+	}
+
+	want := Rect{X: 5, Y: 20, Width: 25, Height: 30}
+	if got := a.Bounds(); got != want {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+
+	a = Annotation{Rects: []Rect{{X: 72, Y: 700, Width: 100, Height: 12}, {X: 72, Y: 686, Width: 50, Height: 12}}}
+
+	want = Rect{X: 72, Y: 686, Width: 100, Height: 26}
+	if got := a.Bounds(); got != want {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestAnnotation_RGB(t *testing.T) {
+	a := Annotation{Color: "#FF8000"}
+
+	r, g, b := a.RGB()
+	if r != 1 || g != 128.0/255 || b != 0 {
+		t.Errorf("got %v %v %v, want 1 %v 0", r, g, b, 128.0/255)
+	}
+}
@@ -0,0 +1,55 @@
+package models
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+// pseudonymPrefix starts every pseudonym, so that pseudonyms cannot
+// be mistaken for Net IDs.
+const pseudonymPrefix = "anon-"
+
+// NewAnonymousKey makes the secret that an assignment's pseudonyms
+// are derived from.
+func NewAnonymousKey() (string, error) {
+	b := make([]byte, 32)
+
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
+}
+
+// Pseudonym is the name a student is shown under while an
+// assignment is graded anonymously. A student always has the same
+// pseudonym for the same key, and it cannot be worked out from their
+// Net ID without the key.
+func Pseudonym(key, netId string) string {
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write([]byte(netId))
+
+	return pseudonymPrefix + hex.EncodeToString(mac.Sum(nil))[:10]
+}
+
+// IsPseudonym checks if an ID looks like a pseudonym rather than a
+// Net ID.
+func IsPseudonym(id string) bool {
+	return strings.HasPrefix(id, pseudonymPrefix)
+}
+
+// Blind checks if the students who submitted to the assignment are
+// hidden from its graders, which they are until grades are released.
+func (a *Assignment) Blind() bool {
+	return a.Anonymous && !a.GradesReleased
+}
+
+// Anonymize replaces the student of a submission with their
+// pseudonym, leaving out their name.
+func (s *Submission) Anonymize(key string) {
+	s.User = User{Entity: Entity{ID: Pseudonym(key, s.User.ID)}}
+}
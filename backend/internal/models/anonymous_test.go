@@ -0,0 +1,45 @@
+package models
+
+import "testing"
+
+func TestPseudonym(t *testing.T) {
+	p := Pseudonym("key", "abc123")
+
+	if p != Pseudonym("key", "abc123") {
+		t.Errorf("got a different pseudonym for the same student")
+	}
+
+	if p == Pseudonym("key", "xyz789") {
+		t.Errorf("got the same pseudonym for two students")
+	}
+
+	if p == Pseudonym("other", "abc123") {
+		t.Errorf("got the same pseudonym under two keys")
+	}
+
+	if !IsPseudonym(p) || IsPseudonym("abc123") {
+		t.Errorf("got %v, %v, want true, false", IsPseudonym(p), IsPseudonym("abc123"))
+	}
+}
+
+func TestAssignment_Blind(t *testing.T) {
+	tests := []struct {
+		name string
+		a    Assignment
+		want bool
+	}{
+		{name: "not anonymous", a: Assignment{}},
+		{name: "anonymous", a: Assignment{Anonymous: true}, want: true},
+		{name: "released", a: Assignment{Anonymous: true, GradesReleased: true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				if got := tt.a.Blind(); got != tt.want {
+					t.Errorf("got %v, want %v", got, tt.want)
+				}
+			},
+		)
+	}
+}
@@ -0,0 +1,182 @@
+package models
+
+import (
+	"fmt"
+	"path"
+	"slices"
+	"time"
+)
+
+// AttemptSelection decides which of a student's attempts at an
+// assignment counts towards their grade.
+type AttemptSelection string
+
+const (
+	LATEST_ATTEMPT AttemptSelection = "latest"
+	BEST_ATTEMPT   AttemptSelection = "best"
+)
+
+// Valid checks if a selection is one of the known selections. Empty
+// selections count the latest attempt.
+func (as AttemptSelection) Valid() bool {
+	switch as {
+	case "", LATEST_ATTEMPT, BEST_ATTEMPT:
+		return true
+	default:
+		return false
+	}
+}
+
+// ValidAttempts checks an assignment's attempt settings, returning
+// problems keyed by field.
+func ValidAttempts(maxAttempts int, selection AttemptSelection) map[string]string {
+	errs := make(map[string]string)
+
+	if maxAttempts < 0 {
+		errs["max_attempts"] = "must not be negative"
+	}
+
+	if !selection.Valid() {
+		errs["counted_attempt"] = fmt.Sprintf(
+			"must be %s or %s",
+			LATEST_ATTEMPT,
+			BEST_ATTEMPT,
+		)
+	}
+
+	return errs
+}
+
+// CountedAttempt is the attempt that counts towards a student's grade,
+// out of all of their attempts at an assignment. An attempt a teacher
+// has pinned always counts. Otherwise, the best attempt is the graded
+// attempt with the highest grade, or the latest attempt when none
+// are graded. Ties go to the later attempt. It is nil when there are
+// no attempts.
+func CountedAttempt(attempts []*Submission, selection AttemptSelection) *Submission {
+	var latest, best, pinned *Submission
+
+	for _, a := range attempts {
+		if a.Pinned {
+			pinned = a
+		}
+
+		if latest == nil || a.Attempt > latest.Attempt {
+			latest = a
+		}
+
+		if !a.Graded {
+			continue
+		}
+
+		if best == nil || a.Grade > best.Grade || (a.Grade == best.Grade && a.Attempt > best.Attempt) {
+			best = a
+		}
+	}
+
+	switch {
+	case pinned != nil:
+		return pinned
+	case selection == BEST_ATTEMPT && best != nil:
+		return best
+	default:
+		return latest
+	}
+}
+
+// AttemptDiff is what changed between two of a student's attempts at
+// an assignment. Files are matched by name, and are changed when
+// their contents differ.
+type AttemptDiff struct {
+	From int `json:"from"`
+	To   int `json:"to"`
+
+	// Elapsed is how many seconds passed between the two attempts.
+	Elapsed int64 `json:"elapsed"`
+
+	Added     []string `json:"added"`
+	Removed   []string `json:"removed"`
+	Changed   []string `json:"changed"`
+	Unchanged []string `json:"unchanged"`
+
+	// GradeChange is how much the grade went up, or down when
+	// negative. It is only set when both attempts are graded.
+	GradeChange *float64 `json:"grade_change,omitempty"`
+
+	FeedbackChanged bool `json:"feedback_changed"`
+}
+
+// DiffAttempts works out what changed from one attempt to another,
+// using the attempts' Files.
+func DiffAttempts(from, to *Submission) *AttemptDiff {
+	d := &AttemptDiff{
+		From:      from.Attempt,
+		To:        to.Attempt,
+		Elapsed:   int64(to.SubmissionTime.Sub(from.SubmissionTime) / time.Second),
+		Added:     []string{},
+		Removed:   []string{},
+		Changed:   []string{},
+		Unchanged: []string{},
+	}
+
+	before := filesByName(from.Files)
+	after := filesByName(to.Files)
+
+	for name, m := range after {
+		old, ok := before[name]
+
+		switch {
+		case !ok:
+			d.Added = append(d.Added, name)
+		case sameContents(old, m):
+			d.Unchanged = append(d.Unchanged, name)
+		default:
+			d.Changed = append(d.Changed, name)
+		}
+	}
+
+	for name := range before {
+		if _, ok := after[name]; !ok {
+			d.Removed = append(d.Removed, name)
+		}
+	}
+
+	for _, names := range [][]string{d.Added, d.Removed, d.Changed, d.Unchanged} {
+		slices.Sort(names)
+	}
+
+	if from.Graded && to.Graded {
+		change := roundPercent(to.Grade - from.Grade)
+		d.GradeChange = &change
+	}
+
+	d.FeedbackChanged = from.Feedback != to.Feedback
+
+	return d
+}
+
+// DisplayName is the name a file was uploaded with. Files uploaded
+// before names were kept fall back to the name they are stored under.
+func (m *Media) DisplayName() string {
+	if m.FileName != "" {
+		return m.FileName
+	}
+	return path.Base(m.FilePath)
+}
+
+func filesByName(files []*Media) map[string]*Media {
+	byName := make(map[string]*Media, len(files))
+	for _, m := range files {
+		byName[m.DisplayName()] = m
+	}
+	return byName
+}
+
+// sameContents compares two files by checksum, or by size for files
+// uploaded before checksums were kept.
+func sameContents(a, b *Media) bool {
+	if a.Checksum != "" && b.Checksum != "" {
+		return a.Checksum == b.Checksum
+	}
+	return a.Size == b.Size
+}
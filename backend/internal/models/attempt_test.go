@@ -0,0 +1,145 @@
+package models
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestCountedAttempt(t *testing.T) {
+	attempt := func(n int, grade float64, graded, pinned bool) *Submission {
+		return &Submission{Attempt: n, Grade: grade, Graded: graded, Pinned: pinned}
+	}
+
+	tests := []struct {
+		name      string
+		attempts  []*Submission
+		selection AttemptSelection
+		want      int
+	}{
+		{name: "no attempts"},
+		{
+			name:     "latest by default",
+			attempts: []*Submission{attempt(1, 90, true, false), attempt(2, 70, true, false)},
+			want:     2,
+		},
+		{
+			name:      "best",
+			attempts:  []*Submission{attempt(1, 90, true, false), attempt(2, 70, true, false)},
+			selection: BEST_ATTEMPT,
+			want:      1,
+		},
+		{
+			name:      "best skips ungraded attempts",
+			attempts:  []*Submission{attempt(1, 60, true, false), attempt(2, 0, false, false)},
+			selection: BEST_ATTEMPT,
+			want:      1,
+		},
+		{
+			name:      "best tie goes to the later attempt",
+			attempts:  []*Submission{attempt(1, 80, true, false), attempt(2, 80, true, false)},
+			selection: BEST_ATTEMPT,
+			want:      2,
+		},
+		{
+			name:      "best without grades is the latest",
+			attempts:  []*Submission{attempt(1, 0, false, false), attempt(2, 0, false, false)},
+			selection: BEST_ATTEMPT,
+			want:      2,
+		},
+		{
+			name:      "pinned",
+			attempts:  []*Submission{attempt(1, 50, true, true), attempt(2, 90, true, false)},
+			selection: BEST_ATTEMPT,
+			want:      1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				got := CountedAttempt(tt.attempts, tt.selection)
+
+				if got == nil {
+					if tt.want != 0 {
+						t.Fatalf("got no attempt, want %d", tt.want)
+					}
+					return
+				}
+
+				if got.Attempt != tt.want {
+					t.Errorf("got attempt %d, want %d", got.Attempt, tt.want)
+				}
+			},
+		)
+	}
+}
+
+func TestDiffAttempts(t *testing.T) {
+	at := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	from := &Submission{
+		Attempt:        1,
+		SubmissionTime: at,
+		Grade:          70,
+		Graded:         true,
+		Feedback:       "needs tests",
+		Files: []*Media{
+			{FileName: "main.go", Checksum: "aaa"},
+			{FileName: "README.md", Checksum: "bbb"},
+			{FileName: "notes.txt", Size: 10},
+			{FilePath: "/v/s1_old.go", Size: 5},
+		},
+	}
+
+	to := &Submission{
+		Attempt:        2,
+		SubmissionTime: at.Add(90 * time.Minute),
+		Grade:          85.5,
+		Graded:         true,
+		Feedback:       "needs tests",
+		Files: []*Media{
+			{FileName: "main.go", Checksum: "ccc"},
+			{FileName: "README.md", Checksum: "bbb"},
+			{FileName: "notes.txt", Size: 10},
+			{FileName: "main_test.go", Checksum: "ddd"},
+		},
+	}
+
+	d := DiffAttempts(from, to)
+
+	if d.From != 1 || d.To != 2 || d.Elapsed != 5400 {
+		t.Errorf("got from %d, to %d, elapsed %d, want 1, 2, 5400", d.From, d.To, d.Elapsed)
+	}
+
+	lists := []struct {
+		name string
+		got  []string
+		want []string
+	}{
+		{name: "added", got: d.Added, want: []string{"main_test.go"}},
+		{name: "removed", got: d.Removed, want: []string{"s1_old.go"}},
+		{name: "changed", got: d.Changed, want: []string{"main.go"}},
+		{name: "unchanged", got: d.Unchanged, want: []string{"README.md", "notes.txt"}},
+	}
+
+	for _, l := range lists {
+		if !slices.Equal(l.got, l.want) {
+			t.Errorf("%s: got %v, want %v", l.name, l.got, l.want)
+		}
+	}
+
+	if d.GradeChange == nil || *d.GradeChange != 15.5 {
+		t.Errorf("got grade change %v, want 15.5", d.GradeChange)
+	}
+
+	if d.FeedbackChanged {
+		t.Errorf("got feedback changed, want unchanged")
+	}
+
+	to.Graded = false
+
+	if d := DiffAttempts(from, to); d.GradeChange != nil {
+		t.Errorf("got grade change %v, want none for an ungraded attempt", *d.GradeChange)
+	}
+}
@@ -0,0 +1,263 @@
+package models
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Limits that harnesses are run with when a teacher does not set them.
+const (
+	DefaultHarnessTimeout = 60
+	DefaultHarnessMemory  = 256
+	MaxHarnessTimeout     = 600
+	MaxHarnessMemory      = 4096
+)
+
+// MaxLogSize is the most output of a run that is kept with its job.
+const MaxLogSize = 64 << 10
+
+// Harness is what an assignment's submissions are tested with. Its
+// files are put in a workspace along with the files of the submission,
+// and its command is run there and reports its tests in TAP, the Test
+// Anything Protocol, on file descriptor 3. What it prints is only
+// logged. The command must start the submission with file descriptor
+// 3 closed, such as with 3>&- in the shell, so that the submission
+// cannot report tests of its own.
+type Harness struct {
+	AssignmentId string `json:"assignment_id"`
+
+	// Command is run with the shell, from the workspace.
+	Command string `json:"command"`
+
+	// Image is the container image that the command is run in, when
+	// submissions are run in containers.
+	Image string `json:"image,omitempty"`
+
+	// Timeout is how many seconds a run may take, and Memory how many
+	// megabytes it may use.
+	Timeout int `json:"timeout"`
+	Memory  int `json:"memory"`
+
+	Files []*Media `json:"files,omitempty"`
+}
+
+// Valid checks the harness, setting the default limits for those that
+// are not set.
+func (h *Harness) Valid() map[string]string {
+	errs := make(map[string]string)
+
+	if strings.TrimSpace(h.Command) == "" {
+		errs["command"] = "must be provided"
+	}
+
+	if h.Timeout == 0 {
+		h.Timeout = DefaultHarnessTimeout
+	}
+
+	if h.Timeout < 0 || h.Timeout > MaxHarnessTimeout {
+		errs["timeout"] = "must be between 1 and 600 seconds"
+	}
+
+	if h.Memory == 0 {
+		h.Memory = DefaultHarnessMemory
+	}
+
+	if h.Memory < 0 || h.Memory > MaxHarnessMemory {
+		errs["memory"] = "must be between 1 and 4096 megabytes"
+	}
+
+	return errs
+}
+
+// RunSpec is how a runner runs a harness over a workspace.
+type RunSpec struct {
+	// Dir is the workspace, holding the harness's files and, in its
+	// submission directory, the files of the submission.
+	Dir     string
+	Command string
+	Image   string
+	Timeout time.Duration
+
+	// Memory is the most memory the run may use, in bytes.
+	Memory int64
+}
+
+// RunResult is what came of running a harness.
+type RunResult struct {
+	// Output is the run's standard output and error, interleaved, cut
+	// to MaxLogSize.
+	Output string
+
+	// TAP is what the run wrote to file descriptor 3, cut to
+	// MaxLogSize.
+	TAP      string
+	ExitCode int
+	TimedOut bool
+}
+
+// GradeJobStatus is where a grading job is in being run.
+type GradeJobStatus string
+
+const (
+	GRADE_JOB_QUEUED  GradeJobStatus = "queued"
+	GRADE_JOB_RUNNING GradeJobStatus = "running"
+	GRADE_JOB_DONE    GradeJobStatus = "done"
+	GRADE_JOB_FAILED  GradeJobStatus = "failed"
+)
+
+// GradeJob is a run of an assignment's harness over a submission.
+type GradeJob struct {
+	Entity
+	SubmissionId string         `json:"submission_id"`
+	AssignmentId string         `json:"assignment_id"`
+	Status       GradeJobStatus `json:"status"`
+
+	// Score is the percentage of tests that passed.
+	Score  *float64 `json:"score,omitempty"`
+	Passed int      `json:"passed"`
+	Total  int      `json:"total"`
+
+	Tests []*TestResult `json:"tests"`
+	Log   string        `json:"log,omitempty"`
+
+	// Error is why the job failed.
+	Error string `json:"error,omitempty"`
+
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
+// TestResult is how a submission did on one test of a harness.
+type TestResult struct {
+	Name    string `json:"name"`
+	Passed  bool   `json:"passed"`
+	Skipped bool   `json:"skipped,omitempty"`
+
+	// Output is the diagnostics the harness printed for the test.
+	Output string `json:"output,omitempty"`
+}
+
+// Finish records the tests of a run, scoring the job by the share of
+// tests that passed. Skipped tests count as neither passed nor failed.
+// A run that reported no tests fails the job.
+func (j *GradeJob) Finish(tests []*TestResult, log string, at time.Time) {
+	j.Tests = tests
+	j.Log = log
+	j.FinishedAt = &at
+	j.Passed, j.Total = 0, 0
+
+	for _, t := range tests {
+		if t.Skipped {
+			continue
+		}
+
+		j.Total++
+
+		if t.Passed {
+			j.Passed++
+		}
+	}
+
+	if j.Total == 0 {
+		j.Fail("the harness reported no tests", at)
+		return
+	}
+
+	score := float64(j.Passed) / float64(j.Total) * 100
+	j.Score = &score
+	j.Status = GRADE_JOB_DONE
+}
+
+// Fail marks the job as failed, along with why.
+func (j *GradeJob) Fail(reason string, at time.Time) {
+	j.Status = GRADE_JOB_FAILED
+	j.Error = reason
+	j.Score = nil
+	j.FinishedAt = &at
+}
+
+// HideScore takes the score off a job, for students to see how they
+// did on each test before grades are released.
+func (j *GradeJob) HideScore() {
+	j.Score = nil
+}
+
+// ParseTAP reads the tests reported in TAP output, such as "ok 1 -
+// adds numbers" and "not ok 2 - divides by zero". Tests marked with a
+// SKIP directive are skipped, and those marked TODO are not expected
+// to pass. Diagnostic lines, which start with "#", are kept with the
+// test before them.
+func ParseTAP(output string) []*TestResult {
+	var tests []*TestResult
+
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimRight(line, "\r")
+		trimmed := strings.TrimSpace(line)
+
+		var passed bool
+
+		switch {
+		case strings.HasPrefix(trimmed, "ok"):
+			passed = true
+			trimmed = strings.TrimPrefix(trimmed, "ok")
+		case strings.HasPrefix(trimmed, "not ok"):
+			trimmed = strings.TrimPrefix(trimmed, "not ok")
+		case strings.HasPrefix(trimmed, "#") && len(tests) > 0:
+			t := tests[len(tests)-1]
+			diagnostic := strings.TrimSpace(strings.TrimPrefix(trimmed, "#"))
+			if t.Output != "" {
+				t.Output += "\n"
+			}
+			t.Output += diagnostic
+			continue
+		default:
+			continue
+		}
+
+		// "ok" must be a word of its own, not the start of another.
+		if trimmed != "" && trimmed[0] != ' ' && trimmed[0] != '\t' {
+			continue
+		}
+
+		name, directive, _ := strings.Cut(trimmed, "#")
+		name = strings.TrimSpace(name)
+		directive = strings.ToUpper(strings.TrimSpace(directive))
+
+		// The test's number comes before its name.
+		number, rest, _ := strings.Cut(name, " ")
+		if isDigits(number) {
+			name = rest
+		}
+
+		name = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(name), "-"))
+
+		t := &TestResult{Name: name, Passed: passed}
+
+		if strings.HasPrefix(directive, "SKIP") || strings.HasPrefix(directive, "TODO") {
+			t.Skipped = true
+		}
+
+		if t.Name == "" {
+			t.Name = "test " + strconv.Itoa(len(tests)+1)
+		}
+
+		tests = append(tests, t)
+	}
+
+	return tests
+}
+
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,134 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseTAP(t *testing.T) {
+	output := `TAP version 13
+1..5
+ok 1 - adds numbers
+not ok 2 - divides by zero
+# expected an error
+# got 0
+ok 3 # SKIP no network
+not ok 4 - sorts strings # TODO not written yet
+okay this is not a test
+ok
+`
+
+	want := []TestResult{
+		{Name: "adds numbers", Passed: true},
+		{Name: "divides by zero", Output: "expected an error\ngot 0"},
+		{Name: "test 3", Passed: true, Skipped: true},
+		{Name: "sorts strings", Skipped: true},
+		{Name: "test 5", Passed: true},
+	}
+
+	got := ParseTAP(output)
+
+	if len(got) != len(want) {
+		t.Fatalf("got %d tests, want %d", len(got), len(want))
+	}
+
+	for i, w := range want {
+		if *got[i] != w {
+			t.Errorf("test %d: got %+v, want %+v", i+1, *got[i], w)
+		}
+	}
+}
+
+func TestGradeJob_Finish(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name   string
+		tests  []*TestResult
+		status GradeJobStatus
+		score  float64
+	}{
+		{
+			name: "some passed",
+			tests: []*TestResult{
+				{Passed: true}, {Passed: false}, {Passed: true}, {Passed: true},
+			},
+			status: GRADE_JOB_DONE,
+			score:  75,
+		},
+		{
+			name: "skipped left out",
+			tests: []*TestResult{
+				{Passed: true}, {Skipped: true}, {Passed: false, Skipped: true},
+			},
+			status: GRADE_JOB_DONE,
+			score:  100,
+		},
+		{
+			name:   "no tests",
+			status: GRADE_JOB_FAILED,
+		},
+		{
+			name:   "only skipped",
+			tests:  []*TestResult{{Skipped: true}},
+			status: GRADE_JOB_FAILED,
+		},
+	}
+
+	for _, tt := range tests {
+		j := &GradeJob{Status: GRADE_JOB_RUNNING}
+		j.Finish(tt.tests, "", now)
+
+		if j.Status != tt.status {
+			t.Errorf("%s: got %s, want %s", tt.name, j.Status, tt.status)
+		}
+
+		if tt.status == GRADE_JOB_FAILED {
+			if j.Score != nil || j.Error == "" {
+				t.Errorf("%s: got score %v and error %q, want no score and why", tt.name, j.Score, j.Error)
+			}
+			continue
+		}
+
+		if j.Score == nil || *j.Score != tt.score {
+			t.Errorf("%s: got %v, want %v", tt.name, j.Score, tt.score)
+		}
+	}
+}
+
+func TestHarness_Valid(t *testing.T) {
+	tests := []struct {
+		name    string
+		harness Harness
+		errs    []string
+	}{
+		{name: "defaults", harness: Harness{Command: "./test.sh"}},
+		{name: "no command", harness: Harness{Command: "  "}, errs: []string{"command"}},
+		{
+			name:    "out of bounds",
+			harness: Harness{Command: "make test", Timeout: MaxHarnessTimeout + 1, Memory: -1},
+			errs:    []string{"timeout", "memory"},
+		},
+	}
+
+	for _, tt := range tests {
+		errs := tt.harness.Valid()
+
+		if len(errs) != len(tt.errs) {
+			t.Errorf("%s: got %v, want errors for %v", tt.name, errs, tt.errs)
+		}
+
+		for _, field := range tt.errs {
+			if _, ok := errs[field]; !ok {
+				t.Errorf("%s: got %v, want an error for %s", tt.name, errs, field)
+			}
+		}
+	}
+
+	h := Harness{Command: "./test.sh"}
+	h.Valid()
+
+	if h.Timeout != DefaultHarnessTimeout || h.Memory != DefaultHarnessMemory {
+		t.Errorf("got %d seconds and %d MB, want the defaults", h.Timeout, h.Memory)
+	}
+}
@@ -0,0 +1,93 @@
+package models
+
+import (
+	"math"
+	"time"
+)
+
+// Extension moves one student's due date for one assignment.
+type Extension struct {
+	AssignmentId string    `json:"assignment_id"`
+	NetId        string    `json:"netid"`
+	DueDate      time.Time `json:"due_date"`
+	Reason       string    `json:"reason,omitempty"`
+	GrantedBy    string    `json:"granted_by,omitempty"`
+}
+
+// Valid checks an extension, returning problems keyed by field.
+func (e *Extension) Valid() map[string]string {
+	errs := make(map[string]string)
+
+	if e.NetId == "" {
+		errs["netid"] = "must be provided"
+	}
+
+	if e.DueDate.IsZero() {
+		errs["due_date"] = "must be provided"
+	}
+
+	return errs
+}
+
+// Accommodation is a student's standing change to every assignment and
+// quiz in a course. Assignments the student has an extension for use
+// the extension's due date instead.
+type Accommodation struct {
+	CourseId string `json:"course_id"`
+	NetId    string `json:"netid"`
+
+	// ExtraTime is how many seconds every due date is pushed back.
+	ExtraTime int `json:"extra_time"`
+
+	// TimeMultiplier scales quiz time limits, such as 1.5 for time and
+	// a half. Zero leaves time limits as they are.
+	TimeMultiplier float64 `json:"time_multiplier"`
+
+	Note string `json:"note,omitempty"`
+}
+
+// Valid checks an accommodation, returning problems keyed by field.
+func (a *Accommodation) Valid() map[string]string {
+	errs := make(map[string]string)
+
+	if a.NetId == "" {
+		errs["netid"] = "must be provided"
+	}
+
+	if a.ExtraTime < 0 {
+		errs["extra_time"] = "must not be negative"
+	}
+
+	if a.TimeMultiplier != 0 && a.TimeMultiplier < 1 {
+		errs["time_multiplier"] = "must be at least 1"
+	}
+
+	return errs
+}
+
+// DueDateFor is when a due date falls for a student with an extension
+// and an accommodation, either of which may be nil. An extension
+// replaces the due date, while an accommodation pushes it back. A zero
+// due date, meaning there is none, is only changed by an extension.
+func DueDateFor(due time.Time, ext *Extension, acc *Accommodation) time.Time {
+	if ext != nil {
+		return ext.DueDate
+	}
+
+	if acc == nil || due.IsZero() {
+		return due
+	}
+
+	return due.Add(time.Duration(acc.ExtraTime) * time.Second)
+}
+
+// TimeLimitFor is a quiz time limit, in seconds, for a student with an
+// accommodation, which may be nil. A zero time limit, meaning there is
+// none, stays zero.
+func TimeLimitFor(limit int, acc *Accommodation) int {
+	if acc == nil || acc.TimeMultiplier == 0 {
+		return limit
+	}
+
+	return int(math.Ceil(float64(limit) * acc.TimeMultiplier))
+}
@@ -0,0 +1,161 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDueDateFor(t *testing.T) {
+	due := time.Date(2024, 5, 1, 23, 59, 0, 0, time.UTC)
+	extended := due.Add(72 * time.Hour)
+
+	tests := []struct {
+		name string
+		due  time.Time
+		ext  *Extension
+		acc  *Accommodation
+		want time.Time
+	}{
+		{name: "no changes", due: due, want: due},
+		{
+			name: "accommodation",
+			due:  due,
+			acc:  &Accommodation{ExtraTime: 48 * 3600},
+			want: due.Add(48 * time.Hour),
+		},
+		{
+			name: "extension",
+			due:  due,
+			ext:  &Extension{DueDate: extended},
+			want: extended,
+		},
+		{
+			name: "extension replaces accommodation",
+			due:  due,
+			ext:  &Extension{DueDate: extended},
+			acc:  &Accommodation{ExtraTime: 48 * 3600},
+			want: extended,
+		},
+		{
+			name: "no due date stays without one",
+			acc:  &Accommodation{ExtraTime: 48 * 3600},
+		},
+		{
+			name: "extension gives a due date",
+			ext:  &Extension{DueDate: extended},
+			want: extended,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				if got := DueDateFor(tt.due, tt.ext, tt.acc); !got.Equal(tt.want) {
+					t.Errorf("got %v, want %v", got, tt.want)
+				}
+			},
+		)
+	}
+}
+
+func TestTimeLimitFor(t *testing.T) {
+	tests := []struct {
+		name  string
+		limit int
+		acc   *Accommodation
+		want  int
+	}{
+		{name: "no accommodation", limit: 600, want: 600},
+		{name: "no multiplier", limit: 600, acc: &Accommodation{ExtraTime: 3600}, want: 600},
+		{name: "time and a half", limit: 600, acc: &Accommodation{TimeMultiplier: 1.5}, want: 900},
+		{name: "rounds up", limit: 7, acc: &Accommodation{TimeMultiplier: 1.5}, want: 11},
+		{name: "no limit", limit: 0, acc: &Accommodation{TimeMultiplier: 2}, want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				if got := TimeLimitFor(tt.limit, tt.acc); got != tt.want {
+					t.Errorf("got %d, want %d", got, tt.want)
+				}
+			},
+		)
+	}
+}
+
+func TestAssignment_For(t *testing.T) {
+	due := time.Date(2024, 5, 1, 23, 59, 0, 0, time.UTC)
+	a := &Assignment{
+		DueDate:    due,
+		LatePolicy: &LatePolicy{HardCutoff: true},
+	}
+
+	personal := a.For(nil, &Accommodation{ExtraTime: 3600})
+
+	if !a.DueDate.Equal(due) || a.ClassDueDate != nil {
+		t.Errorf("the class's assignment was changed")
+	}
+
+	if personal.ClassDueDate == nil || !personal.ClassDueDate.Equal(due) {
+		t.Errorf("got class due date %v, want %v", personal.ClassDueDate, due)
+	}
+
+	// The hard cutoff is an hour later for the student.
+	at := due.Add(30 * time.Minute)
+
+	if a.Accepts(at) || !personal.Accepts(at) {
+		t.Errorf("got class %v, student %v, want false, true", a.Accepts(at), personal.Accepts(at))
+	}
+
+	if same := a.For(nil, nil); same.ClassDueDate != nil {
+		t.Errorf("got class due date %v, want none when nothing changes", same.ClassDueDate)
+	}
+}
+
+func TestExtension_Valid(t *testing.T) {
+	tests := []struct {
+		name  string
+		ext   Extension
+		valid bool
+	}{
+		{name: "valid", ext: Extension{NetId: "stu1", DueDate: time.Now()}, valid: true},
+		{name: "no student", ext: Extension{DueDate: time.Now()}},
+		{name: "no due date", ext: Extension{NetId: "stu1"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				errs := tt.ext.Valid()
+				if (len(errs) == 0) != tt.valid {
+					t.Errorf("got %v, want valid %v", errs, tt.valid)
+				}
+			},
+		)
+	}
+}
+
+func TestAccommodation_Valid(t *testing.T) {
+	tests := []struct {
+		name  string
+		acc   Accommodation
+		valid bool
+	}{
+		{name: "extra time", acc: Accommodation{NetId: "stu1", ExtraTime: 3600}, valid: true},
+		{name: "time multiplier", acc: Accommodation{NetId: "stu1", TimeMultiplier: 1.5}, valid: true},
+		{name: "no student", acc: Accommodation{ExtraTime: 3600}},
+		{name: "negative extra time", acc: Accommodation{NetId: "stu1", ExtraTime: -1}},
+		{name: "multiplier under 1", acc: Accommodation{NetId: "stu1", TimeMultiplier: 0.5}},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				errs := tt.acc.Valid()
+				if (len(errs) == 0) != tt.valid {
+					t.Errorf("got %v, want valid %v", errs, tt.valid)
+				}
+			},
+		)
+	}
+}
@@ -0,0 +1,72 @@
+package models
+
+import "math"
+
+// Filters are the pagination parameters of a request for a list.
+type Filters struct {
+	Page     int
+	PageSize int
+}
+
+const (
+	DefaultPage     = 1
+	DefaultPageSize = 20
+	MaxPage         = 10_000
+	MaxPageSize     = 100
+)
+
+// NewFilters returns filters for the first page, at the default
+// page size.
+func NewFilters() Filters {
+	return Filters{Page: DefaultPage, PageSize: DefaultPageSize}
+}
+
+// Valid reports problems with the filters, keyed by parameter name.
+// An empty map means the filters are valid.
+func (f Filters) Valid() map[string]string {
+	errs := make(map[string]string)
+
+	if f.Page < 1 || f.Page > MaxPage {
+		errs["page"] = "must be between 1 and 10000"
+	}
+
+	if f.PageSize < 1 || f.PageSize > MaxPageSize {
+		errs["page_size"] = "must be between 1 and 100"
+	}
+
+	return errs
+}
+
+func (f Filters) Limit() int {
+	return f.PageSize
+}
+
+func (f Filters) Offset() int {
+	return (f.Page - 1) * f.PageSize
+}
+
+// Metadata describes a page of results, so that a client knows
+// which pages exist.
+type Metadata struct {
+	CurrentPage  int `json:"current_page,omitempty"`
+	PageSize     int `json:"page_size,omitempty"`
+	FirstPage    int `json:"first_page,omitempty"`
+	LastPage     int `json:"last_page,omitempty"`
+	TotalRecords int `json:"total_records,omitempty"`
+}
+
+// NewMetadata calculates the metadata of a page of results out of
+// totalRecords. Empty results have empty metadata.
+func NewMetadata(totalRecords int, f Filters) Metadata {
+	if totalRecords == 0 {
+		return Metadata{}
+	}
+
+	return Metadata{
+		CurrentPage:  f.Page,
+		PageSize:     f.PageSize,
+		FirstPage:    1,
+		LastPage:     int(math.Ceil(float64(totalRecords) / float64(f.PageSize))),
+		TotalRecords: totalRecords,
+	}
+}
@@ -0,0 +1,71 @@
+package models
+
+import "testing"
+
+func TestNewMetadata(t *testing.T) {
+	tests := []struct {
+		name  string
+		total int
+		f     Filters
+		want  Metadata
+	}{
+		{
+			name:  "empty",
+			total: 0,
+			f:     NewFilters(),
+			want:  Metadata{},
+		},
+		{
+			name:  "partial last page",
+			total: 45,
+			f:     Filters{Page: 2, PageSize: 20},
+			want: Metadata{
+				CurrentPage:  2,
+				PageSize:     20,
+				FirstPage:    1,
+				LastPage:     3,
+				TotalRecords: 45,
+			},
+		},
+		{
+			name:  "full last page",
+			total: 40,
+			f:     Filters{Page: 1, PageSize: 20},
+			want: Metadata{
+				CurrentPage:  1,
+				PageSize:     20,
+				FirstPage:    1,
+				LastPage:     2,
+				TotalRecords: 40,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				got := NewMetadata(tt.total, tt.f)
+				if got != tt.want {
+					t.Errorf("got %+v, want %+v", got, tt.want)
+				}
+			},
+		)
+	}
+}
+
+func TestFilters(t *testing.T) {
+	f := Filters{Page: 3, PageSize: 10}
+
+	if f.Limit() != 10 || f.Offset() != 20 {
+		t.Errorf("got limit %d offset %d, want 10 and 20", f.Limit(), f.Offset())
+	}
+
+	if errs := f.Valid(); len(errs) != 0 {
+		t.Errorf("got %v, want no errors", errs)
+	}
+
+	errs := Filters{Page: 0, PageSize: 101}.Valid()
+	if errs["page"] == "" || errs["page_size"] == "" {
+		t.Errorf("got %v, want errors for page and page_size", errs)
+	}
+}
@@ -0,0 +1,349 @@
+package models
+
+import (
+	"math"
+	"slices"
+)
+
+// GradeCategory groups a course's assignments, such as homework or
+// exams, so that they count towards the course grade by weight.
+type GradeCategory struct {
+	Entity
+	CourseId string `json:"course_id"`
+	Name     string `json:"name"`
+
+	// Weight is how much the category counts towards the course
+	// grade, relative to the other categories. Weights do not need to
+	// add up to 100.
+	Weight float64 `json:"weight"`
+
+	// DropLowest is how many of the category's lowest scores are left
+	// out of its grade.
+	DropLowest int `json:"drop_lowest"`
+}
+
+// Valid checks a category, returning problems keyed by field.
+func (c *GradeCategory) Valid() map[string]string {
+	errs := make(map[string]string)
+
+	if c.Name == "" {
+		errs["name"] = "must be provided"
+	}
+
+	if c.Weight < 0 {
+		errs["weight"] = "must not be negative"
+	}
+
+	if c.DropLowest < 0 {
+		errs["drop_lowest"] = "must not be negative"
+	}
+
+	return errs
+}
+
+// GradeItem is an assignment as it counts in the gradebook.
+// Assignments without a category do not count towards the course
+// grade.
+type GradeItem struct {
+	AssignmentId string `json:"assignment_id"`
+	Title        string `json:"title"`
+	CategoryId   string `json:"category_id,omitempty"`
+
+	// Points is what the assignment is graded out of.
+	Points float64 `json:"points"`
+
+	// ExtraCredit items add to their category's points earned, but
+	// not to its points possible, and are never dropped.
+	ExtraCredit bool `json:"extra_credit"`
+}
+
+// Valid checks an item, returning problems keyed by field.
+func (i *GradeItem) Valid() map[string]string {
+	errs := make(map[string]string)
+
+	if i.Points < 0 {
+		errs["points"] = "must not be negative"
+	}
+
+	if i.Points == 0 && !i.ExtraCredit {
+		errs["points"] = "must be more than zero unless the item is extra credit"
+	}
+
+	return errs
+}
+
+// LetterGrade is the lowest percentage that earns a letter.
+type LetterGrade struct {
+	Letter string  `json:"letter"`
+	Min    float64 `json:"min"`
+}
+
+// GradeScheme maps course percentages to letter grades.
+type GradeScheme []LetterGrade
+
+// DefaultGradeScheme is the scheme used by courses that have not set
+// their own.
+func DefaultGradeScheme() GradeScheme {
+	return GradeScheme{
+		{Letter: "A", Min: 93},
+		{Letter: "A-", Min: 90},
+		{Letter: "B+", Min: 87},
+		{Letter: "B", Min: 83},
+		{Letter: "B-", Min: 80},
+		{Letter: "C+", Min: 77},
+		{Letter: "C", Min: 73},
+		{Letter: "C-", Min: 70},
+		{Letter: "D+", Min: 67},
+		{Letter: "D", Min: 60},
+		{Letter: "F", Min: 0},
+	}
+}
+
+// Valid checks that a scheme's letters and minimums are distinct and
+// that every percentage earns a letter.
+func (gs GradeScheme) Valid() map[string]string {
+	errs := make(map[string]string)
+
+	if len(gs) == 0 {
+		errs["scheme"] = "must have at least one letter"
+		return errs
+	}
+
+	letters := make(map[string]bool, len(gs))
+	mins := make(map[float64]bool, len(gs))
+	var floor bool
+
+	for _, lg := range gs {
+		if lg.Letter == "" {
+			errs["scheme"] = "letters must not be empty"
+		}
+
+		if letters[lg.Letter] || mins[lg.Min] {
+			errs["scheme"] = "letters and minimums must be unique"
+		}
+
+		if lg.Min < 0 {
+			errs["scheme"] = "minimums must not be negative"
+		}
+
+		letters[lg.Letter] = true
+		mins[lg.Min] = true
+		floor = floor || lg.Min == 0
+	}
+
+	if !floor {
+		errs["scheme"] = "must have a letter with a minimum of 0"
+	}
+
+	return errs
+}
+
+// Letter is the letter earned by a percentage.
+func (gs GradeScheme) Letter(percent float64) string {
+	var letter string
+	best := math.Inf(-1)
+
+	for _, lg := range gs {
+		if percent >= lg.Min && lg.Min > best {
+			letter = lg.Letter
+			best = lg.Min
+		}
+	}
+
+	return letter
+}
+
+// Gradebook is a course's grading setup, along with the grades of
+// its students.
+type Gradebook struct {
+	CourseId   string           `json:"course_id"`
+	Categories []*GradeCategory `json:"categories"`
+	Items      []*GradeItem     `json:"items"`
+	Scheme     GradeScheme      `json:"scheme"`
+	Students   []*StudentGrade  `json:"students"`
+}
+
+// StudentGrade is a student's standing in a course. Running grades
+// only count graded work, while final grades count work that has not
+// been graded as zero. Percentages are nil when nothing counts
+// towards them yet.
+type StudentGrade struct {
+	NetId    string `json:"netid"`
+	FullName string `json:"full_name,omitempty"`
+
+	// Scores are the points given for each graded assignment, keyed
+	// by assignment ID.
+	Scores map[string]float64 `json:"scores"`
+
+	Categories []*CategoryGrade `json:"categories"`
+
+	Running       *float64 `json:"running"`
+	RunningLetter string   `json:"running_letter,omitempty"`
+	Final         *float64 `json:"final"`
+	FinalLetter   string   `json:"final_letter,omitempty"`
+}
+
+// CategoryGrade is a student's percentage in one category.
+type CategoryGrade struct {
+	CategoryId string   `json:"category_id"`
+	Name       string   `json:"name"`
+	Weight     float64  `json:"weight"`
+	Running    *float64 `json:"running"`
+	Final      *float64 `json:"final"`
+
+	// Dropped are the assignments left out of the final percentage.
+	Dropped []string `json:"dropped,omitempty"`
+}
+
+// Grade works out a student's grades from the points they were given
+// for each graded assignment, keyed by assignment ID.
+func (g *Gradebook) Grade(netId string, scores map[string]float64) *StudentGrade {
+	sg := &StudentGrade{
+		NetId:  netId,
+		Scores: scores,
+	}
+
+	var running, final weightedSum
+
+	for _, c := range g.Categories {
+		var items []*GradeItem
+		for _, item := range g.Items {
+			if item.CategoryId == c.ID {
+				items = append(items, item)
+			}
+		}
+
+		cg := &CategoryGrade{
+			CategoryId: c.ID,
+			Name:       c.Name,
+			Weight:     c.Weight,
+		}
+
+		cg.Running, _ = categoryPercent(items, scores, c.DropLowest, false)
+		cg.Final, cg.Dropped = categoryPercent(items, scores, c.DropLowest, true)
+
+		running.add(cg.Running, c.Weight)
+		final.add(cg.Final, c.Weight)
+
+		sg.Categories = append(sg.Categories, cg)
+	}
+
+	sg.Running = running.percent()
+	sg.Final = final.percent()
+
+	if sg.Running != nil {
+		sg.RunningLetter = g.Scheme.Letter(*sg.Running)
+	}
+
+	if sg.Final != nil {
+		sg.FinalLetter = g.Scheme.Letter(*sg.Final)
+	}
+
+	return sg
+}
+
+// categoryPercent works out the percentage earned on a category's
+// items, after dropping the lowest scores. When missing is true,
+// items that have not been graded count as zero, otherwise they are
+// left out. The IDs of the dropped items are returned along with the
+// percentage, which is nil when there are no points possible.
+func categoryPercent(
+	items []*GradeItem,
+	scores map[string]float64,
+	drop int,
+	missing bool,
+) (*float64, []string) {
+	type counted struct {
+		item   *GradeItem
+		earned float64
+	}
+
+	var regular []counted
+	var extra float64
+
+	for _, item := range items {
+		earned, graded := scores[item.AssignmentId]
+
+		if item.ExtraCredit {
+			extra += earned
+			continue
+		}
+
+		if !graded && !missing {
+			continue
+		}
+
+		regular = append(regular, counted{item: item, earned: earned})
+	}
+
+	// Always keep at least one score, so that dropping cannot leave
+	// a category with nothing in it.
+	drop = min(drop, len(regular)-1)
+
+	var dropped []string
+
+	if drop > 0 {
+		slices.SortStableFunc(
+			regular, func(a, b counted) int {
+				ra := a.earned / a.item.Points
+				rb := b.earned / b.item.Points
+				switch {
+				case ra < rb:
+					return -1
+				case ra > rb:
+					return 1
+				default:
+					return 0
+				}
+			},
+		)
+
+		for _, c := range regular[:drop] {
+			dropped = append(dropped, c.item.AssignmentId)
+		}
+
+		regular = regular[drop:]
+	}
+
+	var earned, possible float64
+	for _, c := range regular {
+		earned += c.earned
+		possible += c.item.Points
+	}
+
+	if possible == 0 {
+		return nil, nil
+	}
+
+	percent := roundPercent((earned + extra) / possible * 100)
+
+	return &percent, dropped
+}
+
+// weightedSum is a running weighted average of category percentages.
+// Categories without a percentage are left out, so that the weights
+// of the rest are scaled up to make up for them.
+type weightedSum struct {
+	total, weights float64
+}
+
+func (w *weightedSum) add(percent *float64, weight float64) {
+	if percent == nil || weight == 0 {
+		return
+	}
+	w.total += *percent * weight
+	w.weights += weight
+}
+
+func (w *weightedSum) percent() *float64 {
+	if w.weights == 0 {
+		return nil
+	}
+	p := roundPercent(w.total / w.weights)
+	return &p
+}
+
+// roundPercent rounds a percentage to two decimal places.
+func roundPercent(p float64) float64 {
+	return math.Round(p*100) / 100
+}
@@ -0,0 +1,206 @@
+package models
+
+import (
+	"slices"
+	"testing"
+)
+
+// newTestGradebook has homework worth 40 with its lowest score
+// dropped, and exams worth 60. Each homework is out of 10 points and
+// each exam out of 100, with "bonus" as a 5 point extra credit exam.
+func newTestGradebook() *Gradebook {
+	return &Gradebook{
+		Categories: []*GradeCategory{
+			{Entity: Entity{ID: "hw"}, Name: "Homework", Weight: 40, DropLowest: 1},
+			{Entity: Entity{ID: "exams"}, Name: "Exams", Weight: 60},
+		},
+		Items: []*GradeItem{
+			{AssignmentId: "hw1", CategoryId: "hw", Points: 10},
+			{AssignmentId: "hw2", CategoryId: "hw", Points: 10},
+			{AssignmentId: "hw3", CategoryId: "hw", Points: 10},
+			{AssignmentId: "midterm", CategoryId: "exams", Points: 100},
+			{AssignmentId: "final", CategoryId: "exams", Points: 100},
+			{AssignmentId: "bonus", CategoryId: "exams", Points: 5, ExtraCredit: true},
+			{AssignmentId: "survey", Points: 1},
+		},
+		Scheme: DefaultGradeScheme(),
+	}
+}
+
+func TestGradebook_Grade(t *testing.T) {
+	tests := []struct {
+		name          string
+		scores        map[string]float64
+		running       *float64
+		runningLetter string
+		final         *float64
+		finalLetter   string
+		dropped       []string
+	}{
+		{
+			name:   "nothing graded",
+			scores: map[string]float64{},
+			// Every homework counts as zero, so dropping one leaves
+			// two zeros, and both exams are zeros.
+			final:       ptr(0),
+			finalLetter: "F",
+			dropped:     []string{"hw1"},
+		},
+		{
+			name: "running grade only counts graded work",
+			scores: map[string]float64{
+				"hw1":     10,
+				"midterm": 80,
+			},
+			// Homework 100% at 40, exams 80% at 60.
+			running:       ptr(88),
+			runningLetter: "B+",
+			// Homework drops one zero, leaving 10/20, exams 80/200.
+			final:       ptr(44),
+			finalLetter: "F",
+			dropped:     []string{"hw2"},
+		},
+		{
+			name: "lowest homework is dropped",
+			scores: map[string]float64{
+				"hw1":     2,
+				"hw2":     9,
+				"hw3":     10,
+				"midterm": 90,
+				"final":   94,
+			},
+			// Homework 19/20 is 95%, exams 184/200 is 92%.
+			running:       ptr(93.2),
+			runningLetter: "A",
+			final:         ptr(93.2),
+			finalLetter:   "A",
+			dropped:       []string{"hw1"},
+		},
+		{
+			name: "extra credit adds to earned points only",
+			scores: map[string]float64{
+				"hw1":     10,
+				"hw2":     10,
+				"hw3":     10,
+				"midterm": 88,
+				"final":   90,
+				"bonus":   4,
+			},
+			// Exams (178 + 4) / 200 is 91%.
+			running:       ptr(94.6),
+			runningLetter: "A",
+			final:         ptr(94.6),
+			finalLetter:   "A",
+			dropped:       []string{"hw1"},
+		},
+		{
+			name: "uncategorized work does not count",
+			scores: map[string]float64{
+				"survey":  0,
+				"midterm": 75,
+			},
+			running:       ptr(75),
+			runningLetter: "C",
+			final:         ptr(22.5),
+			finalLetter:   "F",
+			dropped:       []string{"hw1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				sg := newTestGradebook().Grade("stu1", tt.scores)
+
+				if !samePercent(sg.Running, tt.running) || sg.RunningLetter != tt.runningLetter {
+					t.Errorf(
+						"got running %v %q, want %v %q",
+						fmtPercent(sg.Running),
+						sg.RunningLetter,
+						fmtPercent(tt.running),
+						tt.runningLetter,
+					)
+				}
+
+				if !samePercent(sg.Final, tt.final) || sg.FinalLetter != tt.finalLetter {
+					t.Errorf(
+						"got final %v %q, want %v %q",
+						fmtPercent(sg.Final),
+						sg.FinalLetter,
+						fmtPercent(tt.final),
+						tt.finalLetter,
+					)
+				}
+
+				if got := sg.Categories[0].Dropped; !slices.Equal(got, tt.dropped) {
+					t.Errorf("got dropped %v, want %v", got, tt.dropped)
+				}
+			},
+		)
+	}
+}
+
+func TestGradeScheme_Letter(t *testing.T) {
+	scheme := DefaultGradeScheme()
+
+	tests := []struct {
+		percent float64
+		want    string
+	}{
+		{percent: 104, want: "A"},
+		{percent: 93, want: "A"},
+		{percent: 92.99, want: "A-"},
+		{percent: 60, want: "D"},
+		{percent: 0, want: "F"},
+	}
+
+	for _, tt := range tests {
+		if got := scheme.Letter(tt.percent); got != tt.want {
+			t.Errorf("%v: got %q, want %q", tt.percent, got, tt.want)
+		}
+	}
+}
+
+func TestGradeScheme_Valid(t *testing.T) {
+	tests := []struct {
+		name   string
+		scheme GradeScheme
+		valid  bool
+	}{
+		{name: "default", scheme: DefaultGradeScheme(), valid: true},
+		{name: "pass fail", scheme: GradeScheme{{"P", 65}, {"F", 0}}, valid: true},
+		{name: "empty", scheme: GradeScheme{}},
+		{name: "no floor", scheme: GradeScheme{{"P", 65}, {"F", 10}}},
+		{name: "duplicate letter", scheme: GradeScheme{{"P", 65}, {"P", 0}}},
+		{name: "duplicate minimum", scheme: GradeScheme{{"P", 0}, {"F", 0}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				errs := tt.scheme.Valid()
+				if (len(errs) == 0) != tt.valid {
+					t.Errorf("got %v, want valid %v", errs, tt.valid)
+				}
+			},
+		)
+	}
+}
+
+func ptr(f float64) *float64 {
+	return &f
+}
+
+func samePercent(a, b *float64) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+func fmtPercent(p *float64) any {
+	if p == nil {
+		return "nil"
+	}
+	return *p
+}
@@ -0,0 +1,159 @@
+package models
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// LateInterval is the unit of lateness that late deductions are
+// charged per.
+type LateInterval string
+
+const (
+	PER_HOUR LateInterval = "hour"
+	PER_DAY  LateInterval = "day"
+)
+
+// Duration is the length of the interval.
+func (li LateInterval) Duration() time.Duration {
+	switch li {
+	case PER_HOUR:
+		return time.Hour
+	case PER_DAY:
+		return 24 * time.Hour
+	default:
+		return 0
+	}
+}
+
+// LatePolicy decides how an assignment treats submissions made after
+// its due date. Assignments without a policy accept late submissions
+// without any penalty.
+type LatePolicy struct {
+	AssignmentId string `json:"assignment_id"`
+
+	// GracePeriod is how many seconds after the due date submissions
+	// are still on time.
+	GracePeriod int `json:"grace_period"`
+
+	// HardCutoff refuses any submission made after the grace period.
+	HardCutoff bool `json:"hard_cutoff"`
+
+	// Deduction is the percentage of the grade taken off for each
+	// Interval, or part of one, that a submission is late.
+	Deduction float64      `json:"deduction"`
+	Interval  LateInterval `json:"interval,omitempty"`
+
+	// MaxLateness is how many seconds late a submission may be before
+	// it is refused. Zero means there is no limit.
+	MaxLateness int `json:"max_lateness"`
+}
+
+// Valid checks a late policy, returning problems keyed by field.
+func (p *LatePolicy) Valid() map[string]string {
+	errs := make(map[string]string)
+
+	if p.GracePeriod < 0 {
+		errs["grace_period"] = "must not be negative"
+	}
+
+	if p.MaxLateness < 0 {
+		errs["max_lateness"] = "must not be negative"
+	}
+
+	if p.Deduction < 0 || p.Deduction > 100 {
+		errs["deduction"] = "must be between 0 and 100"
+	}
+
+	if p.Deduction > 0 && p.Interval.Duration() == 0 {
+		errs["interval"] = fmt.Sprintf("must be %s or %s", PER_HOUR, PER_DAY)
+	}
+
+	return errs
+}
+
+// Lateness is how long after a due date, and after the policy's
+// grace period, a submission was made. It is zero for submissions
+// that are on time, and for assignments without a due date. Times are
+// compared as instants, so the time zones they are in do not matter.
+func (p *LatePolicy) Lateness(due, at time.Time) time.Duration {
+	if due.IsZero() {
+		return 0
+	}
+
+	if p != nil {
+		due = due.Add(time.Duration(p.GracePeriod) * time.Second)
+	}
+
+	return max(at.Sub(due), 0)
+}
+
+// Accepts checks if a submission made at a time is allowed.
+func (p *LatePolicy) Accepts(due, at time.Time) bool {
+	if p == nil {
+		return true
+	}
+
+	late := p.Lateness(due, at)
+
+	if late > 0 && p.HardCutoff {
+		return false
+	}
+
+	if p.MaxLateness > 0 && late > time.Duration(p.MaxLateness)*time.Second {
+		return false
+	}
+
+	return true
+}
+
+// Penalty is the percentage taken off the grade of a submission made
+// at a time. Every started interval of lateness is charged in full,
+// and the penalty is never more than 100.
+func (p *LatePolicy) Penalty(due, at time.Time) float64 {
+	if p == nil || p.Deduction == 0 {
+		return 0
+	}
+
+	interval := p.Interval.Duration()
+	if interval == 0 {
+		return 0
+	}
+
+	late := p.Lateness(due, at)
+	intervals := math.Ceil(float64(late) / float64(interval))
+
+	return min(intervals*p.Deduction, 100)
+}
+
+// ParseDueDate reads a due date sent by a client. Full RFC 3339 times
+// carry their own offset. Plain dates, such as "2024-05-01", are due at
+// the very end of that day in the named time zone, or in UTC when no
+// zone is named. Due dates are returned in UTC, which is how they are
+// stored.
+func ParseDueDate(value, zone string) (time.Time, error) {
+	if due, err := time.Parse(time.RFC3339, value); err == nil {
+		return due.UTC(), nil
+	}
+
+	loc := time.UTC
+
+	if zone != "" {
+		var err error
+		loc, err = time.LoadLocation(zone)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("unknown time zone %q", zone)
+		}
+	}
+
+	day, err := time.ParseInLocation(time.DateOnly, value, loc)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	// The last microsecond of the day, which is as precise as the
+	// database keeps times, so that anything submitted on the due date
+	// is on time.
+	return day.AddDate(0, 0, 1).Add(-time.Microsecond).UTC(), nil
+}
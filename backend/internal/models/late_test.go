@@ -0,0 +1,303 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLatePolicy_Lateness(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Due at 11:59 PM in New York, which is 03:59 UTC the next day.
+	due := time.Date(2024, 5, 1, 23, 59, 0, 0, ny)
+
+	tests := []struct {
+		name   string
+		policy *LatePolicy
+		due    time.Time
+		at     time.Time
+		want   time.Duration
+	}{
+		{
+			name: "on time in another time zone",
+			due:  due,
+			at:   time.Date(2024, 5, 2, 3, 58, 0, 0, time.UTC),
+		},
+		{
+			name: "late in another time zone",
+			due:  due,
+			at:   time.Date(2024, 5, 2, 13, 59, 0, 0, tokyo),
+			want: 1 * time.Hour,
+		},
+		{
+			name: "exactly at the due date",
+			due:  due,
+			at:   due.UTC(),
+		},
+		{
+			name:   "within the grace period",
+			policy: &LatePolicy{GracePeriod: 900},
+			due:    due,
+			at:     due.Add(10 * time.Minute),
+		},
+		{
+			name:   "after the grace period",
+			policy: &LatePolicy{GracePeriod: 900},
+			due:    due,
+			at:     due.Add(20 * time.Minute),
+			want:   5 * time.Minute,
+		},
+		{
+			name: "no due date",
+			at:   due,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				got := tt.policy.Lateness(tt.due, tt.at)
+				if got != tt.want {
+					t.Errorf("got %v, want %v", got, tt.want)
+				}
+			},
+		)
+	}
+}
+
+func TestLatePolicy_Accepts(t *testing.T) {
+	due := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		policy *LatePolicy
+		late   time.Duration
+		want   bool
+	}{
+		{name: "no policy", late: 1000 * time.Hour, want: true},
+		{name: "hard cutoff on time", policy: &LatePolicy{HardCutoff: true}, want: true},
+		{name: "hard cutoff late", policy: &LatePolicy{HardCutoff: true}, late: time.Second},
+		{
+			name:   "hard cutoff within grace",
+			policy: &LatePolicy{HardCutoff: true, GracePeriod: 60},
+			late:   time.Minute,
+			want:   true,
+		},
+		{
+			name:   "within max lateness",
+			policy: &LatePolicy{MaxLateness: 3600},
+			late:   time.Hour,
+			want:   true,
+		},
+		{
+			name:   "past max lateness",
+			policy: &LatePolicy{MaxLateness: 3600},
+			late:   time.Hour + time.Second,
+		},
+		{
+			name:   "max lateness counts from the grace period",
+			policy: &LatePolicy{MaxLateness: 3600, GracePeriod: 600},
+			late:   time.Hour + 5*time.Minute,
+			want:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				if got := tt.policy.Accepts(due, due.Add(tt.late)); got != tt.want {
+					t.Errorf("got %v, want %v", got, tt.want)
+				}
+			},
+		)
+	}
+}
+
+func TestLatePolicy_Penalty(t *testing.T) {
+	due := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	hourly := &LatePolicy{Deduction: 2, Interval: PER_HOUR}
+	daily := &LatePolicy{Deduction: 10, Interval: PER_DAY, GracePeriod: 3600}
+
+	tests := []struct {
+		name   string
+		policy *LatePolicy
+		late   time.Duration
+		want   float64
+	}{
+		{name: "no policy", late: 48 * time.Hour, want: 0},
+		{name: "on time", policy: hourly, late: 0, want: 0},
+		{name: "part of an hour", policy: hourly, late: time.Minute, want: 2},
+		{name: "exactly an hour", policy: hourly, late: time.Hour, want: 2},
+		{name: "just over an hour", policy: hourly, late: time.Hour + time.Second, want: 4},
+		{name: "within grace", policy: daily, late: time.Hour, want: 0},
+		{name: "first day after grace", policy: daily, late: 2 * time.Hour, want: 10},
+		{name: "third day", policy: daily, late: 49*time.Hour + time.Minute, want: 30},
+		{name: "capped at 100", policy: daily, late: 30 * 24 * time.Hour, want: 100},
+		{
+			name:   "no deduction",
+			policy: &LatePolicy{HardCutoff: true},
+			late:   time.Hour,
+			want:   0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				if got := tt.policy.Penalty(due, due.Add(tt.late)); got != tt.want {
+					t.Errorf("got %v, want %v", got, tt.want)
+				}
+			},
+		)
+	}
+}
+
+func TestLatePolicy_Valid(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy LatePolicy
+		valid  bool
+	}{
+		{name: "empty", valid: true},
+		{name: "hourly", policy: LatePolicy{Deduction: 5, Interval: PER_HOUR}, valid: true},
+		{name: "deduction without interval", policy: LatePolicy{Deduction: 5}},
+		{name: "unknown interval", policy: LatePolicy{Deduction: 5, Interval: "week"}},
+		{name: "deduction over 100", policy: LatePolicy{Deduction: 101, Interval: PER_DAY}},
+		{name: "negative grace", policy: LatePolicy{GracePeriod: -1}},
+		{name: "negative max lateness", policy: LatePolicy{MaxLateness: -1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				errs := tt.policy.Valid()
+				if (len(errs) == 0) != tt.valid {
+					t.Errorf("got %v, want valid %v", errs, tt.valid)
+				}
+			},
+		)
+	}
+}
+
+func TestSubmission_SetGrade(t *testing.T) {
+	due := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	a := &Assignment{
+		DueDate:    due,
+		LatePolicy: &LatePolicy{Deduction: 15, Interval: PER_DAY},
+	}
+
+	tests := []struct {
+		name    string
+		late    time.Duration
+		raw     float64
+		penalty float64
+		want    float64
+	}{
+		{name: "on time", raw: 87.5, want: 87.5},
+		{name: "one day late", late: time.Hour, raw: 87.5, penalty: 15, want: 74.38},
+		{name: "zero stays zero", late: 100 * time.Hour, raw: 0, penalty: 75, want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				s := &Submission{SubmissionTime: due.Add(tt.late)}
+				s.SetGrade(tt.raw, a)
+
+				if s.RawGrade != tt.raw || s.LatePenalty != tt.penalty || s.Grade != tt.want {
+					t.Errorf(
+						"got raw %v, penalty %v, grade %v, want %v, %v, %v",
+						s.RawGrade,
+						s.LatePenalty,
+						s.Grade,
+						tt.raw,
+						tt.penalty,
+						tt.want,
+					)
+				}
+			},
+		)
+	}
+}
+
+func TestSubmission_IsOnTime(t *testing.T) {
+	due := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		at   time.Time
+		want bool
+	}{
+		{name: "early", at: due.Add(-time.Minute), want: true},
+		{name: "exactly due", at: due, want: true},
+		{name: "late", at: due.Add(time.Second)},
+	}
+
+	for _, tt := range tests {
+		s := &Submission{SubmissionTime: tt.at}
+		if got := s.IsOnTime(due); got != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestParseDueDate(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		zone  string
+		want  time.Time
+		err   bool
+	}{
+		{
+			name:  "date in UTC",
+			value: "2024-05-01",
+			want:  time.Date(2024, 5, 1, 23, 59, 59, 999999000, time.UTC),
+		},
+		{
+			name:  "date in New York",
+			value: "2024-05-01",
+			zone:  "America/New_York",
+			want:  time.Date(2024, 5, 2, 3, 59, 59, 999999000, time.UTC),
+		},
+		{
+			name:  "date in New York in winter",
+			value: "2024-01-15",
+			zone:  "America/New_York",
+			want:  time.Date(2024, 1, 16, 4, 59, 59, 999999000, time.UTC),
+		},
+		{
+			name:  "full time ignores the zone",
+			value: "2024-05-01T17:00:00+09:00",
+			zone:  "America/New_York",
+			want:  time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC),
+		},
+		{name: "unknown zone", value: "2024-05-01", zone: "Mars/Olympus", err: true},
+		{name: "not a date", value: "May 1st", err: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				got, err := ParseDueDate(tt.value, tt.zone)
+				if (err != nil) != tt.err {
+					t.Fatalf("got error %v, want error %v", err, tt.err)
+				}
+
+				if !got.Equal(tt.want) || (!tt.err && got.Location() != time.UTC) {
+					t.Errorf("got %v, want %v", got, tt.want)
+				}
+			},
+		)
+	}
+}
@@ -0,0 +1,138 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// AssignmentState is where an assignment is in its lifecycle.
+type AssignmentState string
+
+const (
+	// ASSIGNMENT_DRAFT assignments are still being written, and are
+	// only seen by the course's teachers.
+	ASSIGNMENT_DRAFT AssignmentState = "draft"
+
+	// ASSIGNMENT_PUBLISHED assignments are seen by the course's
+	// students, and take submissions while they are available.
+	ASSIGNMENT_PUBLISHED AssignmentState = "published"
+
+	// ASSIGNMENT_LOCKED assignments are still seen by the course's
+	// students, but take no more submissions.
+	ASSIGNMENT_LOCKED AssignmentState = "locked"
+)
+
+// Schedule is an assignment's state, and the times it moves between
+// them.
+type Schedule struct {
+	// State is where the assignment is in its lifecycle.
+	State AssignmentState `json:"state"`
+
+	// PublishAt is when a draft is scheduled to be published, or when
+	// it was. LockAt is when the assignment is scheduled to be locked.
+	PublishAt *time.Time `json:"publish_at,omitempty"`
+	LockAt    *time.Time `json:"lock_at,omitempty"`
+
+	// AvailableFrom is when a published assignment starts to take
+	// submissions. It is nil when it takes them as soon as it is
+	// published.
+	AvailableFrom *time.Time `json:"available_from,omitempty"`
+}
+
+// Valid checks if the state is one an assignment can be in.
+func (s AssignmentState) Valid() bool {
+	switch s {
+	case ASSIGNMENT_DRAFT, ASSIGNMENT_PUBLISHED, ASSIGNMENT_LOCKED:
+		return true
+	}
+	return false
+}
+
+// IsDraft checks if an assignment is a draft. Assignments made before
+// they had a state are published.
+func (a *Assignment) IsDraft() bool {
+	return a.State == ASSIGNMENT_DRAFT
+}
+
+// PublishDue checks if a draft is scheduled to be published by a time.
+func (a *Assignment) PublishDue(now time.Time) bool {
+	return a.IsDraft() && a.PublishAt != nil && !now.Before(*a.PublishAt)
+}
+
+// LockDue checks if a published assignment is scheduled to be locked
+// by a time.
+func (a *Assignment) LockDue(now time.Time) bool {
+	return !a.IsDraft() && a.State != ASSIGNMENT_LOCKED &&
+		a.LockAt != nil && !now.Before(*a.LockAt)
+}
+
+// Opened checks if the assignment was taking submissions by a time,
+// being published and past the time it is available from.
+func (a *Assignment) Opened(at time.Time) bool {
+	if a.IsDraft() {
+		return false
+	}
+
+	return a.AvailableFrom == nil || !at.Before(*a.AvailableFrom)
+}
+
+// Locked checks if the assignment had stopped taking submissions by a
+// time. A locked assignment takes none from anyone, while a lock date
+// applies to each student as moved by their extension or
+// accommodation.
+func (a *Assignment) Locked(at time.Time) bool {
+	if a.State == ASSIGNMENT_LOCKED {
+		return true
+	}
+
+	return a.LockAt != nil && !at.Before(*a.LockAt)
+}
+
+// ValidSchedule checks the assignment's state and the times it is
+// published, available from and locked.
+func (a *Assignment) ValidSchedule() map[string]string {
+	errs := make(map[string]string)
+
+	if !a.State.Valid() {
+		errs["state"] = "must be draft, published or locked"
+	}
+
+	if a.LockAt != nil {
+		if a.AvailableFrom != nil && !a.LockAt.After(*a.AvailableFrom) {
+			errs["lock_at"] = "must be after the assignment is available"
+		} else if a.IsDraft() && a.PublishAt != nil && !a.LockAt.After(*a.PublishAt) {
+			errs["lock_at"] = "must be after the assignment is published"
+		} else if a.LockAt.Before(a.DueDate) {
+			errs["lock_at"] = "must not be before the due date"
+		}
+	}
+
+	return errs
+}
+
+// ParseOpenDate reads a time that something opens at, sent by a
+// client. Full RFC 3339 times carry their own offset. Plain dates open
+// at the very start of that day in the named time zone, or in UTC
+// when no zone is named. Times are returned in UTC.
+func ParseOpenDate(value, zone string) (time.Time, error) {
+	if at, err := time.Parse(time.RFC3339, value); err == nil {
+		return at.UTC(), nil
+	}
+
+	loc := time.UTC
+
+	if zone != "" {
+		var err error
+		loc, err = time.LoadLocation(zone)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("unknown time zone %q", zone)
+		}
+	}
+
+	day, err := time.ParseInLocation(time.DateOnly, value, loc)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return day.UTC(), nil
+}
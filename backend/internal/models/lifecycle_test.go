@@ -0,0 +1,179 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAssignment_Schedule(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	past := now.Add(-time.Minute)
+	future := now.Add(time.Minute)
+
+	tests := []struct {
+		name       string
+		s          Schedule
+		publishDue bool
+		lockDue    bool
+		opened     bool
+		locked     bool
+	}{
+		{name: "made before states", s: Schedule{}, opened: true},
+		{name: "published", s: Schedule{State: ASSIGNMENT_PUBLISHED}, opened: true},
+		{name: "draft", s: Schedule{State: ASSIGNMENT_DRAFT}},
+		{name: "draft to publish later", s: Schedule{State: ASSIGNMENT_DRAFT, PublishAt: &future}},
+		{name: "draft to publish now", s: Schedule{State: ASSIGNMENT_DRAFT, PublishAt: &now}, publishDue: true},
+		{name: "available later", s: Schedule{State: ASSIGNMENT_PUBLISHED, AvailableFrom: &future}},
+		{name: "available earlier", s: Schedule{State: ASSIGNMENT_PUBLISHED, AvailableFrom: &past}, opened: true},
+		{name: "to lock later", s: Schedule{State: ASSIGNMENT_PUBLISHED, LockAt: &future}, opened: true},
+		{name: "to lock now", s: Schedule{State: ASSIGNMENT_PUBLISHED, LockAt: &now}, lockDue: true, opened: true, locked: true},
+		{name: "draft past its lock date", s: Schedule{State: ASSIGNMENT_DRAFT, LockAt: &past}, locked: true},
+		{name: "locked", s: Schedule{State: ASSIGNMENT_LOCKED, LockAt: &past}, opened: true, locked: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				a := Assignment{Schedule: tt.s}
+
+				if got := a.PublishDue(now); got != tt.publishDue {
+					t.Errorf("got publish due %v, want %v", got, tt.publishDue)
+				}
+
+				if got := a.LockDue(now); got != tt.lockDue {
+					t.Errorf("got lock due %v, want %v", got, tt.lockDue)
+				}
+
+				if got := a.Opened(now); got != tt.opened {
+					t.Errorf("got opened %v, want %v", got, tt.opened)
+				}
+
+				if got := a.Locked(now); got != tt.locked {
+					t.Errorf("got locked %v, want %v", got, tt.locked)
+				}
+			},
+		)
+	}
+}
+
+func TestAssignment_LockedWithExtension(t *testing.T) {
+	due := time.Date(2024, 5, 1, 23, 59, 0, 0, time.UTC)
+	lock := due.Add(time.Hour)
+
+	a := &Assignment{
+		DueDate:    due,
+		Schedule:   Schedule{State: ASSIGNMENT_PUBLISHED, LockAt: &lock},
+		LatePolicy: &LatePolicy{GracePeriod: 30 * 60, Deduction: 10, Interval: PER_DAY},
+	}
+
+	extension := &Extension{DueDate: due.Add(48 * time.Hour)}
+	personal := a.For(extension, nil)
+
+	locked := *a
+	locked.State = ASSIGNMENT_LOCKED
+
+	tests := []struct {
+		name   string
+		a      *Assignment
+		at     time.Time
+		locked bool
+	}{
+		{name: "class after the lock date", a: a, at: lock.Add(time.Minute), locked: true},
+		{name: "extended after the class lock date", a: personal, at: lock.Add(time.Minute)},
+		{name: "extended inside the grace period", a: personal, at: personal.DueDate.Add(10 * time.Minute)},
+		{name: "extended after the moved lock date", a: personal, at: personal.LockAt.Add(time.Minute), locked: true},
+		{name: "extended on a locked assignment", a: locked.For(extension, nil), at: lock.Add(time.Minute), locked: true},
+	}
+
+	for _, tt := range tests {
+		if got := tt.a.Locked(tt.at); got != tt.locked {
+			t.Errorf("%s: got locked %v, want %v", tt.name, got, tt.locked)
+		}
+	}
+
+	if want := lock.Add(48 * time.Hour); !personal.LockAt.Equal(want) {
+		t.Errorf("got lock date %v, want %v", personal.LockAt, want)
+	}
+
+	inGrace := personal.DueDate.Add(10 * time.Minute)
+	if personal.IsLate(inGrace) || !personal.Accepts(inGrace) {
+		t.Errorf("got late %v and accepted %v, want on time inside the grace period", personal.IsLate(inGrace), personal.Accepts(inGrace))
+	}
+}
+
+func TestAssignment_ValidSchedule(t *testing.T) {
+	due := time.Date(2024, 5, 1, 23, 59, 59, 0, time.UTC)
+	before := due.Add(-time.Hour)
+	after := due.Add(time.Hour)
+	later := due.Add(2 * time.Hour)
+
+	tests := []struct {
+		name  string
+		s     Schedule
+		field string
+	}{
+		{name: "published", s: Schedule{State: ASSIGNMENT_PUBLISHED}},
+		{name: "unknown state", s: Schedule{State: "archived"}, field: "state"},
+		{name: "no state", s: Schedule{}, field: "state"},
+		{name: "lock after due", s: Schedule{State: ASSIGNMENT_PUBLISHED, AvailableFrom: &before, LockAt: &after}},
+		{name: "lock before due", s: Schedule{State: ASSIGNMENT_PUBLISHED, LockAt: &before}, field: "lock_at"},
+		{name: "lock before available", s: Schedule{State: ASSIGNMENT_PUBLISHED, AvailableFrom: &later, LockAt: &after}, field: "lock_at"},
+		{name: "lock before publish", s: Schedule{State: ASSIGNMENT_DRAFT, PublishAt: &later, LockAt: &after}, field: "lock_at"},
+		{name: "published after lock", s: Schedule{State: ASSIGNMENT_LOCKED, PublishAt: &later, LockAt: &after}},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				a := Assignment{DueDate: due, Schedule: tt.s}
+
+				errs := a.ValidSchedule()
+
+				if tt.field == "" && len(errs) != 0 {
+					t.Errorf("got %v, want no errors", errs)
+				}
+
+				if _, ok := errs[tt.field]; tt.field != "" && !ok {
+					t.Errorf("got %v, want an error for %s", errs, tt.field)
+				}
+			},
+		)
+	}
+}
+
+func TestParseOpenDate(t *testing.T) {
+	tests := []struct {
+		value string
+		zone  string
+		want  time.Time
+	}{
+		{
+			value: "2024-05-01T09:00:00-04:00",
+			want:  time.Date(2024, 5, 1, 13, 0, 0, 0, time.UTC),
+		},
+		{
+			value: "2024-05-01",
+			want:  time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			value: "2024-05-01",
+			zone:  "America/New_York",
+			want:  time.Date(2024, 5, 1, 4, 0, 0, 0, time.UTC),
+		},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseOpenDate(tt.value, tt.zone)
+		if err != nil {
+			t.Fatalf("%+v", err)
+		}
+
+		if got.Location() != time.UTC || !got.Equal(tt.want) {
+			t.Errorf("got %v, want %v in UTC", got, tt.want)
+		}
+	}
+
+	if _, err := ParseOpenDate("2024-05-01", "Nowhere/Special"); err == nil {
+		t.Errorf("got no error for an unknown time zone")
+	}
+}
@@ -1,7 +1,16 @@
 package models
 
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
 type FileType int
 
+// New file types are appended after NULL, since the numeric value of a
+// FileType is what gets stored in the media table.
 const (
 	JPG FileType = iota
 	PNG
@@ -11,8 +20,57 @@ const (
 	TXT
 	XLSX
 	NULL
+	DOCX
+	PPTX
+	ZIP
+	MP4
+	IPYNB
+	GO
+	PY
+	JAVA
+	C
+	CPP
+	H
+	JS
+	TS
+	HTML
+	CSS
+	SQL
+	MD
 )
 
+// fileTypeExtensions maps a lowercase file extension to its FileType.
+var fileTypeExtensions = map[string]FileType{
+	"jpg":   JPG,
+	"jpeg":  JPG,
+	"png":   PNG,
+	"pdf":   PDF,
+	"m4a":   M4A,
+	"mp3":   MP3,
+	"txt":   TXT,
+	"xlsx":  XLSX,
+	"docx":  DOCX,
+	"pptx":  PPTX,
+	"zip":   ZIP,
+	"mp4":   MP4,
+	"ipynb": IPYNB,
+	"go":    GO,
+	"py":    PY,
+	"java":  JAVA,
+	"c":     C,
+	"cpp":   CPP,
+	"cc":    CPP,
+	"h":     H,
+	"hpp":   H,
+	"js":    JS,
+	"ts":    TS,
+	"html":  HTML,
+	"htm":   HTML,
+	"css":   CSS,
+	"sql":   SQL,
+	"md":    MD,
+}
+
 func (f FileType) String() string {
 	switch f {
 	case JPG:
@@ -29,18 +87,197 @@ func (f FileType) String() string {
 		return "txt"
 	case XLSX:
 		return "xlsx"
+	case DOCX:
+		return "docx"
+	case PPTX:
+		return "pptx"
+	case ZIP:
+		return "zip"
+	case MP4:
+		return "mp4"
+	case IPYNB:
+		return "ipynb"
+	case GO:
+		return "go"
+	case PY:
+		return "py"
+	case JAVA:
+		return "java"
+	case C:
+		return "c"
+	case CPP:
+		return "cpp"
+	case H:
+		return "h"
+	case JS:
+		return "js"
+	case TS:
+		return "ts"
+	case HTML:
+		return "html"
+	case CSS:
+		return "css"
+	case SQL:
+		return "sql"
+	case MD:
+		return "md"
 	case NULL:
 		return ""
 	}
 	return ""
 }
 
+// IsImage reports whether the file type is a raster image.
+func (f FileType) IsImage() bool {
+	return f == JPG || f == PNG
+}
+
+// IsSourceCode reports whether the file type is a plain text source
+// code file.
+func (f FileType) IsSourceCode() bool {
+	switch f {
+	case GO, PY, JAVA, C, CPP, H, JS, TS, HTML, CSS, SQL:
+		return true
+	}
+	return false
+}
+
+// IsText reports whether the content of a file type is expected to be
+// plain UTF-8 text.
+func (f FileType) IsText() bool {
+	return f == TXT || f == MD || f == IPYNB || f.IsSourceCode()
+}
+
+// ParseFileType returns the FileType of an extension, such as "pdf" or
+// ".PDF". NULL is returned if the extension is unknown.
+func ParseFileType(ext string) FileType {
+	ext = strings.ToLower(strings.TrimPrefix(ext, "."))
+
+	if ft, ok := fileTypeExtensions[ext]; ok {
+		return ft
+	}
+
+	return NULL
+}
+
+// FileTypeFromName returns the declared FileType of a file name using
+// its extension.
+func FileTypeFromName(name string) FileType {
+	return ParseFileType(filepath.Ext(name))
+}
+
+// FileTypes is a list of file types. It is serialized as a comma
+// separated list of extensions in the database and as a list of
+// extensions in JSON.
+type FileTypes []FileType
+
+// ParseFileTypes parses a list of extensions, skipping blank ones. An
+// error naming the extension is returned for the first that is
+// unknown, so that a typo does not leave the list empty, which would
+// accept every type.
+func ParseFileTypes(exts ...string) (FileTypes, error) {
+	var fts FileTypes
+
+	for _, ext := range exts {
+		ext = strings.TrimSpace(ext)
+		if ext == "" {
+			continue
+		}
+
+		ft := ParseFileType(ext)
+		if ft == NULL {
+			return nil, fmt.Errorf("unknown file type %q", ext)
+		}
+
+		fts = append(fts, ft)
+	}
+
+	return fts, nil
+}
+
+// Contains reports whether a file type is in the list.
+func (fts FileTypes) Contains(f FileType) bool {
+	for _, ft := range fts {
+		if ft == f {
+			return true
+		}
+	}
+	return false
+}
+
+func (fts FileTypes) String() string {
+	s := make([]string, len(fts))
+	for i, ft := range fts {
+		s[i] = ft.String()
+	}
+	return strings.Join(s, ",")
+}
+
+func (fts FileTypes) MarshalJSON() ([]byte, error) {
+	s := make([]string, len(fts))
+	for i, ft := range fts {
+		s[i] = ft.String()
+	}
+	return json.Marshal(s)
+}
+
+func (fts *FileTypes) UnmarshalJSON(data []byte) error {
+	var s []string
+
+	err := json.Unmarshal(data, &s)
+	if err != nil {
+		return err
+	}
+
+	*fts, err = ParseFileTypes(s...)
+
+	return err
+}
+
+// ImageSize names a resized variant of an uploaded image.
+type ImageSize string
+
+const (
+	THUMBNAIL ImageSize = "thumbnail"
+	CARD      ImageSize = "card"
+	FULL      ImageSize = "full"
+)
+
+// ParseImageSize returns the image size named by s, defaulting to
+// FULL when s is empty or unknown.
+func ParseImageSize(s string) ImageSize {
+	switch ImageSize(strings.ToLower(s)) {
+	case THUMBNAIL:
+		return THUMBNAIL
+	case CARD:
+		return CARD
+	default:
+		return FULL
+	}
+}
+
 type Media struct {
 	Entity
 	FileName           string            `json:"name"`
 	AttributionsByType map[string]string `json:"attributions_by_type"`
 	FileType           FileType          `json:"file_type"`
 	FilePath           string            `json:"file_path"`
+
+	// Size is the size of the stored file in bytes, which counts
+	// toward storage quotas.
+	Size int64 `json:"size,omitempty"`
+
+	// Checksum is the SHA-256 of the stored file, in hex.
+	Checksum string `json:"checksum,omitempty"`
+
+	// ScanResult is the outcome of scanning the file for malware
+	// when it was uploaded.
+	ScanResult
+
+	// ParentId is the ID of the media this media is a variant of,
+	// such as the full size image of a thumbnail.
+	ParentId string    `json:"parent_id,omitempty"`
+	Variant  ImageSize `json:"variant,omitempty"`
 }
 
 func NewMedia(fileName string, fileType FileType) *Media {
@@ -55,4 +292,4 @@ func NewMedia(fileName string, fileType FileType) *Media {
 
 const (
 	DefaultImageId = "default_image"
-)
\ No newline at end of file
+)
@@ -0,0 +1,57 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestParseFileTypes(t *testing.T) {
+	tests := []struct {
+		exts []string
+		want FileTypes
+		err  bool
+	}{
+		{exts: []string{"pdf", ".PY", " txt "}, want: FileTypes{PDF, PY, TXT}},
+		{exts: []string{""}, want: nil},
+		{exts: nil, want: nil},
+		{exts: []string{"pdf", "pfd"}, err: true},
+		{exts: []string{"doc"}, err: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseFileTypes(tt.exts...)
+
+		if tt.err {
+			if err == nil {
+				t.Errorf("got %v for %v, want an error", got, tt.exts)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Fatalf("%+v", err)
+		}
+
+		if got.String() != tt.want.String() {
+			t.Errorf("got %v, want %v", got, tt.want)
+		}
+	}
+}
+
+func TestFileTypes_UnmarshalJSON(t *testing.T) {
+	var fts FileTypes
+
+	err := json.Unmarshal([]byte(`["pfd"]`), &fts)
+	if err == nil {
+		t.Errorf("got %v, want an error for an unknown type", fts)
+	}
+
+	err = json.Unmarshal([]byte(`["pdf", "md"]`), &fts)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+
+	if fts.String() != "pdf,md" {
+		t.Errorf("got %v, want pdf,md", fts)
+	}
+}
@@ -0,0 +1,277 @@
+package models
+
+import (
+	"net/url"
+	"strings"
+)
+
+// ModuleItemKind is what a module item is.
+type ModuleItemKind string
+
+const (
+	// MODULE_ASSIGNMENT items are assignments of the module's course.
+	MODULE_ASSIGNMENT ModuleItemKind = "assignment"
+
+	// MODULE_ANNOUNCEMENT items are announcements made in the course.
+	MODULE_ANNOUNCEMENT ModuleItemKind = "announcement"
+
+	// MODULE_FILE items are files uploaded to the module.
+	MODULE_FILE ModuleItemKind = "file"
+
+	// MODULE_LINK items link to a page outside of the course.
+	MODULE_LINK ModuleItemKind = "link"
+
+	// MODULE_PAGE items are pages written in the module, in Markdown.
+	MODULE_PAGE ModuleItemKind = "page"
+)
+
+// Requirement is what a student must do for a module item to count as
+// completed.
+type Requirement string
+
+const (
+	// REQUIRE_NONE items are not needed to complete their module.
+	REQUIRE_NONE Requirement = ""
+
+	// REQUIRE_VIEW items are completed once the student opens them.
+	REQUIRE_VIEW Requirement = "view"
+
+	// REQUIRE_MARK_DONE items are completed once the student marks
+	// them as done.
+	REQUIRE_MARK_DONE Requirement = "mark_done"
+
+	// REQUIRE_SUBMIT items are assignments, completed once the student
+	// has made a submission.
+	REQUIRE_SUBMIT Requirement = "submit"
+)
+
+// Limits on the size of a module and its items.
+const (
+	MaxModuleTitle = 200
+	MaxModulePage  = 100000
+)
+
+// ModuleState is how far a student is through a module.
+type ModuleState string
+
+const (
+	// MODULE_LOCKED modules have prerequisites the student has not
+	// completed yet.
+	MODULE_LOCKED ModuleState = "locked"
+
+	// MODULE_UNLOCKED modules are open, with nothing completed yet.
+	MODULE_UNLOCKED ModuleState = "unlocked"
+
+	// MODULE_STARTED modules have some, but not all, of their required
+	// items completed.
+	MODULE_STARTED ModuleState = "started"
+
+	// MODULE_COMPLETED modules have all of their required items
+	// completed. Modules without any requirements are completed once
+	// they are unlocked.
+	MODULE_COMPLETED ModuleState = "completed"
+)
+
+// Module is a unit of a course, such as a week, holding an ordered list
+// of the course's materials and assignments.
+type Module struct {
+	Entity
+	CourseId string `json:"course_id"`
+	Title    string `json:"title"`
+
+	// Position orders the course's modules, from 0.
+	Position int `json:"position"`
+
+	// Published modules are seen by the course's students. Others are
+	// only seen by its teachers.
+	Published bool `json:"published"`
+
+	// Prerequisites are the IDs of modules that must be completed
+	// before this one is unlocked. They come before it in the course.
+	Prerequisites []string `json:"prerequisites"`
+
+	Items []*ModuleItem `json:"items"`
+
+	// Progress is how far a student is through the module, when the
+	// module is read by a student.
+	Progress *ModuleProgress `json:"progress,omitempty"`
+}
+
+// Valid checks a module's title, and that it does not require itself.
+func (m *Module) Valid() map[string]string {
+	errs := make(map[string]string)
+
+	m.Title = strings.TrimSpace(m.Title)
+
+	if m.Title == "" {
+		errs["title"] = "must be provided"
+	}
+
+	if len(m.Title) > MaxModuleTitle {
+		errs["title"] = "must be no more than 200 bytes long"
+	}
+
+	for _, id := range m.Prerequisites {
+		if id == m.ID {
+			errs["prerequisites"] = "must not include the module itself"
+		}
+	}
+
+	return errs
+}
+
+// Item finds one of the module's items by its ID.
+func (m *Module) Item(id string) *ModuleItem {
+	for _, item := range m.Items {
+		if item.ID == id {
+			return item
+		}
+	}
+	return nil
+}
+
+// ModuleItem is one thing in a module. RefId is the assignment,
+// announcement or file it is, URL where a link goes, and Body the text
+// of a page.
+type ModuleItem struct {
+	Entity
+	ModuleId string         `json:"module_id"`
+	Kind     ModuleItemKind `json:"kind"`
+	Title    string         `json:"title"`
+
+	// Position orders the module's items, from 0.
+	Position int `json:"position"`
+
+	RefId string `json:"ref_id,omitempty"`
+	URL   string `json:"url,omitempty"`
+	Body  string `json:"body,omitempty"`
+
+	Requirement Requirement `json:"requirement,omitempty"`
+
+	// Completed is set on the items a student has completed, when the
+	// module is read by a student.
+	Completed bool `json:"completed,omitempty"`
+}
+
+// Valid checks that an item has what its kind needs, and a requirement
+// that it can be completed with. Only what its kind uses is kept.
+func (i *ModuleItem) Valid() map[string]string {
+	errs := make(map[string]string)
+
+	i.Title = strings.TrimSpace(i.Title)
+	i.URL = strings.TrimSpace(i.URL)
+
+	if i.Title == "" {
+		errs["title"] = "must be provided"
+	}
+
+	if len(i.Title) > MaxModuleTitle {
+		errs["title"] = "must be no more than 200 bytes long"
+	}
+
+	switch i.Kind {
+	case MODULE_ASSIGNMENT, MODULE_ANNOUNCEMENT:
+		i.URL, i.Body = "", ""
+
+		if i.RefId == "" {
+			errs["ref_id"] = "must be provided"
+		}
+	case MODULE_FILE:
+		// Files refer to what was uploaded for them.
+		i.URL, i.Body = "", ""
+	case MODULE_LINK:
+		i.RefId, i.Body = "", ""
+
+		u, err := url.Parse(i.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs["url"] = "must be an http or https URL"
+		}
+	case MODULE_PAGE:
+		i.RefId, i.URL = "", ""
+
+		if len(i.Body) > MaxModulePage {
+			errs["body"] = "must be no more than 100000 bytes long"
+		}
+	default:
+		errs["kind"] = "must be assignment, announcement, file, link or page"
+	}
+
+	switch i.Requirement {
+	case REQUIRE_NONE, REQUIRE_VIEW, REQUIRE_MARK_DONE:
+	case REQUIRE_SUBMIT:
+		if i.Kind != MODULE_ASSIGNMENT {
+			errs["requirement"] = "only assignments can require a submission"
+		}
+	default:
+		errs["requirement"] = "must be view, mark_done or submit"
+	}
+
+	return errs
+}
+
+// ModuleCompletion records that a student viewed a module item, or
+// marked it as done. Marking an item as done also views it.
+type ModuleCompletion struct {
+	ItemId      string      `json:"item_id"`
+	NetId       string      `json:"netid"`
+	Requirement Requirement `json:"requirement"`
+}
+
+// Meets checks if the completion meets an item's requirement.
+func (c *ModuleCompletion) Meets(r Requirement) bool {
+	switch r {
+	case REQUIRE_VIEW:
+		return c.Requirement == REQUIRE_VIEW || c.Requirement == REQUIRE_MARK_DONE
+	case REQUIRE_MARK_DONE:
+		return c.Requirement == REQUIRE_MARK_DONE
+	}
+	return false
+}
+
+// ModuleProgress is how far a student is through a module.
+type ModuleProgress struct {
+	ModuleId string      `json:"module_id"`
+	NetId    string      `json:"netid"`
+	State    ModuleState `json:"state"`
+
+	// Required is how many of the module's items have requirements,
+	// and Completed how many of those the student has completed.
+	Required  int `json:"required"`
+	Completed int `json:"completed"`
+}
+
+// ProgressOf works out how far a student is through a module, given the
+// items they have completed and whether the module's prerequisites are
+// met.
+func (m *Module) ProgressOf(
+	netId string,
+	completed map[string]bool,
+	unlocked bool,
+) *ModuleProgress {
+	p := &ModuleProgress{ModuleId: m.ID, NetId: netId}
+
+	for _, item := range m.Items {
+		if item.Requirement == REQUIRE_NONE {
+			continue
+		}
+
+		p.Required++
+
+		if completed[item.ID] {
+			p.Completed++
+		}
+	}
+
+	switch {
+	case !unlocked:
+		p.State = MODULE_LOCKED
+	case p.Completed == p.Required:
+		p.State = MODULE_COMPLETED
+	case p.Completed > 0:
+		p.State = MODULE_STARTED
+	default:
+		p.State = MODULE_UNLOCKED
+	}
+
+	return p
+}
@@ -0,0 +1,158 @@
+package models
+
+import "testing"
+
+func TestModuleItem_Valid(t *testing.T) {
+	tests := []struct {
+		name  string
+		item  ModuleItem
+		field string
+	}{
+		{
+			name: "assignment to submit",
+			item: ModuleItem{Kind: MODULE_ASSIGNMENT, Title: "Lab 1", RefId: "a1", Requirement: REQUIRE_SUBMIT},
+		},
+		{
+			name:  "assignment without a reference",
+			item:  ModuleItem{Kind: MODULE_ASSIGNMENT, Title: "Lab 1"},
+			field: "ref_id",
+		},
+		{
+			name: "uploaded file",
+			item: ModuleItem{Kind: MODULE_FILE, Title: "Slides", Requirement: REQUIRE_VIEW},
+		},
+		{
+			name: "link",
+			item: ModuleItem{Kind: MODULE_LINK, Title: "Docs", URL: " https://go.dev/doc "},
+		},
+		{
+			name:  "link that is not a web page",
+			item:  ModuleItem{Kind: MODULE_LINK, Title: "Run", URL: "javascript:alert(1)"},
+			field: "url",
+		},
+		{
+			name: "page to mark done",
+			item: ModuleItem{Kind: MODULE_PAGE, Title: "Welcome", Body: "# Hi", Requirement: REQUIRE_MARK_DONE},
+		},
+		{
+			name:  "page to submit",
+			item:  ModuleItem{Kind: MODULE_PAGE, Title: "Welcome", Requirement: REQUIRE_SUBMIT},
+			field: "requirement",
+		},
+		{
+			name:  "unknown requirement",
+			item:  ModuleItem{Kind: MODULE_PAGE, Title: "Welcome", Requirement: "read"},
+			field: "requirement",
+		},
+		{
+			name:  "unknown kind",
+			item:  ModuleItem{Kind: "video", Title: "Lecture"},
+			field: "kind",
+		},
+		{
+			name:  "no title",
+			item:  ModuleItem{Kind: MODULE_PAGE, Title: "  "},
+			field: "title",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				errs := tt.item.Valid()
+
+				if tt.field == "" && len(errs) != 0 {
+					t.Errorf("got %v, want no errors", errs)
+				}
+
+				if _, ok := errs[tt.field]; tt.field != "" && !ok {
+					t.Errorf("got %v, want an error for %s", errs, tt.field)
+				}
+			},
+		)
+	}
+}
+
+func TestModuleItem_ValidKeepsWhatKindUses(t *testing.T) {
+	item := ModuleItem{
+		Kind:  MODULE_LINK,
+		Title: "Docs",
+		RefId: "a1",
+		URL:   "https://go.dev",
+		Body:  "text",
+	}
+
+	item.Valid()
+
+	if item.RefId != "" || item.Body != "" || item.URL != "https://go.dev" {
+		t.Errorf("got %+v, want only the URL kept", item)
+	}
+}
+
+func TestModule_ProgressOf(t *testing.T) {
+	m := &Module{
+		Entity: Entity{ID: "m1"},
+		Items: []*ModuleItem{
+			{Entity: Entity{ID: "i1"}, Requirement: REQUIRE_VIEW},
+			{Entity: Entity{ID: "i2"}, Requirement: REQUIRE_SUBMIT},
+			{Entity: Entity{ID: "i3"}},
+		},
+	}
+
+	tests := []struct {
+		name      string
+		completed map[string]bool
+		unlocked  bool
+		want      ModuleState
+		done      int
+	}{
+		{name: "locked", completed: map[string]bool{"i1": true}, want: MODULE_LOCKED, done: 1},
+		{name: "nothing done", unlocked: true, want: MODULE_UNLOCKED},
+		{name: "item without a requirement", completed: map[string]bool{"i3": true}, unlocked: true, want: MODULE_UNLOCKED},
+		{name: "some done", completed: map[string]bool{"i1": true}, unlocked: true, want: MODULE_STARTED, done: 1},
+		{name: "all done", completed: map[string]bool{"i1": true, "i2": true}, unlocked: true, want: MODULE_COMPLETED, done: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				p := m.ProgressOf("stu1", tt.completed, tt.unlocked)
+
+				if p.State != tt.want {
+					t.Errorf("got %s, want %s", p.State, tt.want)
+				}
+
+				if p.Required != 2 || p.Completed != tt.done {
+					t.Errorf("got %d of %d, want %d of 2", p.Completed, p.Required, tt.done)
+				}
+			},
+		)
+	}
+
+	empty := &Module{}
+	if got := empty.ProgressOf("stu1", nil, true).State; got != MODULE_COMPLETED {
+		t.Errorf("got %s, want a module without requirements completed", got)
+	}
+}
+
+func TestModuleCompletion_Meets(t *testing.T) {
+	tests := []struct {
+		completion  Requirement
+		requirement Requirement
+		want        bool
+	}{
+		{completion: REQUIRE_VIEW, requirement: REQUIRE_VIEW, want: true},
+		{completion: REQUIRE_MARK_DONE, requirement: REQUIRE_VIEW, want: true},
+		{completion: REQUIRE_VIEW, requirement: REQUIRE_MARK_DONE, want: false},
+		{completion: REQUIRE_MARK_DONE, requirement: REQUIRE_MARK_DONE, want: true},
+		{completion: REQUIRE_VIEW, requirement: REQUIRE_SUBMIT, want: false},
+	}
+
+	for _, tt := range tests {
+		c := ModuleCompletion{Requirement: tt.completion}
+
+		if got := c.Meets(tt.requirement); got != tt.want {
+			t.Errorf("got %s meeting %s %v, want %v", tt.completion, tt.requirement, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+// NotificationKind is what a notification is about.
+type NotificationKind string
+
+const (
+	GRADES_RELEASED        NotificationKind = "grades_released"
+	PEER_REVIEWS_ALLOCATED NotificationKind = "peer_reviews_allocated"
+	REGRADE_ASSIGNED       NotificationKind = "regrade_assigned"
+	REGRADE_DECIDED        NotificationKind = "regrade_decided"
+	SIMILARITY_REPORTED    NotificationKind = "similarity_reported"
+	ASSIGNMENT_POSTED      NotificationKind = "assignment_posted"
+)
+
+// Notification tells a user that something happened that concerns
+// them, such as their grade for an assignment being released.
+type Notification struct {
+	Entity
+	NetId   string           `json:"netid"`
+	Kind    NotificationKind `json:"kind"`
+	Message string           `json:"message"`
+
+	// Ref is the ID of what the notification is about, such as an
+	// assignment.
+	Ref string `json:"ref,omitempty"`
+
+	// ReadAt is when the user read the notification. It is nil while
+	// it is unread.
+	ReadAt *time.Time `json:"read_at,omitempty"`
+}
@@ -1,6 +1,9 @@
 package models
 
-import "time"
+import (
+	"math"
+	"time"
+)
 
 type Post struct {
 	Entity
@@ -26,41 +29,155 @@ type Assignment struct {
 	Post
 	Submission []string  `json:"submission,omitempty"`
 	DueDate    time.Time `json:"due_date"`
+
+	// FileTypes are the types of files accepted for a submission.
+	// When empty, any known file type is accepted.
+	FileTypes FileTypes `json:"allowed_file_types,omitempty"`
+
+	// RubricId is the rubric submissions are graded against. It is
+	// empty when submissions are given a single grade.
+	RubricId string `json:"rubric_id,omitempty"`
+
+	// LatePolicy decides how late submissions are treated. It is nil
+	// when late submissions are accepted without a penalty.
+	LatePolicy *LatePolicy `json:"late_policy,omitempty"`
+
+	// ClassDueDate is the due date of the rest of the class. It is
+	// only set on an assignment as it applies to a student whose due
+	// date is different.
+	ClassDueDate *time.Time `json:"class_due_date,omitempty"`
+
+	// MaxAttempts is how many times a student may submit. Zero allows
+	// any number of attempts.
+	MaxAttempts int `json:"max_attempts"`
+
+	// CountedAttempt decides which attempt counts towards a student's
+	// grade, unless a teacher has pinned one.
+	CountedAttempt AttemptSelection `json:"counted_attempt,omitempty"`
+
+	// Anonymous assignments show submissions to their graders under
+	// pseudonyms derived from AnonymousKey, until GradesReleased.
+	Anonymous      bool   `json:"anonymous"`
+	AnonymousKey   string `json:"-"`
+	GradesReleased bool   `json:"grades_released"`
+
+	// ReleaseAt is when grades are scheduled to be released, or when
+	// they were. Until they are, students cannot see their grades or
+	// feedback.
+	ReleaseAt *time.Time `json:"release_at,omitempty"`
+
+	// RegradeWindow is how many seconds after grades are released
+	// students may request a regrade. Zero means there is no limit.
+	RegradeWindow int `json:"regrade_window"`
+
+	// PeerReview decides how students review each other's submissions.
+	// It is nil when the assignment is not peer reviewed.
+	PeerReview *PeerReviewPolicy `json:"peer_review,omitempty"`
+
+	// Schedule decides when the assignment is seen by students, and
+	// when it takes submissions.
+	Schedule
 }
 
 func NewAssignment() *Assignment {
-	return &Assignment{}
+	return &Assignment{Schedule: Schedule{State: ASSIGNMENT_PUBLISHED}}
+}
+
+// For is the assignment as it applies to a student with an extension
+// and an accommodation, either of which may be nil. A lock date moves
+// along with a later due date, so that the student has as long after
+// their due date as the rest of the class does.
+func (a *Assignment) For(ext *Extension, acc *Accommodation) *Assignment {
+	personal := *a
+	personal.DueDate = DueDateFor(a.DueDate, ext, acc)
+
+	if !personal.DueDate.Equal(a.DueDate) {
+		classDueDate := a.DueDate
+		personal.ClassDueDate = &classDueDate
+	}
+
+	if a.LockAt != nil && personal.DueDate.After(a.DueDate) {
+		lockAt := a.LockAt.Add(personal.DueDate.Sub(a.DueDate))
+		personal.LockAt = &lockAt
+	}
+
+	return &personal
+}
+
+// IsLate checks if a submission made at a time is late, taking the
+// assignment's grace period into account.
+func (a *Assignment) IsLate(at time.Time) bool {
+	return a.LatePolicy.Lateness(a.DueDate, at) > 0
+}
+
+// Accepts checks if the assignment's late policy allows a submission
+// made at a time.
+func (a *Assignment) Accepts(at time.Time) bool {
+	return a.LatePolicy.Accepts(a.DueDate, at)
 }
 
 type Submission struct {
 	Entity
-	Grade          float64 `json:"grade,omitempty"`
-	AssignmentId   string  `json:"assignment_id"`
+
+	// Grade is the grade after any late penalty, which is the grade
+	// that counts. RawGrade is the grade as it was given, and
+	// LatePenalty is the percentage that was taken off it.
+	Grade       float64 `json:"grade,omitempty"`
+	RawGrade    float64 `json:"raw_grade,omitempty"`
+	LatePenalty float64 `json:"late_penalty,omitempty"`
+
+	AssignmentId   string `json:"assignment_id"`
 	User           User
 	SubmissionTime time.Time
 	Media          []string
 	Feedback       string `json:"feedback"`
 	OnTime         bool
+
+	// Rubric is the breakdown of the grade by rubric criterion, for
+	// submissions graded with a rubric.
+	Rubric []*CriterionScore `json:"rubric,omitempty"`
+
+	// Attempt numbers a student's submissions to an assignment from 1.
+	// Counts is set on the one attempt that counts towards their
+	// grade, and Pinned when a teacher chose it.
+	Attempt int  `json:"attempt"`
+	Graded  bool `json:"graded"`
+	Counts  bool `json:"counts"`
+	Pinned  bool `json:"pinned,omitempty"`
+
+	// Files are the files uploaded with the submission, when they are
+	// needed along with it.
+	Files []*Media `json:"files,omitempty"`
+
+	// PeerScore is the average score given by the submission's peer
+	// reviewers. It is nil until one of them has submitted a review.
+	PeerScore *float64 `json:"peer_score,omitempty"`
+
+	// Regrades are the regrade requests made for the submission, when
+	// they are needed along with it.
+	Regrades []*RegradeRequest `json:"regrades,omitempty"`
 }
 
 func NewSubmission() *Submission {
-	return &Submission{}
+	return &Submission{Attempt: 1, Counts: true}
 }
 
 // IsOnTime checks if an assignment's submission time is
 // submitted on or before its due date, returning either true or false.
-// This function is a variation of the one found here:
-// https://stackoverflow.com/a/34100548/20087581
+// Times are compared as instants, so a due date and a submission time
+// in different time zones are still compared correctly.
 func (s *Submission) IsOnTime(due time.Time) bool {
-	loc, _ := time.LoadLocation("UTC")
-
-	dueDate := due.In(loc)
-	s.SubmissionTime = s.SubmissionTime.In(loc)
-	dur := dueDate.Sub(s.SubmissionTime)
+	return !s.SubmissionTime.After(due)
+}
 
-	// If the duration is less than 0, that means the assignment is
-	// not on time.
-	return dur.Seconds() < 0
+// SetGrade grades the submission, blending the raw grade with the
+// submission's peer score by its assignment's peer review weight, and
+// taking the late penalty off the result.
+func (s *Submission) SetGrade(raw float64, a *Assignment) {
+	s.RawGrade = raw
+	s.LatePenalty = a.LatePolicy.Penalty(a.DueDate, s.SubmissionTime)
+	s.Grade = math.Round(a.PeerReview.Blend(raw, s.PeerScore)*(100-s.LatePenalty)) / 100
+	s.Graded = true
 }
 
 type Course struct {
@@ -81,6 +198,13 @@ type Message struct {
 	Post
 	Comments []string
 	Type     bool // false if discussion, true if announcement
+
+	// Moderation flags, set by a course's teachers. Locked
+	// discussions take no new comments, pinned discussions are listed
+	// first and hidden discussions are only shown to teachers.
+	Locked bool `json:"locked"`
+	Pinned bool `json:"pinned"`
+	Hidden bool `json:"hidden"`
 }
 
 func NewMessage(title, description, owner string, t bool) *Message {
@@ -90,17 +214,86 @@ func NewMessage(title, description, owner string, t bool) *Message {
 	}
 }
 
+// Comment is a comment on a discussion, or a reply to another
+// comment. The comment's text is its Description.
 type Comment struct {
 	Post
-	ID      string
-	Replies []string
-}
-
-// type Project struct {
-// 	Name            string     `json:"name"`
-// 	string              string     `json:"id"`
-// 	Deadline        time.Time  `json:"deadline"`
-// 	MediaReferences []MediaId  `json:"media_references"`
-// 	Members         []User     `json:"members"`
-// 	Discussion      Discussion `json:"discussion"`
-// }
+	MessageId string `json:"message_id"`
+
+	// ParentId is the ID of the comment this is a reply to. It is
+	// empty for top level comments.
+	ParentId string `json:"parent_id,omitempty"`
+
+	// Hidden comments were hidden by a teacher. Deleted comments keep
+	// their place in the thread so that their replies still make
+	// sense, but their text is removed.
+	Hidden  bool `json:"hidden"`
+	Deleted bool `json:"deleted"`
+
+	Replies []*Comment `json:"replies,omitempty"`
+}
+
+func NewComment(messageId, parentId, body, owner string) *Comment {
+	return &Comment{
+		Post:      *NewPost("", body, owner),
+		MessageId: messageId,
+		ParentId:  parentId,
+	}
+}
+
+// Project is a group project within a course. Students work on it
+// in teams of at most TeamSize members.
+type Project struct {
+	Entity
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	CourseId    string `json:"course_id"`
+
+	// AssignmentId is the assignment that team submissions are made
+	// for. Projects without one are not graded.
+	AssignmentId string `json:"assignment_id,omitempty"`
+
+	// Owner is the Net ID of the teacher who created the project.
+	Owner    string `json:"owner"`
+	TeamSize int    `json:"team_size"`
+
+	// SelfEnroll lets students join and leave teams themselves.
+	// Otherwise only teachers assign students to teams.
+	SelfEnroll bool    `json:"self_enroll"`
+	Teams      []*Team `json:"teams,omitempty"`
+}
+
+func NewProject(name, description, courseId, owner string) *Project {
+	return &Project{
+		Name:        name,
+		Description: description,
+		CourseId:    courseId,
+		Owner:       owner,
+	}
+}
+
+// Team is a group of students working on a project. Members are
+// Net IDs.
+type Team struct {
+	Entity
+	ProjectId string   `json:"project_id"`
+	Name      string   `json:"name"`
+	Members   []string `json:"members"`
+
+	// DiscussionId is the ID of the team's private discussion.
+	DiscussionId string `json:"discussion_id"`
+
+	// SubmissionId is the ID of the team's submission for the
+	// project's assignment, if the team has made one.
+	SubmissionId string `json:"submission_id,omitempty"`
+}
+
+// HasMember checks if a user is on the team.
+func (t *Team) HasMember(netId string) bool {
+	for _, m := range t.Members {
+		if m == netId {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,142 @@
+package models
+
+import (
+	"math"
+	"math/rand"
+	"time"
+)
+
+// PeerReviewPolicy decides how students review each other's
+// submissions to an assignment. Assignments without a policy are not
+// peer reviewed.
+type PeerReviewPolicy struct {
+	AssignmentId string `json:"assignment_id"`
+
+	// Reviewers is how many students review each submission.
+	Reviewers int `json:"reviewers"`
+
+	// Weight is the percentage of a submission's grade that comes from
+	// its peer score, with the rest coming from its teacher's grade.
+	Weight float64 `json:"weight"`
+
+	// AllocatedAt is when reviewers were allocated. It is nil until
+	// they are, which happens once the assignment is due.
+	AllocatedAt *time.Time `json:"allocated_at,omitempty"`
+}
+
+// Valid checks a peer review policy, returning problems keyed by
+// field.
+func (p *PeerReviewPolicy) Valid() map[string]string {
+	errs := make(map[string]string)
+
+	if p.Reviewers < 1 {
+		errs["reviewers"] = "must be at least 1"
+	}
+
+	if p.Weight < 0 || p.Weight > 100 {
+		errs["weight"] = "must be between 0 and 100"
+	}
+
+	return errs
+}
+
+// Blend combines a teacher's grade with a peer score by the policy's
+// weight. The teacher's grade is used alone without a policy or a
+// peer score.
+func (p *PeerReviewPolicy) Blend(grade float64, peer *float64) float64 {
+	if p == nil || peer == nil {
+		return grade
+	}
+
+	blended := grade*(100-p.Weight)/100 + *peer*p.Weight/100
+
+	return math.Round(blended*100) / 100
+}
+
+// PeerReview is a student's review of another student's submission.
+// Reviews are allocated empty, and are done once SubmittedAt is set.
+type PeerReview struct {
+	Entity
+	AssignmentId string `json:"assignment_id"`
+	SubmissionId string `json:"submission_id"`
+
+	// Reviewer is the Net ID of the student reviewing. It is left out
+	// when the review is shown to the submission's author.
+	Reviewer string `json:"reviewer,omitempty"`
+
+	// Score is the score the reviewer gave, on the same scale as the
+	// teacher's grade. For assignments with a rubric, it is the total
+	// of the Rubric scores.
+	Score    *float64          `json:"score,omitempty"`
+	Rubric   []*CriterionScore `json:"rubric,omitempty"`
+	Comments string            `json:"comments"`
+
+	SubmittedAt *time.Time `json:"submitted_at,omitempty"`
+
+	// Submission is the submission under review, without its author,
+	// when it is shown to the reviewer.
+	Submission *Submission `json:"submission,omitempty"`
+}
+
+// Done checks if the reviewer has submitted the review.
+func (r *PeerReview) Done() bool {
+	return r.SubmittedAt != nil
+}
+
+// PeerScore is the average score of the reviews that are done. It is
+// nil when none are.
+func PeerScore(reviews []*PeerReview) *float64 {
+	var total float64
+	var done int
+
+	for _, r := range reviews {
+		if r.Done() && r.Score != nil {
+			total += *r.Score
+			done++
+		}
+	}
+
+	if done == 0 {
+		return nil
+	}
+
+	score := math.Round(total/float64(done)*100) / 100
+
+	return &score
+}
+
+// AllocateReviewers allocates n reviewers to each submission, from
+// among the students who made the other submissions. Nobody reviews
+// their own submission, and every student reviews n submissions. The
+// order is shuffled by the seed, so that students cannot tell who
+// reviews them. At most one fewer reviewers than submissions can be
+// allocated.
+func AllocateReviewers(
+	submissions []*Submission,
+	n int,
+	seed int64,
+) []*PeerReview {
+	n = min(n, len(submissions)-1)
+
+	rng := rand.New(rand.NewSource(seed))
+	order := rng.Perm(len(submissions))
+
+	var reviews []*PeerReview
+
+	// Shifting the order by k for k from 1 to n gives each submission
+	// n different reviewers, none of them its author.
+	for k := 1; k <= n; k++ {
+		for i := range order {
+			reviewer := submissions[order[i]]
+			reviewed := submissions[order[(i+k)%len(order)]]
+
+			reviews = append(reviews, &PeerReview{
+				AssignmentId: reviewed.AssignmentId,
+				SubmissionId: reviewed.ID,
+				Reviewer:     reviewer.User.ID,
+			})
+		}
+	}
+
+	return reviews
+}
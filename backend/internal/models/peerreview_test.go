@@ -0,0 +1,109 @@
+package models
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestAllocateReviewers(t *testing.T) {
+	tests := []struct {
+		name        string
+		submissions int
+		reviewers   int
+		want        int
+	}{
+		{name: "one reviewer", submissions: 5, reviewers: 1, want: 1},
+		{name: "several reviewers", submissions: 7, reviewers: 3, want: 3},
+		{name: "everyone else reviews", submissions: 4, reviewers: 3, want: 3},
+		{name: "too few submissions", submissions: 3, reviewers: 5, want: 2},
+		{name: "a single submission", submissions: 1, reviewers: 2, want: 0},
+	}
+
+	for _, tt := range tests {
+		var submissions []*Submission
+		for i := 0; i < tt.submissions; i++ {
+			s := &Submission{Entity: Entity{ID: fmt.Sprintf("s%d", i)}}
+			s.User.ID = fmt.Sprintf("stu%d", i)
+			submissions = append(submissions, s)
+		}
+
+		author := make(map[string]string)
+		for _, s := range submissions {
+			author[s.ID] = s.User.ID
+		}
+
+		reviewers := make(map[string]map[string]bool)
+		load := make(map[string]int)
+
+		for _, r := range AllocateReviewers(submissions, tt.reviewers, 42) {
+			if author[r.SubmissionId] == r.Reviewer {
+				t.Errorf("%s: %s reviews their own submission", tt.name, r.Reviewer)
+			}
+
+			if reviewers[r.SubmissionId] == nil {
+				reviewers[r.SubmissionId] = make(map[string]bool)
+			}
+
+			if reviewers[r.SubmissionId][r.Reviewer] {
+				t.Errorf("%s: %s reviews %s twice", tt.name, r.Reviewer, r.SubmissionId)
+			}
+
+			reviewers[r.SubmissionId][r.Reviewer] = true
+			load[r.Reviewer]++
+		}
+
+		for _, s := range submissions {
+			if got := len(reviewers[s.ID]); got != tt.want {
+				t.Errorf("%s: got %d reviewers for %s, want %d", tt.name, got, s.ID, tt.want)
+			}
+
+			if got := load[s.User.ID]; got != tt.want {
+				t.Errorf("%s: got %d reviews by %s, want %d", tt.name, got, s.User.ID, tt.want)
+			}
+		}
+	}
+}
+
+func TestPeerReviewPolicy_Blend(t *testing.T) {
+	peer := 60.0
+
+	tests := []struct {
+		name   string
+		policy *PeerReviewPolicy
+		peer   *float64
+		want   float64
+	}{
+		{name: "no policy", peer: &peer, want: 90},
+		{name: "no peer score", policy: &PeerReviewPolicy{Weight: 50}, want: 90},
+		{name: "half from peers", policy: &PeerReviewPolicy{Weight: 50}, peer: &peer, want: 75},
+		{name: "only peers", policy: &PeerReviewPolicy{Weight: 100}, peer: &peer, want: 60},
+	}
+
+	for _, tt := range tests {
+		got := tt.policy.Blend(90, tt.peer)
+		if got != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestPeerScore(t *testing.T) {
+	done := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	score := func(f float64) *float64 { return &f }
+
+	reviews := []*PeerReview{
+		{Score: score(80), SubmittedAt: &done},
+		{Score: score(70), SubmittedAt: &done},
+		{Score: score(10)},
+	}
+
+	got := PeerScore(reviews)
+	if got == nil || *got != 75 {
+		t.Errorf("got %v, want 75 from the reviews that are done", got)
+	}
+
+	if got := PeerScore(reviews[2:]); got != nil {
+		t.Errorf("got %v, want nil without reviews that are done", *got)
+	}
+}
@@ -0,0 +1,376 @@
+package models
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"time"
+)
+
+// QuestionType is the kind of a quiz question, which decides what an
+// answer looks like and how it is graded.
+type QuestionType string
+
+const (
+	MULTIPLE_CHOICE QuestionType = "multiple_choice"
+	MULTI_SELECT    QuestionType = "multi_select"
+	TRUE_FALSE      QuestionType = "true_false"
+	NUMERIC         QuestionType = "numeric"
+	SHORT_ANSWER    QuestionType = "short_answer"
+)
+
+// Valid checks if a question type is one of the known types.
+func (qt QuestionType) Valid() bool {
+	switch qt {
+	case MULTIPLE_CHOICE, MULTI_SELECT, TRUE_FALSE, NUMERIC, SHORT_ANSWER:
+		return true
+	default:
+		return false
+	}
+}
+
+// Quiz is a set of questions that students attempt within a time
+// limit. Each quiz has an assignment, so that attempts are graded
+// into the submissions table like any other assignment.
+type Quiz struct {
+	Entity
+	CourseId     string `json:"course_id"`
+	AssignmentId string `json:"assignment_id"`
+	Title        string `json:"title"`
+	Description  string `json:"description"`
+	Owner        string `json:"owner"`
+
+	// TimeLimit is how many seconds an attempt may take. Zero means
+	// no limit, other than ClosesAt.
+	TimeLimit int `json:"time_limit"`
+
+	// OpensAt and ClosesAt bound when attempts may be started. Zero
+	// times are unbounded.
+	OpensAt  time.Time `json:"opens_at"`
+	ClosesAt time.Time `json:"closes_at"`
+
+	// MaxAttempts is how many attempts each student may make.
+	MaxAttempts int `json:"max_attempts"`
+
+	// Questions and their choices are shown in a different order to
+	// each attempt when shuffled.
+	ShuffleQuestions bool `json:"shuffle_questions"`
+	ShuffleChoices   bool `json:"shuffle_choices"`
+
+	Questions []*Question `json:"questions,omitempty"`
+}
+
+// For is the quiz as it applies to a student with an extension for
+// its assignment and an accommodation, either of which may be nil.
+// The extension or accommodation moves when the quiz closes, and the
+// accommodation scales its time limit.
+func (q *Quiz) For(ext *Extension, acc *Accommodation) *Quiz {
+	personal := *q
+	personal.ClosesAt = DueDateFor(q.ClosesAt, ext, acc)
+	personal.TimeLimit = TimeLimitFor(q.TimeLimit, acc)
+	return &personal
+}
+
+// Valid checks a quiz and its questions, returning problems keyed by
+// field. Question fields are keyed by their index, such as
+// "questions[2].key".
+func (q *Quiz) Valid() map[string]string {
+	errs := make(map[string]string)
+
+	if q.Title == "" {
+		errs["title"] = "must be provided"
+	}
+
+	if q.TimeLimit < 0 {
+		errs["time_limit"] = "must not be negative"
+	}
+
+	if q.MaxAttempts < 1 {
+		errs["max_attempts"] = "must be at least 1"
+	}
+
+	if !q.OpensAt.IsZero() && !q.ClosesAt.IsZero() && !q.ClosesAt.After(q.OpensAt) {
+		errs["closes_at"] = "must be after opens_at"
+	}
+
+	if len(q.Questions) == 0 {
+		errs["questions"] = "must have at least one question"
+	}
+
+	for i, question := range q.Questions {
+		prefix := fmt.Sprintf("questions[%d].", i)
+		for key, msg := range question.Valid() {
+			errs[prefix+key] = msg
+		}
+	}
+
+	return errs
+}
+
+// Open checks if attempts may be started at a time.
+func (q *Quiz) Open(at time.Time) bool {
+	if !q.OpensAt.IsZero() && at.Before(q.OpensAt) {
+		return false
+	}
+
+	if !q.ClosesAt.IsZero() && !at.Before(q.ClosesAt) {
+		return false
+	}
+
+	return true
+}
+
+// Deadline is when an attempt started at a time must be submitted,
+// which is the end of its time limit, or when the quiz closes if that
+// is sooner. The zero time means there is no deadline.
+func (q *Quiz) Deadline(started time.Time) time.Time {
+	var deadline time.Time
+
+	if q.TimeLimit > 0 {
+		deadline = started.Add(time.Duration(q.TimeLimit) * time.Second)
+	}
+
+	if !q.ClosesAt.IsZero() && (deadline.IsZero() || q.ClosesAt.Before(deadline)) {
+		deadline = q.ClosesAt
+	}
+
+	return deadline
+}
+
+// Points is the most points that can be scored on the quiz.
+func (q *Quiz) Points() float64 {
+	var total float64
+	for _, question := range q.Questions {
+		total += question.Points
+	}
+	return total
+}
+
+// Choice is one of the options of a multiple choice or multi select
+// question. Answers refer to choices by ID, so that choices can be
+// shuffled.
+type Choice struct {
+	Id   int    `json:"id"`
+	Text string `json:"text"`
+}
+
+// AnswerKey is the correct answer to a question. Which fields are
+// used depends on the question's type.
+type AnswerKey struct {
+	// Choices are the IDs of the correct choices. Multiple choice
+	// questions have exactly one.
+	Choices []int `json:"choices,omitempty"`
+
+	Bool bool `json:"bool,omitempty"`
+
+	// Number is correct within plus or minus Tolerance.
+	Number    float64 `json:"number,omitempty"`
+	Tolerance float64 `json:"tolerance,omitempty"`
+
+	// Accepted are the short answers that are correct. Matching
+	// ignores case and surrounding space. Answers that do not match
+	// are left for a teacher to grade.
+	Accepted []string `json:"accepted,omitempty"`
+}
+
+type Question struct {
+	Entity
+	QuizId   string       `json:"quiz_id"`
+	Position int          `json:"position"`
+	Type     QuestionType `json:"type"`
+	Prompt   string       `json:"prompt"`
+	Points   float64      `json:"points"`
+	Choices  []Choice     `json:"choices,omitempty"`
+
+	// Key is only sent to teachers.
+	Key *AnswerKey `json:"key,omitempty"`
+}
+
+// Valid checks that a question's choices and key fit its type.
+func (q *Question) Valid() map[string]string {
+	errs := make(map[string]string)
+
+	if !q.Type.Valid() {
+		errs["type"] = "must be one of multiple_choice, multi_select, true_false, numeric or short_answer"
+		return errs
+	}
+
+	if q.Prompt == "" {
+		errs["prompt"] = "must be provided"
+	}
+
+	if q.Points < 0 {
+		errs["points"] = "must not be negative"
+	}
+
+	if q.Key == nil {
+		if q.Type != SHORT_ANSWER {
+			errs["key"] = "must be provided"
+		}
+		return errs
+	}
+
+	switch q.Type {
+	case MULTIPLE_CHOICE, MULTI_SELECT:
+		if len(q.Choices) < 2 {
+			errs["choices"] = "must have at least two choices"
+		}
+
+		ids := make(map[int]bool, len(q.Choices))
+		for _, c := range q.Choices {
+			if ids[c.Id] {
+				errs["choices"] = "must have unique IDs"
+			}
+			ids[c.Id] = true
+		}
+
+		if q.Type == MULTIPLE_CHOICE && len(q.Key.Choices) != 1 {
+			errs["key"] = "must have exactly one correct choice"
+		}
+
+		if q.Type == MULTI_SELECT && len(q.Key.Choices) == 0 {
+			errs["key"] = "must have at least one correct choice"
+		}
+
+		for _, id := range q.Key.Choices {
+			if !ids[id] {
+				errs["key"] = "must only refer to the question's choices"
+			}
+		}
+
+	case NUMERIC:
+		if q.Key.Tolerance < 0 {
+			errs["key"] = "tolerance must not be negative"
+		}
+	}
+
+	return errs
+}
+
+// Answer is a student's answer to a question. Which field is used
+// depends on the question's type.
+type Answer struct {
+	Choices []int    `json:"choices,omitempty"`
+	Bool    *bool    `json:"bool,omitempty"`
+	Number  *float64 `json:"number,omitempty"`
+	Text    string   `json:"text,omitempty"`
+}
+
+// Grade scores an answer against the question's key. Graded is false
+// when the answer must be graded by a teacher, which is the case for
+// short answers that do not match an accepted answer.
+func (q *Question) Grade(a Answer) (points float64, graded bool) {
+	if q.Key == nil {
+		return 0, false
+	}
+
+	var correct bool
+
+	switch q.Type {
+	case MULTIPLE_CHOICE:
+		correct = len(a.Choices) == 1 &&
+			len(q.Key.Choices) == 1 &&
+			a.Choices[0] == q.Key.Choices[0]
+
+	case MULTI_SELECT:
+		correct = sameChoices(a.Choices, q.Key.Choices)
+
+	case TRUE_FALSE:
+		correct = a.Bool != nil && *a.Bool == q.Key.Bool
+
+	case NUMERIC:
+		correct = a.Number != nil &&
+			math.Abs(*a.Number-q.Key.Number) <= q.Key.Tolerance
+
+	case SHORT_ANSWER:
+		text := normalizeAnswer(a.Text)
+		for _, accepted := range q.Key.Accepted {
+			if text != "" && text == normalizeAnswer(accepted) {
+				return q.Points, true
+			}
+		}
+		return 0, false
+	}
+
+	if correct {
+		return q.Points, true
+	}
+
+	return 0, true
+}
+
+// sameChoices checks if two lists of choice IDs hold the same set of
+// choices.
+func sameChoices(a, b []int) bool {
+	a, b = slices.Clone(a), slices.Clone(b)
+
+	slices.Sort(a)
+	slices.Sort(b)
+
+	a, b = slices.Compact(a), slices.Compact(b)
+
+	return len(a) > 0 && slices.Equal(a, b)
+}
+
+func normalizeAnswer(s string) string {
+	return strings.ToLower(strings.Join(strings.Fields(s), " "))
+}
+
+// Response is an attempt's answer to a question, along with the
+// points it was given.
+type Response struct {
+	QuestionId string  `json:"question_id"`
+	Answer     Answer  `json:"answer"`
+	Points     float64 `json:"points"`
+
+	// Graded is false until the response has been scored, either
+	// automatically or by a teacher.
+	Graded bool `json:"graded"`
+}
+
+// QuizAttempt is one student's attempt at a quiz.
+type QuizAttempt struct {
+	Entity
+	QuizId      string    `json:"quiz_id"`
+	NetId       string    `json:"netid"`
+	StartedAt   time.Time `json:"started_at"`
+	Deadline    time.Time `json:"deadline"`
+	SubmittedAt time.Time `json:"submitted_at"`
+
+	// Seed decides the order questions and choices are shown in.
+	Seed int64 `json:"-"`
+
+	Score float64 `json:"score"`
+
+	// SubmissionId is the ID of the submission the attempt's score
+	// was graded into, once it has been submitted.
+	SubmissionId string `json:"submission_id,omitempty"`
+
+	Responses []*Response `json:"responses"`
+
+	// Questions are the quiz's questions in the order this attempt
+	// shows them, without their keys.
+	Questions []*Question `json:"questions,omitempty"`
+}
+
+// Submitted checks if the attempt has been submitted.
+func (a *QuizAttempt) Submitted() bool {
+	return !a.SubmittedAt.IsZero()
+}
+
+// Expired checks if the attempt's deadline has passed at a time.
+func (a *QuizAttempt) Expired(at time.Time) bool {
+	return !a.Deadline.IsZero() && at.After(a.Deadline)
+}
+
+// NeedsReview checks if any of the attempt's responses are waiting
+// to be graded by a teacher.
+func (a *QuizAttempt) NeedsReview() bool {
+	for _, r := range a.Responses {
+		if !r.Graded {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,286 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestQuestion_Grade(t *testing.T) {
+	yes := true
+	no := false
+	pi := 3.14
+	far := 3.3
+
+	tests := []struct {
+		name     string
+		question Question
+		answer   Answer
+		points   float64
+		graded   bool
+	}{
+		{
+			name: "multiple choice correct",
+			question: Question{
+				Type:   MULTIPLE_CHOICE,
+				Points: 2,
+				Key:    &AnswerKey{Choices: []int{3}},
+			},
+			answer: Answer{Choices: []int{3}},
+			points: 2,
+			graded: true,
+		},
+		{
+			name: "multiple choice with two picked",
+			question: Question{
+				Type:   MULTIPLE_CHOICE,
+				Points: 2,
+				Key:    &AnswerKey{Choices: []int{3}},
+			},
+			answer: Answer{Choices: []int{3, 1}},
+			graded: true,
+		},
+		{
+			name: "multi select in any order",
+			question: Question{
+				Type:   MULTI_SELECT,
+				Points: 1,
+				Key:    &AnswerKey{Choices: []int{1, 2}},
+			},
+			answer: Answer{Choices: []int{2, 1, 2}},
+			points: 1,
+			graded: true,
+		},
+		{
+			name: "multi select missing a choice",
+			question: Question{
+				Type:   MULTI_SELECT,
+				Points: 1,
+				Key:    &AnswerKey{Choices: []int{1, 2}},
+			},
+			answer: Answer{Choices: []int{1}},
+			graded: true,
+		},
+		{
+			name: "true false correct",
+			question: Question{
+				Type:   TRUE_FALSE,
+				Points: 1,
+				Key:    &AnswerKey{Bool: false},
+			},
+			answer: Answer{Bool: &no},
+			points: 1,
+			graded: true,
+		},
+		{
+			name: "true false wrong",
+			question: Question{
+				Type:   TRUE_FALSE,
+				Points: 1,
+				Key:    &AnswerKey{Bool: false},
+			},
+			answer: Answer{Bool: &yes},
+			graded: true,
+		},
+		{
+			name: "true false unanswered",
+			question: Question{
+				Type:   TRUE_FALSE,
+				Points: 1,
+				Key:    &AnswerKey{Bool: false},
+			},
+			graded: true,
+		},
+		{
+			name: "numeric within tolerance",
+			question: Question{
+				Type:   NUMERIC,
+				Points: 4,
+				Key:    &AnswerKey{Number: 3.14159, Tolerance: 0.01},
+			},
+			answer: Answer{Number: &pi},
+			points: 4,
+			graded: true,
+		},
+		{
+			name: "numeric outside tolerance",
+			question: Question{
+				Type:   NUMERIC,
+				Points: 4,
+				Key:    &AnswerKey{Number: 3.14159, Tolerance: 0.01},
+			},
+			answer: Answer{Number: &far},
+			graded: true,
+		},
+		{
+			name: "short answer accepted",
+			question: Question{
+				Type:   SHORT_ANSWER,
+				Points: 3,
+				Key:    &AnswerKey{Accepted: []string{"Ada Lovelace"}},
+			},
+			answer: Answer{Text: "  ada   LOVELACE "},
+			points: 3,
+			graded: true,
+		},
+		{
+			name: "short answer left for review",
+			question: Question{
+				Type:   SHORT_ANSWER,
+				Points: 3,
+				Key:    &AnswerKey{Accepted: []string{"Ada Lovelace"}},
+			},
+			answer: Answer{Text: "Countess of Lovelace"},
+		},
+		{
+			name: "short answer without a key",
+			question: Question{
+				Type:   SHORT_ANSWER,
+				Points: 3,
+			},
+			answer: Answer{Text: "anything"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				points, graded := tt.question.Grade(tt.answer)
+
+				if points != tt.points || graded != tt.graded {
+					t.Errorf(
+						"got %v %v, want %v %v",
+						points,
+						graded,
+						tt.points,
+						tt.graded,
+					)
+				}
+			},
+		)
+	}
+}
+
+func TestQuiz_Valid(t *testing.T) {
+	valid := func() *Quiz {
+		return &Quiz{
+			Title:       "Week 1",
+			MaxAttempts: 1,
+			Questions: []*Question{
+				{
+					Type:    MULTIPLE_CHOICE,
+					Prompt:  "2 + 2?",
+					Points:  1,
+					Choices: []Choice{{Id: 1, Text: "4"}, {Id: 2, Text: "5"}},
+					Key:     &AnswerKey{Choices: []int{1}},
+				},
+			},
+		}
+	}
+
+	tests := []struct {
+		name   string
+		modify func(q *Quiz)
+		want   string
+	}{
+		{
+			name:   "valid",
+			modify: func(q *Quiz) {},
+		},
+		{
+			name:   "no questions",
+			modify: func(q *Quiz) { q.Questions = nil },
+			want:   "questions",
+		},
+		{
+			name: "closes before it opens",
+			modify: func(q *Quiz) {
+				q.OpensAt = time.Date(2024, 5, 2, 0, 0, 0, 0, time.UTC)
+				q.ClosesAt = time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+			},
+			want: "closes_at",
+		},
+		{
+			name:   "unknown question type",
+			modify: func(q *Quiz) { q.Questions[0].Type = "essay" },
+			want:   "questions[0].type",
+		},
+		{
+			name:   "key refers to a missing choice",
+			modify: func(q *Quiz) { q.Questions[0].Key.Choices = []int{7} },
+			want:   "questions[0].key",
+		},
+		{
+			name:   "missing key",
+			modify: func(q *Quiz) { q.Questions[0].Key = nil },
+			want:   "questions[0].key",
+		},
+		{
+			name: "short answer without a key",
+			modify: func(q *Quiz) {
+				q.Questions[0] = &Question{Type: SHORT_ANSWER, Prompt: "Why?"}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				q := valid()
+				tt.modify(q)
+
+				errs := q.Valid()
+
+				if tt.want == "" {
+					if len(errs) > 0 {
+						t.Errorf("got %v, want no errors", errs)
+					}
+					return
+				}
+
+				if _, ok := errs[tt.want]; !ok {
+					t.Errorf("got %v, want an error for %s", errs, tt.want)
+				}
+			},
+		)
+	}
+}
+
+func TestQuiz_Deadline(t *testing.T) {
+	start := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		quiz Quiz
+		want time.Time
+	}{
+		{
+			name: "no limit",
+		},
+		{
+			name: "time limit",
+			quiz: Quiz{TimeLimit: 1800},
+			want: start.Add(30 * time.Minute),
+		},
+		{
+			name: "closes before the limit ends",
+			quiz: Quiz{TimeLimit: 3600, ClosesAt: start.Add(10 * time.Minute)},
+			want: start.Add(10 * time.Minute),
+		},
+		{
+			name: "closes without a limit",
+			quiz: Quiz{ClosesAt: start.Add(time.Hour)},
+			want: start.Add(time.Hour),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				got := tt.quiz.Deadline(start)
+				if !got.Equal(tt.want) {
+					t.Errorf("got %v, want %v", got, tt.want)
+				}
+			},
+		)
+	}
+}
@@ -0,0 +1,114 @@
+package models
+
+import "time"
+
+// RegradeState is where a regrade request is in being handled.
+type RegradeState string
+
+const (
+	REGRADE_OPEN      RegradeState = "open"
+	REGRADE_ESCALATED RegradeState = "escalated"
+	REGRADE_ACCEPTED  RegradeState = "accepted"
+	REGRADE_REJECTED  RegradeState = "rejected"
+)
+
+// Resolved checks if a request in the state has been decided.
+func (s RegradeState) Resolved() bool {
+	return s == REGRADE_ACCEPTED || s == REGRADE_REJECTED
+}
+
+// RegradeRequest is a student's request to have the grade of one of
+// their submissions looked at again.
+type RegradeRequest struct {
+	Entity
+	SubmissionId string `json:"submission_id"`
+	AssignmentId string `json:"assignment_id"`
+
+	// NetId is the student who made the request.
+	NetId         string       `json:"netid"`
+	Justification string       `json:"justification"`
+	State         RegradeState `json:"state"`
+
+	// AssignedTo is the teacher the request was escalated to, who is
+	// then the only one who may decide it.
+	AssignedTo string `json:"assigned_to,omitempty"`
+
+	// OldGrade is the grade when the request was made, and NewGrade
+	// the grade it was given if the request was accepted.
+	OldGrade float64  `json:"old_grade"`
+	NewGrade *float64 `json:"new_grade,omitempty"`
+
+	// Reason is why the request was rejected.
+	Reason string `json:"reason,omitempty"`
+
+	ResolvedBy string     `json:"resolved_by,omitempty"`
+	ResolvedAt *time.Time `json:"resolved_at,omitempty"`
+
+	// History is every change to the request, oldest first.
+	History []*RegradeEvent `json:"history,omitempty"`
+}
+
+// RegradeEvent is a change to a regrade request, such as it being
+// made, escalated or decided.
+type RegradeEvent struct {
+	RequestId string       `json:"request_id"`
+	Actor     string       `json:"actor"`
+	State     RegradeState `json:"state"`
+	Note      string       `json:"note,omitempty"`
+	At        time.Time    `json:"at"`
+}
+
+// Move puts the request in a new state, returning the event to record
+// in its history. Requests that are accepted or rejected are resolved
+// by the actor.
+func (r *RegradeRequest) Move(
+	state RegradeState,
+	actor, note string,
+	at time.Time,
+) *RegradeEvent {
+	r.State = state
+
+	if state.Resolved() {
+		r.ResolvedBy = actor
+		r.ResolvedAt = &at
+	}
+
+	e := &RegradeEvent{
+		RequestId: r.ID,
+		Actor:     actor,
+		State:     state,
+		Note:      note,
+		At:        at,
+	}
+
+	r.History = append(r.History, e)
+
+	return e
+}
+
+// RegradeDeadline is the last time regrades can be requested for the
+// assignment, which is RegradeWindow after its grades were released.
+// It is nil when grades have not been released, or when there is no
+// deadline.
+func (a *Assignment) RegradeDeadline() *time.Time {
+	if !a.GradesReleased || a.ReleaseAt == nil || a.RegradeWindow == 0 {
+		return nil
+	}
+
+	deadline := a.ReleaseAt.Add(time.Duration(a.RegradeWindow) * time.Second)
+
+	return &deadline
+}
+
+// AcceptsRegrades checks if regrades can be requested for the
+// assignment at a time. Students can only dispute grades once they
+// have been released, and until the deadline.
+func (a *Assignment) AcceptsRegrades(at time.Time) bool {
+	if !a.GradesReleased {
+		return false
+	}
+
+	deadline := a.RegradeDeadline()
+
+	return deadline == nil || !at.After(*deadline)
+}
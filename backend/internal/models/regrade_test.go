@@ -0,0 +1,75 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAssignment_AcceptsRegrades(t *testing.T) {
+	released := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	week := 7 * 24 * 60 * 60
+
+	tests := []struct {
+		name       string
+		assignment *Assignment
+		at         time.Time
+		want       bool
+	}{
+		{
+			name:       "not released",
+			assignment: &Assignment{ReleaseAt: &released, RegradeWindow: week},
+			at:         released.Add(time.Hour),
+		},
+		{
+			name:       "within the window",
+			assignment: &Assignment{GradesReleased: true, ReleaseAt: &released, RegradeWindow: week},
+			at:         released.Add(6 * 24 * time.Hour),
+			want:       true,
+		},
+		{
+			name:       "at the deadline",
+			assignment: &Assignment{GradesReleased: true, ReleaseAt: &released, RegradeWindow: week},
+			at:         released.Add(7 * 24 * time.Hour),
+			want:       true,
+		},
+		{
+			name:       "after the deadline",
+			assignment: &Assignment{GradesReleased: true, ReleaseAt: &released, RegradeWindow: week},
+			at:         released.Add(8 * 24 * time.Hour),
+		},
+		{
+			name:       "no limit",
+			assignment: &Assignment{GradesReleased: true, ReleaseAt: &released},
+			at:         released.Add(365 * 24 * time.Hour),
+			want:       true,
+		},
+	}
+
+	for _, tt := range tests {
+		got := tt.assignment.AcceptsRegrades(tt.at)
+		if got != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestRegradeRequest_Move(t *testing.T) {
+	at := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	r := &RegradeRequest{Entity: Entity{ID: "r1"}, State: REGRADE_OPEN}
+
+	r.Move(REGRADE_ESCALATED, "prof", "", at)
+
+	if r.ResolvedAt != nil {
+		t.Errorf("got resolved at %v, want an escalated request unresolved", r.ResolvedAt)
+	}
+
+	e := r.Move(REGRADE_REJECTED, "ta", "no change", at)
+
+	if r.ResolvedBy != "ta" || r.ResolvedAt == nil || !r.ResolvedAt.Equal(at) {
+		t.Errorf("got resolved by %s at %v, want ta at %v", r.ResolvedBy, r.ResolvedAt, at)
+	}
+
+	if e.RequestId != "r1" || e.State != REGRADE_REJECTED || len(r.History) != 2 {
+		t.Errorf("got event %+v and %d events, want the rejection as the second", e, len(r.History))
+	}
+}
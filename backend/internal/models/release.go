@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// ReleaseDue checks if an assignment's grades are scheduled to be
+// released by a time and have not been released yet.
+func (a *Assignment) ReleaseDue(now time.Time) bool {
+	return !a.GradesReleased && a.ReleaseAt != nil && !now.Before(*a.ReleaseAt)
+}
+
+// HideGrade takes a submission's grade and feedback off it, for a
+// student whose grades have not been released.
+func (s *Submission) HideGrade() {
+	s.Grade = 0
+	s.RawGrade = 0
+	s.LatePenalty = 0
+	s.Feedback = ""
+	s.Graded = false
+	s.Rubric = nil
+}
@@ -0,0 +1,34 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAssignment_ReleaseDue(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	past := now.Add(-time.Minute)
+	future := now.Add(time.Minute)
+
+	tests := []struct {
+		name string
+		a    Assignment
+		want bool
+	}{
+		{name: "not scheduled", a: Assignment{}},
+		{name: "scheduled later", a: Assignment{ReleaseAt: &future}},
+		{name: "scheduled now", a: Assignment{ReleaseAt: &now}, want: true},
+		{name: "scheduled earlier", a: Assignment{ReleaseAt: &past}, want: true},
+		{name: "already released", a: Assignment{ReleaseAt: &past, GradesReleased: true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				if got := tt.a.ReleaseDue(now); got != tt.want {
+					t.Errorf("got %v, want %v", got, tt.want)
+				}
+			},
+		)
+	}
+}
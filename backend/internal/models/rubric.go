@@ -0,0 +1,143 @@
+package models
+
+import "fmt"
+
+// Rubric is a reusable set of criteria that assignments in a course
+// are graded against. A rubric is attached to an assignment through
+// the assignment's RubricId.
+type Rubric struct {
+	Entity
+	CourseId    string       `json:"course_id"`
+	Title       string       `json:"title"`
+	Description string       `json:"description"`
+	Owner       string       `json:"owner"`
+	Criteria    []*Criterion `json:"criteria,omitempty"`
+}
+
+// Valid checks a rubric and its criteria, returning problems keyed by
+// field. Criterion fields are keyed by their index, such as
+// "criteria[1].levels".
+func (r *Rubric) Valid() map[string]string {
+	errs := make(map[string]string)
+
+	if r.Title == "" {
+		errs["title"] = "must be provided"
+	}
+
+	if len(r.Criteria) == 0 {
+		errs["criteria"] = "must have at least one criterion"
+	}
+
+	for i, c := range r.Criteria {
+		prefix := fmt.Sprintf("criteria[%d].", i)
+		for key, msg := range c.Valid() {
+			errs[prefix+key] = msg
+		}
+	}
+
+	return errs
+}
+
+// Points is the most points that can be scored with the rubric.
+func (r *Rubric) Points() float64 {
+	var total float64
+	for _, c := range r.Criteria {
+		total += c.Points()
+	}
+	return total
+}
+
+// Criterion finds one of the rubric's criteria by its ID.
+func (r *Rubric) Criterion(id string) *Criterion {
+	for _, c := range r.Criteria {
+		if c.ID == id {
+			return c
+		}
+	}
+	return nil
+}
+
+// Criterion is one thing a rubric grades, such as "Clarity", along
+// with the levels of achievement it can be scored at.
+type Criterion struct {
+	Entity
+	RubricId    string  `json:"rubric_id"`
+	Position    int     `json:"position"`
+	Name        string  `json:"name"`
+	Description string  `json:"description"`
+	Levels      []Level `json:"levels"`
+}
+
+// Valid checks that a criterion has a name and distinct levels.
+func (c *Criterion) Valid() map[string]string {
+	errs := make(map[string]string)
+
+	if c.Name == "" {
+		errs["name"] = "must be provided"
+	}
+
+	if len(c.Levels) == 0 {
+		errs["levels"] = "must have at least one level"
+	}
+
+	names := make(map[string]bool, len(c.Levels))
+	for _, l := range c.Levels {
+		if l.Name == "" {
+			errs["levels"] = "must all have a name"
+		}
+
+		if names[l.Name] {
+			errs["levels"] = "must have unique names"
+		}
+		names[l.Name] = true
+
+		if l.Points < 0 {
+			errs["levels"] = "must not have negative points"
+		}
+	}
+
+	return errs
+}
+
+// Points is the most points the criterion can be scored, which are
+// the points of its highest level.
+func (c *Criterion) Points() float64 {
+	var most float64
+	for _, l := range c.Levels {
+		most = max(most, l.Points)
+	}
+	return most
+}
+
+// Level finds one of the criterion's levels by its name.
+func (c *Criterion) Level(name string) (Level, bool) {
+	for _, l := range c.Levels {
+		if l.Name == name {
+			return l, true
+		}
+	}
+	return Level{}, false
+}
+
+// Level is a level of achievement for a criterion, such as
+// "Excellent", and the points it is worth.
+type Level struct {
+	Name        string  `json:"name"`
+	Description string  `json:"description,omitempty"`
+	Points      float64 `json:"points"`
+}
+
+// CriterionScore is the score a submission was given for one of its
+// assignment rubric's criteria.
+type CriterionScore struct {
+	CriterionId string `json:"criterion_id"`
+
+	// Criterion is the name of the criterion, for display.
+	Criterion string `json:"criterion,omitempty"`
+
+	// Level is the name of the level the submission was placed at.
+	// When it is empty, Points were given directly.
+	Level   string  `json:"level,omitempty"`
+	Points  float64 `json:"points"`
+	Comment string  `json:"comment,omitempty"`
+}
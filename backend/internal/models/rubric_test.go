@@ -0,0 +1,93 @@
+package models
+
+import "testing"
+
+func TestRubric_Valid(t *testing.T) {
+	valid := func() *Rubric {
+		return &Rubric{
+			Title: "Essay",
+			Criteria: []*Criterion{
+				{
+					Name: "Clarity",
+					Levels: []Level{
+						{Name: "Excellent", Points: 4},
+						{Name: "Poor", Points: 1},
+					},
+				},
+			},
+		}
+	}
+
+	tests := []struct {
+		name   string
+		modify func(r *Rubric)
+		want   string
+	}{
+		{
+			name:   "valid",
+			modify: func(r *Rubric) {},
+		},
+		{
+			name:   "no title",
+			modify: func(r *Rubric) { r.Title = "" },
+			want:   "title",
+		},
+		{
+			name:   "no criteria",
+			modify: func(r *Rubric) { r.Criteria = nil },
+			want:   "criteria",
+		},
+		{
+			name:   "criterion without levels",
+			modify: func(r *Rubric) { r.Criteria[0].Levels = nil },
+			want:   "criteria[0].levels",
+		},
+		{
+			name: "duplicate level names",
+			modify: func(r *Rubric) {
+				r.Criteria[0].Levels[1].Name = "Excellent"
+			},
+			want: "criteria[0].levels",
+		},
+		{
+			name:   "negative points",
+			modify: func(r *Rubric) { r.Criteria[0].Levels[1].Points = -1 },
+			want:   "criteria[0].levels",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				r := valid()
+				tt.modify(r)
+
+				errs := r.Valid()
+
+				if tt.want == "" {
+					if len(errs) > 0 {
+						t.Errorf("got %v, want no errors", errs)
+					}
+					return
+				}
+
+				if _, ok := errs[tt.want]; !ok {
+					t.Errorf("got %v, want an error for %s", errs, tt.want)
+				}
+			},
+		)
+	}
+}
+
+func TestRubric_Points(t *testing.T) {
+	r := &Rubric{
+		Criteria: []*Criterion{
+			{Levels: []Level{{Points: 1}, {Points: 4}, {Points: 2}}},
+			{Levels: []Level{{Points: 0}, {Points: 2.5}}},
+		},
+	}
+
+	if got := r.Points(); got != 6.5 {
+		t.Errorf("got %v, want 6.5", got)
+	}
+}
@@ -0,0 +1,317 @@
+package models
+
+import (
+	"hash/fnv"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// SimilarityStatus is where a similarity report is in being made.
+type SimilarityStatus string
+
+const (
+	SIMILARITY_PENDING SimilarityStatus = "pending"
+	SIMILARITY_DONE    SimilarityStatus = "done"
+	SIMILARITY_FAILED  SimilarityStatus = "failed"
+)
+
+// Fingerprints are made from k-grams of KGramSize words, one picked
+// from every window of WindowSize k-grams. Any passage that two texts
+// share of at least KGramSize+WindowSize-1 words is sure to be found.
+const (
+	KGramSize  = 5
+	WindowSize = 4
+)
+
+// MinSimilarity is the least score a pair of submissions must have to
+// be reported, so that phrases everyone uses, such as those quoted from
+// the assignment, do not bury the pairs worth looking at.
+const MinSimilarity = 0.05
+
+// SimilarityReport is a comparison of the text of every submission to
+// an assignment, and optionally of those to past offerings of it,
+// which pairs of submissions are ranked by how much they share.
+type SimilarityReport struct {
+	Entity
+	AssignmentId string `json:"assignment_id"`
+
+	// RequestedBy is the teacher who asked for the report.
+	RequestedBy string `json:"requested_by"`
+
+	// CompareWith are the assignments of past offerings whose
+	// submissions are compared against.
+	CompareWith []string `json:"compare_with"`
+
+	Status SimilarityStatus `json:"status"`
+
+	// Error is why the report failed.
+	Error string `json:"error,omitempty"`
+
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+
+	// Pairs are the pairs of submissions that share text, the most
+	// similar first.
+	Pairs []*SimilarityPair `json:"pairs,omitempty"`
+}
+
+// SimilarityPair is how similar two submissions are. The first is
+// always a submission to the report's assignment, and the second may
+// be to a past offering of it.
+type SimilarityPair struct {
+	ReportId string `json:"report_id"`
+
+	SubmissionA string `json:"submission_a"`
+	NetIdA      string `json:"netid_a"`
+
+	SubmissionB string `json:"submission_b"`
+	NetIdB      string `json:"netid_b"`
+	AssignmentB string `json:"assignment_b"`
+
+	// Score is the share of fingerprints of the shorter of the two
+	// texts that are found in the other, from 0 to 1.
+	Score float64 `json:"score"`
+
+	Matches []*Match `json:"matches"`
+}
+
+// Match is a passage found in both submissions of a pair. Starts and
+// ends are byte offsets into each submission's text, for highlighting.
+type Match struct {
+	Text   string `json:"text"`
+	AStart int    `json:"a_start"`
+	AEnd   int    `json:"a_end"`
+	BStart int    `json:"b_start"`
+	BEnd   int    `json:"b_end"`
+}
+
+// Fingerprint is the hash of a k-gram picked by winnowing, along with
+// where the k-gram is in the text it came from.
+type Fingerprint struct {
+	Hash  uint64
+	Start int
+	End   int
+}
+
+// Document is the text of a submission, ready to be compared.
+type Document struct {
+	SubmissionId string
+	NetId        string
+	AssignmentId string
+	Text         string
+
+	prints []Fingerprint
+}
+
+// NewDocument fingerprints the text of a submission.
+func NewDocument(submissionId, netId, assignmentId, text string) *Document {
+	return &Document{
+		SubmissionId: submissionId,
+		NetId:        netId,
+		AssignmentId: assignmentId,
+		Text:         text,
+		prints:       Winnow(text, KGramSize, WindowSize),
+	}
+}
+
+// word is a normalized word and where it is in the text.
+type word struct {
+	text       string
+	start, end int
+}
+
+// words splits text into lowercase words of letters and digits, so
+// that case, punctuation and spacing do not hide a copied passage.
+func words(text string) []word {
+	var ws []word
+
+	start := -1
+
+	for i, r := range text {
+		inWord := unicode.IsLetter(r) || unicode.IsDigit(r)
+
+		if inWord && start < 0 {
+			start = i
+		}
+
+		if !inWord && start >= 0 {
+			ws = append(ws, word{strings.ToLower(text[start:i]), start, i})
+			start = -1
+		}
+	}
+
+	if start >= 0 {
+		ws = append(ws, word{strings.ToLower(text[start:]), start, len(text)})
+	}
+
+	return ws
+}
+
+// Winnow fingerprints text by hashing every k-gram of words and
+// keeping the smallest hash of every window of w k-grams, the
+// rightmost one on ties. A hash is kept once for as long as it stays
+// the smallest of the windows it is in.
+func Winnow(text string, k, w int) []Fingerprint {
+	ws := words(text)
+	if len(ws) < k {
+		return nil
+	}
+
+	grams := make([]Fingerprint, len(ws)-k+1)
+
+	for i := range grams {
+		h := fnv.New64a()
+		for _, w := range ws[i : i+k] {
+			h.Write([]byte(w.text))
+			h.Write([]byte{0})
+		}
+
+		grams[i] = Fingerprint{
+			Hash:  h.Sum64(),
+			Start: ws[i].start,
+			End:   ws[i+k-1].end,
+		}
+	}
+
+	if len(grams) <= w {
+		w = len(grams)
+	}
+
+	var prints []Fingerprint
+
+	picked := -1
+
+	for i := 0; i+w <= len(grams); i++ {
+		least := i
+		for j := i; j < i+w; j++ {
+			if grams[j].Hash <= grams[least].Hash {
+				least = j
+			}
+		}
+
+		if least != picked {
+			prints = append(prints, grams[least])
+			picked = least
+		}
+	}
+
+	return prints
+}
+
+// hashes is the set of distinct fingerprint hashes of a document,
+// along with the first fingerprint of each.
+func (d *Document) hashes() map[uint64]Fingerprint {
+	set := make(map[uint64]Fingerprint, len(d.prints))
+
+	for _, p := range d.prints {
+		if _, ok := set[p.Hash]; !ok {
+			set[p.Hash] = p
+		}
+	}
+
+	return set
+}
+
+// Compare finds how similar two documents are, and the passages they
+// share. It returns nil when they share nothing.
+func Compare(a, b *Document) *SimilarityPair {
+	ha, hb := a.hashes(), b.hashes()
+	if len(ha) == 0 || len(hb) == 0 {
+		return nil
+	}
+
+	shared := 0
+	for h := range ha {
+		if _, ok := hb[h]; ok {
+			shared++
+		}
+	}
+
+	if shared == 0 {
+		return nil
+	}
+
+	fewer := len(ha)
+	if len(hb) < fewer {
+		fewer = len(hb)
+	}
+
+	return &SimilarityPair{
+		SubmissionA: a.SubmissionId,
+		NetIdA:      a.NetId,
+		SubmissionB: b.SubmissionId,
+		NetIdB:      b.NetId,
+		AssignmentB: b.AssignmentId,
+		Score:       float64(shared) / float64(fewer),
+		Matches:     matches(a, b, hb),
+	}
+}
+
+// matches walks the fingerprints of a in order, joining those found
+// in b into passages for as long as they overlap in both texts.
+func matches(a, b *Document, hb map[uint64]Fingerprint) []*Match {
+	var ms []*Match
+
+	var m *Match
+
+	for _, pa := range a.prints {
+		pb, ok := hb[pa.Hash]
+		if !ok {
+			continue
+		}
+
+		if m != nil && pa.Start <= m.AEnd && pb.Start <= m.BEnd && pb.End >= m.BStart {
+			m.AEnd = max(m.AEnd, pa.End)
+			m.BStart = min(m.BStart, pb.Start)
+			m.BEnd = max(m.BEnd, pb.End)
+			continue
+		}
+
+		m = &Match{AStart: pa.Start, AEnd: pa.End, BStart: pb.Start, BEnd: pb.End}
+		ms = append(ms, m)
+	}
+
+	for _, m := range ms {
+		m.Text = a.Text[m.AStart:m.AEnd]
+	}
+
+	return ms
+}
+
+// RankPairs compares every two documents of an assignment, and each
+// of them with every document of past offerings, leaving out
+// documents by the same student. Pairs that share less than
+// MinSimilarity are left out, and the rest are ranked from most to
+// least similar.
+func RankPairs(docs, past []*Document) []*SimilarityPair {
+	var pairs []*SimilarityPair
+
+	add := func(a, b *Document) {
+		if a.NetId != "" && a.NetId == b.NetId {
+			return
+		}
+
+		if p := Compare(a, b); p != nil && p.Score >= MinSimilarity {
+			pairs = append(pairs, p)
+		}
+	}
+
+	for i, a := range docs {
+		for _, b := range docs[i+1:] {
+			add(a, b)
+		}
+
+		for _, b := range past {
+			add(a, b)
+		}
+	}
+
+	sort.SliceStable(
+		pairs, func(i, j int) bool {
+			return pairs[i].Score > pairs[j].Score
+		},
+	)
+
+	return pairs
+}
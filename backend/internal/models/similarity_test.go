@@ -0,0 +1,112 @@
+package models
+
+import (
+	"strings"
+	"testing"
+)
+
+const copied = "The mitochondria is the powerhouse of the cell and it produces most of the chemical energy needed to power the cell's biochemical reactions"
+
+func TestWinnow(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		same bool
+	}{
+		{name: "same text", a: copied, b: copied, same: true},
+		{name: "case and punctuation", a: copied, b: strings.ToUpper(copied) + "!!", same: true},
+		{name: "spacing", a: copied, b: strings.ReplaceAll(copied, " ", "\n\t "), same: true},
+		{name: "different text", a: copied, b: "An entirely different essay about the French revolution and its causes in the eighteenth century"},
+	}
+
+	for _, tt := range tests {
+		a, b := Winnow(tt.a, KGramSize, WindowSize), Winnow(tt.b, KGramSize, WindowSize)
+
+		same := len(a) == len(b)
+		for i := 0; same && i < len(a); i++ {
+			same = a[i].Hash == b[i].Hash
+		}
+
+		if same != tt.same {
+			t.Errorf("%s: got same %v, want %v", tt.name, same, tt.same)
+		}
+	}
+
+	if got := Winnow("too short", KGramSize, WindowSize); got != nil {
+		t.Errorf("got %v, want no fingerprints for fewer words than a k-gram", got)
+	}
+}
+
+func TestCompare(t *testing.T) {
+	a := NewDocument("s1", "stu1", "a1", "In my own words I will argue that. "+copied+". That is all I have to say.")
+	b := NewDocument("s2", "stu2", "a1", "Some introduction of mine comes first here. "+copied)
+	c := NewDocument("s3", "stu3", "a1", "An entirely different essay about the French revolution and its causes in the eighteenth century")
+
+	p := Compare(a, b)
+	if p == nil {
+		t.Fatalf("got no similarity, want the copied passage found")
+	}
+
+	if p.Score <= 0.5 {
+		t.Errorf("got score %v, want most of the shorter text shared", p.Score)
+	}
+
+	if len(p.Matches) != 1 {
+		t.Fatalf("got %d matches, want 1", len(p.Matches))
+	}
+
+	m := p.Matches[0]
+
+	if !strings.Contains(copied, m.Text) || len(m.Text) < len(copied)/2 {
+		t.Errorf("got match %q, want most of the copied passage", m.Text)
+	}
+
+	if got := b.Text[m.BStart:m.BEnd]; !strings.Contains(copied, got) {
+		t.Errorf("got %q in the second text, want part of the copied passage", got)
+	}
+
+	if p := Compare(a, c); p != nil {
+		t.Errorf("got %v, want nothing shared", p.Score)
+	}
+}
+
+func TestRankPairs(t *testing.T) {
+	half := copied[:len(copied)/2]
+
+	docs := []*Document{
+		NewDocument("s1", "stu1", "a1", copied),
+		NewDocument("s2", "stu2", "a1", half+" and then something else entirely written by the second student alone"),
+		NewDocument("s3", "stu3", "a1", copied),
+	}
+
+	past := []*Document{
+		NewDocument("p1", "stu1", "old", copied),
+		NewDocument("p2", "old1", "old", copied),
+	}
+
+	pairs := RankPairs(docs, past)
+
+	for i := 1; i < len(pairs); i++ {
+		if pairs[i].Score > pairs[i-1].Score {
+			t.Errorf("pair %d scores %v, more than the pair before it", i, pairs[i].Score)
+		}
+	}
+
+	found := make(map[string]bool)
+	for _, p := range pairs {
+		if p.NetIdA == p.NetIdB {
+			t.Errorf("%s was compared with their own submission", p.NetIdA)
+		}
+		found[p.SubmissionA+"-"+p.SubmissionB] = true
+	}
+
+	for _, want := range []string{"s1-s3", "s1-s2", "s3-p1", "s1-p2"} {
+		if !found[want] {
+			t.Errorf("got %v, want pair %s", found, want)
+		}
+	}
+
+	if pairs[0].Score != 1 {
+		t.Errorf("got top score %v, want 1", pairs[0].Score)
+	}
+}
@@ -0,0 +1,98 @@
+package models
+
+import "time"
+
+// Blob is a file kept in a storage volume, such as an uploaded
+// submission or a course banner.
+type Blob struct {
+	Path    string    `json:"path"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mod_time"`
+}
+
+// StoredMedia is a media record along with whether anything in the
+// database still refers to it, such as a course, an assignment or
+// a submission.
+type StoredMedia struct {
+	Media
+	Referenced bool `json:"referenced"`
+}
+
+// GarbageReport describes the outcome of reconciling the media table
+// with the files in a storage volume.
+type GarbageReport struct {
+	// DryRun is true when nothing was actually deleted.
+	DryRun bool `json:"dry_run"`
+
+	// OrphanedMedia are IDs of media records that nothing refers to.
+	OrphanedMedia []string `json:"orphaned_media"`
+
+	// RemovedBlobs are paths of files that no live media refers to.
+	RemovedBlobs []string `json:"removed_blobs"`
+
+	// DanglingMedia are live media records whose files are missing.
+	DanglingMedia []*Media `json:"dangling_media"`
+
+	// FreedBytes is the total size of the removed blobs.
+	FreedBytes int64 `json:"freed_bytes"`
+}
+
+// StorageUsage is the amount of storage used by a course.
+type StorageUsage struct {
+	CourseId string `json:"course_id"`
+	Files    int    `json:"files"`
+	Bytes    int64  `json:"bytes"`
+
+	// ByKind breaks down the bytes used by what the media is
+	// attributed to, such as "course", "assignment" or "submission".
+	ByKind map[string]int64 `json:"by_kind"`
+}
+
+// StorageQuota is the most storage, in bytes, that a single user or
+// course may use. A limit of zero means unlimited.
+type StorageQuota struct {
+	User   int64 `json:"user"`
+	Course int64 `json:"course"`
+}
+
+// QuotaUsage is the storage used by a user or a course, against
+// its quota.
+type QuotaUsage struct {
+	// Kind is either "user" or "course".
+	Kind  string `json:"kind"`
+	Id    string `json:"id"`
+	Used  int64  `json:"used"`
+	Limit int64  `json:"limit"`
+}
+
+// Allows reports whether size more bytes fit within the quota.
+func (q *QuotaUsage) Allows(size int64) bool {
+	return q.Limit == 0 || q.Used+size <= q.Limit
+}
+
+// ScanStatus is the outcome of scanning an uploaded file for malware.
+type ScanStatus string
+
+const (
+	// UNSCANNED files were stored without a scanner configured.
+	UNSCANNED ScanStatus = "unscanned"
+
+	// CLEAN files were scanned and nothing was found.
+	CLEAN ScanStatus = "clean"
+
+	// QUARANTINED files were found to contain malware. They are kept
+	// for inspection but are never served.
+	QUARANTINED ScanStatus = "quarantined"
+)
+
+// ScanResult is the outcome of scanning a file, along with the name
+// of the threat that was found, if any.
+type ScanResult struct {
+	Status ScanStatus `json:"scan_status,omitempty"`
+	Threat string     `json:"threat,omitempty"`
+}
+
+// Quarantined reports whether the scanned file contains malware.
+func (sr ScanResult) Quarantined() bool {
+	return sr.Status == QUARANTINED
+}
@@ -25,7 +25,12 @@ CREATE TABLE IF NOT EXISTS media (
    type VARCHAR NOT NULL,
    path VARCHAR NOT NULL,
    created_at TIMESTAMP WITHOUT TIME ZONE NOT NULL,
-   updated_at TIMESTAMP WITHOUT TIME ZONE NOT NULL
+   updated_at TIMESTAMP WITHOUT TIME ZONE NOT NULL,
+   parent_id UUID REFERENCES media(id) ON DELETE CASCADE,
+   variant VARCHAR,
+   size BIGINT NOT NULL DEFAULT 0,
+   scan_status VARCHAR NOT NULL DEFAULT 'unscanned',
+   threat VARCHAR
 );
 
 -- Courses Table
@@ -57,16 +62,38 @@ CREATE TABLE IF NOT EXISTS messages (
    title VARCHAR NOT NULL,
    description TEXT,
    date TIMESTAMP WITHOUT TIME ZONE,
-   type BOOLEAN
+   type BOOLEAN,
+   owner VARCHAR REFERENCES users(net_id) ON DELETE SET NULL,
+   updated_at TIMESTAMP WITHOUT TIME ZONE,
+   locked BOOLEAN NOT NULL DEFAULT FALSE,
+   pinned BOOLEAN NOT NULL DEFAULT FALSE,
+   hidden BOOLEAN NOT NULL DEFAULT FALSE
+);
+
+-- Comments Table, for discussion messages. Replies refer to the
+-- comment they reply to through parent_id.
+CREATE TABLE IF NOT EXISTS comments (
+   id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+   message_id UUID NOT NULL REFERENCES messages(id) ON DELETE CASCADE,
+   parent_id UUID REFERENCES comments(id) ON DELETE CASCADE,
+   owner VARCHAR REFERENCES users(net_id) ON DELETE SET NULL,
+   body TEXT NOT NULL,
+   created_at TIMESTAMP WITHOUT TIME ZONE NOT NULL,
+   updated_at TIMESTAMP WITHOUT TIME ZONE NOT NULL,
+   deleted_at TIMESTAMP WITHOUT TIME ZONE,
+   hidden BOOLEAN NOT NULL DEFAULT FALSE
 );
 
+CREATE INDEX IF NOT EXISTS comments_message_id_idx ON comments (message_id, parent_id, created_at);
+
 -- Assignments Table
 CREATE TABLE IF NOT EXISTS assignments (
    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
    title VARCHAR NOT NULL,
    description TEXT,
    date TIMESTAMP WITHOUT TIME ZONE,
-   due_date TIMESTAMP WITHOUT TIME ZONE
+   due_date TIMESTAMP WITHOUT TIME ZONE,
+   allowed_file_types VARCHAR
 );
 
 -- Submissions Table
@@ -225,6 +252,417 @@ CREATE TABLE IF NOT EXISTS submission_media (
       PRIMARY KEY (submission_id, media_id)
 );
 
+-- Junction Table for the Feedback on a Submission and Media, such as
+-- audio comments and marked up documents.
+CREATE TABLE IF NOT EXISTS feedback_media (
+   submission_id UUID REFERENCES submissions(id) ON
+   DELETE
+      CASCADE,
+      media_id UUID REFERENCES media(id) ON
+   DELETE
+      CASCADE,
+      media_path VARCHAR,
+      PRIMARY KEY (submission_id, media_id)
+);
+
+-- Teams Table, for the teams of a project. Each team has its own
+-- discussion, which is not linked to the course like other
+-- discussions so that only the team and its teachers can see it.
+CREATE TABLE IF NOT EXISTS teams (
+   id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+   project_id UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+   name VARCHAR NOT NULL,
+   message_id UUID REFERENCES messages(id) ON DELETE SET NULL,
+   submission_id UUID REFERENCES submissions(id) ON DELETE SET NULL,
+   created_at TIMESTAMP WITHOUT TIME ZONE NOT NULL
+);
+
+-- Junction Table for Teams and Users (Many-to-Many). A student may
+-- only be on one team per project.
+CREATE TABLE IF NOT EXISTS team_members (
+   team_id UUID REFERENCES teams(id) ON
+   DELETE
+      CASCADE,
+      project_id UUID NOT NULL REFERENCES projects(id) ON
+   DELETE
+      CASCADE,
+      user_net_id VARCHAR REFERENCES users(net_id) ON
+   DELETE
+      CASCADE,
+      PRIMARY KEY (team_id, user_net_id),
+      UNIQUE (project_id, user_net_id)
+);
+
+-- Junction Table for Teams and Media (Many-to-Many)
+CREATE TABLE IF NOT EXISTS team_media (
+   team_id UUID REFERENCES teams(id) ON
+   DELETE
+      CASCADE,
+      media_id UUID REFERENCES media(id) ON
+   DELETE
+      CASCADE,
+      media_path VARCHAR,
+      PRIMARY KEY (team_id, media_id)
+);
+
+-- Quizzes Table. Each quiz has an assignment that its attempts are
+-- graded into.
+CREATE TABLE IF NOT EXISTS quizzes (
+   id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+   course_id UUID NOT NULL REFERENCES courses(id) ON DELETE CASCADE,
+   assignment_id UUID REFERENCES assignments(id) ON DELETE SET NULL,
+   title VARCHAR NOT NULL,
+   description TEXT,
+   owner VARCHAR REFERENCES users(net_id) ON DELETE SET NULL,
+   time_limit INT NOT NULL DEFAULT 0,
+   opens_at TIMESTAMP WITHOUT TIME ZONE,
+   closes_at TIMESTAMP WITHOUT TIME ZONE,
+   max_attempts INT NOT NULL DEFAULT 1,
+   shuffle_questions BOOLEAN NOT NULL DEFAULT FALSE,
+   shuffle_choices BOOLEAN NOT NULL DEFAULT FALSE,
+   created_at TIMESTAMP WITHOUT TIME ZONE NOT NULL
+);
+
+-- Quiz Questions Table. Choices and the answer key are stored as
+-- JSON, since their shape depends on the question type.
+CREATE TABLE IF NOT EXISTS quiz_questions (
+   id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+   quiz_id UUID NOT NULL REFERENCES quizzes(id) ON DELETE CASCADE,
+   position INT NOT NULL,
+   type VARCHAR NOT NULL,
+   prompt TEXT NOT NULL,
+   points FLOAT NOT NULL DEFAULT 1,
+   choices JSONB,
+   answer_key JSONB
+);
+
+-- Quiz Attempts Table. The deadline is fixed when an attempt starts.
+CREATE TABLE IF NOT EXISTS quiz_attempts (
+   id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+   quiz_id UUID NOT NULL REFERENCES quizzes(id) ON DELETE CASCADE,
+   user_net_id VARCHAR NOT NULL REFERENCES users(net_id) ON DELETE CASCADE,
+   started_at TIMESTAMP WITHOUT TIME ZONE NOT NULL,
+   deadline TIMESTAMP WITHOUT TIME ZONE,
+   submitted_at TIMESTAMP WITHOUT TIME ZONE,
+   seed BIGINT NOT NULL,
+   score FLOAT NOT NULL DEFAULT 0,
+   submission_id UUID REFERENCES submissions(id) ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS quiz_attempts_quiz_id_idx ON quiz_attempts (quiz_id, user_net_id);
+
+-- Quiz Responses Table, for an attempt's answer to each question.
+CREATE TABLE IF NOT EXISTS quiz_responses (
+   attempt_id UUID REFERENCES quiz_attempts(id) ON
+   DELETE
+      CASCADE,
+      question_id UUID REFERENCES quiz_questions(id) ON
+   DELETE
+      CASCADE,
+      answer JSONB NOT NULL,
+      points FLOAT NOT NULL DEFAULT 0,
+      graded BOOLEAN NOT NULL DEFAULT FALSE,
+      PRIMARY KEY (attempt_id, question_id)
+);
+
+-- Rubrics Table. Rubrics belong to a course and can be attached to
+-- any of its assignments.
+CREATE TABLE IF NOT EXISTS rubrics (
+   id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+   course_id UUID NOT NULL REFERENCES courses(id) ON DELETE CASCADE,
+   title VARCHAR NOT NULL,
+   description TEXT,
+   owner VARCHAR REFERENCES users(net_id) ON DELETE SET NULL,
+   created_at TIMESTAMP WITHOUT TIME ZONE NOT NULL
+);
+
+-- Rubric Criteria Table. Levels are stored as JSON.
+CREATE TABLE IF NOT EXISTS rubric_criteria (
+   id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+   rubric_id UUID NOT NULL REFERENCES rubrics(id) ON DELETE CASCADE,
+   position INT NOT NULL,
+   name VARCHAR NOT NULL,
+   description TEXT,
+   levels JSONB NOT NULL
+);
+
+-- Rubric Scores Table, for a submission's score on each criterion.
+CREATE TABLE IF NOT EXISTS rubric_scores (
+   submission_id UUID REFERENCES submissions(id) ON
+   DELETE
+      CASCADE,
+      criterion_id UUID REFERENCES rubric_criteria(id) ON
+   DELETE
+      CASCADE,
+      level VARCHAR,
+      points FLOAT NOT NULL DEFAULT 0,
+      comment TEXT,
+      PRIMARY KEY (submission_id, criterion_id)
+);
+
+-- Grade Categories Table. Categories group a course's assignments so
+-- that they count towards the course grade by weight.
+CREATE TABLE IF NOT EXISTS grade_categories (
+   id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+   course_id UUID NOT NULL REFERENCES courses(id) ON DELETE CASCADE,
+   name VARCHAR NOT NULL,
+   weight FLOAT NOT NULL DEFAULT 0,
+   drop_lowest INT NOT NULL DEFAULT 0,
+   created_at TIMESTAMP WITHOUT TIME ZONE NOT NULL
+);
+
+-- Gradebook Items Table, for how an assignment counts in the
+-- gradebook.
+CREATE TABLE IF NOT EXISTS gradebook_items (
+   assignment_id UUID PRIMARY KEY REFERENCES assignments(id) ON DELETE CASCADE,
+   category_id UUID REFERENCES grade_categories(id) ON DELETE SET NULL,
+   points FLOAT NOT NULL DEFAULT 100,
+   extra_credit BOOLEAN NOT NULL DEFAULT FALSE
+);
+
+-- Grade Schemes Table, for courses that set their own letter grades.
+CREATE TABLE IF NOT EXISTS grade_schemes (
+   course_id UUID PRIMARY KEY REFERENCES courses(id) ON DELETE CASCADE,
+   scheme JSONB NOT NULL
+);
+
+-- Late Policies Table, for how an assignment treats late
+-- submissions. Durations are in seconds.
+CREATE TABLE IF NOT EXISTS late_policies (
+   assignment_id UUID PRIMARY KEY REFERENCES assignments(id) ON DELETE CASCADE,
+   grace_period INT NOT NULL DEFAULT 0,
+   hard_cutoff BOOLEAN NOT NULL DEFAULT FALSE,
+   deduction FLOAT NOT NULL DEFAULT 0,
+   deduction_interval VARCHAR,
+   max_lateness INT NOT NULL DEFAULT 0
+);
+
+-- Extensions Table, for a student's own due date on an assignment.
+CREATE TABLE IF NOT EXISTS extensions (
+   assignment_id UUID REFERENCES assignments(id) ON DELETE CASCADE,
+   net_id VARCHAR REFERENCES users(net_id) ON DELETE CASCADE,
+   due_date TIMESTAMP WITHOUT TIME ZONE NOT NULL,
+   reason TEXT,
+   granted_by VARCHAR REFERENCES users(net_id) ON DELETE SET NULL,
+   PRIMARY KEY (assignment_id, net_id)
+);
+
+-- Accommodations Table, for a student's standing changes to every
+-- due date and quiz time limit in a course.
+CREATE TABLE IF NOT EXISTS accommodations (
+   course_id UUID REFERENCES courses(id) ON DELETE CASCADE,
+   net_id VARCHAR REFERENCES users(net_id) ON DELETE CASCADE,
+   extra_time INT NOT NULL DEFAULT 0,
+   time_multiplier FLOAT NOT NULL DEFAULT 0,
+   note TEXT,
+   PRIMARY KEY (course_id, net_id)
+);
+
+-- Notifications Table, for telling users about things that concern
+-- them, such as their grades being released.
+CREATE TABLE IF NOT EXISTS notifications (
+   id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+   net_id VARCHAR NOT NULL REFERENCES users(net_id) ON DELETE CASCADE,
+   kind VARCHAR NOT NULL,
+   message TEXT NOT NULL,
+   ref VARCHAR,
+   created_at TIMESTAMP WITHOUT TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+   read_at TIMESTAMP WITHOUT TIME ZONE
+);
+
+-- Peer Review Policies Table, for how many students review each
+-- submission to an assignment and how much their scores count.
+CREATE TABLE IF NOT EXISTS peer_review_policies (
+   assignment_id UUID PRIMARY KEY REFERENCES assignments(id) ON DELETE CASCADE,
+   reviewers INT NOT NULL DEFAULT 0,
+   weight FLOAT NOT NULL DEFAULT 0,
+   allocated_at TIMESTAMP WITHOUT TIME ZONE
+);
+
+-- Peer Reviews Table, for a student's review of another student's
+-- submission. Reviews are allocated empty and filled in later.
+CREATE TABLE IF NOT EXISTS peer_reviews (
+   id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+   assignment_id UUID NOT NULL REFERENCES assignments(id) ON DELETE CASCADE,
+   submission_id UUID NOT NULL REFERENCES submissions(id) ON DELETE CASCADE,
+   reviewer VARCHAR NOT NULL REFERENCES users(net_id) ON DELETE CASCADE,
+   score FLOAT,
+   comments TEXT,
+   submitted_at TIMESTAMP WITHOUT TIME ZONE,
+   UNIQUE (submission_id, reviewer)
+);
+
+-- Peer Review Scores Table, for a review's score on each criterion of
+-- the assignment's rubric.
+CREATE TABLE IF NOT EXISTS peer_review_scores (
+   review_id UUID REFERENCES peer_reviews(id) ON DELETE CASCADE,
+   criterion_id UUID REFERENCES rubric_criteria(id) ON DELETE CASCADE,
+   level VARCHAR,
+   points FLOAT NOT NULL DEFAULT 0,
+   comment TEXT,
+   PRIMARY KEY (review_id, criterion_id)
+);
+
+-- Regrade Requests Table, for students disputing the grade of one of
+-- their submissions.
+CREATE TABLE IF NOT EXISTS regrade_requests (
+   id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+   submission_id UUID NOT NULL REFERENCES submissions(id) ON DELETE CASCADE,
+   assignment_id UUID NOT NULL REFERENCES assignments(id) ON DELETE CASCADE,
+   net_id VARCHAR NOT NULL REFERENCES users(net_id) ON DELETE CASCADE,
+   justification TEXT NOT NULL,
+   state VARCHAR NOT NULL,
+   assigned_to VARCHAR REFERENCES users(net_id) ON DELETE SET NULL,
+   old_grade FLOAT NOT NULL DEFAULT 0,
+   new_grade FLOAT,
+   reason TEXT,
+   resolved_by VARCHAR REFERENCES users(net_id) ON DELETE SET NULL,
+   resolved_at TIMESTAMP WITHOUT TIME ZONE,
+   created_at TIMESTAMP WITHOUT TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Regrade Events Table, for the history of each regrade request.
+CREATE TABLE IF NOT EXISTS regrade_events (
+   id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+   request_id UUID NOT NULL REFERENCES regrade_requests(id) ON DELETE CASCADE,
+   actor VARCHAR NOT NULL,
+   state VARCHAR NOT NULL,
+   note TEXT,
+   created_at TIMESTAMP WITHOUT TIME ZONE NOT NULL
+);
+
+-- Similarity Reports Table, for comparing the text of an assignment's
+-- submissions to find copying. compare_with holds the IDs of the
+-- assignments of past offerings that are compared against.
+CREATE TABLE IF NOT EXISTS similarity_reports (
+   id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+   assignment_id UUID NOT NULL REFERENCES assignments(id) ON DELETE CASCADE,
+   requested_by VARCHAR NOT NULL REFERENCES users(net_id) ON DELETE CASCADE,
+   compare_with JSONB NOT NULL DEFAULT '[]',
+   status VARCHAR NOT NULL,
+   error TEXT,
+   created_at TIMESTAMP WITHOUT TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+   finished_at TIMESTAMP WITHOUT TIME ZONE
+);
+
+-- Similarity Pairs Table, for the pairs of submissions a similarity
+-- report found to share text, along with the passages they share.
+CREATE TABLE IF NOT EXISTS similarity_pairs (
+   id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+   report_id UUID NOT NULL REFERENCES similarity_reports(id) ON DELETE CASCADE,
+   submission_a UUID NOT NULL REFERENCES submissions(id) ON DELETE CASCADE,
+   netid_a VARCHAR NOT NULL,
+   submission_b UUID NOT NULL REFERENCES submissions(id) ON DELETE CASCADE,
+   netid_b VARCHAR NOT NULL,
+   assignment_b UUID NOT NULL REFERENCES assignments(id) ON DELETE CASCADE,
+   score FLOAT NOT NULL,
+   matches JSONB NOT NULL
+);
+
+-- Harnesses Table, for what an assignment's submissions are tested
+-- with by the autograder. timeout is in seconds and memory in
+-- megabytes.
+CREATE TABLE IF NOT EXISTS harnesses (
+   assignment_id UUID PRIMARY KEY REFERENCES assignments(id) ON DELETE CASCADE,
+   command TEXT NOT NULL,
+   image VARCHAR,
+   timeout INT NOT NULL DEFAULT 60,
+   memory INT NOT NULL DEFAULT 256
+);
+
+-- Harness Media Table, for the files of a harness.
+CREATE TABLE IF NOT EXISTS harness_media (
+   assignment_id UUID REFERENCES harnesses(assignment_id) ON DELETE CASCADE,
+   media_id UUID REFERENCES media(id) ON DELETE CASCADE,
+   PRIMARY KEY (assignment_id, media_id)
+);
+
+-- Grade Jobs Table, for each run of a harness over a submission.
+CREATE TABLE IF NOT EXISTS grade_jobs (
+   id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+   submission_id UUID NOT NULL REFERENCES submissions(id) ON DELETE CASCADE,
+   assignment_id UUID NOT NULL REFERENCES assignments(id) ON DELETE CASCADE,
+   status VARCHAR NOT NULL,
+   score FLOAT,
+   passed INT NOT NULL DEFAULT 0,
+   total INT NOT NULL DEFAULT 0,
+   tests JSONB NOT NULL DEFAULT '[]',
+   log TEXT,
+   error TEXT,
+   created_at TIMESTAMP WITHOUT TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+   started_at TIMESTAMP WITHOUT TIME ZONE,
+   finished_at TIMESTAMP WITHOUT TIME ZONE
+);
+
+-- Annotations Table, for teachers' marks on the pages of submitted PDFs.
+CREATE TABLE IF NOT EXISTS annotations (
+   id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+   submission_id UUID NOT NULL REFERENCES submissions(id) ON DELETE CASCADE,
+   media_id UUID NOT NULL REFERENCES media(id) ON DELETE CASCADE,
+   author VARCHAR NOT NULL,
+   kind VARCHAR NOT NULL,
+   page INT NOT NULL,
+   rects JSONB NOT NULL DEFAULT 'null',
+   point JSONB,
+   strokes JSONB NOT NULL DEFAULT 'null',
+   comment TEXT,
+   color VARCHAR NOT NULL,
+   created_at TIMESTAMP WITHOUT TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+   updated_at TIMESTAMP WITHOUT TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS annotations_submission_idx ON annotations (submission_id);
+
+-- Modules organize a course into units, such as weeks. Their items
+-- are ordered, and refer to an assignment, announcement or file of the
+-- course, or are links or pages of their own.
+CREATE TABLE IF NOT EXISTS modules (
+   id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+   course_id UUID NOT NULL REFERENCES courses(id) ON DELETE CASCADE,
+   title VARCHAR NOT NULL,
+   position INT NOT NULL,
+   published BOOLEAN NOT NULL DEFAULT FALSE,
+   created_at TIMESTAMP WITHOUT TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+   updated_at TIMESTAMP WITHOUT TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS modules_course_idx ON modules (course_id);
+
+-- Modules that must be completed before a module is unlocked.
+CREATE TABLE IF NOT EXISTS module_prerequisites (
+   module_id UUID REFERENCES modules(id) ON DELETE CASCADE,
+   prerequisite_id UUID REFERENCES modules(id) ON DELETE CASCADE,
+   PRIMARY KEY (module_id, prerequisite_id)
+);
+
+CREATE TABLE IF NOT EXISTS module_items (
+   id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+   module_id UUID NOT NULL REFERENCES modules(id) ON DELETE CASCADE,
+   kind VARCHAR NOT NULL,
+   title VARCHAR NOT NULL,
+   position INT NOT NULL,
+   assignment_id UUID REFERENCES assignments(id) ON DELETE CASCADE,
+   message_id UUID REFERENCES messages(id) ON DELETE CASCADE,
+   media_id UUID REFERENCES media(id) ON DELETE CASCADE,
+   url VARCHAR,
+   body TEXT,
+   requirement VARCHAR,
+   created_at TIMESTAMP WITHOUT TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+   updated_at TIMESTAMP WITHOUT TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS module_items_module_idx ON module_items (module_id);
+
+-- Module items students completed by viewing them or marking them as
+-- done. Submissions complete items on their own.
+CREATE TABLE IF NOT EXISTS module_completions (
+   item_id UUID REFERENCES module_items(id) ON DELETE CASCADE,
+   net_id VARCHAR REFERENCES users(net_id) ON DELETE CASCADE,
+   requirement VARCHAR NOT NULL,
+   completed_at TIMESTAMP WITHOUT TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+   PRIMARY KEY (item_id, net_id, requirement)
+);
+
 -- Adding foreign key constraints after all tables are established and maintain direct single relationships
 -- Use a cascade deletion.
 ALTER TABLE
@@ -234,6 +672,32 @@ ADD
 DELETE
    CASCADE;
 
+ALTER TABLE
+   projects
+ADD
+   COLUMN course_id UUID REFERENCES courses(id) ON
+DELETE
+   CASCADE;
+
+-- The assignment that a project's team submissions are made for.
+ALTER TABLE
+   projects
+ADD
+   COLUMN assignment_id UUID REFERENCES assignments(id) ON
+DELETE
+SET
+   NULL;
+
+ALTER TABLE
+   projects
+ADD
+   COLUMN team_size INT NOT NULL DEFAULT 4;
+
+ALTER TABLE
+   projects
+ADD
+   COLUMN self_enroll BOOLEAN NOT NULL DEFAULT FALSE;
+
 ALTER TABLE
    assignments
 ADD
@@ -265,6 +729,105 @@ ADD
 DELETE
    CASCADE;
 
+-- The rubric that an assignment's submissions are graded against.
+ALTER TABLE
+   assignments
+ADD
+   COLUMN rubric_id UUID REFERENCES rubrics(id) ON
+DELETE
+SET
+   NULL;
+
+-- Whether a submission has been given a grade, so that the gradebook
+-- can tell a grade of zero apart from no grade.
+ALTER TABLE
+   submissions
+ADD
+   COLUMN graded BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- The grade a submission was given before its late penalty, and the
+-- percentage that was taken off it.
+ALTER TABLE
+   submissions
+ADD
+   COLUMN raw_grade FLOAT,
+ADD
+   COLUMN late_penalty FLOAT NOT NULL DEFAULT 0;
+
+-- Every submission is one attempt at an assignment. Counts marks the
+-- attempt that counts towards the student's grade, and pinned marks
+-- one a teacher chose.
+ALTER TABLE
+   submissions
+ADD
+   COLUMN attempt INT NOT NULL DEFAULT 1,
+ADD
+   COLUMN counts BOOLEAN NOT NULL DEFAULT TRUE,
+ADD
+   COLUMN pinned BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- How many attempts an assignment allows, where 0 allows any number,
+-- and which attempt counts.
+ALTER TABLE
+   assignments
+ADD
+   COLUMN max_attempts INT NOT NULL DEFAULT 0,
+ADD
+   COLUMN counted_attempt VARCHAR;
+
+-- The name a file was uploaded with, and a checksum of its contents,
+-- so that attempts can be compared.
+ALTER TABLE
+   media
+ADD
+   COLUMN name VARCHAR,
+ADD
+   COLUMN checksum VARCHAR;
+
+-- Anonymous assignments hide who made each submission from graders
+-- until grades are released. Pseudonyms are derived from the key.
+ALTER TABLE
+   assignments
+ADD
+   COLUMN anonymous BOOLEAN NOT NULL DEFAULT FALSE,
+ADD
+   COLUMN anonymous_key VARCHAR,
+ADD
+   COLUMN grades_released BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- When an assignment's grades are scheduled to be released.
+ALTER TABLE
+   assignments
+ADD
+   COLUMN release_at TIMESTAMP WITHOUT TIME ZONE;
+
+-- The average score a submission's peer reviewers gave it.
+ALTER TABLE
+   submissions
+ADD
+   COLUMN peer_score FLOAT;
+
+-- How many seconds after grades are released students may request a
+-- regrade, where 0 means there is no limit.
+ALTER TABLE
+   assignments
+ADD
+   COLUMN regrade_window INT NOT NULL DEFAULT 604800;
+
+-- Where an assignment is in its lifecycle, being a draft, published or
+-- locked, and when it is published, takes submissions from and is
+-- locked. Assignments made before they had a state are published.
+ALTER TABLE
+   assignments
+ADD
+   COLUMN state VARCHAR NOT NULL DEFAULT 'published',
+ADD
+   COLUMN publish_at TIMESTAMP WITHOUT TIME ZONE,
+ADD
+   COLUMN available_from TIMESTAMP WITHOUT TIME ZONE,
+ADD
+   COLUMN lock_at TIMESTAMP WITHOUT TIME ZONE;
+
 -- Foreign key for profile picture which relates to the Media table
 ALTER TABLE
    users